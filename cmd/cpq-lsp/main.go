@@ -0,0 +1,43 @@
+// cmd/cpq-lsp/main.go
+// Language server for CPQ rule expressions, speaking LSP over stdio
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"DD/lsp"
+)
+
+func main() {
+	modelPath := flag.String("model", "", "Path to the model JSON file rules are checked against")
+	logPath := flag.String("log", "", "Optional file for server logs (stdout is reserved for the protocol)")
+	flag.Parse()
+
+	if *modelPath == "" {
+		fmt.Fprintln(os.Stderr, "usage: cpq-lsp -model <model.json> [-log <file>]")
+		os.Exit(2)
+	}
+
+	workspace, err := lsp.LoadWorkspace(*modelPath)
+	if err != nil {
+		log.Fatalf("❌ Failed to load model: %v", err)
+	}
+
+	server := lsp.NewServer(workspace)
+	if *logPath != "" {
+		logFile, err := os.OpenFile(*logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Fatalf("❌ Failed to open log file: %v", err)
+		}
+		defer logFile.Close()
+		server.SetLogger(log.New(logFile, "cpq-lsp ", log.LstdFlags))
+	}
+
+	if err := server.Serve(os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("❌ Language server stopped: %v", err)
+	}
+}
@@ -0,0 +1,357 @@
+// approval.go - Discount approval policies and workflow
+// Policies are rule-language conditions over a price breakdown; a quote that
+// trips one needs approval from the policy's role before it can complete
+
+package cpq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"DD/evaluator"
+	"DD/parser"
+)
+
+// ApprovalStatus is the state of a quote in the approval workflow:
+// draft → pending_approval → approved or rejected
+type ApprovalStatus string
+
+const (
+	ApprovalDraft    ApprovalStatus = "draft"
+	ApprovalPending  ApprovalStatus = "pending_approval"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
+)
+
+// AdminRole may act on any approval requirement
+const AdminRole = "admin"
+
+// MarginFloorApproverRole approves prices below an approval margin floor
+// that names no approver role of its own
+const MarginFloorApproverRole = AdminRole
+
+// ApprovalPolicy requires approval from a role when its condition holds for
+// a quote's price breakdown
+type ApprovalPolicy struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Description  string `json:"description,omitempty"`
+	Condition    string `json:"condition"` // e.g. `discount_pct > 15 OR margin_pct < 30`
+	ApproverRole string `json:"approver_role"`
+}
+
+// ApprovalRequirement is an approval a quote needs, from a policy or a
+// margin floor
+type ApprovalRequirement struct {
+	PolicyID     string     `json:"policy_id"`
+	PolicyName   string     `json:"policy_name"`
+	ApproverRole string     `json:"approver_role"`
+	ApprovedBy   string     `json:"approved_by,omitempty"`
+	ApprovedAt   *time.Time `json:"approved_at,omitempty"`
+}
+
+// ApprovalEvent is an entry in an approval's history
+type ApprovalEvent struct {
+	Action  string    `json:"action"` // "submitted", "approved", "rejected", "commented" or "invalidated"
+	UserID  string    `json:"user_id,omitempty"`
+	Role    string    `json:"role,omitempty"`
+	Comment string    `json:"comment,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Approval tracks a quote through the approval workflow. The fingerprint
+// identifies the selections and price that were submitted; an approval only
+// holds for an unchanged quote.
+type Approval struct {
+	Status       ApprovalStatus        `json:"status"`
+	Requirements []ApprovalRequirement `json:"requirements,omitempty"`
+	Fingerprint  string                `json:"fingerprint,omitempty"`
+	SubmittedBy  string                `json:"submitted_by,omitempty"`
+	SubmittedAt  *time.Time            `json:"submitted_at,omitempty"`
+	History      []ApprovalEvent       `json:"history"`
+}
+
+// Variables available to approval conditions
+const (
+	approvalBaseVariable         = "base"                   // Sum of quantity × base price
+	approvalListVariable         = "list_price"             // Net price before contract terms and requested discounts
+	approvalTotalVariable        = "total"                  // Net price
+	approvalDiscountVariable     = "discount"               // base - total
+	approvalDiscountPctVariable  = "discount_pct"           // discount as a percentage of base
+	approvalRequestedPctVariable = "requested_discount_pct" // Requested discount percentage
+	approvalCostVariable         = "cost"                   // Total cost over the term
+	approvalMarginVariable       = "margin"                 // Margin over the term
+	approvalMarginPctVariable    = "margin_pct"             // Margin as a percentage of contract value
+	approvalOneTimeVariable      = "one_time_total"         // Net price plus one-time charges
+	approvalMRRVariable          = "mrr"                    // Monthly recurring revenue
+	approvalARRVariable          = "arr"                    // Annual recurring revenue
+	approvalTCVVariable          = "tcv"                    // Total contract value
+	approvalTermVariable         = "term_months"            // Contract term
+	approvalBelowFloorVariable   = "below_margin_floor"     // Price breaches a margin floor
+	approvalContractVariable     = "has_contract"           // Customer contract terms apply
+)
+
+// approvalContext binds the variables of approval conditions to a breakdown
+func approvalContext(b PriceBreakdown) evaluator.Context {
+	list := b.TotalPrice
+	requested := 0.0
+	if b.Discount != nil {
+		list -= b.Discount.Amount
+		if list > 0 {
+			requested = -b.Discount.Amount / list * 100
+		}
+	}
+	if b.Contract != nil {
+		list = b.Contract.ListPrice
+	}
+
+	discount := b.BasePrice - b.TotalPrice
+	discountPct := 0.0
+	if b.BasePrice > 0 {
+		discountPct = discount / b.BasePrice * 100
+	}
+
+	return evaluator.Context{
+		approvalBaseVariable:         b.BasePrice,
+		approvalListVariable:         list,
+		approvalTotalVariable:        b.TotalPrice,
+		approvalDiscountVariable:     discount,
+		approvalDiscountPctVariable:  discountPct,
+		approvalRequestedPctVariable: requested,
+		approvalCostVariable:         b.TotalCost,
+		approvalMarginVariable:       b.Margin,
+		approvalMarginPctVariable:    b.MarginPercent,
+		approvalOneTimeVariable:      b.OneTimeTotal,
+		approvalMRRVariable:          b.MRR,
+		approvalARRVariable:          b.ARR,
+		approvalTCVVariable:          b.TotalContractValue,
+		approvalTermVariable:         float64(b.TermMonths),
+		approvalBelowFloorVariable:   b.RequiresApproval,
+		approvalContractVariable:     b.Contract != nil,
+	}
+}
+
+// RequiredApprovals returns the approvals a price breakdown needs: one per
+// policy whose condition holds, and one per approval margin floor it falls
+// below. A policy whose condition fails to evaluate requires approval, so a
+// broken policy cannot wave quotes through.
+func RequiredApprovals(model *Model, breakdown PriceBreakdown) []ApprovalRequirement {
+	var requirements []ApprovalRequirement
+	context := approvalContext(breakdown)
+
+	for _, policy := range model.ApprovalPolicies {
+		holds, err := evaluator.EvaluateExpression(policy.Condition, context)
+		if triggered, _ := holds.(bool); err != nil || triggered {
+			requirements = append(requirements, ApprovalRequirement{
+				PolicyID:     policy.ID,
+				PolicyName:   policy.Name,
+				ApproverRole: policy.ApproverRole,
+			})
+		}
+	}
+
+	for _, violation := range breakdown.MarginViolations {
+		if violation.Action != ApproveBelowFloor {
+			continue
+		}
+		role := MarginFloorApproverRole
+		for _, floor := range model.MarginFloors {
+			if floor.ID == violation.FloorID {
+				role = floor.approverRole()
+			}
+		}
+		name := violation.FloorName
+		if name == "" {
+			name = violation.FloorID
+		}
+		requirements = append(requirements, ApprovalRequirement{
+			PolicyID:     "margin_floor:" + violation.FloorID,
+			PolicyName:   fmt.Sprintf("%s (margin %.1f%% below %.1f%%)", name, violation.MarginPercent, violation.MinMarginPercent),
+			ApproverRole: role,
+		})
+	}
+
+	return requirements
+}
+
+// QuoteFingerprint identifies a quote's selections and price
+func QuoteFingerprint(selections []Selection, breakdown PriceBreakdown) string {
+	parts := make([]string, 0, len(selections)+1)
+	for _, selection := range selections {
+		if selection.Quantity > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", selection.OptionID, selection.Quantity))
+		}
+	}
+	sort.Strings(parts)
+	parts = append(parts, fmt.Sprintf("%s:%.2f:%.2f", breakdown.Currency, breakdown.TotalPrice, breakdown.TotalContractValue))
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, ";")))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewApproval starts a quote's approval in draft
+func NewApproval() *Approval {
+	return &Approval{Status: ApprovalDraft, History: []ApprovalEvent{}}
+}
+
+// Submit sends a draft or rejected quote for approval. A quote that needs no
+// approval is approved straight away.
+func (a *Approval) Submit(requirements []ApprovalRequirement, fingerprint, userID string, now time.Time) error {
+	if a.Status != ApprovalDraft && a.Status != ApprovalRejected {
+		return fmt.Errorf("cannot submit a quote that is %s", a.Status)
+	}
+
+	a.Requirements = requirements
+	a.Fingerprint = fingerprint
+	a.SubmittedBy = userID
+	a.SubmittedAt = &now
+	a.record("submitted", userID, "", "", now)
+
+	a.Status = ApprovalPending
+	if len(requirements) == 0 {
+		a.Status = ApprovalApproved
+	}
+	return nil
+}
+
+// Approve records an approval by a user in a role. The user approves every
+// outstanding requirement for their role, or all of them as an admin; the
+// quote is approved once none remain.
+func (a *Approval) Approve(userID, role, comment string, now time.Time) error {
+	if a.Status != ApprovalPending {
+		return fmt.Errorf("cannot approve a quote that is %s", a.Status)
+	}
+	if userID == a.SubmittedBy && role != AdminRole {
+		return fmt.Errorf("quotes cannot be approved by their submitter")
+	}
+
+	approved := false
+	outstanding := 0
+	for i := range a.Requirements {
+		requirement := &a.Requirements[i]
+		if requirement.ApprovedBy != "" {
+			continue
+		}
+		if role == AdminRole || role == requirement.ApproverRole {
+			requirement.ApprovedBy = userID
+			requirement.ApprovedAt = &now
+			approved = true
+			continue
+		}
+		outstanding++
+	}
+	if !approved {
+		return fmt.Errorf("role %q cannot approve this quote", role)
+	}
+
+	a.record("approved", userID, role, comment, now)
+	if outstanding == 0 {
+		a.Status = ApprovalApproved
+	}
+	return nil
+}
+
+// Reject rejects a pending quote. Any role with an outstanding requirement
+// may reject, and a reason is required.
+func (a *Approval) Reject(userID, role, comment string, now time.Time) error {
+	if a.Status != ApprovalPending {
+		return fmt.Errorf("cannot reject a quote that is %s", a.Status)
+	}
+	if strings.TrimSpace(comment) == "" {
+		return fmt.Errorf("a reason is required to reject a quote")
+	}
+	if !a.CanAct(role) {
+		return fmt.Errorf("role %q cannot reject this quote", role)
+	}
+
+	a.record("rejected", userID, role, comment, now)
+	a.Status = ApprovalRejected
+	return nil
+}
+
+// Comment adds a comment to the approval history
+func (a *Approval) Comment(userID, role, comment string, now time.Time) error {
+	if strings.TrimSpace(comment) == "" {
+		return fmt.Errorf("comment cannot be empty")
+	}
+	a.record("commented", userID, role, comment, now)
+	return nil
+}
+
+// Invalidate returns a pending or approved quote to draft after an edit.
+// It reports whether the approval changed.
+func (a *Approval) Invalidate(reason string, now time.Time) bool {
+	if a.Status != ApprovalPending && a.Status != ApprovalApproved {
+		return false
+	}
+	a.Status = ApprovalDraft
+	a.Requirements = nil
+	a.Fingerprint = ""
+	a.record("invalidated", "", "", reason, now)
+	return true
+}
+
+// Holds reports whether the approval covers a quote with a fingerprint
+func (a *Approval) Holds(fingerprint string) bool {
+	return a.Status == ApprovalApproved && a.Fingerprint == fingerprint
+}
+
+// CanAct reports whether a role may approve or reject the quote: an admin,
+// or a role with an outstanding requirement
+func (a *Approval) CanAct(role string) bool {
+	if role == AdminRole {
+		return true
+	}
+	for _, requirement := range a.Requirements {
+		if requirement.ApprovedBy == "" && requirement.ApproverRole == role {
+			return true
+		}
+	}
+	return false
+}
+
+// record appends an event to the history
+func (a *Approval) record(action, userID, role, comment string, now time.Time) {
+	a.History = append(a.History, ApprovalEvent{
+		Action:  action,
+		UserID:  userID,
+		Role:    role,
+		Comment: comment,
+		At:      now,
+	})
+}
+
+// ValidateApprovalPolicies validates that policy conditions parse as
+// conditions over the approval variables
+func ValidateApprovalPolicies(policies []ApprovalPolicy) error {
+	ids := make(map[string]bool)
+	context := approvalContext(PriceBreakdown{})
+
+	for _, policy := range policies {
+		if policy.ID == "" {
+			return fmt.Errorf("approval policy ID cannot be empty")
+		}
+		if ids[policy.ID] {
+			return fmt.Errorf("duplicate approval policy %s", policy.ID)
+		}
+		ids[policy.ID] = true
+
+		if policy.ApproverRole == "" {
+			return fmt.Errorf("approval policy %s must name an approver role", policy.ID)
+		}
+
+		expr, err := parser.ParseExpression(policy.Condition)
+		if err != nil {
+			return fmt.Errorf("approval policy %s: %w", policy.ID, err)
+		}
+		if err := checkTrialExpression(expr, context, true); err != nil {
+			return fmt.Errorf("approval policy %s: %w", policy.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,260 @@
+package cpq
+
+import (
+	"testing"
+	"time"
+)
+
+func createTestModelWithApprovals() *Model {
+	model := createTestModelWithCosts()
+	model.ApprovalPolicies = []ApprovalPolicy{
+		{ID: "deal_desk", Name: "Deal desk", Condition: "discount_pct > 15 OR margin_pct < 30", ApproverRole: "manager"},
+		{ID: "large_deal", Name: "Large deal", Condition: "total >= 1000", ApproverRole: "director"},
+	}
+	return model
+}
+
+func TestRequiredApprovals(t *testing.T) {
+	selections := []Selection{
+		{OptionID: "opt1", Quantity: 1},
+		{OptionID: "opt2", Quantity: 1},
+	} // $150, cost $90
+
+	tests := []struct {
+		name     string
+		discount float64
+		want     []string
+	}{
+		{"no discount", 0, nil},
+		{"discount within policy", 10, nil},                    // 33.3% margin
+		{"discount over threshold", 20, []string{"deal_desk"}}, // 25% margin
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := createTestModelWithApprovals()
+			list := NewPricingCalculator(model).CalculatePrice(selections, PricingContext{})
+			priced, err := ApplyDiscount(model, list, tt.discount)
+			if err != nil {
+				t.Fatalf("ApplyDiscount failed: %v", err)
+			}
+
+			requirements := RequiredApprovals(model, priced)
+			if len(requirements) != len(tt.want) {
+				t.Fatalf("Expected requirements %v, got %v", tt.want, requirements)
+			}
+			for i, id := range tt.want {
+				if requirements[i].PolicyID != id || requirements[i].ApproverRole != "manager" {
+					t.Errorf("Expected requirement %s for manager, got %+v", id, requirements[i])
+				}
+			}
+		})
+	}
+
+	model := createTestModelWithApprovals()
+	large := NewPricingCalculator(model).CalculatePrice([]Selection{{OptionID: "opt3", Quantity: 5}}, PricingContext{})
+	// $1000 at a 25% margin needs both policies
+	requirements := RequiredApprovals(model, large)
+	if len(requirements) != 2 || requirements[1].ApproverRole != "director" {
+		t.Errorf("Expected manager and director approval for a large deal, got %v", requirements)
+	}
+}
+
+func TestRequiredApprovals_MarginFloor(t *testing.T) {
+	model := createTestModelWithCosts()
+	model.MarginFloors = []MarginFloor{{ID: "floor", Name: "Floor", MinMarginPercent: 30, Action: ApproveBelowFloor}}
+	list := NewPricingCalculator(model).CalculatePrice([]Selection{{OptionID: "opt1", Quantity: 1}}, PricingContext{})
+
+	priced, err := ApplyDiscount(model, list, 20)
+	if err != nil {
+		t.Fatalf("ApplyDiscount failed: %v", err)
+	}
+
+	requirements := RequiredApprovals(model, priced)
+	if len(requirements) != 1 || requirements[0].PolicyID != "margin_floor:floor" || requirements[0].ApproverRole != MarginFloorApproverRole {
+		t.Errorf("Expected margin floor approval, got %v", requirements)
+	}
+}
+
+func TestRequiredApprovals_MarginFloorApproverRole(t *testing.T) {
+	model := createTestModelWithCosts()
+	model.MarginFloors = []MarginFloor{{ID: "floor", MinMarginPercent: 30, Action: ApproveBelowFloor, ApproverRole: "user"}}
+	list := NewPricingCalculator(model).CalculatePrice([]Selection{{OptionID: "opt1", Quantity: 1}}, PricingContext{})
+
+	priced, err := ApplyDiscount(model, list, 20)
+	if err != nil {
+		t.Fatalf("ApplyDiscount failed: %v", err)
+	}
+	requirements := RequiredApprovals(model, priced)
+	if len(requirements) != 1 || requirements[0].ApproverRole != "user" {
+		t.Fatalf("Expected approval from the floor's role, got %v", requirements)
+	}
+
+	approval := NewApproval()
+	if err := approval.Submit(requirements, QuoteFingerprint(nil, priced), "alice", time.Now()); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if err := approval.Approve("carol", "demo", "", time.Now()); err == nil {
+		t.Error("Expected error approving with another role")
+	}
+	if err := approval.Approve("bob", "user", "", time.Now()); err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	if approval.Status != ApprovalApproved {
+		t.Errorf("Expected the floor's role to clear the requirement, got %s", approval.Status)
+	}
+}
+
+func TestRequiredApprovals_BrokenPolicy(t *testing.T) {
+	model := createTestModelForPricing()
+	model.ApprovalPolicies = []ApprovalPolicy{{ID: "broken", Condition: "total >", ApproverRole: "manager"}}
+
+	if requirements := RequiredApprovals(model, PriceBreakdown{}); len(requirements) != 1 {
+		t.Errorf("A policy that fails to evaluate should require approval, got %v", requirements)
+	}
+}
+
+func TestApproval_Workflow(t *testing.T) {
+	now := time.Now()
+	requirements := []ApprovalRequirement{
+		{PolicyID: "deal_desk", ApproverRole: "manager"},
+		{PolicyID: "large_deal", ApproverRole: "director"},
+	}
+
+	approval := NewApproval()
+	if err := approval.Approve("bob", "manager", "", now); err == nil {
+		t.Error("Expected error approving a draft")
+	}
+	if err := approval.Submit(requirements, "fp", "alice", now); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if approval.Status != ApprovalPending {
+		t.Fatalf("Expected pending approval, got %s", approval.Status)
+	}
+	if err := approval.Submit(requirements, "fp", "alice", now); err == nil {
+		t.Error("Expected error resubmitting a pending quote")
+	}
+
+	if err := approval.Approve("alice", "manager", "", now); err == nil {
+		t.Error("Submitter should not approve their own quote")
+	}
+	if err := approval.Approve("carol", "sales", "", now); err == nil {
+		t.Error("Role without a requirement should not approve")
+	}
+	if err := approval.Approve("bob", "manager", "Fine by me", now); err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	if approval.Status != ApprovalPending || approval.CanAct("manager") {
+		t.Errorf("Expected quote pending director approval, got %s", approval.Status)
+	}
+	if err := approval.Approve("dan", "director", "", now); err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	if !approval.Holds("fp") || approval.Holds("other") {
+		t.Error("Approval should hold for the submitted quote only")
+	}
+
+	// Edits return the quote to draft
+	if !approval.Invalidate("Configuration changed", now) {
+		t.Fatal("Expected approval to be invalidated")
+	}
+	if approval.Status != ApprovalDraft || approval.Holds("fp") || len(approval.Requirements) != 0 {
+		t.Errorf("Expected invalidated draft, got %s with %v", approval.Status, approval.Requirements)
+	}
+	if approval.Invalidate("Again", now) {
+		t.Error("Draft should not be invalidated again")
+	}
+
+	actions := []string{"submitted", "approved", "approved", "invalidated"}
+	if len(approval.History) != len(actions) {
+		t.Fatalf("Expected history %v, got %v", actions, approval.History)
+	}
+	for i, action := range actions {
+		if approval.History[i].Action != action {
+			t.Errorf("Expected history entry %d to be %s, got %s", i, action, approval.History[i].Action)
+		}
+	}
+}
+
+func TestApproval_Reject(t *testing.T) {
+	now := time.Now()
+	approval := NewApproval()
+	approval.Submit([]ApprovalRequirement{{PolicyID: "p", ApproverRole: "manager"}}, "fp", "alice", now)
+
+	if err := approval.Reject("bob", "manager", " ", now); err == nil {
+		t.Error("Expected error rejecting without a reason")
+	}
+	if err := approval.Reject("bob", "manager", "Too deep", now); err != nil {
+		t.Fatalf("Reject failed: %v", err)
+	}
+	if approval.Status != ApprovalRejected || approval.Invalidate("edit", now) {
+		t.Errorf("Expected rejected quote to stay rejected, got %s", approval.Status)
+	}
+
+	// A rejected quote may be resubmitted; an admin may approve anything
+	approval.Submit([]ApprovalRequirement{{PolicyID: "p", ApproverRole: "manager"}}, "fp2", "alice", now)
+	if err := approval.Approve("alice", AdminRole, "", now); err != nil {
+		t.Fatalf("Admin approve failed: %v", err)
+	}
+	if !approval.Holds("fp2") {
+		t.Errorf("Expected approved quote, got %s", approval.Status)
+	}
+}
+
+func TestApproval_SubmitWithoutRequirements(t *testing.T) {
+	approval := NewApproval()
+	if err := approval.Submit(nil, "fp", "alice", time.Now()); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if approval.Status != ApprovalApproved {
+		t.Errorf("Quote needing no approval should be approved, got %s", approval.Status)
+	}
+}
+
+func TestQuoteFingerprint(t *testing.T) {
+	breakdown := PriceBreakdown{TotalPrice: 150, TotalContractValue: 150}
+	a := QuoteFingerprint([]Selection{{OptionID: "opt1", Quantity: 1}, {OptionID: "opt2", Quantity: 2}}, breakdown)
+	b := QuoteFingerprint([]Selection{{OptionID: "opt2", Quantity: 2}, {OptionID: "opt1", Quantity: 1}}, breakdown)
+	if a != b {
+		t.Error("Fingerprint should not depend on selection order")
+	}
+
+	if a == QuoteFingerprint([]Selection{{OptionID: "opt1", Quantity: 1}, {OptionID: "opt2", Quantity: 3}}, breakdown) {
+		t.Error("Fingerprint should change with quantities")
+	}
+	breakdown.TotalPrice = 140
+	if a == QuoteFingerprint([]Selection{{OptionID: "opt1", Quantity: 1}, {OptionID: "opt2", Quantity: 2}}, breakdown) {
+		t.Error("Fingerprint should change with price")
+	}
+}
+
+func TestValidateApprovalPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ApprovalPolicy
+		wantErr bool
+	}{
+		{"valid policy", ApprovalPolicy{ID: "p", Condition: "discount_pct > 15 OR margin_pct < 30", ApproverRole: "manager"}, false},
+		{"boolean variable", ApprovalPolicy{ID: "p", Condition: "has_contract AND requested_discount_pct > 0", ApproverRole: "manager"}, false},
+		{"division by zero at validation", ApprovalPolicy{ID: "p", Condition: "margin / total < 0.2", ApproverRole: "manager"}, false},
+		{"missing ID", ApprovalPolicy{Condition: "total > 0", ApproverRole: "manager"}, true},
+		{"missing role", ApprovalPolicy{ID: "p", Condition: "total > 0"}, true},
+		{"syntax error", ApprovalPolicy{ID: "p", Condition: "total >", ApproverRole: "manager"}, true},
+		{"unknown variable", ApprovalPolicy{ID: "p", Condition: "discount_percent > 15", ApproverRole: "manager"}, true},
+		{"not a condition", ApprovalPolicy{ID: "p", Condition: "total * 2", ApproverRole: "manager"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateApprovalPolicies([]ApprovalPolicy{tt.policy})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	duplicate := ApprovalPolicy{ID: "p", Condition: "total > 0", ApproverRole: "manager"}
+	if err := ValidateApprovalPolicies([]ApprovalPolicy{duplicate, duplicate}); err == nil {
+		t.Error("Expected error for duplicate policy")
+	}
+}
@@ -0,0 +1,529 @@
+// assembly.go - Hierarchical configuration
+// An option may reference a child model; each unit selected is a sub-assembly
+// configured from that model, e.g. a rack holding servers holding drives.
+// Assembly rules check numeric option attributes across levels, and pricing
+// rolls up from the sub-assemblies to the top level.
+
+package cpq
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"DD/evaluator"
+	"DD/parser"
+	"DD/tax"
+)
+
+// maxAssemblyDepth bounds the nesting of sub-assemblies
+const maxAssemblyDepth = 8
+
+// AssemblyRule is a condition over the attributes of an assembly and its
+// sub-assemblies, e.g. `power_watts >= servers_psu_watts`
+type AssemblyRule struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	Message    string `json:"message"`
+}
+
+// Variables available to assembly rules besides option IDs and <option>_qty:
+//
+//	<attribute>            total of a numeric option attribute over the
+//	                       assembly and every sub-assembly below it
+//	<option>_count         number of sub-assemblies of a sub-assembly option
+//	<option>_<attribute>   total of an attribute over those sub-assemblies
+const assemblyCountSuffix = "_count"
+
+// SubAssemblyOptions returns the options that reference a child model
+func (m *Model) SubAssemblyOptions() []Option {
+	var options []Option
+	for _, option := range m.Options {
+		if option.ChildModelID != "" {
+			options = append(options, option)
+		}
+	}
+	return options
+}
+
+// IsAssembly reports whether the model has sub-assemblies or assembly rules,
+// so its configurations need validating as a tree
+func (m *Model) IsAssembly() bool {
+	return len(m.AssemblyRules) > 0 || len(m.SubAssemblyOptions()) > 0
+}
+
+// ChildModelIDs returns the models the model's sub-assembly options reference
+func (m *Model) ChildModelIDs() []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, option := range m.SubAssemblyOptions() {
+		if !seen[option.ChildModelID] {
+			seen[option.ChildModelID] = true
+			ids = append(ids, option.ChildModelID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// childPath names the index-th sub-assembly of an option below a path
+func childPath(path, optionID string, index int) string {
+	name := fmt.Sprintf("%s[%d]", optionID, index)
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// numericAttributes returns an option's numeric attributes
+func numericAttributes(option *Option) map[string]float64 {
+	values := make(map[string]float64)
+	for key, value := range option.Attributes {
+		if number, ok := evaluator.ToFloat64(value); ok {
+			values[key] = number
+		}
+	}
+	return values
+}
+
+// attributeNames returns the numeric attribute names of a model and every
+// model below it
+func attributeNames(models map[string]*Model, modelID string, depth int) map[string]bool {
+	names := make(map[string]bool)
+	model, ok := models[modelID]
+	if !ok || depth > maxAssemblyDepth {
+		return names
+	}
+	for i := range model.Options {
+		for name := range numericAttributes(&model.Options[i]) {
+			names[name] = true
+		}
+	}
+	for _, childID := range model.ChildModelIDs() {
+		for name := range attributeNames(models, childID, depth+1) {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// assemblyContext binds the variables of a model's assembly rules. totals
+// holds the attribute totals of the whole assembly, children those of each
+// sub-assembly option's instances and counts their number.
+func assemblyContext(models map[string]*Model, model *Model, selections []Selection, totals map[string]float64, children map[string]map[string]float64, counts map[string]int) evaluator.Context {
+	context := make(evaluator.Context)
+	for _, option := range model.Options {
+		context[option.ID] = false
+		context[option.ID+priceQuantitySuffix] = 0.0
+	}
+	for _, selection := range selections {
+		if _, known := context[selection.OptionID]; known && selection.Quantity > 0 {
+			context[selection.OptionID] = true
+			context[selection.OptionID+priceQuantitySuffix] = context[selection.OptionID+priceQuantitySuffix].(float64) + float64(selection.Quantity)
+		}
+	}
+
+	for name := range attributeNames(models, model.ID, 0) {
+		context[name] = totals[name]
+	}
+	for _, option := range model.SubAssemblyOptions() {
+		context[option.ID+assemblyCountSuffix] = float64(counts[option.ID])
+		for name := range attributeNames(models, option.ChildModelID, 1) {
+			context[option.ID+"_"+name] = children[option.ID][name]
+		}
+	}
+	return context
+}
+
+// ValidateAssembly validates a configuration and its sub-assemblies: each
+// level against its model's constraints, the number of sub-assemblies
+// against the quantity of their option, and each model's assembly rules.
+// models holds every model in the tree; violations carry the path of the
+// sub-assembly they are in.
+func ValidateAssembly(models map[string]*Model, config Configuration) ValidationResult {
+	validator := &assemblyValidator{models: models, engines: make(map[string]*ConstraintEngine)}
+	validator.validate(config, "", 0)
+	return ValidationResult{
+		IsValid:     len(validator.violations) == 0,
+		Violations:  validator.violations,
+		Suggestions: []string{},
+	}
+}
+
+// assemblyValidator walks an assembly, sharing constraint engines between
+// sub-assemblies of the same model
+type assemblyValidator struct {
+	models     map[string]*Model
+	engines    map[string]*ConstraintEngine
+	violations []RuleViolation
+}
+
+func (v *assemblyValidator) violation(path, ruleID, ruleName, message string) {
+	v.violations = append(v.violations, RuleViolation{
+		RuleID:          ruleID,
+		RuleName:        ruleName,
+		Message:         message,
+		AffectedOptions: []string{},
+		Path:            path,
+	})
+}
+
+// validate validates one level and returns the attribute totals of it and
+// everything below it
+func (v *assemblyValidator) validate(config Configuration, path string, depth int) map[string]float64 {
+	totals := make(map[string]float64)
+	if depth > maxAssemblyDepth {
+		v.violation(path, "assembly_depth", "Assembly depth", fmt.Sprintf("sub-assemblies cannot be nested more than %d levels deep", maxAssemblyDepth))
+		return totals
+	}
+	model, ok := v.models[config.ModelID]
+	if !ok {
+		v.violation(path, "assembly_model", "Assembly model", fmt.Sprintf("model not found: %s", config.ModelID))
+		return totals
+	}
+
+	// This level's own constraints
+	engine, ok := v.engines[model.ID]
+	if !ok {
+		var err error
+		if engine, err = NewConstraintEngine(model); err != nil {
+			v.violation(path, "assembly_model", "Assembly model", err.Error())
+			return totals
+		}
+		v.engines[model.ID] = engine
+	}
+	for _, violation := range engine.ValidateSelections(config.Selections).Violations {
+		violation.Path = path
+		v.violations = append(v.violations, violation)
+	}
+
+	quantities := make(map[string]int)
+	for _, selection := range config.Selections {
+		option, err := model.GetOption(selection.OptionID)
+		if err != nil || selection.Quantity <= 0 {
+			continue
+		}
+		quantities[option.ID] += selection.Quantity
+		for name, value := range numericAttributes(option) {
+			totals[name] += value * float64(selection.Quantity)
+		}
+	}
+
+	// Sub-assemblies, numbered per option
+	children := make(map[string]map[string]float64)
+	counts := make(map[string]int)
+	for _, child := range config.Children {
+		option, err := model.GetOption(child.ParentOptionID)
+		if err != nil || option.ChildModelID == "" {
+			v.violation(path, "assembly_structure", "Assembly structure",
+				fmt.Sprintf("%s is not a sub-assembly option of %s", child.ParentOptionID, model.Name))
+			continue
+		}
+		childAt := childPath(path, option.ID, counts[option.ID])
+		counts[option.ID]++
+		if child.ModelID == "" {
+			child.ModelID = option.ChildModelID
+		}
+		if child.ModelID != option.ChildModelID {
+			v.violation(childAt, "assembly_structure", "Assembly structure",
+				fmt.Sprintf("%s must be configured from model %s, not %s", option.Name, option.ChildModelID, child.ModelID))
+			continue
+		}
+
+		if children[option.ID] == nil {
+			children[option.ID] = make(map[string]float64)
+		}
+		for name, value := range v.validate(child, childAt, depth+1) {
+			children[option.ID][name] += value
+			totals[name] += value
+		}
+	}
+	for _, option := range model.SubAssemblyOptions() {
+		if counts[option.ID] != quantities[option.ID] {
+			v.violation(path, "assembly_structure", "Assembly structure",
+				fmt.Sprintf("%s needs %d configured sub-assemblies, found %d", option.Name, quantities[option.ID], counts[option.ID]))
+		}
+	}
+
+	// Rules across this level and the levels below
+	if len(model.AssemblyRules) > 0 {
+		context := assemblyContext(v.models, model, config.Selections, totals, children, counts)
+		for _, rule := range model.AssemblyRules {
+			result, err := evaluator.EvaluateExpression(rule.Expression, context)
+			if err != nil {
+				v.violation(path, rule.ID, rule.Name, fmt.Sprintf("cannot be evaluated: %v", err))
+				continue
+			}
+			if holds, _ := result.(bool); !holds {
+				message := rule.Message
+				if message == "" {
+					message = fmt.Sprintf("%s is not satisfied", rule.Name)
+				}
+				v.violation(path, rule.ID, rule.Name, message)
+			}
+		}
+	}
+
+	return totals
+}
+
+// AssemblyPrice is the price of an assembly level. Breakdown prices the
+// level's own selections; Total adds the lines, charges and totals of every
+// sub-assembly below it.
+type AssemblyPrice struct {
+	Path      string          `json:"path,omitempty"`
+	ModelID   string          `json:"model_id"`
+	OptionID  string          `json:"option_id,omitempty"` // Parent option; empty at the top level
+	Breakdown PriceBreakdown  `json:"breakdown"`
+	Children  []AssemblyPrice `json:"children,omitempty"`
+	Total     PriceBreakdown  `json:"total"`
+}
+
+// PriceAssembly prices a configuration and its sub-assemblies. Sub-assemblies
+// are priced in the top level's currency and contract term, from their own
+// models' default price books, and roll up into the top level's total.
+func PriceAssembly(models map[string]*Model, config Configuration, ctx PricingContext) (*AssemblyPrice, error) {
+	return priceAssembly(models, config, ctx, "", 0)
+}
+
+func priceAssembly(models map[string]*Model, config Configuration, ctx PricingContext, path string, depth int) (*AssemblyPrice, error) {
+	where := path
+	if where == "" {
+		where = "assembly"
+	}
+	if depth > maxAssemblyDepth {
+		return nil, fmt.Errorf("%s: sub-assemblies cannot be nested more than %d levels deep", where, maxAssemblyDepth)
+	}
+	model, ok := models[config.ModelID]
+	if !ok {
+		return nil, fmt.Errorf("%s: model not found: %s", where, config.ModelID)
+	}
+	if err := ValidatePricingContext(model, ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", where, err)
+	}
+
+	breakdown := NewPricingCalculator(model).CalculatePrice(config.Selections, ctx)
+	if path != "" && len(breakdown.Warnings) > 0 {
+		warnings := make([]string, len(breakdown.Warnings))
+		for i, warning := range breakdown.Warnings {
+			warnings[i] = path + ": " + warning
+		}
+		breakdown.Warnings = warnings
+	}
+	price := &AssemblyPrice{Path: path, ModelID: model.ID, OptionID: config.ParentOptionID, Breakdown: breakdown}
+
+	childCtx := ctx
+	childCtx.PriceBookID = ""
+	childCtx.Currency = breakdown.Currency
+	childCtx.TermMonths = breakdown.TermMonths
+
+	counts := make(map[string]int)
+	for _, child := range config.Children {
+		option, err := model.GetOption(child.ParentOptionID)
+		if err != nil || option.ChildModelID == "" {
+			return nil, fmt.Errorf("%s: %s is not a sub-assembly option", where, child.ParentOptionID)
+		}
+		if child.ModelID == "" {
+			child.ModelID = option.ChildModelID
+		}
+		childAt := childPath(path, option.ID, counts[option.ID])
+		counts[option.ID]++
+
+		childPrice, err := priceAssembly(models, child, childCtx, childAt, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		price.Children = append(price.Children, *childPrice)
+	}
+
+	price.Total = RollUp(breakdown, price.Children)
+	return price, nil
+}
+
+// RollUp adds the totals of sub-assemblies to a level's breakdown. Their
+// lines and charges are appended, tagged with their path, so the rolled-up
+// totals and margin stay consistent when discounts are applied afterwards.
+func RollUp(breakdown PriceBreakdown, children []AssemblyPrice) PriceBreakdown {
+	if len(children) == 0 {
+		return breakdown
+	}
+
+	rolled := breakdown
+	rolled.Lines = append([]LinePrice(nil), breakdown.Lines...)
+	rolled.Charges = append([]ChargeLine(nil), breakdown.Charges...)
+	rolled.Warnings = append([]string(nil), breakdown.Warnings...)
+	for _, child := range children {
+		rolled.BasePrice += child.Total.BasePrice
+		rolled.TotalPrice += child.Total.TotalPrice
+		rolled.Warnings = append(rolled.Warnings, child.Total.Warnings...)
+		for _, line := range child.Total.Lines {
+			if line.Path == "" {
+				line.Path = child.Path
+			}
+			rolled.Lines = append(rolled.Lines, line)
+		}
+		for _, charge := range child.Total.Charges {
+			if charge.Path == "" {
+				charge.Path = child.Path
+			}
+			rolled.Charges = append(rolled.Charges, charge)
+		}
+	}
+	rolled.updateTotals()
+	return rolled
+}
+
+// ApplyAssemblyTax calculates tax on a rolled-up assembly breakdown like
+// ApplyTax, taking each sub-assembly line's tax category from the model it
+// was configured from
+func ApplyAssemblyTax(models map[string]*Model, rootID string, breakdown PriceBreakdown, ctx PricingContext, exemptions []tax.Exemption) (PriceBreakdown, error) {
+	return applyTax(breakdown, ctx, exemptions, assemblyTaxCategory(models, rootID))
+}
+
+// assemblyTaxCategory looks up the tax category of an option by the
+// sub-assembly path it was rolled up from
+func assemblyTaxCategory(models map[string]*Model, rootID string) func(path, optionID string) string {
+	return func(path, optionID string) string {
+		model := modelAtPath(models, rootID, path)
+		if model == nil {
+			return ""
+		}
+		if option, err := model.GetOption(optionID); err == nil {
+			return option.TaxCategory
+		}
+		return ""
+	}
+}
+
+// modelAtPath returns the model a sub-assembly path is configured from, or
+// nil if the path does not lead to a known model
+func modelAtPath(models map[string]*Model, rootID, path string) *Model {
+	model := models[rootID]
+	if path == "" {
+		return model
+	}
+	for _, step := range strings.Split(path, ".") {
+		if model == nil {
+			return nil
+		}
+		optionID := step
+		if i := strings.Index(step, "["); i >= 0 {
+			optionID = step[:i]
+		}
+		option, err := model.GetOption(optionID)
+		if err != nil || option.ChildModelID == "" {
+			return nil
+		}
+		model = models[option.ChildModelID]
+	}
+	return model
+}
+
+// ValidateAssemblyRules validates a model's assembly rules on their own
+func ValidateAssemblyRules(model *Model) error {
+	ids := make(map[string]bool)
+	for _, rule := range model.AssemblyRules {
+		if rule.ID == "" {
+			return fmt.Errorf("assembly rule ID cannot be empty")
+		}
+		if ids[rule.ID] {
+			return fmt.Errorf("duplicate assembly rule %s", rule.ID)
+		}
+		ids[rule.ID] = true
+
+		if _, err := parser.ParseExpression(rule.Expression); err != nil {
+			return fmt.Errorf("assembly rule %s: %w", rule.ID, err)
+		}
+	}
+	for _, option := range model.SubAssemblyOptions() {
+		if option.ChildModelID == model.ID {
+			return fmt.Errorf("option %s cannot use its own model %s as a sub-assembly", option.ID, model.ID)
+		}
+	}
+	return nil
+}
+
+// ValidateModelTree validates the sub-assembly references of a model and
+// every model below it: each child model exists, no model contains itself
+// and assembly rules are conditions over known variables. models holds the
+// models of the tree.
+func ValidateModelTree(models map[string]*Model, rootID string) error {
+	return validateModelTree(models, rootID, nil)
+}
+
+// ModelParents maps each model ID to the sorted IDs of the models in models
+// with sub-assembly options configured from it
+func ModelParents(models map[string]*Model) map[string][]string {
+	parents := make(map[string][]string)
+	for _, model := range models {
+		for _, childID := range model.ChildModelIDs() {
+			parents[childID] = append(parents[childID], model.ID)
+		}
+	}
+	for _, ids := range parents {
+		sort.Strings(ids)
+	}
+	return parents
+}
+
+// ValidateParentTrees validates the tree of every model above a model, so a
+// change to a sub-assembly model cannot break the assemblies using it.
+// models holds every model, with the changed one in its new version.
+func ValidateParentTrees(models map[string]*Model, modelID string) error {
+	parents := ModelParents(models)
+	checked := make(map[string]bool)
+	pending := parents[modelID]
+	for len(pending) > 0 {
+		parentID := pending[0]
+		pending = pending[1:]
+		if checked[parentID] || parentID == modelID {
+			continue
+		}
+		checked[parentID] = true
+
+		if err := ValidateAssemblyRules(models[parentID]); err != nil {
+			return fmt.Errorf("parent model %s: %w", parentID, err)
+		}
+		if err := ValidateModelTree(models, parentID); err != nil {
+			return fmt.Errorf("parent model %s: %w", parentID, err)
+		}
+		pending = append(pending, parents[parentID]...)
+	}
+	return nil
+}
+
+func validateModelTree(models map[string]*Model, modelID string, ancestors []string) error {
+	for _, ancestor := range ancestors {
+		if ancestor == modelID {
+			return fmt.Errorf("sub-assembly cycle: %s -> %s", strings.Join(ancestors, " -> "), modelID)
+		}
+	}
+	if len(ancestors) > maxAssemblyDepth {
+		return fmt.Errorf("sub-assemblies cannot be nested more than %d levels deep", maxAssemblyDepth)
+	}
+	model, ok := models[modelID]
+	if !ok {
+		return fmt.Errorf("sub-assembly model not found: %s", modelID)
+	}
+
+	// Trial evaluate each rule with nothing selected
+	context := assemblyContext(models, model, nil, nil, nil, nil)
+	for _, rule := range model.AssemblyRules {
+		expr, err := parser.ParseExpression(rule.Expression)
+		if err != nil {
+			return fmt.Errorf("assembly rule %s: %w", rule.ID, err)
+		}
+		if err := checkTrialExpression(expr, context, true); err != nil {
+			return fmt.Errorf("assembly rule %s: %w", rule.ID, err)
+		}
+	}
+
+	path := append(append([]string(nil), ancestors...), modelID)
+	for _, childID := range model.ChildModelIDs() {
+		if err := validateModelTree(models, childID, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package cpq
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createTestAssembly returns a rack holding servers holding drives
+func createTestAssembly() map[string]*Model {
+	drive := NewModel("drive", "Drive")
+	drive.AddGroup(Group{ID: "media", Name: "Media", Type: SingleSelect})
+	drive.AddOption(Option{ID: "ssd", Name: "SSD", GroupID: "media", BasePrice: 200, Cost: 120, IsActive: true,
+		Attributes: map[string]interface{}{"capacity_tb": 2}})
+	drive.AddOption(Option{ID: "hdd", Name: "HDD", GroupID: "media", BasePrice: 100, Cost: 50, IsActive: true,
+		Attributes: map[string]interface{}{"capacity_tb": 8}})
+
+	server := NewModel("server", "Server")
+	server.AddGroup(Group{ID: "chassis", Name: "Chassis", Type: SingleSelect})
+	server.AddGroup(Group{ID: "storage", Name: "Storage", Type: MultiSelect})
+	server.AddOption(Option{ID: "r1", Name: "1U Server", GroupID: "chassis", BasePrice: 3000, Cost: 2000, IsActive: true,
+		Attributes: map[string]interface{}{"psu_watts": 750.0}})
+	server.AddOption(Option{ID: "drives", Name: "Drives", GroupID: "storage", IsActive: true, ChildModelID: "drive"})
+
+	rack := NewModel("rack", "Rack")
+	rack.AddGroup(Group{ID: "enclosure", Name: "Enclosure", Type: SingleSelect})
+	rack.AddGroup(Group{ID: "compute", Name: "Compute", Type: MultiSelect})
+	rack.AddOption(Option{ID: "rack42", Name: "42U Rack", GroupID: "enclosure", BasePrice: 1000, Cost: 600, IsActive: true,
+		Attributes: map[string]interface{}{"power_watts": 2000.0}})
+	rack.AddOption(Option{ID: "servers", Name: "Servers", GroupID: "compute", IsActive: true, ChildModelID: "server"})
+	rack.AssemblyRules = []AssemblyRule{
+		{ID: "power", Name: "Rack power", Expression: "power_watts >= servers_psu_watts", Message: "Servers draw more power than the rack supplies"},
+	}
+
+	return map[string]*Model{drive.ID: drive, server.ID: server, rack.ID: rack}
+}
+
+// createTestRack configures a rack with the given number of servers, each
+// holding two drives
+func createTestRack(servers int) Configuration {
+	config := Configuration{ModelID: "rack", Selections: []Selection{
+		{OptionID: "rack42", Quantity: 1},
+		{OptionID: "servers", Quantity: servers},
+	}}
+	for i := 0; i < servers; i++ {
+		server := Configuration{ModelID: "server", ParentOptionID: "servers", Selections: []Selection{
+			{OptionID: "r1", Quantity: 1},
+			{OptionID: "drives", Quantity: 2},
+		}}
+		for j := 0; j < 2; j++ {
+			server.Children = append(server.Children, Configuration{ParentOptionID: "drives",
+				Selections: []Selection{{OptionID: "ssd", Quantity: 1}}})
+		}
+		config.Children = append(config.Children, server)
+	}
+	return config
+}
+
+func TestValidateAssembly(t *testing.T) {
+	models := createTestAssembly()
+
+	result := ValidateAssembly(models, createTestRack(2))
+	if !result.IsValid {
+		t.Fatalf("Expected two servers to fit the rack, got %+v", result.Violations)
+	}
+
+	// Three servers draw 2250 W from a 2000 W rack
+	result = ValidateAssembly(models, createTestRack(3))
+	if result.IsValid || len(result.Violations) != 1 || result.Violations[0].RuleID != "power" {
+		t.Errorf("Expected the power rule to fail, got %+v", result.Violations)
+	}
+}
+
+func TestValidateAssembly_Structure(t *testing.T) {
+	models := createTestAssembly()
+
+	tests := []struct {
+		name   string
+		modify func(c *Configuration)
+		path   string
+	}{
+		{"fewer servers than selected", func(c *Configuration) { c.Children = c.Children[:1] }, ""},
+		{"not a sub-assembly option", func(c *Configuration) { c.Children[0].ParentOptionID = "rack42" }, ""},
+		{"wrong child model", func(c *Configuration) { c.Children[1].ModelID = "drive" }, "servers[1]"},
+		{"missing drive", func(c *Configuration) { c.Children[1].Children = c.Children[1].Children[:1] }, "servers[1]"},
+		{"two media in one drive", func(c *Configuration) {
+			c.Children[0].Children[1].Selections = []Selection{{OptionID: "ssd", Quantity: 1}, {OptionID: "hdd", Quantity: 1}}
+		}, "servers[0].drives[1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestRack(2)
+			tt.modify(&config)
+			result := ValidateAssembly(models, config)
+			if result.IsValid {
+				t.Fatal("Expected invalid assembly")
+			}
+			found := false
+			for _, violation := range result.Violations {
+				found = found || violation.Path == tt.path
+			}
+			if !found {
+				t.Errorf("Expected a violation at %q, got %+v", tt.path, result.Violations)
+			}
+		})
+	}
+}
+
+func TestPriceAssembly(t *testing.T) {
+	models := createTestAssembly()
+
+	price, err := PriceAssembly(models, createTestRack(2), PricingContext{})
+	if err != nil {
+		t.Fatalf("PriceAssembly failed: %v", err)
+	}
+
+	// 1000 + 2 × (3000 + 2 × 200)
+	if price.Breakdown.TotalPrice != 1000 || math.Abs(price.Total.TotalPrice-7800) > 0.001 {
+		t.Errorf("Expected own price 1000.00 and total 7800.00, got %.2f and %.2f",
+			price.Breakdown.TotalPrice, price.Total.TotalPrice)
+	}
+	// 600 + 2 × (2000 + 2 × 120)
+	if math.Abs(price.Total.TotalCost-5080) > 0.001 {
+		t.Errorf("Expected rolled-up cost 5080.00, got %.2f", price.Total.TotalCost)
+	}
+	if len(price.Children) != 2 || price.Children[1].Children[0].Path != "servers[1].drives[0]" {
+		t.Errorf("Expected nested sub-assembly prices, got %+v", price.Children)
+	}
+
+	paths := make(map[string]bool)
+	for _, line := range price.Total.Lines {
+		paths[line.Path] = true
+	}
+	if !paths[""] || !paths["servers[0]"] || !paths["servers[1].drives[1]"] {
+		t.Errorf("Expected lines tagged with their sub-assembly, got %v", paths)
+	}
+}
+
+func TestValidateModelTree(t *testing.T) {
+	models := createTestAssembly()
+	if err := ValidateModelTree(models, "rack"); err != nil {
+		t.Fatalf("Expected valid tree, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(models map[string]*Model)
+	}{
+		{"missing child model", func(m map[string]*Model) { delete(m, "drive") }},
+		{"cycle", func(m map[string]*Model) { m["drive"].Options[0].ChildModelID = "server" }},
+		{"unknown variable", func(m map[string]*Model) { m["rack"].AssemblyRules[0].Expression = "power_watts >= drives_psu_watts" }},
+		{"not a condition", func(m map[string]*Model) { m["rack"].AssemblyRules[0].Expression = "servers_psu_watts" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := createTestAssembly()
+			tt.modify(models)
+			if err := ValidateModelTree(models, "rack"); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestModelParents(t *testing.T) {
+	models := createTestAssembly()
+	other := NewModel("cabinet", "Cabinet")
+	other.AddOption(Option{ID: "servers", Name: "Servers", IsActive: true, ChildModelID: "server"})
+	models[other.ID] = other
+
+	parents := ModelParents(models)
+	if got := strings.Join(parents["server"], ","); got != "cabinet,rack" {
+		t.Errorf("Expected server used by cabinet and rack, got %q", got)
+	}
+	if got := strings.Join(parents["drive"], ","); got != "server" {
+		t.Errorf("Expected drive used by server, got %q", got)
+	}
+	if len(parents["rack"]) != 0 {
+		t.Errorf("Expected rack to be unused, got %v", parents["rack"])
+	}
+}
+
+func TestValidateParentTrees(t *testing.T) {
+	tests := []struct {
+		name    string
+		modelID string
+		modify  func(model *Model)
+		wantErr string
+	}{
+		{"attribute change keeping parent rules", "server", func(m *Model) { m.Options[0].Attributes["psu_watts"] = 500.0 }, ""},
+		{"attribute removed from a parent rule", "server", func(m *Model) { m.Options[0].Attributes = nil }, "parent model rack"},
+		{"cycle through a parent", "drive", func(m *Model) { m.Options[0].ChildModelID = "rack" }, "sub-assembly cycle"},
+		{"unused model", "rack", func(m *Model) { m.AssemblyRules = nil }, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := createTestAssembly()
+			tt.modify(models[tt.modelID])
+
+			err := ValidateParentTrees(models, tt.modelID)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected parents to stay valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestApplyAssemblyTax(t *testing.T) {
+	models := createTestAssembly()
+	models["drive"].Options[0].TaxCategory = "software"
+	ctx := createTestTaxContext(t)
+
+	price, err := PriceAssembly(models, createTestRack(2), PricingContext{})
+	if err != nil {
+		t.Fatalf("PriceAssembly failed: %v", err)
+	}
+	taxed, err := ApplyAssemblyTax(models, "rack", price.Total, ctx, nil)
+	if err != nil {
+		t.Fatalf("ApplyAssemblyTax failed: %v", err)
+	}
+
+	// Drives $800 at 5%, rack and servers $7000 at 12%
+	if taxed.Tax == nil || taxed.Tax.Tax != 880 {
+		t.Errorf("Expected tax 880.00 with drives taxed as software, got %+v", taxed.Tax)
+	}
+}
+
+func TestPriceQuote_SubAssemblies(t *testing.T) {
+	models := createTestAssembly()
+	rack := createTestRack(2)
+
+	quote := NewQuote("q1", "Racks", 30, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	quote.Lines = []QuoteLine{
+		{ID: "l1", Type: ConfiguredLine, ModelID: "rack", Selections: rack.Selections, Children: rack.Children, Quantity: 2},
+	}
+
+	pricing, err := PriceQuote(quote, models, PricingContext{}, QuoteTerms{})
+	if err != nil {
+		t.Fatalf("PriceQuote failed: %v", err)
+	}
+	if pricing.Lines[0].UnitPrice != 7800 || pricing.TotalPrice != 15600 {
+		t.Errorf("Expected two racks at 7800.00 rolled up, got %.2f each and %.2f", pricing.Lines[0].UnitPrice, pricing.TotalPrice)
+	}
+}
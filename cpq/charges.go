@@ -0,0 +1,201 @@
+// charges.go - Recurring, one-time and usage price components
+// Charges priced over a contract term alongside the option base price
+
+package cpq
+
+import "fmt"
+
+// DefaultTermMonths is the contract term when neither the pricing context
+// nor the model names one
+const DefaultTermMonths = 12
+
+// ChargeType is the charge model of a price component
+type ChargeType string
+
+const (
+	OneTimeCharge   ChargeType = "one_time"  // Charged once, e.g. installation
+	RecurringCharge ChargeType = "recurring" // Charged every billing period, e.g. support
+	UsageCharge     ChargeType = "usage"     // Charged per unit used, priced from an estimate
+)
+
+// BillingPeriod is how often a recurring charge is billed
+type BillingPeriod string
+
+const (
+	MonthlyPeriod   BillingPeriod = "monthly"
+	QuarterlyPeriod BillingPeriod = "quarterly"
+	AnnualPeriod    BillingPeriod = "annual"
+)
+
+// months returns the length of the period in months, or 0 if unknown
+func (p BillingPeriod) months() int {
+	switch p {
+	case MonthlyPeriod:
+		return 1
+	case QuarterlyPeriod:
+		return 3
+	case AnnualPeriod:
+		return 12
+	}
+	return 0
+}
+
+// PriceComponent is a charge an option carries besides its base price. The
+// base price stays the option's one-time list price and is the only part
+// subject to price books, volume tiers, price rules and contracts.
+type PriceComponent struct {
+	ID             string        `json:"id"`
+	Name           string        `json:"name"`
+	ChargeType     ChargeType    `json:"charge_type"`
+	Price          float64       `json:"price"`                     // Per option unit; per period if recurring, per usage unit if usage
+	Cost           float64       `json:"cost,omitempty"`            // Per unit, like Price
+	Period         BillingPeriod `json:"period,omitempty"`          // Recurring only
+	EstimatedUsage float64       `json:"estimated_usage,omitempty"` // Usage units per month per option unit
+	UsageUnit      string        `json:"usage_unit,omitempty"`      // e.g. "GB", "API call"
+}
+
+// ChargeLine is one price component of a selection
+type ChargeLine struct {
+	OptionID      string        `json:"option_id"`
+	ComponentID   string        `json:"component_id"`
+	Name          string        `json:"name"`
+	ChargeType    ChargeType    `json:"charge_type"`
+	Period        BillingPeriod `json:"period,omitempty"`
+	Quantity      int           `json:"quantity"`
+	UnitPrice     float64       `json:"unit_price"`
+	MonthlyAmount float64       `json:"monthly_amount"` // Recurring and usage charges
+	Amount        float64       `json:"amount"`         // Over the contract term
+	Cost          float64       `json:"cost"`           // Over the contract term
+	Path          string        `json:"path,omitempty"` // Sub-assembly the charge was rolled up from
+}
+
+// calculateCharges prices the components of each selection over the term
+func (pc *PricingCalculator) calculateCharges(selections []Selection, source pricingSource) []ChargeLine {
+	var charges []ChargeLine
+
+	for _, selection := range selections {
+		if selection.Quantity <= 0 {
+			continue
+		}
+		option, err := pc.model.GetOption(selection.OptionID)
+		if err != nil {
+			continue // Skip invalid options
+		}
+
+		quantity := float64(selection.Quantity)
+		for _, component := range option.Components {
+			line := ChargeLine{
+				OptionID:    option.ID,
+				ComponentID: component.ID,
+				Name:        component.Name,
+				ChargeType:  component.ChargeType,
+				Period:      component.Period,
+				Quantity:    selection.Quantity,
+				UnitPrice:   component.Price * source.baseRate,
+			}
+			unitCost := component.Cost * source.baseRate
+
+			switch component.ChargeType {
+			case OneTimeCharge:
+				line.Amount = line.UnitPrice * quantity
+				line.Cost = unitCost * quantity
+			case RecurringCharge:
+				months := component.Period.months()
+				if months == 0 {
+					continue
+				}
+				line.MonthlyAmount = line.UnitPrice / float64(months) * quantity
+				line.Amount = line.MonthlyAmount * float64(source.termMonths)
+				line.Cost = unitCost / float64(months) * quantity * float64(source.termMonths)
+			case UsageCharge:
+				line.MonthlyAmount = line.UnitPrice * component.EstimatedUsage * quantity
+				line.Amount = line.MonthlyAmount * float64(source.termMonths)
+				line.Cost = unitCost * component.EstimatedUsage * quantity * float64(source.termMonths)
+			default:
+				continue
+			}
+
+			charges = append(charges, line)
+		}
+	}
+
+	return charges
+}
+
+// updateTotals recomputes the one-time, recurring, contract value and
+// margin totals from the net price, the lines and the charge lines
+func (b *PriceBreakdown) updateTotals() {
+	b.OneTimeTotal = b.TotalPrice
+	b.MRR = 0
+	b.UsageMonthly = 0
+	b.TotalCost = 0
+
+	for _, line := range b.Lines {
+		b.TotalCost += line.Cost
+	}
+
+	for _, charge := range b.Charges {
+		b.TotalCost += charge.Cost
+		switch charge.ChargeType {
+		case OneTimeCharge:
+			b.OneTimeTotal += charge.Amount
+		case RecurringCharge:
+			b.MRR += charge.MonthlyAmount
+		case UsageCharge:
+			b.UsageMonthly += charge.MonthlyAmount
+		}
+	}
+
+	b.ARR = b.MRR * 12
+	b.TotalContractValue = b.OneTimeTotal + (b.MRR+b.UsageMonthly)*float64(b.TermMonths)
+	b.Margin = b.TotalContractValue - b.TotalCost
+	b.MarginPercent = marginPercent(b.TotalContractValue, b.TotalCost)
+}
+
+// ValidatePriceComponents validates the price components and costs of a
+// model's options
+func ValidatePriceComponents(model *Model) error {
+	if model.TermMonths < 0 {
+		return fmt.Errorf("contract term cannot be negative")
+	}
+
+	for _, option := range model.Options {
+		if option.Cost < 0 {
+			return fmt.Errorf("option %s: cost cannot be negative", option.ID)
+		}
+
+		ids := make(map[string]bool)
+		for _, component := range option.Components {
+			if component.ID == "" {
+				return fmt.Errorf("option %s: price component ID cannot be empty", option.ID)
+			}
+			if ids[component.ID] {
+				return fmt.Errorf("option %s: duplicate price component %s", option.ID, component.ID)
+			}
+			ids[component.ID] = true
+
+			if component.Price < 0 {
+				return fmt.Errorf("option %s: price of component %s cannot be negative", option.ID, component.ID)
+			}
+			if component.Cost < 0 {
+				return fmt.Errorf("option %s: cost of component %s cannot be negative", option.ID, component.ID)
+			}
+
+			switch component.ChargeType {
+			case OneTimeCharge:
+			case RecurringCharge:
+				if component.Period.months() == 0 {
+					return fmt.Errorf("option %s: recurring component %s has invalid period %q", option.ID, component.ID, component.Period)
+				}
+			case UsageCharge:
+				if component.EstimatedUsage < 0 {
+					return fmt.Errorf("option %s: estimated usage of component %s cannot be negative", option.ID, component.ID)
+				}
+			default:
+				return fmt.Errorf("option %s: component %s has invalid charge type %q", option.ID, component.ID, component.ChargeType)
+			}
+		}
+	}
+
+	return nil
+}
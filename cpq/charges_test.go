@@ -0,0 +1,154 @@
+package cpq
+
+import (
+	"math"
+	"testing"
+)
+
+func createTestModelWithCharges() *Model {
+	model := createTestModelForPricing()
+
+	model.Options[0].Components = []PriceComponent{
+		{ID: "install", Name: "Installation", ChargeType: OneTimeCharge, Price: 25},
+		{ID: "support", Name: "Support", ChargeType: RecurringCharge, Price: 120, Period: AnnualPeriod},
+	}
+	model.Options[1].Components = []PriceComponent{
+		{ID: "license", Name: "License", ChargeType: RecurringCharge, Price: 30, Period: QuarterlyPeriod},
+		{ID: "storage", Name: "Storage", ChargeType: UsageCharge, Price: 0.5, EstimatedUsage: 20, UsageUnit: "GB"},
+	}
+	return model
+}
+
+func TestPricingCalculator_Charges(t *testing.T) {
+	model := createTestModelWithCharges()
+	calc := NewPricingCalculator(model)
+
+	selections := []Selection{
+		{OptionID: "opt1", Quantity: 1}, // $100 + $25 install, $10/month support
+		{OptionID: "opt2", Quantity: 2}, // 2 × $50, 2 × $10/month license, 2 × 20 GB × $0.50 usage
+	}
+
+	breakdown := calc.CalculatePrice(selections, PricingContext{})
+
+	if breakdown.TotalPrice != 200 {
+		t.Errorf("Expected total price 200.00, got %.2f", breakdown.TotalPrice)
+	}
+	if len(breakdown.Charges) != 4 {
+		t.Fatalf("Expected 4 charge lines, got %d", len(breakdown.Charges))
+	}
+
+	checks := []struct {
+		name     string
+		got      float64
+		expected float64
+	}{
+		{"one-time total", breakdown.OneTimeTotal, 225},
+		{"MRR", breakdown.MRR, 30},
+		{"ARR", breakdown.ARR, 360},
+		{"usage monthly", breakdown.UsageMonthly, 20},
+		{"total contract value", breakdown.TotalContractValue, 225 + 50*12},
+	}
+	for _, check := range checks {
+		if math.Abs(check.got-check.expected) > 0.001 {
+			t.Errorf("Expected %s %.2f, got %.2f", check.name, check.expected, check.got)
+		}
+	}
+	if breakdown.TermMonths != DefaultTermMonths {
+		t.Errorf("Expected default term %d, got %d", DefaultTermMonths, breakdown.TermMonths)
+	}
+}
+
+func TestPricingCalculator_ChargeTerm(t *testing.T) {
+	model := createTestModelWithCharges()
+	model.TermMonths = 24
+	calc := NewPricingCalculator(model)
+
+	selections := []Selection{{OptionID: "opt1", Quantity: 1}} // $125 one-time, $10/month
+
+	breakdown := calc.CalculatePrice(selections, PricingContext{})
+	if breakdown.TermMonths != 24 || breakdown.TotalContractValue != 125+10*24 {
+		t.Errorf("Expected 24-month TCV 365.00, got %d months %.2f", breakdown.TermMonths, breakdown.TotalContractValue)
+	}
+
+	// Context term overrides the model term
+	breakdown = calc.CalculatePrice(selections, PricingContext{TermMonths: 36})
+	if breakdown.TermMonths != 36 || breakdown.TotalContractValue != 125+10*36 {
+		t.Errorf("Expected 36-month TCV 485.00, got %d months %.2f", breakdown.TermMonths, breakdown.TotalContractValue)
+	}
+
+	if err := ValidatePricingContext(model, PricingContext{TermMonths: -1}); err == nil {
+		t.Error("Expected error for negative term")
+	}
+}
+
+func TestPricingCalculator_ChargeCurrency(t *testing.T) {
+	model := createTestModelWithCharges()
+	calc := NewPricingCalculator(model)
+
+	rates := ExchangeRates{{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.5}}
+	breakdown := calc.CalculatePrice([]Selection{{OptionID: "opt1", Quantity: 1}}, PricingContext{Currency: "EUR", Rates: rates})
+
+	if breakdown.Currency != "EUR" || math.Abs(breakdown.MRR-5) > 0.001 {
+		t.Errorf("Expected MRR EUR 5.00, got %s %.2f", breakdown.Currency, breakdown.MRR)
+	}
+}
+
+func TestApplyContract_KeepsRecurringCharges(t *testing.T) {
+	model := createTestModelWithCharges()
+	calc := NewPricingCalculator(model)
+
+	list := calc.CalculatePrice([]Selection{{OptionID: "opt1", Quantity: 1}}, PricingContext{})
+	priced, err := ApplyContract(list, &Contract{ID: "c1", CustomerID: "acme", DiscountPercent: 10})
+	if err != nil {
+		t.Fatalf("ApplyContract failed: %v", err)
+	}
+
+	// Discount applies to the base price only: 100 × 0.9 + 25 install
+	if math.Abs(priced.OneTimeTotal-115) > 0.001 {
+		t.Errorf("Expected one-time total 115.00, got %.2f", priced.OneTimeTotal)
+	}
+	if priced.MRR != list.MRR {
+		t.Errorf("Contract should not change MRR, got %.2f want %.2f", priced.MRR, list.MRR)
+	}
+	if math.Abs(priced.TotalContractValue-(115+10*12)) > 0.001 {
+		t.Errorf("Expected total contract value 235.00, got %.2f", priced.TotalContractValue)
+	}
+}
+
+func TestValidatePriceComponents(t *testing.T) {
+	tests := []struct {
+		name      string
+		component PriceComponent
+		wantErr   bool
+	}{
+		{"one-time", PriceComponent{ID: "c", ChargeType: OneTimeCharge, Price: 10}, false},
+		{"recurring", PriceComponent{ID: "c", ChargeType: RecurringCharge, Price: 10, Period: MonthlyPeriod}, false},
+		{"usage", PriceComponent{ID: "c", ChargeType: UsageCharge, Price: 0.1, EstimatedUsage: 100}, false},
+		{"missing ID", PriceComponent{ChargeType: OneTimeCharge}, true},
+		{"negative price", PriceComponent{ID: "c", ChargeType: OneTimeCharge, Price: -1}, true},
+		{"recurring without period", PriceComponent{ID: "c", ChargeType: RecurringCharge, Price: 10}, true},
+		{"negative usage", PriceComponent{ID: "c", ChargeType: UsageCharge, EstimatedUsage: -1}, true},
+		{"unknown charge type", PriceComponent{ID: "c", ChargeType: "weekly"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := createTestModelForPricing()
+			model.Options[0].Components = []PriceComponent{tt.component}
+
+			err := ValidatePriceComponents(model)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	model := createTestModelForPricing()
+	model.Options[0].Components = []PriceComponent{
+		{ID: "c", ChargeType: OneTimeCharge},
+		{ID: "c", ChargeType: OneTimeCharge},
+	}
+	if err := ValidatePriceComponents(model); err == nil {
+		t.Error("Expected error for duplicate component")
+	}
+}
@@ -5,6 +5,7 @@
 package cpq
 
 import (
+	"DD/evaluator"
 	"fmt"
 	"sort"
 	"strings"
@@ -25,6 +26,7 @@ type Configurator struct {
 	mutex            sync.RWMutex
 	stats            ConfiguratorStats
 	ruleAnalyzer     *RuleAnalyzer
+	pricingContext   PricingContext // Price book, date and currency for pricing
 }
 
 // ConfiguratorStats tracks overall configurator performance
@@ -50,10 +52,41 @@ type ConfigurationUpdate struct {
 
 // PricingResult represents the result of pricing calculations
 type PricingResult struct {
-	BasePrice   float64           `json:"base_price"`
-	Adjustments []PriceAdjustment `json:"adjustments"`
-	TotalPrice  float64           `json:"total_price"`
-	Breakdown   *PriceBreakdown   `json:"breakdown,omitempty"`
+	BasePrice          float64           `json:"base_price"`
+	Adjustments        []PriceAdjustment `json:"adjustments"`
+	TotalPrice         float64           `json:"total_price"`
+	OneTimeTotal       float64           `json:"one_time_total"`
+	MRR                float64           `json:"mrr"`
+	ARR                float64           `json:"arr"`
+	TotalContractValue float64           `json:"total_contract_value"`
+	Margin             float64           `json:"margin"`
+	MarginPercent      float64           `json:"margin_percent"`
+	RequiresApproval   bool              `json:"requires_approval"`
+	Tax                float64           `json:"tax,omitempty"`         // Tax at the ship-to address
+	GrossTotal         float64           `json:"gross_total,omitempty"` // Contract value with tax
+	Breakdown          *PriceBreakdown   `json:"breakdown,omitempty"`
+}
+
+// NewPricingResult summarizes a price breakdown
+func NewPricingResult(breakdown PriceBreakdown) *PricingResult {
+	result := &PricingResult{
+		BasePrice:          breakdown.BasePrice,
+		Adjustments:        breakdown.Adjustments,
+		TotalPrice:         breakdown.TotalPrice,
+		OneTimeTotal:       breakdown.OneTimeTotal,
+		MRR:                breakdown.MRR,
+		ARR:                breakdown.ARR,
+		TotalContractValue: breakdown.TotalContractValue,
+		Margin:             breakdown.Margin,
+		MarginPercent:      breakdown.MarginPercent,
+		RequiresApproval:   breakdown.RequiresApproval,
+		Breakdown:          &breakdown,
+	}
+	if breakdown.Tax != nil {
+		result.Tax = breakdown.Tax.Tax
+		result.GrossTotal = breakdown.Tax.Gross
+	}
+	return result
 }
 
 // SelectionStatus indicates how an option can be selected
@@ -295,7 +328,7 @@ func (c *Configurator) evaluateConfiguration(config Configuration, elapsed time.
 	c.stats.ValidationCalls++
 
 	// Phase 2: Calculate pricing (separate from constraints)
-	priceBreakdown := c.pricingCalc.CalculatePrice(config.Selections)
+	priceBreakdown := c.pricingCalc.CalculatePrice(config.Selections, c.pricingContext)
 	c.stats.PricingCalls++
 
 	// Update configuration with results
@@ -747,7 +780,28 @@ func (c *Configurator) GetCurrentPrice() float64 {
 func (c *Configurator) GetDetailedPrice() PriceBreakdown {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
-	return c.pricingCalc.CalculatePrice(c.currentConfig.Selections)
+	return c.pricingCalc.CalculatePrice(c.currentConfig.Selections, c.pricingContext)
+}
+
+// SetPricingContext selects the price book, date and currency the
+// configuration is priced in and reprices the current configuration
+func (c *Configurator) SetPricingContext(ctx PricingContext) error {
+	if err := ValidatePricingContext(c.model, ctx); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.pricingContext = ctx
+	c.currentConfig.TotalPrice = c.pricingCalc.CalculatePrice(c.currentConfig.Selections, ctx).TotalPrice
+	return nil
+}
+
+// GetPricingContext returns the context the configuration is priced in
+func (c *Configurator) GetPricingContext() PricingContext {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.pricingContext
 }
 
 // ValidateCurrentConfiguration performs validation on current configuration
@@ -757,6 +811,23 @@ func (c *Configurator) ValidateCurrentConfiguration() ValidationResult {
 	return c.constraintEngine.ValidateSelections(c.currentConfig.Selections)
 }
 
+// ExplainCurrentConflict finds the smallest set of selections and rules
+// that makes the current configuration impossible to complete
+func (c *Configurator) ExplainCurrentConflict() *evaluator.ConflictExplanation {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.constraintEngine.ExplainConflict(c.currentConfig.Selections)
+}
+
+// SuggestRepairs returns the nearest valid configurations to the current
+// one as concrete change sets with their price impact
+func (c *Configurator) SuggestRepairs(options RepairOptions) []Repair {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	options.Pricing = c.pricingContext
+	return NewRepairEngine(c.model, c.constraintEngine, c.pricingCalc).SuggestRepairs(c.currentConfig.Selections, options)
+}
+
 // GetAvailableOptionsForGroup returns available options for a specific group
 func (c *Configurator) GetAvailableOptionsForGroup(groupID string) ([]AvailableOption, error) {
 	c.mutex.RLock()
@@ -932,15 +1003,7 @@ func (c *Configurator) CalculatePricing(selections []Selection) (*PricingResult,
 	defer c.mutex.RUnlock()
 	
 	// Calculate pricing
-	breakdown := c.pricingCalc.CalculatePrice(selections)
-	
-	// Convert to PricingResult
-	result := &PricingResult{
-		BasePrice:   breakdown.BasePrice,
-		Adjustments: breakdown.Adjustments,
-		TotalPrice:  breakdown.TotalPrice,
-		Breakdown:   &breakdown,
-	}
+	breakdown := c.pricingCalc.CalculatePrice(selections, c.pricingContext)
 	
-	return result, nil
+	return NewPricingResult(breakdown), nil
 }
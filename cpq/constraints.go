@@ -5,7 +5,10 @@
 package cpq
 
 import (
+	"DD/evaluator"
+	"DD/i18n"
 	"DD/mtbdd"
+	"DD/parser"
 	"fmt"
 	"sort"
 	"strings"
@@ -21,6 +24,7 @@ import (
 type ConstraintEngine struct {
 	model             *Model
 	mtbdd             *mtbdd.MTBDD
+	context           *mtbdd.StandardCompilerContext // Shares compiled definitions across rules
 	compiledRules     map[string]mtbdd.NodeRef
 	rulesByID         map[string]*Rule
 	variables         map[string]mtbdd.NodeRef
@@ -43,9 +47,11 @@ func NewConstraintEngine(model *Model) (*ConstraintEngine, error) {
 		return nil, fmt.Errorf("model cannot be nil")
 	}
 
+	manager := mtbdd.NewMTBDD()
 	engine := &ConstraintEngine{
 		model:             model,
-		mtbdd:             mtbdd.NewMTBDD(),
+		mtbdd:             manager,
+		context:           mtbdd.NewCompilerContext(manager),
 		compiledRules:     make(map[string]mtbdd.NodeRef),
 		rulesByID:         make(map[string]*Rule),
 		variables:         make(map[string]mtbdd.NodeRef),
@@ -69,14 +75,22 @@ func (ce *ConstraintEngine) compileConstraints() error {
 		return fmt.Errorf("variable declaration failed: %w", err)
 	}
 
-	// Step 2: Compile each rule using direct ParseAndCompile
+	// Step 2: Register named definitions; each compiles once on first reference
+	definitions, err := ce.model.ParseDefinitions()
+	if err != nil {
+		return fmt.Errorf("invalid definitions: %w", err)
+	}
+	if err := ce.context.Define(definitions); err != nil {
+		return fmt.Errorf("invalid definitions: %w", err)
+	}
+
+	// Step 3: Compile each rule against the shared context
 	for _, rule := range ce.model.Rules {
 		if !rule.IsActive {
 			continue
 		}
 
-		// Use direct MTBDD ParseAndCompile - simplified approach
-		compiledRule, _, err := mtbdd.ParseAndCompile(rule.Expression, ce.mtbdd)
+		compiledRule, err := mtbdd.ParseAndCompileWithContext(rule.Expression, ce.context)
 		if err != nil {
 			return fmt.Errorf("failed to compile rule %s: %w", rule.ID, err)
 		}
@@ -87,12 +101,12 @@ func (ce *ConstraintEngine) compileConstraints() error {
 		ce.rulesByID[rule.ID] = &ruleCopy
 	}
 
-	// Step 3: Add group constraints (single/multi select limits)
+	// Step 4: Add group constraints (single/multi select limits)
 	if err := ce.addGroupConstraints(); err != nil {
 		return fmt.Errorf("group constraint generation failed: %w", err)
 	}
 
-	// Step 4: Combine all constraints into a single BDD
+	// Step 5: Combine all constraints into a single BDD
 	ce.combineAllConstraints()
 
 	ce.stats.CompilationTime = time.Since(startTime)
@@ -372,7 +386,7 @@ func (ce *ConstraintEngine) ValidateSelections(selections []Selection) Validatio
 
 		// If rule evaluates to false, it's violated
 		if boolResult, ok := result.(bool); ok && !boolResult {
-			violation := ce.createViolation(ruleID, selections)
+			violation := ce.createViolation(ruleID, selections, assignments)
 			violations = append(violations, violation)
 		}
 	}
@@ -382,14 +396,77 @@ func (ce *ConstraintEngine) ValidateSelections(selections []Selection) Validatio
 	elapsed := time.Since(startTime)
 	ce.stats.AverageTime = time.Duration((int64(ce.stats.AverageTime)*ce.stats.TotalEvaluations + int64(elapsed)) / (ce.stats.TotalEvaluations + 1))
 
+	suggestions := ce.generateSuggestions(violations)
 	return ValidationResult{
 		IsValid:      len(violations) == 0,
 		Violations:   violations,
-		Suggestions:  ce.generateSuggestions(violations),
+		Suggestions:  renderMessages(i18n.English(), suggestions),
 		ResponseTime: elapsed,
+		suggestions:  suggestions,
 	}
 }
 
+// ExplainConflict finds a minimal set of selections and rules that cannot
+// all hold, so that no completion of the selections is valid. Returns an
+// explanation without a conflict when some completion satisfies every rule.
+func (ce *ConstraintEngine) ExplainConflict(selections []Selection) *evaluator.ConflictExplanation {
+	ce.mutex.RLock()
+	defer ce.mutex.RUnlock()
+
+	explainer := evaluator.NewExplainer()
+	var constraints []evaluator.ConflictConstraint
+
+	// Rules first in model order, then generated group constraints
+	for _, rule := range ce.model.Rules {
+		if compiled, exists := ce.compiledRules[rule.ID]; exists {
+			constraints = append(constraints, evaluator.ConflictConstraint{
+				ID:         rule.ID,
+				Kind:       evaluator.ConflictRule,
+				Name:       rule.Name,
+				Expression: rule.Expression,
+				BDD:        compiled,
+			})
+		}
+	}
+	var generated []string
+	for ruleID := range ce.compiledRules {
+		if _, isModelRule := ce.rulesByID[ruleID]; !isModelRule {
+			generated = append(generated, ruleID)
+		}
+	}
+	sort.Strings(generated)
+	for _, ruleID := range generated {
+		constraints = append(constraints, evaluator.ConflictConstraint{
+			ID:    ruleID,
+			Kind:  evaluator.ConflictRule,
+			Name:  ruleID,
+			BDD:   ce.compiledRules[ruleID],
+			Label: ce.generatedRuleName(ruleID),
+		})
+	}
+
+	// Selections last, in the order they were made
+	for _, selection := range selections {
+		variable, declared := ce.variables[selection.OptionID]
+		if selection.Quantity <= 0 || !declared {
+			continue
+		}
+		name := selection.OptionID
+		if option, err := ce.model.GetOption(selection.OptionID); err == nil {
+			name = option.Name
+			explainer.SetVariableDisplayName(option.ID, option.Name)
+		}
+		constraints = append(constraints, evaluator.ConflictConstraint{
+			ID:   selection.OptionID,
+			Kind: evaluator.ConflictSelection,
+			Name: name,
+			BDD:  variable,
+		})
+	}
+
+	return explainer.ExplainConflict(ce.mtbdd, constraints)
+}
+
 // selectionsToAssignments converts user selections to MTBDD variable assignments
 func (ce *ConstraintEngine) selectionsToAssignments(selections []Selection) map[string]bool {
 	assignments := make(map[string]bool)
@@ -431,15 +508,22 @@ func (ce *ConstraintEngine) selectionsToAssignments(selections []Selection) map[
 }
 
 // createViolation creates a rule violation with user-friendly information
-func (ce *ConstraintEngine) createViolation(ruleID string, selections []Selection) RuleViolation {
+func (ce *ConstraintEngine) createViolation(ruleID string, selections []Selection, assignments map[string]bool) RuleViolation {
 	// Find the rule definition
+	english := i18n.English()
 	for _, rule := range ce.model.Rules {
 		if rule.ID == ruleID {
+			metadata, _ := rule.Metadata()
+			reason := ce.explainViolation(rule, assignments)
 			return RuleViolation{
 				RuleID:          ruleID,
 				RuleName:        rule.Name,
 				Message:         rule.Message,
 				AffectedOptions: ce.findAffectedOptions(rule, selections),
+				Severity:        metadata.Severity,
+				Reason:          english.Render(reason),
+				message:         i18n.Literal(rule.Message, metadata.Messages),
+				reason:          reason,
 			}
 		}
 	}
@@ -448,11 +532,15 @@ func (ce *ConstraintEngine) createViolation(ruleID string, selections []Selectio
 	if strings.Contains(ruleID, "group_") && strings.Contains(ruleID, "_constraint") {
 		groupID := ce.extractGroupIDFromRuleID(ruleID)
 		if group, err := ce.model.GetGroup(groupID); err == nil {
+			name := i18n.NewMessage("group.rule_name", i18n.Args{"group": group.Name})
+			message := ce.generateGroupViolationMessage(*group)
 			return RuleViolation{
 				RuleID:          ruleID,
-				RuleName:        fmt.Sprintf("%s Selection Rule", group.Name),
-				Message:         ce.generateGroupViolationMessage(*group),
+				RuleName:        english.Render(name),
+				Message:         english.Render(message),
 				AffectedOptions: ce.getGroupOptionIDs(groupID),
+				name:            name,
+				message:         message,
 			}
 		} else {
 			fmt.Printf("WARNING: Could not find group %s for constraint %s\n", groupID, ruleID)
@@ -463,9 +551,11 @@ func (ce *ConstraintEngine) createViolation(ruleID string, selections []Selectio
 	fmt.Printf("WARNING: Unknown rule violation: %s\n", ruleID)
 	return RuleViolation{
 		RuleID:          ruleID,
-		RuleName:        "Unknown Rule",
-		Message:         "A constraint was violated",
+		RuleName:        english.T("violation.unknown_rule", nil),
+		Message:         english.T("violation.unknown", nil),
 		AffectedOptions: []string{},
+		name:            i18n.NewMessage("violation.unknown_rule", nil),
+		message:         i18n.NewMessage("violation.unknown", nil),
 	}
 }
 
@@ -481,6 +571,93 @@ func (ce *ConstraintEngine) findAffectedOptions(rule Rule, selections []Selectio
 	return affected
 }
 
+// explainViolation traces the rule under the assignments and names the
+// options that decided it, e.g. "violated because cpu_i9 is selected and
+// cooling_liquid is not selected". Returns an empty message when the rule
+// cannot be traced.
+func (ce *ConstraintEngine) explainViolation(rule Rule, assignments map[string]bool) i18n.Message {
+	context := make(evaluator.Context, len(assignments))
+	for name, value := range assignments {
+		context[name] = value
+	}
+
+	// Definitions evaluate in dependency order so later ones can use earlier ones
+	definitions, err := ce.model.ParseDefinitions()
+	if err != nil {
+		return i18n.Message{}
+	}
+	order, err := parser.DefinitionOrder(definitions)
+	if err != nil {
+		return i18n.Message{}
+	}
+	for _, name := range order {
+		value, err := evaluator.Evaluate(definitions[name], context)
+		if err != nil {
+			return i18n.Message{}
+		}
+		context[name] = value
+	}
+
+	trace, err := evaluator.EvaluateExpressionWithTrace(rule.Expression, context)
+	if err != nil {
+		return i18n.Message{}
+	}
+
+	// A definition is explained by the options that decided its own value
+	var reasons []evaluator.TraceReason
+	seen := make(map[string]bool)
+	var expand func([]evaluator.TraceReason) bool
+	expand = func(traced []evaluator.TraceReason) bool {
+		for _, reason := range traced {
+			if body, ok := definitions[reason.Variable]; ok {
+				inner, err := evaluator.EvaluateWithTrace(body, context)
+				if err != nil || !expand(inner.Reasons) {
+					return false
+				}
+			} else if !seen[reason.Variable] {
+				seen[reason.Variable] = true
+				reasons = append(reasons, reason)
+			}
+		}
+		return true
+	}
+	if !expand(trace.Reasons) || len(reasons) == 0 {
+		return i18n.Message{}
+	}
+
+	parts := make([]i18n.Message, 0, len(reasons))
+	for _, reason := range reasons {
+		_, err := ce.model.GetOption(reason.Variable)
+		isOption := err == nil
+		selected, isBool := reason.Value.(bool)
+		switch {
+		case isOption && isBool && selected:
+			parts = append(parts, i18n.NewMessage("reason.selected", i18n.Args{"option": reason.Variable}))
+		case isOption && isBool:
+			parts = append(parts, i18n.NewMessage("reason.not_selected", i18n.Args{"option": reason.Variable}))
+		default:
+			parts = append(parts, i18n.NewMessage("reason.value", i18n.Args{
+				"variable": reason.Variable,
+				"value":    fmt.Sprint(reason.Value),
+			}))
+		}
+	}
+	return i18n.NewMessage("reason.because", i18n.Args{"reasons": parts})
+}
+
+// generatedRuleName names a generated group constraint after its group,
+// e.g. "Processor Selection Rule"
+func (ce *ConstraintEngine) generatedRuleName(ruleID string) i18n.Message {
+	groupID := ce.extractGroupIDFromRuleID(ruleID)
+	if groupID == "" {
+		groupID = strings.TrimSuffix(strings.TrimPrefix(ruleID, "group_"), "_min")
+	}
+	if group, err := ce.model.GetGroup(groupID); err == nil {
+		return i18n.NewMessage("group.rule_name", i18n.Args{"group": group.Name})
+	}
+	return i18n.Literal(ruleID, nil)
+}
+
 func (ce *ConstraintEngine) extractGroupIDFromRuleID(ruleID string) string {
 	// Extract group ID from rule ID like "group_grp_processor_constraint_0"
 	// We need to handle group IDs that contain underscores
@@ -496,20 +673,22 @@ func (ce *ConstraintEngine) extractGroupIDFromRuleID(ruleID string) string {
 	return ""
 }
 
-func (ce *ConstraintEngine) generateGroupViolationMessage(group Group) string {
+func (ce *ConstraintEngine) generateGroupViolationMessage(group Group) i18n.Message {
+	args := i18n.Args{"group": group.Name}
 	switch group.Type {
 	case SingleSelect:
 		if group.IsRequired {
-			return fmt.Sprintf("Please select exactly one option from %s", group.Name)
+			return i18n.NewMessage("group.exactly_one", args)
 		}
-		return fmt.Sprintf("Please select at most one option from %s", group.Name)
+		return i18n.NewMessage("group.at_most_one", args)
 	case MultiSelect:
 		if group.MinSelections > 0 {
-			return fmt.Sprintf("Please select at least %d options from %s", group.MinSelections, group.Name)
+			args["count"] = group.MinSelections
+			return i18n.NewMessage("group.at_least", args)
 		}
-		return fmt.Sprintf("Selection limit exceeded for %s", group.Name)
+		return i18n.NewMessage("group.limit_exceeded", args)
 	default:
-		return fmt.Sprintf("Selection constraint violated for %s", group.Name)
+		return i18n.NewMessage("group.violated", args)
 	}
 }
 
@@ -523,35 +702,45 @@ func (ce *ConstraintEngine) getGroupOptionIDs(groupID string) []string {
 	return optionIDs
 }
 
-func (ce *ConstraintEngine) generateSuggestions(violations []RuleViolation) []string {
+// generateSuggestions matches the English violations, which are rendered
+// before any localization
+func (ce *ConstraintEngine) generateSuggestions(violations []RuleViolation) []i18n.Message {
 	var suggestions []string
 
 	for _, violation := range violations {
 		switch {
 		case strings.Contains(violation.Message, "exactly one"):
-			suggestions = append(suggestions, "Choose only one option from this group")
+			suggestions = append(suggestions, "suggestion.choose_one")
 		case strings.Contains(violation.Message, "at least"):
-			suggestions = append(suggestions, "Select more options to meet minimum requirements")
+			suggestions = append(suggestions, "suggestion.select_more")
 		case strings.Contains(violation.RuleName, "requires"):
-			suggestions = append(suggestions, "Add required options to your selection")
+			suggestions = append(suggestions, "suggestion.add_required")
 		case strings.Contains(violation.RuleName, "excludes"):
-			suggestions = append(suggestions, "Remove conflicting options from your selection")
+			suggestions = append(suggestions, "suggestion.remove_conflicting")
 		}
 	}
 
 	// Remove duplicates
 	uniqueSuggestions := make(map[string]bool)
-	var result []string
+	var result []i18n.Message
 	for _, suggestion := range suggestions {
 		if !uniqueSuggestions[suggestion] {
 			uniqueSuggestions[suggestion] = true
-			result = append(result, suggestion)
+			result = append(result, i18n.NewMessage(suggestion, nil))
 		}
 	}
 
 	return result
 }
 
+func renderMessages(localizer *i18n.Localizer, messages []i18n.Message) []string {
+	var rendered []string
+	for _, message := range messages {
+		rendered = append(rendered, localizer.Render(message))
+	}
+	return rendered
+}
+
 // ===================================================================
 // ENGINE INFORMATION AND STATS
 // ===================================================================
@@ -1,6 +1,7 @@
 package cpq
 
 import (
+	"DD/i18n"
 	"fmt"
 	"strings"
 	"testing"
@@ -164,6 +165,209 @@ func TestConstraintEngine_ValidateSelections_CustomRuleViolation(t *testing.T) {
 	}
 }
 
+func TestConstraintEngine_RuleReferencingDefinition(t *testing.T) {
+	model := createTestModelWithCustomRule()
+	model.Rules = nil
+	model.AddDefinition(Definition{ID: "premium", Name: "Premium", Expression: "opt1 OR opt2"})
+	model.AddRule(Rule{
+		ID:         "premium_rule",
+		Name:       "Premium Dependency",
+		Type:       RequiresRule,
+		Expression: "premium -> opt3",
+		Message:    "Premium options require Option 3",
+		IsActive:   true,
+	})
+
+	engine, err := NewConstraintEngine(model)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	// Definition names are not declared as option variables
+	for _, variable := range engine.GetDeclaredVariables() {
+		if variable == "premium" {
+			t.Error("Definition should not be declared as a variable")
+		}
+	}
+
+	tests := []struct {
+		assignments map[string]bool
+		expected    bool
+	}{
+		{map[string]bool{"opt1": true}, false},
+		{map[string]bool{"opt2": true}, false},
+		{map[string]bool{"opt2": true, "opt3": true}, true},
+		{map[string]bool{"opt3": true}, true},
+	}
+	for _, tt := range tests {
+		result, err := engine.EvaluateRule("premium_rule", tt.assignments)
+		if err != nil {
+			t.Fatalf("EvaluateRule failed: %v", err)
+		}
+		if result != tt.expected {
+			t.Errorf("EvaluateRule(%v) = %v, expected %v", tt.assignments, result, tt.expected)
+		}
+	}
+
+	// Cyclic definitions fail engine construction
+	model.AddDefinition(Definition{ID: "loop", Expression: "NOT loop"})
+	if _, err := NewConstraintEngine(model); err == nil {
+		t.Error("Expected error for cyclic definition")
+	}
+}
+
+func TestConstraintEngine_ViolationReason(t *testing.T) {
+	model := createTestModel()
+	model.AddGroup(Group{ID: "cooling", Name: "Cooling", Type: MultiSelect, MaxSelections: 2})
+	model.AddOption(Option{ID: "cpu_i9", Name: "Core i9", GroupID: "cooling", IsActive: true})
+	model.AddOption(Option{ID: "cooling_liquid", Name: "Liquid Cooling", GroupID: "cooling", IsActive: true})
+	model.AddDefinition(Definition{ID: "high_end", Name: "High End", Expression: "cpu_i9 AND NOT opt2"})
+	model.AddRule(Rule{
+		ID:         "cooling_rule",
+		Name:       "Cooling Requirement",
+		Type:       RequiresRule,
+		Expression: "high_end -> cooling_liquid",
+		Message:    "High-end CPUs need liquid cooling",
+		IsActive:   true,
+	})
+
+	engine, err := NewConstraintEngine(model)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	result := engine.ValidateSelections([]Selection{
+		{OptionID: "opt1", Quantity: 1},
+		{OptionID: "cpu_i9", Quantity: 1},
+	})
+	var violation *RuleViolation
+	for i := range result.Violations {
+		if result.Violations[i].RuleID == "cooling_rule" {
+			violation = &result.Violations[i]
+		}
+	}
+	if violation == nil {
+		t.Fatalf("Expected cooling_rule violation, got %v", result.Violations)
+	}
+
+	// The definition is explained by the options that decided it
+	expected := "violated because cpu_i9 is selected, opt2 is not selected and cooling_liquid is not selected"
+	if violation.Reason != expected {
+		t.Errorf("Expected reason %q, got %q", expected, violation.Reason)
+	}
+}
+
+func TestConstraintEngine_ExplainConflict(t *testing.T) {
+	model := createTestModel()
+	model.AddGroup(Group{ID: "extras", Name: "Extras", Type: MultiSelect, MaxSelections: 2})
+	model.AddOption(Option{ID: "dock", Name: "Docking Station", GroupID: "extras", IsActive: true})
+	model.AddOption(Option{ID: "travel", Name: "Travel Kit", GroupID: "extras", IsActive: true})
+	model.AddRule(Rule{ID: "needs_dock", Name: "Option 1 needs a dock", Type: RequiresRule,
+		Expression: "opt1 -> dock", IsActive: true})
+	model.AddRule(Rule{ID: "no_dock", Name: "Travel kit excludes dock", Type: ExcludesRule,
+		Expression: "travel -> NOT dock", IsActive: true})
+
+	engine, err := NewConstraintEngine(model)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	// Neither selection violates a rule yet, but dock can be neither
+	explanation := engine.ExplainConflict([]Selection{
+		{OptionID: "travel", Quantity: 1},
+		{OptionID: "opt1", Quantity: 1},
+	})
+	if !explanation.HasConflict {
+		t.Fatalf("Expected a conflict")
+	}
+	expected := "Selecting Travel Kit, Option 1 together conflicts with Option 1 needs a dock, Travel kit excludes dock"
+	if explanation.Message != expected {
+		t.Errorf("Expected %q, got %q", expected, explanation.Message)
+	}
+
+	// Two options of a single-select group conflict through the group rule
+	explanation = engine.ExplainConflict([]Selection{
+		{OptionID: "opt1", Quantity: 1},
+		{OptionID: "opt2", Quantity: 1},
+	})
+	if len(explanation.Rules) != 1 || explanation.Rules[0].Name != "Group 1 Selection Rule" || len(explanation.Selections) != 2 {
+		t.Errorf("Expected the group rule and both selections, got %+v", explanation)
+	}
+
+	if explanation := engine.ExplainConflict([]Selection{{OptionID: "opt1", Quantity: 1}}); explanation.HasConflict {
+		t.Errorf("Expected no conflict, got %+v", explanation)
+	}
+}
+
+func TestConstraintEngine_LocalizedMessages(t *testing.T) {
+	model := createTestModel()
+	model.AddRule(Rule{
+		ID:         "no_opt2",
+		Name:       "Option 2 retired",
+		Type:       ValidationRule,
+		Expression: `@message(de="Option 2 ist nicht mehr lieferbar") NOT opt2`,
+		Message:    "Option 2 is no longer available",
+		IsActive:   true,
+	})
+
+	engine, err := NewConstraintEngine(model)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	result := engine.ValidateSelections([]Selection{
+		{OptionID: "opt1", Quantity: 1},
+		{OptionID: "opt2", Quantity: 1},
+	})
+	german := result.Localized(i18n.Default.Localizer("de-DE"))
+
+	messages := make(map[string]string)
+	for _, violation := range german.Violations {
+		ruleID := violation.RuleID
+		if strings.HasPrefix(ruleID, "group_group1") {
+			ruleID = "group1"
+		}
+		messages[ruleID] = violation.Message + " / " + violation.Reason
+	}
+	if got := messages["no_opt2"]; got != "Option 2 ist nicht mehr lieferbar / verletzt, weil opt2 ausgewählt ist" {
+		t.Errorf("Unexpected German rule violation %q", got)
+	}
+	if got := messages["group1"]; got != "Bitte wählen Sie genau eine Option aus Group 1 / " {
+		t.Errorf("Unexpected German group violation %q", got)
+	}
+	if len(german.Suggestions) != 1 || german.Suggestions[0] != "Wählen Sie nur eine Option aus dieser Gruppe" {
+		t.Errorf("Unexpected German suggestions %v", german.Suggestions)
+	}
+
+	// Localizing returns a copy; the English result is unchanged
+	for _, violation := range result.Violations {
+		if violation.RuleID == "no_opt2" && violation.Message != "Option 2 is no longer available" {
+			t.Errorf("Expected English message to be kept, got %q", violation.Message)
+		}
+	}
+
+	// A language without a translation falls back to the authored message
+	for _, violation := range result.Localized(i18n.Default.Localizer("fr")).Violations {
+		if violation.RuleID == "no_opt2" && violation.Message != "Option 2 is no longer available" {
+			t.Errorf("Expected authored message, got %q", violation.Message)
+		}
+	}
+
+	// Generated rule names in conflicts are localized too
+	engine, err = NewConstraintEngine(createTestModel())
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	explanation := engine.ExplainConflict([]Selection{
+		{OptionID: "opt1", Quantity: 1},
+		{OptionID: "opt2", Quantity: 1},
+	})
+	explanation.Localize(i18n.Default.Localizer("es"))
+	if len(explanation.Rules) != 1 || explanation.Rules[0].Name != "Regla de selección de Group 1" {
+		t.Errorf("Expected the Spanish group rule name, got %+v", explanation.Rules)
+	}
+}
+
 func TestConstraintEngine_IsValidConfiguration(t *testing.T) {
 	model := createTestModel()
 	engine, err := NewConstraintEngine(model)
@@ -0,0 +1,266 @@
+// contract.go - Customer accounts and contract pricing
+// Negotiated terms applied as a separate layer on top of list pricing
+
+package cpq
+
+import (
+	"DD/tax"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Customer is a customer account that can hold pricing contracts
+type Customer struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TaxExemptions []tax.Exemption `json:"tax_exemptions,omitempty"`
+}
+
+// Contract holds a customer's negotiated pricing terms. Terms apply after
+// list pricing, so model price rules are computed on list prices and the
+// contract adjusts their result:
+//
+//  1. Negotiated option prices replace the net price of their lines
+//  2. The blanket discount applies to the total after negotiated prices
+//  3. The total contract discount is capped at MaxDiscountPercent of list
+//  4. The net total never falls below MinTotal
+type Contract struct {
+	ID                 string          `json:"id"`
+	CustomerID         string          `json:"customer_id"`
+	Name               string          `json:"name"`
+	ModelID            string          `json:"model_id,omitempty"` // Empty applies to every model
+	Currency           string          `json:"currency,omitempty"` // Empty applies in any currency
+	Prices             []ContractPrice `json:"prices,omitempty"`
+	DiscountPercent    float64         `json:"discount_percent"`
+	MaxDiscountPercent float64         `json:"max_discount_percent,omitempty"` // 0 for no cap
+	MinTotal           float64         `json:"min_total,omitempty"`            // 0 for no floor
+	EffectiveFrom      time.Time       `json:"effective_from"`
+	ExpiresAt          *time.Time      `json:"expires_at,omitempty"` // Exclusive; nil never expires
+	CreatedAt          time.Time       `json:"created_at"`
+	UpdatedAt          time.Time       `json:"updated_at"`
+}
+
+// ContractPrice is a negotiated unit price for an option
+type ContractPrice struct {
+	OptionID string  `json:"option_id"`
+	Price    float64 `json:"price"`
+}
+
+// ContractPricing is the contract layer of a price breakdown
+type ContractPricing struct {
+	ContractID  string            `json:"contract_id"`
+	CustomerID  string            `json:"customer_id"`
+	ListPrice   float64           `json:"list_price"` // Total before contract terms
+	Adjustments []PriceAdjustment `json:"adjustments"`
+	NetPrice    float64           `json:"net_price"`
+}
+
+// ActiveOn reports whether the contract is in effect on a date
+func (c *Contract) ActiveOn(date time.Time) bool {
+	if date.Before(c.EffectiveFrom) {
+		return false
+	}
+	return c.ExpiresAt == nil || date.Before(*c.ExpiresAt)
+}
+
+// AppliesTo reports whether the contract covers a model
+func (c *Contract) AppliesTo(modelID string) bool {
+	return c.ModelID == "" || c.ModelID == modelID
+}
+
+// price returns the negotiated unit price of an option
+func (c *Contract) price(optionID string) (float64, bool) {
+	for _, p := range c.Prices {
+		if p.OptionID == optionID {
+			return p.Price, true
+		}
+	}
+	return 0, false
+}
+
+// Errors for prices requested for a customer that cannot be priced
+var (
+	ErrCustomerNotFound = errors.New("customer not found")
+	ErrCustomerInactive = errors.New("customer is inactive")
+)
+
+// PricingCustomer loads the customer a price is calculated for with lookup.
+// Only active customers can be priced.
+func PricingCustomer(lookup func(customerID string) (*Customer, error), customerID string) (*Customer, error) {
+	customer, err := lookup(customerID)
+	if err != nil || customer == nil {
+		return nil, fmt.Errorf("%w: %s", ErrCustomerNotFound, customerID)
+	}
+	if !customer.IsActive {
+		return nil, fmt.Errorf("%w: %s", ErrCustomerInactive, customerID)
+	}
+	return customer, nil
+}
+
+// ActiveContract picks the contract that prices a model on a date: one
+// naming the model wins over a general one, then the latest to take effect
+func ActiveContract(contracts []*Contract, modelID string, date time.Time) *Contract {
+	var found *Contract
+	for _, contract := range contracts {
+		if !contract.AppliesTo(modelID) || !contract.ActiveOn(date) {
+			continue
+		}
+		if found == nil {
+			found = contract
+			continue
+		}
+		specific, foundSpecific := contract.ModelID != "", found.ModelID != ""
+		if specific != foundSpecific {
+			if specific {
+				found = contract
+			}
+			continue
+		}
+		if contract.EffectiveFrom.After(found.EffectiveFrom) {
+			found = contract
+		}
+	}
+	return found
+}
+
+// ApplyContract applies contract terms to a list price breakdown. The list
+// adjustments are kept; contract adjustments are recorded in
+// breakdown.Contract and the total becomes the contract net price.
+func ApplyContract(breakdown PriceBreakdown, contract *Contract) (PriceBreakdown, error) {
+	if contract.Currency != "" && breakdown.Currency != "" &&
+		normalizeCurrency(contract.Currency) != normalizeCurrency(breakdown.Currency) {
+		return breakdown, fmt.Errorf("contract %s is in %s, price is in %s",
+			contract.ID, normalizeCurrency(contract.Currency), breakdown.Currency)
+	}
+
+	currency := breakdown.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	layer := &ContractPricing{
+		ContractID:  contract.ID,
+		CustomerID:  contract.CustomerID,
+		ListPrice:   breakdown.TotalPrice,
+		Adjustments: []PriceAdjustment{},
+	}
+	total := breakdown.TotalPrice
+
+	// Negotiated prices replace the line's net price, volume discounts included
+	for _, line := range breakdown.Lines {
+		price, ok := contract.price(line.OptionID)
+		if !ok {
+			continue
+		}
+		amount := price*float64(line.Quantity) - line.NetPrice
+		if amount == 0 {
+			continue
+		}
+		layer.Adjustments = append(layer.Adjustments, PriceAdjustment{
+			RuleID:      contract.ID,
+			RuleName:    contract.Name,
+			Type:        "contract_price",
+			Amount:      amount,
+			Description: fmt.Sprintf("Contract price for %s: %s each", line.OptionID, formatMoney(price, currency)),
+		})
+		total += amount
+	}
+
+	if contract.DiscountPercent > 0 && total > 0 {
+		amount := -total * contract.DiscountPercent / 100
+		layer.Adjustments = append(layer.Adjustments, PriceAdjustment{
+			RuleID:      contract.ID,
+			RuleName:    contract.Name,
+			Type:        "discount",
+			Amount:      amount,
+			Description: fmt.Sprintf("Contract discount: %.1f%%", contract.DiscountPercent),
+		})
+		total += amount
+	}
+
+	if contract.MaxDiscountPercent > 0 {
+		maxDiscount := layer.ListPrice * contract.MaxDiscountPercent / 100
+		if discount := layer.ListPrice - total; discount > maxDiscount {
+			layer.Adjustments = append(layer.Adjustments, PriceAdjustment{
+				RuleID:      contract.ID,
+				RuleName:    contract.Name,
+				Type:        "cap",
+				Amount:      discount - maxDiscount,
+				Description: fmt.Sprintf("Contract discount capped at %.1f%%", contract.MaxDiscountPercent),
+			})
+			total = layer.ListPrice - maxDiscount
+		}
+	}
+
+	if contract.MinTotal > 0 && total < contract.MinTotal {
+		layer.Adjustments = append(layer.Adjustments, PriceAdjustment{
+			RuleID:      contract.ID,
+			RuleName:    contract.Name,
+			Type:        "floor",
+			Amount:      contract.MinTotal - total,
+			Description: fmt.Sprintf("Contract minimum of %s", formatMoney(contract.MinTotal, currency)),
+		})
+		total = contract.MinTotal
+	}
+
+	layer.NetPrice = total
+	breakdown.Contract = layer
+	breakdown.TotalPrice = total
+	breakdown.updateTotals()
+	return breakdown, nil
+}
+
+// ValidateContract validates contract terms. Negotiated prices are checked
+// against the model when the contract names one and it is given.
+func ValidateContract(contract *Contract, model *Model) error {
+	if contract.ID == "" {
+		return fmt.Errorf("contract ID cannot be empty")
+	}
+	if contract.CustomerID == "" {
+		return fmt.Errorf("contract %s must belong to a customer", contract.ID)
+	}
+	if contract.Currency != "" && len(normalizeCurrency(contract.Currency)) != 3 {
+		return fmt.Errorf("contract %s: currency must be a three-letter code", contract.ID)
+	}
+	// Percentage terms hold in any currency, absolute amounts only in their own
+	if contract.Currency == "" && (len(contract.Prices) > 0 || contract.MinTotal > 0) {
+		return fmt.Errorf("contract %s: a currency is required for negotiated prices and minimum totals", contract.ID)
+	}
+	if contract.DiscountPercent < 0 || contract.DiscountPercent > 100 {
+		return fmt.Errorf("contract %s: discount must be between 0 and 100 percent", contract.ID)
+	}
+	if contract.MaxDiscountPercent < 0 || contract.MaxDiscountPercent > 100 {
+		return fmt.Errorf("contract %s: discount cap must be between 0 and 100 percent", contract.ID)
+	}
+	if contract.MinTotal < 0 {
+		return fmt.Errorf("contract %s: minimum total cannot be negative", contract.ID)
+	}
+	if contract.ExpiresAt != nil && !contract.ExpiresAt.After(contract.EffectiveFrom) {
+		return fmt.Errorf("contract %s expires before it takes effect", contract.ID)
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range contract.Prices {
+		if seen[p.OptionID] {
+			return fmt.Errorf("contract %s prices %s twice", contract.ID, p.OptionID)
+		}
+		seen[p.OptionID] = true
+
+		if p.Price < 0 {
+			return fmt.Errorf("contract %s: price of %s cannot be negative", contract.ID, p.OptionID)
+		}
+		if model != nil && contract.ModelID == model.ID {
+			if _, err := model.GetOption(p.OptionID); err != nil {
+				return fmt.Errorf("contract %s references invalid option %s", contract.ID, p.OptionID)
+			}
+		}
+	}
+
+	return nil
+}
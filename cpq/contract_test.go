@@ -0,0 +1,225 @@
+package cpq
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestApplyContract_Precedence(t *testing.T) {
+	model := createTestModelWithPriceRules()
+	calc := NewPricingCalculator(model)
+
+	selections := []Selection{
+		{OptionID: "opt1", Quantity: 1}, // $100, $20 fixed discount rule
+		{OptionID: "opt2", Quantity: 1}, // $50, 15% discount rule on the base price
+	}
+	list := calc.CalculatePrice(selections, PricingContext{})
+	if math.Abs(list.TotalPrice-107.5) > 0.01 {
+		t.Fatalf("Expected list total 107.50, got %.2f", list.TotalPrice)
+	}
+
+	tests := []struct {
+		name     string
+		contract Contract
+		total    float64
+		types    []string
+	}{
+		{
+			name:     "negotiated price replaces line, rules still apply",
+			contract: Contract{Prices: []ContractPrice{{OptionID: "opt1", Price: 60}}},
+			total:    67.5, // 107.50 - (100 - 60)
+			types:    []string{"contract_price"},
+		},
+		{
+			name:     "blanket discount applies after model rules",
+			contract: Contract{DiscountPercent: 10},
+			total:    96.75, // 107.50 * 0.9
+			types:    []string{"discount"},
+		},
+		{
+			name:     "blanket discount applies after negotiated prices",
+			contract: Contract{Prices: []ContractPrice{{OptionID: "opt1", Price: 60}}, DiscountPercent: 10},
+			total:    60.75, // 67.50 * 0.9
+			types:    []string{"contract_price", "discount"},
+		},
+		{
+			name:     "discount cap",
+			contract: Contract{Prices: []ContractPrice{{OptionID: "opt1", Price: 60}}, DiscountPercent: 10, MaxDiscountPercent: 30},
+			total:    75.25, // 107.50 * 0.7
+			types:    []string{"contract_price", "discount", "cap"},
+		},
+		{
+			name:     "floor after cap",
+			contract: Contract{Prices: []ContractPrice{{OptionID: "opt1", Price: 60}}, DiscountPercent: 10, MaxDiscountPercent: 30, MinTotal: 90},
+			total:    90,
+			types:    []string{"contract_price", "discount", "cap", "floor"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.contract.ID = "c1"
+			tt.contract.CustomerID = "acme"
+
+			priced, err := ApplyContract(list, &tt.contract)
+			if err != nil {
+				t.Fatalf("ApplyContract failed: %v", err)
+			}
+
+			if math.Abs(priced.TotalPrice-tt.total) > 0.01 {
+				t.Errorf("Expected total %.2f, got %.2f", tt.total, priced.TotalPrice)
+			}
+			if priced.Contract == nil {
+				t.Fatal("Expected contract layer in breakdown")
+			}
+			if priced.Contract.ListPrice != list.TotalPrice || priced.Contract.NetPrice != priced.TotalPrice {
+				t.Errorf("Contract layer should run from list %.2f to net %.2f, got %.2f to %.2f",
+					list.TotalPrice, priced.TotalPrice, priced.Contract.ListPrice, priced.Contract.NetPrice)
+			}
+			if len(priced.Adjustments) != len(list.Adjustments) {
+				t.Errorf("List adjustments should be kept apart, got %d want %d", len(priced.Adjustments), len(list.Adjustments))
+			}
+
+			if len(priced.Contract.Adjustments) != len(tt.types) {
+				t.Fatalf("Expected adjustments %v, got %v", tt.types, priced.Contract.Adjustments)
+			}
+			for i, adj := range priced.Contract.Adjustments {
+				if adj.Type != tt.types[i] {
+					t.Errorf("Adjustment %d: expected %s, got %s", i, tt.types[i], adj.Type)
+				}
+				if adj.RuleID != "c1" {
+					t.Errorf("Adjustment %d should be attributed to the contract, got %q", i, adj.RuleID)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyContract_CurrencyMismatch(t *testing.T) {
+	breakdown := PriceBreakdown{TotalPrice: 100, Currency: "USD"}
+
+	if _, err := ApplyContract(breakdown, &Contract{ID: "c1", Currency: "EUR"}); err == nil {
+		t.Error("Expected error for contract in another currency")
+	}
+	if _, err := ApplyContract(breakdown, &Contract{ID: "c1", Currency: "usd"}); err != nil {
+		t.Errorf("Contract in the same currency should apply: %v", err)
+	}
+}
+
+func TestPricingCustomer(t *testing.T) {
+	customers := map[string]*Customer{
+		"acme":           {ID: "acme", Name: "Acme", IsActive: true},
+		"closed_account": {ID: "closed_account", Name: "Closed Account", IsActive: false},
+	}
+	lookup := func(customerID string) (*Customer, error) {
+		if customer, ok := customers[customerID]; ok {
+			return customer, nil
+		}
+		return nil, fmt.Errorf("customer %s not found", customerID)
+	}
+
+	tests := []struct {
+		name       string
+		customerID string
+		wantErr    error
+	}{
+		{"active customer", "acme", nil},
+		{"unknown customer", "no_such_customer", ErrCustomerNotFound},
+		{"inactive customer", "closed_account", ErrCustomerInactive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customer, err := PricingCustomer(lookup, tt.customerID)
+			if tt.wantErr == nil {
+				if err != nil || customer == nil || customer.ID != tt.customerID {
+					t.Errorf("Expected customer %s, got %v, %v", tt.customerID, customer, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if customer != nil {
+				t.Errorf("Expected no customer, got %s", customer.ID)
+			}
+		})
+	}
+}
+
+func TestActiveContract(t *testing.T) {
+	expired := date(2024, time.January, 1)
+	contracts := []*Contract{
+		{ID: "old", EffectiveFrom: date(2022, time.January, 1), ExpiresAt: &expired},
+		{ID: "general", EffectiveFrom: date(2023, time.January, 1)},
+		{ID: "newer", EffectiveFrom: date(2024, time.March, 1)},
+		{ID: "laptop", ModelID: "laptop", EffectiveFrom: date(2023, time.June, 1)},
+		{ID: "future", ModelID: "laptop", EffectiveFrom: date(2030, time.January, 1)},
+	}
+
+	tests := []struct {
+		name    string
+		modelID string
+		date    time.Time
+		want    string
+	}{
+		{"expired contract is skipped", "server", date(2023, time.March, 1), "general"},
+		{"before any contract", "server", date(2021, time.March, 1), ""},
+		{"latest general contract", "server", date(2024, time.June, 1), "newer"},
+		{"model contract wins", "laptop", date(2024, time.June, 1), "laptop"},
+		{"contract not yet in effect", "laptop", date(2023, time.March, 1), "general"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ActiveContract(contracts, tt.modelID, tt.date)
+			id := ""
+			if got != nil {
+				id = got.ID
+			}
+			if id != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, id)
+			}
+		})
+	}
+}
+
+func TestValidateContract(t *testing.T) {
+	model := createTestModelForPricing()
+	start := date(2024, time.January, 1)
+	before := date(2023, time.January, 1)
+
+	tests := []struct {
+		name     string
+		contract Contract
+		wantErr  bool
+	}{
+		{"valid", Contract{ID: "c1", CustomerID: "acme", ModelID: model.ID, Currency: "usd", DiscountPercent: 10,
+			Prices: []ContractPrice{{OptionID: "opt1", Price: 80}}, EffectiveFrom: start}, false},
+		{"missing ID", Contract{CustomerID: "acme"}, true},
+		{"missing customer", Contract{ID: "c1"}, true},
+		{"bad currency", Contract{ID: "c1", CustomerID: "acme", Currency: "dollars"}, true},
+		{"discount over 100", Contract{ID: "c1", CustomerID: "acme", DiscountPercent: 120}, true},
+		{"negative cap", Contract{ID: "c1", CustomerID: "acme", MaxDiscountPercent: -5}, true},
+		{"negative floor", Contract{ID: "c1", CustomerID: "acme", MinTotal: -1}, true},
+		{"expires before start", Contract{ID: "c1", CustomerID: "acme", EffectiveFrom: start, ExpiresAt: &before}, true},
+		{"negative price", Contract{ID: "c1", CustomerID: "acme", Currency: "USD", Prices: []ContractPrice{{OptionID: "opt1", Price: -1}}}, true},
+		{"duplicate price", Contract{ID: "c1", CustomerID: "acme", Currency: "USD", Prices: []ContractPrice{{OptionID: "opt1"}, {OptionID: "opt1"}}}, true},
+		{"unknown option", Contract{ID: "c1", CustomerID: "acme", Currency: "USD", ModelID: model.ID, Prices: []ContractPrice{{OptionID: "nope"}}}, true},
+		{"prices without currency", Contract{ID: "c1", CustomerID: "acme", Prices: []ContractPrice{{OptionID: "opt1", Price: 80}}}, true},
+		{"minimum total without currency", Contract{ID: "c1", CustomerID: "acme", MinTotal: 100}, true},
+		{"discount without currency", Contract{ID: "c1", CustomerID: "acme", DiscountPercent: 10}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateContract(&tt.contract, model)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
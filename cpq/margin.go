@@ -0,0 +1,194 @@
+// margin.go - Cost, margin and margin-floor guardrails
+// Keeps discounting from silently taking a configuration below cost
+
+package cpq
+
+import (
+	"fmt"
+	"math"
+)
+
+// MarginFloorAction is what happens when a price breaches a margin floor
+type MarginFloorAction string
+
+const (
+	BlockBelowFloor   MarginFloorAction = "block"    // Requested discounts breaching the floor are rejected
+	ApproveBelowFloor MarginFloorAction = "approval" // Breaches are flagged for approval
+)
+
+// MarginFloor is a minimum margin for a model or one of its groups. Margin
+// is measured over the contract term: one-time and term charges less cost.
+type MarginFloor struct {
+	ID               string            `json:"id"`
+	Name             string            `json:"name"`
+	GroupID          string            `json:"group_id,omitempty"` // Empty applies to the whole configuration
+	MinMarginPercent float64           `json:"min_margin_percent"`
+	Action           MarginFloorAction `json:"action"`
+	ApproverRole     string            `json:"approver_role,omitempty"` // Approval floors; empty uses MarginFloorApproverRole
+}
+
+// approverRole returns the role that approves prices below the floor
+func (f MarginFloor) approverRole() string {
+	if f.ApproverRole == "" {
+		return MarginFloorApproverRole
+	}
+	return f.ApproverRole
+}
+
+// MarginViolation is a margin floor the price falls below
+type MarginViolation struct {
+	FloorID          string            `json:"floor_id"`
+	FloorName        string            `json:"floor_name"`
+	GroupID          string            `json:"group_id,omitempty"`
+	MinMarginPercent float64           `json:"min_margin_percent"`
+	MarginPercent    float64           `json:"margin_percent"`
+	Action           MarginFloorAction `json:"action"`
+}
+
+// marginPercent returns margin as a percentage of revenue
+func marginPercent(revenue, cost float64) float64 {
+	if revenue <= 0 {
+		if cost > 0 {
+			return -100
+		}
+		return 0
+	}
+	return math.Round((revenue-cost)/revenue*10000) / 100
+}
+
+// scopeMargin returns the revenue and cost of a breakdown's lines and charges
+// in a group. Order-level adjustments are shared across lines by net price.
+// Lines rolled up from sub-assemblies belong to other models' groups.
+func scopeMargin(model *Model, breakdown PriceBreakdown, groupID string) (revenue, cost float64) {
+	inScope := func(path, optionID string) bool {
+		if path != "" {
+			return false
+		}
+		option, err := model.GetOption(optionID)
+		return err == nil && option.GroupID == groupID
+	}
+
+	var net float64
+	for _, line := range breakdown.Lines {
+		net += line.NetPrice
+	}
+	share := 0.0
+	if net > 0 {
+		share = breakdown.TotalPrice / net
+	}
+
+	for _, line := range breakdown.Lines {
+		if inScope(line.Path, line.OptionID) {
+			revenue += line.NetPrice * share
+			cost += line.Cost
+		}
+	}
+	for _, charge := range breakdown.Charges {
+		if inScope(charge.Path, charge.OptionID) {
+			revenue += charge.Amount
+			cost += charge.Cost
+		}
+	}
+	return revenue, cost
+}
+
+// CheckMarginFloors evaluates a model's margin floors against a breakdown,
+// recording the floors it falls below. Any violation requires approval; a
+// requested discount breaching a blocking floor is rejected by ApplyDiscount.
+func CheckMarginFloors(model *Model, breakdown PriceBreakdown) PriceBreakdown {
+	breakdown.MarginViolations = nil
+
+	for _, floor := range model.MarginFloors {
+		margin := breakdown.MarginPercent
+		cost := breakdown.TotalCost
+		if floor.GroupID != "" {
+			var revenue float64
+			revenue, cost = scopeMargin(model, breakdown, floor.GroupID)
+			margin = marginPercent(revenue, cost)
+		}
+
+		// Nothing with a cost is priced, so there is no margin to protect
+		if cost == 0 || margin >= floor.MinMarginPercent {
+			continue
+		}
+
+		breakdown.MarginViolations = append(breakdown.MarginViolations, MarginViolation{
+			FloorID:          floor.ID,
+			FloorName:        floor.Name,
+			GroupID:          floor.GroupID,
+			MinMarginPercent: floor.MinMarginPercent,
+			MarginPercent:    margin,
+			Action:           floor.Action,
+		})
+	}
+
+	breakdown.RequiresApproval = len(breakdown.MarginViolations) > 0
+	return breakdown
+}
+
+// ApplyDiscount applies a requested percentage discount to the net price,
+// after any contract terms, and checks the margin floors. A discount that
+// leaves the price below a blocking floor is rejected; breaches of approval
+// floors are flagged on the breakdown.
+func ApplyDiscount(model *Model, breakdown PriceBreakdown, percent float64) (PriceBreakdown, error) {
+	if percent < 0 || percent > 100 {
+		return breakdown, fmt.Errorf("discount must be between 0 and 100 percent")
+	}
+
+	if percent > 0 && breakdown.TotalPrice > 0 {
+		amount := -breakdown.TotalPrice * percent / 100
+		breakdown.Discount = &PriceAdjustment{
+			RuleID:      "requested_discount",
+			RuleName:    "Requested discount",
+			Type:        "discount",
+			Amount:      amount,
+			Description: fmt.Sprintf("Requested discount: %.1f%%", percent),
+		}
+		breakdown.TotalPrice += amount
+		breakdown.updateTotals()
+	}
+
+	breakdown = CheckMarginFloors(model, breakdown)
+	if breakdown.Discount == nil {
+		return breakdown, nil
+	}
+
+	for _, violation := range breakdown.MarginViolations {
+		if violation.Action == BlockBelowFloor {
+			return breakdown, fmt.Errorf("discount of %.1f%% breaches margin floor %s: margin %.1f%% is below %.1f%%",
+				percent, violation.FloorID, violation.MarginPercent, violation.MinMarginPercent)
+		}
+	}
+	return breakdown, nil
+}
+
+// ValidateMarginFloors validates margin floors against a model
+func ValidateMarginFloors(model *Model, floors []MarginFloor) error {
+	ids := make(map[string]bool)
+	for _, floor := range floors {
+		if floor.ID == "" {
+			return fmt.Errorf("margin floor ID cannot be empty")
+		}
+		if ids[floor.ID] {
+			return fmt.Errorf("duplicate margin floor %s", floor.ID)
+		}
+		ids[floor.ID] = true
+
+		if floor.MinMarginPercent >= 100 {
+			return fmt.Errorf("margin floor %s: minimum margin must be below 100 percent", floor.ID)
+		}
+		if floor.GroupID != "" {
+			if _, err := model.GetGroup(floor.GroupID); err != nil {
+				return fmt.Errorf("margin floor %s references invalid group %s", floor.ID, floor.GroupID)
+			}
+		}
+		if floor.Action != BlockBelowFloor && floor.Action != ApproveBelowFloor {
+			return fmt.Errorf("margin floor %s: unknown action %q", floor.ID, floor.Action)
+		}
+		if floor.ApproverRole != "" && floor.Action != ApproveBelowFloor {
+			return fmt.Errorf("margin floor %s: only approval floors have an approver role", floor.ID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package cpq
+
+import (
+	"math"
+	"testing"
+)
+
+func createTestModelWithCosts() *Model {
+	model := createTestModelForPricing()
+	model.Options[0].Cost = 60 // $100 list
+	model.Options[1].Cost = 30 // $50 list
+
+	model.AddGroup(Group{ID: "group2", Name: "Hardware", Type: MultiSelect})
+	model.AddOption(Option{ID: "opt3", Name: "Option 3", GroupID: "group2", BasePrice: 200, Cost: 150, IsActive: true})
+	return model
+}
+
+func TestPricingCalculator_Margin(t *testing.T) {
+	model := createTestModelWithCosts()
+	calc := NewPricingCalculator(model)
+
+	breakdown := calc.CalculatePrice([]Selection{
+		{OptionID: "opt1", Quantity: 1},
+		{OptionID: "opt2", Quantity: 1},
+	}, PricingContext{})
+
+	if breakdown.TotalCost != 90 || breakdown.Margin != 60 || breakdown.MarginPercent != 40 {
+		t.Errorf("Expected cost 90.00, margin 60.00 (40%%), got %.2f, %.2f (%.2f%%)",
+			breakdown.TotalCost, breakdown.Margin, breakdown.MarginPercent)
+	}
+	if breakdown.RequiresApproval || len(breakdown.MarginViolations) != 0 {
+		t.Errorf("Model without floors should not need approval, got %v", breakdown.MarginViolations)
+	}
+}
+
+func TestPricingCalculator_MarginWithCharges(t *testing.T) {
+	model := createTestModelWithCosts()
+	model.Options[0].Components = []PriceComponent{
+		{ID: "support", Name: "Support", ChargeType: RecurringCharge, Price: 120, Cost: 60, Period: AnnualPeriod},
+	}
+	calc := NewPricingCalculator(model)
+
+	breakdown := calc.CalculatePrice([]Selection{{OptionID: "opt1", Quantity: 1}}, PricingContext{})
+
+	// Revenue 100 + 10 × 12, cost 60 + 5 × 12
+	if math.Abs(breakdown.TotalCost-120) > 0.001 || math.Abs(breakdown.Margin-100) > 0.001 {
+		t.Errorf("Expected cost 120.00 and margin 100.00, got %.2f and %.2f", breakdown.TotalCost, breakdown.Margin)
+	}
+	if breakdown.MarginPercent != 45.45 {
+		t.Errorf("Expected margin 45.45%%, got %.2f%%", breakdown.MarginPercent)
+	}
+}
+
+func TestApplyDiscount_MarginFloors(t *testing.T) {
+	selections := []Selection{
+		{OptionID: "opt1", Quantity: 1},
+		{OptionID: "opt2", Quantity: 1},
+	} // $150, cost $90
+
+	tests := []struct {
+		name     string
+		action   MarginFloorAction
+		discount float64
+		wantErr  bool
+		approval bool
+	}{
+		{"discount within floor", ApproveBelowFloor, 10, false, false}, // 33.3% margin
+		{"discount below approval floor is flagged", ApproveBelowFloor, 20, false, true},
+		{"discount within blocking floor", BlockBelowFloor, 5, false, false},
+		{"discount below blocking floor is rejected", BlockBelowFloor, 20, true, true},
+		{"no discount", BlockBelowFloor, 0, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := createTestModelWithCosts()
+			model.MarginFloors = []MarginFloor{{ID: "floor", Name: "Floor", MinMarginPercent: 30, Action: tt.action}}
+			list := NewPricingCalculator(model).CalculatePrice(selections, PricingContext{})
+
+			priced, err := ApplyDiscount(model, list, tt.discount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if priced.RequiresApproval != tt.approval {
+				t.Errorf("Expected requires approval %v, got %v", tt.approval, priced.RequiresApproval)
+			}
+
+			expected := list.TotalPrice * (1 - tt.discount/100)
+			if math.Abs(priced.TotalPrice-expected) > 0.001 {
+				t.Errorf("Expected total %.2f, got %.2f", expected, priced.TotalPrice)
+			}
+			if tt.discount > 0 && (priced.Discount == nil || len(priced.Adjustments) != len(list.Adjustments)) {
+				t.Error("Requested discount should be recorded apart from list adjustments")
+			}
+		})
+	}
+}
+
+func TestApplyDiscount_GroupFloor(t *testing.T) {
+	model := createTestModelWithCosts()
+	model.MarginFloors = []MarginFloor{{ID: "hardware", GroupID: "group2", MinMarginPercent: 20, Action: BlockBelowFloor}}
+	calc := NewPricingCalculator(model)
+
+	// opt1 $100 cost $60, opt3 $200 cost $150: 25% hardware margin
+	list := calc.CalculatePrice([]Selection{
+		{OptionID: "opt1", Quantity: 1},
+		{OptionID: "opt3", Quantity: 1},
+	}, PricingContext{})
+	if list.RequiresApproval {
+		t.Fatalf("List price should clear the hardware floor, got %v", list.MarginViolations)
+	}
+
+	// A 10% discount leaves hardware at 180 against 150 cost: 16.7%, although
+	// the configuration keeps a 22.2% margin
+	priced, err := ApplyDiscount(model, list, 10)
+	if err == nil {
+		t.Fatal("Expected discount breaching the group floor to be rejected")
+	}
+	if len(priced.MarginViolations) != 1 || priced.MarginViolations[0].GroupID != "group2" {
+		t.Fatalf("Expected hardware floor violation, got %v", priced.MarginViolations)
+	}
+	if priced.MarginViolations[0].MarginPercent != 16.67 {
+		t.Errorf("Expected hardware margin 16.67%%, got %.2f%%", priced.MarginViolations[0].MarginPercent)
+	}
+
+	// The floor does not apply to a configuration without hardware
+	other := calc.CalculatePrice([]Selection{{OptionID: "opt1", Quantity: 1}}, PricingContext{})
+	if _, err := ApplyDiscount(model, other, 30); err != nil {
+		t.Errorf("Group floor should not apply without group selections: %v", err)
+	}
+}
+
+func TestCheckMarginFloors_AfterContract(t *testing.T) {
+	model := createTestModelWithCosts()
+	model.MarginFloors = []MarginFloor{{ID: "floor", MinMarginPercent: 30, Action: BlockBelowFloor}}
+
+	list := NewPricingCalculator(model).CalculatePrice([]Selection{{OptionID: "opt1", Quantity: 1}}, PricingContext{})
+	priced, err := ApplyContract(list, &Contract{ID: "c1", CustomerID: "acme", DiscountPercent: 50})
+	if err != nil {
+		t.Fatalf("ApplyContract failed: %v", err)
+	}
+
+	// Contract terms are negotiated already, so a breach is flagged, not rejected
+	priced, err = ApplyDiscount(model, priced, 0)
+	if err != nil {
+		t.Fatalf("Contract pricing below a floor should not be rejected: %v", err)
+	}
+	if !priced.RequiresApproval || priced.MarginPercent != -20 {
+		t.Errorf("Expected -20%% margin flagged for approval, got %.2f%% (approval %v)", priced.MarginPercent, priced.RequiresApproval)
+	}
+}
+
+func TestValidateMarginFloors(t *testing.T) {
+	tests := []struct {
+		name    string
+		floor   MarginFloor
+		wantErr bool
+	}{
+		{"model floor", MarginFloor{ID: "f", MinMarginPercent: 20, Action: ApproveBelowFloor}, false},
+		{"group floor", MarginFloor{ID: "f", GroupID: "group1", MinMarginPercent: 20, Action: BlockBelowFloor}, false},
+		{"missing ID", MarginFloor{MinMarginPercent: 20, Action: BlockBelowFloor}, true},
+		{"margin of 100", MarginFloor{ID: "f", MinMarginPercent: 100, Action: BlockBelowFloor}, true},
+		{"unknown group", MarginFloor{ID: "f", GroupID: "nope", Action: BlockBelowFloor}, true},
+		{"unknown action", MarginFloor{ID: "f", Action: "warn"}, true},
+		{"approver role", MarginFloor{ID: "f", Action: ApproveBelowFloor, ApproverRole: "user"}, false},
+		{"approver role on blocking floor", MarginFloor{ID: "f", Action: BlockBelowFloor, ApproverRole: "user"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMarginFloors(createTestModelForPricing(), []MarginFloor{tt.floor})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	model := createTestModelForPricing()
+	model.Options[0].Cost = -1
+	if err := ValidatePriceComponents(model); err == nil {
+		t.Error("Expected error for negative option cost")
+	}
+
+	if _, err := ApplyDiscount(model, PriceBreakdown{}, 120); err == nil {
+		t.Error("Expected error for discount over 100 percent")
+	}
+}
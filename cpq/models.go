@@ -4,7 +4,11 @@
 package cpq
 
 import (
+	"DD/i18n"
+	"DD/parser"
+	"DD/tax"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -14,17 +18,25 @@ import (
 
 // Model represents a complete SMB CPQ configuration model
 type Model struct {
-	ID          string      `json:"id"`
-	Name        string      `json:"name"`
-	Description string      `json:"description"`
-	Version     string      `json:"version"`
-	Groups      []Group     `json:"groups"`
-	Options     []Option    `json:"options"`
-	Rules       []Rule      `json:"rules"`
-	PriceRules  []PriceRule `json:"price_rules"`
-	CreatedAt   time.Time   `json:"created_at"`
-	UpdatedAt   time.Time   `json:"updated_at"`
-	IsActive    bool        `json:"is_active"`
+	ID               string           `json:"id"`
+	Name             string           `json:"name"`
+	Description      string           `json:"description"`
+	Version          string           `json:"version"`
+	Groups           []Group          `json:"groups"`
+	Options          []Option         `json:"options"`
+	Rules            []Rule           `json:"rules"`
+	PriceRules       []PriceRule      `json:"price_rules"`
+	Definitions      []Definition     `json:"definitions"`
+	VolumeTiers      []VolumeTier     `json:"volume_tiers,omitempty"` // Empty uses the default tiers
+	Currency         string           `json:"currency,omitempty"`     // Currency of base prices, default USD
+	PriceBooks       []PriceBook      `json:"price_books,omitempty"`
+	TermMonths       int              `json:"term_months,omitempty"` // Default contract term, 12 if unset
+	MarginFloors     []MarginFloor    `json:"margin_floors,omitempty"`
+	ApprovalPolicies []ApprovalPolicy `json:"approval_policies,omitempty"`
+	AssemblyRules    []AssemblyRule   `json:"assembly_rules,omitempty"` // Checked across sub-assembly levels
+	CreatedAt        time.Time        `json:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at"`
+	IsActive         bool             `json:"is_active"`
 }
 
 // Group defines option groups with selection constraints
@@ -54,8 +66,12 @@ type Option struct {
 	IsActive     bool                   `json:"is_active"`
 	DisplayOrder int                    `json:"display_order"`
 	Price        float64                `json:"price"`
-	SKU          string                 `json:"sku,omitempty"`        // Added for database compatibility
-	Attributes   map[string]interface{} `json:"attributes,omitempty"` // Added for frontend compatibility
+	SKU          string                 `json:"sku,omitempty"`            // Added for database compatibility
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`     // Added for frontend compatibility
+	Components   []PriceComponent       `json:"components,omitempty"`     // Recurring, one-time and usage charges
+	Cost         float64                `json:"cost,omitempty"`           // Unit cost in the model currency
+	TaxCategory  string                 `json:"tax_category,omitempty"`   // Product tax category; empty takes standard rates
+	ChildModelID string                 `json:"child_model_id,omitempty"` // Sub-assembly: each unit is configured from this model
 }
 
 // Rule defines static boolean constraints (Phase 1)
@@ -82,6 +98,30 @@ type PriceRule struct {
 	Priority      int           `json:"priority"`
 }
 
+// Definition names a reusable sub-expression (`let highend = ...`) that rules
+// and price rules reference by ID
+type Definition struct {
+	ID          string `json:"id"` // Identifier used in expressions
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Expression  string `json:"expression"` // Expression the identifier stands for
+}
+
+// VolumeTier is a quantity band with a price multiplier. A tier applies to
+// the whole model unless it names an option or a group, in which case it is
+// matched against that option's or group's quantity only.
+type VolumeTier struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	OptionID    string         `json:"option_id,omitempty"`
+	GroupID     string         `json:"group_id,omitempty"`
+	Mode        VolumeTierMode `json:"mode,omitempty"` // Defaults to all-units
+	MinQuantity int            `json:"min_quantity"`
+	MaxQuantity int            `json:"max_quantity"` // -1 for unlimited
+	Multiplier  float64        `json:"multiplier"`   // Price multiplier (0.9 = 10% discount)
+	Priority    int            `json:"priority"`     // Lower number = higher priority
+}
+
 // ===================================================================
 // OPERATIONAL TYPES
 // ===================================================================
@@ -104,6 +144,11 @@ type Configuration struct {
 	Status      string      `json:"status,omitempty"` // 'draft', 'quoted', 'ordered'
 	CreatedAt   time.Time   `json:"created_at"`
 	UpdatedAt   time.Time   `json:"updated_at"`
+
+	// Sub-assemblies: one child configuration per unit of each selected
+	// option that references a child model
+	ParentOptionID string          `json:"parent_option_id,omitempty"`
+	Children       []Configuration `json:"children,omitempty"`
 }
 
 // ValidationResult contains constraint validation outcome
@@ -112,6 +157,25 @@ type ValidationResult struct {
 	Violations   []RuleViolation `json:"violations"`
 	Suggestions  []string        `json:"suggestions"`
 	ResponseTime time.Duration   `json:"response_time"`
+
+	suggestions []i18n.Message // Localizable Suggestions
+}
+
+// Localized returns a copy of the result with violations and suggestions
+// in the localizer's language
+func (r ValidationResult) Localized(localizer *i18n.Localizer) ValidationResult {
+	violations := make([]RuleViolation, len(r.Violations))
+	for i, violation := range r.Violations {
+		violations[i] = violation.Localized(localizer)
+	}
+	r.Violations = violations
+	if r.suggestions != nil {
+		r.Suggestions = make([]string, len(r.suggestions))
+		for i, suggestion := range r.suggestions {
+			r.Suggestions[i] = localizer.Render(suggestion)
+		}
+	}
+	return r
 }
 
 // RuleViolation represents a constraint violation
@@ -120,6 +184,26 @@ type RuleViolation struct {
 	RuleName        string   `json:"rule_name"`
 	Message         string   `json:"message"`
 	AffectedOptions []string `json:"affected_options"`
+	Severity        string   `json:"severity,omitempty"` // From the rule's annotations
+	Reason          string   `json:"reason,omitempty"`   // The selections that decided the violation
+	Path            string   `json:"path,omitempty"`     // Sub-assembly in violation, e.g. servers[1]; empty at the top level
+
+	// Localizable RuleName, Message and Reason
+	name, message, reason i18n.Message
+}
+
+// Localized returns a copy of the violation in the localizer's language
+func (v RuleViolation) Localized(localizer *i18n.Localizer) RuleViolation {
+	if !v.name.IsZero() {
+		v.RuleName = localizer.Render(v.name)
+	}
+	if !v.message.IsZero() {
+		v.Message = localizer.Render(v.message)
+	}
+	if !v.reason.IsZero() {
+		v.Reason = localizer.Render(v.reason)
+	}
+	return v
 }
 
 // PriceBreakdown contains detailed pricing calculation
@@ -128,6 +212,51 @@ type PriceBreakdown struct {
 	Adjustments     []PriceAdjustment `json:"adjustments"`
 	TotalPrice      float64           `json:"total_price"`
 	CalculationTime time.Duration     `json:"calculation_time"`
+	Lines           []LinePrice       `json:"lines,omitempty"`
+	Currency        string            `json:"currency,omitempty"`
+	PriceBookID     string            `json:"price_book_id,omitempty"`
+	Contract        *ContractPricing  `json:"contract,omitempty"` // Customer terms applied after list pricing
+
+	// Price components over the contract term. TotalPrice is the net of
+	// option base prices; OneTimeTotal adds one-time components to it.
+	Charges            []ChargeLine `json:"charges,omitempty"`
+	TermMonths         int          `json:"term_months"`
+	OneTimeTotal       float64      `json:"one_time_total"`
+	MRR                float64      `json:"mrr"`
+	ARR                float64      `json:"arr"`
+	UsageMonthly       float64      `json:"usage_monthly"` // Estimated
+	TotalContractValue float64      `json:"total_contract_value"`
+
+	// Requested discount applied after contract terms
+	Discount *PriceAdjustment `json:"discount,omitempty"`
+
+	// Cost and margin over the contract term, checked against margin floors
+	TotalCost        float64           `json:"total_cost"`
+	Margin           float64           `json:"margin"`
+	MarginPercent    float64           `json:"margin_percent"`
+	MarginViolations []MarginViolation `json:"margin_violations,omitempty"`
+	RequiresApproval bool              `json:"requires_approval"`
+
+	// Tax on the net price at the ship-to address. Kept apart from the
+	// totals above, so discounts and margins are always computed pre-tax.
+	Tax *tax.Summary `json:"tax,omitempty"`
+
+	// Price rules that could not be applied, e.g. a formula failing on
+	// these selections
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// LinePrice is the price of one selection after volume tiers
+type LinePrice struct {
+	OptionID       string  `json:"option_id"`
+	Quantity       int     `json:"quantity"`
+	UnitPrice      float64 `json:"unit_price"`
+	BasePrice      float64 `json:"base_price"`      // Quantity × unit price
+	VolumeDiscount float64 `json:"volume_discount"` // Share of the tier discounts, positive
+	TierID         string  `json:"tier_id,omitempty"`
+	NetPrice       float64 `json:"net_price"`
+	Cost           float64 `json:"cost"`           // Quantity × unit cost
+	Path           string  `json:"path,omitempty"` // Sub-assembly the line was rolled up from
 }
 
 // PriceAdjustment represents a single pricing modification
@@ -165,10 +294,18 @@ const (
 type PriceRuleType string
 
 const (
-	VolumeTierRule      PriceRuleType = "volume_tier"      // Quantity-based tiers
+	VolumeTierRule      PriceRuleType = "volume_tier"      // Not evaluated; use Model.VolumeTiers
 	FixedDiscountRule   PriceRuleType = "fixed_discount"   // Fixed amount off
 	PercentDiscountRule PriceRuleType = "percent_discount" // Percentage off
 	SurchargeRule       PriceRuleType = "surcharge"        // Additional cost
+	ExpressionPriceRule PriceRuleType = "expression"       // Formula in the expression language
+)
+
+type VolumeTierMode string
+
+const (
+	AllUnitsTiers    VolumeTierMode = "all_units"   // The reached tier prices every unit
+	IncrementalTiers VolumeTierMode = "incremental" // Each tier prices only the units within it
 )
 
 // ===================================================================
@@ -178,16 +315,17 @@ const (
 // NewModel creates a new CPQ model with defaults
 func NewModel(id, name string) *Model {
 	return &Model{
-		ID:         id,
-		Name:       name,
-		Version:    "1.0.0",
-		Groups:     make([]Group, 0),
-		Options:    make([]Option, 0),
-		Rules:      make([]Rule, 0),
-		PriceRules: make([]PriceRule, 0),
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
-		IsActive:   true,
+		ID:          id,
+		Name:        name,
+		Version:     "1.0.0",
+		Groups:      make([]Group, 0),
+		Options:     make([]Option, 0),
+		Rules:       make([]Rule, 0),
+		PriceRules:  make([]PriceRule, 0),
+		Definitions: make([]Definition, 0),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		IsActive:    true,
 	}
 }
 
@@ -219,6 +357,13 @@ func (m *Model) AddPriceRule(rule PriceRule) *Model {
 	return m
 }
 
+// AddDefinition adds a named definition to the model
+func (m *Model) AddDefinition(definition Definition) *Model {
+	m.Definitions = append(m.Definitions, definition)
+	m.UpdatedAt = time.Now()
+	return m
+}
+
 // GetOption finds an option by ID
 func (m *Model) GetOption(optionID string) (*Option, error) {
 	for i := range m.Options {
@@ -239,6 +384,59 @@ func (m *Model) GetGroup(groupID string) (*Group, error) {
 	return nil, fmt.Errorf("group %s not found", groupID)
 }
 
+// GetDefinition finds a definition by ID
+func (m *Model) GetDefinition(definitionID string) (*Definition, error) {
+	for i := range m.Definitions {
+		if m.Definitions[i].ID == definitionID {
+			return &m.Definitions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("definition %s not found", definitionID)
+}
+
+// UpdateDefinition replaces the definition with the same ID. The model is
+// left unchanged when the definitions would no longer parse.
+func (m *Model) UpdateDefinition(definition Definition) error {
+	if _, err := m.GetDefinition(definition.ID); err != nil {
+		return err
+	}
+
+	definitions := make([]Definition, len(m.Definitions))
+	for i, existing := range m.Definitions {
+		if existing.ID == definition.ID {
+			existing = definition
+		}
+		definitions[i] = existing
+	}
+
+	previous := m.Definitions
+	m.Definitions = definitions
+	if _, err := m.ParseDefinitions(); err != nil {
+		m.Definitions = previous
+		return err
+	}
+	m.UpdatedAt = time.Now()
+	return nil
+}
+
+// RemoveDefinition removes a definition from the model. References to it are
+// not checked; see DefinitionReferences.
+func (m *Model) RemoveDefinition(definitionID string) error {
+	if _, err := m.GetDefinition(definitionID); err != nil {
+		return err
+	}
+
+	var definitions []Definition
+	for _, definition := range m.Definitions {
+		if definition.ID != definitionID {
+			definitions = append(definitions, definition)
+		}
+	}
+	m.Definitions = definitions
+	m.UpdatedAt = time.Now()
+	return nil
+}
+
 // GetOptionsInGroup returns all options for a specific group
 func (m *Model) GetOptionsInGroup(groupID string) []Option {
 	var options []Option
@@ -266,5 +464,210 @@ func (m *Model) Validate() error {
 		}
 	}
 
+	// Validate definitions parse, do not shadow variables and are acyclic
+	if _, err := m.ParseDefinitions(); err != nil {
+		return err
+	}
+
+	// Validate rule annotations carry well-formed metadata
+	for _, rule := range m.Rules {
+		if _, err := rule.Metadata(); err != nil {
+			return fmt.Errorf("rule %s: %w", rule.ID, err)
+		}
+	}
+
+	// Validate price formulas parse and reference known variables
+	for _, priceRule := range m.PriceRules {
+		if priceRule.Type != ExpressionPriceRule {
+			continue
+		}
+		if err := ValidatePriceRule(m, priceRule); err != nil {
+			return fmt.Errorf("price rule %s: %w", priceRule.ID, err)
+		}
+	}
+
+	// Validate volume tiers reference known options and groups
+	if err := ValidateVolumeTiers(m, m.VolumeTiers); err != nil {
+		return err
+	}
+
+	// Validate price books reference known options
+	if err := ValidatePriceBooks(m, m.PriceBooks); err != nil {
+		return err
+	}
+
+	// Validate option price components
+	if err := ValidatePriceComponents(m); err != nil {
+		return err
+	}
+
+	// Validate margin floors reference known groups
+	if err := ValidateMarginFloors(m, m.MarginFloors); err != nil {
+		return err
+	}
+
+	// Validate approval policy conditions
+	if err := ValidateApprovalPolicies(m.ApprovalPolicies); err != nil {
+		return err
+	}
+
+	// Validate assembly rules parse and sub-assemblies are other models
+	if err := ValidateAssemblyRules(m); err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// RuleMetadata is rule information written as annotations in front of the
+// rule expression, e.g. `@meta(severity="warning", owner="hardware") a -> b`
+type RuleMetadata struct {
+	Severity      string            `json:"severity,omitempty"` // "error", "warning" or "info"
+	Owner         string            `json:"owner,omitempty"`
+	EffectiveDate *time.Time        `json:"effective_date,omitempty"`
+	Values        map[string]string `json:"values,omitempty"`   // Every annotation argument
+	Messages      map[string]string `json:"messages,omitempty"` // Translations of the rule message by locale
+}
+
+// Metadata returns the metadata carried by the rule expression's annotations.
+// Arguments of all annotations share one namespace; an annotation without
+// arguments sets its name to "true". The exception is @message, whose
+// arguments translate the rule message, e.g. `@message(de="...", fr="...")`.
+func (r Rule) Metadata() (RuleMetadata, error) {
+	metadata := RuleMetadata{Values: make(map[string]string)}
+
+	annotations, err := parser.ParseAnnotations(r.Expression)
+	if err != nil {
+		return metadata, err
+	}
+
+	for _, annotation := range annotations {
+		if annotation.Name == "message" {
+			if metadata.Messages == nil {
+				metadata.Messages = make(map[string]string)
+			}
+			for _, arg := range annotation.Args {
+				metadata.Messages[arg.Key] = arg.Value
+			}
+			continue
+		}
+		args := annotation.Args
+		if len(args) == 0 {
+			args = []parser.AnnotationArg{{Key: annotation.Name, Value: "true"}}
+		}
+		for _, arg := range args {
+			if _, exists := metadata.Values[arg.Key]; exists {
+				return metadata, fmt.Errorf("duplicate rule metadata %q", arg.Key)
+			}
+			metadata.Values[arg.Key] = arg.Value
+		}
+	}
+
+	if severity, ok := metadata.Values["severity"]; ok {
+		switch severity {
+		case "error", "warning", "info":
+			metadata.Severity = severity
+		default:
+			return metadata, fmt.Errorf("invalid severity %q: must be error, warning or info", severity)
+		}
+	}
+	metadata.Owner = metadata.Values["owner"]
+	if value, ok := metadata.Values["effective_date"]; ok {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return metadata, fmt.Errorf("invalid effective_date %q: use YYYY-MM-DD", value)
+		}
+		metadata.EffectiveDate = &date
+	}
+
+	return metadata, nil
+}
+
+// ParseDefinitions parses the model's definitions keyed by ID. Definition IDs
+// must be identifiers that do not clash with option or group count variables,
+// and definitions may not reference each other in a cycle.
+func (m *Model) ParseDefinitions() (map[string]parser.Expression, error) {
+	definitions := make(map[string]parser.Expression, len(m.Definitions))
+	for _, definition := range m.Definitions {
+		if _, exists := definitions[definition.ID]; exists {
+			return nil, fmt.Errorf("duplicate definition %s", definition.ID)
+		}
+		if _, err := m.GetOption(definition.ID); err == nil {
+			return nil, fmt.Errorf("definition %s conflicts with an option ID", definition.ID)
+		}
+		if strings.HasPrefix(definition.ID, "group_") && strings.HasSuffix(definition.ID, "_count") {
+			return nil, fmt.Errorf("definition %s conflicts with a group count variable", definition.ID)
+		}
+
+		parsed, err := parser.ParseDefinition(definition.String())
+		if err != nil {
+			return nil, fmt.Errorf("invalid definition %s: %w", definition.ID, err)
+		}
+		if parsed.Name != definition.ID {
+			return nil, fmt.Errorf("invalid definition ID %q: must be an identifier", definition.ID)
+		}
+		definitions[definition.ID] = parsed.Body
+	}
+
+	if _, err := parser.DefinitionOrder(definitions); err != nil {
+		return nil, err
+	}
+	return definitions, nil
+}
+
+// DefinitionReferences lists the rules, price rules and definitions that
+// reference a definition
+func (m *Model) DefinitionReferences(definitionID string) []string {
+	var references []string
+	mentions := func(expr parser.Expression, err error) bool {
+		if err != nil {
+			return false
+		}
+		for _, name := range parser.CollectVariables(expr) {
+			if name == definitionID {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, rule := range m.Rules {
+		if mentions(parser.ParseExpression(rule.Expression)) {
+			references = append(references, "rule "+rule.ID)
+		}
+	}
+	for _, priceRule := range m.PriceRules {
+		if priceRule.Type == ExpressionPriceRule {
+			if mentions(parser.ParseFormula(priceRule.Expression)) {
+				references = append(references, "pricing rule "+priceRule.ID)
+			}
+			continue
+		}
+		// Other price rule conditions use the "condition:amount" form
+		if condition, _, found := strings.Cut(priceRule.Expression, ":"); found && strings.TrimSpace(condition) == definitionID {
+			references = append(references, "pricing rule "+priceRule.ID)
+		}
+	}
+	for _, definition := range m.Definitions {
+		if definition.ID != definitionID && mentions(parser.ParseExpression(definition.Expression)) {
+			references = append(references, "definition "+definition.ID)
+		}
+	}
+	return references
+}
+
+// String returns the definition in `let id = expression` form
+func (d Definition) String() string {
+	return fmt.Sprintf("let %s = %s", d.ID, d.Expression)
+}
+
+// ParseDefinitionSource builds a definition from `let id = expression` text
+func ParseDefinitionSource(source string) (Definition, error) {
+	parsed, err := parser.ParseDefinition(source)
+	if err != nil {
+		return Definition{}, err
+	}
+	// The first '=' is the assignment since it cannot appear in 'let' or the name
+	body := strings.TrimSpace(source[strings.Index(source, "=")+1:])
+	return Definition{ID: parsed.Name, Name: parsed.Name, Expression: body}, nil
+}
@@ -1,6 +1,7 @@
 package cpq
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -289,6 +290,227 @@ func TestModel_Validate(t *testing.T) {
 	}
 }
 
+func TestModel_ParseDefinitions(t *testing.T) {
+	newModel := func(definitions ...Definition) *Model {
+		model := NewModel("test", "Test Model")
+		model.AddGroup(Group{ID: "cpu", Name: "CPU", Type: SingleSelect})
+		model.AddOption(Option{ID: "cpu_i9", GroupID: "cpu", Name: "i9"})
+		model.AddOption(Option{ID: "cpu_xeon", GroupID: "cpu", Name: "Xeon"})
+		for _, definition := range definitions {
+			model.AddDefinition(definition)
+		}
+		return model
+	}
+
+	tests := []struct {
+		name          string
+		definitions   []Definition
+		expectedError string
+	}{
+		{
+			name: "Valid Chain",
+			definitions: []Definition{
+				{ID: "fastcpu", Expression: "cpu_i9 OR cpu_xeon"},
+				{ID: "highend", Expression: "fastcpu AND ram_64"},
+			},
+		},
+		{
+			name: "Cycle",
+			definitions: []Definition{
+				{ID: "a", Expression: "b OR cpu_i9"},
+				{ID: "b", Expression: "NOT a"},
+			},
+			expectedError: "definition cycle: a -> b -> a",
+		},
+		{
+			name:          "Self Reference",
+			definitions:   []Definition{{ID: "a", Expression: "a AND cpu_i9"}},
+			expectedError: "definition cycle: a -> a",
+		},
+		{
+			name:          "Shadows Option",
+			definitions:   []Definition{{ID: "cpu_i9", Expression: "cpu_xeon"}},
+			expectedError: "definition cpu_i9 conflicts with an option ID",
+		},
+		{
+			name:          "Shadows Group Count",
+			definitions:   []Definition{{ID: "group_cpu_count", Expression: "cpu_xeon"}},
+			expectedError: "definition group_cpu_count conflicts with a group count variable",
+		},
+		{
+			name: "Duplicate",
+			definitions: []Definition{
+				{ID: "a", Expression: "cpu_i9"},
+				{ID: "a", Expression: "cpu_xeon"},
+			},
+			expectedError: "duplicate definition a",
+		},
+		{
+			name:          "Not An Identifier",
+			definitions:   []Definition{{ID: "high end", Expression: "cpu_i9"}},
+			expectedError: "invalid definition high end",
+		},
+		{
+			name:          "Keyword ID",
+			definitions:   []Definition{{ID: "AND", Expression: "cpu_i9"}},
+			expectedError: "invalid definition AND",
+		},
+		{
+			name:          "Syntax Error",
+			definitions:   []Definition{{ID: "a", Expression: "cpu_i9 AND"}},
+			expectedError: "invalid definition a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := newModel(tt.definitions...)
+			definitions, err := model.ParseDefinitions()
+
+			if tt.expectedError != "" {
+				if err == nil {
+					t.Fatalf("Expected error containing '%s', got none", tt.expectedError)
+				}
+				if !strings.Contains(err.Error(), tt.expectedError) {
+					t.Errorf("Expected error containing '%s', got '%s'", tt.expectedError, err.Error())
+				}
+				if model.Validate() == nil {
+					t.Error("Expected Validate to reject invalid definitions")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(definitions) != len(tt.definitions) {
+				t.Errorf("Expected %d definitions, got %d", len(tt.definitions), len(definitions))
+			}
+			if err := model.Validate(); err != nil {
+				t.Errorf("Valid definitions should pass validation: %v", err)
+			}
+		})
+	}
+}
+
+func newDefinitionTestModel() *Model {
+	model := NewModel("test", "Test Model")
+	model.AddGroup(Group{ID: "cpu", Name: "CPU", Type: SingleSelect})
+	model.AddOption(Option{ID: "cpu_i9", GroupID: "cpu", Name: "i9"})
+	model.AddOption(Option{ID: "cpu_xeon", GroupID: "cpu", Name: "Xeon"})
+	model.AddDefinition(Definition{ID: "fastcpu", Expression: "cpu_i9 OR cpu_xeon"})
+	model.AddDefinition(Definition{ID: "highend", Expression: "fastcpu AND ram_64"})
+	model.AddRule(Rule{ID: "rule_fast", Type: RequiresRule, Expression: "fastcpu -> cpu_i9"})
+	model.AddPriceRule(PriceRule{ID: "fast_surcharge", Type: ExpressionPriceRule, Expression: "IF(highend, 50, 0)"})
+	model.AddPriceRule(PriceRule{ID: "fast_discount", Type: VolumeTierRule, Expression: "fastcpu:-5"})
+	return model
+}
+
+func TestModel_UpdateDefinition(t *testing.T) {
+	tests := []struct {
+		name          string
+		definition    Definition
+		expectedError string
+	}{
+		{"Valid Update", Definition{ID: "fastcpu", Expression: "cpu_xeon"}, ""},
+		{"Unknown Definition", Definition{ID: "slowcpu", Expression: "cpu_xeon"}, "definition slowcpu not found"},
+		{"Syntax Error", Definition{ID: "fastcpu", Expression: "cpu_i9 AND ("}, "invalid definition fastcpu"},
+		{"Cycle", Definition{ID: "fastcpu", Expression: "highend OR cpu_i9"}, "definition cycle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := newDefinitionTestModel()
+			shared := model.Definitions
+			err := model.UpdateDefinition(tt.definition)
+
+			if shared[0].Expression != "cpu_i9 OR cpu_xeon" {
+				t.Error("UpdateDefinition should not write into the previous definitions slice")
+			}
+
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("Expected error containing '%s', got %v", tt.expectedError, err)
+				}
+				if model.Definitions[0].Expression != "cpu_i9 OR cpu_xeon" {
+					t.Errorf("Rejected update should leave the model unchanged, got %q", model.Definitions[0].Expression)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			definition, err := model.GetDefinition(tt.definition.ID)
+			if err != nil || definition.Expression != tt.definition.Expression {
+				t.Errorf("Expected updated expression %q, got %v", tt.definition.Expression, definition)
+			}
+		})
+	}
+}
+
+func TestModel_RemoveDefinition(t *testing.T) {
+	model := newDefinitionTestModel()
+	shared := model.Definitions
+
+	if err := model.RemoveDefinition("slowcpu"); err == nil {
+		t.Error("Expected error removing an unknown definition")
+	}
+	if err := model.RemoveDefinition("highend"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(model.Definitions) != 1 || model.Definitions[0].ID != "fastcpu" {
+		t.Errorf("Expected only fastcpu to remain, got %v", model.Definitions)
+	}
+	if len(shared) != 2 || shared[1].ID != "highend" {
+		t.Error("RemoveDefinition should not write into the previous definitions slice")
+	}
+}
+
+func TestModel_DefinitionReferences(t *testing.T) {
+	model := newDefinitionTestModel()
+
+	tests := []struct {
+		definitionID string
+		expected     []string
+	}{
+		{"fastcpu", []string{"rule rule_fast", "pricing rule fast_discount", "definition highend"}},
+		{"highend", []string{"pricing rule fast_surcharge"}},
+		{"unused", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.definitionID, func(t *testing.T) {
+			references := model.DefinitionReferences(tt.definitionID)
+			if strings.Join(references, ", ") != strings.Join(tt.expected, ", ") {
+				t.Errorf("Expected references %v, got %v", tt.expected, references)
+			}
+		})
+	}
+}
+
+func TestParseDefinitionSource(t *testing.T) {
+	definition, err := ParseDefinitionSource("let highend = (cpu_i9 OR cpu_xeon) AND ram_64")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if definition.ID != "highend" {
+		t.Errorf("Expected ID 'highend', got '%s'", definition.ID)
+	}
+	if definition.Expression != "(cpu_i9 OR cpu_xeon) AND ram_64" {
+		t.Errorf("Expected expression to keep source text, got '%s'", definition.Expression)
+	}
+	if definition.String() != "let highend = (cpu_i9 OR cpu_xeon) AND ram_64" {
+		t.Errorf("Unexpected definition string '%s'", definition.String())
+	}
+
+	if _, err := ParseDefinitionSource("highend = cpu_i9"); err == nil {
+		t.Error("Expected error for definition without 'let'")
+	}
+}
+
 func TestModelChaining(t *testing.T) {
 	// Test method chaining
 	model := NewModel("test", "Test").
@@ -387,3 +609,50 @@ func TestValidationResult(t *testing.T) {
 		t.Errorf("Expected 2 affected options, got %d", len(result.Violations[0].AffectedOptions))
 	}
 }
+
+func TestRule_Metadata(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		severity   string
+		owner      string
+		date       string
+		wantErr    bool
+	}{
+		{"no annotations", "opt_a -> opt_b", "", "", "", false},
+		{"meta annotation", `@meta(severity="warning", owner="hardware") opt_a -> opt_b`, "warning", "hardware", "", false},
+		{"effective date", "@meta(effective_date=\"2026-01-01\")\n// starts next year\nopt_a", "", "", "2026-01-01", false},
+		{"invalid severity", `@meta(severity="fatal") opt_a`, "", "", "", true},
+		{"invalid date", `@meta(effective_date="01/01/2026") opt_a`, "", "", "", true},
+		{"duplicate key", `@meta(owner="a") @team(owner="b") opt_a`, "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := Rule{ID: "r1", Expression: tt.expression}.Metadata()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected metadata error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if metadata.Severity != tt.severity || metadata.Owner != tt.owner {
+				t.Errorf("Got severity %q owner %q", metadata.Severity, metadata.Owner)
+			}
+			if tt.date != "" && (metadata.EffectiveDate == nil || metadata.EffectiveDate.Format("2006-01-02") != tt.date) {
+				t.Errorf("Expected effective date %s, got %v", tt.date, metadata.EffectiveDate)
+			}
+		})
+	}
+
+	model := NewModel("m1", "Model")
+	model.AddGroup(Group{ID: "g1", Name: "G", Type: SingleSelect})
+	model.AddOption(Option{ID: "opt_a", Name: "A", GroupID: "g1"})
+	model.AddRule(Rule{ID: "r1", Name: "R", Type: RequiresRule, Expression: `@meta(severity="fatal") opt_a`})
+	if err := model.Validate(); err == nil || !strings.Contains(err.Error(), "rule r1") {
+		t.Errorf("Expected validation to reject invalid rule metadata, got %v", err)
+	}
+}
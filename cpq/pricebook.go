@@ -0,0 +1,362 @@
+// pricebook.go - Price books, currencies and exchange rates
+// Named price lists with effective-dated entries, priced through a context
+
+package cpq
+
+import (
+	"DD/tax"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCurrency is the currency of option base prices when a model names none
+const DefaultCurrency = "USD"
+
+// ===================================================================
+// PRICE BOOKS
+// ===================================================================
+
+// PriceBook is a named price list in one currency, such as list, partner
+// or EMEA pricing. Options without an entry effective on the pricing date
+// fall back to their base price.
+type PriceBook struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Currency    string           `json:"currency"`   // ISO 4217 code, e.g. "EUR"
+	IsDefault   bool             `json:"is_default"` // Used when the pricing context names no book
+	Entries     []PriceBookEntry `json:"entries"`
+}
+
+// PriceBookEntry prices an option from a date, optionally until a date
+type PriceBookEntry struct {
+	OptionID      string     `json:"option_id"`
+	Price         float64    `json:"price"`
+	EffectiveFrom time.Time  `json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"` // Exclusive; nil for open-ended
+}
+
+// effectiveOn reports whether the entry is valid on a date
+func (e PriceBookEntry) effectiveOn(date time.Time) bool {
+	if date.Before(e.EffectiveFrom) {
+		return false
+	}
+	return e.EffectiveTo == nil || date.Before(*e.EffectiveTo)
+}
+
+// Price returns an option's price on a date. When entries overlap the one
+// that took effect last wins.
+func (b *PriceBook) Price(optionID string, date time.Time) (float64, bool) {
+	var found *PriceBookEntry
+	for i := range b.Entries {
+		entry := &b.Entries[i]
+		if entry.OptionID != optionID || !entry.effectiveOn(date) {
+			continue
+		}
+		if found == nil || entry.EffectiveFrom.After(found.EffectiveFrom) {
+			found = entry
+		}
+	}
+	if found == nil {
+		return 0, false
+	}
+	return found.Price, true
+}
+
+// GetPriceBook finds a price book by ID
+func (m *Model) GetPriceBook(id string) (*PriceBook, error) {
+	for i := range m.PriceBooks {
+		if m.PriceBooks[i].ID == id {
+			return &m.PriceBooks[i], nil
+		}
+	}
+	return nil, fmt.Errorf("price book not found: %s", id)
+}
+
+// DefaultPriceBook returns the model's default price book, or nil
+func (m *Model) DefaultPriceBook() *PriceBook {
+	for i := range m.PriceBooks {
+		if m.PriceBooks[i].IsDefault {
+			return &m.PriceBooks[i]
+		}
+	}
+	return nil
+}
+
+// BaseCurrency returns the currency of the model's option base prices and
+// fixed price rule amounts
+func (m *Model) BaseCurrency() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return normalizeCurrency(m.Currency)
+}
+
+// ===================================================================
+// EXCHANGE RATES
+// ===================================================================
+
+// ExchangeRate converts one currency to another from a date on
+type ExchangeRate struct {
+	FromCurrency  string    `json:"from_currency"`
+	ToCurrency    string    `json:"to_currency"`
+	Rate          float64   `json:"rate"` // Units of ToCurrency per unit of FromCurrency
+	EffectiveFrom time.Time `json:"effective_from"`
+}
+
+// ExchangeRates is a locally stored table of exchange rates
+type ExchangeRates []ExchangeRate
+
+// Rate returns the rate converting from one currency to another on a date:
+// the latest direct rate in effect, else the inverse of the latest reverse
+// rate
+func (rates ExchangeRates) Rate(from, to string, date time.Time) (float64, error) {
+	from, to = normalizeCurrency(from), normalizeCurrency(to)
+	if from == to {
+		return 1, nil
+	}
+
+	if rate, ok := rates.latest(from, to, date); ok {
+		return rate, nil
+	}
+	if rate, ok := rates.latest(to, from, date); ok {
+		return 1 / rate, nil
+	}
+	return 0, fmt.Errorf("no exchange rate from %s to %s on %s", from, to, date.Format("2006-01-02"))
+}
+
+// latest returns the most recent rate in effect on a date
+func (rates ExchangeRates) latest(from, to string, date time.Time) (float64, bool) {
+	var found *ExchangeRate
+	for i := range rates {
+		rate := &rates[i]
+		if normalizeCurrency(rate.FromCurrency) != from || normalizeCurrency(rate.ToCurrency) != to {
+			continue
+		}
+		if rate.EffectiveFrom.After(date) || rate.Rate <= 0 {
+			continue
+		}
+		if found == nil || rate.EffectiveFrom.After(found.EffectiveFrom) {
+			found = rate
+		}
+	}
+	if found == nil {
+		return 0, false
+	}
+	return found.Rate, true
+}
+
+// normalizeCurrency upper-cases a currency code
+func normalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ===================================================================
+// PRICING CONTEXT
+// ===================================================================
+
+// PricingContext selects the price book, date and currency a configuration
+// is priced in. The zero context prices from base prices in the model's
+// currency as of now.
+type PricingContext struct {
+	PriceBookID string        `json:"price_book_id,omitempty"` // Empty uses the model's default book, if any
+	Date        time.Time     `json:"date,omitempty"`          // Zero means now
+	Currency    string        `json:"currency,omitempty"`      // Empty uses the book's currency
+	TermMonths  int           `json:"term_months,omitempty"`   // Zero uses the model's term
+	Rates       ExchangeRates `json:"-"`                       // Rates for converting to Currency
+
+	// Ship-to address taxes are calculated for; nil quotes pre-tax
+	ShipTo     *tax.Address    `json:"ship_to,omitempty"`
+	TaxDisplay tax.DisplayMode `json:"tax_display,omitempty"` // Exclusive by default
+	TaxRates   *tax.Table      `json:"-"`                     // Jurisdiction rates for ShipTo
+}
+
+// pricingSource is a resolved pricing context
+type pricingSource struct {
+	book     *PriceBook
+	date     time.Time
+	currency string  // Currency prices are returned in
+	bookRate float64 // Book currency -> currency
+	baseRate float64 // Model currency -> currency, for base prices and fixed amounts
+
+	termMonths int // Contract term for recurring and usage charges
+}
+
+// ValidatePricingContext checks a model can be priced in a context: the price
+// book exists, exchange rates are available for the conversions needed and
+// there are tax rates for the ship-to address
+func ValidatePricingContext(model *Model, ctx PricingContext) error {
+	if _, err := resolvePricing(model, ctx); err != nil {
+		return err
+	}
+	if ctx.ShipTo != nil {
+		if _, err := tax.Calculate(ctx.TaxRates, *ctx.ShipTo, nil, nil, ctx.TaxDisplay); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolvePricing resolves a pricing context against a model
+func resolvePricing(model *Model, ctx PricingContext) (pricingSource, error) {
+	source := basePricing(model, ctx.Date)
+	if source.date.IsZero() {
+		source.date = time.Now()
+	}
+
+	if ctx.TermMonths < 0 {
+		return source, fmt.Errorf("contract term cannot be negative")
+	}
+	if ctx.TermMonths > 0 {
+		source.termMonths = ctx.TermMonths
+	}
+
+	if ctx.PriceBookID != "" {
+		book, err := model.GetPriceBook(ctx.PriceBookID)
+		if err != nil {
+			return source, err
+		}
+		source.book = book
+	} else {
+		source.book = model.DefaultPriceBook()
+	}
+
+	if source.book != nil {
+		source.currency = normalizeCurrency(source.book.Currency)
+	}
+	if ctx.Currency != "" {
+		source.currency = normalizeCurrency(ctx.Currency)
+	}
+
+	var err error
+	if source.book != nil {
+		source.bookRate, err = ctx.Rates.Rate(source.book.Currency, source.currency, source.date)
+		if err != nil {
+			return source, err
+		}
+	}
+	source.baseRate, err = ctx.Rates.Rate(model.BaseCurrency(), source.currency, source.date)
+	if err != nil {
+		return source, err
+	}
+
+	return source, nil
+}
+
+// basePricing prices from base prices in the model's currency over the
+// model's term
+func basePricing(model *Model, date time.Time) pricingSource {
+	term := model.TermMonths
+	if term <= 0 {
+		term = DefaultTermMonths
+	}
+	return pricingSource{date: date, currency: model.BaseCurrency(), bookRate: 1, baseRate: 1, termMonths: term}
+}
+
+// unitPrice returns an option's unit price in the source currency
+func (s pricingSource) unitPrice(option *Option) float64 {
+	if s.book != nil {
+		if price, ok := s.book.Price(option.ID, s.date); ok {
+			return price * s.bookRate
+		}
+	}
+	return option.BasePrice * s.baseRate
+}
+
+// cacheKey identifies the source in price cache keys
+func (s pricingSource) cacheKey() string {
+	book := ""
+	if s.book != nil {
+		book = s.book.ID
+	}
+	return fmt.Sprintf("%s|%s|%s|%g|%g|%d", book, s.date.Format("2006-01-02"), s.currency, s.bookRate, s.baseRate, s.termMonths)
+}
+
+// ===================================================================
+// MONEY FORMATTING
+// ===================================================================
+
+// currencySymbols are the currencies written with a symbol prefix
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+}
+
+// formatMoney formats an amount for adjustment descriptions, e.g. "$12.00"
+// or "12.00 CHF"
+func formatMoney(amount float64, currency string) string {
+	if symbol, ok := currencySymbols[currency]; ok {
+		return fmt.Sprintf("%s%.2f", symbol, amount)
+	}
+	return fmt.Sprintf("%.2f %s", amount, currency)
+}
+
+// ===================================================================
+// VALIDATION
+// ===================================================================
+
+// ValidatePriceBooks validates price books against a model
+func ValidatePriceBooks(model *Model, books []PriceBook) error {
+	ids := make(map[string]bool)
+	defaults := 0
+	for _, book := range books {
+		if book.ID == "" {
+			return fmt.Errorf("price book ID cannot be empty")
+		}
+		if ids[book.ID] {
+			return fmt.Errorf("duplicate price book %s", book.ID)
+		}
+		ids[book.ID] = true
+
+		if len(normalizeCurrency(book.Currency)) != 3 {
+			return fmt.Errorf("price book %s: currency must be a three-letter code", book.ID)
+		}
+		if book.IsDefault {
+			defaults++
+		}
+
+		for _, entry := range book.Entries {
+			if _, err := model.GetOption(entry.OptionID); err != nil {
+				return fmt.Errorf("price book %s references invalid option %s", book.ID, entry.OptionID)
+			}
+			if entry.Price < 0 {
+				return fmt.Errorf("price book %s: price of %s cannot be negative", book.ID, entry.OptionID)
+			}
+			if entry.EffectiveTo != nil && !entry.EffectiveTo.After(entry.EffectiveFrom) {
+				return fmt.Errorf("price book %s: entry for %s ends before it starts", book.ID, entry.OptionID)
+			}
+		}
+		if err := checkEntryOverlaps(book); err != nil {
+			return err
+		}
+	}
+
+	if defaults > 1 {
+		return fmt.Errorf("at most one price book can be the default")
+	}
+	return nil
+}
+
+// checkEntryOverlaps rejects entries for the same option starting on the
+// same date, which leave the price ambiguous
+func checkEntryOverlaps(book PriceBook) error {
+	entries := make([]PriceBookEntry, len(book.Entries))
+	copy(entries, book.Entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].OptionID != entries[j].OptionID {
+			return entries[i].OptionID < entries[j].OptionID
+		}
+		return entries[i].EffectiveFrom.Before(entries[j].EffectiveFrom)
+	})
+	for i := 1; i < len(entries); i++ {
+		if entries[i].OptionID == entries[i-1].OptionID && entries[i].EffectiveFrom.Equal(entries[i-1].EffectiveFrom) {
+			return fmt.Errorf("price book %s has two entries for %s effective from %s",
+				book.ID, entries[i].OptionID, entries[i].EffectiveFrom.Format("2006-01-02"))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package cpq
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func createTestModelWithPriceBooks() *Model {
+	model := createTestModelForPricing()
+	endOfYear := date(2025, time.January, 1)
+
+	model.PriceBooks = []PriceBook{
+		{
+			ID:        "list",
+			Name:      "List",
+			Currency:  "USD",
+			IsDefault: true,
+			Entries: []PriceBookEntry{
+				{OptionID: "opt1", Price: 90, EffectiveFrom: date(2024, time.January, 1), EffectiveTo: &endOfYear},
+				{OptionID: "opt1", Price: 110, EffectiveFrom: date(2025, time.January, 1)},
+			},
+		},
+		{
+			ID:       "emea",
+			Name:     "EMEA",
+			Currency: "EUR",
+			Entries: []PriceBookEntry{
+				{OptionID: "opt1", Price: 80, EffectiveFrom: date(2024, time.January, 1)},
+			},
+		},
+	}
+	return model
+}
+
+func TestPriceBook_Price(t *testing.T) {
+	model := createTestModelWithPriceBooks()
+	book, err := model.GetPriceBook("list")
+	if err != nil {
+		t.Fatalf("GetPriceBook failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		date  time.Time
+		price float64
+		found bool
+	}{
+		{"before first entry", date(2023, time.June, 1), 0, false},
+		{"first entry", date(2024, time.June, 1), 90, true},
+		{"end date is exclusive", date(2025, time.January, 1), 110, true},
+		{"open-ended entry", date(2030, time.June, 1), 110, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, found := book.Price("opt1", tt.date)
+			if found != tt.found || price != tt.price {
+				t.Errorf("Expected (%.2f, %v), got (%.2f, %v)", tt.price, tt.found, price, found)
+			}
+		})
+	}
+
+	if _, found := book.Price("opt2", date(2024, time.June, 1)); found {
+		t.Error("Option without entries should not be found")
+	}
+}
+
+func TestPricingCalculator_PriceBooks(t *testing.T) {
+	model := createTestModelWithPriceBooks()
+	calc := NewPricingCalculator(model)
+
+	selections := []Selection{
+		{OptionID: "opt1", Quantity: 1}, // Book price
+		{OptionID: "opt2", Quantity: 1}, // No entry, $50 base price
+	}
+
+	// Default book applies when the context names none
+	breakdown := calc.CalculatePrice(selections, PricingContext{Date: date(2024, time.June, 1)})
+	if breakdown.BasePrice != 140 {
+		t.Errorf("Expected base price 140.00, got %.2f", breakdown.BasePrice)
+	}
+	if breakdown.PriceBookID != "list" || breakdown.Currency != "USD" {
+		t.Errorf("Expected list book in USD, got %s in %s", breakdown.PriceBookID, breakdown.Currency)
+	}
+
+	// Later date picks up the newer entry
+	breakdown = calc.CalculatePrice(selections, PricingContext{Date: date(2025, time.June, 1)})
+	if breakdown.BasePrice != 160 {
+		t.Errorf("Expected base price 160.00, got %.2f", breakdown.BasePrice)
+	}
+
+	// EUR book converts base prices for options it does not list
+	rates := ExchangeRates{{FromCurrency: "EUR", ToCurrency: "USD", Rate: 1.25, EffectiveFrom: date(2024, time.January, 1)}}
+	breakdown = calc.CalculatePrice(selections, PricingContext{PriceBookID: "emea", Date: date(2024, time.June, 1), Rates: rates})
+	if breakdown.Currency != "EUR" {
+		t.Errorf("Expected EUR, got %s", breakdown.Currency)
+	}
+	if math.Abs(breakdown.BasePrice-120) > 0.001 { // 80 + 50/1.25
+		t.Errorf("Expected base price 120.00, got %.2f", breakdown.BasePrice)
+	}
+
+	// Same book reported in another currency
+	breakdown = calc.CalculatePrice(selections, PricingContext{PriceBookID: "emea", Currency: "USD", Date: date(2024, time.June, 1), Rates: rates})
+	if math.Abs(breakdown.BasePrice-150) > 0.001 { // 80*1.25 + 50
+		t.Errorf("Expected base price 150.00, got %.2f", breakdown.BasePrice)
+	}
+
+	// Unresolvable context falls back to base prices
+	breakdown = calc.CalculatePrice(selections, PricingContext{PriceBookID: "missing"})
+	if breakdown.BasePrice != 150 || breakdown.PriceBookID != "" {
+		t.Errorf("Expected base pricing fallback, got %.2f from %q", breakdown.BasePrice, breakdown.PriceBookID)
+	}
+}
+
+func TestExchangeRates_Rate(t *testing.T) {
+	rates := ExchangeRates{
+		{FromCurrency: "EUR", ToCurrency: "USD", Rate: 1.10, EffectiveFrom: date(2024, time.January, 1)},
+		{FromCurrency: "EUR", ToCurrency: "USD", Rate: 1.20, EffectiveFrom: date(2024, time.July, 1)},
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		date     time.Time
+		rate     float64
+		wantErr  bool
+	}{
+		{"same currency", "usd", "USD", date(2024, time.March, 1), 1, false},
+		{"direct rate", "EUR", "USD", date(2024, time.March, 1), 1.10, false},
+		{"latest effective rate", "EUR", "USD", date(2024, time.August, 1), 1.20, false},
+		{"inverse rate", "USD", "EUR", date(2024, time.August, 1), 1 / 1.20, false},
+		{"before any rate", "EUR", "USD", date(2023, time.March, 1), 0, true},
+		{"unknown pair", "GBP", "USD", date(2024, time.March, 1), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, err := rates.Rate(tt.from, tt.to, tt.date)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if math.Abs(rate-tt.rate) > 1e-9 {
+				t.Errorf("Expected rate %.4f, got %.4f", tt.rate, rate)
+			}
+		})
+	}
+}
+
+func TestValidatePricingContext(t *testing.T) {
+	model := createTestModelWithPriceBooks()
+
+	if err := ValidatePricingContext(model, PricingContext{}); err != nil {
+		t.Errorf("Default context should be valid: %v", err)
+	}
+	if err := ValidatePricingContext(model, PricingContext{PriceBookID: "missing"}); err == nil {
+		t.Error("Expected error for unknown price book")
+	}
+	if err := ValidatePricingContext(model, PricingContext{PriceBookID: "emea"}); err == nil {
+		t.Error("Expected error for missing EUR rate")
+	}
+	if err := ValidatePricingContext(model, PricingContext{Currency: "GBP"}); err == nil {
+		t.Error("Expected error for missing GBP rate")
+	}
+}
+
+func TestValidatePriceBooks(t *testing.T) {
+	model := createTestModelForPricing()
+	start := date(2024, time.January, 1)
+	before := date(2023, time.January, 1)
+
+	tests := []struct {
+		name    string
+		books   []PriceBook
+		wantErr bool
+	}{
+		{"valid", []PriceBook{{ID: "list", Currency: "usd", Entries: []PriceBookEntry{{OptionID: "opt1", Price: 10, EffectiveFrom: start}}}}, false},
+		{"empty ID", []PriceBook{{Currency: "USD"}}, true},
+		{"duplicate ID", []PriceBook{{ID: "list", Currency: "USD"}, {ID: "list", Currency: "EUR"}}, true},
+		{"bad currency", []PriceBook{{ID: "list", Currency: "dollars"}}, true},
+		{"two defaults", []PriceBook{{ID: "a", Currency: "USD", IsDefault: true}, {ID: "b", Currency: "EUR", IsDefault: true}}, true},
+		{"unknown option", []PriceBook{{ID: "list", Currency: "USD", Entries: []PriceBookEntry{{OptionID: "nope", EffectiveFrom: start}}}}, true},
+		{"negative price", []PriceBook{{ID: "list", Currency: "USD", Entries: []PriceBookEntry{{OptionID: "opt1", Price: -1, EffectiveFrom: start}}}}, true},
+		{"ends before start", []PriceBook{{ID: "list", Currency: "USD", Entries: []PriceBookEntry{{OptionID: "opt1", EffectiveFrom: start, EffectiveTo: &before}}}}, true},
+		{"same start date", []PriceBook{{ID: "list", Currency: "USD", Entries: []PriceBookEntry{
+			{OptionID: "opt1", Price: 10, EffectiveFrom: start},
+			{OptionID: "opt1", Price: 12, EffectiveFrom: start},
+		}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePriceBooks(model, tt.books)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConfigurator_SetPricingContext(t *testing.T) {
+	model := createTestModelWithPriceBooks()
+	configurator, err := NewConfigurator(model)
+	if err != nil {
+		t.Fatalf("Failed to create configurator: %v", err)
+	}
+
+	if err := configurator.SetPricingContext(PricingContext{PriceBookID: "missing"}); err == nil {
+		t.Error("Expected error for unknown price book")
+	}
+
+	rates := ExchangeRates{{FromCurrency: "USD", ToCurrency: "EUR", Rate: 0.8, EffectiveFrom: date(2024, time.January, 1)}}
+	ctx := PricingContext{PriceBookID: "emea", Date: date(2024, time.June, 1), Rates: rates}
+	if err := configurator.SetPricingContext(ctx); err != nil {
+		t.Fatalf("SetPricingContext failed: %v", err)
+	}
+	if got := configurator.GetPricingContext(); got.PriceBookID != "emea" {
+		t.Errorf("Expected emea context, got %q", got.PriceBookID)
+	}
+
+	if _, err := configurator.AddSelection("opt1", 1); err != nil {
+		t.Fatalf("AddSelection failed: %v", err)
+	}
+	breakdown := configurator.GetDetailedPrice()
+	if breakdown.Currency != "EUR" || breakdown.BasePrice != 80 {
+		t.Errorf("Expected EUR 80.00, got %s %.2f", breakdown.Currency, breakdown.BasePrice)
+	}
+}
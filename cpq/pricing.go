@@ -6,9 +6,14 @@
 package cpq
 
 import (
+	"DD/evaluator"
+	"DD/evaluator/vm"
+	"DD/mtbdd"
+	"DD/parser"
 	"fmt"
 	"math"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -24,6 +29,15 @@ type PricingCalculator struct {
 	cache       map[string]PriceBreakdown
 	mutex       sync.RWMutex
 	stats       PricingStats
+
+	// Compiled model definitions usable as price rule conditions
+	mtbdd       *mtbdd.MTBDD
+	definitions map[string]mtbdd.NodeRef
+
+	// Parsed definitions and compiled formulas for expression price rules
+	definitionExprs map[string]parser.Expression
+	programs        map[string]*vm.Program // Expression -> program, nil if invalid
+	machine         *vm.Machine
 }
 
 // PricingStats tracks calculator performance
@@ -34,27 +48,53 @@ type PricingStats struct {
 	CacheMisses       int64
 }
 
-// VolumeTierRule defines quantity-based pricing tiers
-type VolumeTier struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	MinQuantity int     `json:"min_quantity"`
-	MaxQuantity int     `json:"max_quantity"` // -1 for unlimited
-	Multiplier  float64 `json:"multiplier"`   // Price multiplier (0.9 = 10% discount)
-	Priority    int     `json:"priority"`     // Lower number = higher priority
-}
-
 // NewPricingCalculator creates a basic pricing calculator
 func NewPricingCalculator(model *Model) *PricingCalculator {
 	calc := &PricingCalculator{
 		model:       model,
-		volumeTiers: createDefaultVolumeTiers(),
+		volumeTiers: EffectiveVolumeTiers(model),
 		cache:       make(map[string]PriceBreakdown),
+		definitions: make(map[string]mtbdd.NodeRef),
+		programs:    make(map[string]*vm.Program),
+		machine:     vm.NewMachine(),
 	}
+	calc.compileDefinitions()
 
 	return calc
 }
 
+// compileDefinitions compiles model definitions so price rules can use them as
+// conditions. Invalid definitions are skipped; model validation reports them.
+func (pc *PricingCalculator) compileDefinitions() {
+	definitions, err := pc.model.ParseDefinitions()
+	if err != nil || len(definitions) == 0 {
+		return
+	}
+	pc.definitionExprs = definitions
+
+	pc.mtbdd = mtbdd.NewMTBDD()
+	context := mtbdd.NewCompilerContext(pc.mtbdd)
+	if err := context.Define(definitions); err != nil {
+		return
+	}
+	for id, body := range definitions {
+		if ref, err := mtbdd.CompileWithContext(body, context); err == nil {
+			pc.definitions[id] = ref
+		}
+	}
+}
+
+// EffectiveVolumeTiers returns the model's volume tiers, or the default tiers
+// when the model defines none
+func EffectiveVolumeTiers(model *Model) []VolumeTier {
+	if len(model.VolumeTiers) == 0 {
+		return createDefaultVolumeTiers()
+	}
+	tiers := make([]VolumeTier, len(model.VolumeTiers))
+	copy(tiers, model.VolumeTiers)
+	return tiers
+}
+
 // createDefaultVolumeTiers creates SMB-focused volume tiers
 func createDefaultVolumeTiers() []VolumeTier {
 	return []VolumeTier{
@@ -97,14 +137,22 @@ func createDefaultVolumeTiers() []VolumeTier {
 // CORE PRICING CALCULATION METHODS
 // ===================================================================
 
-// CalculatePrice computes total price for given selections
-func (pc *PricingCalculator) CalculatePrice(selections []Selection) PriceBreakdown {
+// CalculatePrice computes total price for given selections in a pricing
+// context. A context that cannot be resolved, such as one naming an unknown
+// price book, prices from base prices in the model's currency; check
+// contexts with ValidatePricingContext first.
+func (pc *PricingCalculator) CalculatePrice(selections []Selection, ctx PricingContext) PriceBreakdown {
 	startTime := time.Now()
 	pc.mutex.Lock()
 	defer pc.mutex.Unlock()
 
+	source, err := resolvePricing(pc.model, ctx)
+	if err != nil {
+		source = basePricing(pc.model, source.date)
+	}
+
 	// Generate cache key
-	cacheKey := pc.generateCacheKey(selections)
+	cacheKey := pc.generateCacheKey(selections) + source.cacheKey()
 
 	// Check cache first
 	if cached, exists := pc.cache[cacheKey]; exists {
@@ -116,16 +164,13 @@ func (pc *PricingCalculator) CalculatePrice(selections []Selection) PriceBreakdo
 	pc.stats.CacheMisses++
 
 	// Calculate base price
-	basePrice := pc.calculateBasePrice(selections)
+	basePrice := pc.calculateBasePrice(selections, source)
 
-	// Calculate total quantity
-	totalQuantity := pc.calculateTotalQuantity(selections)
-
-	// Apply volume tier adjustments
-	adjustments := pc.calculateVolumeAdjustments(basePrice, totalQuantity)
+	// Price each line and apply volume tier adjustments
+	lines, adjustments := pc.calculateVolumeAdjustments(selections, source)
 
 	// Apply price rule adjustments
-	ruleAdjustments := pc.calculatePriceRuleAdjustments(selections, basePrice)
+	ruleAdjustments, warnings := pc.calculatePriceRuleAdjustments(selections, basePrice, source)
 	adjustments = append(adjustments, ruleAdjustments...)
 
 	// Calculate final price
@@ -136,7 +181,17 @@ func (pc *PricingCalculator) CalculatePrice(selections []Selection) PriceBreakdo
 		Adjustments:     adjustments,
 		TotalPrice:      finalPrice,
 		CalculationTime: time.Since(startTime),
+		Lines:           lines,
+		Currency:        source.currency,
+		Charges:         pc.calculateCharges(selections, source),
+		TermMonths:      source.termMonths,
+		Warnings:        warnings,
+	}
+	if source.book != nil {
+		breakdown.PriceBookID = source.book.ID
 	}
+	breakdown.updateTotals()
+	breakdown = CheckMarginFloors(pc.model, breakdown)
 
 	// Cache result
 	pc.cache[cacheKey] = breakdown
@@ -149,8 +204,8 @@ func (pc *PricingCalculator) CalculatePrice(selections []Selection) PriceBreakdo
 	return breakdown
 }
 
-// calculateBasePrice sums up base prices for all selections
-func (pc *PricingCalculator) calculateBasePrice(selections []Selection) float64 {
+// calculateBasePrice sums up unit prices for all selections
+func (pc *PricingCalculator) calculateBasePrice(selections []Selection, source pricingSource) float64 {
 	var total float64
 
 	for _, selection := range selections {
@@ -163,53 +218,240 @@ func (pc *PricingCalculator) calculateBasePrice(selections []Selection) float64
 			continue // Skip invalid options
 		}
 
-		total += option.BasePrice * float64(selection.Quantity)
+		total += source.unitPrice(option) * float64(selection.Quantity)
 	}
 
 	return total
 }
 
-// calculateTotalQuantity sums all selected quantities
-func (pc *PricingCalculator) calculateTotalQuantity(selections []Selection) int {
-	var total int
+// calculateVolumeAdjustments prices each selection and applies volume tiers.
+// A line is priced by the tiers of its option if there are any, else by the
+// tiers of its group, else by the model-wide tiers; the quantity matched
+// against tiers is the total quantity of the lines sharing those tiers.
+func (pc *PricingCalculator) calculateVolumeAdjustments(selections []Selection, source pricingSource) ([]LinePrice, []PriceAdjustment) {
+	var lines []LinePrice
+	var scopes []string
+	members := make(map[string][]int) // Scope -> line indexes
+
 	for _, selection := range selections {
-		if selection.Quantity > 0 {
-			total += selection.Quantity
+		if selection.Quantity <= 0 {
+			continue
 		}
+		option, err := pc.model.GetOption(selection.OptionID)
+		if err != nil {
+			continue // Skip invalid options
+		}
+
+		scope := pc.tierScope(option)
+		if _, exists := members[scope]; !exists {
+			scopes = append(scopes, scope)
+		}
+		members[scope] = append(members[scope], len(lines))
+		unitPrice := source.unitPrice(option)
+		lines = append(lines, LinePrice{
+			OptionID:  option.ID,
+			Quantity:  selection.Quantity,
+			UnitPrice: unitPrice,
+			BasePrice: unitPrice * float64(selection.Quantity),
+			Cost:      option.Cost * source.baseRate * float64(selection.Quantity),
+		})
 	}
-	return total
-}
 
-// calculateVolumeAdjustments applies volume tier pricing
-func (pc *PricingCalculator) calculateVolumeAdjustments(basePrice float64, totalQuantity int) []PriceAdjustment {
 	var adjustments []PriceAdjustment
+	for _, scope := range scopes {
+		adjustments = append(adjustments, pc.applyVolumeTiers(scope, lines, members[scope])...)
+	}
 
-	// Find applicable volume tier
-	tier := pc.findVolumeTier(totalQuantity)
-	if tier == nil || tier.Multiplier == 1.0 {
-		return adjustments // No volume discount
+	for i := range lines {
+		lines[i].VolumeDiscount = math.Round(lines[i].VolumeDiscount*100) / 100
+		lines[i].NetPrice = math.Round((lines[i].BasePrice-lines[i].VolumeDiscount)*100) / 100
 	}
 
-	// Calculate discount amount
-	discountAmount := basePrice * (1.0 - tier.Multiplier)
+	return lines, adjustments
+}
 
-	adjustments = append(adjustments, PriceAdjustment{
-		RuleID:      tier.ID,
-		RuleName:    tier.Name,
-		Type:        "volume_discount",
-		Amount:      -discountAmount, // Negative for discount
-		Description: fmt.Sprintf("%s discount (%.0f%% off)", tier.Name, (1.0-tier.Multiplier)*100),
+// tierScope returns the scope of the tiers pricing an option: "option:<id>",
+// "group:<id>" or "" for the model-wide tiers
+func (pc *PricingCalculator) tierScope(option *Option) string {
+	scope := ""
+	for _, tier := range pc.volumeTiers {
+		switch {
+		case tier.OptionID != "" && tier.OptionID == option.ID:
+			return "option:" + option.ID
+		case tier.GroupID != "" && tier.GroupID == option.GroupID:
+			scope = "group:" + option.GroupID
+		}
+	}
+	return scope
+}
+
+// scope returns the scope a tier applies to, as returned by tierScope
+func (t VolumeTier) scope() string {
+	switch {
+	case t.OptionID != "":
+		return "option:" + t.OptionID
+	case t.GroupID != "":
+		return "group:" + t.GroupID
+	default:
+		return ""
+	}
+}
+
+// scopeTiers returns the tiers of a scope
+func (pc *PricingCalculator) scopeTiers(scope string) []VolumeTier {
+	var tiers []VolumeTier
+	for _, tier := range pc.volumeTiers {
+		if tier.scope() == scope {
+			tiers = append(tiers, tier)
+		}
+	}
+	return tiers
+}
+
+// scopeName names a scope in adjustment descriptions
+func (pc *PricingCalculator) scopeName(scope string) string {
+	kind, id, _ := strings.Cut(scope, ":")
+	switch kind {
+	case "option":
+		if option, err := pc.model.GetOption(id); err == nil {
+			return option.Name
+		}
+	case "group":
+		if group, err := pc.model.GetGroup(id); err == nil {
+			return group.Name
+		}
+	}
+	return id
+}
+
+// applyVolumeTiers applies a scope's tiers to its lines, allocating the
+// discounts to the lines in proportion to their base price
+func (pc *PricingCalculator) applyVolumeTiers(scope string, lines []LinePrice, indexes []int) []PriceAdjustment {
+	tiers := pc.scopeTiers(scope)
+	if len(tiers) == 0 {
+		return nil
+	}
+
+	var quantity int
+	var basePrice float64
+	for _, i := range indexes {
+		quantity += lines[i].Quantity
+		basePrice += lines[i].BasePrice
+	}
+	if quantity == 0 || basePrice == 0 {
+		return nil
+	}
+
+	adjustments, reached := volumeTierAdjustments(tiers, quantity, basePrice, func(tier *VolumeTier, units int) string {
+		return pc.tierDescription(tier, scope, units)
 	})
 
+	var discount float64
+	for _, adjustment := range adjustments {
+		discount -= adjustment.Amount
+	}
+	for _, i := range indexes {
+		lines[i].VolumeDiscount += discount * lines[i].BasePrice / basePrice
+		if reached != nil {
+			lines[i].TierID = reached.ID
+		}
+	}
+
 	return adjustments
 }
 
+// volumeTierAdjustments prices a quantity against one scope's tiers, all in
+// the same mode. It returns the tier discounts and the tier reached;
+// describe words an adjustment, with units set for incremental tiers.
+func volumeTierAdjustments(tiers []VolumeTier, quantity int, basePrice float64, describe func(tier *VolumeTier, units int) string) ([]PriceAdjustment, *VolumeTier) {
+	if tiers[0].Mode == IncrementalTiers {
+		return incrementalTierAdjustments(tiers, quantity, basePrice, describe)
+	}
+
+	reached := findVolumeTier(tiers, quantity)
+	if reached == nil || reached.Multiplier == 1.0 {
+		return nil, reached
+	}
+	return []PriceAdjustment{{
+		RuleID:      reached.ID,
+		RuleName:    reached.Name,
+		Type:        "volume_discount",
+		Amount:      -basePrice * (1.0 - reached.Multiplier), // Negative for discount
+		Description: describe(reached, 0),
+	}}, reached
+}
+
+// incrementalTierAdjustments prices the units within each tier at that
+// tier's multiplier, using the scope's average unit price. It returns one
+// adjustment per discounted tier and the highest tier reached.
+func incrementalTierAdjustments(tiers []VolumeTier, quantity int, basePrice float64, describe func(tier *VolumeTier, units int) string) ([]PriceAdjustment, *VolumeTier) {
+	sort.SliceStable(tiers, func(i, j int) bool {
+		return tiers[i].MinQuantity < tiers[j].MinQuantity
+	})
+	unitPrice := basePrice / float64(quantity)
+
+	var adjustments []PriceAdjustment
+	var reached *VolumeTier
+	for i := range tiers {
+		tier := &tiers[i]
+		if quantity < tier.MinQuantity {
+			break
+		}
+		reached = tier
+
+		upper := quantity
+		if tier.MaxQuantity != -1 && tier.MaxQuantity < upper {
+			upper = tier.MaxQuantity
+		}
+		units := upper - tier.MinQuantity + 1
+		if units <= 0 || tier.Multiplier == 1.0 {
+			continue
+		}
+
+		adjustments = append(adjustments, PriceAdjustment{
+			RuleID:      tier.ID,
+			RuleName:    tier.Name,
+			Type:        "volume_discount",
+			Amount:      -float64(units) * unitPrice * (1.0 - tier.Multiplier),
+			Description: describe(tier, units),
+		})
+	}
+
+	return adjustments, reached
+}
+
+// tierDescription describes a tier adjustment; units is set for incremental
+// tiers
+func (pc *PricingCalculator) tierDescription(tier *VolumeTier, scope string, units int) string {
+	target := ""
+	if scope != "" {
+		target = pc.scopeName(scope)
+	}
+	return describeTier(tier, target, units)
+}
+
+// describeTier describes a tier adjustment on a named target, or on
+// everything when target is empty
+func describeTier(tier *VolumeTier, target string, units int) string {
+	percent := (1.0 - tier.Multiplier) * 100
+	switch {
+	case units > 0 && target != "":
+		return fmt.Sprintf("%s discount on %d units of %s (%.0f%% off)", tier.Name, units, target, percent)
+	case units > 0:
+		return fmt.Sprintf("%s discount on %d units (%.0f%% off)", tier.Name, units, percent)
+	case target != "":
+		return fmt.Sprintf("%s discount on %s (%.0f%% off)", tier.Name, target, percent)
+	default:
+		return fmt.Sprintf("%s discount (%.0f%% off)", tier.Name, percent)
+	}
+}
+
 // findVolumeTier finds the appropriate volume tier for given quantity
-func (pc *PricingCalculator) findVolumeTier(quantity int) *VolumeTier {
+func findVolumeTier(tiers []VolumeTier, quantity int) *VolumeTier {
 	var applicableTier *VolumeTier
 
-	for i := range pc.volumeTiers {
-		tier := &pc.volumeTiers[i]
+	for i := range tiers {
+		tier := &tiers[i]
 
 		// Check if quantity falls within tier range
 		if quantity >= tier.MinQuantity {
@@ -225,9 +467,11 @@ func (pc *PricingCalculator) findVolumeTier(quantity int) *VolumeTier {
 	return applicableTier
 }
 
-// calculatePriceRuleAdjustments applies static price rules
-func (pc *PricingCalculator) calculatePriceRuleAdjustments(selections []Selection, basePrice float64) []PriceAdjustment {
+// calculatePriceRuleAdjustments applies static price rules, with a warning
+// for each rule that fails to evaluate
+func (pc *PricingCalculator) calculatePriceRuleAdjustments(selections []Selection, basePrice float64, source pricingSource) ([]PriceAdjustment, []string) {
 	var adjustments []PriceAdjustment
+	var warnings []string
 
 	// Sort price rules by priority
 	rules := make([]PriceRule, len(pc.model.PriceRules))
@@ -241,53 +485,58 @@ func (pc *PricingCalculator) calculatePriceRuleAdjustments(selections []Selectio
 			continue
 		}
 
-		adjustment := pc.evaluatePriceRule(rule, selections, basePrice)
+		adjustment, err := pc.evaluatePriceRule(rule, selections, basePrice, source)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("price rule %s not applied: %v", rule.ID, err))
+			continue
+		}
 		if adjustment != nil {
 			adjustments = append(adjustments, *adjustment)
 		}
 	}
 
-	return adjustments
+	return adjustments, warnings
 }
 
-// evaluatePriceRule evaluates a single price rule
-func (pc *PricingCalculator) evaluatePriceRule(rule PriceRule, selections []Selection, basePrice float64) *PriceAdjustment {
+// evaluatePriceRule evaluates a single price rule. Fixed amounts are in the
+// model's currency and converted to the pricing currency.
+func (pc *PricingCalculator) evaluatePriceRule(rule PriceRule, selections []Selection, basePrice float64, source pricingSource) (*PriceAdjustment, error) {
 	switch rule.Type {
 	case FixedDiscountRule:
-		return pc.evaluateFixedDiscount(rule, selections)
+		return pc.evaluateFixedDiscount(rule, selections, source), nil
 	case PercentDiscountRule:
-		return pc.evaluatePercentDiscount(rule, selections, basePrice)
+		return pc.evaluatePercentDiscount(rule, selections, basePrice), nil
 	case SurchargeRule:
-		return pc.evaluateSurcharge(rule, selections, basePrice)
+		return pc.evaluateSurcharge(rule, selections, source), nil
+	case ExpressionPriceRule:
+		return pc.evaluateExpressionRule(rule, selections, basePrice, source)
 	default:
-		// For complex expressions, use simple evaluation
-		return pc.evaluateExpressionRule(rule, selections, basePrice)
+		// Volume tiers come from the model's tier table, not price rules
+		return nil, nil
 	}
 }
 
 // evaluateFixedDiscount applies fixed amount discounts
-func (pc *PricingCalculator) evaluateFixedDiscount(rule PriceRule, selections []Selection) *PriceAdjustment {
+func (pc *PricingCalculator) evaluateFixedDiscount(rule PriceRule, selections []Selection, source pricingSource) *PriceAdjustment {
 	// Simple rule: if specific option selected, apply fixed discount
 	// Expression format: "opt1:10.0" (if opt1 selected, $10 discount)
+	// The condition may also name a model definition: "highend:10.0"
 
 	parts := pc.parseSimpleExpression(rule.Expression)
 	if len(parts) != 2 {
 		return nil
 	}
 
-	optionID := parts[0]
-	discountAmount := pc.parseFloat(parts[1])
+	condition := parts[0]
+	discountAmount := pc.parseFloat(parts[1]) * source.baseRate
 
-	// Check if option is selected
-	for _, selection := range selections {
-		if selection.OptionID == optionID && selection.Quantity > 0 {
-			return &PriceAdjustment{
-				RuleID:      rule.ID,
-				RuleName:    rule.Name,
-				Type:        "fixed_discount",
-				Amount:      -discountAmount,
-				Description: fmt.Sprintf("$%.2f discount for %s", discountAmount, optionID),
-			}
+	if pc.conditionHolds(condition, selections) {
+		return &PriceAdjustment{
+			RuleID:      rule.ID,
+			RuleName:    rule.Name,
+			Type:        "fixed_discount",
+			Amount:      -discountAmount,
+			Description: fmt.Sprintf("%s discount for %s", formatMoney(discountAmount, source.currency), condition),
 		}
 	}
 
@@ -303,20 +552,17 @@ func (pc *PricingCalculator) evaluatePercentDiscount(rule PriceRule, selections
 		return nil
 	}
 
-	optionID := parts[0]
+	condition := parts[0]
 	discountPercent := pc.parseFloat(parts[1])
 
-	// Check if option is selected
-	for _, selection := range selections {
-		if selection.OptionID == optionID && selection.Quantity > 0 {
-			discountAmount := basePrice * discountPercent
-			return &PriceAdjustment{
-				RuleID:      rule.ID,
-				RuleName:    rule.Name,
-				Type:        "percent_discount",
-				Amount:      -discountAmount,
-				Description: fmt.Sprintf("%.0f%% discount for %s", discountPercent*100, optionID),
-			}
+	if pc.conditionHolds(condition, selections) {
+		discountAmount := basePrice * discountPercent
+		return &PriceAdjustment{
+			RuleID:      rule.ID,
+			RuleName:    rule.Name,
+			Type:        "percent_discount",
+			Amount:      -discountAmount,
+			Description: fmt.Sprintf("%.0f%% discount for %s", discountPercent*100, condition),
 		}
 	}
 
@@ -324,7 +570,7 @@ func (pc *PricingCalculator) evaluatePercentDiscount(rule PriceRule, selections
 }
 
 // evaluateSurcharge applies additional charges
-func (pc *PricingCalculator) evaluateSurcharge(rule PriceRule, selections []Selection, basePrice float64) *PriceAdjustment {
+func (pc *PricingCalculator) evaluateSurcharge(rule PriceRule, selections []Selection, source pricingSource) *PriceAdjustment {
 	// Expression format: "opt1:25.0" (if opt1 selected, $25 surcharge)
 
 	parts := pc.parseSimpleExpression(rule.Expression)
@@ -332,32 +578,144 @@ func (pc *PricingCalculator) evaluateSurcharge(rule PriceRule, selections []Sele
 		return nil
 	}
 
-	optionID := parts[0]
-	surchargeAmount := pc.parseFloat(parts[1])
+	condition := parts[0]
+	surchargeAmount := pc.parseFloat(parts[1]) * source.baseRate
 
-	// Check if option is selected
-	for _, selection := range selections {
-		if selection.OptionID == optionID && selection.Quantity > 0 {
-			return &PriceAdjustment{
-				RuleID:      rule.ID,
-				RuleName:    rule.Name,
-				Type:        "surcharge",
-				Amount:      surchargeAmount,
-				Description: fmt.Sprintf("$%.2f surcharge for %s", surchargeAmount, optionID),
-			}
+	if pc.conditionHolds(condition, selections) {
+		return &PriceAdjustment{
+			RuleID:      rule.ID,
+			RuleName:    rule.Name,
+			Type:        "surcharge",
+			Amount:      surchargeAmount,
+			Description: fmt.Sprintf("%s surcharge for %s", formatMoney(surchargeAmount, source.currency), condition),
 		}
 	}
 
 	return nil
 }
 
-// evaluateExpressionRule handles complex expressions (simplified)
-func (pc *PricingCalculator) evaluateExpressionRule(rule PriceRule, selections []Selection, basePrice float64) *PriceAdjustment {
-	// This is a simplified implementation
-	// In a full system, you'd parse and evaluate the expression properly
+// conditionHolds checks a price rule condition, which is either an option ID
+// that must be selected or the ID of a model definition that must hold
+func (pc *PricingCalculator) conditionHolds(condition string, selections []Selection) bool {
+	if ref, isDefinition := pc.definitions[condition]; isDefinition {
+		assignments := make(map[string]bool, len(selections))
+		for _, selection := range selections {
+			if selection.Quantity > 0 {
+				assignments[selection.OptionID] = true
+			}
+		}
+		holds, _ := pc.mtbdd.Evaluate(ref, assignments).(bool)
+		return holds
+	}
+
+	for _, selection := range selections {
+		if selection.OptionID == condition && selection.Quantity > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateExpressionRule evaluates a price formula such as
+// `IF(cpu_i9 AND ram_64, -0.08 * base, 0)`. The result is the signed amount
+// added to the price; zero means the rule does not apply. Formulas are
+// compiled once and run on the VM. A formula that fails to compile or
+// evaluate returns an error, which pricing reports as a warning;
+// ValidatePriceRule rejects such formulas when the model is saved.
+func (pc *PricingCalculator) evaluateExpressionRule(rule PriceRule, selections []Selection, basePrice float64, source pricingSource) (*PriceAdjustment, error) {
+	program, compiled := pc.programs[rule.Expression]
+	if !compiled {
+		expr, err := parser.ParseFormula(rule.Expression)
+		if err == nil {
+			program, err = vm.Compile(expr)
+		}
+		pc.programs[rule.Expression] = program
+		if err != nil {
+			return nil, err
+		}
+	}
+	if program == nil {
+		return nil, fmt.Errorf("formula does not compile")
+	}
 
-	// For now, return nil (no adjustment)
-	return nil
+	result, err := pc.machine.Run(program, priceContext(pc.model, pc.definitionExprs, selections, basePrice, source.unitPrice))
+	if err != nil {
+		return nil, err
+	}
+	amount, isNumber := result.(float64)
+	if !isNumber || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return nil, fmt.Errorf("formula must compute an amount, got %v", result)
+	}
+	if amount == 0 {
+		return nil, nil
+	}
+	amount = math.Round(amount*100) / 100
+
+	description := rule.Description
+	if description == "" && amount < 0 {
+		description = fmt.Sprintf("%s discount from %s", formatMoney(-amount, source.currency), rule.Name)
+	} else if description == "" {
+		description = fmt.Sprintf("%s surcharge from %s", formatMoney(amount, source.currency), rule.Name)
+	}
+	return &PriceAdjustment{
+		RuleID:      rule.ID,
+		RuleName:    rule.Name,
+		Type:        string(ExpressionPriceRule),
+		Amount:      amount,
+		Description: description,
+	}, nil
+}
+
+// Variables available to price formulas besides option IDs and definitions
+const (
+	priceBaseVariable     = "base"           // Sum of quantity × base price
+	priceQuantityVariable = "total_quantity" // Sum of selected quantities
+	priceQuantitySuffix   = "_qty"           // <option>_qty: selected quantity, 0 if not selected
+	priceUnitSuffix       = "_price"         // <option>_price: the option's unit price
+)
+
+// priceContext binds the variables of price formulas: each option ID is
+// whether it is selected, with <option>_qty and <option>_price, plus base,
+// total_quantity and the values of the model's definitions. Prices come from
+// unitPrice, or are the options' base prices when it is nil.
+func priceContext(model *Model, definitions map[string]parser.Expression, selections []Selection, basePrice float64, unitPrice func(*Option) float64) evaluator.Context {
+	context := make(evaluator.Context, 3*len(model.Options)+len(definitions)+2)
+	for i := range model.Options {
+		option := &model.Options[i]
+		context[option.ID] = false
+		context[option.ID+priceQuantitySuffix] = 0.0
+		context[option.ID+priceUnitSuffix] = option.BasePrice
+		if unitPrice != nil {
+			context[option.ID+priceUnitSuffix] = unitPrice(option)
+		}
+	}
+
+	totalQuantity := 0.0
+	for _, selection := range selections {
+		if selection.Quantity <= 0 {
+			continue
+		}
+		totalQuantity += float64(selection.Quantity)
+		if _, known := context[selection.OptionID]; known {
+			context[selection.OptionID] = true
+			context[selection.OptionID+priceQuantitySuffix] = context[selection.OptionID+priceQuantitySuffix].(float64) + float64(selection.Quantity)
+		}
+	}
+	context[priceBaseVariable] = basePrice
+	context[priceQuantityVariable] = totalQuantity
+
+	// Definitions evaluate in dependency order; one that cannot be evaluated
+	// stays unbound so formulas using it fail
+	order, err := parser.DefinitionOrder(definitions)
+	if err != nil {
+		return context
+	}
+	for _, name := range order {
+		if value, err := evaluator.Evaluate(definitions[name], context); err == nil {
+			context[name] = value
+		}
+	}
+	return context
 }
 
 // ===================================================================
@@ -3,6 +3,7 @@ package cpq
 import (
 	"fmt"
 	"math"
+	"strings"
 	"testing"
 	"time"
 )
@@ -34,7 +35,7 @@ func TestPricingCalculator_CalculatePrice_BasePrice(t *testing.T) {
 		{OptionID: "opt2", Quantity: 2}, // $50 each = $100
 	}
 
-	breakdown := calc.CalculatePrice(selections)
+	breakdown := calc.CalculatePrice(selections, PricingContext{})
 
 	expectedBase := 200.0 // $100 + $100
 	if breakdown.BasePrice != expectedBase {
@@ -60,7 +61,7 @@ func TestPricingCalculator_CalculatePrice_VolumeTier(t *testing.T) {
 		{OptionID: "opt1", Quantity: 15}, // Triggers Small Volume tier (5% discount)
 	}
 
-	breakdown := calc.CalculatePrice(selections)
+	breakdown := calc.CalculatePrice(selections, PricingContext{})
 
 	expectedBase := 1500.0 // 15 * $100
 	if breakdown.BasePrice != expectedBase {
@@ -113,7 +114,7 @@ func TestPricingCalculator_VolumeTiers(t *testing.T) {
 				{OptionID: "opt1", Quantity: tc.quantity},
 			}
 
-			breakdown := calc.CalculatePrice(selections)
+			breakdown := calc.CalculatePrice(selections, PricingContext{})
 
 			basePrice := float64(tc.quantity) * 100.0
 			expectedPrice := basePrice * tc.expectedMultiplier
@@ -135,7 +136,7 @@ func TestPricingCalculator_PriceRules_FixedDiscount(t *testing.T) {
 		{OptionID: "opt1", Quantity: 1}, // Triggers $20 fixed discount
 	}
 
-	breakdown := calc.CalculatePrice(selections)
+	breakdown := calc.CalculatePrice(selections, PricingContext{})
 
 	// Should have fixed discount adjustment
 	foundDiscount := false
@@ -168,7 +169,7 @@ func TestPricingCalculator_PriceRules_PercentDiscount(t *testing.T) {
 		{OptionID: "opt2", Quantity: 1}, // Triggers 15% discount
 	}
 
-	breakdown := calc.CalculatePrice(selections)
+	breakdown := calc.CalculatePrice(selections, PricingContext{})
 
 	// Should have percent discount adjustment
 	foundDiscount := false
@@ -194,7 +195,7 @@ func TestPricingCalculator_PriceRules_Surcharge(t *testing.T) {
 		{OptionID: "opt3", Quantity: 1}, // Triggers $15 surcharge
 	}
 
-	breakdown := calc.CalculatePrice(selections)
+	breakdown := calc.CalculatePrice(selections, PricingContext{})
 
 	// Should have surcharge adjustment
 	foundSurcharge := false
@@ -215,6 +216,126 @@ func TestPricingCalculator_PriceRules_Surcharge(t *testing.T) {
 	}
 }
 
+func TestPricingCalculator_PriceRules_DefinitionCondition(t *testing.T) {
+	model := createTestModelForPricing()
+	model.AddDefinition(Definition{ID: "any_option", Expression: "opt1 OR opt2"})
+	model.AddPriceRule(PriceRule{
+		ID:         "definition_rule",
+		Name:       "Definition Discount Rule",
+		Type:       FixedDiscountRule,
+		Expression: "any_option:10.0", // $10 discount when the definition holds
+		IsActive:   true,
+		Priority:   1,
+	})
+	calc := NewPricingCalculator(model)
+
+	breakdown := calc.CalculatePrice([]Selection{{OptionID: "opt2", Quantity: 1}}, PricingContext{})
+
+	expectedTotal := 40.0 // $50 - $10
+	if math.Abs(breakdown.TotalPrice-expectedTotal) > 0.01 {
+		t.Errorf("Expected total price %.2f, got %.2f", expectedTotal, breakdown.TotalPrice)
+	}
+
+	breakdown = calc.CalculatePrice([]Selection{{OptionID: "opt2", Quantity: 0}}, PricingContext{})
+	for _, adj := range breakdown.Adjustments {
+		if adj.RuleID == "definition_rule" {
+			t.Error("Definition condition should not hold without selections")
+		}
+	}
+}
+
+func TestPricingCalculator_PriceRules_Expression(t *testing.T) {
+	model := createTestModelForPricing()
+	model.AddDefinition(Definition{ID: "bundle", Expression: "opt1 AND opt2"})
+	model.AddPriceRule(PriceRule{
+		ID:         "bundle_discount",
+		Name:       "Bundle Discount",
+		Type:       ExpressionPriceRule,
+		Expression: "IF(bundle, -0.08 * base, 0)",
+		IsActive:   true,
+		Priority:   1,
+	})
+	model.AddPriceRule(PriceRule{
+		ID:          "bulk_opt2",
+		Name:        "Option 2 Handling",
+		Description: "Handling for more than two units of Option 2",
+		Type:        ExpressionPriceRule,
+		Expression:  "MAX(opt2_qty - 2, 0) * opt2_price * 0.1",
+		IsActive:    true,
+		Priority:    2,
+	})
+	calc := NewPricingCalculator(model)
+
+	tests := []struct {
+		name        string
+		selections  []Selection
+		adjustments map[string]float64
+		total       float64
+	}{
+		{"no rule applies", []Selection{{OptionID: "opt1", Quantity: 1}}, map[string]float64{}, 100},
+		{"bundle", []Selection{{OptionID: "opt1", Quantity: 1}, {OptionID: "opt2", Quantity: 1}},
+			map[string]float64{"bundle_discount": -12}, 138},
+		{"bundle and quantity surcharge", []Selection{{OptionID: "opt1", Quantity: 1}, {OptionID: "opt2", Quantity: 4}},
+			map[string]float64{"bundle_discount": -24, "bulk_opt2": 10}, 286},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown := calc.CalculatePrice(tt.selections, PricingContext{})
+			found := make(map[string]float64)
+			for _, adj := range breakdown.Adjustments {
+				if adj.Type == string(ExpressionPriceRule) {
+					found[adj.RuleID] = adj.Amount
+				}
+			}
+			if len(found) != len(tt.adjustments) {
+				t.Errorf("Expected adjustments %v, got %v", tt.adjustments, found)
+			}
+			for ruleID, amount := range tt.adjustments {
+				if math.Abs(found[ruleID]-amount) > 0.001 {
+					t.Errorf("Expected %s adjustment %.2f, got %.2f", ruleID, amount, found[ruleID])
+				}
+			}
+			if math.Abs(breakdown.TotalPrice-tt.total) > 0.01 {
+				t.Errorf("Expected total price %.2f, got %.2f", tt.total, breakdown.TotalPrice)
+			}
+		})
+	}
+
+	breakdown := calc.CalculatePrice([]Selection{{OptionID: "opt1", Quantity: 1}, {OptionID: "opt2", Quantity: 1}}, PricingContext{})
+	for _, adj := range breakdown.Adjustments {
+		if adj.RuleID == "bundle_discount" && adj.Description != "$12.00 discount from Bundle Discount" {
+			t.Errorf("Unexpected description %q", adj.Description)
+		}
+	}
+}
+
+func TestPricingCalculator_PriceRules_ExpressionWarning(t *testing.T) {
+	model := createTestModelForPricing()
+	model.AddPriceRule(PriceRule{
+		ID:         "per_unit_credit",
+		Name:       "Per Unit Credit",
+		Type:       ExpressionPriceRule,
+		Expression: "-100 / opt2_qty",
+		IsActive:   true,
+		Priority:   1,
+	})
+	calc := NewPricingCalculator(model)
+
+	breakdown := calc.CalculatePrice([]Selection{{OptionID: "opt1", Quantity: 1}}, PricingContext{})
+	if len(breakdown.Warnings) != 1 || !strings.Contains(breakdown.Warnings[0], "per_unit_credit") {
+		t.Errorf("Expected a warning for per_unit_credit, got %v", breakdown.Warnings)
+	}
+	if math.Abs(breakdown.TotalPrice-100) > 0.01 {
+		t.Errorf("Expected total price 100.00, got %.2f", breakdown.TotalPrice)
+	}
+
+	breakdown = calc.CalculatePrice([]Selection{{OptionID: "opt1", Quantity: 1}, {OptionID: "opt2", Quantity: 2}}, PricingContext{})
+	if len(breakdown.Warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", breakdown.Warnings)
+	}
+}
+
 func TestPricingCalculator_Cache(t *testing.T) {
 	model := createTestModelForPricing()
 	calc := NewPricingCalculator(model)
@@ -224,7 +345,7 @@ func TestPricingCalculator_Cache(t *testing.T) {
 	}
 
 	// First calculation - cache miss
-	breakdown1 := calc.CalculatePrice(selections)
+	breakdown1 := calc.CalculatePrice(selections, PricingContext{})
 	stats1 := calc.GetStats()
 
 	if stats1.CacheMisses != 1 {
@@ -236,7 +357,7 @@ func TestPricingCalculator_Cache(t *testing.T) {
 	}
 
 	// Second calculation - cache hit
-	breakdown2 := calc.CalculatePrice(selections)
+	breakdown2 := calc.CalculatePrice(selections, PricingContext{})
 	stats2 := calc.GetStats()
 
 	if stats2.CacheHits != 1 {
@@ -266,7 +387,7 @@ func TestPricingCalculator_ClearCache(t *testing.T) {
 
 	// Generate some cache entries
 	selections := []Selection{{OptionID: "opt1", Quantity: 1}}
-	calc.CalculatePrice(selections)
+	calc.CalculatePrice(selections, PricingContext{})
 
 	if calc.GetCacheSize() == 0 {
 		t.Error("Expected cache to have entries")
@@ -312,7 +433,7 @@ func TestPricingCalculator_SetVolumeTiers(t *testing.T) {
 		{OptionID: "opt1", Quantity: 3},
 	}
 
-	breakdown := calc.CalculatePrice(selections)
+	breakdown := calc.CalculatePrice(selections, PricingContext{})
 	expectedPrice := 300.0 * 0.8 // 20% discount
 
 	if math.Abs(breakdown.TotalPrice-expectedPrice) > 0.01 {
@@ -320,6 +441,90 @@ func TestPricingCalculator_SetVolumeTiers(t *testing.T) {
 	}
 }
 
+func TestPricingCalculator_ModelVolumeTiers(t *testing.T) {
+	model := createTestModelForPricing()
+	model.AddGroup(Group{ID: "group2", Name: "Accessories", Type: MultiSelect})
+	model.AddOption(Option{ID: "opt3", Name: "Cable", GroupID: "group2", BasePrice: 10.0, IsActive: true})
+	model.AddOption(Option{ID: "opt4", Name: "Adapter", GroupID: "group2", BasePrice: 20.0, IsActive: true})
+	model.VolumeTiers = []VolumeTier{
+		{ID: "opt1_bulk", Name: "Bulk", OptionID: "opt1", MinQuantity: 5, MaxQuantity: -1, Multiplier: 0.8},
+		{ID: "accessory_bulk", Name: "Accessory Bulk", GroupID: "group2", MinQuantity: 10, MaxQuantity: -1, Multiplier: 0.9},
+	}
+
+	calc := NewPricingCalculator(model)
+	breakdown := calc.CalculatePrice([]Selection{
+		{OptionID: "opt1", Quantity: 5},  // $500, option tier reached
+		{OptionID: "opt2", Quantity: 20}, // $1000, no model-wide tiers
+		{OptionID: "opt3", Quantity: 6},  // $60, group quantity 10
+		{OptionID: "opt4", Quantity: 4},  // $80
+	}, PricingContext{})
+
+	if math.Abs(breakdown.TotalPrice-1526.0) > 0.01 {
+		t.Errorf("Expected total price 1526.00, got %.2f (adjustments %v)", breakdown.TotalPrice, breakdown.Adjustments)
+	}
+	if len(breakdown.Adjustments) != 2 {
+		t.Fatalf("Expected an option and a group adjustment, got %v", breakdown.Adjustments)
+	}
+	if breakdown.Adjustments[1].Description != "Accessory Bulk discount on Accessories (10% off)" {
+		t.Errorf("Unexpected description %q", breakdown.Adjustments[1].Description)
+	}
+
+	expected := map[string]struct {
+		discount float64
+		tierID   string
+	}{
+		"opt1": {100.0, "opt1_bulk"},
+		"opt2": {0, ""},
+		"opt3": {6.0, "accessory_bulk"},
+		"opt4": {8.0, "accessory_bulk"},
+	}
+	if len(breakdown.Lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d", len(expected), len(breakdown.Lines))
+	}
+	for _, line := range breakdown.Lines {
+		want := expected[line.OptionID]
+		if math.Abs(line.VolumeDiscount-want.discount) > 0.01 || line.TierID != want.tierID {
+			t.Errorf("%s: expected discount %.2f from %q, got %.2f from %q",
+				line.OptionID, want.discount, want.tierID, line.VolumeDiscount, line.TierID)
+		}
+		if math.Abs(line.NetPrice-(line.BasePrice-line.VolumeDiscount)) > 0.01 {
+			t.Errorf("%s: net price %.2f does not match base minus discount", line.OptionID, line.NetPrice)
+		}
+	}
+}
+
+func TestPricingCalculator_IncrementalVolumeTiers(t *testing.T) {
+	model := createTestModelForPricing()
+	model.VolumeTiers = []VolumeTier{
+		{ID: "first", Name: "First", OptionID: "opt1", Mode: IncrementalTiers, MinQuantity: 1, MaxQuantity: 10, Multiplier: 1.0},
+		{ID: "next", Name: "Next", OptionID: "opt1", Mode: IncrementalTiers, MinQuantity: 11, MaxQuantity: 20, Multiplier: 0.9},
+		{ID: "rest", Name: "Rest", OptionID: "opt1", Mode: IncrementalTiers, MinQuantity: 21, MaxQuantity: -1, Multiplier: 0.8},
+	}
+
+	calc := NewPricingCalculator(model)
+	breakdown := calc.CalculatePrice([]Selection{{OptionID: "opt1", Quantity: 25}}, PricingContext{})
+
+	// Units 11-20 save $10 each and units 21-25 save $20 each
+	if math.Abs(breakdown.TotalPrice-2300.0) > 0.01 {
+		t.Errorf("Expected total price 2300.00, got %.2f", breakdown.TotalPrice)
+	}
+	if len(breakdown.Adjustments) != 2 {
+		t.Fatalf("Expected one adjustment per discounted tier, got %v", breakdown.Adjustments)
+	}
+	if breakdown.Adjustments[1].Description != "Rest discount on 5 units of Option 1 (20% off)" {
+		t.Errorf("Unexpected description %q", breakdown.Adjustments[1].Description)
+	}
+	if line := breakdown.Lines[0]; line.TierID != "rest" || math.Abs(line.NetPrice-2300.0) > 0.01 {
+		t.Errorf("Expected line priced at 2300.00 in tier rest, got %+v", line)
+	}
+
+	// Below the first discounted tier nothing changes
+	breakdown = calc.CalculatePrice([]Selection{{OptionID: "opt1", Quantity: 10}}, PricingContext{})
+	if breakdown.TotalPrice != 1000.0 || len(breakdown.Adjustments) != 0 {
+		t.Errorf("Expected undiscounted 1000.00, got %.2f with %v", breakdown.TotalPrice, breakdown.Adjustments)
+	}
+}
+
 func TestPricingCalculator_Performance(t *testing.T) {
 	model := createLargeTestModelForPricing()
 	calc := NewPricingCalculator(model)
@@ -333,7 +538,7 @@ func TestPricingCalculator_Performance(t *testing.T) {
 	}
 
 	start := time.Now()
-	breakdown := calc.CalculatePrice(selections)
+	breakdown := calc.CalculatePrice(selections, PricingContext{})
 	elapsed := time.Since(start)
 
 	// Performance target: <200ms (same as constraints)
@@ -365,7 +570,7 @@ func TestPricingCalculator_ZeroQuantity(t *testing.T) {
 		{OptionID: "opt2", Quantity: 1},
 	}
 
-	breakdown := calc.CalculatePrice(selections)
+	breakdown := calc.CalculatePrice(selections, PricingContext{})
 
 	// Should only include opt2 ($50)
 	expectedPrice := 50.0
@@ -384,7 +589,7 @@ func TestPricingCalculator_InvalidOption(t *testing.T) {
 		{OptionID: "opt1", Quantity: 1},
 	}
 
-	breakdown := calc.CalculatePrice(selections)
+	breakdown := calc.CalculatePrice(selections, PricingContext{})
 
 	// Should only include valid option opt1 ($100)
 	expectedPrice := 100.0
@@ -402,7 +607,7 @@ func TestPricingCalculator_RulePriority(t *testing.T) {
 		{OptionID: "opt1", Quantity: 1},
 	}
 
-	breakdown := calc.CalculatePrice(selections)
+	breakdown := calc.CalculatePrice(selections, PricingContext{})
 
 	// Should apply both rules but in priority order
 	if len(breakdown.Adjustments) < 2 {
@@ -0,0 +1,454 @@
+// quote.go - Multi-line quotes
+// Quotes group configured products and plain SKUs with line and quote-level
+// discounts, priced by aggregating the breakdowns of their lines
+
+package cpq
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"DD/tax"
+)
+
+// QuoteStatus is a quote's place in its lifecycle
+type QuoteStatus string
+
+const (
+	QuoteDraft    QuoteStatus = "draft"    // Being edited
+	QuoteSent     QuoteStatus = "sent"     // Sent to the customer
+	QuoteAccepted QuoteStatus = "accepted" // Accepted by the customer
+	QuoteDeclined QuoteStatus = "declined" // Declined by the customer
+	QuoteExpired  QuoteStatus = "expired"  // Past its validity date before acceptance
+)
+
+// QuoteLineType distinguishes configured products from plain SKUs
+type QuoteLineType string
+
+const (
+	ConfiguredLine QuoteLineType = "configured" // Priced from a model configuration
+	ProductLine    QuoteLineType = "product"    // Plain SKU at a unit price
+)
+
+// Quote is a customer quote of one or more lines. Prices are in the quote
+// currency; lines are priced, then discounted by their line discount, then
+// by quote-level volume tiers over the units of every line and finally by
+// the quote discount.
+type Quote struct {
+	ID              string       `json:"id"`
+	Name            string       `json:"name"`
+	CustomerID      string       `json:"customer_id,omitempty"`
+	Currency        string       `json:"currency,omitempty"` // Empty uses the default currency
+	Status          QuoteStatus  `json:"status"`
+	ValidUntil      time.Time    `json:"valid_until"`
+	Lines           []QuoteLine  `json:"lines"`
+	DiscountPercent float64      `json:"discount_percent"`       // Quote-level, after line discounts and tiers
+	VolumeTiers     []VolumeTier `json:"volume_tiers,omitempty"` // Spanning the units of all lines
+	Notes           string       `json:"notes,omitempty"`
+	CreatedBy       string       `json:"created_by,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+
+	// Price book, date and ship-to address the quote is priced with; the
+	// quote currency overrides the context's
+	PricingContext PricingContext `json:"pricing_context"`
+
+	Pricing *QuotePricing `json:"pricing,omitempty"` // Computed by PriceQuote; kept from when the quote was sent
+}
+
+// QuoteLine is one line of a quote. A configured line keeps the model and
+// selections of the configuration it was added from, so later edits to the
+// configuration do not change the quote.
+type QuoteLine struct {
+	ID              string        `json:"id"`
+	Type            QuoteLineType `json:"type"`
+	Description     string        `json:"description,omitempty"`
+	Quantity        int           `json:"quantity"`
+	DiscountPercent float64       `json:"discount_percent"`
+
+	// Configured lines
+	ConfigurationID string          `json:"configuration_id,omitempty"`
+	ModelID         string          `json:"model_id,omitempty"`
+	Selections      []Selection     `json:"selections,omitempty"`
+	Children        []Configuration `json:"children,omitempty"` // Sub-assemblies, rolled up into the unit price
+
+	// Product lines, in the quote currency
+	SKU       string  `json:"sku,omitempty"`
+	UnitPrice float64 `json:"unit_price,omitempty"`
+	UnitCost  float64 `json:"unit_cost,omitempty"`
+}
+
+// QuoteLinePrice is the price of a quote line. Discounts are positive.
+type QuoteLinePrice struct {
+	LineID         string          `json:"line_id"`
+	Quantity       int             `json:"quantity"`
+	UnitPrice      float64         `json:"unit_price"` // One item, before line and quote discounts
+	ListPrice      float64         `json:"list_price"` // Quantity × unit price
+	LineDiscount   float64         `json:"line_discount"`
+	VolumeDiscount float64         `json:"volume_discount"` // Share of the quote tier discounts
+	QuoteDiscount  float64         `json:"quote_discount"`  // Share of the quote discount
+	NetPrice       float64         `json:"net_price"`
+	ContractValue  float64         `json:"contract_value"` // Net price plus charges over the term
+	Cost           float64         `json:"cost"`
+	Breakdown      *PriceBreakdown `json:"breakdown,omitempty"` // One configured item, after contract terms
+
+	// Margin floors of the line's model its net price falls below
+	MarginViolations []MarginViolation `json:"margin_violations,omitempty"`
+}
+
+// QuotePricing is the aggregated price of a quote
+type QuotePricing struct {
+	Currency           string            `json:"currency"`
+	Lines              []QuoteLinePrice  `json:"lines"`
+	TotalQuantity      int               `json:"total_quantity"`
+	Subtotal           float64           `json:"subtotal"`       // Sum of line list prices
+	LineDiscounts      float64           `json:"line_discounts"` // Positive
+	Adjustments        []PriceAdjustment `json:"adjustments"`    // Quote tiers and discount
+	TotalPrice         float64           `json:"total_price"`
+	OneTimeTotal       float64           `json:"one_time_total"`
+	MRR                float64           `json:"mrr"`
+	ARR                float64           `json:"arr"`
+	TotalContractValue float64           `json:"total_contract_value"`
+	TotalCost          float64           `json:"total_cost"`
+	Margin             float64           `json:"margin"`
+	MarginPercent      float64           `json:"margin_percent"`
+	RequiresApproval   bool              `json:"requires_approval"` // A line falls below a margin floor
+	Tax                *tax.Summary      `json:"tax,omitempty"`     // At the ship-to address, if any
+}
+
+// QuoteTerms are the customer terms a quote is priced with
+type QuoteTerms struct {
+	Contracts  []*Contract     // The contract active on the pricing date for a line's model applies to it
+	Exemptions []tax.Exemption // Tax exemptions of the customer
+}
+
+// NewQuote starts a draft quote valid for a number of days
+func NewQuote(id, name string, validDays int, now time.Time) *Quote {
+	return &Quote{
+		ID:         id,
+		Name:       name,
+		Status:     QuoteDraft,
+		ValidUntil: now.AddDate(0, 0, validDays),
+		Lines:      []QuoteLine{},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+// StatusAt returns the quote's status on a date: a draft or sent quote past
+// its validity date has expired
+func (q *Quote) StatusAt(now time.Time) QuoteStatus {
+	if (q.Status == QuoteDraft || q.Status == QuoteSent) && now.After(q.ValidUntil) {
+		return QuoteExpired
+	}
+	return q.Status
+}
+
+// Editable reports whether the quote's header and lines may be changed
+func (q *Quote) Editable() bool {
+	return q.Status == QuoteDraft
+}
+
+// quoteTransitions lists the statuses a quote may move to from each status
+var quoteTransitions = map[QuoteStatus][]QuoteStatus{
+	QuoteDraft:    {QuoteSent},
+	QuoteSent:     {QuoteAccepted, QuoteDeclined, QuoteDraft},
+	QuoteDeclined: {QuoteDraft},
+	QuoteExpired:  {QuoteDraft},
+}
+
+// Transition moves the quote to a new status. Expired quotes can only be
+// revised back to draft, and must be given a new validity date before they
+// are sent again.
+func (q *Quote) Transition(status QuoteStatus, now time.Time) error {
+	current := q.StatusAt(now)
+	for _, allowed := range quoteTransitions[current] {
+		if allowed != status {
+			continue
+		}
+		if status == QuoteSent && len(q.Lines) == 0 {
+			return fmt.Errorf("cannot send a quote without lines")
+		}
+		q.Status = status
+		q.UpdatedAt = now
+		return nil
+	}
+	return fmt.Errorf("cannot move a quote from %s to %s", current, status)
+}
+
+// GetLine returns a line by ID
+func (q *Quote) GetLine(lineID string) (*QuoteLine, error) {
+	for i := range q.Lines {
+		if q.Lines[i].ID == lineID {
+			return &q.Lines[i], nil
+		}
+	}
+	return nil, fmt.Errorf("quote line not found: %s", lineID)
+}
+
+// ModelIDs returns the models the quote's configured lines are priced from
+func (q *Quote) ModelIDs() []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, line := range q.Lines {
+		if line.Type == ConfiguredLine && !seen[line.ModelID] {
+			seen[line.ModelID] = true
+			ids = append(ids, line.ModelID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// PriceQuote prices a quote. Each configured line is priced from its model
+// in the quote currency, with its sub-assemblies rolled up, and the
+// customer's contract active on the pricing date applied to it; models holds
+// every model the quote references or its sub-assemblies are configured from
+// and ctx supplies the price book, date, exchange rates and ship-to address.
+// Line discounts apply to each line's net price; quote volume tiers and the
+// quote discount apply to the total and are shared across lines by net
+// price. Recurring and one-time charges of configured items are not
+// discounted. The discounts of a configured line are checked against its
+// model's margin floors, so a line discounted below a blocking floor fails
+// the quote. Tax is calculated last, when the context has a ship-to address.
+func PriceQuote(quote *Quote, models map[string]*Model, ctx PricingContext, terms QuoteTerms) (*QuotePricing, error) {
+	currency := DefaultCurrency
+	if quote.Currency != "" {
+		currency = normalizeCurrency(quote.Currency)
+	}
+	ctx.Currency = currency
+	date := ctx.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	pricing := &QuotePricing{
+		Currency:    currency,
+		Lines:       make([]QuoteLinePrice, len(quote.Lines)),
+		Adjustments: []PriceAdjustment{},
+	}
+	extras := make([]PriceBreakdown, len(quote.Lines)) // Charges of one configured item
+
+	for i, line := range quote.Lines {
+		price := QuoteLinePrice{LineID: line.ID, Quantity: line.Quantity, UnitPrice: line.UnitPrice}
+		quantity := float64(line.Quantity)
+
+		if line.Type == ConfiguredLine {
+			model, ok := models[line.ModelID]
+			if !ok {
+				return nil, fmt.Errorf("line %s: model %s not found", line.ID, line.ModelID)
+			}
+			if err := ValidatePricingContext(model, ctx); err != nil {
+				return nil, fmt.Errorf("line %s: %w", line.ID, err)
+			}
+			breakdown := NewPricingCalculator(model).CalculatePrice(line.Selections, ctx)
+			if len(line.Children) > 0 {
+				assembly, err := PriceAssembly(models, Configuration{ModelID: model.ID, Selections: line.Selections, Children: line.Children}, ctx)
+				if err != nil {
+					return nil, fmt.Errorf("line %s: %w", line.ID, err)
+				}
+				breakdown = assembly.Total
+			}
+			if contract := ActiveContract(terms.Contracts, model.ID, date); contract != nil {
+				var err error
+				if breakdown, err = ApplyContract(breakdown, contract); err != nil {
+					return nil, fmt.Errorf("line %s: %w", line.ID, err)
+				}
+			}
+			price.UnitPrice = breakdown.TotalPrice
+			price.Breakdown = &breakdown
+			extras[i] = breakdown
+		} else {
+			extras[i] = PriceBreakdown{TotalCost: line.UnitCost}
+		}
+
+		price.ListPrice = price.UnitPrice * quantity
+		price.LineDiscount = price.ListPrice * line.DiscountPercent / 100
+		price.NetPrice = price.ListPrice - price.LineDiscount
+		price.Cost = extras[i].TotalCost * quantity
+
+		pricing.Lines[i] = price
+		pricing.TotalQuantity += line.Quantity
+		pricing.Subtotal += price.ListPrice
+		pricing.LineDiscounts += price.LineDiscount
+		pricing.TotalPrice += price.NetPrice
+	}
+
+	// Quote volume tiers span the units of every line
+	if len(quote.VolumeTiers) > 0 && pricing.TotalQuantity > 0 && pricing.TotalPrice > 0 {
+		tiers := make([]VolumeTier, len(quote.VolumeTiers))
+		copy(tiers, quote.VolumeTiers)
+		adjustments, _ := volumeTierAdjustments(tiers, pricing.TotalQuantity, pricing.TotalPrice, func(tier *VolumeTier, units int) string {
+			return describeTier(tier, "quote", units)
+		})
+		pricing.shareAdjustments(adjustments, func(line *QuoteLinePrice, amount float64) {
+			line.VolumeDiscount -= amount
+		})
+	}
+
+	if quote.DiscountPercent > 0 && pricing.TotalPrice > 0 {
+		pricing.shareAdjustments([]PriceAdjustment{{
+			RuleID:      "quote_discount",
+			RuleName:    "Quote discount",
+			Type:        "discount",
+			Amount:      -pricing.TotalPrice * quote.DiscountPercent / 100,
+			Description: fmt.Sprintf("Quote discount: %.1f%%", quote.DiscountPercent),
+		}}, func(line *QuoteLinePrice, amount float64) {
+			line.QuoteDiscount -= amount
+		})
+	}
+
+	var taxLines []tax.Line
+	for i := range pricing.Lines {
+		line := &pricing.Lines[i]
+		extra := extras[i]
+		quantity := float64(line.Quantity)
+
+		if quote.Lines[i].Type == ConfiguredLine {
+			modelID := quote.Lines[i].ModelID
+			percent := 0.0
+			if line.ListPrice > 0 {
+				percent = math.Min((line.LineDiscount+line.VolumeDiscount+line.QuoteDiscount)/line.ListPrice*100, 100)
+			}
+			discounted, err := ApplyDiscount(models[modelID], extra, percent)
+			if err != nil {
+				return nil, fmt.Errorf("line %s: %w", line.LineID, err)
+			}
+			line.MarginViolations = discounted.MarginViolations
+			pricing.RequiresApproval = pricing.RequiresApproval || discounted.RequiresApproval
+
+			if ctx.ShipTo != nil {
+				category := assemblyTaxCategory(models, modelID)
+				for j, amount := range netShares(discounted) {
+					option := discounted.Lines[j]
+					taxLines = append(taxLines, tax.Line{
+						ID:       line.LineID + ":" + taxLineID(option.Path, option.OptionID),
+						Category: category(option.Path, option.OptionID),
+						Amount:   amount * quantity,
+					})
+				}
+				for _, charge := range discounted.Charges {
+					taxLines = append(taxLines, tax.Line{
+						ID:       line.LineID + ":" + taxLineID(charge.Path, charge.OptionID) + ":" + charge.ComponentID,
+						Category: category(charge.Path, charge.OptionID),
+						Amount:   charge.Amount * quantity,
+					})
+				}
+			}
+		} else if ctx.ShipTo != nil {
+			taxLines = append(taxLines, tax.Line{ID: line.LineID, Amount: line.NetPrice})
+		}
+
+		line.ContractValue = line.NetPrice + (extra.TotalContractValue-extra.TotalPrice)*quantity
+		pricing.OneTimeTotal += line.NetPrice + (extra.OneTimeTotal-extra.TotalPrice)*quantity
+		pricing.MRR += extra.MRR * quantity
+		pricing.TotalContractValue += line.ContractValue
+		pricing.TotalCost += line.Cost
+	}
+	pricing.ARR = pricing.MRR * 12
+	pricing.Margin = pricing.TotalContractValue - pricing.TotalCost
+	pricing.MarginPercent = marginPercent(pricing.TotalContractValue, pricing.TotalCost)
+
+	if ctx.ShipTo != nil {
+		summary, err := tax.Calculate(ctx.TaxRates, *ctx.ShipTo, taxLines, terms.Exemptions, ctx.TaxDisplay)
+		if err != nil {
+			return nil, err
+		}
+		pricing.Tax = summary
+	}
+
+	return pricing, nil
+}
+
+// shareAdjustments applies quote-level adjustments to the total, sharing
+// each across lines in proportion to their net prices
+func (p *QuotePricing) shareAdjustments(adjustments []PriceAdjustment, record func(line *QuoteLinePrice, amount float64)) {
+	for _, adjustment := range adjustments {
+		total := p.TotalPrice
+		for i := range p.Lines {
+			line := &p.Lines[i]
+			share := adjustment.Amount * line.NetPrice / total
+			record(line, share)
+			line.NetPrice += share
+		}
+		p.TotalPrice += adjustment.Amount
+		p.Adjustments = append(p.Adjustments, adjustment)
+	}
+}
+
+// ValidateQuote validates a quote's header and lines. Configured lines are
+// checked against their models separately, when they are added.
+func ValidateQuote(quote *Quote) error {
+	if quote.ID == "" {
+		return fmt.Errorf("quote ID cannot be empty")
+	}
+	if quote.Name == "" {
+		return fmt.Errorf("quote name cannot be empty")
+	}
+	if quote.ValidUntil.IsZero() {
+		return fmt.Errorf("quote must have a validity date")
+	}
+	if quote.DiscountPercent < 0 || quote.DiscountPercent > 100 {
+		return fmt.Errorf("quote discount must be between 0 and 100 percent")
+	}
+
+	for _, tier := range quote.VolumeTiers {
+		if tier.OptionID != "" || tier.GroupID != "" {
+			return fmt.Errorf("quote volume tier %s cannot apply to an option or group", tier.ID)
+		}
+	}
+	if err := ValidateVolumeTiers(&Model{}, quote.VolumeTiers); err != nil {
+		return err
+	}
+
+	ids := make(map[string]bool)
+	for _, line := range quote.Lines {
+		if err := ValidateQuoteLine(line); err != nil {
+			return err
+		}
+		if ids[line.ID] {
+			return fmt.Errorf("duplicate quote line %s", line.ID)
+		}
+		ids[line.ID] = true
+	}
+
+	return nil
+}
+
+// ValidateQuoteLine validates a quote line on its own
+func ValidateQuoteLine(line QuoteLine) error {
+	if line.ID == "" {
+		return fmt.Errorf("quote line ID cannot be empty")
+	}
+	if line.Quantity < 1 {
+		return fmt.Errorf("line %s: quantity must be at least 1", line.ID)
+	}
+	if line.DiscountPercent < 0 || line.DiscountPercent > 100 {
+		return fmt.Errorf("line %s: discount must be between 0 and 100 percent", line.ID)
+	}
+
+	switch line.Type {
+	case ConfiguredLine:
+		if line.ModelID == "" {
+			return fmt.Errorf("line %s: configured lines must name a model", line.ID)
+		}
+		for _, selection := range line.Selections {
+			if selection.Quantity < 0 {
+				return fmt.Errorf("line %s: selection %s has a negative quantity", line.ID, selection.OptionID)
+			}
+		}
+	case ProductLine:
+		if line.SKU == "" {
+			return fmt.Errorf("line %s: product lines must have a SKU", line.ID)
+		}
+		if line.UnitPrice < 0 || line.UnitCost < 0 {
+			return fmt.Errorf("line %s: unit price and cost cannot be negative", line.ID)
+		}
+	default:
+		return fmt.Errorf("line %s: unknown line type %q", line.ID, line.Type)
+	}
+
+	return nil
+}
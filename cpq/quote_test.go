@@ -0,0 +1,269 @@
+package cpq
+
+import (
+	"DD/tax"
+	"math"
+	"testing"
+	"time"
+)
+
+func createTestQuote() (*Quote, map[string]*Model) {
+	model := createTestModelWithCosts()
+	quote := NewQuote("q1", "Test Quote", 30, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	quote.Lines = []QuoteLine{
+		{ID: "l1", Type: ConfiguredLine, ModelID: model.ID, Selections: []Selection{{OptionID: "opt1", Quantity: 1}}, Quantity: 2},
+		{ID: "l2", Type: ProductLine, SKU: "INSTALL", UnitPrice: 50, UnitCost: 20, Quantity: 4},
+	}
+	return quote, map[string]*Model{model.ID: model}
+}
+
+func TestPriceQuote(t *testing.T) {
+	quote, models := createTestQuote()
+	quote.Lines[0].DiscountPercent = 10
+	quote.DiscountPercent = 5
+
+	pricing, err := PriceQuote(quote, models, PricingContext{}, QuoteTerms{})
+	if err != nil {
+		t.Fatalf("PriceQuote failed: %v", err)
+	}
+
+	// 2 × 100 less 10% = 180, plus 4 × 50 = 200, less 5% of 380
+	if pricing.Subtotal != 400 || pricing.LineDiscounts != 20 {
+		t.Errorf("Expected subtotal 400.00 and line discounts 20.00, got %.2f and %.2f", pricing.Subtotal, pricing.LineDiscounts)
+	}
+	if math.Abs(pricing.TotalPrice-361) > 0.001 {
+		t.Errorf("Expected total 361.00, got %.2f", pricing.TotalPrice)
+	}
+	if pricing.TotalQuantity != 6 || len(pricing.Adjustments) != 1 {
+		t.Errorf("Expected 6 units and one quote adjustment, got %d and %v", pricing.TotalQuantity, pricing.Adjustments)
+	}
+
+	line := pricing.Lines[0]
+	if line.UnitPrice != 100 || line.Breakdown == nil || math.Abs(line.QuoteDiscount-9) > 0.001 || math.Abs(line.NetPrice-171) > 0.001 {
+		t.Errorf("Expected configured line at 100.00 less 9.00 quote discount, got %+v", line)
+	}
+
+	// Cost 2 × 60 + 4 × 20
+	if pricing.TotalCost != 200 || math.Abs(pricing.Margin-161) > 0.001 {
+		t.Errorf("Expected cost 200.00 and margin 161.00, got %.2f and %.2f", pricing.TotalCost, pricing.Margin)
+	}
+}
+
+func TestPriceQuote_VolumeTiersSpanLines(t *testing.T) {
+	quote, models := createTestQuote()
+	quote.VolumeTiers = []VolumeTier{
+		{ID: "t1", Name: "Small", MinQuantity: 1, MaxQuantity: 4, Multiplier: 1.0},
+		{ID: "t2", Name: "Bulk", MinQuantity: 5, MaxQuantity: -1, Multiplier: 0.9},
+	}
+
+	pricing, err := PriceQuote(quote, models, PricingContext{}, QuoteTerms{})
+	if err != nil {
+		t.Fatalf("PriceQuote failed: %v", err)
+	}
+
+	// Neither line reaches 5 units, but together they have 6
+	if math.Abs(pricing.TotalPrice-360) > 0.001 {
+		t.Errorf("Expected total 360.00 after bulk discount, got %.2f", pricing.TotalPrice)
+	}
+	if math.Abs(pricing.Lines[0].VolumeDiscount-20) > 0.001 || math.Abs(pricing.Lines[1].VolumeDiscount-20) > 0.001 {
+		t.Errorf("Expected the tier discount shared by net price, got %.2f and %.2f",
+			pricing.Lines[0].VolumeDiscount, pricing.Lines[1].VolumeDiscount)
+	}
+}
+
+func TestPriceQuote_Charges(t *testing.T) {
+	quote, models := createTestQuote()
+	models["pricing-test"].Options[0].Components = []PriceComponent{
+		{ID: "support", Name: "Support", ChargeType: RecurringCharge, Price: 10, Period: MonthlyPeriod},
+	}
+	quote.DiscountPercent = 50
+
+	pricing, err := PriceQuote(quote, models, PricingContext{}, QuoteTerms{})
+	if err != nil {
+		t.Fatalf("PriceQuote failed: %v", err)
+	}
+
+	// Recurring charges scale by quantity and are not discounted
+	if pricing.MRR != 20 || pricing.ARR != 240 {
+		t.Errorf("Expected MRR 20.00 and ARR 240.00, got %.2f and %.2f", pricing.MRR, pricing.ARR)
+	}
+	if pricing.TotalContractValue <= pricing.TotalPrice {
+		t.Errorf("Expected contract value above total price, got %.2f", pricing.TotalContractValue)
+	}
+}
+
+func TestPriceQuote_Contract(t *testing.T) {
+	quote, models := createTestQuote()
+	expiry := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	terms := QuoteTerms{Contracts: []*Contract{
+		{ID: "old", DiscountPercent: 50, EffectiveFrom: expiry.AddDate(-1, 0, 0), ExpiresAt: &expiry},
+		{ID: "new", DiscountPercent: 20, EffectiveFrom: expiry},
+	}}
+
+	tests := []struct {
+		name string
+		date time.Time
+		unit float64
+	}{
+		{"current contract", expiry.AddDate(0, 1, 0), 80},
+		{"expired contract on an earlier date", expiry.AddDate(0, -1, 0), 50},
+		{"no contract before either", expiry.AddDate(-2, 0, 0), 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pricing, err := PriceQuote(quote, models, PricingContext{Date: tt.date}, terms)
+			if err != nil {
+				t.Fatalf("PriceQuote failed: %v", err)
+			}
+			line := pricing.Lines[0]
+			if line.UnitPrice != tt.unit || line.NetPrice != 2*tt.unit {
+				t.Errorf("Expected unit price %.2f, got %+v", tt.unit, line)
+			}
+			// Product lines are not covered by contracts
+			if pricing.Lines[1].NetPrice != 200 {
+				t.Errorf("Expected product line at 200.00, got %.2f", pricing.Lines[1].NetPrice)
+			}
+		})
+	}
+}
+
+func TestPriceQuote_MarginFloors(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   MarginFloorAction
+		line     float64
+		quote    float64
+		wantErr  bool
+		approval bool
+	}{
+		{"discount within floor", BlockBelowFloor, 10, 0, false, false}, // 33.3% margin
+		{"discount below approval floor is flagged", ApproveBelowFloor, 20, 0, false, true},
+		{"discount below blocking floor is rejected", BlockBelowFloor, 20, 0, true, false},
+		{"quote discount counts against the floor", BlockBelowFloor, 10, 12, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, models := createTestQuote()
+			models["pricing-test"].MarginFloors = []MarginFloor{{ID: "floor", MinMarginPercent: 30, Action: tt.action}}
+			quote.Lines[0].DiscountPercent = tt.line
+			quote.DiscountPercent = tt.quote
+
+			pricing, err := PriceQuote(quote, models, PricingContext{}, QuoteTerms{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if pricing.RequiresApproval != tt.approval || (len(pricing.Lines[0].MarginViolations) > 0) != tt.approval {
+				t.Errorf("Expected requires approval %v, got %v with %v",
+					tt.approval, pricing.RequiresApproval, pricing.Lines[0].MarginViolations)
+			}
+		})
+	}
+}
+
+func TestPriceQuote_Tax(t *testing.T) {
+	quote, models := createTestQuote()
+	quote.DiscountPercent = 10
+	ctx := createTestTaxContext(t)
+
+	pricing, err := PriceQuote(quote, models, ctx, QuoteTerms{})
+	if err != nil {
+		t.Fatalf("PriceQuote failed: %v", err)
+	}
+
+	// Both lines are taxed after the quote discount: 180.00 each at 12%
+	if pricing.Tax == nil || pricing.Tax.Net != 360 || math.Abs(pricing.Tax.Tax-43.2) > 0.001 {
+		t.Fatalf("Expected tax 43.20 on 360.00, got %+v", pricing.Tax)
+	}
+	if pricing.TotalPrice != 360 {
+		t.Errorf("Tax should not change the pre-tax total, got %.2f", pricing.TotalPrice)
+	}
+
+	exempt, err := PriceQuote(quote, models, ctx, QuoteTerms{Exemptions: []tax.Exemption{{Country: "CA", Region: "BC"}}})
+	if err != nil {
+		t.Fatalf("PriceQuote failed: %v", err)
+	}
+	if math.Abs(exempt.Tax.Tax-18) > 0.001 {
+		t.Errorf("Expected only GST of 18.00 for a BC exemption, got %.2f", exempt.Tax.Tax)
+	}
+
+	pretax, err := PriceQuote(quote, models, PricingContext{}, QuoteTerms{})
+	if err != nil || pretax.Tax != nil {
+		t.Errorf("Expected a pre-tax quote without a ship-to address, got %+v (%v)", pretax.Tax, err)
+	}
+}
+
+func TestPriceQuote_MissingModel(t *testing.T) {
+	quote, _ := createTestQuote()
+	if _, err := PriceQuote(quote, map[string]*Model{}, PricingContext{}, QuoteTerms{}); err == nil {
+		t.Error("Expected error for unknown model")
+	}
+}
+
+func TestQuote_Transition(t *testing.T) {
+	quote, _ := createTestQuote()
+	now := quote.CreatedAt.AddDate(0, 0, 1)
+
+	if err := quote.Transition(QuoteAccepted, now); err == nil {
+		t.Error("Draft quote should not be accepted before it is sent")
+	}
+	if err := quote.Transition(QuoteSent, now); err != nil {
+		t.Fatalf("Expected send to succeed: %v", err)
+	}
+	if quote.Editable() {
+		t.Error("Sent quote should not be editable")
+	}
+
+	later := quote.ValidUntil.AddDate(0, 0, 1)
+	if quote.StatusAt(later) != QuoteExpired {
+		t.Errorf("Expected quote to expire, got %s", quote.StatusAt(later))
+	}
+	if err := quote.Transition(QuoteAccepted, later); err == nil {
+		t.Error("Expired quote should not be accepted")
+	}
+	if err := quote.Transition(QuoteDraft, later); err != nil {
+		t.Errorf("Expected expired quote to be revised: %v", err)
+	}
+
+	empty := NewQuote("q2", "Empty", 30, now)
+	if err := empty.Transition(QuoteSent, now); err == nil {
+		t.Error("Quote without lines should not be sent")
+	}
+}
+
+func TestValidateQuote(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(q *Quote)
+	}{
+		{"missing validity date", func(q *Quote) { q.ValidUntil = time.Time{} }},
+		{"quote discount over 100", func(q *Quote) { q.DiscountPercent = 120 }},
+		{"duplicate line", func(q *Quote) { q.Lines[1].ID = "l1" }},
+		{"zero quantity", func(q *Quote) { q.Lines[0].Quantity = 0 }},
+		{"product without SKU", func(q *Quote) { q.Lines[1].SKU = "" }},
+		{"configured without model", func(q *Quote) { q.Lines[0].ModelID = "" }},
+		{"unknown line type", func(q *Quote) { q.Lines[0].Type = "bundle" }},
+		{"option tier", func(q *Quote) {
+			q.VolumeTiers = []VolumeTier{{ID: "t", OptionID: "opt1", MinQuantity: 1, MaxQuantity: -1, Multiplier: 0.9}}
+		}},
+	}
+
+	quote, _ := createTestQuote()
+	if err := ValidateQuote(quote); err != nil {
+		t.Fatalf("Expected valid quote, got %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, _ := createTestQuote()
+			tt.modify(quote)
+			if err := ValidateQuote(quote); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
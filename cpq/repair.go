@@ -0,0 +1,270 @@
+// repair.go - Nearest valid configuration search
+// Finds the cheapest change sets that turn invalid selections into a valid
+// configuration by searching the combined constraint MTBDD
+
+package cpq
+
+import (
+	"DD/i18n"
+	"DD/mtbdd"
+	"math"
+	"sort"
+)
+
+// ===================================================================
+// REPAIR TYPES
+// ===================================================================
+
+// RepairOptions controls the search for nearby valid configurations
+type RepairOptions struct {
+	MaxResults  int                `json:"max_results"`  // Change sets to return, default 3
+	Pinned      []string           `json:"pinned"`       // Options whose selection must not change
+	Weights     map[string]float64 `json:"weights"`      // Cost of changing an option, default 1
+	PriceWeight float64            `json:"price_weight"` // Extra cost per unit of base price added
+	Pricing     PricingContext     `json:"-"`            // Context repairs are priced in
+}
+
+// RepairChange adds or removes one option
+type RepairChange struct {
+	Action     string `json:"action"` // "add" or "remove"
+	OptionID   string `json:"option_id"`
+	OptionName string `json:"option_name"`
+}
+
+// Repair is a concrete change set that makes the configuration valid
+type Repair struct {
+	Changes     []RepairChange `json:"changes"`
+	Description string         `json:"description"` // e.g. "Add Docking Station, remove Travel Kit"
+	Distance    int            `json:"distance"`    // Number of options changed
+	Cost        float64        `json:"cost"`        // Weighted cost used for ranking
+	Selections  []Selection    `json:"selections"`  // Selections after the repair
+	NewPrice    float64        `json:"new_price"`
+	PriceDelta  float64        `json:"price_delta"`
+}
+
+// Localize renders the description in the localizer's language
+func (r *Repair) Localize(localizer *i18n.Localizer) {
+	r.Description = describeRepair(localizer, r.Changes)
+}
+
+// ===================================================================
+// REPAIR ENGINE
+// ===================================================================
+
+// RepairEngine suggests minimal changes using the constraint engine's
+// combined rule MTBDD and prices them with the pricing calculator
+type RepairEngine struct {
+	model       *Model
+	constraints *ConstraintEngine
+	pricing     *PricingCalculator
+}
+
+// NewRepairEngine creates a repair engine
+func NewRepairEngine(model *Model, constraints *ConstraintEngine, pricing *PricingCalculator) *RepairEngine {
+	return &RepairEngine{
+		model:       model,
+		constraints: constraints,
+		pricing:     pricing,
+	}
+}
+
+// repairPath is a partial path from an MTBDD node to the TRUE terminal
+type repairPath struct {
+	cost  float64
+	flips []RepairChange // In variable order
+	key   string         // Identifies the change set for deduplication
+}
+
+// SuggestRepairs returns up to MaxResults change sets that make the
+// selections valid, nearest first. Each path to TRUE in the combined
+// constraint MTBDD is one family of valid configurations; variables the
+// path does not test keep their current value, so the cheapest member of
+// every family is found with a k-best shortest path search over the DAG.
+// Returns nil when the selections are already valid.
+func (re *RepairEngine) SuggestRepairs(selections []Selection, options RepairOptions) []Repair {
+	limit := options.MaxResults
+	if limit <= 0 {
+		limit = 3
+	}
+
+	current := make(map[string]bool)
+	for _, selection := range selections {
+		if selection.Quantity > 0 {
+			current[selection.OptionID] = true
+		}
+	}
+	pinned := make(map[string]bool)
+	for _, optionID := range options.Pinned {
+		pinned[optionID] = true
+	}
+
+	// changeCost is the cost of giving a variable a value; group count
+	// variables follow the options and are free
+	changeCost := func(variable string, value bool) float64 {
+		option, err := re.model.GetOption(variable)
+		if err != nil || current[variable] == value {
+			return 0
+		}
+		if pinned[variable] {
+			return math.Inf(1)
+		}
+		cost := 1.0
+		if weight, ok := options.Weights[variable]; ok {
+			cost = weight
+		}
+		if value {
+			cost += options.PriceWeight * math.Max(0, option.BasePrice)
+		}
+		return cost
+	}
+
+	re.constraints.mutex.RLock()
+	manager := re.constraints.mtbdd
+	root := re.constraints.allConstraintsBDD
+	re.constraints.mutex.RUnlock()
+
+	memo := make(map[mtbdd.NodeRef][]repairPath)
+	var best func(ref mtbdd.NodeRef) []repairPath
+	best = func(ref mtbdd.NodeRef) []repairPath {
+		if paths, done := memo[ref]; done {
+			return paths
+		}
+		node, terminal, exists := manager.GetNode(ref)
+		var paths []repairPath
+		switch {
+		case !exists:
+		case terminal != nil:
+			if valid, ok := terminal.Value.(bool); ok && valid {
+				paths = []repairPath{{}}
+			}
+		default:
+			var candidates []repairPath
+			for _, branch := range []struct {
+				value bool
+				child mtbdd.NodeRef
+			}{{false, node.Low}, {true, node.High}} {
+				cost := changeCost(node.Variable, branch.value)
+				if math.IsInf(cost, 1) {
+					continue
+				}
+				var flip []RepairChange
+				if _, err := re.model.GetOption(node.Variable); err == nil && current[node.Variable] != branch.value {
+					flip = []RepairChange{re.change(node.Variable, branch.value)}
+				}
+				for _, path := range best(branch.child) {
+					candidates = append(candidates, extendRepairPath(path, cost, flip))
+				}
+			}
+			paths = bestRepairPaths(candidates, limit)
+		}
+		memo[ref] = paths
+		return paths
+	}
+
+	paths := best(root)
+	if len(paths) > 0 && len(paths[0].flips) == 0 {
+		return nil
+	}
+
+	currentPrice := re.pricing.CalculatePrice(selections, options.Pricing).TotalPrice
+	repairs := make([]Repair, 0, len(paths))
+	for _, path := range paths {
+		repaired := applyRepair(selections, path.flips)
+		newPrice := re.pricing.CalculatePrice(repaired, options.Pricing).TotalPrice
+		repairs = append(repairs, Repair{
+			Changes:     path.flips,
+			Description: describeRepair(i18n.English(), path.flips),
+			Distance:    len(path.flips),
+			Cost:        path.cost,
+			Selections:  repaired,
+			NewPrice:    newPrice,
+			PriceDelta:  newPrice - currentPrice,
+		})
+	}
+
+	// Equal cost repairs prefer the cheaper price
+	sort.SliceStable(repairs, func(i, j int) bool {
+		if repairs[i].Cost != repairs[j].Cost {
+			return repairs[i].Cost < repairs[j].Cost
+		}
+		return repairs[i].PriceDelta < repairs[j].PriceDelta
+	})
+	return repairs
+}
+
+func (re *RepairEngine) change(optionID string, selected bool) RepairChange {
+	change := RepairChange{Action: "remove", OptionID: optionID, OptionName: optionID}
+	if selected {
+		change.Action = "add"
+	}
+	if option, err := re.model.GetOption(optionID); err == nil && option.Name != "" {
+		change.OptionName = option.Name
+	}
+	return change
+}
+
+// extendRepairPath prepends an edge to a path
+func extendRepairPath(path repairPath, cost float64, flip []RepairChange) repairPath {
+	if len(flip) == 0 {
+		return repairPath{cost: path.cost + cost, flips: path.flips, key: path.key}
+	}
+	flips := make([]RepairChange, 0, len(path.flips)+1)
+	flips = append(append(flips, flip[0]), path.flips...)
+	return repairPath{
+		cost:  path.cost + cost,
+		flips: flips,
+		key:   flip[0].Action + " " + flip[0].OptionID + ";" + path.key,
+	}
+}
+
+// bestRepairPaths keeps the cheapest distinct change sets
+func bestRepairPaths(candidates []repairPath, limit int) []repairPath {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].cost < candidates[j].cost
+	})
+	seen := make(map[string]bool)
+	var result []repairPath
+	for _, path := range candidates {
+		if seen[path.key] {
+			continue
+		}
+		seen[path.key] = true
+		result = append(result, path)
+		if len(result) == limit {
+			break
+		}
+	}
+	return result
+}
+
+// applyRepair returns the selections with the changes applied; added
+// options get quantity 1
+func applyRepair(selections []Selection, changes []RepairChange) []Selection {
+	removed := make(map[string]bool)
+	for _, change := range changes {
+		if change.Action == "remove" {
+			removed[change.OptionID] = true
+		}
+	}
+	var repaired []Selection
+	for _, selection := range selections {
+		if selection.Quantity > 0 && !removed[selection.OptionID] {
+			repaired = append(repaired, selection)
+		}
+	}
+	for _, change := range changes {
+		if change.Action == "add" {
+			repaired = append(repaired, Selection{OptionID: change.OptionID, Quantity: 1})
+		}
+	}
+	return repaired
+}
+
+// describeRepair renders changes as "Add X, remove Y"
+func describeRepair(localizer *i18n.Localizer, changes []RepairChange) string {
+	parts := make([]string, len(changes))
+	for i, change := range changes {
+		parts[i] = localizer.T("repair."+change.Action, i18n.Args{"option": change.OptionName})
+	}
+	return i18n.Capitalize(localizer.Join(parts))
+}
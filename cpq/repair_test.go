@@ -0,0 +1,95 @@
+package cpq
+
+import (
+	"math"
+	"testing"
+)
+
+func createRepairTestModel() *Model {
+	model := createTestModel()
+	model.Options[0].BasePrice = 100
+	model.Options[1].BasePrice = 50
+	model.AddGroup(Group{ID: "extras", Name: "Extras", Type: MultiSelect, MaxSelections: 2})
+	model.AddOption(Option{ID: "dock", Name: "Docking Station", GroupID: "extras", BasePrice: 200, IsActive: true})
+	model.AddOption(Option{ID: "travel", Name: "Travel Kit", GroupID: "extras", BasePrice: 30, IsActive: true})
+	model.AddRule(Rule{ID: "needs_dock", Name: "Option 1 needs a dock", Type: RequiresRule,
+		Expression: "opt1 -> dock", IsActive: true})
+	model.AddRule(Rule{ID: "no_dock", Name: "Travel kit excludes dock", Type: ExcludesRule,
+		Expression: "travel -> NOT dock", IsActive: true})
+	return model
+}
+
+func TestRepairEngine_SuggestRepairs(t *testing.T) {
+	model := createRepairTestModel()
+	constraints, err := NewConstraintEngine(model)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	pricing := NewPricingCalculator(model)
+	pricing.SetVolumeTiers(nil)
+	engine := NewRepairEngine(model, constraints, pricing)
+	selections := []Selection{{OptionID: "opt1", Quantity: 1}, {OptionID: "travel", Quantity: 1}}
+
+	tests := []struct {
+		name     string
+		options  RepairOptions
+		expected []string
+		deltas   []float64
+	}{
+		{
+			name:     "nearest first, cheaper on ties",
+			options:  RepairOptions{},
+			expected: []string{"Remove Option 1, add Option 2", "Add Docking Station, remove Travel Kit", "Add Docking Station, remove Option 1, add Option 2, remove Travel Kit"},
+			deltas:   []float64{-50, 170, 120},
+		},
+		{
+			name:     "pinned choice",
+			options:  RepairOptions{Pinned: []string{"opt1"}, MaxResults: 5},
+			expected: []string{"Add Docking Station, remove Travel Kit"},
+			deltas:   []float64{170},
+		},
+		{
+			name:     "weights",
+			options:  RepairOptions{Weights: map[string]float64{"opt1": 5}, MaxResults: 1},
+			expected: []string{"Add Docking Station, remove Travel Kit"},
+			deltas:   []float64{170},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repairs := engine.SuggestRepairs(selections, tt.options)
+			if len(repairs) != len(tt.expected) {
+				t.Fatalf("Expected %d repairs, got %+v", len(tt.expected), repairs)
+			}
+			for i, repair := range repairs {
+				if repair.Description != tt.expected[i] {
+					t.Errorf("Repair %d: expected %q, got %q", i, tt.expected[i], repair.Description)
+				}
+				if math.Abs(repair.PriceDelta-tt.deltas[i]) > 1e-9 {
+					t.Errorf("Repair %d: expected price delta %v, got %v", i, tt.deltas[i], repair.PriceDelta)
+				}
+				if repair.Distance != len(repair.Changes) {
+					t.Errorf("Repair %d: distance %d for %d changes", i, repair.Distance, len(repair.Changes))
+				}
+				if !constraints.IsValidConfiguration(repair.Selections) {
+					t.Errorf("Repair %d leaves an invalid configuration: %v", i, repair.Selections)
+				}
+			}
+		})
+	}
+
+	// A price weight makes the expensive dock a worse repair
+	repairs := engine.SuggestRepairs([]Selection{{OptionID: "opt1", Quantity: 1}, {OptionID: "travel", Quantity: 1}},
+		RepairOptions{PriceWeight: 0.01, Weights: map[string]float64{"opt1": 1.5}, MaxResults: 2})
+	if len(repairs) != 2 || repairs[0].Description != "Remove Option 1, add Option 2" || repairs[0].Cost != 3 {
+		t.Errorf("Expected the cheaper swap first, got %+v", repairs)
+	}
+
+	if repairs := engine.SuggestRepairs([]Selection{{OptionID: "opt2", Quantity: 1}}, RepairOptions{}); repairs != nil {
+		t.Errorf("Expected no repairs for a valid configuration, got %+v", repairs)
+	}
+	if repairs := engine.SuggestRepairs(selections, RepairOptions{Pinned: []string{"opt1", "travel"}}); len(repairs) != 0 {
+		t.Errorf("Expected no repairs when every fix is pinned, got %+v", repairs)
+	}
+}
@@ -0,0 +1,110 @@
+// tax.go - Tax on price breakdowns
+// Attaches tax lines for the ship-to address after all discounts
+
+package cpq
+
+import (
+	"DD/tax"
+	"math"
+)
+
+// ApplyTax calculates tax on a breakdown at the context's ship-to address.
+// It runs last, after contract terms and requested discounts: each line is
+// taxed on its share of the net price and each charge on its amount over
+// the term, at the rates for its option's tax category. The totals of the
+// breakdown are left untouched. Without a ship-to address the breakdown
+// stays pre-tax.
+func ApplyTax(model *Model, breakdown PriceBreakdown, ctx PricingContext, exemptions []tax.Exemption) (PriceBreakdown, error) {
+	return applyTax(breakdown, ctx, exemptions, func(path, optionID string) string {
+		if option, err := model.GetOption(optionID); err == nil && path == "" {
+			return option.TaxCategory
+		}
+		return ""
+	})
+}
+
+// applyTax taxes a breakdown with the tax category of each line's option,
+// looked up by the sub-assembly path and option ID
+func applyTax(breakdown PriceBreakdown, ctx PricingContext, exemptions []tax.Exemption, category func(path, optionID string) string) (PriceBreakdown, error) {
+	breakdown.Tax = nil
+	if ctx.ShipTo == nil {
+		return breakdown, nil
+	}
+
+	lines := make([]tax.Line, 0, len(breakdown.Lines)+len(breakdown.Charges))
+	for i, amount := range netShares(breakdown) {
+		line := breakdown.Lines[i]
+		lines = append(lines, tax.Line{
+			ID:       taxLineID(line.Path, line.OptionID),
+			Category: category(line.Path, line.OptionID),
+			Amount:   amount,
+		})
+	}
+	for _, charge := range breakdown.Charges {
+		lines = append(lines, tax.Line{
+			ID:       taxLineID(charge.Path, charge.OptionID) + ":" + charge.ComponentID,
+			Category: category(charge.Path, charge.OptionID),
+			Amount:   charge.Amount,
+		})
+	}
+
+	summary, err := tax.Calculate(ctx.TaxRates, *ctx.ShipTo, lines, exemptions, ctx.TaxDisplay)
+	if err != nil {
+		return breakdown, err
+	}
+	breakdown.Tax = summary
+	return breakdown, nil
+}
+
+// taxLineID identifies an option's tax line, prefixed with the sub-assembly
+// it was rolled up from
+func taxLineID(path, optionID string) string {
+	if path == "" {
+		return optionID
+	}
+	return path + "." + optionID
+}
+
+// netShares splits the net price across the breakdown's lines in proportion
+// to their net prices, in cents, so the shares add up to the total. When the
+// lines carry no net price, such as free lines under a price rule surcharge,
+// the total is split by quantity instead.
+func netShares(breakdown PriceBreakdown) []float64 {
+	shares := make([]float64, len(breakdown.Lines))
+
+	weights := make([]float64, len(breakdown.Lines))
+	var sum float64
+	for i, line := range breakdown.Lines {
+		weights[i] = line.NetPrice
+		sum += line.NetPrice
+	}
+	if sum <= 0 && breakdown.TotalPrice > 0 {
+		sum = 0
+		for i, line := range breakdown.Lines {
+			weights[i] = math.Max(float64(line.Quantity), 1)
+			sum += weights[i]
+		}
+	}
+	if sum <= 0 {
+		return shares
+	}
+
+	total := math.Round(breakdown.TotalPrice * 100)
+	allocated := 0.0
+	last := -1
+	for i, weight := range weights {
+		shares[i] = math.Round(total * weight / sum)
+		allocated += shares[i]
+		if weight > 0 {
+			last = i
+		}
+	}
+	if last >= 0 {
+		shares[last] += total - allocated
+	}
+
+	for i := range shares {
+		shares[i] /= 100
+	}
+	return shares
+}
@@ -0,0 +1,127 @@
+package cpq
+
+import (
+	"DD/tax"
+	"testing"
+)
+
+func createTestTaxContext(t *testing.T) PricingContext {
+	table, err := tax.NewTable([]tax.Rate{
+		{Country: "CA", Name: "GST", Rate: 5},
+		{Country: "CA", Region: "BC", Name: "BC PST", Rate: 7},
+		{Country: "CA", Region: "BC", Category: "software", Name: "BC PST software", Rate: 0},
+	})
+	if err != nil {
+		t.Fatalf("NewTable failed: %v", err)
+	}
+	return PricingContext{ShipTo: &tax.Address{Country: "CA", Region: "BC"}, TaxRates: table}
+}
+
+func TestApplyTax(t *testing.T) {
+	model := createTestModelForPricing()
+	model.Options[1].TaxCategory = "software"
+	ctx := createTestTaxContext(t)
+
+	list := NewPricingCalculator(model).CalculatePrice([]Selection{
+		{OptionID: "opt1", Quantity: 1},
+		{OptionID: "opt2", Quantity: 1},
+	}, PricingContext{})
+	discounted, err := ApplyDiscount(model, list, 10)
+	if err != nil {
+		t.Fatalf("ApplyDiscount failed: %v", err)
+	}
+
+	taxed, err := ApplyTax(model, discounted, ctx, nil)
+	if err != nil {
+		t.Fatalf("ApplyTax failed: %v", err)
+	}
+
+	// Tax comes after the discount: opt1 $90 at 12%, opt2 $45 at 5%
+	if taxed.Tax == nil || taxed.Tax.Net != 135 || taxed.Tax.Tax != 13.05 {
+		t.Fatalf("Expected tax 13.05 on 135.00, got %+v", taxed.Tax)
+	}
+	if taxed.TotalPrice != discounted.TotalPrice || taxed.Margin != discounted.Margin {
+		t.Error("Tax should not change pre-tax totals or margin")
+	}
+
+	exempt, err := ApplyTax(model, discounted, ctx, []tax.Exemption{{Country: "CA", Region: "BC"}})
+	if err != nil {
+		t.Fatalf("ApplyTax failed: %v", err)
+	}
+	if exempt.Tax.Tax != 6.75 {
+		t.Errorf("Expected only GST of 6.75 for a BC exemption, got %.2f", exempt.Tax.Tax)
+	}
+
+	pretax, err := ApplyTax(model, taxed, PricingContext{}, nil)
+	if err != nil || pretax.Tax != nil {
+		t.Errorf("Expected pre-tax quote without a ship-to address, got %+v (%v)", pretax.Tax, err)
+	}
+}
+
+func TestApplyTax_Charges(t *testing.T) {
+	model := createTestModelForPricing()
+	model.Options[0].Components = []PriceComponent{
+		{ID: "support", Name: "Support", ChargeType: RecurringCharge, Price: 10, Period: MonthlyPeriod},
+	}
+	ctx := createTestTaxContext(t)
+	ctx.TaxDisplay = tax.Inclusive
+
+	breakdown := NewPricingCalculator(model).CalculatePrice([]Selection{{OptionID: "opt1", Quantity: 1}}, PricingContext{})
+	taxed, err := ApplyTax(model, breakdown, ctx, nil)
+	if err != nil {
+		t.Fatalf("ApplyTax failed: %v", err)
+	}
+
+	// $100 plus $10 × 12 months, at 12%
+	if len(taxed.Tax.Lines) != 2 || taxed.Tax.Net != 220 || taxed.Tax.Tax != 26.4 {
+		t.Fatalf("Expected tax 26.40 on 220.00 over two lines, got %+v", taxed.Tax)
+	}
+	if taxed.Tax.Lines[0].Display != 112 {
+		t.Errorf("Expected inclusive display of 112.00, got %.2f", taxed.Tax.Lines[0].Display)
+	}
+}
+
+func TestNetShares(t *testing.T) {
+	breakdown := PriceBreakdown{
+		TotalPrice: 100,
+		Lines:      []LinePrice{{NetPrice: 10}, {NetPrice: 10}, {NetPrice: 10}},
+	}
+
+	shares := netShares(breakdown)
+	if sum := shares[0] + shares[1] + shares[2]; sum < 99.999 || sum > 100.001 {
+		t.Errorf("Expected shares to add up to 100.00, got %v", shares)
+	}
+}
+
+func TestNetShares_NoLineNet(t *testing.T) {
+	// Free lines with a surcharge on top still carry the total
+	breakdown := PriceBreakdown{
+		TotalPrice: 50,
+		Lines:      []LinePrice{{Quantity: 1}, {Quantity: 3}},
+	}
+
+	shares := netShares(breakdown)
+	if shares[0] != 12.5 || shares[1] != 37.5 {
+		t.Errorf("Expected the total split 12.50 and 37.50 by quantity, got %v", shares)
+	}
+
+	breakdown.TotalPrice = 0
+	shares = netShares(breakdown)
+	if shares[0] != 0 || shares[1] != 0 {
+		t.Errorf("Expected no shares of a zero total, got %v", shares)
+	}
+}
+
+func TestValidatePricingContext_Tax(t *testing.T) {
+	model := createTestModelForPricing()
+	ctx := createTestTaxContext(t)
+
+	if err := ValidatePricingContext(model, ctx); err != nil {
+		t.Errorf("Expected valid tax context, got %v", err)
+	}
+
+	ctx.ShipTo = &tax.Address{Country: "FR"}
+	if err := ValidatePricingContext(model, ctx); err == nil {
+		t.Error("Expected error for ship-to address without tax rates")
+	}
+}
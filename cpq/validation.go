@@ -4,6 +4,9 @@
 package cpq
 
 import (
+	"DD/evaluator"
+	"DD/parser"
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -39,7 +42,7 @@ func ValidateModel(model *Model) error {
 
 	// Validate price rules
 	for _, priceRule := range model.PriceRules {
-		if err := validatePriceRule(model, priceRule); err != nil {
+		if err := ValidatePriceRule(model, priceRule); err != nil {
 			return fmt.Errorf("price rule %s validation failed: %w", priceRule.ID, err)
 		}
 	}
@@ -112,8 +115,8 @@ func validateRule(model *Model, rule Rule) error {
 	return nil
 }
 
-// validatePriceRule validates a pricing rule
-func validatePriceRule(model *Model, priceRule PriceRule) error {
+// ValidatePriceRule validates a pricing rule
+func ValidatePriceRule(model *Model, priceRule PriceRule) error {
 	if priceRule.ID == "" {
 		return fmt.Errorf("price rule ID cannot be empty")
 	}
@@ -132,11 +135,92 @@ func validatePriceRule(model *Model, priceRule PriceRule) error {
 		if err := validateSimplePriceExpression(model, priceRule.Expression); err != nil {
 			return fmt.Errorf("invalid price expression: %w", err)
 		}
+	case ExpressionPriceRule:
+		if err := validatePriceFormula(model, priceRule.Expression); err != nil {
+			return fmt.Errorf("invalid price expression: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateVolumeTiers validates volume tiers against a model. Tiers sharing
+// a scope must use the same mode, and incremental tiers must not overlap.
+func ValidateVolumeTiers(model *Model, tiers []VolumeTier) error {
+	ids := make(map[string]bool)
+	modes := make(map[string]VolumeTierMode)
+	for _, tier := range tiers {
+		if tier.ID == "" {
+			return fmt.Errorf("volume tier ID cannot be empty")
+		}
+		if ids[tier.ID] {
+			return fmt.Errorf("duplicate volume tier %s", tier.ID)
+		}
+		ids[tier.ID] = true
+
+		if tier.MinQuantity < 1 {
+			return fmt.Errorf("volume tier %s: min quantity must be at least 1", tier.ID)
+		}
+		if tier.MaxQuantity != -1 && tier.MaxQuantity < tier.MinQuantity {
+			return fmt.Errorf("volume tier %s: max quantity (%d) cannot be less than min quantity (%d)",
+				tier.ID, tier.MaxQuantity, tier.MinQuantity)
+		}
+		if tier.Multiplier <= 0 || tier.Multiplier > 1 {
+			return fmt.Errorf("volume tier %s: multiplier must be greater than 0 and at most 1", tier.ID)
+		}
+
+		if tier.OptionID != "" && tier.GroupID != "" {
+			return fmt.Errorf("volume tier %s cannot apply to both an option and a group", tier.ID)
+		}
+		if tier.OptionID != "" {
+			if _, err := model.GetOption(tier.OptionID); err != nil {
+				return fmt.Errorf("volume tier %s references invalid option %s", tier.ID, tier.OptionID)
+			}
+		}
+		if tier.GroupID != "" {
+			if _, err := model.GetGroup(tier.GroupID); err != nil {
+				return fmt.Errorf("volume tier %s references invalid group %s", tier.ID, tier.GroupID)
+			}
+		}
+
+		mode := tier.Mode
+		if mode == "" {
+			mode = AllUnitsTiers
+		}
+		if mode != AllUnitsTiers && mode != IncrementalTiers {
+			return fmt.Errorf("volume tier %s: unknown mode %s", tier.ID, tier.Mode)
+		}
+		if previous, exists := modes[tier.scope()]; exists && previous != mode {
+			return fmt.Errorf("volume tier %s: tiers for the same scope must share one mode", tier.ID)
+		}
+		modes[tier.scope()] = mode
+	}
+
+	// Incremental tiers price disjoint bands of units
+	for i, tier := range tiers {
+		if tier.Mode != IncrementalTiers {
+			continue
+		}
+		for _, other := range tiers[i+1:] {
+			if other.scope() != tier.scope() {
+				continue
+			}
+			if tiersOverlap(tier, other) {
+				return fmt.Errorf("incremental volume tiers %s and %s overlap", tier.ID, other.ID)
+			}
+		}
 	}
 
 	return nil
 }
 
+// tiersOverlap reports whether two tiers' quantity ranges intersect
+func tiersOverlap(a, b VolumeTier) bool {
+	aBelowB := a.MaxQuantity != -1 && a.MaxQuantity < b.MinQuantity
+	bBelowA := b.MaxQuantity != -1 && b.MaxQuantity < a.MinQuantity
+	return !aBelowB && !bBelowA
+}
+
 // ===================================================================
 // CONFIGURATION VALIDATION HELPERS
 // ===================================================================
@@ -273,6 +357,63 @@ func validateSimplePriceExpression(model *Model, expression string) error {
 	return nil
 }
 
+// validatePriceFormula checks that a price formula parses, references only
+// pricing variables and computes a number. The formula is trial evaluated
+// with every option selected once; division by zero is allowed since it
+// depends on the selections.
+func validatePriceFormula(model *Model, expression string) error {
+	expr, err := parser.ParseFormula(expression)
+	if err != nil {
+		return err
+	}
+	if parser.GetExpressionType(expr) == parser.TYPE_BOOLEAN {
+		return fmt.Errorf("expression must compute an amount, not a condition")
+	}
+
+	definitions, err := model.ParseDefinitions()
+	if err != nil {
+		return err
+	}
+	selections := make([]Selection, 0, len(model.Options))
+	basePrice := 0.0
+	for _, option := range model.Options {
+		selections = append(selections, Selection{OptionID: option.ID, Quantity: 1})
+		basePrice += option.BasePrice
+	}
+	return checkTrialExpression(expr, priceContext(model, definitions, selections, basePrice, nil), false)
+}
+
+// checkTrialExpression checks that an expression references only variables
+// bound in a trial context and evaluates there to a condition or, when
+// condition is false, to an amount. Division by zero passes, since whether
+// it happens depends on the values at run time.
+func checkTrialExpression(expr parser.Expression, context evaluator.Context, condition bool) error {
+	for _, variable := range parser.CollectVariables(expr) {
+		if _, known := context[variable]; !known {
+			return fmt.Errorf("unknown variable %s", variable)
+		}
+	}
+
+	value, err := evaluator.Evaluate(expr, context)
+	if errors.Is(err, evaluator.ErrDivisionByZero) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if condition {
+		if _, isCondition := value.(bool); !isCondition {
+			return fmt.Errorf("condition must be true or false, got %T", value)
+		}
+		return nil
+	}
+	if _, isNumber := value.(float64); !isNumber {
+		return fmt.Errorf("expression must compute an amount, got %T", value)
+	}
+	return nil
+}
+
 // contains checks if a slice contains a string
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
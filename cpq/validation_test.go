@@ -152,7 +152,7 @@ func TestValidatePriceRule(t *testing.T) {
 		IsActive:   true,
 	}
 
-	err := validatePriceRule(model, validPriceRule)
+	err := ValidatePriceRule(model, validPriceRule)
 	if err != nil {
 		t.Errorf("Valid price rule should pass validation: %v", err)
 	}
@@ -160,7 +160,7 @@ func TestValidatePriceRule(t *testing.T) {
 	// Test price rule with empty ID
 	invalidPriceRule := validPriceRule
 	invalidPriceRule.ID = ""
-	err = validatePriceRule(model, invalidPriceRule)
+	err = ValidatePriceRule(model, invalidPriceRule)
 	if err == nil {
 		t.Error("Price rule with empty ID should fail validation")
 	}
@@ -168,7 +168,7 @@ func TestValidatePriceRule(t *testing.T) {
 	// Test price rule with empty expression
 	invalidPriceRule = validPriceRule
 	invalidPriceRule.Expression = ""
-	err = validatePriceRule(model, invalidPriceRule)
+	err = ValidatePriceRule(model, invalidPriceRule)
 	if err == nil {
 		t.Error("Price rule with empty expression should fail validation")
 	}
@@ -176,12 +176,115 @@ func TestValidatePriceRule(t *testing.T) {
 	// Test price rule with invalid format
 	invalidPriceRule = validPriceRule
 	invalidPriceRule.Expression = "invalid_format"
-	err = validatePriceRule(model, invalidPriceRule)
+	err = ValidatePriceRule(model, invalidPriceRule)
 	if err == nil {
 		t.Error("Price rule with invalid format should fail validation")
 	}
 }
 
+func TestValidatePriceRule_Expression(t *testing.T) {
+	model := createValidTestModel()
+	model.AddDefinition(Definition{ID: "premium", Expression: "opt1"})
+
+	tests := []struct {
+		name       string
+		expression string
+		valid      bool
+	}{
+		{"conditional amount", "IF(premium AND opt1, -0.05 * base, 0)", true},
+		{"quantities and prices", "opt1_qty * opt1_price * 0.1 + total_quantity", true},
+		{"division depending on selections", "base / (opt1_qty - 1)", true},
+		{"syntax error", "IF(opt1, 10", false},
+		{"unknown variable", "missing_qty * 2", false},
+		{"condition instead of amount", "opt1 AND premium", false},
+		{"type error", "opt1 + 5", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := PriceRule{ID: "formula", Name: "Formula", Type: ExpressionPriceRule, Expression: tt.expression}
+			err := ValidatePriceRule(model, rule)
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid formula, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected formula to be rejected")
+			}
+		})
+	}
+
+	// Saving the model checks formulas too
+	model.AddPriceRule(PriceRule{ID: "broken", Name: "Broken", Type: ExpressionPriceRule, Expression: "opt1 * 2"})
+	if err := model.Validate(); err == nil {
+		t.Error("Expected model with an invalid price formula to fail validation")
+	}
+}
+
+func TestValidatePriceRule_IFOnlyInFormulas(t *testing.T) {
+	// Models may name an option IF; only formulas read it as ITE
+	model := createValidTestModel()
+	model.AddOption(Option{ID: "IF", Name: "Installation fee", GroupID: "group1", BasePrice: 25, IsActive: true})
+	model.AddRule(Rule{ID: "r1", Name: "Fee", Type: RequiresRule, Expression: "IF -> NOT opt2", IsActive: true})
+
+	if _, err := NewConstraintEngine(model); err != nil {
+		t.Fatalf("Expected rule over option IF to compile, got %v", err)
+	}
+	rule := PriceRule{ID: "formula", Name: "Formula", Type: ExpressionPriceRule, Expression: "IF(opt1, -10, 0)"}
+	if err := ValidatePriceRule(model, rule); err != nil {
+		t.Errorf("Expected IF to remain an alias of ITE in formulas, got %v", err)
+	}
+}
+
+func TestValidateVolumeTiers(t *testing.T) {
+	model := createValidTestModel()
+
+	tests := []struct {
+		name  string
+		tiers []VolumeTier
+		valid bool
+	}{
+		{"model tiers", []VolumeTier{
+			{ID: "t1", MinQuantity: 1, MaxQuantity: 9, Multiplier: 1.0},
+			{ID: "t2", MinQuantity: 10, MaxQuantity: -1, Multiplier: 0.9},
+		}, true},
+		{"incremental option tiers", []VolumeTier{
+			{ID: "t1", OptionID: "opt1", Mode: IncrementalTiers, MinQuantity: 1, MaxQuantity: 9, Multiplier: 1.0},
+			{ID: "t2", OptionID: "opt1", Mode: IncrementalTiers, MinQuantity: 10, MaxQuantity: -1, Multiplier: 0.9},
+		}, true},
+		{"missing ID", []VolumeTier{{MinQuantity: 1, MaxQuantity: -1, Multiplier: 0.9}}, false},
+		{"duplicate ID", []VolumeTier{
+			{ID: "t1", MinQuantity: 1, MaxQuantity: 9, Multiplier: 1.0},
+			{ID: "t1", MinQuantity: 10, MaxQuantity: -1, Multiplier: 0.9},
+		}, false},
+		{"max below min", []VolumeTier{{ID: "t1", MinQuantity: 10, MaxQuantity: 5, Multiplier: 0.9}}, false},
+		{"zero multiplier", []VolumeTier{{ID: "t1", MinQuantity: 1, MaxQuantity: -1, Multiplier: 0}}, false},
+		{"unknown option", []VolumeTier{{ID: "t1", OptionID: "missing", MinQuantity: 1, MaxQuantity: -1, Multiplier: 0.9}}, false},
+		{"unknown group", []VolumeTier{{ID: "t1", GroupID: "missing", MinQuantity: 1, MaxQuantity: -1, Multiplier: 0.9}}, false},
+		{"option and group", []VolumeTier{{ID: "t1", OptionID: "opt1", GroupID: "group1", MinQuantity: 1, MaxQuantity: -1, Multiplier: 0.9}}, false},
+		{"unknown mode", []VolumeTier{{ID: "t1", Mode: "tiered", MinQuantity: 1, MaxQuantity: -1, Multiplier: 0.9}}, false},
+		{"mixed modes in scope", []VolumeTier{
+			{ID: "t1", GroupID: "group1", MinQuantity: 1, MaxQuantity: 9, Multiplier: 1.0},
+			{ID: "t2", GroupID: "group1", Mode: IncrementalTiers, MinQuantity: 10, MaxQuantity: -1, Multiplier: 0.9},
+		}, false},
+		{"overlapping incremental tiers", []VolumeTier{
+			{ID: "t1", Mode: IncrementalTiers, MinQuantity: 1, MaxQuantity: 10, Multiplier: 1.0},
+			{ID: "t2", Mode: IncrementalTiers, MinQuantity: 10, MaxQuantity: -1, Multiplier: 0.9},
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateVolumeTiers(model, tt.tiers)
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid tiers, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected tiers to be rejected")
+			}
+		})
+	}
+}
+
 func TestValidateConfiguration(t *testing.T) {
 	model := createValidTestModel()
 
@@ -5,11 +5,14 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"DD/cpq"
+	"DD/parser/format"
 	_ "github.com/lib/pq"
 )
 
@@ -70,13 +73,14 @@ type User struct {
 // GetModel retrieves a model by ID with all related data
 func (db *DB) GetModel(modelID string) (*cpq.Model, error) {
 	model := &cpq.Model{}
+	var marginFloors, approvalPolicies, assemblyRules []byte
 
 	// Get model basic info
 	err := db.QueryRow(`
-		SELECT id, name, description, version, is_active, created_at, updated_at
+		SELECT id, name, description, version, currency, term_months, margin_floors, approval_policies, assembly_rules, is_active, created_at, updated_at
 		FROM models WHERE id = $1 AND is_active = true
 	`, modelID).Scan(
-		&model.ID, &model.Name, &model.Description, &model.Version, &model.IsActive, &model.CreatedAt, &model.UpdatedAt,
+		&model.ID, &model.Name, &model.Description, &model.Version, &model.Currency, &model.TermMonths, &marginFloors, &approvalPolicies, &assemblyRules, &model.IsActive, &model.CreatedAt, &model.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -84,6 +88,15 @@ func (db *DB) GetModel(modelID string) (*cpq.Model, error) {
 		}
 		return nil, fmt.Errorf("failed to get model: %w", err)
 	}
+	if err := json.Unmarshal(marginFloors, &model.MarginFloors); err != nil {
+		return nil, fmt.Errorf("failed to decode margin floors: %w", err)
+	}
+	if err := json.Unmarshal(approvalPolicies, &model.ApprovalPolicies); err != nil {
+		return nil, fmt.Errorf("failed to decode approval policies: %w", err)
+	}
+	if err := json.Unmarshal(assemblyRules, &model.AssemblyRules); err != nil {
+		return nil, fmt.Errorf("failed to decode assembly rules: %w", err)
+	}
 
 	// Get groups
 	groups, err := db.getModelGroups(modelID)
@@ -113,13 +126,34 @@ func (db *DB) GetModel(modelID string) (*cpq.Model, error) {
 	}
 	model.PriceRules = pricingRules
 
+	// Get definitions
+	definitions, err := db.getModelDefinitions(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model definitions: %w", err)
+	}
+	model.Definitions = definitions
+
+	// Get volume tiers
+	volumeTiers, err := db.getModelVolumeTiers(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model volume tiers: %w", err)
+	}
+	model.VolumeTiers = volumeTiers
+
+	// Get price books
+	priceBooks, err := db.getModelPriceBooks(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model price books: %w", err)
+	}
+	model.PriceBooks = priceBooks
+
 	return model, nil
 }
 
 // ListModels returns all active models
 func (db *DB) ListModels() ([]*cpq.Model, error) {
 	rows, err := db.Query(`
-		SELECT id, name, description, version, is_active, created_at, updated_at
+		SELECT id, name, description, version, currency, term_months, is_active, created_at, updated_at
 		FROM models WHERE is_active = true ORDER BY name
 	`)
 	if err != nil {
@@ -131,7 +165,7 @@ func (db *DB) ListModels() ([]*cpq.Model, error) {
 	for rows.Next() {
 		model := &cpq.Model{}
 		err := rows.Scan(
-			&model.ID, &model.Name, &model.Description, &model.Version, &model.IsActive, &model.CreatedAt, &model.UpdatedAt,
+			&model.ID, &model.Name, &model.Description, &model.Version, &model.Currency, &model.TermMonths, &model.IsActive, &model.CreatedAt, &model.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan model: %w", err)
@@ -150,11 +184,24 @@ func (db *DB) CreateModel(model *cpq.Model, userID string) error {
 	}
 	defer tx.Rollback()
 
+	marginFloors, err := MarginFloorsJSON(model)
+	if err != nil {
+		return fmt.Errorf("failed to encode margin floors: %w", err)
+	}
+	approvalPolicies, err := ApprovalPoliciesJSON(model)
+	if err != nil {
+		return fmt.Errorf("failed to encode approval policies: %w", err)
+	}
+	assemblyRules, err := AssemblyRulesJSON(model)
+	if err != nil {
+		return fmt.Errorf("failed to encode assembly rules: %w", err)
+	}
+
 	// Insert model
 	_, err = tx.Exec(`
-		INSERT INTO models (id, name, description, version, is_active, created_by)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`, model.ID, model.Name, model.Description, model.Version, model.IsActive, userID)
+		INSERT INTO models (id, name, description, version, currency, term_months, margin_floors, approval_policies, assembly_rules, is_active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, model.ID, model.Name, model.Description, model.Version, model.BaseCurrency(), model.TermMonths, marginFloors, approvalPolicies, assemblyRules, model.IsActive, userID)
 	if err != nil {
 		return fmt.Errorf("failed to insert model: %w", err)
 	}
@@ -175,8 +222,9 @@ func (db *DB) CreateModel(model *cpq.Model, userID string) error {
 		}
 	}
 
-	// Insert rules
+	// Insert rules in canonical form
 	for _, rule := range model.Rules {
+		rule.Expression = format.Normalize(rule.Expression)
 		err = db.insertRule(tx, model.ID, rule)
 		if err != nil {
 			return fmt.Errorf("failed to insert rule %s: %w", rule.ID, err)
@@ -191,6 +239,31 @@ func (db *DB) CreateModel(model *cpq.Model, userID string) error {
 		}
 	}
 
+	// Insert definitions in canonical form
+	for _, definition := range model.Definitions {
+		definition.Expression = format.Normalize(definition.Expression)
+		err = db.insertDefinition(tx, model.ID, definition)
+		if err != nil {
+			return fmt.Errorf("failed to insert definition %s: %w", definition.ID, err)
+		}
+	}
+
+	// Insert volume tiers
+	for _, tier := range model.VolumeTiers {
+		err = db.insertVolumeTier(tx, model.ID, tier)
+		if err != nil {
+			return fmt.Errorf("failed to insert volume tier %s: %w", tier.ID, err)
+		}
+	}
+
+	// Insert price books with their entries
+	for _, book := range model.PriceBooks {
+		err = db.InsertPriceBook(tx, model.ID, book)
+		if err != nil {
+			return fmt.Errorf("failed to insert price book %s: %w", book.ID, err)
+		}
+	}
+
 	return tx.Commit()
 }
 
@@ -226,7 +299,7 @@ func (db *DB) GetConfiguration(configID string, userID *string) (*cpq.Configurat
 	config := &cpq.Configuration{}
 
 	query := `
-		SELECT id, model_id, is_valid, total_price, created_at, updated_at
+		SELECT id, model_id, is_valid, total_price, children, created_at, updated_at
 		FROM configurations WHERE id = $1
 	`
 	args := []interface{}{configID}
@@ -237,9 +310,10 @@ func (db *DB) GetConfiguration(configID string, userID *string) (*cpq.Configurat
 		args = append(args, *userID)
 	}
 
+	var children []byte
 	err := db.QueryRow(query, args...).Scan(
 		&config.ID, &config.ModelID, &config.IsValid, &config.TotalPrice,
-		&config.CreatedAt, &config.UpdatedAt,
+		&children, &config.CreatedAt, &config.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -247,6 +321,9 @@ func (db *DB) GetConfiguration(configID string, userID *string) (*cpq.Configurat
 		}
 		return nil, fmt.Errorf("failed to get configuration: %w", err)
 	}
+	if err := json.Unmarshal(children, &config.Children); err != nil {
+		return nil, fmt.Errorf("failed to decode sub-assemblies: %w", err)
+	}
 
 	// Get selections
 	selections, err := db.getConfigurationSelections(configID)
@@ -324,7 +401,7 @@ func (db *DB) getModelGroups(modelID string) ([]cpq.Group, error) {
 
 func (db *DB) getModelOptions(modelID string) ([]cpq.Option, error) {
 	rows, err := db.Query(`
-		SELECT id, group_id, name, description, base_price, sku, display_order, is_active
+		SELECT id, group_id, name, description, base_price, cost, tax_category, sku, display_order, is_active, price_components, child_model_id
 		FROM options WHERE model_id = $1 ORDER BY display_order
 	`, modelID)
 	if err != nil {
@@ -335,10 +412,11 @@ func (db *DB) getModelOptions(modelID string) ([]cpq.Option, error) {
 	var options []cpq.Option
 	for rows.Next() {
 		option := cpq.Option{}
-		var sku sql.NullString
+		var sku, childModelID sql.NullString
+		var components []byte
 		err := rows.Scan(
 			&option.ID, &option.GroupID, &option.Name, &option.Description,
-			&option.BasePrice, &sku, &option.DisplayOrder, &option.IsActive,
+			&option.BasePrice, &option.Cost, &option.TaxCategory, &sku, &option.DisplayOrder, &option.IsActive, &components, &childModelID,
 		)
 		if err != nil {
 			return nil, err
@@ -346,6 +424,10 @@ func (db *DB) getModelOptions(modelID string) ([]cpq.Option, error) {
 		if sku.Valid {
 			option.SKU = sku.String
 		}
+		option.ChildModelID = childModelID.String
+		if err := json.Unmarshal(components, &option.Components); err != nil {
+			return nil, fmt.Errorf("failed to decode price components of %s: %w", option.ID, err)
+		}
 		options = append(options, option)
 	}
 
@@ -414,6 +496,115 @@ func (db *DB) getModelPricingRules(modelID string) ([]cpq.PriceRule, error) {
 	return pricingRules, nil
 }
 
+func (db *DB) getModelDefinitions(modelID string) ([]cpq.Definition, error) {
+	rows, err := db.Query(`
+		SELECT id, name, description, expression
+		FROM definitions WHERE model_id = $1 ORDER BY id
+	`, modelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	definitions := []cpq.Definition{}
+	for rows.Next() {
+		definition := cpq.Definition{}
+		var description sql.NullString
+		err := rows.Scan(&definition.ID, &definition.Name, &description, &definition.Expression)
+		if err != nil {
+			return nil, err
+		}
+		if description.Valid {
+			definition.Description = description.String
+		}
+		definitions = append(definitions, definition)
+	}
+
+	return definitions, nil
+}
+
+func (db *DB) getModelVolumeTiers(modelID string) ([]cpq.VolumeTier, error) {
+	rows, err := db.Query(`
+		SELECT id, name, option_id, group_id, mode, min_quantity, max_quantity, multiplier, priority
+		FROM volume_tiers WHERE model_id = $1 ORDER BY priority, min_quantity, id
+	`, modelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tiers []cpq.VolumeTier
+	for rows.Next() {
+		tier := cpq.VolumeTier{}
+		var optionID, groupID sql.NullString
+		err := rows.Scan(&tier.ID, &tier.Name, &optionID, &groupID, &tier.Mode,
+			&tier.MinQuantity, &tier.MaxQuantity, &tier.Multiplier, &tier.Priority)
+		if err != nil {
+			return nil, err
+		}
+		tier.OptionID = optionID.String
+		tier.GroupID = groupID.String
+		tiers = append(tiers, tier)
+	}
+
+	return tiers, nil
+}
+
+func (db *DB) getModelPriceBooks(modelID string) ([]cpq.PriceBook, error) {
+	rows, err := db.Query(`
+		SELECT id, name, description, currency, is_default
+		FROM price_books WHERE model_id = $1 ORDER BY id
+	`, modelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var books []cpq.PriceBook
+	index := make(map[string]int)
+	for rows.Next() {
+		book := cpq.PriceBook{Entries: []cpq.PriceBookEntry{}}
+		var description sql.NullString
+		err := rows.Scan(&book.ID, &book.Name, &description, &book.Currency, &book.IsDefault)
+		if err != nil {
+			return nil, err
+		}
+		book.Description = description.String
+		index[book.ID] = len(books)
+		books = append(books, book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := db.Query(`
+		SELECT price_book_id, option_id, price, effective_from, effective_to
+		FROM price_book_entries WHERE model_id = $1 ORDER BY price_book_id, option_id, effective_from
+	`, modelID)
+	if err != nil {
+		return nil, err
+	}
+	defer entries.Close()
+
+	for entries.Next() {
+		var bookID string
+		var effectiveTo sql.NullTime
+		entry := cpq.PriceBookEntry{}
+		err := entries.Scan(&bookID, &entry.OptionID, &entry.Price, &entry.EffectiveFrom, &effectiveTo)
+		if err != nil {
+			return nil, err
+		}
+		if effectiveTo.Valid {
+			entry.EffectiveTo = &effectiveTo.Time
+		}
+		if i, ok := index[bookID]; ok {
+			books[i].Entries = append(books[i].Entries, entry)
+		}
+	}
+
+	return books, entries.Err()
+}
+
 func (db *DB) getConfigurationSelections(configID string) ([]cpq.Selection, error) {
 	rows, err := db.Query(`
 		SELECT option_id, quantity FROM selections WHERE configuration_id = $1
@@ -461,14 +652,72 @@ func (db *DB) insertGroup(tx *sql.Tx, modelID string, group cpq.Group) error {
 }
 
 func (db *DB) insertOption(tx *sql.Tx, modelID string, option cpq.Option) error {
-	_, err := tx.Exec(`
-		INSERT INTO options (id, model_id, group_id, name, description, base_price, display_order, is_active)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	components, err := PriceComponentsJSON(option)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO options (id, model_id, group_id, name, description, base_price, cost, tax_category, display_order, is_active, price_components, child_model_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`, option.ID, modelID, option.GroupID, option.Name, option.Description,
-		option.BasePrice, option.DisplayOrder, option.IsActive)
+		option.BasePrice, option.Cost, option.TaxCategory, option.DisplayOrder, option.IsActive, components, nullableString(option.ChildModelID))
 	return err
 }
 
+// PriceComponentsJSON encodes an option's price components for the
+// price_components column
+func PriceComponentsJSON(option cpq.Option) ([]byte, error) {
+	if len(option.Components) == 0 {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(option.Components)
+}
+
+// MarginFloorsJSON encodes a model's margin floors for the margin_floors
+// column
+func MarginFloorsJSON(model *cpq.Model) ([]byte, error) {
+	if len(model.MarginFloors) == 0 {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(model.MarginFloors)
+}
+
+// TaxExemptionsJSON encodes a customer's tax exemptions for the
+// tax_exemptions column
+func TaxExemptionsJSON(customer *cpq.Customer) ([]byte, error) {
+	if len(customer.TaxExemptions) == 0 {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(customer.TaxExemptions)
+}
+
+// ApprovalPoliciesJSON encodes a model's approval policies for the
+// approval_policies column
+func ApprovalPoliciesJSON(model *cpq.Model) ([]byte, error) {
+	if len(model.ApprovalPolicies) == 0 {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(model.ApprovalPolicies)
+}
+
+// AssemblyRulesJSON encodes a model's assembly rules for the assembly_rules
+// column
+func AssemblyRulesJSON(model *cpq.Model) ([]byte, error) {
+	if len(model.AssemblyRules) == 0 {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(model.AssemblyRules)
+}
+
+// ChildConfigurationsJSON encodes a configuration's sub-assemblies for the
+// children column
+func ChildConfigurationsJSON(children []cpq.Configuration) ([]byte, error) {
+	if len(children) == 0 {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(children)
+}
+
 func (db *DB) insertRule(tx *sql.Tx, modelID string, rule cpq.Rule) error {
 	_, err := tx.Exec(`
 		INSERT INTO rules (id, model_id, name, type, expression, message, priority, is_active)
@@ -487,6 +736,49 @@ func (db *DB) insertPricingRule(tx *sql.Tx, modelID string, priceRule cpq.PriceR
 	return err
 }
 
+func (db *DB) insertDefinition(tx *sql.Tx, modelID string, definition cpq.Definition) error {
+	_, err := tx.Exec(`
+		INSERT INTO definitions (id, model_id, name, description, expression)
+		VALUES ($1, $2, $3, $4, $5)
+	`, definition.ID, modelID, definition.Name, nullableString(definition.Description), definition.Expression)
+	return err
+}
+
+func (db *DB) insertVolumeTier(tx *sql.Tx, modelID string, tier cpq.VolumeTier) error {
+	mode := tier.Mode
+	if mode == "" {
+		mode = cpq.AllUnitsTiers
+	}
+	_, err := tx.Exec(`
+		INSERT INTO volume_tiers (id, model_id, name, option_id, group_id, mode, min_quantity, max_quantity, multiplier, priority)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, tier.ID, modelID, tier.Name, nullableString(tier.OptionID), nullableString(tier.GroupID), mode,
+		tier.MinQuantity, tier.MaxQuantity, tier.Multiplier, tier.Priority)
+	return err
+}
+
+// InsertPriceBook inserts a price book and its entries within a transaction
+func (db *DB) InsertPriceBook(tx *sql.Tx, modelID string, book cpq.PriceBook) error {
+	_, err := tx.Exec(`
+		INSERT INTO price_books (id, model_id, name, description, currency, is_default)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, book.ID, modelID, book.Name, nullableString(book.Description), strings.ToUpper(book.Currency), book.IsDefault)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range book.Entries {
+		_, err = tx.Exec(`
+			INSERT INTO price_book_entries (model_id, price_book_id, option_id, price, effective_from, effective_to)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, modelID, book.ID, entry.OptionID, entry.Price, entry.EffectiveFrom, entry.EffectiveTo)
+		if err != nil {
+			return fmt.Errorf("failed to insert entry for %s: %w", entry.OptionID, err)
+		}
+	}
+	return nil
+}
+
 // Utility functions
 func nullableString(s string) interface{} {
 	if s == "" {
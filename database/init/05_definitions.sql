@@ -0,0 +1,19 @@
+-- database/init/05_definitions.sql
+-- Named definitions: reusable sub-expressions referenced by rules and pricing rules
+-- A definition `let highend = ...` is stored with id 'highend'
+
+CREATE TABLE IF NOT EXISTS definitions (
+    id VARCHAR(100) NOT NULL, -- Identifier used in expressions
+    model_id VARCHAR(100) NOT NULL REFERENCES models(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    description TEXT,
+    expression TEXT NOT NULL, -- Expression the identifier stands for
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (model_id, id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_definitions_model ON definitions(model_id);
+
+CREATE TRIGGER update_definitions_updated_at BEFORE UPDATE ON definitions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
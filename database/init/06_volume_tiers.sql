@@ -0,0 +1,24 @@
+-- database/init/06_volume_tiers.sql
+-- Volume tiers: quantity bands with price multipliers for a model, group or option
+-- A tier with neither option_id nor group_id applies to the whole model
+
+CREATE TABLE IF NOT EXISTS volume_tiers (
+    id VARCHAR(100) NOT NULL,
+    model_id VARCHAR(100) NOT NULL REFERENCES models(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    option_id VARCHAR(100),
+    group_id VARCHAR(100),
+    mode VARCHAR(20) NOT NULL DEFAULT 'all_units' CHECK (mode IN ('all_units', 'incremental')),
+    min_quantity INTEGER NOT NULL CHECK (min_quantity >= 1),
+    max_quantity INTEGER NOT NULL DEFAULT -1, -- -1 for unlimited
+    multiplier DECIMAL(6,4) NOT NULL CHECK (multiplier > 0),
+    priority INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (model_id, id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_volume_tiers_model ON volume_tiers(model_id);
+
+CREATE TRIGGER update_volume_tiers_updated_at BEFORE UPDATE ON volume_tiers FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
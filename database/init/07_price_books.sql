@@ -0,0 +1,54 @@
+-- database/init/07_price_books.sql
+-- Price books: named price lists (list, partner, EMEA) in one currency with
+-- effective-dated entries, plus a local exchange-rate table for conversions
+
+-- Currency of option base prices and fixed price rule amounts
+ALTER TABLE models ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+CREATE TABLE IF NOT EXISTS price_books (
+    id VARCHAR(100) NOT NULL,
+    model_id VARCHAR(100) NOT NULL REFERENCES models(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    description TEXT,
+    currency CHAR(3) NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT false, -- Used when a pricing context names no book
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (model_id, id)
+);
+
+-- At most one default book per model
+CREATE UNIQUE INDEX IF NOT EXISTS idx_price_books_default ON price_books(model_id) WHERE is_default;
+
+CREATE TABLE IF NOT EXISTS price_book_entries (
+    model_id VARCHAR(100) NOT NULL,
+    price_book_id VARCHAR(100) NOT NULL,
+    option_id VARCHAR(100) NOT NULL,
+    price DECIMAL(12,2) NOT NULL CHECK (price >= 0),
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    effective_to TIMESTAMP WITH TIME ZONE, -- Exclusive; NULL for open-ended
+
+    PRIMARY KEY (model_id, price_book_id, option_id, effective_from),
+    FOREIGN KEY (model_id, price_book_id) REFERENCES price_books(model_id, id) ON DELETE CASCADE,
+    CHECK (effective_to IS NULL OR effective_to > effective_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_book_entries_option ON price_book_entries(model_id, option_id);
+
+-- Exchange rates are maintained locally; a rate applies from its effective date
+-- until a later rate for the same pair takes over
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    from_currency CHAR(3) NOT NULL,
+    to_currency CHAR(3) NOT NULL,
+    rate DECIMAL(18,8) NOT NULL CHECK (rate > 0), -- Units of to_currency per from_currency
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (from_currency, to_currency, effective_from)
+);
+
+-- The pricing context a configuration session is priced in
+ALTER TABLE configuration_sessions ADD COLUMN IF NOT EXISTS pricing_context JSONB DEFAULT '{}';
+
+CREATE TRIGGER update_price_books_updated_at BEFORE UPDATE ON price_books FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,42 @@
+-- database/init/08_customer_contracts.sql
+-- Customer accounts and contract pricing terms applied after list pricing
+
+CREATE TABLE IF NOT EXISTS customers (
+    id VARCHAR(100) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS contracts (
+    id VARCHAR(100) PRIMARY KEY,
+    customer_id VARCHAR(100) NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    model_id VARCHAR(100) REFERENCES models(id) ON DELETE CASCADE, -- NULL applies to every model
+    currency CHAR(3), -- NULL applies in any currency
+    discount_percent DECIMAL(5,2) NOT NULL DEFAULT 0 CHECK (discount_percent BETWEEN 0 AND 100),
+    max_discount_percent DECIMAL(5,2) NOT NULL DEFAULT 0 CHECK (max_discount_percent BETWEEN 0 AND 100), -- 0 for no cap
+    min_total DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (min_total >= 0), -- 0 for no floor
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE, -- Exclusive; NULL never expires
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CHECK (expires_at IS NULL OR expires_at > effective_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_contracts_customer ON contracts(customer_id);
+
+-- Negotiated unit prices
+CREATE TABLE IF NOT EXISTS contract_prices (
+    contract_id VARCHAR(100) NOT NULL REFERENCES contracts(id) ON DELETE CASCADE,
+    option_id VARCHAR(100) NOT NULL,
+    price DECIMAL(12,2) NOT NULL CHECK (price >= 0),
+
+    PRIMARY KEY (contract_id, option_id)
+);
+
+CREATE TRIGGER update_customers_updated_at BEFORE UPDATE ON customers FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_contracts_updated_at BEFORE UPDATE ON contracts FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,10 @@
+-- database/init/09_price_components.sql
+-- Recurring, one-time and usage price components on options, priced over a
+-- contract term
+
+-- Components besides the option base price, e.g.
+-- [{"id": "support", "name": "Support", "charge_type": "recurring", "price": 120, "period": "annual"}]
+ALTER TABLE options ADD COLUMN IF NOT EXISTS price_components JSONB NOT NULL DEFAULT '[]';
+
+-- Default contract term in months; 0 uses the 12-month default
+ALTER TABLE models ADD COLUMN IF NOT EXISTS term_months INTEGER NOT NULL DEFAULT 0 CHECK (term_months >= 0);
@@ -0,0 +1,9 @@
+-- database/init/10_margin_floors.sql
+-- Option and price component costs with margin floors guarding discounts
+
+-- Unit cost in the model currency; price component costs live in price_components
+ALTER TABLE options ADD COLUMN IF NOT EXISTS cost DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (cost >= 0);
+
+-- Minimum margins for the model or a group, e.g.
+-- [{"id": "floor", "name": "Floor", "min_margin_percent": 20, "action": "approval", "approver_role": "user"}]
+ALTER TABLE models ADD COLUMN IF NOT EXISTS margin_floors JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,20 @@
+-- database/init/11_approvals.sql
+-- Discount approval policies on models and the approval workflow of
+-- configuration sessions
+
+-- Policies requiring approval when a rule-language condition over the price
+-- breakdown holds, e.g.
+-- [{"id": "deep_discount", "name": "Deep discount", "condition": "discount_pct > 15 OR margin_pct < 30", "approver_role": "manager"}]
+ALTER TABLE models ADD COLUMN IF NOT EXISTS approval_policies JSONB NOT NULL DEFAULT '[]';
+
+-- Requested discount and approval state with its history
+ALTER TABLE configuration_sessions ADD COLUMN IF NOT EXISTS discount_percent DECIMAL(5,2) NOT NULL DEFAULT 0
+    CHECK (discount_percent >= 0 AND discount_percent <= 100);
+ALTER TABLE configuration_sessions ADD COLUMN IF NOT EXISTS approval JSONB DEFAULT NULL;
+
+-- Sessions move through draft -> pending_approval -> approved/rejected
+ALTER TABLE configuration_sessions DROP CONSTRAINT IF EXISTS configuration_sessions_status_check;
+ALTER TABLE configuration_sessions ADD CONSTRAINT configuration_sessions_status_check
+    CHECK (status IN ('draft', 'validated', 'pending_approval', 'approved', 'rejected', 'completed', 'abandoned'));
+
+CREATE INDEX IF NOT EXISTS idx_sessions_pending_approval ON configuration_sessions(status) WHERE status = 'pending_approval';
@@ -0,0 +1,20 @@
+-- database/init/12_tax.sql
+-- Jurisdiction tax rates, product tax categories and customer exemptions
+
+CREATE TABLE IF NOT EXISTS tax_rates (
+    country CHAR(2) NOT NULL,
+    region VARCHAR(10) NOT NULL DEFAULT '', -- Empty applies to the whole country
+    postal_prefix VARCHAR(20) NOT NULL DEFAULT '', -- Empty applies to the whole region
+    category VARCHAR(50) NOT NULL DEFAULT '', -- Empty is the jurisdiction's standard rate
+    name VARCHAR(100) NOT NULL,
+    rate DECIMAL(7,4) NOT NULL CHECK (rate BETWEEN 0 AND 100), -- Percent
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (country, region, postal_prefix, category)
+);
+
+-- Product tax category of an option; empty takes standard rates
+ALTER TABLE options ADD COLUMN IF NOT EXISTS tax_category VARCHAR(50) NOT NULL DEFAULT '';
+
+-- Exemptions held by a customer account
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS tax_exemptions JSONB NOT NULL DEFAULT '[]';
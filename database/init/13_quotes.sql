@@ -0,0 +1,41 @@
+-- database/init/13_quotes.sql
+-- Multi-line quotes of configured products and plain SKUs
+
+CREATE TABLE IF NOT EXISTS quotes (
+    id VARCHAR(100) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    customer_id VARCHAR(100) REFERENCES customers(id) ON DELETE SET NULL,
+    currency CHAR(3), -- NULL uses the default currency
+    status VARCHAR(20) NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'sent', 'accepted', 'declined')), -- Expiry is derived from valid_until
+    valid_until TIMESTAMP WITH TIME ZONE NOT NULL,
+    discount_percent DECIMAL(5,2) NOT NULL DEFAULT 0 CHECK (discount_percent BETWEEN 0 AND 100),
+    volume_tiers JSONB NOT NULL DEFAULT '[]', -- Spanning the units of all lines
+    notes TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_quotes_customer ON quotes(customer_id);
+
+CREATE TABLE IF NOT EXISTS quote_lines (
+    quote_id VARCHAR(100) NOT NULL REFERENCES quotes(id) ON DELETE CASCADE,
+    id VARCHAR(100) NOT NULL,
+    position INTEGER NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('configured', 'product')),
+    description TEXT NOT NULL DEFAULT '',
+    quantity INTEGER NOT NULL CHECK (quantity >= 1),
+    discount_percent DECIMAL(5,2) NOT NULL DEFAULT 0 CHECK (discount_percent BETWEEN 0 AND 100),
+    configuration_id VARCHAR(100), -- Configuration the line was added from, if any
+    model_id VARCHAR(100) REFERENCES models(id), -- Configured lines
+    selections JSONB NOT NULL DEFAULT '[]', -- Snapshot of the configured selections
+    sku VARCHAR(100), -- Product lines
+    unit_price DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (unit_price >= 0),
+    unit_cost DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (unit_cost >= 0),
+
+    PRIMARY KEY (quote_id, id),
+    CHECK ((type = 'configured' AND model_id IS NOT NULL) OR (type = 'product' AND sku IS NOT NULL))
+);
+
+CREATE TRIGGER update_quotes_updated_at BEFORE UPDATE ON quotes FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,18 @@
+-- database/init/14_assemblies.sql
+-- Sub-assemblies: options configured from a child model, rules across
+-- assembly levels and the child configurations of configurations
+
+-- Each unit of the option is a sub-assembly configured from this model
+ALTER TABLE options ADD COLUMN IF NOT EXISTS child_model_id VARCHAR(100) REFERENCES models(id);
+
+-- Conditions over the attributes of an assembly and its sub-assemblies, e.g.
+-- [{"id": "power", "name": "Rack power", "expression": "power_watts >= servers_psu_watts", "message": "Servers draw more power than the rack supplies"}]
+ALTER TABLE models ADD COLUMN IF NOT EXISTS assembly_rules JSONB NOT NULL DEFAULT '[]';
+
+-- Child configurations, nested to any depth, of configurations, sessions
+-- and configured quote lines
+ALTER TABLE configurations ADD COLUMN IF NOT EXISTS children JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE configuration_sessions ADD COLUMN IF NOT EXISTS children JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE quote_lines ADD COLUMN IF NOT EXISTS children JSONB NOT NULL DEFAULT '[]';
+
+CREATE INDEX IF NOT EXISTS idx_options_child_model ON options(child_model_id) WHERE child_model_id IS NOT NULL;
@@ -0,0 +1,12 @@
+-- database/init/15_quote_pricing.sql
+-- Pricing of quotes: the context a quote is priced in and the prices it
+-- was sent with
+
+-- Price book, pricing date, ship-to address and tax display of a quote; its
+-- currency is the quote's own
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS pricing_context JSONB NOT NULL DEFAULT '{}';
+
+-- Snapshot of the quote's pricing taken when it is sent, so later changes to
+-- models, rates or contracts do not change the prices the customer received;
+-- NULL for drafts
+ALTER TABLE quotes ADD COLUMN IF NOT EXISTS pricing JSONB;
@@ -0,0 +1,163 @@
+// conflict.go - Minimal unsatisfiable core extraction over compiled MTBDDs
+package evaluator
+
+import (
+	"DD/i18n"
+	"DD/mtbdd"
+)
+
+// ===================================================================
+// CONFLICT TYPES
+// ===================================================================
+
+// Conflict constraint kinds
+const (
+	ConflictSelection = "selection"
+	ConflictRule      = "rule"
+)
+
+// ConflictConstraint is a selection or rule that may take part in a
+// conflict, compiled to a boolean MTBDD
+type ConflictConstraint struct {
+	ID         string        `json:"id"`
+	Kind       string        `json:"kind"` // ConflictSelection or ConflictRule
+	Name       string        `json:"name"`
+	Expression string        `json:"expression,omitempty"`
+	BDD        mtbdd.NodeRef `json:"-"`
+	Label      i18n.Message  `json:"-"` // Localizable name, replaces Name when set
+}
+
+// ConflictExplanation is a minimal set of selections and rules that cannot
+// hold together: removing any one of them makes the rest satisfiable
+type ConflictExplanation struct {
+	HasConflict bool                 `json:"has_conflict"`
+	Selections  []ConflictConstraint `json:"selections"`
+	Rules       []ConflictConstraint `json:"rules"`
+	Message     string               `json:"message"`
+	Checks      int                  `json:"checks"` // Satisfiability checks performed
+}
+
+// ===================================================================
+// QUICKXPLAIN
+// ===================================================================
+
+// ExplainConflict finds a minimal unsatisfiable subset of constraints with
+// QuickXplain. The conflict is drawn from the shortest inconsistent prefix
+// of constraints, so listing rules before selections in the order they were
+// made explains a conflict by the earliest selections that caused it.
+// Satisfiability is a conjunction that is not the FALSE terminal.
+func (e *Explainer) ExplainConflict(manager *mtbdd.MTBDD, constraints []ConflictConstraint) *ConflictExplanation {
+	x := &quickXplain{manager: manager}
+	explanation := &ConflictExplanation{Selections: []ConflictConstraint{}, Rules: []ConflictConstraint{}}
+
+	if x.consistent(x.conjoin(mtbdd.TrueRef, constraints)) {
+		explanation.Message = e.localizer.T("conflict.none", nil)
+		explanation.Checks = x.checks
+		return explanation
+	}
+
+	explanation.HasConflict = true
+	for _, constraint := range x.search(mtbdd.TrueRef, false, constraints) {
+		constraint.Name = e.conflictName(constraint)
+		if constraint.Kind == ConflictSelection {
+			explanation.Selections = append(explanation.Selections, constraint)
+		} else {
+			explanation.Rules = append(explanation.Rules, constraint)
+		}
+	}
+	explanation.Message = conflictMessage(e.localizer, explanation)
+	explanation.Checks = x.checks
+	return explanation
+}
+
+// Localize renders the names of labelled constraints and the message in
+// the localizer's language
+func (ce *ConflictExplanation) Localize(localizer *i18n.Localizer) {
+	for _, constraints := range [][]ConflictConstraint{ce.Selections, ce.Rules} {
+		for i := range constraints {
+			if !constraints[i].Label.IsZero() {
+				constraints[i].Name = localizer.Render(constraints[i].Label)
+			}
+		}
+	}
+	if ce.HasConflict {
+		ce.Message = conflictMessage(localizer, ce)
+	} else {
+		ce.Message = localizer.T("conflict.none", nil)
+	}
+}
+
+// quickXplain holds the manager and counts satisfiability checks
+type quickXplain struct {
+	manager *mtbdd.MTBDD
+	checks  int
+}
+
+// search returns a minimal subset of candidates that conflicts with the
+// background. hasDelta is set when constraints were just added to the
+// background, so an inconsistent background needs none of the candidates.
+func (x *quickXplain) search(background mtbdd.NodeRef, hasDelta bool, candidates []ConflictConstraint) []ConflictConstraint {
+	if hasDelta && !x.consistent(background) {
+		return nil
+	}
+	if len(candidates) == 1 {
+		return candidates
+	}
+
+	half := len(candidates) / 2
+	first, second := candidates[:half], candidates[half:]
+
+	secondConflict := x.search(x.conjoin(background, first), len(first) > 0, second)
+	firstConflict := x.search(x.conjoin(background, secondConflict), len(secondConflict) > 0, first)
+
+	result := make([]ConflictConstraint, 0, len(firstConflict)+len(secondConflict))
+	result = append(result, firstConflict...)
+	return append(result, secondConflict...)
+}
+
+func (x *quickXplain) conjoin(background mtbdd.NodeRef, constraints []ConflictConstraint) mtbdd.NodeRef {
+	for _, constraint := range constraints {
+		background = x.manager.AND(background, constraint.BDD)
+	}
+	return background
+}
+
+func (x *quickXplain) consistent(bdd mtbdd.NodeRef) bool {
+	x.checks++
+	return bdd != mtbdd.FalseRef
+}
+
+// conflictMessage summarizes the conflict by constraint name
+func conflictMessage(localizer *i18n.Localizer, explanation *ConflictExplanation) string {
+	var selections, rules []string
+	for _, selection := range explanation.Selections {
+		selections = append(selections, selection.Name)
+	}
+	for _, rule := range explanation.Rules {
+		rules = append(rules, rule.Name)
+	}
+
+	switch {
+	case len(selections) == 0 && len(rules) == 1:
+		return localizer.T("conflict.rule_unsatisfiable", i18n.Args{"rule": rules[0]})
+	case len(selections) == 0:
+		return localizer.T("conflict.rules", i18n.Args{"rules": localizer.Join(rules)})
+	case len(selections) == 1:
+		return localizer.T("conflict.selection", i18n.Args{"selection": selections[0], "rules": localizer.Join(rules)})
+	default:
+		return localizer.T("conflict.selections", i18n.Args{"selections": localizer.Join(selections), "rules": localizer.Join(rules)})
+	}
+}
+
+func (e *Explainer) conflictName(constraint ConflictConstraint) string {
+	if !constraint.Label.IsZero() {
+		return e.localizer.Render(constraint.Label)
+	}
+	if constraint.Name != "" {
+		return constraint.Name
+	}
+	if constraint.Kind == ConflictSelection {
+		return e.getDisplayName(constraint.ID)
+	}
+	return constraint.ID
+}
@@ -0,0 +1,119 @@
+package evaluator
+
+import (
+	"DD/mtbdd"
+	"reflect"
+	"testing"
+)
+
+// TestExplainConflict tests that QuickXplain returns a minimal conflict
+func TestExplainConflict(t *testing.T) {
+	manager := mtbdd.NewMTBDD()
+	manager.Declare("a", "b", "c", "d")
+
+	compile := func(kind, id, expression string) ConflictConstraint {
+		bdd, _, err := mtbdd.ParseAndCompile(expression, manager)
+		if err != nil {
+			t.Fatalf("Failed to compile %s: %v", expression, err)
+		}
+		return ConflictConstraint{ID: id, Kind: kind, Expression: expression, BDD: bdd}
+	}
+	rule := func(id, expression string) ConflictConstraint { return compile(ConflictRule, id, expression) }
+	selection := func(id string) ConflictConstraint { return compile(ConflictSelection, id, id) }
+
+	tests := []struct {
+		name        string
+		constraints []ConflictConstraint
+		selections  []string
+		rules       []string
+		message     string
+	}{
+		{
+			name:        "consistent",
+			constraints: []ConflictConstraint{rule("r1", "a -> b"), selection("a")},
+			message:     "The selections can be completed to a valid configuration",
+		},
+		{
+			// d forces a through r3, and d was selected first
+			name: "chain through rules",
+			constraints: []ConflictConstraint{
+				rule("r1", "a -> c"), rule("r2", "b -> !c"), rule("r3", "d -> a"),
+				selection("d"), selection("a"), selection("b"),
+			},
+			selections: []string{"d", "b"},
+			rules:      []string{"r1", "r2", "r3"},
+			message:    "Selecting D, B together conflicts with r1, r2, r3",
+		},
+		{
+			name: "earliest selections",
+			constraints: []ConflictConstraint{
+				rule("r1", "!(a && b)"), rule("r2", "!(c && d)"),
+				selection("c"), selection("d"), selection("a"), selection("b"),
+			},
+			selections: []string{"c", "d"},
+			rules:      []string{"r2"},
+			message:    "Selecting C, D together conflicts with r2",
+		},
+		{
+			name:        "display names",
+			constraints: []ConflictConstraint{rule("r1", "a -> !b"), selection("a"), selection("b")},
+			selections:  []string{"a", "b"},
+			rules:       []string{"r1"},
+			message:     "Selecting Alpha, B together conflicts with r1",
+		},
+		{
+			name:        "contradicting rules",
+			constraints: []ConflictConstraint{rule("r1", "a"), rule("r2", "b -> c"), rule("r3", "!a"), selection("b")},
+			rules:       []string{"r1", "r3"},
+			message:     "Rules r1, r3 contradict each other",
+		},
+		{
+			name:        "unsatisfiable rule",
+			constraints: []ConflictConstraint{selection("a"), rule("r1", "b && !b")},
+			rules:       []string{"r1"},
+			message:     "Rule r1 can never be satisfied",
+		},
+	}
+
+	explainer := NewExplainer()
+	explainer.SetVariableDisplayName("a", "Alpha")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			explanation := explainer.ExplainConflict(manager, tt.constraints)
+			if explanation.HasConflict != (len(tt.rules) > 0) {
+				t.Fatalf("Expected conflict %v, got %+v", len(tt.rules) > 0, explanation)
+			}
+
+			ids := func(constraints []ConflictConstraint) []string {
+				result := []string{}
+				for _, c := range constraints {
+					result = append(result, c.ID)
+				}
+				return result
+			}
+			if got := ids(explanation.Selections); !reflect.DeepEqual(got, append([]string{}, tt.selections...)) {
+				t.Errorf("Expected selections %v, got %v", tt.selections, got)
+			}
+			if got := ids(explanation.Rules); !reflect.DeepEqual(got, append([]string{}, tt.rules...)) {
+				t.Errorf("Expected rules %v, got %v", tt.rules, got)
+			}
+			if explanation.Message != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, explanation.Message)
+			}
+
+			// Dropping any member of the conflict makes the rest satisfiable
+			conflict := append(append([]ConflictConstraint{}, explanation.Rules...), explanation.Selections...)
+			for i := range conflict {
+				rest := mtbdd.TrueRef
+				for j, c := range conflict {
+					if j != i {
+						rest = manager.AND(rest, c.BDD)
+					}
+				}
+				if rest == mtbdd.FalseRef {
+					t.Errorf("Conflict is not minimal without %s", conflict[i].ID)
+				}
+			}
+		})
+	}
+}
@@ -4,10 +4,15 @@ package evaluator
 
 import (
 	"DD/parser"
+	"errors"
 	"fmt"
 	"math"
 )
 
+// ErrDivisionByZero is wrapped by evaluation errors from dividing, or
+// taking the modulo, by zero
+var ErrDivisionByZero = errors.New("division by zero")
+
 // ===== TYPE DEFINITIONS =====
 
 // Context provides variable values for expression evaluation
@@ -222,6 +227,7 @@ func (e *Evaluator) applyBinaryOperation(node *parser.BinaryOperation, leftVal,
 					Range:      node.Right.GetRange(),
 					ErrorType:  "semantic",
 					Suggestion: "Ensure divisor is not zero",
+					Err:        ErrDivisionByZero,
 				}
 			}
 			return leftNum / rightNum, nil
@@ -232,6 +238,7 @@ func (e *Evaluator) applyBinaryOperation(node *parser.BinaryOperation, leftVal,
 					Range:      node.Right.GetRange(),
 					ErrorType:  "semantic",
 					Suggestion: "Ensure divisor is not zero",
+					Err:        ErrDivisionByZero,
 				}
 			}
 			return math.Mod(leftNum, rightNum), nil
@@ -2,6 +2,7 @@
 package evaluator
 
 import (
+	"DD/i18n"
 	"DD/parser"
 	"fmt"
 	"sort"
@@ -84,6 +85,8 @@ type CompiledDependencyGraph struct {
 	HasCycles          bool             `json:"has_cycles"`
 	CyclicDependencies [][]string       `json:"cyclic_dependencies"`
 	CompilationStats   CompilationStats `json:"compilation_stats"`
+
+	localizer *i18n.Localizer // Explainer's localizer at compile time
 }
 
 // CompilationStats tracks performance and complexity metrics
@@ -149,6 +152,8 @@ type Explainer struct {
 	// Compilation state
 	isCompiled    bool
 	compiledGraph *CompiledDependencyGraph
+
+	localizer *i18n.Localizer // Language of generated explanations
 }
 
 // NewExplainer creates a new explainer visitor
@@ -163,6 +168,7 @@ func NewExplainer() *Explainer {
 		reverseGraph:     make(map[string][]string),
 		ruleGraph:        make(map[string][]string),
 		isCompiled:       false,
+		localizer:        i18n.English(),
 	}
 }
 
@@ -196,6 +202,12 @@ func (e *Explainer) SetVariableDisplayName(varID, displayName string) {
 	e.variableNames[varID] = displayName
 }
 
+// SetLocalizer sets the language of explanations generated afterwards;
+// explanations default to English
+func (e *Explainer) SetLocalizer(localizer *i18n.Localizer) {
+	e.localizer = localizer
+}
+
 // ===================================================================
 // VISITOR PATTERN IMPLEMENTATION
 // ===================================================================
@@ -334,7 +346,7 @@ func (e *Explainer) handleNegation(node *parser.UnaryOperation) (interface{}, er
 			Conditions:  []string{},
 			RuleID:      e.currentRuleID,
 			RuleName:    e.currentRuleName,
-			Explanation: fmt.Sprintf("%s is not allowed", e.getDisplayName(variable)),
+			Explanation: e.localizer.T("explain.not_allowed", i18n.Args{"option": e.getDisplayName(variable)}),
 		}
 
 		e.addExclusion(exclusion)
@@ -410,7 +422,7 @@ func (e *Explainer) handleExcludesFunction(node *parser.FunctionCall) (interface
 				Conditions:  []string{},
 				RuleID:      e.currentRuleID,
 				RuleName:    e.currentRuleName,
-				Explanation: fmt.Sprintf("%s excludes %s", e.getDisplayName(source), e.getDisplayName(target)),
+				Explanation: e.localizer.T("explain.excludes", i18n.Args{"source": e.getDisplayName(source), "target": e.getDisplayName(target)}),
 			}
 			e.addExclusion(exclusion)
 		}
@@ -573,6 +585,7 @@ func (e *Explainer) Compile() (*CompiledDependencyGraph, error) {
 		Rules:                  e.getAllRules(),
 		HasCycles:              false,
 		CyclicDependencies:     [][]string{},
+		localizer:              e.localizer,
 	}
 
 	// Step 1: Detect cycles
@@ -958,7 +971,7 @@ func (e *Explainer) generateDependencyExplanation(source, target string, conditi
 	targetName := e.getDisplayName(target)
 
 	if len(conditions) == 0 {
-		return fmt.Sprintf("%s requires %s", sourceName, targetName)
+		return e.localizer.T("explain.requires", i18n.Args{"source": sourceName, "target": targetName})
 	}
 
 	conditionNames := make([]string, len(conditions))
@@ -966,12 +979,19 @@ func (e *Explainer) generateDependencyExplanation(source, target string, conditi
 		conditionNames[i] = e.getDisplayName(condition)
 	}
 
-	return fmt.Sprintf("%s requires %s when %s", sourceName, targetName, strings.Join(conditionNames, " and "))
+	return e.localizer.T("explain.requires_when", i18n.Args{
+		"source":     sourceName,
+		"target":     targetName,
+		"conditions": e.joinAnd(conditionNames),
+	})
 }
 
 // generateImplicationExplanation creates explanations for complex implications
 func (e *Explainer) generateImplicationExplanation(conditions, consequences ConditionAnalysis) string {
-	return fmt.Sprintf("When %s, then %s", conditions.description, consequences.description)
+	return e.localizer.T("explain.when_then", i18n.Args{
+		"conditions":   conditions.description,
+		"consequences": consequences.description,
+	})
 }
 
 // generateConditionDescription creates readable descriptions of conditions
@@ -986,16 +1006,18 @@ func (e *Explainer) generateConditionDescription(expr parser.Expression, variabl
 		if node.Operator == parser.TOKEN_AND {
 			leftVars := e.extractVariables(node.Left)
 			rightVars := e.extractVariables(node.Right)
-			return fmt.Sprintf("%s and %s",
-				e.generateConditionDescription(node.Left, leftVars),
-				e.generateConditionDescription(node.Right, rightVars))
+			return e.localizer.T("explain.and", i18n.Args{
+				"left":  e.generateConditionDescription(node.Left, leftVars),
+				"right": e.generateConditionDescription(node.Right, rightVars),
+			})
 		}
 		if node.Operator == parser.TOKEN_OR {
 			leftVars := e.extractVariables(node.Left)
 			rightVars := e.extractVariables(node.Right)
-			return fmt.Sprintf("%s or %s",
-				e.generateConditionDescription(node.Left, leftVars),
-				e.generateConditionDescription(node.Right, rightVars))
+			return e.localizer.T("explain.or", i18n.Args{
+				"left":  e.generateConditionDescription(node.Left, leftVars),
+				"right": e.generateConditionDescription(node.Right, rightVars),
+			})
 		}
 	}
 
@@ -1004,7 +1026,19 @@ func (e *Explainer) generateConditionDescription(expr parser.Expression, variabl
 	for i, variable := range variables {
 		displayNames[i] = e.getDisplayName(variable)
 	}
-	return strings.Join(displayNames, " and ")
+	return e.joinAnd(displayNames)
+}
+
+// joinAnd joins names as "a and b and c"
+func (e *Explainer) joinAnd(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	joined := names[0]
+	for _, name := range names[1:] {
+		joined = e.localizer.T("explain.and", i18n.Args{"left": joined, "right": name})
+	}
+	return joined
 }
 
 // generateConsequenceDescription creates readable descriptions of consequences
@@ -1187,10 +1221,19 @@ func (cdg *CompiledDependencyGraph) generatePathExplanation(path []string) strin
 		sourceName := cdg.getDisplayName(source)
 		targetName := cdg.getDisplayName(target)
 
-		parts = append(parts, fmt.Sprintf("%s requires %s", sourceName, targetName))
+		parts = append(parts, cdg.localize().T("explain.requires", i18n.Args{"source": sourceName, "target": targetName}))
 	}
 
-	return strings.Join(parts, ", which ")
+	return strings.Join(parts, cdg.localize().T("explain.path_separator", nil))
+}
+
+// localize returns the localizer the graph was compiled with; graphs
+// decoded from JSON explain in English
+func (cdg *CompiledDependencyGraph) localize() *i18n.Localizer {
+	if cdg.localizer == nil {
+		return i18n.English()
+	}
+	return cdg.localizer
 }
 
 func (cdg *CompiledDependencyGraph) getDisplayName(variable string) string {
@@ -1267,14 +1310,14 @@ func (cdg *CompiledDependencyGraph) findBlockingPaths(
 
 func (cdg *CompiledDependencyGraph) generateChainExplanation(paths []DependencyPath) string {
 	if len(paths) == 0 {
-		return "Option is unavailable due to constraint violations"
+		return cdg.localize().T("explain.unavailable", nil)
 	}
 
 	if len(paths) == 1 {
 		return paths[0].Explanation
 	}
 
-	return fmt.Sprintf("Option is unavailable due to %d dependency chains", len(paths))
+	return cdg.localize().T("explain.unavailable_chains", i18n.Args{"count": len(paths)})
 }
 
 func (cdg *CompiledDependencyGraph) generateSuggestions(
@@ -1287,8 +1330,9 @@ func (cdg *CompiledDependencyGraph) generateSuggestions(
 			// Suggest changing the first selected item in the path
 			firstSelected := path.Path[0]
 			if selections[firstSelected] {
-				suggestion := fmt.Sprintf("Change %s to enable this option",
-					cdg.getDisplayName(firstSelected))
+				suggestion := cdg.localize().T("explain.change_to_enable", i18n.Args{
+					"option": cdg.getDisplayName(firstSelected),
+				})
 				suggestions = append(suggestions, suggestion)
 			}
 		}
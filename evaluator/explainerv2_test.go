@@ -2,6 +2,7 @@
 package evaluator
 
 import (
+	"DD/i18n"
 	"DD/parser"
 	"fmt"
 	"strings"
@@ -189,6 +190,23 @@ func TestExplainer_NegationRule(t *testing.T) {
 	}
 }
 
+func TestExplainer_Localizer(t *testing.T) {
+	explainer := createTestExplainer()
+	explainer.SetLocalizer(i18n.Default.Localizer("de"))
+
+	processRule(t, explainer, "rule1", "Cooling", "opt_cpu_high -> opt_cooling_liquid")
+	processRule(t, explainer, "rule2", "No Budget CPU", "NOT opt_cpu_low")
+
+	dependencies := explainer.GetDependencies("opt_cpu_high")
+	if len(dependencies) != 1 || dependencies[0].Explanation != "High-end CPU erfordert Liquid Cooling" {
+		t.Errorf("Expected German dependency explanation, got %+v", dependencies)
+	}
+	exclusions := explainer.GetExclusions("any")
+	if len(exclusions) != 1 || exclusions[0].Explanation != "Budget CPU ist nicht zulässig" {
+		t.Errorf("Expected German exclusion explanation, got %+v", exclusions)
+	}
+}
+
 // ===================================================================
 // COMPILATION TESTS
 // ===================================================================
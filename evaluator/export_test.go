@@ -0,0 +1,21 @@
+package evaluator
+
+// CorpusCase is a parse-and-evaluate case from the language test tables,
+// exported for differential tests of other evaluators
+type CorpusCase struct {
+	Name       string
+	Expression string
+	Variables  Context
+}
+
+// Corpus returns every case of TestComprehensiveLanguageFeatures and
+// TestInfixLogicalOperators, including those expected to fail
+func Corpus() []CorpusCase {
+	var cases []CorpusCase
+	for _, tests := range [][]languageTest{languageFeatureTests, infixLogicalTests} {
+		for _, tt := range tests {
+			cases = append(cases, CorpusCase{Name: tt.name, Expression: tt.expression, Variables: tt.variables})
+		}
+	}
+	return cases
+}
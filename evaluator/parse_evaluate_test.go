@@ -12,705 +12,711 @@ import (
 	"time"
 )
 
-// TestComprehensiveLanguageFeatures tests every language construct
-func TestComprehensiveLanguageFeatures(t *testing.T) {
-	tests := []struct {
-		name        string
-		expression  string
-		variables   Context
-		expected    interface{}
-		shouldError bool
-		errorType   string
-	}{
-		// ==== ARITHMETIC OPERATIONS ====
-		{
-			name:       "Basic Addition",
-			expression: "x + y",
-			variables:  Context{"x": 5, "y": 3},
-			expected:   8.0,
-		},
-		{
-			name:       "Basic Subtraction",
-			expression: "x - y",
-			variables:  Context{"x": 10, "y": 4},
-			expected:   6.0,
-		},
-		{
-			name:       "Basic Multiplication",
-			expression: "x * y",
-			variables:  Context{"x": 6, "y": 7},
-			expected:   42.0,
-		},
-		{
-			name:       "Basic Division",
-			expression: "x / y",
-			variables:  Context{"x": 15, "y": 3},
-			expected:   5.0,
-		},
-		{
-			name:       "Modulo Operation",
-			expression: "x % y",
-			variables:  Context{"x": 17, "y": 5},
-			expected:   2.0,
-		},
-		{
-			name:       "Complex Arithmetic with Precedence",
-			expression: "x + y * z - w / v",
-			variables:  Context{"x": 10, "y": 2, "z": 5, "w": 8, "v": 4},
-			expected:   18.0, // 10 + (2*5) - (8/4) = 10 + 10 - 2 = 18
-		},
-		{
-			name:       "Parentheses Override Precedence",
-			expression: "(x + y) * (z - w)",
-			variables:  Context{"x": 3, "y": 2, "z": 8, "w": 3},
-			expected:   25.0, // (3+2) * (8-3) = 5 * 5 = 25
-		},
-		{
-			name:       "Unary Plus",
-			expression: "+x + +y",
-			variables:  Context{"x": 5, "y": -3},
-			expected:   2.0,
-		},
-		{
-			name:       "Unary Minus",
-			expression: "-x + -y",
-			variables:  Context{"x": 5, "y": -3},
-			expected:   -2.0, // -5 + -(-3) = -5 + 3 = -2
-		},
-
-		// ==== NUMERIC TYPE COMPATIBILITY ====
-		{
-			name:       "Mixed Numeric Types int + float64",
-			expression: "x + y",
-			variables:  Context{"x": int(5), "y": 3.5},
-			expected:   8.5,
-		},
-		{
-			name:       "Mixed Numeric Types int32 * float32",
-			expression: "x * y",
-			variables:  Context{"x": int32(4), "y": float32(2.5)},
-			expected:   10.0,
-		},
-		{
-			name:       "Mixed Numeric Types uint + int",
-			expression: "x + y",
-			variables:  Context{"x": uint(10), "y": int(-3)},
-			expected:   7.0,
-		},
-
-		// ==== MIN/MAX OPERATIONS ====
-		{
-			name:       "MIN Function",
-			expression: "MIN(x, y)",
-			variables:  Context{"x": 10, "y": 5},
-			expected:   5.0,
-		},
-		{
-			name:       "MAX Function",
-			expression: "MAX(x, y)",
-			variables:  Context{"x": 10, "y": 15},
-			expected:   15.0,
-		},
-		{
-			name:       "Nested MIN/MAX",
-			expression: "MIN(MAX(x, y), z)",
-			variables:  Context{"x": 5, "y": 10, "z": 7},
-			expected:   7.0, // MIN(MAX(5,10), 7) = MIN(10, 7) = 7
-		},
-		{
-			name:       "MIN with Infix Syntax",
-			expression: "x MIN y",
-			variables:  Context{"x": 10, "y": 5},
-			expected:   5.0,
-		},
-		{
-			name:       "MAX with Infix Syntax",
-			expression: "x MAX y",
-			variables:  Context{"x": 10, "y": 15},
-			expected:   15.0,
-		},
-
-		// ==== BOOLEAN EQUALITY COMPARISONS ====
-		{
-			name:       "Boolean Equality True == True",
-			expression: "x == y",
-			variables:  Context{"x": true, "y": true},
-			expected:   true,
-		},
-		{
-			name:       "Boolean Equality True == False",
-			expression: "x == y",
-			variables:  Context{"x": true, "y": false},
-			expected:   false,
-		},
-		{
-			name:       "Boolean Inequality True != False",
-			expression: "x != y",
-			variables:  Context{"x": true, "y": false},
-			expected:   true,
-		},
-		{
-			name:       "Boolean Inequality False != False",
-			expression: "x != y",
-			variables:  Context{"x": false, "y": false},
-			expected:   false,
-		},
-		{
-			name:       "Boolean Literals Equality",
-			expression: "true == false",
-			variables:  Context{},
-			expected:   false,
-		},
-		{
-			name:       "Boolean Literals Inequality",
-			expression: "true != false",
-			variables:  Context{},
-			expected:   true,
-		},
-		{
-			name:       "Complex Boolean Comparison",
-			expression: "(x > 5) == (y < 10)",
-			variables:  Context{"x": 6, "y": 8},
-			expected:   true, // (true) == (true) = true
-		},
-		{
-			name:       "Complex Boolean Inequality",
-			expression: "(x > 5) != (y > 10)",
-			variables:  Context{"x": 6, "y": 8},
-			expected:   true, // (true) != (false) = true
-		},
-
-		// ==== COMPARISON OPERATIONS ====
-		{
-			name:       "Greater Than",
-			expression: "x > y",
-			variables:  Context{"x": 10, "y": 5},
-			expected:   true,
-		},
-		{
-			name:       "Greater Than or Equal",
-			expression: "x >= y",
-			variables:  Context{"x": 5, "y": 5},
-			expected:   true,
-		},
-		{
-			name:       "Less Than",
-			expression: "x < y",
-			variables:  Context{"x": 3, "y": 8},
-			expected:   true,
-		},
-		{
-			name:       "Less Than or Equal",
-			expression: "x <= y",
-			variables:  Context{"x": 5, "y": 5},
-			expected:   true,
-		},
-		{
-			name:       "Equality",
-			expression: "x == y",
-			variables:  Context{"x": 7, "y": 7},
-			expected:   true,
-		},
-		{
-			name:       "Inequality",
-			expression: "x != y",
-			variables:  Context{"x": 7, "y": 8},
-			expected:   true,
-		},
-		{
-			name:       "Chained Comparisons (Left Associative)",
-			expression: "(x > y) == (z < w)",
-			variables:  Context{"x": 10, "y": 5, "z": 3, "w": 8},
-			expected:   true, // (10>5) == (3<8) = true == true = true
-		},
-
-		// ==== BOOLEAN OPERATIONS ====
-		{
-			name:       "Boolean AND (&&)",
-			expression: "x && y",
-			variables:  Context{"x": true, "y": true},
-			expected:   true,
-		},
-		{
-			name:       "Boolean AND (AND)",
-			expression: "x AND y",
-			variables:  Context{"x": true, "y": false},
-			expected:   false,
-		},
-		{
-			name:       "Boolean OR (||)",
-			expression: "x || y",
-			variables:  Context{"x": false, "y": true},
-			expected:   true,
-		},
-		{
-			name:       "Boolean OR (OR)",
-			expression: "x OR y",
-			variables:  Context{"x": false, "y": false},
-			expected:   false,
-		},
-		{
-			name:       "Boolean NOT (!)",
-			expression: "!x",
-			variables:  Context{"x": true},
-			expected:   false,
-		},
-		{
-			name:       "Boolean NOT (NOT)",
-			expression: "NOT x",
-			variables:  Context{"x": false},
-			expected:   true,
-		},
-		{
-			name:       "Complex Boolean Logic",
-			expression: "(x > 5 AND y < 10) OR (z == 0 AND NOT w)",
-			variables:  Context{"x": 6, "y": 8, "z": 1, "w": false},
-			expected:   true, // (true AND true) OR (false AND true) = true OR false = true
-		},
-		{
-			name:       "Short Circuit AND",
-			expression: "false AND (x / 0 > 1)", // Should not evaluate x/0
-			variables:  Context{"x": 10},
-			expected:   false,
-		},
-		{
-			name:       "Short Circuit OR",
-			expression: "true OR (x / 0 > 1)", // Should not evaluate x/0
-			variables:  Context{"x": 10},
-			expected:   true,
-		},
-
-		// ==== MATHEMATICAL FUNCTIONS ====
-		{
-			name:       "ABS Function Positive",
-			expression: "ABS(x)",
-			variables:  Context{"x": 5},
-			expected:   5.0,
-		},
-		{
-			name:       "ABS Function Negative",
-			expression: "ABS(x)",
-			variables:  Context{"x": -7},
-			expected:   7.0,
-		},
-		{
-			name:       "NEGATE Function",
-			expression: "NEGATE(x)",
-			variables:  Context{"x": 5},
-			expected:   -5.0,
-		},
-		{
-			name:       "CEIL Function",
-			expression: "CEIL(x)",
-			variables:  Context{"x": 3.2},
-			expected:   4.0,
-		},
-		{
-			name:       "FLOOR Function",
-			expression: "FLOOR(x)",
-			variables:  Context{"x": 3.8},
-			expected:   3.0,
-		},
-		{
-			name:       "THRESHOLD Function Above",
-			expression: "THRESHOLD(x, 10)",
-			variables:  Context{"x": 15},
-			expected:   true, // Changed from 1.0 to true
-		},
-		{
-			name:       "THRESHOLD Function Below",
-			expression: "THRESHOLD(x, 10)",
-			variables:  Context{"x": 5},
-			expected:   false, // Changed from 0.0 to false
-		},
-		{
-			name:       "THRESHOLD Function Equal",
-			expression: "THRESHOLD(x, 10)",
-			variables:  Context{"x": 10},
-			expected:   true, // Changed from 1.0 to true
-		},
-		{
-			name:       "Nested Mathematical Functions",
-			expression: "ABS(NEGATE(CEIL(x)))",
-			variables:  Context{"x": -3.2},
-			expected:   3.0, // ABS(NEGATE(CEIL(-3.2))) = ABS(NEGATE(-3)) = ABS(3) = 3
-		},
-
-		// ==== CONDITIONAL EXPRESSIONS ====
-		{
-			name:       "ITE True Branch",
-			expression: "ITE(x > 5, y + 1, z * 2)",
-			variables:  Context{"x": 10, "y": 3, "z": 4},
-			expected:   4.0, // x > 5 is true, so y + 1 = 3 + 1 = 4
-		},
-		{
-			name:       "ITE False Branch",
-			expression: "ITE(x > 5, y + 1, z * 2)",
-			variables:  Context{"x": 2, "y": 3, "z": 4},
-			expected:   8.0, // x > 5 is false, so z * 2 = 4 * 2 = 8
-		},
-		{
-			name:       "Nested ITE",
-			expression: "ITE(x > 0, ITE(y > 0, x + y, x - y), 0)",
-			variables:  Context{"x": 5, "y": 3},
-			expected:   8.0, // x > 0 true, y > 0 true, so x + y = 5 + 3 = 8
-		},
-		{
-			name:       "ITE with Complex Condition",
-			expression: "ITE((x > 5 AND y < 10), x * y, x + y)",
-			variables:  Context{"x": 6, "y": 8},
-			expected:   48.0, // (6>5 AND 8<10) true, so 6*8 = 48
-		},
-
-		// ==== LOGICAL FUNCTIONS ====
-		{
-			name:       "IMPLIES True -> True",
-			expression: "IMPLIES(x > 5, y < 10)",
-			variables:  Context{"x": 6, "y": 8},
-			expected:   true, // true -> true = true
-		},
-		{
-			name:       "IMPLIES True -> False",
-			expression: "IMPLIES(x > 5, y < 10)",
-			variables:  Context{"x": 6, "y": 12},
-			expected:   false, // true -> false = false
-		},
-		{
-			name:       "IMPLIES False -> True",
-			expression: "IMPLIES(x > 5, y < 10)",
-			variables:  Context{"x": 3, "y": 8},
-			expected:   true, // false -> true = true
-		},
-		{
-			name:       "IMPLIES False -> False",
-			expression: "IMPLIES(x > 5, y < 10)",
-			variables:  Context{"x": 3, "y": 12},
-			expected:   true, // false -> false = true
-		},
-		{
-			name:       "EQUIV Both True",
-			expression: "EQUIV(x > 5, y < 10)",
-			variables:  Context{"x": 6, "y": 8},
-			expected:   true, // true <-> true = true
-		},
-		{
-			name:       "EQUIV Both False",
-			expression: "EQUIV(x > 5, y < 10)",
-			variables:  Context{"x": 3, "y": 12},
-			expected:   true, // false <-> false = true
-		},
-		{
-			name:       "EQUIV Different",
-			expression: "EQUIV(x > 5, y < 10)",
-			variables:  Context{"x": 6, "y": 12},
-			expected:   false, // true <-> false = false
-		},
-		{
-			name:       "XOR Both False",
-			expression: "XOR(x > 5, y < 10)",
-			variables:  Context{"x": 3, "y": 12},
-			expected:   false, // false XOR false = false
-		},
-		{
-			name:       "XOR Both True",
-			expression: "XOR(x > 5, y < 10)",
-			variables:  Context{"x": 6, "y": 8},
-			expected:   false, // true XOR true = false
-		},
-		{
-			name:       "XOR Different",
-			expression: "XOR(x > 5, y < 10)",
-			variables:  Context{"x": 6, "y": 12},
-			expected:   true, // true XOR false = true
-		},
-
-		// ==== INFIX LOGICAL OPERATORS ====
-		{
-			name:       "Infix Implies True -> True",
-			expression: "true -> true",
-			variables:  Context{},
-			expected:   true,
-		},
-		{
-			name:       "Infix Implies True -> False",
-			expression: "true -> false",
-			variables:  Context{},
-			expected:   false,
-		},
-		{
-			name:       "Infix Implies False -> True",
-			expression: "false -> true",
-			variables:  Context{},
-			expected:   true,
-		},
-		{
-			name:       "Infix Implies False -> False",
-			expression: "false -> false",
-			variables:  Context{},
-			expected:   true,
-		},
-		{
-			name:       "Infix Equiv Both True",
-			expression: "true <-> true",
-			variables:  Context{},
-			expected:   true,
-		},
-		{
-			name:       "Infix Equiv Both False",
-			expression: "false <-> false",
-			variables:  Context{},
-			expected:   true,
-		},
-		{
-			name:       "Infix Equiv Different",
-			expression: "true <-> false",
-			variables:  Context{},
-			expected:   false,
-		},
-		{
-			name:       "Complex Infix Logic with Parentheses",
-			expression: "(x > 5 -> y < 10) && (a <-> b)",
-			variables:  Context{"x": 6, "y": 8, "a": true, "b": true},
-			expected:   true,
-		},
-		{
-			name:       "Mixed Function and Infix Styles",
-			expression: "IMPLIES(x > 0, y > 0) && (z <-> w)",
-			variables:  Context{"x": 5, "y": 3, "z": true, "w": true},
-			expected:   true,
-		},
-		{
-			name:       "Infix Implies with Variables",
-			expression: "x > 5 -> y < 10",
-			variables:  Context{"x": 6, "y": 8},
-			expected:   true,
-		},
-		{
-			name:       "Infix Equiv with Variables",
-			expression: "x > 5 <-> y < 10",
-			variables:  Context{"x": 6, "y": 8},
-			expected:   true,
-		},
-		{
-			name:       "Precedence Implies with AND",
-			expression: "x > 0 && y > 0 -> z > 0",
-			variables:  Context{"x": 1, "y": 1, "z": 1},
-			expected:   true,
-		},
-		{
-			name:       "Precedence Equiv with OR",
-			expression: "x > 0 || y > 0 <-> z > 0",
-			variables:  Context{"x": 1, "y": 0, "z": 1},
-			expected:   true,
-		},
-		{
-			name:       "Parenthesized Chaining Implies",
-			expression: "(x -> y) -> z",
-			variables:  Context{"x": false, "y": true, "z": true},
-			expected:   true,
-		},
-		{
-			name:       "Parenthesized Chaining Equiv",
-			expression: "(x <-> y) <-> z",
-			variables:  Context{"x": true, "y": true, "z": true},
-			expected:   true,
-		},
-
-		// ==== LITERALS ====
-		{
-			name:       "Integer Literal",
-			expression: "42",
-			variables:  Context{},
-			expected:   42.0,
-		},
-		{
-			name:       "Float Literal",
-			expression: "3.14159",
-			variables:  Context{},
-			expected:   3.14159,
-		},
-		{
-			name:       "Boolean True Literal",
-			expression: "true",
-			variables:  Context{},
-			expected:   true,
-		},
-		{
-			name:       "Boolean False Literal",
-			expression: "false",
-			variables:  Context{},
-			expected:   false,
-		},
-		{
-			name:       "Boolean TRUE Literal",
-			expression: "TRUE",
-			variables:  Context{},
-			expected:   true,
-		},
-		{
-			name:       "Boolean FALSE Literal",
-			expression: "FALSE",
-			variables:  Context{},
-			expected:   false,
-		},
-
-		// ==== PRECEDENCE TESTS ====
-		{
-			name:       "Arithmetic Precedence",
-			expression: "2 + 3 * 4",
-			variables:  Context{},
-			expected:   14.0, // 2 + (3*4) = 2 + 12 = 14
-		},
-		{
-			name:       "Comparison vs Arithmetic",
-			expression: "2 + 3 > 4",
-			variables:  Context{},
-			expected:   true, // (2+3) > 4 = 5 > 4 = true
-		},
-		{
-			name:       "Boolean vs Comparison",
-			expression: "2 > 1 AND 3 < 4",
-			variables:  Context{},
-			expected:   true, // (2>1) AND (3<4) = true AND true = true
-		},
-		{
-			name:       "Complex Precedence",
-			expression: "2 + 3 * 4 > 10 AND 5 < 6 OR false",
-			variables:  Context{},
-			expected:   true, // ((2+(3*4)) > 10) AND (5<6) OR false = (14>10) AND true OR false = true AND true OR false = true
-		},
-
-		// ==== ERROR CASES ====
-		{
-			name:        "Division by Zero",
-			expression:  "x / 0",
-			variables:   Context{"x": 10},
-			shouldError: true,
-			errorType:   "runtime",
-		},
-		{
-			name:        "Modulo by Zero",
-			expression:  "x % 0",
-			variables:   Context{"x": 10},
-			shouldError: true,
-			errorType:   "runtime",
-		},
-		{
-			name:        "Type Mismatch in Arithmetic",
-			expression:  "x + y",
-			variables:   Context{"x": 10, "y": true},
-			shouldError: true,
-			errorType:   "type",
-		},
-		{
-			name:        "Type Mismatch in Comparison",
-			expression:  "x > y",
-			variables:   Context{"x": 10, "y": "hello"},
-			shouldError: true,
-			errorType:   "type",
-		},
-		{
-			name:        "Boolean Ordering Comparison Invalid",
-			expression:  "x > y",
-			variables:   Context{"x": true, "y": false},
-			shouldError: true,
-			errorType:   "type",
-		},
-		{
-			name:        "Mixed Type Equality Invalid",
-			expression:  "x == y",
-			variables:   Context{"x": 10, "y": true},
-			shouldError: true,
-			errorType:   "type",
-		},
-		{
-			name:        "Type Mismatch in Boolean Logic",
-			expression:  "x AND y",
-			variables:   Context{"x": 10, "y": true},
-			shouldError: true,
-			errorType:   "type",
-		},
-		{
-			name:        "Undefined Variable",
-			expression:  "x + undefined_var",
-			variables:   Context{"x": 10},
-			shouldError: true,
-			errorType:   "variable",
-		},
-		{
-			name:        "Invalid Function Call",
-			expression:  "ABS(x, y)", // ABS takes one argument
-			variables:   Context{"x": 10, "y": 5},
-			shouldError: true,
-			errorType:   "syntax",
-		},
-
-		// ==== EDGE CASES ====
-		{
-			name:       "Very Large Numbers",
-			expression: "x + y",
-			variables:  Context{"x": 1e10, "y": 2e10},
-			expected:   3e10,
-		},
-		{
-			name:       "Very Small Numbers",
-			expression: "x + y",
-			variables:  Context{"x": 1e-10, "y": 2e-10},
-			expected:   3e-10,
-		},
-		{
-			name:       "Zero Values",
-			expression: "x * y + z",
-			variables:  Context{"x": 0, "y": 100, "z": 5},
-			expected:   5.0,
-		},
-		{
-			name:       "Negative Zero",
-			expression: "x + y",
-			variables:  Context{"x": 0.0, "y": -0.0},
-			expected:   0.0,
-		},
+// languageTest is one parse-and-evaluate case
+type languageTest struct {
+	name        string
+	expression  string
+	variables   Context
+	expected    interface{}
+	shouldError bool
+	errorType   string
+}
 
-		// ==== COMPLEX REAL-WORLD EXPRESSIONS ====
-		{
-			name:       "Temperature Conversion Formula",
-			expression: "ITE(unit == 1, (temp * 9 / 5) + 32, temp)", // C to F conversion
-			variables:  Context{"unit": 1, "temp": 25},
-			expected:   77.0, // (25*9/5) + 32 = 45 + 32 = 77
-		},
-		{
-			name:       "Financial Calculation",
-			expression: "ITE(balance > 1000, balance * 0.02, ITE(balance > 100, balance * 0.01, 0))",
-			variables:  Context{"balance": 1500},
-			expected:   30.0, // 1500 * 0.02 = 30
-		},
-		{
-			name:       "Complex Decision Logic",
-			expression: "((age >= 18 AND age <= 65) AND (income > 30000 OR hasGuarantor)) AND NOT hasBadCredit",
-			variables:  Context{"age": 25, "income": 35000, "hasGuarantor": false, "hasBadCredit": false},
-			expected:   true,
-		},
-		{
-			name:       "Scientific Formula",
-			expression: "ABS(CEIL(velocity * time + 0.5 * acceleration * time * time))",
-			variables:  Context{"velocity": 10, "time": 3, "acceleration": 9.8},
-			expected:   75.0, // ABS(CEIL(10*3 + 0.5*9.8*3*3)) = ABS(CEIL(30 + 44.1)) = ABS(CEIL(74.1)) = ABS(75) = 75
-		},
+// languageFeatureTests covers every language construct
+var languageFeatureTests = []languageTest{
+	// ==== ARITHMETIC OPERATIONS ====
+	{
+		name:       "Basic Addition",
+		expression: "x + y",
+		variables:  Context{"x": 5, "y": 3},
+		expected:   8.0,
+	},
+	{
+		name:       "Basic Subtraction",
+		expression: "x - y",
+		variables:  Context{"x": 10, "y": 4},
+		expected:   6.0,
+	},
+	{
+		name:       "Basic Multiplication",
+		expression: "x * y",
+		variables:  Context{"x": 6, "y": 7},
+		expected:   42.0,
+	},
+	{
+		name:       "Basic Division",
+		expression: "x / y",
+		variables:  Context{"x": 15, "y": 3},
+		expected:   5.0,
+	},
+	{
+		name:       "Modulo Operation",
+		expression: "x % y",
+		variables:  Context{"x": 17, "y": 5},
+		expected:   2.0,
+	},
+	{
+		name:       "Complex Arithmetic with Precedence",
+		expression: "x + y * z - w / v",
+		variables:  Context{"x": 10, "y": 2, "z": 5, "w": 8, "v": 4},
+		expected:   18.0, // 10 + (2*5) - (8/4) = 10 + 10 - 2 = 18
+	},
+	{
+		name:       "Parentheses Override Precedence",
+		expression: "(x + y) * (z - w)",
+		variables:  Context{"x": 3, "y": 2, "z": 8, "w": 3},
+		expected:   25.0, // (3+2) * (8-3) = 5 * 5 = 25
+	},
+	{
+		name:       "Unary Plus",
+		expression: "+x + +y",
+		variables:  Context{"x": 5, "y": -3},
+		expected:   2.0,
+	},
+	{
+		name:       "Unary Minus",
+		expression: "-x + -y",
+		variables:  Context{"x": 5, "y": -3},
+		expected:   -2.0, // -5 + -(-3) = -5 + 3 = -2
+	},
+
+	// ==== NUMERIC TYPE COMPATIBILITY ====
+	{
+		name:       "Mixed Numeric Types int + float64",
+		expression: "x + y",
+		variables:  Context{"x": int(5), "y": 3.5},
+		expected:   8.5,
+	},
+	{
+		name:       "Mixed Numeric Types int32 * float32",
+		expression: "x * y",
+		variables:  Context{"x": int32(4), "y": float32(2.5)},
+		expected:   10.0,
+	},
+	{
+		name:       "Mixed Numeric Types uint + int",
+		expression: "x + y",
+		variables:  Context{"x": uint(10), "y": int(-3)},
+		expected:   7.0,
+	},
+
+	// ==== MIN/MAX OPERATIONS ====
+	{
+		name:       "MIN Function",
+		expression: "MIN(x, y)",
+		variables:  Context{"x": 10, "y": 5},
+		expected:   5.0,
+	},
+	{
+		name:       "MAX Function",
+		expression: "MAX(x, y)",
+		variables:  Context{"x": 10, "y": 15},
+		expected:   15.0,
+	},
+	{
+		name:       "Nested MIN/MAX",
+		expression: "MIN(MAX(x, y), z)",
+		variables:  Context{"x": 5, "y": 10, "z": 7},
+		expected:   7.0, // MIN(MAX(5,10), 7) = MIN(10, 7) = 7
+	},
+	{
+		name:       "MIN with Infix Syntax",
+		expression: "x MIN y",
+		variables:  Context{"x": 10, "y": 5},
+		expected:   5.0,
+	},
+	{
+		name:       "MAX with Infix Syntax",
+		expression: "x MAX y",
+		variables:  Context{"x": 10, "y": 15},
+		expected:   15.0,
+	},
+
+	// ==== BOOLEAN EQUALITY COMPARISONS ====
+	{
+		name:       "Boolean Equality True == True",
+		expression: "x == y",
+		variables:  Context{"x": true, "y": true},
+		expected:   true,
+	},
+	{
+		name:       "Boolean Equality True == False",
+		expression: "x == y",
+		variables:  Context{"x": true, "y": false},
+		expected:   false,
+	},
+	{
+		name:       "Boolean Inequality True != False",
+		expression: "x != y",
+		variables:  Context{"x": true, "y": false},
+		expected:   true,
+	},
+	{
+		name:       "Boolean Inequality False != False",
+		expression: "x != y",
+		variables:  Context{"x": false, "y": false},
+		expected:   false,
+	},
+	{
+		name:       "Boolean Literals Equality",
+		expression: "true == false",
+		variables:  Context{},
+		expected:   false,
+	},
+	{
+		name:       "Boolean Literals Inequality",
+		expression: "true != false",
+		variables:  Context{},
+		expected:   true,
+	},
+	{
+		name:       "Complex Boolean Comparison",
+		expression: "(x > 5) == (y < 10)",
+		variables:  Context{"x": 6, "y": 8},
+		expected:   true, // (true) == (true) = true
+	},
+	{
+		name:       "Complex Boolean Inequality",
+		expression: "(x > 5) != (y > 10)",
+		variables:  Context{"x": 6, "y": 8},
+		expected:   true, // (true) != (false) = true
+	},
+
+	// ==== COMPARISON OPERATIONS ====
+	{
+		name:       "Greater Than",
+		expression: "x > y",
+		variables:  Context{"x": 10, "y": 5},
+		expected:   true,
+	},
+	{
+		name:       "Greater Than or Equal",
+		expression: "x >= y",
+		variables:  Context{"x": 5, "y": 5},
+		expected:   true,
+	},
+	{
+		name:       "Less Than",
+		expression: "x < y",
+		variables:  Context{"x": 3, "y": 8},
+		expected:   true,
+	},
+	{
+		name:       "Less Than or Equal",
+		expression: "x <= y",
+		variables:  Context{"x": 5, "y": 5},
+		expected:   true,
+	},
+	{
+		name:       "Equality",
+		expression: "x == y",
+		variables:  Context{"x": 7, "y": 7},
+		expected:   true,
+	},
+	{
+		name:       "Inequality",
+		expression: "x != y",
+		variables:  Context{"x": 7, "y": 8},
+		expected:   true,
+	},
+	{
+		name:       "Chained Comparisons (Left Associative)",
+		expression: "(x > y) == (z < w)",
+		variables:  Context{"x": 10, "y": 5, "z": 3, "w": 8},
+		expected:   true, // (10>5) == (3<8) = true == true = true
+	},
+
+	// ==== BOOLEAN OPERATIONS ====
+	{
+		name:       "Boolean AND (&&)",
+		expression: "x && y",
+		variables:  Context{"x": true, "y": true},
+		expected:   true,
+	},
+	{
+		name:       "Boolean AND (AND)",
+		expression: "x AND y",
+		variables:  Context{"x": true, "y": false},
+		expected:   false,
+	},
+	{
+		name:       "Boolean OR (||)",
+		expression: "x || y",
+		variables:  Context{"x": false, "y": true},
+		expected:   true,
+	},
+	{
+		name:       "Boolean OR (OR)",
+		expression: "x OR y",
+		variables:  Context{"x": false, "y": false},
+		expected:   false,
+	},
+	{
+		name:       "Boolean NOT (!)",
+		expression: "!x",
+		variables:  Context{"x": true},
+		expected:   false,
+	},
+	{
+		name:       "Boolean NOT (NOT)",
+		expression: "NOT x",
+		variables:  Context{"x": false},
+		expected:   true,
+	},
+	{
+		name:       "Complex Boolean Logic",
+		expression: "(x > 5 AND y < 10) OR (z == 0 AND NOT w)",
+		variables:  Context{"x": 6, "y": 8, "z": 1, "w": false},
+		expected:   true, // (true AND true) OR (false AND true) = true OR false = true
+	},
+	{
+		name:       "Short Circuit AND",
+		expression: "false AND (x / 0 > 1)", // Should not evaluate x/0
+		variables:  Context{"x": 10},
+		expected:   false,
+	},
+	{
+		name:       "Short Circuit OR",
+		expression: "true OR (x / 0 > 1)", // Should not evaluate x/0
+		variables:  Context{"x": 10},
+		expected:   true,
+	},
+
+	// ==== MATHEMATICAL FUNCTIONS ====
+	{
+		name:       "ABS Function Positive",
+		expression: "ABS(x)",
+		variables:  Context{"x": 5},
+		expected:   5.0,
+	},
+	{
+		name:       "ABS Function Negative",
+		expression: "ABS(x)",
+		variables:  Context{"x": -7},
+		expected:   7.0,
+	},
+	{
+		name:       "NEGATE Function",
+		expression: "NEGATE(x)",
+		variables:  Context{"x": 5},
+		expected:   -5.0,
+	},
+	{
+		name:       "CEIL Function",
+		expression: "CEIL(x)",
+		variables:  Context{"x": 3.2},
+		expected:   4.0,
+	},
+	{
+		name:       "FLOOR Function",
+		expression: "FLOOR(x)",
+		variables:  Context{"x": 3.8},
+		expected:   3.0,
+	},
+	{
+		name:       "THRESHOLD Function Above",
+		expression: "THRESHOLD(x, 10)",
+		variables:  Context{"x": 15},
+		expected:   true, // Changed from 1.0 to true
+	},
+	{
+		name:       "THRESHOLD Function Below",
+		expression: "THRESHOLD(x, 10)",
+		variables:  Context{"x": 5},
+		expected:   false, // Changed from 0.0 to false
+	},
+	{
+		name:       "THRESHOLD Function Equal",
+		expression: "THRESHOLD(x, 10)",
+		variables:  Context{"x": 10},
+		expected:   true, // Changed from 1.0 to true
+	},
+	{
+		name:       "Nested Mathematical Functions",
+		expression: "ABS(NEGATE(CEIL(x)))",
+		variables:  Context{"x": -3.2},
+		expected:   3.0, // ABS(NEGATE(CEIL(-3.2))) = ABS(NEGATE(-3)) = ABS(3) = 3
+	},
+
+	// ==== CONDITIONAL EXPRESSIONS ====
+	{
+		name:       "ITE True Branch",
+		expression: "ITE(x > 5, y + 1, z * 2)",
+		variables:  Context{"x": 10, "y": 3, "z": 4},
+		expected:   4.0, // x > 5 is true, so y + 1 = 3 + 1 = 4
+	},
+	{
+		name:       "ITE False Branch",
+		expression: "ITE(x > 5, y + 1, z * 2)",
+		variables:  Context{"x": 2, "y": 3, "z": 4},
+		expected:   8.0, // x > 5 is false, so z * 2 = 4 * 2 = 8
+	},
+	{
+		name:       "Nested ITE",
+		expression: "ITE(x > 0, ITE(y > 0, x + y, x - y), 0)",
+		variables:  Context{"x": 5, "y": 3},
+		expected:   8.0, // x > 0 true, y > 0 true, so x + y = 5 + 3 = 8
+	},
+	{
+		name:       "ITE with Complex Condition",
+		expression: "ITE((x > 5 AND y < 10), x * y, x + y)",
+		variables:  Context{"x": 6, "y": 8},
+		expected:   48.0, // (6>5 AND 8<10) true, so 6*8 = 48
+	},
+
+	// ==== LOGICAL FUNCTIONS ====
+	{
+		name:       "IMPLIES True -> True",
+		expression: "IMPLIES(x > 5, y < 10)",
+		variables:  Context{"x": 6, "y": 8},
+		expected:   true, // true -> true = true
+	},
+	{
+		name:       "IMPLIES True -> False",
+		expression: "IMPLIES(x > 5, y < 10)",
+		variables:  Context{"x": 6, "y": 12},
+		expected:   false, // true -> false = false
+	},
+	{
+		name:       "IMPLIES False -> True",
+		expression: "IMPLIES(x > 5, y < 10)",
+		variables:  Context{"x": 3, "y": 8},
+		expected:   true, // false -> true = true
+	},
+	{
+		name:       "IMPLIES False -> False",
+		expression: "IMPLIES(x > 5, y < 10)",
+		variables:  Context{"x": 3, "y": 12},
+		expected:   true, // false -> false = true
+	},
+	{
+		name:       "EQUIV Both True",
+		expression: "EQUIV(x > 5, y < 10)",
+		variables:  Context{"x": 6, "y": 8},
+		expected:   true, // true <-> true = true
+	},
+	{
+		name:       "EQUIV Both False",
+		expression: "EQUIV(x > 5, y < 10)",
+		variables:  Context{"x": 3, "y": 12},
+		expected:   true, // false <-> false = true
+	},
+	{
+		name:       "EQUIV Different",
+		expression: "EQUIV(x > 5, y < 10)",
+		variables:  Context{"x": 6, "y": 12},
+		expected:   false, // true <-> false = false
+	},
+	{
+		name:       "XOR Both False",
+		expression: "XOR(x > 5, y < 10)",
+		variables:  Context{"x": 3, "y": 12},
+		expected:   false, // false XOR false = false
+	},
+	{
+		name:       "XOR Both True",
+		expression: "XOR(x > 5, y < 10)",
+		variables:  Context{"x": 6, "y": 8},
+		expected:   false, // true XOR true = false
+	},
+	{
+		name:       "XOR Different",
+		expression: "XOR(x > 5, y < 10)",
+		variables:  Context{"x": 6, "y": 12},
+		expected:   true, // true XOR false = true
+	},
+
+	// ==== INFIX LOGICAL OPERATORS ====
+	{
+		name:       "Infix Implies True -> True",
+		expression: "true -> true",
+		variables:  Context{},
+		expected:   true,
+	},
+	{
+		name:       "Infix Implies True -> False",
+		expression: "true -> false",
+		variables:  Context{},
+		expected:   false,
+	},
+	{
+		name:       "Infix Implies False -> True",
+		expression: "false -> true",
+		variables:  Context{},
+		expected:   true,
+	},
+	{
+		name:       "Infix Implies False -> False",
+		expression: "false -> false",
+		variables:  Context{},
+		expected:   true,
+	},
+	{
+		name:       "Infix Equiv Both True",
+		expression: "true <-> true",
+		variables:  Context{},
+		expected:   true,
+	},
+	{
+		name:       "Infix Equiv Both False",
+		expression: "false <-> false",
+		variables:  Context{},
+		expected:   true,
+	},
+	{
+		name:       "Infix Equiv Different",
+		expression: "true <-> false",
+		variables:  Context{},
+		expected:   false,
+	},
+	{
+		name:       "Complex Infix Logic with Parentheses",
+		expression: "(x > 5 -> y < 10) && (a <-> b)",
+		variables:  Context{"x": 6, "y": 8, "a": true, "b": true},
+		expected:   true,
+	},
+	{
+		name:       "Mixed Function and Infix Styles",
+		expression: "IMPLIES(x > 0, y > 0) && (z <-> w)",
+		variables:  Context{"x": 5, "y": 3, "z": true, "w": true},
+		expected:   true,
+	},
+	{
+		name:       "Infix Implies with Variables",
+		expression: "x > 5 -> y < 10",
+		variables:  Context{"x": 6, "y": 8},
+		expected:   true,
+	},
+	{
+		name:       "Infix Equiv with Variables",
+		expression: "x > 5 <-> y < 10",
+		variables:  Context{"x": 6, "y": 8},
+		expected:   true,
+	},
+	{
+		name:       "Precedence Implies with AND",
+		expression: "x > 0 && y > 0 -> z > 0",
+		variables:  Context{"x": 1, "y": 1, "z": 1},
+		expected:   true,
+	},
+	{
+		name:       "Precedence Equiv with OR",
+		expression: "x > 0 || y > 0 <-> z > 0",
+		variables:  Context{"x": 1, "y": 0, "z": 1},
+		expected:   true,
+	},
+	{
+		name:       "Parenthesized Chaining Implies",
+		expression: "(x -> y) -> z",
+		variables:  Context{"x": false, "y": true, "z": true},
+		expected:   true,
+	},
+	{
+		name:       "Parenthesized Chaining Equiv",
+		expression: "(x <-> y) <-> z",
+		variables:  Context{"x": true, "y": true, "z": true},
+		expected:   true,
+	},
+
+	// ==== LITERALS ====
+	{
+		name:       "Integer Literal",
+		expression: "42",
+		variables:  Context{},
+		expected:   42.0,
+	},
+	{
+		name:       "Float Literal",
+		expression: "3.14159",
+		variables:  Context{},
+		expected:   3.14159,
+	},
+	{
+		name:       "Boolean True Literal",
+		expression: "true",
+		variables:  Context{},
+		expected:   true,
+	},
+	{
+		name:       "Boolean False Literal",
+		expression: "false",
+		variables:  Context{},
+		expected:   false,
+	},
+	{
+		name:       "Boolean TRUE Literal",
+		expression: "TRUE",
+		variables:  Context{},
+		expected:   true,
+	},
+	{
+		name:       "Boolean FALSE Literal",
+		expression: "FALSE",
+		variables:  Context{},
+		expected:   false,
+	},
+
+	// ==== PRECEDENCE TESTS ====
+	{
+		name:       "Arithmetic Precedence",
+		expression: "2 + 3 * 4",
+		variables:  Context{},
+		expected:   14.0, // 2 + (3*4) = 2 + 12 = 14
+	},
+	{
+		name:       "Comparison vs Arithmetic",
+		expression: "2 + 3 > 4",
+		variables:  Context{},
+		expected:   true, // (2+3) > 4 = 5 > 4 = true
+	},
+	{
+		name:       "Boolean vs Comparison",
+		expression: "2 > 1 AND 3 < 4",
+		variables:  Context{},
+		expected:   true, // (2>1) AND (3<4) = true AND true = true
+	},
+	{
+		name:       "Complex Precedence",
+		expression: "2 + 3 * 4 > 10 AND 5 < 6 OR false",
+		variables:  Context{},
+		expected:   true, // ((2+(3*4)) > 10) AND (5<6) OR false = (14>10) AND true OR false = true AND true OR false = true
+	},
+
+	// ==== ERROR CASES ====
+	{
+		name:        "Division by Zero",
+		expression:  "x / 0",
+		variables:   Context{"x": 10},
+		shouldError: true,
+		errorType:   "runtime",
+	},
+	{
+		name:        "Modulo by Zero",
+		expression:  "x % 0",
+		variables:   Context{"x": 10},
+		shouldError: true,
+		errorType:   "runtime",
+	},
+	{
+		name:        "Type Mismatch in Arithmetic",
+		expression:  "x + y",
+		variables:   Context{"x": 10, "y": true},
+		shouldError: true,
+		errorType:   "type",
+	},
+	{
+		name:        "Type Mismatch in Comparison",
+		expression:  "x > y",
+		variables:   Context{"x": 10, "y": "hello"},
+		shouldError: true,
+		errorType:   "type",
+	},
+	{
+		name:        "Boolean Ordering Comparison Invalid",
+		expression:  "x > y",
+		variables:   Context{"x": true, "y": false},
+		shouldError: true,
+		errorType:   "type",
+	},
+	{
+		name:        "Mixed Type Equality Invalid",
+		expression:  "x == y",
+		variables:   Context{"x": 10, "y": true},
+		shouldError: true,
+		errorType:   "type",
+	},
+	{
+		name:        "Type Mismatch in Boolean Logic",
+		expression:  "x AND y",
+		variables:   Context{"x": 10, "y": true},
+		shouldError: true,
+		errorType:   "type",
+	},
+	{
+		name:        "Undefined Variable",
+		expression:  "x + undefined_var",
+		variables:   Context{"x": 10},
+		shouldError: true,
+		errorType:   "variable",
+	},
+	{
+		name:        "Invalid Function Call",
+		expression:  "ABS(x, y)", // ABS takes one argument
+		variables:   Context{"x": 10, "y": 5},
+		shouldError: true,
+		errorType:   "syntax",
+	},
+
+	// ==== EDGE CASES ====
+	{
+		name:       "Very Large Numbers",
+		expression: "x + y",
+		variables:  Context{"x": 1e10, "y": 2e10},
+		expected:   3e10,
+	},
+	{
+		name:       "Very Small Numbers",
+		expression: "x + y",
+		variables:  Context{"x": 1e-10, "y": 2e-10},
+		expected:   3e-10,
+	},
+	{
+		name:       "Zero Values",
+		expression: "x * y + z",
+		variables:  Context{"x": 0, "y": 100, "z": 5},
+		expected:   5.0,
+	},
+	{
+		name:       "Negative Zero",
+		expression: "x + y",
+		variables:  Context{"x": 0.0, "y": -0.0},
+		expected:   0.0,
+	},
+
+	// ==== COMPLEX REAL-WORLD EXPRESSIONS ====
+	{
+		name:       "Temperature Conversion Formula",
+		expression: "ITE(unit == 1, (temp * 9 / 5) + 32, temp)", // C to F conversion
+		variables:  Context{"unit": 1, "temp": 25},
+		expected:   77.0, // (25*9/5) + 32 = 45 + 32 = 77
+	},
+	{
+		name:       "Financial Calculation",
+		expression: "ITE(balance > 1000, balance * 0.02, ITE(balance > 100, balance * 0.01, 0))",
+		variables:  Context{"balance": 1500},
+		expected:   30.0, // 1500 * 0.02 = 30
+	},
+	{
+		name:       "Complex Decision Logic",
+		expression: "((age >= 18 AND age <= 65) AND (income > 30000 OR hasGuarantor)) AND NOT hasBadCredit",
+		variables:  Context{"age": 25, "income": 35000, "hasGuarantor": false, "hasBadCredit": false},
+		expected:   true,
+	},
+	{
+		name:       "Scientific Formula",
+		expression: "ABS(CEIL(velocity * time + 0.5 * acceleration * time * time))",
+		variables:  Context{"velocity": 10, "time": 3, "acceleration": 9.8},
+		expected:   75.0, // ABS(CEIL(10*3 + 0.5*9.8*3*3)) = ABS(CEIL(30 + 44.1)) = ABS(CEIL(74.1)) = ABS(75) = 75
+	},
+
+	// ==== VARIABLE COLLECTION TESTS ====
+	{
+		name:       "Single Variable",
+		expression: "x",
+		variables:  Context{"x": 42},
+		expected:   42.0,
+	},
+	{
+		name:       "Multiple Variables",
+		expression: "x + y + z",
+		variables:  Context{"x": 1, "y": 2, "z": 3},
+		expected:   6.0,
+	},
+}
 
-		// ==== VARIABLE COLLECTION TESTS ====
-		{
-			name:       "Single Variable",
-			expression: "x",
-			variables:  Context{"x": 42},
-			expected:   42.0,
-		},
-		{
-			name:       "Multiple Variables",
-			expression: "x + y + z",
-			variables:  Context{"x": 1, "y": 2, "z": 3},
-			expected:   6.0,
-		},
-	}
+// TestComprehensiveLanguageFeatures tests every language construct
+func TestComprehensiveLanguageFeatures(t *testing.T) {
+	tests := languageFeatureTests
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -875,143 +881,139 @@ func TestParseErrors(t *testing.T) {
 	}
 }
 
+// infixLogicalTests covers the -> and <-> operators
+var infixLogicalTests = []languageTest{
+	// Basic functionality tests
+	{
+		name:       "Simple Implies",
+		expression: "x > 5 -> y < 10",
+		variables:  Context{"x": 6, "y": 8},
+		expected:   true,
+	},
+	{
+		name:       "Simple Equiv",
+		expression: "x > 5 <-> y < 10",
+		variables:  Context{"x": 6, "y": 8},
+		expected:   true,
+	},
+	{
+		name:       "Implies False Antecedent",
+		expression: "x > 10 -> y < 5",
+		variables:  Context{"x": 3, "y": 8},
+		expected:   true, // false -> anything = true
+	},
+	{
+		name:       "Equiv Different Values",
+		expression: "x > 5 <-> y > 10",
+		variables:  Context{"x": 6, "y": 8},
+		expected:   false, // true <-> false = false
+	},
+
+	// Precedence tests
+	{
+		name:       "Implies with AND",
+		expression: "x > 0 && y > 0 -> z > 0",
+		variables:  Context{"x": 1, "y": 1, "z": 1},
+		expected:   true,
+	},
+	{
+		name:       "Equiv with OR",
+		expression: "x > 0 || y > 0 <-> z > 0",
+		variables:  Context{"x": 1, "y": 0, "z": 1},
+		expected:   true,
+	},
+	{
+		name:       "Complex Precedence",
+		expression: "x > 0 && y > 0 -> z > 0 || w > 0",
+		variables:  Context{"x": 1, "y": 1, "z": 0, "w": 1},
+		expected:   true,
+	},
+
+	// Parenthesized chaining tests
+	{
+		name:       "Parenthesized Chaining Implies",
+		expression: "(x -> y) -> z",
+		variables:  Context{"x": false, "y": true, "z": true},
+		expected:   true,
+	},
+	{
+		name:       "Parenthesized Chaining Equiv",
+		expression: "(x <-> y) <-> z",
+		variables:  Context{"x": true, "y": true, "z": true},
+		expected:   true,
+	},
+	{
+		name:       "Right Associative Parentheses",
+		expression: "x -> (y -> z)",
+		variables:  Context{"x": true, "y": false, "z": true},
+		expected:   true, // true -> (false -> true) = true -> true = true
+	},
+
+	// Mixed with function style
+	{
+		name:       "Mixed Function and Infix",
+		expression: "IMPLIES(x > 0, y > 0) && (z <-> w)",
+		variables:  Context{"x": 5, "y": 3, "z": true, "w": true},
+		expected:   true,
+	},
+
+	// Error cases - chained operators
+	{
+		name:        "Chained Implies",
+		expression:  "x -> y -> z",
+		variables:   Context{"x": true, "y": true, "z": true},
+		shouldError: true,
+		errorType:   "syntax",
+	},
+	{
+		name:        "Chained Equiv",
+		expression:  "x <-> y <-> z",
+		variables:   Context{"x": true, "y": true, "z": true},
+		shouldError: true,
+		errorType:   "syntax",
+	},
+	{
+		name:        "Mixed Chained Operators",
+		expression:  "x -> y <-> z",
+		variables:   Context{"x": true, "y": true, "z": true},
+		shouldError: true,
+		errorType:   "syntax",
+	},
+
+	// Type error cases
+	{
+		name:        "Implies Type Error Left",
+		expression:  "5 -> true",
+		variables:   Context{},
+		shouldError: true,
+		errorType:   "type",
+	},
+	{
+		name:        "Implies Type Error Right",
+		expression:  "true -> 5",
+		variables:   Context{},
+		shouldError: true,
+		errorType:   "type",
+	},
+	{
+		name:        "Equiv Type Error Left",
+		expression:  "5 <-> true",
+		variables:   Context{},
+		shouldError: true,
+		errorType:   "type",
+	},
+	{
+		name:        "Equiv Type Error Right",
+		expression:  "true <-> 5",
+		variables:   Context{},
+		shouldError: true,
+		errorType:   "type",
+	},
+}
+
 // TestInfixLogicalOperators tests the new -> and <-> operators specifically
 func TestInfixLogicalOperators(t *testing.T) {
-	tests := []struct {
-		name        string
-		expression  string
-		variables   Context
-		expected    interface{}
-		shouldError bool
-		errorType   string
-	}{
-		// Basic functionality tests
-		{
-			name:       "Simple Implies",
-			expression: "x > 5 -> y < 10",
-			variables:  Context{"x": 6, "y": 8},
-			expected:   true,
-		},
-		{
-			name:       "Simple Equiv",
-			expression: "x > 5 <-> y < 10",
-			variables:  Context{"x": 6, "y": 8},
-			expected:   true,
-		},
-		{
-			name:       "Implies False Antecedent",
-			expression: "x > 10 -> y < 5",
-			variables:  Context{"x": 3, "y": 8},
-			expected:   true, // false -> anything = true
-		},
-		{
-			name:       "Equiv Different Values",
-			expression: "x > 5 <-> y > 10",
-			variables:  Context{"x": 6, "y": 8},
-			expected:   false, // true <-> false = false
-		},
-
-		// Precedence tests
-		{
-			name:       "Implies with AND",
-			expression: "x > 0 && y > 0 -> z > 0",
-			variables:  Context{"x": 1, "y": 1, "z": 1},
-			expected:   true,
-		},
-		{
-			name:       "Equiv with OR",
-			expression: "x > 0 || y > 0 <-> z > 0",
-			variables:  Context{"x": 1, "y": 0, "z": 1},
-			expected:   true,
-		},
-		{
-			name:       "Complex Precedence",
-			expression: "x > 0 && y > 0 -> z > 0 || w > 0",
-			variables:  Context{"x": 1, "y": 1, "z": 0, "w": 1},
-			expected:   true,
-		},
-
-		// Parenthesized chaining tests
-		{
-			name:       "Parenthesized Chaining Implies",
-			expression: "(x -> y) -> z",
-			variables:  Context{"x": false, "y": true, "z": true},
-			expected:   true,
-		},
-		{
-			name:       "Parenthesized Chaining Equiv",
-			expression: "(x <-> y) <-> z",
-			variables:  Context{"x": true, "y": true, "z": true},
-			expected:   true,
-		},
-		{
-			name:       "Right Associative Parentheses",
-			expression: "x -> (y -> z)",
-			variables:  Context{"x": true, "y": false, "z": true},
-			expected:   true, // true -> (false -> true) = true -> true = true
-		},
-
-		// Mixed with function style
-		{
-			name:       "Mixed Function and Infix",
-			expression: "IMPLIES(x > 0, y > 0) && (z <-> w)",
-			variables:  Context{"x": 5, "y": 3, "z": true, "w": true},
-			expected:   true,
-		},
-
-		// Error cases - chained operators
-		{
-			name:        "Chained Implies",
-			expression:  "x -> y -> z",
-			variables:   Context{"x": true, "y": true, "z": true},
-			shouldError: true,
-			errorType:   "syntax",
-		},
-		{
-			name:        "Chained Equiv",
-			expression:  "x <-> y <-> z",
-			variables:   Context{"x": true, "y": true, "z": true},
-			shouldError: true,
-			errorType:   "syntax",
-		},
-		{
-			name:        "Mixed Chained Operators",
-			expression:  "x -> y <-> z",
-			variables:   Context{"x": true, "y": true, "z": true},
-			shouldError: true,
-			errorType:   "syntax",
-		},
-
-		// Type error cases
-		{
-			name:        "Implies Type Error Left",
-			expression:  "5 -> true",
-			variables:   Context{},
-			shouldError: true,
-			errorType:   "type",
-		},
-		{
-			name:        "Implies Type Error Right",
-			expression:  "true -> 5",
-			variables:   Context{},
-			shouldError: true,
-			errorType:   "type",
-		},
-		{
-			name:        "Equiv Type Error Left",
-			expression:  "5 <-> true",
-			variables:   Context{},
-			shouldError: true,
-			errorType:   "type",
-		},
-		{
-			name:        "Equiv Type Error Right",
-			expression:  "true <-> 5",
-			variables:   Context{},
-			shouldError: true,
-			errorType:   "type",
-		},
-	}
+	tests := infixLogicalTests
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1536,6 +1538,101 @@ func TestValidateExpression(t *testing.T) {
 	}
 }
 
+// TestCommentsAndAnnotations tests comments, annotation prefixes and multi-line positions
+func TestCommentsAndAnnotations(t *testing.T) {
+	t.Run("Comments are ignored by evaluation", func(t *testing.T) {
+		input := "// needs cooling\nx > 5 /* threshold */ AND\n    y < 3 // trailing"
+		expr, err := parser.ParseExpression(input)
+		if err != nil {
+			t.Fatalf("Parse error: %v", err)
+		}
+		result, err := Evaluate(expr, Context{"x": 10, "y": 1})
+		if err != nil || result != true {
+			t.Errorf("Expected true, got %v (err %v)", result, err)
+		}
+	})
+
+	t.Run("Comments attach to the following node", func(t *testing.T) {
+		expr, err := parser.ParseExpression("/* lead */ a AND /* mid */ b // end")
+		if err != nil {
+			t.Fatalf("Parse error: %v", err)
+		}
+		root := expr.(*parser.BinaryOperation)
+		rootComments := parser.CommentsOf(root)
+		if len(rootComments) != 2 || rootComments[0].Text != " lead " || !rootComments[1].Trailing || rootComments[1].Text != " end" {
+			t.Errorf("Unexpected root comments: %+v", rootComments)
+		}
+		if right := parser.CommentsOf(root.Right); len(right) != 1 || right[0].Text != " mid " || !right[0].Block {
+			t.Errorf("Unexpected right operand comments: %+v", right)
+		}
+	})
+
+	t.Run("Annotations", func(t *testing.T) {
+		input := "@meta(severity=\"warning\", owner=\"hw \\\"core\\\"\") @deprecated\na -> b"
+		annotated, err := parser.ParseAnnotatedExpression(input)
+		if err != nil {
+			t.Fatalf("Parse error: %v", err)
+		}
+		if len(annotated.Annotations) != 2 {
+			t.Fatalf("Expected 2 annotations, got %d", len(annotated.Annotations))
+		}
+		meta := annotated.Annotations[0]
+		if owner, _ := meta.Get("owner"); meta.Name != "meta" || owner != "hw \"core\"" {
+			t.Errorf("Unexpected annotation: %+v", meta)
+		}
+		if annotated.Annotations[1].Name != "deprecated" || len(annotated.Annotations[1].Args) != 0 {
+			t.Errorf("Unexpected annotation: %+v", annotated.Annotations[1])
+		}
+		if annotated.Expression.String() != "(a -> b)" {
+			t.Errorf("Unexpected expression: %s", annotated.Expression)
+		}
+	})
+
+	errorCases := []struct {
+		name        string
+		expression  string
+		expectError string
+	}{
+		{"Unterminated Block Comment", "a AND /* open", "Unterminated block comment"},
+		{"Annotation Missing Value", "@meta(owner) a", "Expected '='"},
+		{"Annotation Unquoted Value", "@meta(owner=x) a", "Expected quoted annotation value"},
+		{"Annotation Duplicate Argument", "@meta(a=\"1\", a=\"2\") x", "Duplicate annotation argument"},
+		{"Annotation After Expression", "a @meta", "Unexpected character '@'"},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parser.ParseExpression(tc.expression)
+			if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+				t.Errorf("Expected error containing %q, got %v", tc.expectError, err)
+			}
+		})
+	}
+
+	t.Run("Multi-line positions and carets", func(t *testing.T) {
+		input := "a AND\n  (b OR\n   c"
+		_, err := parser.ParseExpression(input)
+		parseErr, ok := err.(*parser.ParseError)
+		if !ok {
+			t.Fatalf("Expected ParseError, got %v", err)
+		}
+		if parseErr.Range.Start.Line != 3 {
+			t.Errorf("Expected error on line 3, got %s", parseErr.Range)
+		}
+
+		expr, err := parser.ParseExpression("x AND\n  y_long")
+		if err != nil {
+			t.Fatalf("Parse error: %v", err)
+		}
+		spanning := &parser.ParseError{Message: "spans lines", Range: expr.GetRange(), SourceText: "x AND\n  y_long"}
+		shown := spanning.ShowError()
+		for _, want := range []string{" 1 | x AND\n   | ^^^^^\n", " 2 |   y_long\n   |   ^^^^^^\n"} {
+			if !strings.Contains(shown, want) {
+				t.Errorf("Expected ShowError to contain %q, got:\n%s", want, shown)
+			}
+		}
+	})
+}
+
 // Helper function for value comparison with floating point tolerance
 func compareParsedValues(a, b interface{}) bool {
 	if reflect.TypeOf(a) != reflect.TypeOf(b) {
@@ -0,0 +1,387 @@
+package evaluator
+
+import (
+	"DD/parser"
+	"fmt"
+	"strings"
+)
+
+// ===== PARTIAL EVALUATION =====
+
+// PartialResult is the outcome of evaluating an expression against a
+// context that may leave some variables undecided. Either Value holds the
+// definite result or Residual holds the expression that remains.
+type PartialResult struct {
+	Value    interface{}       // float64 or bool when the result is known
+	Residual parser.Expression // Simplified expression over the undecided variables
+}
+
+// Known reports whether the context decided the expression
+func (r PartialResult) Known() bool {
+	return r.Residual == nil
+}
+
+// DependsOn returns the undecided variables the result still depends on
+func (r PartialResult) DependsOn() []string {
+	if r.Residual == nil {
+		return nil
+	}
+	return parser.CollectVariables(r.Residual)
+}
+
+func (r PartialResult) String() string {
+	if r.Residual != nil {
+		return r.Residual.String()
+	}
+	return fmt.Sprintf("%v", r.Value)
+}
+
+// residual wraps an expression the partial evaluator could not reduce to a value
+type residual struct {
+	expr parser.Expression
+}
+
+// PartialEvaluator evaluates with Kleene three-valued logic: identifiers
+// missing from the context are unknown, and an unknown operand only leaves a
+// residual when the known operands do not decide the result. AND with a
+// false operand is false and OR with a true operand is true whichever side
+// is unknown.
+type PartialEvaluator struct {
+	evaluator *Evaluator
+}
+
+// NewPartialEvaluator creates a partial evaluator over the given context
+func NewPartialEvaluator(context Context) *PartialEvaluator {
+	return &PartialEvaluator{evaluator: NewEvaluator(context)}
+}
+
+// PartialEvaluate evaluates expr as far as the context allows
+func PartialEvaluate(expr parser.Expression, context Context) (PartialResult, error) {
+	result, err := expr.Accept(NewPartialEvaluator(context))
+	if err != nil {
+		return PartialResult{}, err
+	}
+	if r, ok := result.(residual); ok {
+		return PartialResult{Residual: r.expr}, nil
+	}
+	return PartialResult{Value: result}, nil
+}
+
+// PartialEvaluateExpression parses and partially evaluates an expression in one call
+func PartialEvaluateExpression(input string, context Context) (PartialResult, error) {
+	ast, err := parser.ParseExpression(input)
+	if err != nil {
+		return PartialResult{}, err
+	}
+	return PartialEvaluate(ast, context)
+}
+
+// ===== VISITOR IMPLEMENTATION =====
+
+func (p *PartialEvaluator) VisitNumberLiteral(node *parser.NumberLiteral) (interface{}, error) {
+	return node.Value, nil
+}
+
+func (p *PartialEvaluator) VisitBooleanLiteral(node *parser.BooleanLiteral) (interface{}, error) {
+	return node.Value, nil
+}
+
+func (p *PartialEvaluator) VisitIdentifier(node *parser.Identifier) (interface{}, error) {
+	if _, exists := p.evaluator.context[node.Name]; !exists {
+		return residual{node}, nil
+	}
+	return p.evaluator.VisitIdentifier(node)
+}
+
+func (p *PartialEvaluator) VisitBinaryOperation(node *parser.BinaryOperation) (interface{}, error) {
+	leftVal, err := node.Left.Accept(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit on a deciding left operand, as Evaluate does
+	if node.Operator == parser.TOKEN_AND || node.Operator == parser.TOKEN_OR {
+		if leftBool, ok := ToBool(leftVal); ok && leftBool == (node.Operator == parser.TOKEN_OR) {
+			return leftBool, nil
+		}
+	}
+
+	rightVal, err := node.Right.Accept(p)
+	if err != nil {
+		return nil, err
+	}
+
+	_, leftUnknown := leftVal.(residual)
+	_, rightUnknown := rightVal.(residual)
+	if !leftUnknown && !rightUnknown {
+		if node.Operator == parser.TOKEN_AND || node.Operator == parser.TOKEN_OR {
+			return p.evaluator.VisitBinaryOperation(&parser.BinaryOperation{
+				Left:     valueLiteral(node.Left, leftVal),
+				Operator: node.Operator,
+				Right:    valueLiteral(node.Right, rightVal),
+				Range:    node.Range,
+			})
+		}
+		return p.evaluator.applyBinaryOperation(node, leftVal, rightVal)
+	}
+
+	switch node.Operator {
+	case parser.TOKEN_AND:
+		return p.connective(node, leftVal, rightVal, false, "AND")
+	case parser.TOKEN_OR:
+		return p.connective(node, leftVal, rightVal, true, "OR")
+	case parser.TOKEN_IMPLIES, parser.TOKEN_IMPLIES_OP:
+		return p.implies(node.Left, node.Right, leftVal, rightVal, node)
+	case parser.TOKEN_EQUIV, parser.TOKEN_EQUIV_OP:
+		return p.equivalence(node.Left, node.Right, leftVal, rightVal, false, node)
+	case parser.TOKEN_XOR:
+		return p.equivalence(node.Left, node.Right, leftVal, rightVal, true, node)
+	}
+	return p.rebuildBinary(node, leftVal, rightVal), nil
+}
+
+func (p *PartialEvaluator) VisitUnaryOperation(node *parser.UnaryOperation) (interface{}, error) {
+	operandVal, err := node.Operand.Accept(p)
+	if err != nil {
+		return nil, err
+	}
+	r, unknown := operandVal.(residual)
+	if !unknown {
+		return p.evaluator.applyUnaryOperation(node, operandVal)
+	}
+
+	// NOT NOT x and - - x reduce to x
+	if inner, ok := r.expr.(*parser.UnaryOperation); ok && inner.Operator == node.Operator &&
+		(node.Operator == parser.TOKEN_NOT || node.Operator == parser.TOKEN_MINUS) {
+		return residual{inner.Operand}, nil
+	}
+	if node.Operator == parser.TOKEN_PLUS {
+		return r, nil
+	}
+	if r.expr == node.Operand {
+		return residual{node}, nil
+	}
+	return residual{&parser.UnaryOperation{Operator: node.Operator, Operand: r.expr, Range: node.Range}}, nil
+}
+
+func (p *PartialEvaluator) VisitFunctionCall(node *parser.FunctionCall) (interface{}, error) {
+	// ITE evaluates only the branch its known condition selects
+	if node.Function == parser.TOKEN_ITE && len(node.Args) == 3 {
+		condVal, err := node.Args[0].Accept(p)
+		if err != nil {
+			return nil, err
+		}
+		if _, unknown := condVal.(residual); !unknown {
+			cond, ok := ToBool(condVal)
+			if !ok {
+				return nil, p.evaluator.functionTypeError(node, 0, "boolean", condVal)
+			}
+			if cond {
+				return node.Args[1].Accept(p)
+			}
+			return node.Args[2].Accept(p)
+		}
+	}
+
+	args := make([]interface{}, len(node.Args))
+	unknown := false
+	for i, arg := range node.Args {
+		val, err := arg.Accept(p)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = val
+		if _, ok := val.(residual); ok {
+			unknown = true
+		}
+	}
+	if !unknown {
+		return p.evaluator.applyFunction(node, args)
+	}
+
+	if len(args) == 2 {
+		switch node.Function {
+		case parser.TOKEN_IMPLIES:
+			return p.implies(node.Args[0], node.Args[1], args[0], args[1], node)
+		case parser.TOKEN_EQUIV:
+			return p.equivalence(node.Args[0], node.Args[1], args[0], args[1], false, node)
+		case parser.TOKEN_XOR:
+			return p.equivalence(node.Args[0], node.Args[1], args[0], args[1], true, node)
+		}
+	}
+	if node.Function == parser.TOKEN_ITE && len(args) == 3 {
+		// An undecided condition does not matter when both branches agree
+		if _, ok := args[1].(residual); !ok && args[1] == args[2] {
+			return args[1], nil
+		}
+	}
+	return p.rebuildCall(node, args), nil
+}
+
+// ===== KLEENE CONNECTIVES =====
+
+// connective simplifies AND (dominant false) or OR (dominant true) with at
+// least one unknown operand: the dominant value decides, the other known
+// value is the identity and drops out
+func (p *PartialEvaluator) connective(node *parser.BinaryOperation, leftVal, rightVal interface{}, dominant bool, name string) (interface{}, error) {
+	for i, val := range []interface{}{leftVal, rightVal} {
+		if _, unknown := val.(residual); unknown {
+			continue
+		}
+		b, ok := ToBool(val)
+		if !ok {
+			side, operand := "Left", node.Left
+			if i == 1 {
+				side, operand = "Right", node.Right
+			}
+			return nil, &parser.ParseError{
+				Message:    fmt.Sprintf("%s operand of %s must be boolean, got %T", side, name, val),
+				Range:      operand.GetRange(),
+				ErrorType:  "semantic",
+				Suggestion: fmt.Sprintf("Ensure %s operand is a boolean expression", strings.ToLower(side)),
+			}
+		}
+		if b == dominant {
+			return b, nil
+		}
+		if i == 0 {
+			return rightVal, nil
+		}
+		return leftVal, nil
+	}
+	return p.rebuildBinary(node, leftVal, rightVal), nil
+}
+
+// implies simplifies a -> b with at least one unknown operand
+func (p *PartialEvaluator) implies(left, right parser.Expression, leftVal, rightVal interface{}, node parser.Expression) (interface{}, error) {
+	if b, known, err := p.knownBool(left, leftVal, node, 0); err != nil {
+		return nil, err
+	} else if known {
+		if !b {
+			return true, nil
+		}
+		return rightVal, nil
+	}
+	if b, known, err := p.knownBool(right, rightVal, node, 1); err != nil {
+		return nil, err
+	} else if known {
+		if b {
+			return true, nil
+		}
+		return p.negate(leftVal.(residual).expr, node.GetRange()), nil
+	}
+	return p.rebuild(node, leftVal, rightVal), nil
+}
+
+// equivalence simplifies a <-> b, or a XOR b when exclusive, with at least
+// one unknown operand. A known operand leaves the other, negated when the
+// known value makes the result its opposite.
+func (p *PartialEvaluator) equivalence(left, right parser.Expression, leftVal, rightVal interface{}, exclusive bool, node parser.Expression) (interface{}, error) {
+	operands := []parser.Expression{left, right}
+	values := []interface{}{leftVal, rightVal}
+	for i := range values {
+		b, known, err := p.knownBool(operands[i], values[i], node, i)
+		if err != nil {
+			return nil, err
+		}
+		if !known {
+			continue
+		}
+		other := values[1-i].(residual)
+		if b != exclusive {
+			return other, nil
+		}
+		return p.negate(other.expr, node.GetRange()), nil
+	}
+	return p.rebuild(node, leftVal, rightVal), nil
+}
+
+// knownBool returns the boolean an operand evaluated to, or known=false
+// when it is a residual
+func (p *PartialEvaluator) knownBool(operand parser.Expression, val interface{}, node parser.Expression, index int) (bool, bool, error) {
+	if _, unknown := val.(residual); unknown {
+		return false, false, nil
+	}
+	b, ok := ToBool(val)
+	if ok {
+		return b, true, nil
+	}
+	if call, isCall := node.(*parser.FunctionCall); isCall {
+		return false, false, p.evaluator.functionTypeError(call, index, "boolean", val)
+	}
+	side := "Left"
+	if index == 1 {
+		side = "Right"
+	}
+	return false, false, &parser.ParseError{
+		Message:    fmt.Sprintf("%s operand must be boolean for %s, got %T", side, node.(*parser.BinaryOperation).Operator, val),
+		Range:      operand.GetRange(),
+		ErrorType:  "semantic",
+		Suggestion: "Use a boolean expression",
+	}
+}
+
+// ===== RESIDUAL CONSTRUCTION =====
+
+// negate builds NOT expr, removing a double negation
+func (p *PartialEvaluator) negate(expr parser.Expression, rng parser.SourceRange) residual {
+	if inner, ok := expr.(*parser.UnaryOperation); ok && inner.Operator == parser.TOKEN_NOT {
+		return residual{inner.Operand}
+	}
+	return residual{&parser.UnaryOperation{Operator: parser.TOKEN_NOT, Operand: expr, Range: rng}}
+}
+
+// valueLiteral turns a value back into an expression, keeping the range of the
+// expression it came from
+func valueLiteral(original parser.Expression, val interface{}) parser.Expression {
+	switch v := val.(type) {
+	case residual:
+		return v.expr
+	case bool:
+		if lit, ok := original.(*parser.BooleanLiteral); ok && lit.Value == v {
+			return original
+		}
+		return &parser.BooleanLiteral{Value: v, Range: original.GetRange()}
+	}
+	if num, ok := ToFloat64(val); ok {
+		if lit, isLit := original.(*parser.NumberLiteral); isLit && lit.Value == num {
+			return original
+		}
+		return &parser.NumberLiteral{Value: num, Range: original.GetRange()}
+	}
+	if b, ok := ToBool(val); ok {
+		return &parser.BooleanLiteral{Value: b, Range: original.GetRange()}
+	}
+	return original
+}
+
+// rebuild builds a residual binary operation or two-argument call
+func (p *PartialEvaluator) rebuild(node parser.Expression, leftVal, rightVal interface{}) residual {
+	if call, ok := node.(*parser.FunctionCall); ok {
+		return p.rebuildCall(call, []interface{}{leftVal, rightVal})
+	}
+	return p.rebuildBinary(node.(*parser.BinaryOperation), leftVal, rightVal)
+}
+
+// rebuildBinary reuses node when neither operand was simplified
+func (p *PartialEvaluator) rebuildBinary(node *parser.BinaryOperation, leftVal, rightVal interface{}) residual {
+	left, right := valueLiteral(node.Left, leftVal), valueLiteral(node.Right, rightVal)
+	if left == node.Left && right == node.Right {
+		return residual{node}
+	}
+	return residual{&parser.BinaryOperation{Left: left, Operator: node.Operator, Right: right, Range: node.Range}}
+}
+
+// rebuildCall reuses node when no argument was simplified
+func (p *PartialEvaluator) rebuildCall(node *parser.FunctionCall, args []interface{}) residual {
+	changed := false
+	exprs := make([]parser.Expression, len(args))
+	for i, arg := range args {
+		exprs[i] = valueLiteral(node.Args[i], arg)
+		changed = changed || exprs[i] != node.Args[i]
+	}
+	if !changed {
+		return residual{node}
+	}
+	return residual{&parser.FunctionCall{Function: node.Function, Args: exprs, Range: node.Range}}
+}
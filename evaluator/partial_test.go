@@ -0,0 +1,139 @@
+package evaluator
+
+import (
+	"DD/parser"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestPartialEvaluation tests Kleene three-valued evaluation with residuals
+func TestPartialEvaluation(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		variables  Context
+		expected   interface{} // Value when known
+		residual   string      // Residual when unknown
+		dependsOn  []string
+	}{
+		// ==== DEFINITE RESULTS ====
+		{name: "All variables known", expression: "a AND b", variables: Context{"a": true, "b": false}, expected: false},
+		{name: "False dominates AND", expression: "a AND b", variables: Context{"b": false}, expected: false},
+		{name: "False dominates AND on the left", expression: "a AND b", variables: Context{"a": false}, expected: false},
+		{name: "True dominates OR", expression: "a OR b", variables: Context{"b": true}, expected: true},
+		{name: "False antecedent", expression: "a -> b", variables: Context{"a": false}, expected: true},
+		{name: "True consequent", expression: "IMPLIES(a, b)", variables: Context{"b": true}, expected: true},
+		{name: "Constant folding", expression: "x * 2 + 1", variables: Context{"x": 4}, expected: 9.0},
+		{name: "Known ITE condition skips unknown branch", expression: "ITE(c, x, 5)", variables: Context{"c": false}, expected: 5.0},
+		{name: "Agreeing ITE branches", expression: "ITE(c, 1 + 1, 2)", variables: Context{}, expected: 2.0},
+		{name: "Deciding disjunct deep in a rule", expression: "(a AND b) OR (c AND NOT d)", variables: Context{"c": true, "d": false}, expected: true},
+
+		// ==== RESIDUALS ====
+		{name: "True is the AND identity", expression: "a AND b", variables: Context{"a": true}, residual: "b", dependsOn: []string{"b"}},
+		{name: "False is the OR identity", expression: "a OR b", variables: Context{"b": false}, residual: "a", dependsOn: []string{"a"}},
+		{name: "Nothing known", expression: "a AND b", variables: Context{}, residual: "(a AND b)", dependsOn: []string{"a", "b"}},
+		{name: "True antecedent", expression: "a -> b", variables: Context{"a": true}, residual: "b"},
+		{name: "False consequent", expression: "a -> b", variables: Context{"b": false}, residual: "(NOTa)"},
+		{name: "Equivalence with true", expression: "a <-> b", variables: Context{"a": true}, residual: "b"},
+		{name: "Equivalence with false", expression: "EQUIV(a, b)", variables: Context{"b": false}, residual: "(NOTa)"},
+		{name: "XOR with true", expression: "XOR(a, b)", variables: Context{"a": true}, residual: "(NOTb)"},
+		{name: "Double negation", expression: "NOT a -> b", variables: Context{"b": false}, residual: "a"},
+		{name: "Known operands folded into comparison", expression: "x * 2 + y > 10", variables: Context{"x": 3}, residual: "((6 + y) > 10)", dependsOn: []string{"y"}},
+		{name: "Rule depends only on one option", expression: "(a AND b) OR (c AND NOT d)", variables: Context{"a": true, "b": false, "d": false}, residual: "c", dependsOn: []string{"c"}},
+		{name: "Nested simplification", expression: "a AND (b OR c) AND d", variables: Context{"b": false, "d": true}, residual: "(a AND c)"},
+		{name: "Unknown ITE condition", expression: "ITE(c, x + 1, 2 * 3)", variables: Context{"x": 1}, residual: "ITE(c, 2, 6)"},
+		{name: "Unknown function argument", expression: "MAX(x, 2 + 3)", variables: Context{}, residual: "MAX(x, 5)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := PartialEvaluateExpression(tt.expression, tt.variables)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tt.residual == "" {
+				if !result.Known() || !reflect.DeepEqual(result.Value, tt.expected) {
+					t.Errorf("Expected value %v, got %s", tt.expected, result)
+				}
+				return
+			}
+			if result.Known() {
+				t.Fatalf("Expected residual %s, got value %v", tt.residual, result.Value)
+			}
+			if got := result.Residual.String(); got != tt.residual {
+				t.Errorf("Expected residual %s, got %s", tt.residual, got)
+			}
+			if tt.dependsOn != nil && !reflect.DeepEqual(result.DependsOn(), tt.dependsOn) {
+				t.Errorf("Expected dependencies %v, got %v", tt.dependsOn, result.DependsOn())
+			}
+		})
+	}
+}
+
+// TestPartialEvaluationMatchesEvaluate checks that substituting the rest of
+// the context into a residual gives the full evaluation result
+func TestPartialEvaluationMatchesEvaluate(t *testing.T) {
+	expressions := []string{
+		"(a AND b) OR (c AND NOT d)",
+		"a -> (b <-> c)",
+		"XOR(a, d) AND IMPLIES(b, c)",
+		"ITE(a, x + 1, x * 2) > 3 OR d",
+	}
+	names := []string{"a", "b", "c", "d"}
+
+	for _, input := range expressions {
+		ast, err := parser.ParseExpression(input)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", input, err)
+		}
+		for mask := 0; mask < 1<<len(names); mask++ {
+			full := Context{"x": 2}
+			for i, name := range names {
+				full[name] = mask&(1<<i) != 0
+			}
+			expected, err := Evaluate(ast, full)
+			if err != nil {
+				t.Fatalf("Evaluate(%s) failed: %v", input, err)
+			}
+
+			// Decide a and c first, then the rest
+			partial := Context{"a": full["a"], "c": full["c"]}
+			result, err := PartialEvaluate(ast, partial)
+			if err != nil {
+				t.Fatalf("PartialEvaluate(%s) failed: %v", input, err)
+			}
+			got := result.Value
+			if !result.Known() {
+				if got, err = Evaluate(result.Residual, full); err != nil {
+					t.Fatalf("Evaluating residual %s failed: %v", result.Residual, err)
+				}
+			}
+			if got != expected {
+				t.Errorf("%s with %v: expected %v, got %v (residual %s)", input, full, expected, got, result)
+			}
+		}
+	}
+}
+
+// TestPartialEvaluationErrors tests that known operands are still type checked
+func TestPartialEvaluationErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		variables  Context
+		message    string
+	}{
+		{"x AND b", Context{"x": 5}, "Left operand of AND must be boolean"},
+		{"a OR 3", Context{}, "Right operand of OR must be boolean"},
+		{"a -> 1", Context{}, "Right operand must be boolean for ->"},
+		{"XOR(2, a)", Context{}, "Argument 1 of XOR must be boolean"},
+		{"x / 0 > y", Context{"x": 1}, "Division by zero"},
+	}
+
+	for _, tt := range tests {
+		_, err := PartialEvaluateExpression(tt.expression, tt.variables)
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.expression, tt.message, err)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package evaluator
+
+import (
+	"DD/parser"
+	"encoding/json"
+)
+
+// ===== EVALUATION TRACE =====
+
+// TraceNode records the value one AST node evaluated to. Operands skipped
+// by short-circuit evaluation have no node.
+type TraceNode struct {
+	Kind       string             `json:"kind"`               // AST node type, e.g. "BinaryOperation"
+	Operator   string             `json:"operator,omitempty"` // Operator or function name
+	Expression string             `json:"expression"`
+	Range      parser.SourceRange `json:"range"`
+	Value      interface{}        `json:"value"`
+	Children   []*TraceNode       `json:"children,omitempty"`
+
+	token parser.TokenType
+}
+
+// TraceReason is a variable whose value determined the outcome
+type TraceReason struct {
+	Variable string             `json:"variable"`
+	Value    interface{}        `json:"value"`
+	Range    parser.SourceRange `json:"range"`
+}
+
+// Trace explains an evaluation: the value of every evaluated node and the
+// minimal set of variables that decided the result
+type Trace struct {
+	Root    *TraceNode    `json:"root"`
+	Reasons []TraceReason `json:"reasons"`
+}
+
+// Value returns the result of the traced evaluation
+func (t *Trace) Value() interface{} {
+	return t.Root.Value
+}
+
+// JSON exports the trace for clients
+func (t *Trace) JSON() ([]byte, error) {
+	return json.MarshalIndent(t, "", "  ")
+}
+
+// TracingEvaluator evaluates like Evaluator while recording a TraceNode
+// for every node it visits. Its visit methods return *TraceNode.
+type TracingEvaluator struct {
+	evaluator *Evaluator
+}
+
+// NewTracingEvaluator creates a tracing evaluator over the given context
+func NewTracingEvaluator(context Context) *TracingEvaluator {
+	return &TracingEvaluator{evaluator: NewEvaluator(context)}
+}
+
+// EvaluateWithTrace evaluates expr and records why it has its value
+func EvaluateWithTrace(expr parser.Expression, context Context) (*Trace, error) {
+	root, err := NewTracingEvaluator(context).trace(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Trace{Root: root, Reasons: traceReasons(root)}, nil
+}
+
+// EvaluateExpressionWithTrace parses and traces an expression in one call
+func EvaluateExpressionWithTrace(input string, context Context) (*Trace, error) {
+	ast, err := parser.ParseExpression(input)
+	if err != nil {
+		return nil, err
+	}
+	return EvaluateWithTrace(ast, context)
+}
+
+func (t *TracingEvaluator) trace(expr parser.Expression) (*TraceNode, error) {
+	result, err := expr.Accept(t)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*TraceNode), nil
+}
+
+// ===== VISITOR IMPLEMENTATION =====
+
+func (t *TracingEvaluator) VisitNumberLiteral(node *parser.NumberLiteral) (interface{}, error) {
+	return newTraceNode("NumberLiteral", node, node.Value), nil
+}
+
+func (t *TracingEvaluator) VisitBooleanLiteral(node *parser.BooleanLiteral) (interface{}, error) {
+	return newTraceNode("BooleanLiteral", node, node.Value), nil
+}
+
+func (t *TracingEvaluator) VisitIdentifier(node *parser.Identifier) (interface{}, error) {
+	value, err := t.evaluator.VisitIdentifier(node)
+	if err != nil {
+		return nil, err
+	}
+	return newTraceNode("Identifier", node, value), nil
+}
+
+func (t *TracingEvaluator) VisitBinaryOperation(node *parser.BinaryOperation) (interface{}, error) {
+	left, err := t.trace(node.Left)
+	if err != nil {
+		return nil, err
+	}
+	result := newTraceNode("BinaryOperation", node, nil)
+	result.Operator, result.token = node.Operator.String(), node.Operator
+	result.Children = []*TraceNode{left}
+
+	logical := node.Operator == parser.TOKEN_AND || node.Operator == parser.TOKEN_OR
+	if logical {
+		// Short-circuit as Evaluate does; the right operand stays untraced
+		if leftBool, ok := ToBool(left.Value); ok && leftBool == (node.Operator == parser.TOKEN_OR) {
+			result.Value = leftBool
+			return result, nil
+		}
+	}
+
+	right, err := t.trace(node.Right)
+	if err != nil {
+		return nil, err
+	}
+	result.Children = append(result.Children, right)
+
+	if logical {
+		// Evaluate the operand values so type errors match Evaluate
+		result.Value, err = t.evaluator.VisitBinaryOperation(&parser.BinaryOperation{
+			Left:     valueLiteral(node.Left, left.Value),
+			Operator: node.Operator,
+			Right:    valueLiteral(node.Right, right.Value),
+			Range:    node.Range,
+		})
+	} else {
+		result.Value, err = t.evaluator.applyBinaryOperation(node, left.Value, right.Value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (t *TracingEvaluator) VisitUnaryOperation(node *parser.UnaryOperation) (interface{}, error) {
+	operand, err := t.trace(node.Operand)
+	if err != nil {
+		return nil, err
+	}
+	value, err := t.evaluator.applyUnaryOperation(node, operand.Value)
+	if err != nil {
+		return nil, err
+	}
+	result := newTraceNode("UnaryOperation", node, value)
+	result.Operator, result.token = node.Operator.String(), node.Operator
+	result.Children = []*TraceNode{operand}
+	return result, nil
+}
+
+func (t *TracingEvaluator) VisitFunctionCall(node *parser.FunctionCall) (interface{}, error) {
+	result := newTraceNode("FunctionCall", node, nil)
+	result.Operator, result.token = node.Function.String(), node.Function
+
+	args := make([]interface{}, len(node.Args))
+	for i, arg := range node.Args {
+		child, err := t.trace(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = child.Value
+		result.Children = append(result.Children, child)
+	}
+
+	value, err := t.evaluator.applyFunction(node, args)
+	if err != nil {
+		return nil, err
+	}
+	result.Value = value
+	return result, nil
+}
+
+func newTraceNode(kind string, node parser.Expression, value interface{}) *TraceNode {
+	return &TraceNode{Kind: kind, Expression: node.String(), Range: node.GetRange(), Value: value}
+}
+
+// ===== CAUSALITY =====
+
+// traceReasons lists the variables that decided the root's value, each once
+func traceReasons(root *TraceNode) []TraceReason {
+	var reasons []TraceReason
+	seen := make(map[string]bool)
+	for _, leaf := range traceCauses(root) {
+		if !seen[leaf.Expression] {
+			seen[leaf.Expression] = true
+			reasons = append(reasons, TraceReason{Variable: leaf.Expression, Value: leaf.Value, Range: leaf.Range})
+		}
+	}
+	return reasons
+}
+
+// traceCauses returns the identifier nodes that determined node's value.
+// A dominant operand alone decides AND (false), OR (true) and implication
+// (false antecedent or true consequent); when several operands could
+// decide, the one with fewer causes wins. Every other operation depends on
+// all of its evaluated operands.
+func traceCauses(node *TraceNode) []*TraceNode {
+	switch node.Kind {
+	case "Identifier":
+		return []*TraceNode{node}
+	case "NumberLiteral", "BooleanLiteral":
+		return nil
+	}
+
+	value, isBool := ToBool(node.Value)
+	children := node.Children
+	switch node.token {
+	case parser.TOKEN_AND, parser.TOKEN_OR:
+		if isBool && value == (node.token == parser.TOKEN_OR) {
+			return decidingCauses(children, value)
+		}
+	case parser.TOKEN_IMPLIES, parser.TOKEN_IMPLIES_OP:
+		if isBool && value && len(children) == 2 {
+			var candidates [][]*TraceNode
+			if antecedent, ok := ToBool(children[0].Value); ok && !antecedent {
+				candidates = append(candidates, traceCauses(children[0]))
+			}
+			if consequent, ok := ToBool(children[1].Value); ok && consequent {
+				candidates = append(candidates, traceCauses(children[1]))
+			}
+			return smallestCauses(candidates)
+		}
+	case parser.TOKEN_ITE:
+		if len(children) == 3 {
+			if cond, ok := ToBool(children[0].Value); ok {
+				branch := children[2]
+				if cond {
+					branch = children[1]
+				}
+				return append(traceCauses(children[0]), traceCauses(branch)...)
+			}
+		}
+	}
+
+	var causes []*TraceNode
+	for _, child := range children {
+		causes = append(causes, traceCauses(child)...)
+	}
+	return causes
+}
+
+// decidingCauses picks the smallest cause among operands equal to the
+// dominant value
+func decidingCauses(children []*TraceNode, dominant bool) []*TraceNode {
+	var candidates [][]*TraceNode
+	for _, child := range children {
+		if value, ok := ToBool(child.Value); ok && value == dominant {
+			candidates = append(candidates, traceCauses(child))
+		}
+	}
+	return smallestCauses(candidates)
+}
+
+func smallestCauses(candidates [][]*TraceNode) []*TraceNode {
+	var best []*TraceNode
+	for i, causes := range candidates {
+		if i == 0 || len(causes) < len(best) {
+			best = causes
+		}
+	}
+	return best
+}
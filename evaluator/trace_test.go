@@ -0,0 +1,168 @@
+package evaluator
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestEvaluationTrace tests the minimal reasons derived from a trace
+func TestEvaluationTrace(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		variables  Context
+		expected   interface{}
+		reasons    []string // variable=value
+	}{
+		{
+			name:       "Violated implication",
+			expression: "cpu_i9 -> cooling_liquid",
+			variables:  Context{"cpu_i9": true, "cooling_liquid": false},
+			expected:   false,
+			reasons:    []string{"cpu_i9=true", "cooling_liquid=false"},
+		},
+		{
+			name:       "Implication holds through its antecedent",
+			expression: "cpu_i9 -> cooling_liquid",
+			variables:  Context{"cpu_i9": false, "cooling_liquid": false},
+			expected:   true,
+			reasons:    []string{"cpu_i9=false"},
+		},
+		{
+			name:       "Short-circuited AND",
+			expression: "a AND (b OR c)",
+			variables:  Context{"a": false, "b": true, "c": true},
+			expected:   false,
+			reasons:    []string{"a=false"},
+		},
+		{
+			name:       "AND decided by its right operand",
+			expression: "a AND b AND c",
+			variables:  Context{"a": true, "b": false, "c": true},
+			expected:   false,
+			reasons:    []string{"b=false"},
+		},
+		{
+			name:       "True AND needs every operand",
+			expression: "a AND NOT b",
+			variables:  Context{"a": true, "b": false},
+			expected:   true,
+			reasons:    []string{"a=true", "b=false"},
+		},
+		{
+			name:       "OR picks the smaller cause",
+			expression: "(a AND b) OR c",
+			variables:  Context{"a": true, "b": false, "c": true},
+			expected:   true,
+			reasons:    []string{"c=true"},
+		},
+		{
+			name:       "Implication function picks the smaller cause",
+			expression: "IMPLIES(a AND b, c)",
+			variables:  Context{"a": true, "b": false, "c": true},
+			expected:   true,
+			reasons:    []string{"b=false"},
+		},
+		{
+			name:       "ITE depends on the condition and chosen branch",
+			expression: "ITE(premium, price * 2, discount) > 10",
+			variables:  Context{"premium": true, "price": 6, "discount": 100},
+			expected:   true,
+			reasons:    []string{"premium=true", "price=6"},
+		},
+		{
+			name:       "Repeated variable is reported once",
+			expression: "x > 1 AND x < 5",
+			variables:  Context{"x": 3},
+			expected:   true,
+			reasons:    []string{"x=3"},
+		},
+		{
+			name:       "Constant expression has no reasons",
+			expression: "1 + 2 == 3",
+			variables:  Context{},
+			expected:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace, err := EvaluateExpressionWithTrace(tt.expression, tt.variables)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if trace.Value() != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, trace.Value())
+			}
+			plain, err := EvaluateExpression(tt.expression, tt.variables)
+			if err != nil || plain != trace.Value() {
+				t.Errorf("Trace value %v differs from Evaluate (%v, %v)", trace.Value(), plain, err)
+			}
+
+			var reasons []string
+			for _, reason := range trace.Reasons {
+				reasons = append(reasons, fmt.Sprintf("%s=%v", reason.Variable, reason.Value))
+			}
+			if !reflect.DeepEqual(reasons, tt.reasons) {
+				t.Errorf("Expected reasons %v, got %v", tt.reasons, reasons)
+			}
+		})
+	}
+}
+
+// TestEvaluationTraceNodes tests the recorded nodes, ranges and JSON export
+func TestEvaluationTraceNodes(t *testing.T) {
+	trace, err := EvaluateExpressionWithTrace("a OR b AND c", Context{"a": true, "b": true, "c": false})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	root := trace.Root
+	if root.Kind != "BinaryOperation" || root.Operator != "OR" || root.Value != true {
+		t.Errorf("Unexpected root: %+v", root)
+	}
+	// The right operand of OR is skipped, so it has no trace node
+	if len(root.Children) != 1 || root.Children[0].Expression != "a" {
+		t.Fatalf("Expected only the left operand to be traced, got %d children", len(root.Children))
+	}
+	if rng := trace.Reasons[0].Range; rng.Start.Column != 1 || rng.End.Column != 2 {
+		t.Errorf("Unexpected reason range: %s", rng)
+	}
+
+	data, err := trace.JSON()
+	if err != nil {
+		t.Fatalf("JSON export failed: %v", err)
+	}
+	var decoded struct {
+		Root struct {
+			Kind     string `json:"kind"`
+			Operator string `json:"operator"`
+			Value    bool   `json:"value"`
+			Children []struct {
+				Expression string `json:"expression"`
+			} `json:"children"`
+		} `json:"root"`
+		Reasons []struct {
+			Variable string `json:"variable"`
+			Value    bool   `json:"value"`
+		} `json:"reasons"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, data)
+	}
+	if decoded.Root.Operator != "OR" || !decoded.Root.Value || len(decoded.Reasons) != 1 || decoded.Reasons[0].Variable != "a" {
+		t.Errorf("Unexpected JSON:\n%s", data)
+	}
+
+	if _, err := EvaluateExpressionWithTrace("a AND 3", Context{"a": true}); err == nil ||
+		!strings.Contains(err.Error(), "Right operand of AND must be boolean") {
+		t.Errorf("Expected a type error, got %v", err)
+	}
+	if _, err := EvaluateExpressionWithTrace("a AND missing", Context{"a": true}); err == nil ||
+		!strings.Contains(err.Error(), "Undefined variable 'missing'") {
+		t.Errorf("Expected an undefined variable error, got %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+// Package vm compiles expressions to bytecode for hot-path evaluation.
+// Variables resolve to slots at compile time, constant subexpressions are
+// folded, and programs run on a stack of typed values without boxing.
+// Results match evaluator.Evaluate: errors are rare, so when an instruction
+// fails the program re-evaluates with the tree-walking evaluator to report
+// the same error message and source range.
+//
+// Callers that evaluate one expression many times, such as price rules in
+// bulk pricing requests, compile once and run each context on a Machine.
+package vm
+
+import (
+	"DD/evaluator"
+	"DD/parser"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ===== BYTECODE =====
+
+// Opcode identifies a VM instruction
+type Opcode uint8
+
+const (
+	OpConst Opcode = iota // Push consts[arg]
+	OpTrue                // Push true
+	OpFalse               // Push false
+	OpLoad                // Push slots[arg]
+	OpAdd                 // Numeric binary operations pop two, push one
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpMin
+	OpMax
+	OpEq // Equality of two numbers or two booleans
+	OpNe
+	OpLt // Numeric ordering
+	OpLe
+	OpGt
+	OpGe
+	OpXor // Boolean binary operations
+	OpImplies
+	OpEquiv
+	OpNot // Unary operations pop one, push one
+	OpNeg
+	OpPos
+	OpAbs
+	OpCeil
+	OpFloor
+	OpAndJump // Pop a boolean; if false push false and jump to arg
+	OpOrJump  // Pop a boolean; if true push true and jump to arg
+	OpBool    // Check the top of the stack is a boolean
+	OpSelect  // Pop condition, then, else; push the chosen value
+)
+
+var opcodeNames = [...]string{
+	OpConst: "CONST", OpTrue: "TRUE", OpFalse: "FALSE", OpLoad: "LOAD",
+	OpAdd: "ADD", OpSub: "SUB", OpMul: "MUL", OpDiv: "DIV", OpMod: "MOD", OpMin: "MIN", OpMax: "MAX",
+	OpEq: "EQ", OpNe: "NE", OpLt: "LT", OpLe: "LE", OpGt: "GT", OpGe: "GE",
+	OpXor: "XOR", OpImplies: "IMPLIES", OpEquiv: "EQUIV",
+	OpNot: "NOT", OpNeg: "NEG", OpPos: "POS", OpAbs: "ABS", OpCeil: "CEIL", OpFloor: "FLOOR",
+	OpAndJump: "AND_JUMP", OpOrJump: "OR_JUMP", OpBool: "BOOL", OpSelect: "SELECT",
+}
+
+func (op Opcode) String() string {
+	if int(op) < len(opcodeNames) {
+		return opcodeNames[op]
+	}
+	return fmt.Sprintf("Opcode(%d)", op)
+}
+
+// instruction is one opcode with its operand
+type instruction struct {
+	op  Opcode
+	arg int32
+}
+
+// Program is a compiled expression. It is immutable and safe to run from
+// several goroutines, each with its own Machine.
+type Program struct {
+	code     []instruction
+	consts   []float64
+	slots    []string
+	maxStack int
+	expr     parser.Expression // Source AST for error reporting
+}
+
+// Slots returns the variable names in slot order
+func (p *Program) Slots() []string {
+	return p.slots
+}
+
+// Disassemble lists the instructions, one per line
+func (p *Program) Disassemble() string {
+	var buf strings.Builder
+	for pc, in := range p.code {
+		fmt.Fprintf(&buf, "%3d %s", pc, in.op)
+		switch in.op {
+		case OpConst:
+			fmt.Fprintf(&buf, " %g", p.consts[in.arg])
+		case OpLoad:
+			fmt.Fprintf(&buf, " %s", p.slots[in.arg])
+		case OpAndJump, OpOrJump:
+			fmt.Fprintf(&buf, " %d", in.arg)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// ===== COMPILER =====
+
+// compiler emits bytecode for one expression
+type compiler struct {
+	program   *Program
+	slotIndex map[string]int32
+	depth     int
+}
+
+// Compile translates an expression to bytecode
+func Compile(expr parser.Expression) (*Program, error) {
+	c := &compiler{
+		program:   &Program{expr: expr},
+		slotIndex: make(map[string]int32),
+	}
+	if err := c.compile(expr); err != nil {
+		return nil, err
+	}
+	return c.program, nil
+}
+
+// CompileExpression parses and compiles an expression in one call
+func CompileExpression(input string) (*Program, error) {
+	ast, err := parser.ParseExpression(input)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(ast)
+}
+
+var binaryOpcodes = map[parser.TokenType]Opcode{
+	parser.TOKEN_PLUS: OpAdd, parser.TOKEN_MINUS: OpSub, parser.TOKEN_MULTIPLY: OpMul,
+	parser.TOKEN_DIVIDE: OpDiv, parser.TOKEN_MODULO: OpMod, parser.TOKEN_MIN: OpMin, parser.TOKEN_MAX: OpMax,
+	parser.TOKEN_EQ: OpEq, parser.TOKEN_NE: OpNe,
+	parser.TOKEN_LT: OpLt, parser.TOKEN_LE: OpLe, parser.TOKEN_GT: OpGt, parser.TOKEN_GE: OpGe,
+	parser.TOKEN_XOR: OpXor, parser.TOKEN_IMPLIES: OpImplies, parser.TOKEN_IMPLIES_OP: OpImplies,
+	parser.TOKEN_EQUIV: OpEquiv, parser.TOKEN_EQUIV_OP: OpEquiv,
+}
+
+var unaryOpcodes = map[parser.TokenType]Opcode{
+	parser.TOKEN_NOT: OpNot, parser.TOKEN_MINUS: OpNeg, parser.TOKEN_PLUS: OpPos,
+}
+
+// functionOpcodes maps functions to the instruction applied to their
+// arguments; THRESHOLD(a, b) is a >= b
+var functionOpcodes = map[parser.TokenType]struct {
+	op    Opcode
+	arity int
+}{
+	parser.TOKEN_ABS: {OpAbs, 1}, parser.TOKEN_NEGATE: {OpNeg, 1},
+	parser.TOKEN_CEIL: {OpCeil, 1}, parser.TOKEN_FLOOR: {OpFloor, 1},
+	parser.TOKEN_MIN: {OpMin, 2}, parser.TOKEN_MAX: {OpMax, 2}, parser.TOKEN_THRESHOLD: {OpGe, 2},
+	parser.TOKEN_IMPLIES: {OpImplies, 2}, parser.TOKEN_EQUIV: {OpEquiv, 2}, parser.TOKEN_XOR: {OpXor, 2},
+	parser.TOKEN_ITE: {OpSelect, 3},
+}
+
+func (c *compiler) compile(expr parser.Expression) error {
+	if c.fold(expr) {
+		return nil
+	}
+
+	switch node := expr.(type) {
+	case *parser.NumberLiteral:
+		c.constant(node.Value)
+	case *parser.BooleanLiteral:
+		c.boolean(node.Value)
+	case *parser.Identifier:
+		slot, ok := c.slotIndex[node.Name]
+		if !ok {
+			slot = int32(len(c.program.slots))
+			c.slotIndex[node.Name] = slot
+			c.program.slots = append(c.program.slots, node.Name)
+		}
+		c.emit(OpLoad, slot, 1)
+	case *parser.BinaryOperation:
+		if node.Operator == parser.TOKEN_AND || node.Operator == parser.TOKEN_OR {
+			return c.compileShortCircuit(node)
+		}
+		op, ok := binaryOpcodes[node.Operator]
+		if !ok {
+			return c.unsupported(node, node.Operator.String())
+		}
+		if err := c.compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.compile(node.Right); err != nil {
+			return err
+		}
+		c.emit(op, 0, -1)
+	case *parser.UnaryOperation:
+		op, ok := unaryOpcodes[node.Operator]
+		if !ok {
+			return c.unsupported(node, node.Operator.String())
+		}
+		if err := c.compile(node.Operand); err != nil {
+			return err
+		}
+		c.emit(op, 0, 0)
+	case *parser.FunctionCall:
+		fn, ok := functionOpcodes[node.Function]
+		if !ok || len(node.Args) != fn.arity {
+			return c.unsupported(node, node.Function.String())
+		}
+		// Every argument is evaluated, as Evaluate does, even the ITE
+		// branch that is not taken
+		for _, arg := range node.Args {
+			if err := c.compile(arg); err != nil {
+				return err
+			}
+		}
+		c.emit(fn.op, 0, 1-fn.arity)
+	default:
+		return fmt.Errorf("unsupported expression %T", expr)
+	}
+	return nil
+}
+
+// compileShortCircuit emits AND/OR with a jump past the right operand
+func (c *compiler) compileShortCircuit(node *parser.BinaryOperation) error {
+	if err := c.compile(node.Left); err != nil {
+		return err
+	}
+	op := OpAndJump
+	if node.Operator == parser.TOKEN_OR {
+		op = OpOrJump
+	}
+	jump := len(c.program.code)
+	c.emit(op, 0, -1)
+	if err := c.compile(node.Right); err != nil {
+		return err
+	}
+	c.emit(OpBool, 0, 0)
+	c.program.code[jump].arg = int32(len(c.program.code))
+	return nil
+}
+
+// fold replaces a subexpression without variables by its value. Constant
+// errors such as 1/0 are left to fail at run time like Evaluate does.
+func (c *compiler) fold(expr parser.Expression) bool {
+	switch expr.(type) {
+	case *parser.NumberLiteral, *parser.BooleanLiteral, *parser.Identifier:
+		return false
+	}
+	if len(parser.CollectVariables(expr)) > 0 {
+		return false
+	}
+	value, err := evaluator.Evaluate(expr, nil)
+	if err != nil {
+		return false
+	}
+	switch v := value.(type) {
+	case float64:
+		c.constant(v)
+	case bool:
+		c.boolean(v)
+	default:
+		return false
+	}
+	return true
+}
+
+// constant emits a pooled constant; values are compared bitwise to keep
+// -0 apart from 0
+func (c *compiler) constant(value float64) {
+	for i, existing := range c.program.consts {
+		if math.Float64bits(existing) == math.Float64bits(value) {
+			c.emit(OpConst, int32(i), 1)
+			return
+		}
+	}
+	c.program.consts = append(c.program.consts, value)
+	c.emit(OpConst, int32(len(c.program.consts)-1), 1)
+}
+
+func (c *compiler) boolean(value bool) {
+	if value {
+		c.emit(OpTrue, 0, 1)
+	} else {
+		c.emit(OpFalse, 0, 1)
+	}
+}
+
+// emit appends an instruction that changes the stack depth by effect
+func (c *compiler) emit(op Opcode, arg int32, effect int) {
+	c.program.code = append(c.program.code, instruction{op: op, arg: arg})
+	c.depth += effect
+	if c.depth > c.program.maxStack {
+		c.program.maxStack = c.depth
+	}
+}
+
+func (c *compiler) unsupported(node parser.Expression, name string) error {
+	return &parser.ParseError{
+		Message:   fmt.Sprintf("Cannot compile operator %s", name),
+		Range:     node.GetRange(),
+		ErrorType: "semantic",
+	}
+}
@@ -0,0 +1,282 @@
+package vm
+
+import (
+	"DD/evaluator"
+	"errors"
+	"math"
+)
+
+// ===== VALUES =====
+
+// Kind is the type held by a Value
+type Kind uint8
+
+const (
+	KindUndefined Kind = iota // Slot missing from the context
+	KindNumber
+	KindBool
+	KindOther // Context value of another type, kept for error reporting
+)
+
+// Value is a typed VM register
+type Value struct {
+	Kind  Kind
+	Num   float64
+	Bool  bool
+	Other interface{}
+}
+
+// Number creates a numeric value
+func Number(n float64) Value {
+	return Value{Kind: KindNumber, Num: n}
+}
+
+// Boolean creates a boolean value
+func Boolean(b bool) Value {
+	return Value{Kind: KindBool, Bool: b}
+}
+
+// FromInterface converts a context value the way the evaluator does
+func FromInterface(value interface{}) Value {
+	if num, ok := evaluator.ToFloat64(value); ok {
+		return Number(num)
+	}
+	if b, ok := evaluator.ToBool(value); ok {
+		return Boolean(b)
+	}
+	return Value{Kind: KindOther, Other: value}
+}
+
+// Interface returns the value as Evaluate would: float64, bool or the
+// original context value
+func (v Value) Interface() interface{} {
+	switch v.Kind {
+	case KindNumber:
+		return v.Num
+	case KindBool:
+		return v.Bool
+	}
+	return v.Other
+}
+
+// errFallback marks a failed instruction; the caller re-evaluates with the
+// tree-walking evaluator to produce the error
+var errFallback = errors.New("vm: instruction failed")
+
+// ===== MACHINE =====
+
+// Machine runs programs, reusing its stack and slot buffers between runs.
+// A Machine is not safe for concurrent use.
+type Machine struct {
+	stack []Value
+	slots []Value
+}
+
+// NewMachine creates a machine
+func NewMachine() *Machine {
+	return &Machine{}
+}
+
+// Run is a convenience for NewMachine().Run(p, context)
+func (p *Program) Run(context evaluator.Context) (interface{}, error) {
+	return NewMachine().Run(p, context)
+}
+
+// Bind resolves the program's slots against a context. Missing variables
+// stay undefined and fail only if the program loads them.
+func (p *Program) Bind(context evaluator.Context, slots []Value) []Value {
+	slots = slots[:0]
+	for _, name := range p.slots {
+		value, ok := context[name]
+		if !ok {
+			slots = append(slots, Value{})
+			continue
+		}
+		slots = append(slots, FromInterface(value))
+	}
+	return slots
+}
+
+// Run binds the context and executes the program
+func (m *Machine) Run(p *Program, context evaluator.Context) (interface{}, error) {
+	m.slots = p.Bind(context, m.slots)
+	result, err := m.execute(p, m.slots)
+	if err != nil {
+		return evaluator.Evaluate(p.expr, context)
+	}
+	return result.Interface(), nil
+}
+
+// Execute runs the program on pre-bound slot values, in Slots order
+func (m *Machine) Execute(p *Program, slots []Value) (Value, error) {
+	result, err := m.execute(p, slots)
+	if err != nil {
+		context := make(evaluator.Context, len(slots))
+		for i, name := range p.slots {
+			if i < len(slots) && slots[i].Kind != KindUndefined {
+				context[name] = slots[i].Interface()
+			}
+		}
+		value, err := evaluator.Evaluate(p.expr, context)
+		if err != nil {
+			return Value{}, err
+		}
+		return FromInterface(value), nil
+	}
+	return result, nil
+}
+
+func (m *Machine) execute(p *Program, slots []Value) (Value, error) {
+	if cap(m.stack) < p.maxStack {
+		m.stack = make([]Value, p.maxStack)
+	}
+	stack := m.stack[:p.maxStack]
+	sp := 0
+	code := p.code
+
+	for pc := 0; pc < len(code); pc++ {
+		in := code[pc]
+		switch in.op {
+		case OpConst:
+			stack[sp] = Value{Kind: KindNumber, Num: p.consts[in.arg]}
+			sp++
+		case OpTrue, OpFalse:
+			stack[sp] = Value{Kind: KindBool, Bool: in.op == OpTrue}
+			sp++
+		case OpLoad:
+			if int(in.arg) >= len(slots) || slots[in.arg].Kind > KindBool {
+				return Value{}, errFallback
+			}
+			stack[sp] = slots[in.arg]
+			sp++
+
+		case OpAdd, OpSub, OpMul, OpDiv, OpMod, OpMin, OpMax, OpLt, OpLe, OpGt, OpGe:
+			a, b := stack[sp-2], stack[sp-1]
+			if a.Kind != KindNumber || b.Kind != KindNumber {
+				return Value{}, errFallback
+			}
+			sp--
+			result := &stack[sp-1]
+			switch in.op {
+			case OpAdd:
+				result.Num = a.Num + b.Num
+			case OpSub:
+				result.Num = a.Num - b.Num
+			case OpMul:
+				result.Num = a.Num * b.Num
+			case OpDiv:
+				if b.Num == 0 {
+					return Value{}, errFallback
+				}
+				result.Num = a.Num / b.Num
+			case OpMod:
+				if b.Num == 0 {
+					return Value{}, errFallback
+				}
+				result.Num = math.Mod(a.Num, b.Num)
+			case OpMin:
+				result.Num = math.Min(a.Num, b.Num)
+			case OpMax:
+				result.Num = math.Max(a.Num, b.Num)
+			default:
+				*result = Value{Kind: KindBool, Bool: compare(in.op, a.Num, b.Num)}
+			}
+
+		case OpEq, OpNe:
+			a, b := stack[sp-2], stack[sp-1]
+			var equal bool
+			switch {
+			case a.Kind == KindNumber && b.Kind == KindNumber:
+				equal = a.Num == b.Num
+			case a.Kind == KindBool && b.Kind == KindBool:
+				equal = a.Bool == b.Bool
+			default:
+				return Value{}, errFallback
+			}
+			sp--
+			stack[sp-1] = Value{Kind: KindBool, Bool: equal == (in.op == OpEq)}
+
+		case OpXor, OpImplies, OpEquiv:
+			a, b := stack[sp-2], stack[sp-1]
+			if a.Kind != KindBool || b.Kind != KindBool {
+				return Value{}, errFallback
+			}
+			sp--
+			var result bool
+			switch in.op {
+			case OpXor:
+				result = a.Bool != b.Bool
+			case OpImplies:
+				result = !a.Bool || b.Bool
+			default:
+				result = a.Bool == b.Bool
+			}
+			stack[sp-1] = Value{Kind: KindBool, Bool: result}
+
+		case OpNot, OpBool:
+			top := &stack[sp-1]
+			if top.Kind != KindBool {
+				return Value{}, errFallback
+			}
+			if in.op == OpNot {
+				top.Bool = !top.Bool
+			}
+		case OpNeg, OpPos, OpAbs, OpCeil, OpFloor:
+			top := &stack[sp-1]
+			if top.Kind != KindNumber {
+				return Value{}, errFallback
+			}
+			switch in.op {
+			case OpNeg:
+				top.Num = -top.Num
+			case OpAbs:
+				top.Num = math.Abs(top.Num)
+			case OpCeil:
+				top.Num = math.Ceil(top.Num)
+			case OpFloor:
+				top.Num = math.Floor(top.Num)
+			}
+
+		case OpAndJump, OpOrJump:
+			top := stack[sp-1]
+			if top.Kind != KindBool {
+				return Value{}, errFallback
+			}
+			if top.Bool == (in.op == OpOrJump) {
+				// Leave the deciding operand as the result
+				pc = int(in.arg) - 1
+				continue
+			}
+			sp--
+
+		case OpSelect:
+			cond := stack[sp-3]
+			if cond.Kind != KindBool {
+				return Value{}, errFallback
+			}
+			chosen := stack[sp-1]
+			if cond.Bool {
+				chosen = stack[sp-2]
+			}
+			sp -= 2
+			stack[sp-1] = chosen
+		}
+	}
+
+	if sp != 1 {
+		return Value{}, errFallback
+	}
+	return stack[0], nil
+}
+
+func compare(op Opcode, a, b float64) bool {
+	switch op {
+	case OpLt:
+		return a < b
+	case OpLe:
+		return a <= b
+	case OpGt:
+		return a > b
+	}
+	return a >= b
+}
@@ -0,0 +1,197 @@
+package vm
+
+import (
+	"DD/evaluator"
+	"DD/parser"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestCompile tests constant folding and slot resolution
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		expression string
+		slots      []string
+		code       string
+	}{
+		{
+			expression: "x + 2 * 3",
+			slots:      []string{"x"},
+			code:       "LOAD x|CONST 6|ADD",
+		},
+		{
+			expression: "price * (1 - discount) > price / 2",
+			slots:      []string{"price", "discount"},
+			code:       "LOAD price|CONST 1|LOAD discount|SUB|MUL|LOAD price|CONST 2|DIV|GT",
+		},
+		{
+			expression: "a AND (b OR NOT true)",
+			slots:      []string{"a", "b"},
+			code:       "LOAD a|AND_JUMP 7|LOAD b|OR_JUMP 6|FALSE|BOOL|BOOL",
+		},
+		{
+			expression: "ITE(flag, MIN(x, 10), THRESHOLD(1, 2) <-> false)",
+			slots:      []string{"flag", "x"},
+			code:       "LOAD flag|LOAD x|CONST 10|MIN|TRUE|SELECT",
+		},
+		{
+			// Constant errors are kept so they are reported at run time
+			expression: "x + 1 / 0",
+			slots:      []string{"x"},
+			code:       "LOAD x|CONST 1|CONST 0|DIV|ADD",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			program, err := CompileExpression(tt.expression)
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+			if !reflect.DeepEqual(program.Slots(), tt.slots) {
+				t.Errorf("Expected slots %v, got %v", tt.slots, program.Slots())
+			}
+			var code []string
+			for _, line := range strings.Split(strings.TrimSpace(program.Disassemble()), "\n") {
+				code = append(code, strings.Join(strings.Fields(line)[1:], " "))
+			}
+			if got := strings.Join(code, "|"); got != tt.code {
+				t.Errorf("Expected %s, got %s", tt.code, got)
+			}
+		})
+	}
+}
+
+// TestMachine tests execution with pre-bound slots and error fallback
+func TestMachine(t *testing.T) {
+	program, err := CompileExpression("ITE(premium, base * 1.2, base) + fee")
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	machine := NewMachine()
+
+	slots := []Value{Boolean(true), Number(100), Number(5)}
+	for i := 0; i < 3; i++ {
+		result, err := machine.Execute(program, slots)
+		if err != nil || result != Number(125) {
+			t.Fatalf("Expected 125, got %v (%v)", result, err)
+		}
+	}
+
+	result, err := machine.Run(program, evaluator.Context{"premium": false, "base": 100, "fee": int64(5)})
+	if err != nil || result != 105.0 {
+		t.Errorf("Expected 105, got %v (%v)", result, err)
+	}
+
+	// Failures are reported by the tree-walking evaluator
+	for _, context := range []evaluator.Context{
+		{"premium": 1, "base": 100, "fee": 5},
+		{"premium": true, "base": 100},
+		{"premium": true, "base": "100", "fee": 5},
+	} {
+		_, expected := evaluator.Evaluate(program.expr, context)
+		if _, err := machine.Run(program, context); err == nil || err.Error() != expected.Error() {
+			t.Errorf("Expected error %v, got %v", expected, err)
+		}
+		if _, err := machine.Execute(program, program.Bind(context, nil)); err == nil || err.Error() != expected.Error() {
+			t.Errorf("Expected error %v, got %v", expected, err)
+		}
+	}
+}
+
+// TestRandomExpressions compares the VM with Evaluate on generated
+// expressions and checks that well-typed ones run without falling back
+func TestRandomExpressions(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	machine := NewMachine()
+
+	for i := 0; i < 5000; i++ {
+		input := randomExpression(rng, 4, rng.Intn(2) == 0)
+		context := evaluator.Context{
+			"x": float64(rng.Intn(7) - 3), "y": float64(rng.Intn(7) - 3),
+			"p": rng.Intn(2) == 0, "q": rng.Intn(2) == 0,
+		}
+		expr, err := parser.ParseExpression(input)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", input, err)
+		}
+		program, err := Compile(expr)
+		if err != nil {
+			t.Fatalf("Failed to compile %s: %v", input, err)
+		}
+
+		expected, expectedErr := evaluator.Evaluate(expr, context)
+		result, err := machine.Run(program, context)
+		if expectedErr != nil {
+			if err == nil || err.Error() != expectedErr.Error() {
+				t.Errorf("%s: expected error %v, got %v", input, expectedErr, err)
+			}
+			continue
+		}
+		if err != nil || fmt.Sprint(result) != fmt.Sprint(expected) {
+			t.Errorf("%s: expected %v, got %v (%v)", input, expected, result, err)
+		}
+		if _, err := machine.execute(program, program.Bind(context, nil)); err != nil {
+			t.Errorf("%s: fell back to the evaluator", input)
+		}
+	}
+}
+
+// randomExpression generates a numeric or boolean expression; division
+// and modulo may fail at run time
+func randomExpression(rng *rand.Rand, depth int, boolean bool) string {
+	if depth == 0 || rng.Intn(4) == 0 {
+		if boolean {
+			return []string{"p", "q", "true", "false"}[rng.Intn(4)]
+		}
+		return []string{"x", "y", "0", "2", "1.5"}[rng.Intn(5)]
+	}
+	num := func() string { return randomExpression(rng, depth-1, false) }
+	cond := func() string { return randomExpression(rng, depth-1, true) }
+	if boolean {
+		switch rng.Intn(7) {
+		case 0:
+			return fmt.Sprintf("(%s %s %s)", cond(), []string{"AND", "OR", "->", "<->"}[rng.Intn(4)], cond())
+		case 1:
+			return "NOT (" + cond() + ")"
+		case 2:
+			return fmt.Sprintf("(%s %s %s)", num(), []string{"<", "<=", ">", ">=", "==", "!="}[rng.Intn(6)], num())
+		case 3:
+			return fmt.Sprintf("%s(%s, %s)", []string{"IMPLIES", "EQUIV", "XOR"}[rng.Intn(3)], cond(), cond())
+		case 4:
+			return fmt.Sprintf("THRESHOLD(%s, %s)", num(), num())
+		case 5:
+			return fmt.Sprintf("ITE(%s, %s, %s)", cond(), cond(), cond())
+		default:
+			return fmt.Sprintf("((%s) == (%s))", cond(), cond())
+		}
+	}
+	switch rng.Intn(5) {
+	case 0:
+		return fmt.Sprintf("(%s %s %s)", num(), []string{"+", "-", "*", "/", "%"}[rng.Intn(5)], num())
+	case 1:
+		return "-(" + num() + ")"
+	case 2:
+		return fmt.Sprintf("%s(%s)", []string{"ABS", "NEGATE", "CEIL", "FLOOR"}[rng.Intn(4)], num())
+	case 3:
+		return fmt.Sprintf("%s(%s, %s)", []string{"MIN", "MAX"}[rng.Intn(2)], num(), num())
+	default:
+		return fmt.Sprintf("ITE(%s, %s, %s)", cond(), num(), num())
+	}
+}
+
+func BenchmarkVMComplex(b *testing.B) {
+	program, _ := CompileExpression("((x + y) * 2 > 10 AND z != 0) OR (MIN(a, b) >= 5 AND THRESHOLD(c + d, 5))")
+	machine := NewMachine()
+	slots := program.Bind(evaluator.Context{"x": 5, "y": 3, "z": 1, "a": 10, "b": 8, "c": 15, "d": 5}, nil)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := machine.Execute(program, slots); err != nil {
+			b.Errorf("Execution error: %v", err)
+		}
+	}
+}
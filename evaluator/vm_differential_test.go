@@ -0,0 +1,51 @@
+package evaluator_test
+
+import (
+	"DD/evaluator"
+	"DD/evaluator/vm"
+	"DD/parser"
+	"math"
+	"testing"
+)
+
+// TestVMMatchesEvaluate runs the language corpus through the bytecode VM
+// and requires the same value or the same error as Evaluate
+func TestVMMatchesEvaluate(t *testing.T) {
+	machine := vm.NewMachine()
+	for _, tc := range evaluator.Corpus() {
+		t.Run(tc.Name, func(t *testing.T) {
+			expr, err := parser.ParseExpression(tc.Expression)
+			if err != nil {
+				return
+			}
+			expected, expectedErr := evaluator.Evaluate(expr, tc.Variables)
+
+			program, err := vm.Compile(expr)
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+			result, err := machine.Run(program, tc.Variables)
+
+			if expectedErr != nil {
+				if err == nil || err.Error() != expectedErr.Error() {
+					t.Errorf("Expected error %q, got %v (%v)", expectedErr, err, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected VM error: %v", err)
+			}
+			if !sameValue(result, expected) {
+				t.Errorf("Expected %v (%T), got %v (%T)", expected, expected, result, result)
+			}
+		})
+	}
+}
+
+func sameValue(a, b interface{}) bool {
+	if fa, ok := a.(float64); ok {
+		fb, ok := b.(float64)
+		return ok && (fa == fb || math.IsNaN(fa) && math.IsNaN(fb))
+	}
+	return a == b
+}
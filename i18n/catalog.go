@@ -0,0 +1,165 @@
+// i18n/catalog.go
+// Message catalogs with per-locale templates, plural forms and fallbacks
+
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// DefaultLocale is the locale every lookup falls back to
+const DefaultLocale = "en"
+
+// Default is the catalog of built-in messages in every supported language
+var Default = mustLoadEmbedded()
+
+// English returns a localizer for the default locale
+func English() *Localizer {
+	return Default.Localizer(DefaultLocale)
+}
+
+// ===================================================================
+// CATALOG
+// ===================================================================
+
+// template holds the plural forms of one message; a message without
+// plural forms has only "other"
+type template map[string]string
+
+// Catalog maps locales to message templates. Templates reference
+// arguments as {name}; a message with plural forms picks one by the
+// "count" argument using the locale's plural rules.
+type Catalog struct {
+	fallback string
+	messages map[string]map[string]template
+}
+
+// NewCatalog creates an empty catalog falling back to the given locale
+func NewCatalog(fallback string) *Catalog {
+	return &Catalog{
+		fallback: normalizeLocale(fallback),
+		messages: make(map[string]map[string]template),
+	}
+}
+
+// Load adds messages for a locale from JSON. Values are either a template
+// string or an object of plural forms, e.g. {"one": "...", "other": "..."}.
+func (c *Catalog) Load(locale string, data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("invalid catalog for %s: %w", locale, err)
+	}
+
+	messages := make(map[string]template, len(raw))
+	for key, value := range raw {
+		var text string
+		if err := json.Unmarshal(value, &text); err == nil {
+			messages[key] = template{"other": text}
+			continue
+		}
+		var forms map[string]string
+		if err := json.Unmarshal(value, &forms); err != nil {
+			return fmt.Errorf("invalid message %s in %s: must be a string or plural forms", key, locale)
+		}
+		if _, ok := forms["other"]; !ok {
+			return fmt.Errorf("message %s in %s has no \"other\" plural form", key, locale)
+		}
+		messages[key] = forms
+	}
+
+	locale = normalizeLocale(locale)
+	if c.messages[locale] == nil {
+		c.messages[locale] = messages
+		return nil
+	}
+	for key, forms := range messages {
+		c.messages[locale][key] = forms
+	}
+	return nil
+}
+
+// Locales returns the locales with messages, sorted
+func (c *Catalog) Locales() []string {
+	locales := make([]string, 0, len(c.messages))
+	for locale := range c.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Localizer returns a localizer for the first supported preference. A
+// regional locale such as "de-CH" falls back to "de", and every lookup
+// finally falls back to the catalog's fallback locale.
+func (c *Catalog) Localizer(preferences ...string) *Localizer {
+	var chain []string
+	add := func(locale string) {
+		if _, ok := c.messages[locale]; !ok {
+			return
+		}
+		for _, existing := range chain {
+			if existing == locale {
+				return
+			}
+		}
+		chain = append(chain, locale)
+	}
+
+	for _, preference := range preferences {
+		locale := normalizeLocale(preference)
+		add(locale)
+		if base, _, regional := strings.Cut(locale, "-"); regional {
+			add(base)
+		}
+		if len(chain) > 0 {
+			break
+		}
+	}
+	add(c.fallback)
+	return &Localizer{catalog: c, chain: chain}
+}
+
+// Match returns a localizer for an Accept-Language header value
+func (c *Catalog) Match(acceptLanguage string) *Localizer {
+	return c.Localizer(ParseAcceptLanguage(acceptLanguage)...)
+}
+
+// lookup finds a message template along a locale chain
+func (c *Catalog) lookup(chain []string, key string) (template, string, bool) {
+	for _, locale := range chain {
+		if forms, ok := c.messages[locale][key]; ok {
+			return forms, locale, true
+		}
+	}
+	return nil, "", false
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+func mustLoadEmbedded() *Catalog {
+	catalog := NewCatalog(DefaultLocale)
+	files, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, file := range files {
+		data, err := localeFiles.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+		if err := catalog.Load(strings.TrimSuffix(file.Name(), ".json"), data); err != nil {
+			panic(err)
+		}
+	}
+	return catalog
+}
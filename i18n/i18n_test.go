@@ -0,0 +1,121 @@
+package i18n
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLocalizer(t *testing.T) {
+	tests := []struct {
+		name     string
+		locales  []string
+		key      string
+		args     Args
+		expected string
+	}{
+		{"english", []string{"en"}, "explain.requires", Args{"source": "CPU", "target": "Cooling"}, "CPU requires Cooling"},
+		{"german", []string{"de"}, "explain.requires", Args{"source": "CPU", "target": "Kühlung"}, "CPU erfordert Kühlung"},
+		{"regional falls back to language", []string{"fr-CA"}, "explain.excludes", Args{"source": "A", "target": "B"}, "A exclut B"},
+		{"unsupported falls back to english", []string{"ja"}, "group.exactly_one", Args{"group": "CPU"}, "Please select exactly one option from CPU"},
+		{"first supported preference wins", []string{"ja", "es", "de"}, "repair.add", Args{"option": "RAM"}, "añadir RAM"},
+		{"missing key renders the key", []string{"de"}, "no.such.key", nil, "no.such.key"},
+		{"unknown placeholder is kept", []string{"en"}, "explain.not_allowed", Args{}, "{option} is not allowed"},
+		{"english plural one", []string{"en"}, "group.at_least", Args{"count": 1, "group": "RAM"}, "Please select at least 1 option from RAM"},
+		{"english plural other", []string{"en"}, "group.at_least", Args{"count": 0, "group": "RAM"}, "Please select at least 0 options from RAM"},
+		{"french zero is singular", []string{"fr"}, "group.at_least", Args{"count": 0, "group": "RAM"}, "Veuillez sélectionner au moins 0 option dans RAM"},
+		{"nested message", []string{"de"}, "reason.because", Args{"reasons": []Message{
+			NewMessage("reason.selected", Args{"option": "a"}),
+			NewMessage("reason.not_selected", Args{"option": "b"}),
+			NewMessage("reason.selected", Args{"option": "c"}),
+		}}, "verletzt, weil a ausgewählt ist, b nicht ausgewählt ist und c ausgewählt ist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Default.Localizer(tt.locales...).T(tt.key, tt.args); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRenderLiteral(t *testing.T) {
+	message := Literal("Requires liquid cooling", map[string]string{"de": "Erfordert Flüssigkühlung"})
+
+	if got := Default.Localizer("de-AT").Render(message); got != "Erfordert Flüssigkühlung" {
+		t.Errorf("expected German translation, got %q", got)
+	}
+	if got := Default.Localizer("fr").Render(message); got != "Requires liquid cooling" {
+		t.Errorf("expected authored text, got %q", got)
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected []string
+	}{
+		{"", []string{}},
+		{"de", []string{"de"}},
+		{"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5", []string{"fr-CH", "fr", "en", "de"}},
+		{"en;q=0.5, es", []string{"es", "en"}},
+		{"de;q=0, fr", []string{"fr"}},
+		{"de;q=abc, es", []string{"es"}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseAcceptLanguage(tt.header); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ParseAcceptLanguage(%q) = %v, expected %v", tt.header, got, tt.expected)
+		}
+	}
+
+	if locale := Default.Match("pt-BR, es;q=0.8").Locale(); locale != "es" {
+		t.Errorf("expected es, got %s", locale)
+	}
+	if locale := Default.Match("").Locale(); locale != DefaultLocale {
+		t.Errorf("expected %s, got %s", DefaultLocale, locale)
+	}
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	english := Default.messages[DefaultLocale]
+	if len(Default.Locales()) != 4 {
+		t.Errorf("expected 4 locales, got %v", Default.Locales())
+	}
+
+	for _, locale := range Default.Locales() {
+		for key, forms := range english {
+			translated, ok := Default.messages[locale][key]
+			if !ok {
+				t.Errorf("%s: missing %s", locale, key)
+				continue
+			}
+			for form := range forms {
+				if _, ok := translated[form]; !ok {
+					t.Errorf("%s: %s missing plural form %s", locale, key, form)
+				}
+			}
+		}
+		for key := range Default.messages[locale] {
+			if _, ok := english[key]; !ok {
+				t.Errorf("%s: %s is not in the English catalog", locale, key)
+			}
+		}
+	}
+}
+
+func TestCatalogLoad(t *testing.T) {
+	catalog := NewCatalog("en")
+	if err := catalog.Load("en", []byte(`{"greeting": {"one": "hi"}}`)); err == nil {
+		t.Error("expected error for plural forms without other")
+	}
+	if err := catalog.Load("en", []byte(`{"greeting": 1}`)); err == nil {
+		t.Error("expected error for a non-string message")
+	}
+	if err := catalog.Load("en_GB", []byte(`{"greeting": "Hello {name}"}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := catalog.Localizer("en-gb").T("greeting", Args{"name": "Ann"}); got != "Hello Ann" {
+		t.Errorf("expected %q, got %q", "Hello Ann", got)
+	}
+}
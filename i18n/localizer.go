@@ -0,0 +1,261 @@
+// i18n/localizer.go
+// Rendering of messages for one locale, plural rules and Accept-Language
+
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Args are the values substituted into a template's {name} placeholders.
+// Message values are rendered in the same locale and []Message values are
+// rendered as a list.
+type Args map[string]interface{}
+
+// Message is a deferred, localizable message. It is either a catalog key
+// with arguments or literal text with optional translations, as used for
+// messages authored in models.
+type Message struct {
+	Key          string            `json:"key,omitempty"`
+	Args         Args              `json:"args,omitempty"`
+	Text         string            `json:"text,omitempty"`
+	Translations map[string]string `json:"translations,omitempty"` // Locale -> text
+}
+
+// NewMessage creates a catalog message
+func NewMessage(key string, args Args) Message {
+	return Message{Key: key, Args: args}
+}
+
+// Literal creates a message from authored text and its translations
+func Literal(text string, translations map[string]string) Message {
+	return Message{Text: text, Translations: translations}
+}
+
+// IsZero reports whether the message is empty
+func (m Message) IsZero() bool {
+	return m.Key == "" && m.Text == "" && len(m.Translations) == 0
+}
+
+// ===================================================================
+// LOCALIZER
+// ===================================================================
+
+// Localizer renders messages for a locale with fallbacks
+type Localizer struct {
+	catalog *Catalog
+	chain   []string // Locales to try, most preferred first
+}
+
+// Locale returns the locale messages are rendered in
+func (l *Localizer) Locale() string {
+	if len(l.chain) == 0 {
+		return l.catalog.fallback
+	}
+	return l.chain[0]
+}
+
+// T renders a catalog message; a key missing from every locale renders
+// as the key itself
+func (l *Localizer) T(key string, args Args) string {
+	forms, locale, ok := l.catalog.lookup(l.chain, key)
+	if !ok {
+		return key
+	}
+	text, ok := forms[pluralCategory(locale, args["count"])]
+	if !ok {
+		text = forms["other"]
+	}
+	return l.substitute(text, args)
+}
+
+// Render renders a deferred message
+func (l *Localizer) Render(m Message) string {
+	if m.Key != "" {
+		return l.T(m.Key, m.Args)
+	}
+	for _, locale := range l.chain {
+		for authored, text := range m.Translations {
+			if normalizeLocale(authored) == locale {
+				return text
+			}
+		}
+	}
+	return m.Text
+}
+
+// List joins items as a conjunction, e.g. "a, b and c"
+func (l *Localizer) List(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	}
+	head := strings.Join(items[:len(items)-1], l.T("list.separator", nil))
+	return l.T("list.and", Args{"items": head, "last": items[len(items)-1]})
+}
+
+// Join joins items with the locale's list separator
+func (l *Localizer) Join(items []string) string {
+	return strings.Join(items, l.T("list.separator", nil))
+}
+
+// Capitalize upper-cases the first letter, for sentences built from parts
+func Capitalize(text string) string {
+	first, size := utf8.DecodeRuneInString(text)
+	if size == 0 {
+		return text
+	}
+	return string(unicode.ToUpper(first)) + text[size:]
+}
+
+// substitute replaces {name} placeholders; unknown names are kept
+func (l *Localizer) substitute(text string, args Args) string {
+	if !strings.Contains(text, "{") {
+		return text
+	}
+	var buf strings.Builder
+	for {
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start:], '}')
+		if end < 0 {
+			break
+		}
+		name := text[start+1 : start+end]
+		buf.WriteString(text[:start])
+		if value, ok := args[name]; ok {
+			buf.WriteString(l.format(value))
+		} else {
+			buf.WriteString(text[start : start+end+1])
+		}
+		text = text[start+end+1:]
+	}
+	buf.WriteString(text)
+	return buf.String()
+}
+
+func (l *Localizer) format(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case Message:
+		return l.Render(v)
+	case []Message:
+		items := make([]string, len(v))
+		for i, m := range v {
+			items[i] = l.Render(m)
+		}
+		return l.List(items)
+	case []string:
+		return l.List(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ===================================================================
+// PLURAL RULES
+// ===================================================================
+
+// pluralCategory returns the CLDR plural category of a count for the
+// language of a locale. Only the categories used by the bundled languages
+// are distinguished.
+func pluralCategory(locale string, count interface{}) string {
+	var n float64
+	switch v := count.(type) {
+	case int:
+		n = float64(v)
+	case int64:
+		n = float64(v)
+	case float64:
+		n = v
+	default:
+		return "other"
+	}
+
+	language, _, _ := strings.Cut(locale, "-")
+	switch language {
+	case "fr":
+		// French uses the singular for 0 and 1
+		if n >= 0 && n < 2 {
+			return "one"
+		}
+	default:
+		if n == 1 {
+			return "one"
+		}
+	}
+	return "other"
+}
+
+// ===================================================================
+// ACCEPT-LANGUAGE
+// ===================================================================
+
+// ParseAcceptLanguage returns the languages of an Accept-Language header
+// ordered by quality, dropping those with q=0 and the wildcard
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale  string
+		quality float64
+	}
+	var languages []weighted
+	for _, part := range strings.Split(header, ",") {
+		locale, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		locale = strings.TrimSpace(locale)
+		if locale == "" || locale == "*" {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > 0 {
+			languages = append(languages, weighted{locale, quality})
+		}
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+	result := make([]string, len(languages))
+	for i, language := range languages {
+		result[i] = language.locale
+	}
+	return result
+}
+
+// ===================================================================
+// CONTEXT
+// ===================================================================
+
+type contextKey struct{}
+
+// NewContext returns a context carrying a localizer
+func NewContext(ctx context.Context, localizer *Localizer) context.Context {
+	return context.WithValue(ctx, contextKey{}, localizer)
+}
+
+// FromContext returns the context's localizer, or English
+func FromContext(ctx context.Context) *Localizer {
+	if localizer, ok := ctx.Value(contextKey{}).(*Localizer); ok && localizer != nil {
+		return localizer
+	}
+	return English()
+}
@@ -0,0 +1,455 @@
+// Package lsp implements a Language Server Protocol server for CPQ rule expressions
+// This file contains document storage, position mapping and rule extraction
+package lsp
+
+import (
+	"DD/cpq"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ===== DOCUMENTS =====
+
+// Document is an open text document
+type Document struct {
+	URI     string
+	Version int
+	Text    string
+	IsModel bool // Document is a model JSON file rather than a bare rule file
+
+	lineStarts []int
+	regions    []ExpressionRegion
+	strings    []jsonString
+	jsonErr    error
+}
+
+// ExpressionRegion is a rule expression embedded in a document
+type ExpressionRegion struct {
+	RuleID     string // ID of the rule, definition or price rule holding the expression
+	Kind       string // RuleRegion, DefinitionRegion or PriceRuleRegion
+	Expression string
+	offsets    []int // Document offset of each expression byte, plus the end offset
+}
+
+// Kinds of expression region. Definition bodies may also be used by price
+// formulas, so they may reference the formula variables.
+const (
+	RuleRegion       = "rule"
+	DefinitionRegion = "definition"
+	PriceRuleRegion  = "price rule" // Formula of an expression price rule
+)
+
+// NewDocument creates a document and extracts its rule expressions. Documents
+// with a .json extension are treated as model files.
+func NewDocument(uri string, version int, text string) *Document {
+	return newDocument(uri, version, text, strings.HasSuffix(strings.ToLower(uri), ".json"))
+}
+
+func newDocument(uri string, version int, text string, isModel bool) *Document {
+	doc := &Document{
+		URI:     uri,
+		Version: version,
+		Text:    text,
+		IsModel: isModel,
+	}
+	doc.lineStarts = computeLineStarts(text)
+
+	if doc.IsModel {
+		doc.strings, doc.jsonErr = scanJSONStrings(text)
+		doc.regions = extractExpressionRegions(doc.strings)
+	} else {
+		offsets := make([]int, len(text)+1)
+		for i := range offsets {
+			offsets[i] = i
+		}
+		doc.regions = []ExpressionRegion{{Kind: RuleRegion, Expression: text, offsets: offsets}}
+	}
+
+	return doc
+}
+
+// Regions returns the expressions in the document
+func (d *Document) Regions() []ExpressionRegion {
+	return d.regions
+}
+
+// RegionAt returns the expression region containing a document offset
+func (d *Document) RegionAt(offset int) (*ExpressionRegion, int, bool) {
+	for i := range d.regions {
+		region := &d.regions[i]
+		start, end := region.offsets[0], region.offsets[len(region.offsets)-1]
+		if offset < start || offset > end {
+			continue
+		}
+		// Map back to the expression byte index
+		for j := len(region.offsets) - 1; j >= 0; j-- {
+			if region.offsets[j] <= offset {
+				return region, j, true
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// DocumentOffset converts an expression byte index to a document offset
+func (r *ExpressionRegion) DocumentOffset(index int) int {
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(r.offsets) {
+		index = len(r.offsets) - 1
+	}
+	return r.offsets[index]
+}
+
+// ===== POSITION MAPPING =====
+
+func computeLineStarts(text string) []int {
+	starts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// PositionAt converts a byte offset to an LSP position
+func (d *Document) PositionAt(offset int) Position {
+	if offset > len(d.Text) {
+		offset = len(d.Text)
+	}
+	line := 0
+	for line+1 < len(d.lineStarts) && d.lineStarts[line+1] <= offset {
+		line++
+	}
+	prefix := d.Text[d.lineStarts[line]:offset]
+	return Position{Line: line, Character: len(utf16.Encode([]rune(prefix)))}
+}
+
+// OffsetAt converts an LSP position to a byte offset
+func (d *Document) OffsetAt(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lineStarts) {
+		return len(d.Text)
+	}
+
+	offset := d.lineStarts[pos.Line]
+	units := 0
+	for offset < len(d.Text) && d.Text[offset] != '\n' && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(d.Text[offset:])
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return offset
+}
+
+// RangeOf converts a pair of byte offsets to an LSP range
+func (d *Document) RangeOf(start, end int) Range {
+	return Range{Start: d.PositionAt(start), End: d.PositionAt(end)}
+}
+
+// ===== MODEL JSON SCANNING =====
+
+// jsonString is a string value in a JSON document along with its path
+type jsonString struct {
+	path    []string // Object keys and array indices leading to the value
+	value   string
+	offsets []int // Document offset of each decoded byte, plus the closing quote offset
+}
+
+// pathString joins the path for matching, e.g. "rules.3.expression"
+func (s jsonString) pathString() string {
+	return strings.Join(s.path, ".")
+}
+
+// regionCollections maps the model collections holding expressions to the
+// kind of region their expression strings are
+var regionCollections = map[string]string{
+	"rules":       RuleRegion,
+	"definitions": DefinitionRegion,
+	"price_rules": PriceRuleRegion,
+}
+
+// extractExpressionRegions collects rules[*].expression,
+// definitions[*].expression and the expression of price_rules[*] whose type
+// is a formula
+func extractExpressionRegions(values []jsonString) []ExpressionRegion {
+	ids := make(map[string]string)   // "<collection>.<index>" to the item ID
+	types := make(map[string]string) // "price_rules.<index>" to the price rule type
+	for _, s := range values {
+		if len(s.path) != 3 || regionCollections[s.path[0]] == "" {
+			continue
+		}
+		switch s.path[2] {
+		case "id":
+			ids[s.path[0]+"."+s.path[1]] = s.value
+		case "type":
+			types[s.path[0]+"."+s.path[1]] = s.value
+		}
+	}
+
+	var regions []ExpressionRegion
+	for _, s := range values {
+		if len(s.path) != 3 || s.path[2] != "expression" {
+			continue
+		}
+		kind, item := regionCollections[s.path[0]], s.path[0]+"."+s.path[1]
+		if kind == "" || kind == PriceRuleRegion && types[item] != string(cpq.ExpressionPriceRule) {
+			continue
+		}
+		regions = append(regions, ExpressionRegion{
+			RuleID:     ids[item],
+			Kind:       kind,
+			Expression: s.value,
+			offsets:    s.offsets,
+		})
+	}
+	return regions
+}
+
+// jsonScanner walks JSON text recording every string value and its location
+type jsonScanner struct {
+	text    string
+	pos     int
+	path    []string
+	results []jsonString
+}
+
+// scanJSONStrings returns all string values in a JSON document. Scanning stops
+// at the first syntax error; strings found before it are still returned.
+func scanJSONStrings(text string) ([]jsonString, error) {
+	s := &jsonScanner{text: text}
+	s.skipSpace()
+	if err := s.value(); err != nil {
+		return s.results, err
+	}
+	s.skipSpace()
+	if s.pos < len(s.text) {
+		return s.results, s.errorf("unexpected trailing content")
+	}
+	return s.results, nil
+}
+
+func (s *jsonScanner) errorf(format string, args ...interface{}) error {
+	return &jsonSyntaxError{Offset: s.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func (s *jsonScanner) skipSpace() {
+	for s.pos < len(s.text) {
+		switch s.text[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (s *jsonScanner) value() error {
+	if s.pos >= len(s.text) {
+		return s.errorf("unexpected end of input")
+	}
+
+	switch ch := s.text[s.pos]; {
+	case ch == '{':
+		return s.object()
+	case ch == '[':
+		return s.array()
+	case ch == '"':
+		value, offsets, err := s.stringLiteral()
+		if err != nil {
+			return err
+		}
+		path := make([]string, len(s.path))
+		copy(path, s.path)
+		s.results = append(s.results, jsonString{path: path, value: value, offsets: offsets})
+		return nil
+	default:
+		// Numbers, booleans and null are skipped as a run of literal characters
+		start := s.pos
+		for s.pos < len(s.text) && strings.IndexByte(",]} \t\r\n", s.text[s.pos]) < 0 {
+			s.pos++
+		}
+		if s.pos == start {
+			return s.errorf("unexpected character %q", ch)
+		}
+		return nil
+	}
+}
+
+func (s *jsonScanner) object() error {
+	s.pos++ // consume '{'
+	s.skipSpace()
+	if s.pos < len(s.text) && s.text[s.pos] == '}' {
+		s.pos++
+		return nil
+	}
+
+	for {
+		s.skipSpace()
+		if s.pos >= len(s.text) || s.text[s.pos] != '"' {
+			return s.errorf("expected object key")
+		}
+		key, _, err := s.stringLiteral()
+		if err != nil {
+			return err
+		}
+		s.skipSpace()
+		if s.pos >= len(s.text) || s.text[s.pos] != ':' {
+			return s.errorf("expected ':' after object key")
+		}
+		s.pos++
+		s.skipSpace()
+
+		s.path = append(s.path, key)
+		err = s.value()
+		s.path = s.path[:len(s.path)-1]
+		if err != nil {
+			return err
+		}
+
+		s.skipSpace()
+		if s.pos >= len(s.text) {
+			return s.errorf("unterminated object")
+		}
+		switch s.text[s.pos] {
+		case ',':
+			s.pos++
+		case '}':
+			s.pos++
+			return nil
+		default:
+			return s.errorf("expected ',' or '}'")
+		}
+	}
+}
+
+func (s *jsonScanner) array() error {
+	s.pos++ // consume '['
+	s.skipSpace()
+	if s.pos < len(s.text) && s.text[s.pos] == ']' {
+		s.pos++
+		return nil
+	}
+
+	for index := 0; ; index++ {
+		s.skipSpace()
+		s.path = append(s.path, strconv.Itoa(index))
+		err := s.value()
+		s.path = s.path[:len(s.path)-1]
+		if err != nil {
+			return err
+		}
+
+		s.skipSpace()
+		if s.pos >= len(s.text) {
+			return s.errorf("unterminated array")
+		}
+		switch s.text[s.pos] {
+		case ',':
+			s.pos++
+		case ']':
+			s.pos++
+			return nil
+		default:
+			return s.errorf("expected ',' or ']'")
+		}
+	}
+}
+
+// stringLiteral decodes a string and records the source offset of each decoded byte
+func (s *jsonScanner) stringLiteral() (string, []int, error) {
+	s.pos++ // consume opening quote
+	var value strings.Builder
+	var offsets []int
+
+	for s.pos < len(s.text) {
+		ch := s.text[s.pos]
+		switch {
+		case ch == '"':
+			offsets = append(offsets, s.pos)
+			s.pos++
+			return value.String(), offsets, nil
+
+		case ch == '\\':
+			start := s.pos
+			if s.pos+1 >= len(s.text) {
+				return "", nil, s.errorf("unterminated escape sequence")
+			}
+			var decoded string
+			switch esc := s.text[s.pos+1]; esc {
+			case '"', '\\', '/':
+				decoded = string(esc)
+				s.pos += 2
+			case 'b':
+				decoded, s.pos = "\b", s.pos+2
+			case 'f':
+				decoded, s.pos = "\f", s.pos+2
+			case 'n':
+				decoded, s.pos = "\n", s.pos+2
+			case 'r':
+				decoded, s.pos = "\r", s.pos+2
+			case 't':
+				decoded, s.pos = "\t", s.pos+2
+			case 'u':
+				r, width, err := s.unicodeEscape()
+				if err != nil {
+					return "", nil, err
+				}
+				decoded = string(r)
+				s.pos += width
+			default:
+				return "", nil, s.errorf("invalid escape sequence")
+			}
+			for i := 0; i < len(decoded); i++ {
+				offsets = append(offsets, start)
+			}
+			value.WriteString(decoded)
+
+		default:
+			offsets = append(offsets, s.pos)
+			value.WriteByte(ch)
+			s.pos++
+		}
+	}
+
+	return "", nil, s.errorf("unterminated string")
+}
+
+// unicodeEscape decodes \uXXXX (and a following low surrogate) at the current position
+func (s *jsonScanner) unicodeEscape() (rune, int, error) {
+	parse := func(at int) (rune, bool) {
+		if at+6 > len(s.text) || s.text[at] != '\\' || s.text[at+1] != 'u' {
+			return 0, false
+		}
+		n, err := strconv.ParseUint(s.text[at+2:at+6], 16, 32)
+		return rune(n), err == nil
+	}
+
+	high, ok := parse(s.pos)
+	if !ok {
+		return 0, 0, s.errorf("invalid unicode escape")
+	}
+	if utf16.IsSurrogate(high) {
+		if low, ok := parse(s.pos + 6); ok {
+			return utf16.DecodeRune(high, low), 12, nil
+		}
+	}
+	return high, 6, nil
+}
+
+// jsonSyntaxError reports where model JSON scanning failed
+type jsonSyntaxError struct {
+	Offset  int
+	Message string
+}
+
+func (e *jsonSyntaxError) Error() string {
+	return fmt.Sprintf("invalid JSON at offset %d: %s", e.Offset, e.Message)
+}
@@ -0,0 +1,636 @@
+// Package lsp implements a Language Server Protocol server for CPQ rule expressions
+// This file contains the request dispatcher and language feature handlers
+package lsp
+
+import (
+	"DD/parser"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	serverName    = "cpq-rule-lsp"
+	serverVersion = "0.1.0"
+	sourceName    = "cpq-rules"
+)
+
+// identifierPattern matches names the rule lexer reads as a single identifier
+var identifierPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// ===== SERVER =====
+
+// Server answers LSP requests for rule documents and model files
+type Server struct {
+	workspace *Workspace
+	documents map[string]*Document
+	conn      *Conn
+	shutdown  bool
+	logger    *log.Logger
+}
+
+// NewServer creates a language server for the given workspace
+func NewServer(workspace *Workspace) *Server {
+	return &Server{
+		workspace: workspace,
+		documents: make(map[string]*Document),
+		logger:    log.New(io.Discard, "", 0),
+	}
+}
+
+// SetLogger directs server logging, which must not go to the protocol stream
+func (s *Server) SetLogger(logger *log.Logger) {
+	s.logger = logger
+}
+
+// Serve processes messages until the client sends exit or the input ends
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.conn = NewConn(r, w)
+
+	for {
+		msg, err := s.conn.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			var rpcErr *ResponseError
+			if errors.As(err, &rpcErr) {
+				s.conn.Write(&Message{Error: rpcErr})
+				continue
+			}
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("exit received before shutdown")
+			}
+			return nil
+		}
+
+		result, rpcErr := s.dispatch(msg)
+		if msg.ID == nil {
+			// Notifications get no response
+			if rpcErr != nil {
+				s.logger.Printf("notification %s failed: %s", msg.Method, rpcErr.Message)
+			}
+			continue
+		}
+
+		response := &Message{ID: msg.ID, Error: rpcErr}
+		if rpcErr == nil {
+			raw, err := json.Marshal(result)
+			if err != nil {
+				response.Error = &ResponseError{Code: CodeInternalError, Message: err.Error()}
+			} else {
+				response.Result = raw
+			}
+		}
+		if err := s.conn.Write(response); err != nil {
+			return err
+		}
+	}
+}
+
+// dispatch routes a message to its handler
+func (s *Server) dispatch(msg *Message) (interface{}, *ResponseError) {
+	s.logger.Printf("<- %s", msg.Method)
+
+	switch msg.Method {
+	case "initialize":
+		return s.initialize()
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.didOpen(params)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.didChange(params)
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		delete(s.documents, params.TextDocument.URI)
+		return nil, s.publish(&Document{URI: params.TextDocument.URI}, []Diagnostic{})
+
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.Completion(params)
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.Hover(params)
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.Definition(params)
+	case "textDocument/rename":
+		var params RenameParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.Rename(params)
+	}
+
+	if strings.HasPrefix(msg.Method, "$/") {
+		// Optional protocol notifications may be ignored
+		return nil, nil
+	}
+	return nil, &ResponseError{Code: CodeMethodNotFound, Message: fmt.Sprintf("method not supported: %s", msg.Method)}
+}
+
+func decodeParams(msg *Message, target interface{}) *ResponseError {
+	if err := json.Unmarshal(msg.Params, target); err != nil {
+		return &ResponseError{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) initialize() (interface{}, *ResponseError) {
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:   TextDocumentSyncKindFull,
+			CompletionProvider: &CompletionOptions{TriggerCharacters: []string{"_", "("}},
+			HoverProvider:      true,
+			DefinitionProvider: true,
+			RenameProvider:     true,
+		},
+		ServerInfo: ServerInfo{Name: serverName, Version: serverVersion},
+	}, nil
+}
+
+// ===== DOCUMENT SYNCHRONIZATION =====
+
+func (s *Server) didOpen(params DidOpenTextDocumentParams) *ResponseError {
+	item := params.TextDocument
+	return s.setDocument(item.URI, item.Version, item.Text)
+}
+
+func (s *Server) didChange(params DidChangeTextDocumentParams) *ResponseError {
+	if len(params.ContentChanges) == 0 {
+		return nil
+	}
+	// Full synchronization: the last change holds the complete text
+	text := params.ContentChanges[len(params.ContentChanges)-1].Text
+	return s.setDocument(params.TextDocument.URI, params.TextDocument.Version, text)
+}
+
+func (s *Server) setDocument(uri string, version int, text string) *ResponseError {
+	var doc *Document
+	if uri == s.workspace.ModelURI {
+		doc = newDocument(uri, version, text, true)
+	} else {
+		doc = NewDocument(uri, version, text)
+	}
+	s.documents[uri] = doc
+
+	if uri == s.workspace.ModelURI {
+		if err := s.workspace.updateModelText(doc); err != nil {
+			s.logger.Printf("model reload failed: %v", err)
+		}
+		// Identifier validity may have changed for every open document
+		return s.publishAll()
+	}
+	return s.publish(doc, s.Diagnostics(doc))
+}
+
+func (s *Server) publishAll() *ResponseError {
+	uris := make([]string, 0, len(s.documents))
+	for uri := range s.documents {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	for _, uri := range uris {
+		doc := s.documents[uri]
+		if err := s.publish(doc, s.Diagnostics(doc)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) publish(doc *Document, diagnostics []Diagnostic) *ResponseError {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         doc.URI,
+		Version:     doc.Version,
+		Diagnostics: diagnostics,
+	})
+	if err != nil {
+		return &ResponseError{Code: CodeInternalError, Message: err.Error()}
+	}
+	return nil
+}
+
+// document returns an open document, falling back to the model file on disk
+func (s *Server) document(uri string) (*Document, *ResponseError) {
+	if doc, ok := s.documents[uri]; ok {
+		return doc, nil
+	}
+	if uri == s.workspace.ModelURI {
+		return s.workspace.modelDoc, nil
+	}
+	return nil, &ResponseError{Code: CodeRequestFailed, Message: fmt.Sprintf("document not open: %s", uri)}
+}
+
+// ===== DIAGNOSTICS =====
+
+// Diagnostics analyzes every rule expression in a document
+func (s *Server) Diagnostics(doc *Document) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	if doc.jsonErr != nil {
+		offset := 0
+		var syntaxErr *jsonSyntaxError
+		if errors.As(doc.jsonErr, &syntaxErr) {
+			offset = syntaxErr.Offset
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    doc.RangeOf(offset, offset),
+			Severity: SeverityError,
+			Code:     "invalid-json",
+			Source:   sourceName,
+			Message:  doc.jsonErr.Error(),
+		})
+	}
+
+	for i := range doc.regions {
+		diagnostics = append(diagnostics, s.regionDiagnostics(doc, &doc.regions[i])...)
+	}
+	return diagnostics
+}
+
+func (s *Server) regionDiagnostics(doc *Document, region *ExpressionRegion) []Diagnostic {
+	var diagnostics []Diagnostic
+	rangeOf := func(start, end int) Range {
+		return doc.RangeOf(region.DocumentOffset(start), region.DocumentOffset(end))
+	}
+	prefix := ""
+	if region.RuleID != "" {
+		prefix = fmt.Sprintf("%s %s: ", region.Kind, region.RuleID)
+	}
+
+	if strings.TrimSpace(region.Expression) == "" {
+		return []Diagnostic{{
+			Range:    rangeOf(0, len(region.Expression)),
+			Severity: SeverityError,
+			Code:     "empty-expression",
+			Source:   sourceName,
+			Message:  prefix + region.Kind + " expression is empty",
+		}}
+	}
+
+	parse := parser.ParseExpression
+	if region.Kind == PriceRuleRegion {
+		parse = parser.ParseFormula
+	}
+	if _, err := parse(region.Expression); err != nil {
+		diag := Diagnostic{
+			Range:    rangeOf(0, len(region.Expression)),
+			Severity: SeverityError,
+			Code:     "syntax",
+			Source:   sourceName,
+			Message:  prefix + err.Error(),
+		}
+		var parseErr *parser.ParseError
+		if errors.As(err, &parseErr) {
+			diag.Range = rangeOf(parseErr.Range.Start.Offset, parseErr.Range.End.Offset)
+			diag.Code = parseErr.ErrorType
+			diag.Message = prefix + parseErr.Message
+			if parseErr.Suggestion != "" {
+				diag.Message += " (" + parseErr.Suggestion + ")"
+			}
+		}
+		diagnostics = append(diagnostics, diag)
+	}
+
+	ws := s.workspace
+	for _, sym := range region.identifiers() {
+		if option, ok := ws.options[sym.name]; ok {
+			if !option.IsActive {
+				diagnostics = append(diagnostics, Diagnostic{
+					Range:    rangeOf(sym.start, sym.end),
+					Severity: SeverityInformation,
+					Code:     "inactive-option",
+					Source:   sourceName,
+					Message:  fmt.Sprintf("%soption '%s' is inactive and always evaluates as unselected", prefix, sym.name),
+				})
+			}
+			continue
+		}
+		if _, ok := ws.groupCounts[sym.name]; ok {
+			continue
+		}
+		if _, ok := ws.definitions[sym.name]; ok {
+			continue
+		}
+		if _, ok := ws.formulaOption(sym.name); ok && region.Kind != RuleRegion {
+			continue
+		}
+
+		message := fmt.Sprintf("%sunknown option '%s'", prefix, sym.name)
+		if _, ok := ws.groups[sym.name]; ok {
+			message = fmt.Sprintf("%s'%s' is a group; reference one of its options instead", prefix, sym.name)
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    rangeOf(sym.start, sym.end),
+			Severity: SeverityWarning,
+			Code:     "unknown-identifier",
+			Source:   sourceName,
+			Message:  message,
+		})
+	}
+
+	return diagnostics
+}
+
+// ===== COMPLETION =====
+
+// Completion proposes option IDs, group variables, definitions, functions and keywords
+func (s *Server) Completion(params TextDocumentPositionParams) (*CompletionList, *ResponseError) {
+	doc, rpcErr := s.document(params.TextDocument.URI)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	list := &CompletionList{Items: []CompletionItem{}}
+	offset := doc.OffsetAt(params.Position)
+	region, index, ok := doc.RegionAt(offset)
+	if !ok {
+		return list, nil
+	}
+
+	// The word being typed ends at the cursor
+	start := index
+	for start > 0 && isIdentifierByte(region.Expression[start-1]) {
+		start--
+	}
+	prefix := region.Expression[start:index]
+
+	ws := s.workspace
+	for _, id := range ws.sortedOptionIDs() {
+		if strings.HasPrefix(id, prefix) {
+			option := ws.options[id]
+			list.Items = append(list.Items, CompletionItem{
+				Label:  id,
+				Kind:   CompletionKindVariable,
+				Detail: fmt.Sprintf("%s (%.2f)", option.Name, option.BasePrice),
+			})
+		}
+	}
+	for _, name := range ws.sortedGroupCounts() {
+		if strings.HasPrefix(name, prefix) {
+			list.Items = append(list.Items, CompletionItem{
+				Label:  name,
+				Kind:   CompletionKindModule,
+				Detail: fmt.Sprintf("Selection count of group %s", ws.groupCounts[name].Name),
+			})
+		}
+	}
+	for _, id := range ws.sortedDefinitionIDs() {
+		if strings.HasPrefix(id, prefix) {
+			list.Items = append(list.Items, CompletionItem{
+				Label:  id,
+				Kind:   CompletionKindConstant,
+				Detail: ws.definitions[id].String(),
+			})
+		}
+	}
+	for _, fn := range functionDocs {
+		if strings.HasPrefix(fn.Name, strings.ToUpper(prefix)) {
+			list.Items = append(list.Items, CompletionItem{
+				Label:      fn.Name,
+				Kind:       CompletionKindFunction,
+				Detail:     fn.Signature,
+				InsertText: fn.Name + "(",
+			})
+		}
+	}
+	for _, kw := range keywordDocs {
+		if prefix != "" && strings.HasPrefix(strings.ToUpper(kw.Name), strings.ToUpper(prefix)) {
+			list.Items = append(list.Items, CompletionItem{
+				Label:  kw.Name,
+				Kind:   CompletionKindKeyword,
+				Detail: kw.Summary,
+			})
+		}
+	}
+
+	return list, nil
+}
+
+func isIdentifierByte(ch byte) bool {
+	return ch == '_' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
+
+// ===== HOVER =====
+
+// Hover describes the option, group variable, definition or function under the cursor
+func (s *Server) Hover(params TextDocumentPositionParams) (*Hover, *ResponseError) {
+	doc, rpcErr := s.document(params.TextDocument.URI)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	region, index, ok := doc.RegionAt(doc.OffsetAt(params.Position))
+	if !ok {
+		return nil, nil
+	}
+	token, ok := region.tokenAt(index)
+	if !ok {
+		return nil, nil
+	}
+
+	var contents string
+	ws := s.workspace
+	if token.Type == parser.TOKEN_IDENTIFIER {
+		if option, ok := ws.options[token.Value]; ok {
+			contents = fmt.Sprintf("**%s** `%s`\n\nPrice: %.2f", option.Name, option.ID, option.BasePrice)
+			if group, ok := ws.groups[option.GroupID]; ok {
+				contents += fmt.Sprintf("\n\nGroup: %s", group.Name)
+			}
+			if !option.IsActive {
+				contents += "\n\n_Inactive_"
+			}
+			if option.Description != "" {
+				contents += "\n\n" + option.Description
+			}
+		} else if group, ok := ws.groupCounts[token.Value]; ok {
+			contents = fmt.Sprintf("**%s** `%s`\n\nSelection count of group %s (%d-%d selections)",
+				token.Value, group.ID, group.Name, group.MinSelections, group.MaxSelections)
+		} else if definition, ok := ws.definitions[token.Value]; ok {
+			contents = fmt.Sprintf("**%s** `%s`\n\n```\n%s\n```", definition.Name, definition.ID, definition.String())
+			if definition.Description != "" {
+				contents += "\n\n" + definition.Description
+			}
+		}
+	} else if builtin, ok := lookupBuiltin(token.Value); ok {
+		contents = fmt.Sprintf("`%s`\n\n%s", builtin.Signature, builtin.Summary)
+	}
+
+	if contents == "" {
+		return nil, nil
+	}
+
+	hoverRange := doc.RangeOf(region.DocumentOffset(token.Range.Start.Offset), region.DocumentOffset(token.Range.End.Offset))
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: contents},
+		Range:    &hoverRange,
+	}, nil
+}
+
+// ===== DEFINITION =====
+
+// Definition locates the option, group or named definition under the cursor in the model file
+func (s *Server) Definition(params TextDocumentPositionParams) ([]Location, *ResponseError) {
+	doc, rpcErr := s.document(params.TextDocument.URI)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	region, index, ok := doc.RegionAt(doc.OffsetAt(params.Position))
+	if !ok {
+		return []Location{}, nil
+	}
+	token, ok := region.tokenAt(index)
+	if !ok || token.Type != parser.TOKEN_IDENTIFIER {
+		return []Location{}, nil
+	}
+
+	def, ok := s.workspace.definitionOf(token.Value)
+	if !ok {
+		return []Location{}, nil
+	}
+
+	modelDoc := s.workspace.modelDoc
+	return []Location{{
+		URI:   modelDoc.URI,
+		Range: modelDoc.RangeOf(def.offsets[0], def.offsets[len(def.offsets)-1]),
+	}}, nil
+}
+
+// ===== RENAME =====
+
+// Rename changes an option ID in its definition, group references and every
+// rule, definition and price formula
+func (s *Server) Rename(params RenameParams) (*WorkspaceEdit, *ResponseError) {
+	doc, rpcErr := s.document(params.TextDocument.URI)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	oldName, ok := s.optionAt(doc, doc.OffsetAt(params.Position))
+	if !ok {
+		return nil, &ResponseError{Code: CodeRequestFailed, Message: "no option ID at this position"}
+	}
+
+	newName := params.NewName
+	if !identifierPattern.MatchString(newName) {
+		return nil, &ResponseError{Code: CodeInvalidParams, Message: fmt.Sprintf("'%s' is not a valid option ID", newName)}
+	}
+	if _, isKeyword := lookupBuiltin(strings.ToUpper(newName)); isKeyword {
+		return nil, &ResponseError{Code: CodeInvalidParams, Message: fmt.Sprintf("'%s' is a reserved word", newName)}
+	}
+	if _, exists := s.workspace.options[newName]; exists && newName != oldName {
+		return nil, &ResponseError{Code: CodeInvalidParams, Message: fmt.Sprintf("option '%s' already exists", newName)}
+	}
+	if _, exists := s.workspace.definitions[newName]; exists {
+		return nil, &ResponseError{Code: CodeInvalidParams, Message: fmt.Sprintf("definition '%s' already exists", newName)}
+	}
+
+	edit := &WorkspaceEdit{Changes: make(map[string][]TextEdit)}
+
+	// Model file: option definition, group references and rule expressions
+	modelDoc := s.workspace.modelDoc
+	for _, ref := range s.workspace.optionIDReferences(oldName) {
+		edit.Changes[modelDoc.URI] = append(edit.Changes[modelDoc.URI], TextEdit{
+			Range:   modelDoc.RangeOf(ref.offsets[0], ref.offsets[len(ref.offsets)-1]),
+			NewText: newName,
+		})
+	}
+	edit.Changes[modelDoc.URI] = append(edit.Changes[modelDoc.URI], renameInRegions(modelDoc, oldName, newName)...)
+
+	// Open rule documents
+	for uri, open := range s.documents {
+		if uri == modelDoc.URI || open.IsModel {
+			continue
+		}
+		if edits := renameInRegions(open, oldName, newName); len(edits) > 0 {
+			edit.Changes[uri] = edits
+		}
+	}
+
+	return edit, nil
+}
+
+// optionAt returns the option ID under a document offset, either as a rule
+// identifier or as an option definition in the model file
+func (s *Server) optionAt(doc *Document, offset int) (string, bool) {
+	if region, index, ok := doc.RegionAt(offset); ok {
+		if token, ok := region.tokenAt(index); ok && token.Type == parser.TOKEN_IDENTIFIER {
+			_, known := s.workspace.options[token.Value]
+			return token.Value, known
+		}
+		return "", false
+	}
+
+	if doc.IsModel {
+		for _, str := range doc.strings {
+			if len(str.path) == 3 && str.path[0] == "options" && str.path[2] == "id" &&
+				offset >= str.offsets[0] && offset <= str.offsets[len(str.offsets)-1] {
+				return str.value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// renameInRegions renames an option in every expression of a document,
+// including its <option>_qty and <option>_price formula variables
+func renameInRegions(doc *Document, oldName, newName string) []TextEdit {
+	var edits []TextEdit
+	for i := range doc.regions {
+		region := &doc.regions[i]
+		for _, sym := range region.identifiers() {
+			end := sym.end
+			if sym.name != oldName {
+				if region.Kind == RuleRegion || !isFormulaVariableOf(sym.name, oldName) {
+					continue
+				}
+				end = sym.start + len(oldName)
+			}
+			edits = append(edits, TextEdit{
+				Range:   doc.RangeOf(region.DocumentOffset(sym.start), region.DocumentOffset(end)),
+				NewText: newName,
+			})
+		}
+	}
+	return edits
+}
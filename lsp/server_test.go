@@ -0,0 +1,529 @@
+package lsp
+
+import (
+	"DD/cpq"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+const testModelURI = "file:///models/server.json"
+
+// createTestModel builds a small server model with a few rules
+func createTestModel() *cpq.Model {
+	model := cpq.NewModel("server", "Server")
+	model.AddGroup(cpq.Group{ID: "cpu", Name: "Processor", Type: cpq.SingleSelect, IsRequired: true, OptionIDs: []string{"cpu_i9", "cpu_xeon"}})
+	model.AddGroup(cpq.Group{ID: "extras", Name: "Extras", Type: cpq.MultiSelect, MaxSelections: 3})
+	model.AddOption(cpq.Option{ID: "cpu_i9", Name: "Intel Core i9", GroupID: "cpu", BasePrice: 549.99, IsActive: true})
+	model.AddOption(cpq.Option{ID: "cpu_xeon", Name: "Intel Xeon", GroupID: "cpu", BasePrice: 899, IsActive: true})
+	model.AddOption(cpq.Option{ID: "cooling_liquid", Name: "Liquid Cooling", GroupID: "extras", BasePrice: 120, IsActive: true})
+	model.AddOption(cpq.Option{ID: "legacy_fan", Name: "Legacy Fan", GroupID: "extras", BasePrice: 10, IsActive: false})
+	model.AddRule(cpq.Rule{ID: "r1", Name: "i9 needs cooling", Expression: "cpu_i9 -> cooling_liquid", IsActive: true})
+	model.AddRule(cpq.Rule{ID: "r2", Name: "Xeon excludes fan", Expression: "cpu_xeon -> NOT legacy_fan AND cpu_i9 == false", IsActive: true})
+	return model
+}
+
+func createTestWorkspace(t *testing.T) (*Workspace, string) {
+	t.Helper()
+	data, err := json.MarshalIndent(createTestModel(), "", "  ")
+	if err != nil {
+		t.Fatalf("failed to encode model: %v", err)
+	}
+	text := string(data)
+	model, err := decodeModel(text)
+	if err != nil {
+		t.Fatalf("failed to decode model: %v", err)
+	}
+	return NewWorkspace(testModelURI, model, text), text
+}
+
+// positionOf returns the position of the n-th occurrence of needle plus a character offset
+func positionOf(t *testing.T, text, needle string, occurrence, delta int) Position {
+	t.Helper()
+	offset := -1
+	for i := 0; i <= occurrence; i++ {
+		next := strings.Index(text[offset+1:], needle)
+		if next < 0 {
+			t.Fatalf("%q occurrence %d not found", needle, occurrence)
+		}
+		offset += next + 1
+	}
+	doc := NewDocument("file:///tmp.rule", 0, text)
+	return doc.PositionAt(offset + delta)
+}
+
+// session runs a scripted client conversation and collects server messages
+type session struct {
+	input  bytes.Buffer
+	nextID int
+}
+
+func (s *session) request(method string, params interface{}) int {
+	s.nextID++
+	s.send(s.nextID, method, params)
+	return s.nextID
+}
+
+func (s *session) notify(method string, params interface{}) {
+	s.send(0, method, params)
+}
+
+func (s *session) send(id int, method string, params interface{}) {
+	body := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+	if id > 0 {
+		body["id"] = id
+	}
+	data, _ := json.Marshal(body)
+	fmt.Fprintf(&s.input, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+func (s *session) run(t *testing.T, server *Server) (map[int]*Message, []PublishDiagnosticsParams) {
+	t.Helper()
+	s.request("shutdown", nil)
+	s.notify("exit", nil)
+
+	var output bytes.Buffer
+	if err := server.Serve(&s.input, &output); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	responses := make(map[int]*Message)
+	var diagnostics []PublishDiagnosticsParams
+	conn := NewConn(&output, io.Discard)
+	for {
+		msg, err := conn.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read server output: %v", err)
+		}
+		if msg.Method == "textDocument/publishDiagnostics" {
+			var params PublishDiagnosticsParams
+			json.Unmarshal(msg.Params, &params)
+			diagnostics = append(diagnostics, params)
+			continue
+		}
+		var id int
+		json.Unmarshal(*msg.ID, &id)
+		responses[id] = msg
+	}
+	return responses, diagnostics
+}
+
+func decodeResult(t *testing.T, msg *Message, target interface{}) {
+	t.Helper()
+	if msg == nil {
+		t.Fatal("missing response")
+	}
+	if msg.Error != nil {
+		t.Fatalf("unexpected error response: %s", msg.Error.Message)
+	}
+	if err := json.Unmarshal(msg.Result, target); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+}
+
+func TestServerInitializeAndDiagnostics(t *testing.T) {
+	ws, _ := createTestWorkspace(t)
+	server := NewServer(ws)
+
+	ruleText := "cpu_i9 AND cooling_liquid AND\nunknown_opt AND legacy_fan"
+	brokenText := "cpu_i9 AND (cpu_xeon"
+
+	s := &session{}
+	initID := s.request("initialize", InitializeParams{ProcessID: 1})
+	s.notify("initialized", struct{}{})
+	s.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: "file:///rules/a.rule", Version: 1, Text: ruleText}})
+	s.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: "file:///rules/b.rule", Version: 1, Text: brokenText}})
+	unknownID := s.request("textDocument/unknownFeature", struct{}{})
+	responses, diagnostics := s.run(t, server)
+
+	var init InitializeResult
+	decodeResult(t, responses[initID], &init)
+	if !init.Capabilities.HoverProvider || !init.Capabilities.RenameProvider || init.Capabilities.CompletionProvider == nil {
+		t.Errorf("capabilities not advertised: %+v", init.Capabilities)
+	}
+
+	if responses[unknownID].Error == nil || responses[unknownID].Error.Code != CodeMethodNotFound {
+		t.Errorf("expected method-not-found for unknown request, got %+v", responses[unknownID])
+	}
+
+	if len(diagnostics) != 2 {
+		t.Fatalf("expected 2 diagnostic notifications, got %d", len(diagnostics))
+	}
+
+	ruleDiags := diagnostics[0].Diagnostics
+	if len(ruleDiags) != 2 {
+		t.Fatalf("expected 2 diagnostics for rule file, got %+v", ruleDiags)
+	}
+	if ruleDiags[0].Code != "unknown-identifier" || ruleDiags[0].Severity != SeverityWarning {
+		t.Errorf("unexpected diagnostic: %+v", ruleDiags[0])
+	}
+	expected := Range{Start: Position{Line: 1, Character: 0}, End: Position{Line: 1, Character: 11}}
+	if ruleDiags[0].Range != expected {
+		t.Errorf("unknown identifier range = %+v, expected %+v", ruleDiags[0].Range, expected)
+	}
+	if ruleDiags[1].Code != "inactive-option" {
+		t.Errorf("expected inactive-option diagnostic, got %+v", ruleDiags[1])
+	}
+
+	brokenDiags := diagnostics[1].Diagnostics
+	if len(brokenDiags) != 1 || brokenDiags[0].Severity != SeverityError || brokenDiags[0].Code != "syntax" {
+		t.Fatalf("expected one syntax error, got %+v", brokenDiags)
+	}
+	if brokenDiags[0].Range.Start.Character != len(brokenText) {
+		t.Errorf("syntax error should point at end of input, got %+v", brokenDiags[0].Range)
+	}
+}
+
+func TestServerModelFileDiagnostics(t *testing.T) {
+	ws, text := createTestWorkspace(t)
+	server := NewServer(ws)
+
+	// Introduce a typo in the first rule expression
+	edited := strings.Replace(text, "cpu_i9 -\\u003e cooling_liquid", "cpu_i9 -\\u003e cooling_liqud", 1)
+	if edited == text {
+		edited = strings.Replace(text, "cpu_i9 -> cooling_liquid", "cpu_i9 -> cooling_liqud", 1)
+	}
+
+	s := &session{}
+	s.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: testModelURI, Version: 1, Text: edited}})
+	_, diagnostics := s.run(t, server)
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic notification, got %d", len(diagnostics))
+	}
+	diags := diagnostics[0].Diagnostics
+	if len(diags) != 2 {
+		t.Fatalf("expected typo and inactive option diagnostics, got %+v", diags)
+	}
+	if !strings.Contains(diags[0].Message, "rule r1: unknown option 'cooling_liqud'") {
+		t.Errorf("unexpected message: %s", diags[0].Message)
+	}
+
+	// The range must cover the identifier inside the JSON string
+	doc := NewDocument(testModelURI, 1, edited)
+	start, end := doc.OffsetAt(diags[0].Range.Start), doc.OffsetAt(diags[0].Range.End)
+	if got := edited[start:end]; got != "cooling_liqud" {
+		t.Errorf("diagnostic covers %q", got)
+	}
+}
+
+func TestServerCompletionAndHover(t *testing.T) {
+	ws, _ := createTestWorkspace(t)
+	server := NewServer(ws)
+
+	ruleText := "cpu_ AND TH"
+	s := &session{}
+	s.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: "file:///r.rule", Version: 1, Text: ruleText}})
+	optionsID := s.request("textDocument/completion", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: "file:///r.rule"},
+		Position:     Position{Line: 0, Character: 4},
+	})
+	functionsID := s.request("textDocument/completion", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: "file:///r.rule"},
+		Position:     Position{Line: 0, Character: len(ruleText)},
+	})
+
+	_, modelText := createTestWorkspace(t)
+	hoverID := s.request("textDocument/hover", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: testModelURI},
+		Position:     positionOf(t, modelText, "cpu_i9 -", 0, 2),
+	})
+	responses, _ := s.run(t, server)
+
+	var options CompletionList
+	decodeResult(t, responses[optionsID], &options)
+	var labels []string
+	for _, item := range options.Items {
+		labels = append(labels, item.Label)
+	}
+	if strings.Join(labels, ",") != "cpu_i9,cpu_xeon" {
+		t.Errorf("option completion = %v", labels)
+	}
+
+	var functions CompletionList
+	decodeResult(t, responses[functionsID], &functions)
+	if len(functions.Items) != 1 || functions.Items[0].Label != "THRESHOLD" || functions.Items[0].Kind != CompletionKindFunction {
+		t.Errorf("function completion = %+v", functions.Items)
+	}
+
+	var hover Hover
+	decodeResult(t, responses[hoverID], &hover)
+	for _, want := range []string{"Intel Core i9", "549.99", "Processor"} {
+		if !strings.Contains(hover.Contents.Value, want) {
+			t.Errorf("hover %q missing %q", hover.Contents.Value, want)
+		}
+	}
+}
+
+func TestServerDefinitionAndRename(t *testing.T) {
+	ws, modelText := createTestWorkspace(t)
+	server := NewServer(ws)
+
+	ruleURI := "file:///extra.rule"
+	ruleText := "cpu_xeon OR (cpu_i9 AND cooling_liquid)"
+
+	s := &session{}
+	s.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: ruleURI, Version: 1, Text: ruleText}})
+	defID := s.request("textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: ruleURI},
+		Position:     Position{Line: 0, Character: 15},
+	})
+	renameID := s.request("textDocument/rename", RenameParams{
+		TextDocument: TextDocumentIdentifier{URI: ruleURI},
+		Position:     Position{Line: 0, Character: 15},
+		NewName:      "cpu_core_i9",
+	})
+	badRenameID := s.request("textDocument/rename", RenameParams{
+		TextDocument: TextDocumentIdentifier{URI: ruleURI},
+		Position:     Position{Line: 0, Character: 15},
+		NewName:      "cpu_xeon",
+	})
+	responses, _ := s.run(t, server)
+
+	var locations []Location
+	decodeResult(t, responses[defID], &locations)
+	if len(locations) != 1 || locations[0].URI != testModelURI {
+		t.Fatalf("definition = %+v", locations)
+	}
+	modelDoc := NewDocument(testModelURI, 0, modelText)
+	defStart := modelDoc.OffsetAt(locations[0].Range.Start)
+	if !strings.HasSuffix(modelText[:defStart], `"id": "`) || modelText[defStart:modelDoc.OffsetAt(locations[0].Range.End)] != "cpu_i9" {
+		t.Errorf("definition does not point at the option id: %+v", locations[0].Range)
+	}
+
+	var edit WorkspaceEdit
+	decodeResult(t, responses[renameID], &edit)
+
+	// Option id, group option_ids entry, two rule references
+	if got := len(edit.Changes[testModelURI]); got != 4 {
+		t.Errorf("expected 4 model edits, got %d: %+v", got, edit.Changes[testModelURI])
+	}
+	if got := len(edit.Changes[ruleURI]); got != 1 {
+		t.Errorf("expected 1 rule file edit, got %d", got)
+	}
+
+	renamed := applyEdits(modelDoc, edit.Changes[testModelURI])
+	if strings.Contains(renamed, `"cpu_i9"`) || strings.Contains(renamed, "cpu_i9 ") {
+		t.Errorf("model still references cpu_i9:\n%s", renamed)
+	}
+	model, err := decodeModel(renamed)
+	if err != nil {
+		t.Fatalf("renamed model does not decode: %v", err)
+	}
+	if _, err := model.GetOption("cpu_core_i9"); err != nil {
+		t.Errorf("renamed option missing: %v", err)
+	}
+	if model.Rules[1].Expression != "cpu_xeon -> NOT legacy_fan AND cpu_core_i9 == false" {
+		t.Errorf("rule not renamed: %s", model.Rules[1].Expression)
+	}
+
+	if responses[badRenameID].Error == nil {
+		t.Error("rename onto an existing option should fail")
+	}
+}
+
+func TestServerAnnotatedRules(t *testing.T) {
+	model := createTestModel()
+	model.Rules[0].Expression = `@meta(severity="warning") cpu_i9 -> cooling_liquid`
+	data, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to encode model: %v", err)
+	}
+	modelText := string(data)
+	server := NewServer(NewWorkspace(testModelURI, model, modelText))
+
+	ruleURI := "file:///annotated.rule"
+	ruleText := `@meta(owner="hardware") cpu_i9 -> cooling_liqud`
+	renameAt := Position{Line: 0, Character: strings.Index(ruleText, "cpu_i9") + 2}
+
+	s := &session{}
+	s.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: ruleURI, Version: 1, Text: ruleText}})
+	renameID := s.request("textDocument/rename", RenameParams{
+		TextDocument: TextDocumentIdentifier{URI: ruleURI},
+		Position:     renameAt,
+		NewName:      "cpu_core_i9",
+	})
+	responses, diagnostics := s.run(t, server)
+
+	if len(diagnostics) != 1 || len(diagnostics[0].Diagnostics) != 1 {
+		t.Fatalf("expected one diagnostic for the annotated rule, got %+v", diagnostics)
+	}
+	diag := diagnostics[0].Diagnostics[0]
+	start := strings.Index(ruleText, "cooling_liqud")
+	expected := Range{Start: Position{Line: 0, Character: start}, End: Position{Line: 0, Character: start + len("cooling_liqud")}}
+	if diag.Code != "unknown-identifier" || diag.Range != expected {
+		t.Errorf("unexpected diagnostic %+v, expected unknown-identifier at %+v", diag, expected)
+	}
+
+	var edit WorkspaceEdit
+	decodeResult(t, responses[renameID], &edit)
+	ruleDoc := NewDocument(ruleURI, 1, ruleText)
+	if renamed := applyEdits(ruleDoc, edit.Changes[ruleURI]); renamed != `@meta(owner="hardware") cpu_core_i9 -> cooling_liqud` {
+		t.Errorf("rule file renamed to %q", renamed)
+	}
+	renamedModel, err := decodeModel(applyEdits(NewDocument(testModelURI, 0, modelText), edit.Changes[testModelURI]))
+	if err != nil {
+		t.Fatalf("renamed model does not decode: %v", err)
+	}
+	if renamedModel.Rules[0].Expression != `@meta(severity="warning") cpu_core_i9 -> cooling_liquid` {
+		t.Errorf("annotated rule not renamed: %s", renamedModel.Rules[0].Expression)
+	}
+}
+
+func TestServerNamedDefinitions(t *testing.T) {
+	model := createTestModel()
+	model.AddDefinition(cpq.Definition{ID: "fastcpu", Name: "Fast CPU", Expression: "cpu_i9 OR cpu_xeon"})
+	data, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to encode model: %v", err)
+	}
+	modelText := string(data)
+	server := NewServer(NewWorkspace(testModelURI, model, modelText))
+
+	ruleText := "fastcpu -> cooling_liquid"
+	s := &session{}
+	s.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: "file:///r.rule", Version: 1, Text: ruleText}})
+	hoverID := s.request("textDocument/hover", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: "file:///r.rule"},
+		Position:     Position{Line: 0, Character: 2},
+	})
+	definitionID := s.request("textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: "file:///r.rule"},
+		Position:     Position{Line: 0, Character: 2},
+	})
+	responses, diagnostics := s.run(t, server)
+
+	if len(diagnostics) != 1 || len(diagnostics[0].Diagnostics) != 0 {
+		t.Errorf("definition reference should not produce diagnostics, got %+v", diagnostics)
+	}
+
+	var hover Hover
+	decodeResult(t, responses[hoverID], &hover)
+	if !strings.Contains(hover.Contents.Value, "let fastcpu = cpu_i9 OR cpu_xeon") {
+		t.Errorf("hover %q missing definition source", hover.Contents.Value)
+	}
+
+	var locations []Location
+	decodeResult(t, responses[definitionID], &locations)
+	expected := positionOf(t, modelText, `"fastcpu"`, 0, 1)
+	if len(locations) != 1 || locations[0].Range.Start != expected {
+		t.Errorf("definition location = %+v, expected start %+v", locations, expected)
+	}
+}
+
+func TestServerDefinitionAndFormulaExpressions(t *testing.T) {
+	model := createTestModel()
+	model.AddDefinition(cpq.Definition{ID: "fastcpu", Name: "Fast CPU", Expression: "cpu_i9 OR cpu_xeon"})
+	model.AddDefinition(cpq.Definition{ID: "cooled", Name: "Cooled", Expression: "cooling_liqud"})
+	model.AddPriceRule(cpq.PriceRule{ID: "p1", Name: "i9 fee", Type: cpq.ExpressionPriceRule,
+		Expression: "IF(cpu_i9, cpu_i9_qty * 10, 0) + base * 0", IsActive: true})
+	data, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to encode model: %v", err)
+	}
+	modelText := string(data)
+	server := NewServer(NewWorkspace(testModelURI, model, modelText))
+
+	ruleURI := "file:///r.rule"
+	s := &session{}
+	s.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: testModelURI, Version: 1, Text: modelText}})
+	s.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: ruleURI, Version: 1, Text: "cpu_i9"}})
+	renameID := s.request("textDocument/rename", RenameParams{
+		TextDocument: TextDocumentIdentifier{URI: ruleURI},
+		Position:     Position{Line: 0, Character: 2},
+		NewName:      "cpu_core_i9",
+	})
+	shadowID := s.request("textDocument/rename", RenameParams{
+		TextDocument: TextDocumentIdentifier{URI: ruleURI},
+		Position:     Position{Line: 0, Character: 2},
+		NewName:      "fastcpu",
+	})
+	responses, diagnostics := s.run(t, server)
+
+	// Only the typo in the definition body is reported; formula variables
+	// and the IF keyword are known in the price rule
+	if len(diagnostics) == 0 || diagnostics[0].URI != testModelURI {
+		t.Fatalf("expected model diagnostics first, got %+v", diagnostics)
+	}
+	diags := diagnostics[0].Diagnostics
+	var messages []string
+	for _, diag := range diags {
+		if diag.Code != "inactive-option" {
+			messages = append(messages, diag.Message)
+		}
+	}
+	if len(messages) != 1 || messages[0] != "definition cooled: unknown option 'cooling_liqud'" {
+		t.Errorf("unexpected model diagnostics %q", messages)
+	}
+
+	var edit WorkspaceEdit
+	decodeResult(t, responses[renameID], &edit)
+	renamed, err := decodeModel(applyEdits(NewDocument(testModelURI, 0, modelText), edit.Changes[testModelURI]))
+	if err != nil {
+		t.Fatalf("renamed model does not decode: %v", err)
+	}
+	if renamed.Definitions[0].Expression != "cpu_core_i9 OR cpu_xeon" {
+		t.Errorf("definition not renamed: %s", renamed.Definitions[0].Expression)
+	}
+	if renamed.PriceRules[0].Expression != "IF(cpu_core_i9, cpu_core_i9_qty * 10, 0) + base * 0" {
+		t.Errorf("price formula not renamed: %s", renamed.PriceRules[0].Expression)
+	}
+
+	if responses[shadowID].Error == nil {
+		t.Error("rename onto an existing definition should fail")
+	}
+}
+
+// applyEdits applies non-overlapping edits to a document's text
+func applyEdits(doc *Document, edits []TextEdit) string {
+	type span struct {
+		start, end int
+		text       string
+	}
+	var spans []span
+	for _, e := range edits {
+		spans = append(spans, span{doc.OffsetAt(e.Range.Start), doc.OffsetAt(e.Range.End), e.NewText})
+	}
+	result := doc.Text
+	for len(spans) > 0 {
+		// Apply from the end so earlier offsets stay valid
+		last := 0
+		for i := range spans {
+			if spans[i].start > spans[last].start {
+				last = i
+			}
+		}
+		sp := spans[last]
+		result = result[:sp.start] + sp.text + result[sp.end:]
+		spans = append(spans[:last], spans[last+1:]...)
+	}
+	return result
+}
+
+func TestScanJSONStringsEscapes(t *testing.T) {
+	text := `{"rules": [{"id": "ré", "expression": "a && \"b\""}]}`
+	values, err := scanJSONStrings(text)
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	regions := extractExpressionRegions(values)
+	if len(regions) != 1 || regions[0].Expression != `a && "b"` || regions[0].RuleID != "ré" {
+		t.Fatalf("unexpected regions: %+v", regions)
+	}
+
+	// "a" maps to itself; the first '&' maps to the start of its escape
+	region := regions[0]
+	if text[region.DocumentOffset(0)] != 'a' || !strings.HasPrefix(text[region.DocumentOffset(2):], `&`) {
+		t.Errorf("offset mapping is wrong: %v", region.offsets)
+	}
+
+	if _, err := scanJSONStrings(`{"rules": [`); err == nil {
+		t.Error("expected error for truncated JSON")
+	}
+}
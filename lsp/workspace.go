@@ -0,0 +1,333 @@
+// Package lsp implements a Language Server Protocol server for CPQ rule expressions
+// This file contains the model index used to resolve identifiers in rules
+package lsp
+
+import (
+	"DD/cpq"
+	"DD/parser"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ===== WORKSPACE =====
+
+// Workspace holds the model that rule documents are checked against
+type Workspace struct {
+	ModelURI string
+	model    *cpq.Model
+	modelDoc *Document
+
+	options     map[string]*cpq.Option
+	groups      map[string]*cpq.Group
+	groupCounts map[string]*cpq.Group // group_<id>_count variables of multi-select groups
+	definitions map[string]*cpq.Definition
+}
+
+// NewWorkspace creates a workspace for a model and the JSON text it was loaded from
+func NewWorkspace(modelURI string, model *cpq.Model, text string) *Workspace {
+	ws := &Workspace{ModelURI: modelURI}
+	ws.setModel(model, newDocument(modelURI, 0, text, true))
+	return ws
+}
+
+// LoadWorkspace reads a model JSON file from disk
+func LoadWorkspace(path string) (*Workspace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model file: %w", err)
+	}
+
+	model, err := decodeModel(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve model path: %w", err)
+	}
+
+	return NewWorkspace(PathToURI(absPath), model, string(data)), nil
+}
+
+// PathToURI converts an absolute file path to a file URI
+func PathToURI(path string) string {
+	return "file://" + filepath.ToSlash(path)
+}
+
+// Model returns the current model
+func (ws *Workspace) Model() *cpq.Model {
+	return ws.model
+}
+
+// updateModelText reloads the model from edited JSON text. The previous model
+// is kept when the text does not decode.
+func (ws *Workspace) updateModelText(doc *Document) error {
+	model, err := decodeModel(doc.Text)
+	if err != nil {
+		ws.modelDoc = doc
+		return err
+	}
+	ws.setModel(model, doc)
+	return nil
+}
+
+func (ws *Workspace) setModel(model *cpq.Model, doc *Document) {
+	ws.model = model
+	ws.modelDoc = doc
+	ws.options = make(map[string]*cpq.Option)
+	ws.groups = make(map[string]*cpq.Group)
+	ws.groupCounts = make(map[string]*cpq.Group)
+	ws.definitions = make(map[string]*cpq.Definition)
+
+	for i := range model.Options {
+		ws.options[model.Options[i].ID] = &model.Options[i]
+	}
+	for i := range model.Groups {
+		group := &model.Groups[i]
+		ws.groups[group.ID] = group
+		if group.Type == cpq.MultiSelect {
+			ws.groupCounts[groupCountVariable(group.ID)] = group
+		}
+	}
+	for i := range model.Definitions {
+		ws.definitions[model.Definitions[i].ID] = &model.Definitions[i]
+	}
+}
+
+func decodeModel(text string) (*cpq.Model, error) {
+	var model cpq.Model
+	if err := json.Unmarshal([]byte(text), &model); err != nil {
+		return nil, fmt.Errorf("failed to decode model: %w", err)
+	}
+	return &model, nil
+}
+
+// groupCountVariable matches the variable declared by cpq.ConstraintEngine
+func groupCountVariable(groupID string) string {
+	return fmt.Sprintf("group_%s_count", groupID)
+}
+
+// ===== SYMBOL LOOKUP =====
+
+// symbol is an identifier occurrence inside an expression region
+type symbol struct {
+	name  string
+	start int // Expression byte offsets
+	end   int
+}
+
+// tokens lexes an expression. Lexing stops at the first lexical error so
+// partially typed rules still resolve. Annotation prefixes are not expression
+// tokens, so lexing starts after them and token offsets are shifted back to
+// expression offsets. In price formulas IF is a keyword rather than an
+// identifier.
+func tokens(expression string, formula bool) []parser.Token {
+	offset := 0
+	if annotations, err := parser.ParseAnnotations(expression); err == nil && len(annotations) > 0 {
+		offset = annotations[len(annotations)-1].Range.End.Offset
+	}
+
+	var result []parser.Token
+	lexer := parser.NewLexer(expression[offset:])
+	for {
+		token, err := lexer.NextToken()
+		if err != nil || token.Type == parser.TOKEN_EOF {
+			return result
+		}
+		if formula && token.Type == parser.TOKEN_IDENTIFIER && token.Value == "IF" {
+			token.Type = parser.TOKEN_ITE
+		}
+		token.Range.Start.Offset += offset
+		token.Range.End.Offset += offset
+		result = append(result, token)
+	}
+}
+
+// tokens lexes the region's expression
+func (r *ExpressionRegion) tokens() []parser.Token {
+	return tokens(r.Expression, r.Kind == PriceRuleRegion)
+}
+
+// identifiers returns the identifier occurrences in the region's expression
+func (r *ExpressionRegion) identifiers() []symbol {
+	var symbols []symbol
+	for _, token := range r.tokens() {
+		if token.Type == parser.TOKEN_IDENTIFIER {
+			symbols = append(symbols, symbol{
+				name:  token.Value,
+				start: token.Range.Start.Offset,
+				end:   token.Range.End.Offset,
+			})
+		}
+	}
+	return symbols
+}
+
+// tokenAt returns the token under an expression byte index
+func (r *ExpressionRegion) tokenAt(index int) (parser.Token, bool) {
+	for _, token := range r.tokens() {
+		if index >= token.Range.Start.Offset && index <= token.Range.End.Offset {
+			return token, true
+		}
+	}
+	return parser.Token{}, false
+}
+
+// Variables of price formulas besides option IDs and definitions, matching
+// cpq's price context
+var (
+	formulaVariables = []string{"base", "total_quantity"}
+	formulaSuffixes  = []string{"_qty", "_price"} // <option>_qty and <option>_price
+)
+
+// formulaOption resolves a price formula variable: the option an
+// <option>_qty or <option>_price variable belongs to, or "" for the
+// variables of the whole configuration
+func (ws *Workspace) formulaOption(name string) (string, bool) {
+	for _, variable := range formulaVariables {
+		if name == variable {
+			return "", true
+		}
+	}
+	for _, suffix := range formulaSuffixes {
+		optionID := strings.TrimSuffix(name, suffix)
+		if _, ok := ws.options[optionID]; ok && optionID != name {
+			return optionID, true
+		}
+	}
+	return "", false
+}
+
+// isFormulaVariableOf reports whether a name is the <option>_qty or
+// <option>_price variable of an option
+func isFormulaVariableOf(name, optionID string) bool {
+	for _, suffix := range formulaSuffixes {
+		if name == optionID+suffix {
+			return true
+		}
+	}
+	return false
+}
+
+// definitionOf returns the model JSON string that defines an option, group
+// variable or named definition
+func (ws *Workspace) definitionOf(name string) (jsonString, bool) {
+	collection, id := "options", name
+	if group, ok := ws.groupCounts[name]; ok {
+		collection, id = "groups", group.ID
+	} else if _, ok := ws.definitions[name]; ok {
+		collection = "definitions"
+	} else if _, ok := ws.options[name]; !ok {
+		return jsonString{}, false
+	}
+
+	for _, s := range ws.modelDoc.strings {
+		if len(s.path) == 3 && s.path[0] == collection && s.path[2] == "id" && s.value == id {
+			return s, true
+		}
+	}
+	return jsonString{}, false
+}
+
+// optionIDReferences returns every model JSON string that holds an option ID:
+// the definition, group membership lists, group defaults and embedded options
+func (ws *Workspace) optionIDReferences(optionID string) []jsonString {
+	var refs []jsonString
+	for _, s := range ws.modelDoc.strings {
+		if s.value != optionID {
+			continue
+		}
+		p := s.path
+		switch {
+		case len(p) == 3 && p[0] == "options" && p[2] == "id":
+			refs = append(refs, s)
+		case len(p) == 4 && p[0] == "groups" && p[2] == "option_ids":
+			refs = append(refs, s)
+		case len(p) == 3 && p[0] == "groups" && p[2] == "default_option_id":
+			refs = append(refs, s)
+		case len(p) == 5 && p[0] == "groups" && p[2] == "options" && p[4] == "id":
+			refs = append(refs, s)
+		}
+	}
+	return refs
+}
+
+// ===== FUNCTION CATALOG =====
+
+// functionDoc describes a built-in function of the rule language
+type functionDoc struct {
+	Name      string
+	Signature string
+	Summary   string
+}
+
+var functionDocs = []functionDoc{
+	{"ABS", "ABS(x)", "Absolute value of a number"},
+	{"NEGATE", "NEGATE(x)", "Arithmetic negation of a number"},
+	{"CEIL", "CEIL(x)", "Smallest integer not less than x"},
+	{"FLOOR", "FLOOR(x)", "Largest integer not greater than x"},
+	{"MIN", "MIN(a, b)", "Smaller of two numbers"},
+	{"MAX", "MAX(a, b)", "Larger of two numbers"},
+	{"THRESHOLD", "THRESHOLD(x, limit)", "True when x is at least limit"},
+	{"ITE", "ITE(condition, then, else)", "If-then-else: then when condition holds, otherwise else"},
+	{"IMPLIES", "IMPLIES(a, b)", "Logical implication, same as a -> b"},
+	{"EQUIV", "EQUIV(a, b)", "Logical equivalence, same as a <-> b"},
+	{"XOR", "XOR(a, b)", "Exclusive or"},
+}
+
+var keywordDocs = []functionDoc{
+	{"AND", "a AND b", "Logical conjunction, also written &&"},
+	{"OR", "a OR b", "Logical disjunction, also written ||"},
+	{"NOT", "NOT a", "Logical negation, also written !"},
+	{"true", "true", "Boolean constant"},
+	{"false", "false", "Boolean constant"},
+}
+
+func lookupBuiltin(name string) (functionDoc, bool) {
+	if name == "TRUE" || name == "FALSE" {
+		name = strings.ToLower(name)
+	}
+	for _, docs := range [][]functionDoc{functionDocs, keywordDocs} {
+		for _, doc := range docs {
+			if doc.Name == name {
+				return doc, true
+			}
+		}
+	}
+	return functionDoc{}, false
+}
+
+// sortedOptionIDs returns option IDs in a stable order for completion
+func (ws *Workspace) sortedOptionIDs() []string {
+	ids := make([]string, 0, len(ws.options))
+	for id := range ws.options {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// sortedDefinitionIDs returns named definition IDs in a stable order
+func (ws *Workspace) sortedDefinitionIDs() []string {
+	ids := make([]string, 0, len(ws.definitions))
+	for id := range ws.definitions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// sortedGroupCounts returns group count variables in a stable order
+func (ws *Workspace) sortedGroupCounts() []string {
+	names := make([]string, 0, len(ws.groupCounts))
+	for name := range ws.groupCounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
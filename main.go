@@ -86,6 +86,10 @@ func main() {
 		// Create repositories
 		modelRepo := repository.NewPostgresModelRepository(db)
 		configRepo := repository.NewPostgresConfigRepository(db)
+		rateRepo := repository.NewPostgresExchangeRateRepository(db)
+		taxRepo := repository.NewPostgresTaxRateRepository(db)
+		customerRepo := repository.NewPostgresCustomerRepository(db)
+		quoteRepo := repository.NewPostgresQuoteRepository(db)
 		
 		// Create cache
 		cacheConfig := cache.NewCacheConfig()
@@ -96,7 +100,7 @@ func main() {
 		}
 		
 		// Create enhanced service
-		cpqServiceV2, err := server.NewCPQServiceV2(modelRepo, configRepo, cacheRepo)
+		cpqServiceV2, err := server.NewCPQServiceV2(modelRepo, configRepo, rateRepo, taxRepo, customerRepo, quoteRepo, cacheRepo)
 		if err != nil {
 			log.Fatalf("❌ Failed to create CPQ service: %v", err)
 		}
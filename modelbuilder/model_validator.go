@@ -59,6 +59,7 @@ type ValidationReport struct {
 	ModelComplexity    ModelComplexityMetrics `json:"model_complexity"`
 	RecommendedActions []string               `json:"recommended_actions"`
 	QualityScore       int                    `json:"quality_score"` // 0-100
+	TypeIssues         []TypeIssue            `json:"type_issues,omitempty"`
 }
 
 // ModelValidator performs comprehensive validation of CPQ models
@@ -68,6 +69,7 @@ type ModelValidator struct {
 	existingGroups  map[string]bool
 	dependencyGraph map[string][]string
 	validationRules []ValidationRule
+	typeReport      *TypeCheckReport
 }
 
 // ValidationRule defines a specific validation check
@@ -138,6 +140,7 @@ func (mv *ModelValidator) ValidateModel() (*ValidationReport, error) {
 		ModelComplexity:    complexity,
 		RecommendedActions: recommendedActions,
 		QualityScore:       qualityScore,
+		TypeIssues:         mv.typeCheck().Issues,
 	}
 
 	return report, nil
@@ -213,6 +216,12 @@ func (mv *ModelValidator) initializeValidationRules() {
 			Check:       mv.validateExpressionSyntax,
 			Priority:    6,
 		},
+		{
+			Name:        "Expression Type Validation",
+			Description: "Resolves identifiers against the model and checks expression types",
+			Check:       mv.validateExpressionTypes,
+			Priority:    7,
+		},
 	}
 }
 
@@ -413,8 +422,53 @@ func (mv *ModelValidator) validateExpressionSyntax(validator *ModelValidator) []
 	return errors
 }
 
+// validateExpressionTypes reports type errors and unsatisfiable rules found
+// by the type checker. Unknown identifiers and syntax errors are left to the
+// reference and syntax checks.
+func (mv *ModelValidator) validateExpressionTypes(validator *ModelValidator) []ValidationError {
+	var errors []ValidationError
+
+	for i, issue := range mv.typeCheck().Issues {
+		if issue.Severity != "critical" || issue.Code == IssueUnknownIdentifier || issue.Code == IssueSyntaxError {
+			continue
+		}
+		errors = append(errors, ValidationError{
+			ErrorID:     fmt.Sprintf("%s_%s_%d", issue.Code, issue.SourceID, i),
+			ErrorType:   issue.Code,
+			Severity:    issue.Severity,
+			Message:     issue.Message,
+			AffectedIDs: []string{issue.SourceID},
+			Context:     fmt.Sprintf("Expression: %s (%s)", issue.Range.Text, issue.Range),
+			Suggestion:  "Fix the expression so operand types match the model",
+		})
+	}
+
+	return errors
+}
+
 // Helper methods
 
+// typeCheck runs the model type checker once per validator
+func (mv *ModelValidator) typeCheck() *TypeCheckReport {
+	if mv.typeReport == nil {
+		checker, err := NewTypeChecker(mv.model)
+		if err != nil {
+			// Report the failure rather than treating the model as well typed
+			mv.typeReport = &TypeCheckReport{
+				RuleTypes: make(map[string]string),
+				Issues: []TypeIssue{{
+					Code:     IssueCheckFailed,
+					Severity: "critical",
+					Message:  fmt.Sprintf("type checking failed: %v", err),
+				}},
+			}
+			return mv.typeReport
+		}
+		mv.typeReport = checker.CheckModel()
+	}
+	return mv.typeReport
+}
+
 func (mv *ModelValidator) extractReferencedIDs(expression string) []string {
 	// Simplified ID extraction - in practice, you'd use proper parsing
 	var ids []string
@@ -531,6 +585,20 @@ func (mv *ModelValidator) generateWarnings() []ValidationWarning {
 		}
 	}
 
+	// Rules that always hold never constrain anything
+	for _, issue := range mv.typeCheck().Issues {
+		if issue.Code == IssueConstantTrue {
+			warnings = append(warnings, ValidationWarning{
+				WarningID:   fmt.Sprintf("constant_rule_%s", issue.SourceID),
+				WarningType: "constant_rule",
+				Message:     issue.Message,
+				AffectedIDs: []string{issue.SourceID},
+				Context:     fmt.Sprintf("Rule expression: %s", issue.Range.Text),
+				Suggestion:  "Remove the rule or rewrite it to reference the options it should constrain",
+			})
+		}
+	}
+
 	return warnings
 }
 
@@ -253,6 +253,19 @@ func TestValidateExpressionSyntax(t *testing.T) {
 	}
 }
 
+func TestValidateExpressionTypes_CheckerFailure(t *testing.T) {
+	// A validator without a model cannot build a type checker
+	validator := &ModelValidator{}
+
+	errors := validator.validateExpressionTypes(validator)
+	if len(errors) != 1 {
+		t.Fatalf("Expected one error for the failed type check, got %d", len(errors))
+	}
+	if errors[0].ErrorType != IssueCheckFailed || errors[0].Severity != "critical" {
+		t.Errorf("Expected critical %s error, got %s %s", IssueCheckFailed, errors[0].Severity, errors[0].ErrorType)
+	}
+}
+
 func TestGenerateWarnings(t *testing.T) {
 	model := createModelWithWarningConditions()
 	validator, err := NewModelValidator(model)
@@ -0,0 +1,550 @@
+// type_checker.go - Model-aware static type checking for rule expressions
+// Part of Model Building Tools for CPQ Platform Layer
+// Resolves identifiers against the model and infers boolean vs numeric types
+
+package modelbuilder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"DD/cpq"
+	"DD/mtbdd"
+	"DD/parser"
+)
+
+// ===================================================================
+// SYMBOLS AND ISSUES
+// ===================================================================
+
+// SymbolKind identifies what a model identifier refers to
+type SymbolKind string
+
+const (
+	SymbolOption     SymbolKind = "option"
+	SymbolGroup      SymbolKind = "group"
+	SymbolGroupCount SymbolKind = "group_count"
+	SymbolAttribute  SymbolKind = "attribute"
+	SymbolDefinition SymbolKind = "definition"
+)
+
+// Symbol is a resolved model identifier with its static type
+type Symbol struct {
+	Name string                `json:"name"`
+	Kind SymbolKind            `json:"kind"`
+	Type parser.ExpressionType `json:"type"`
+}
+
+// TypeIssue is a problem found while type checking a model expression
+type TypeIssue struct {
+	Code     string             `json:"code"`
+	Severity string             `json:"severity"` // "critical", "warning", "info"
+	Message  string             `json:"message"`
+	SourceID string             `json:"source_id"` // Rule, definition, price rule or option ID
+	Range    parser.SourceRange `json:"range"`
+}
+
+// Type issue codes
+const (
+	IssueSyntaxError       = "syntax_error"
+	IssueUnknownIdentifier = "unknown_identifier"
+	IssueGroupReference    = "group_reference"
+	IssueTypeMismatch      = "type_mismatch"
+	IssueNonBooleanRule    = "non_boolean_rule"
+	IssueInvalidDefinition = "invalid_definition"
+	IssueConstantTrue      = "constant_true_rule"
+	IssueConstantFalse     = "constant_false_rule"
+	IssueUnusedOption      = "unused_option"
+	IssueCheckFailed       = "type_check_failed"
+)
+
+// TypeCheckReport contains the results of type checking a whole model
+type TypeCheckReport struct {
+	RuleTypes map[string]string `json:"rule_types"` // Rule ID -> inferred type
+	Issues    []TypeIssue       `json:"issues"`
+}
+
+// HasCritical reports whether any issue has critical severity
+func (r *TypeCheckReport) HasCritical() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == "critical" {
+			return true
+		}
+	}
+	return false
+}
+
+// ===================================================================
+// TYPE CHECKER
+// ===================================================================
+
+// TypeChecker resolves expression identifiers against a model and infers
+// their types, reporting mismatches with source ranges
+type TypeChecker struct {
+	model       *cpq.Model
+	symbols     map[string]Symbol
+	definitions map[string]parser.Expression
+	resolving   map[string]bool // Definitions whose type is being inferred
+	referenced  map[string]bool // Identifiers referenced by checked expressions
+	issues      []TypeIssue     // Issues of the expression being checked
+	sourceID    string
+}
+
+// NewTypeChecker creates a type checker for the given model
+func NewTypeChecker(model *cpq.Model) (*TypeChecker, error) {
+	if model == nil {
+		return nil, fmt.Errorf("model cannot be nil")
+	}
+
+	tc := &TypeChecker{
+		model:       model,
+		symbols:     make(map[string]Symbol),
+		definitions: make(map[string]parser.Expression),
+		resolving:   make(map[string]bool),
+		referenced:  make(map[string]bool),
+	}
+	tc.buildSymbols()
+	return tc, nil
+}
+
+// buildSymbols registers attributes, group variables, definitions and options.
+// Later registrations win, so options shadow everything else.
+func (tc *TypeChecker) buildSymbols() {
+	for name, exprType := range tc.attributeTypes() {
+		tc.symbols[name] = Symbol{Name: name, Kind: SymbolAttribute, Type: exprType}
+	}
+
+	for _, group := range tc.model.Groups {
+		tc.symbols[group.ID] = Symbol{Name: group.ID, Kind: SymbolGroup, Type: parser.TYPE_UNKNOWN}
+		if group.Type == cpq.MultiSelect {
+			name := fmt.Sprintf("group_%s_count", group.ID)
+			tc.symbols[name] = Symbol{Name: name, Kind: SymbolGroupCount, Type: parser.TYPE_NUMBER}
+		}
+	}
+
+	for _, definition := range tc.model.Definitions {
+		// Bodies that do not parse are reported by CheckModel; the name still
+		// resolves so references to it do not cascade into unknown identifiers
+		if body, err := parser.ParseExpression(definition.Expression); err == nil {
+			tc.definitions[definition.ID] = body
+		}
+		tc.symbols[definition.ID] = Symbol{Name: definition.ID, Kind: SymbolDefinition, Type: parser.TYPE_UNKNOWN}
+	}
+
+	for _, option := range tc.model.Options {
+		tc.symbols[option.ID] = Symbol{Name: option.ID, Kind: SymbolOption, Type: parser.TYPE_BOOLEAN}
+	}
+}
+
+// attributeTypes infers a type for every option attribute key. Keys whose
+// values are not consistently numeric or boolean have unknown type.
+func (tc *TypeChecker) attributeTypes() map[string]parser.ExpressionType {
+	types := make(map[string]parser.ExpressionType)
+	for _, option := range tc.model.Options {
+		for key, value := range option.Attributes {
+			valueType := parser.TYPE_UNKNOWN
+			switch value.(type) {
+			case float64, float32, int, int64, int32:
+				valueType = parser.TYPE_NUMBER
+			case bool:
+				valueType = parser.TYPE_BOOLEAN
+			}
+			if existing, seen := types[key]; seen && existing != valueType {
+				valueType = parser.TYPE_UNKNOWN
+			}
+			types[key] = valueType
+		}
+	}
+	return types
+}
+
+// Resolve looks up what an identifier refers to in the model
+func (tc *TypeChecker) Resolve(name string) (Symbol, bool) {
+	symbol, ok := tc.symbols[name]
+	if !ok {
+		return Symbol{}, false
+	}
+	if symbol.Kind == SymbolDefinition {
+		symbol.Type = tc.definitionType(name)
+	}
+	return symbol, true
+}
+
+// definitionType infers the type of a definition from its body. Cyclic and
+// unparseable definitions have unknown type.
+func (tc *TypeChecker) definitionType(name string) parser.ExpressionType {
+	body, ok := tc.definitions[name]
+	if !ok || tc.resolving[name] {
+		return parser.TYPE_UNKNOWN
+	}
+
+	// Issues inside the body belong to the definition, not to the caller
+	saved, savedSource := tc.issues, tc.sourceID
+	tc.resolving[name] = true
+	exprType := tc.infer(body)
+	delete(tc.resolving, name)
+	tc.issues, tc.sourceID = saved, savedSource
+	return exprType
+}
+
+// CheckExpression parses and type checks a single expression
+func (tc *TypeChecker) CheckExpression(expression string) (parser.ExpressionType, []TypeIssue) {
+	return tc.check("", expression)
+}
+
+// check parses and type checks an expression on behalf of sourceID
+func (tc *TypeChecker) check(sourceID, expression string) (parser.ExpressionType, []TypeIssue) {
+	tc.issues = nil
+	tc.sourceID = sourceID
+
+	expr, err := parser.ParseExpression(expression)
+	if err != nil {
+		issue := TypeIssue{
+			Code:     IssueSyntaxError,
+			Severity: "critical",
+			Message:  err.Error(),
+			SourceID: sourceID,
+		}
+		if parseErr, ok := err.(*parser.ParseError); ok {
+			issue.Message = parseErr.Message
+			issue.Range = parseErr.Range
+		}
+		return parser.TYPE_UNKNOWN, []TypeIssue{issue}
+	}
+
+	exprType := tc.infer(expr)
+	issues := tc.issues
+	tc.issues = nil
+	return exprType, issues
+}
+
+// CheckModel type checks every definition, rule and price rule condition and
+// reports constant rules and options that nothing references
+func (tc *TypeChecker) CheckModel() *TypeCheckReport {
+	report := &TypeCheckReport{RuleTypes: make(map[string]string)}
+
+	for _, definition := range tc.model.Definitions {
+		_, issues := tc.check(definition.ID, definition.Expression)
+		report.Issues = append(report.Issues, issues...)
+	}
+
+	definitions, definitionErr := tc.model.ParseDefinitions()
+	if definitionErr != nil {
+		report.Issues = append(report.Issues, TypeIssue{
+			Code:     IssueInvalidDefinition,
+			Severity: "critical",
+			Message:  definitionErr.Error(),
+		})
+	}
+
+	for _, rule := range tc.model.Rules {
+		exprType, issues := tc.check(rule.ID, rule.Expression)
+		report.RuleTypes[rule.ID] = exprType.String()
+		report.Issues = append(report.Issues, issues...)
+
+		// Pricing rules may be arithmetic; every other rule is a constraint
+		if rule.Type != cpq.PricingRule && exprType == parser.TYPE_NUMBER {
+			report.Issues = append(report.Issues, TypeIssue{
+				Code:     IssueNonBooleanRule,
+				Severity: "critical",
+				Message:  fmt.Sprintf("Rule '%s' is numeric but constraints must be boolean", rule.ID),
+				SourceID: rule.ID,
+				Range:    wholeRange(rule.Expression),
+			})
+		}
+	}
+
+	for _, priceRule := range tc.model.PriceRules {
+		report.Issues = append(report.Issues, tc.checkPriceCondition(priceRule)...)
+	}
+
+	if definitionErr == nil {
+		report.Issues = append(report.Issues, tc.constantRules(definitions)...)
+	}
+	report.Issues = append(report.Issues, tc.unusedOptions()...)
+
+	return report
+}
+
+// checkPriceCondition resolves the condition of "condition:value" price rules
+func (tc *TypeChecker) checkPriceCondition(priceRule cpq.PriceRule) []TypeIssue {
+	colon := strings.Index(priceRule.Expression, ":")
+	if colon <= 0 {
+		return nil
+	}
+	switch priceRule.Type {
+	case cpq.FixedDiscountRule, cpq.PercentDiscountRule, cpq.SurchargeRule:
+	default:
+		return nil
+	}
+
+	condition := priceRule.Expression[:colon]
+	exprType, issues := tc.check(priceRule.ID, condition)
+	if exprType == parser.TYPE_NUMBER {
+		issues = append(issues, TypeIssue{
+			Code:     IssueTypeMismatch,
+			Severity: "critical",
+			Message:  fmt.Sprintf("Price rule '%s' condition must be boolean, got number", priceRule.ID),
+			SourceID: priceRule.ID,
+			Range:    wholeRange(condition),
+		})
+	}
+	return issues
+}
+
+// constantRules compiles every well-typed boolean rule and reports those
+// that hold or fail regardless of the selection
+func (tc *TypeChecker) constantRules(definitions map[string]parser.Expression) []TypeIssue {
+	var issues []TypeIssue
+
+	m := mtbdd.NewMTBDD()
+	context := mtbdd.NewCompilerContext(m)
+	if err := context.Define(definitions); err != nil {
+		return nil
+	}
+
+	for _, rule := range tc.model.Rules {
+		if rule.Type == cpq.PricingRule {
+			continue
+		}
+		exprType, typeIssues := tc.check(rule.ID, rule.Expression)
+		if exprType != parser.TYPE_BOOLEAN || len(typeIssues) > 0 {
+			continue
+		}
+
+		ref, err := mtbdd.ParseAndCompileWithContext(rule.Expression, context)
+		if err != nil || len(m.Support(ref)) > 0 {
+			continue
+		}
+
+		if value, _ := m.Evaluate(ref, nil).(bool); value {
+			issues = append(issues, TypeIssue{
+				Code:     IssueConstantTrue,
+				Severity: "warning",
+				Message:  fmt.Sprintf("Rule '%s' is always true and never constrains a configuration", rule.ID),
+				SourceID: rule.ID,
+				Range:    wholeRange(rule.Expression),
+			})
+		} else {
+			issues = append(issues, TypeIssue{
+				Code:     IssueConstantFalse,
+				Severity: "critical",
+				Message:  fmt.Sprintf("Rule '%s' is always false, so no configuration is valid", rule.ID),
+				SourceID: rule.ID,
+				Range:    wholeRange(rule.Expression),
+			})
+		}
+	}
+
+	return issues
+}
+
+// unusedOptions reports options not referenced by any checked expression,
+// including references made through definitions
+func (tc *TypeChecker) unusedOptions() []TypeIssue {
+	used := make(map[string]bool)
+	var mark func(name string)
+	mark = func(name string) {
+		if used[name] {
+			return
+		}
+		used[name] = true
+		if body, ok := tc.definitions[name]; ok {
+			for _, ref := range parser.CollectVariables(body) {
+				mark(ref)
+			}
+		}
+	}
+	names := make([]string, 0, len(tc.referenced))
+	for name := range tc.referenced {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mark(name)
+	}
+
+	var issues []TypeIssue
+	for _, option := range tc.model.Options {
+		if !used[option.ID] {
+			issues = append(issues, TypeIssue{
+				Code:     IssueUnusedOption,
+				Severity: "info",
+				Message:  fmt.Sprintf("Option '%s' is not referenced by any rule, definition or price rule", option.ID),
+				SourceID: option.ID,
+			})
+		}
+	}
+	return issues
+}
+
+// ===================================================================
+// TYPE INFERENCE
+// ===================================================================
+
+// infer returns the static type of an expression, recording issues
+func (tc *TypeChecker) infer(expr parser.Expression) parser.ExpressionType {
+	result, _ := expr.Accept(tc)
+	exprType, ok := result.(parser.ExpressionType)
+	if !ok {
+		return parser.TYPE_UNKNOWN
+	}
+	return exprType
+}
+
+// expect records a mismatch when a known type differs from the wanted one.
+// Unknown types are accepted so one error does not cascade.
+func (tc *TypeChecker) expect(expr parser.Expression, got, want parser.ExpressionType, context string) {
+	if got == parser.TYPE_UNKNOWN || got == want {
+		return
+	}
+	tc.issues = append(tc.issues, TypeIssue{
+		Code:     IssueTypeMismatch,
+		Severity: "critical",
+		Message:  fmt.Sprintf("%s expects %s, got %s '%s'", context, want, got, expr),
+		SourceID: tc.sourceID,
+		Range:    expr.GetRange(),
+	})
+}
+
+func (tc *TypeChecker) VisitNumberLiteral(node *parser.NumberLiteral) (interface{}, error) {
+	return parser.TYPE_NUMBER, nil
+}
+
+func (tc *TypeChecker) VisitBooleanLiteral(node *parser.BooleanLiteral) (interface{}, error) {
+	return parser.TYPE_BOOLEAN, nil
+}
+
+func (tc *TypeChecker) VisitIdentifier(node *parser.Identifier) (interface{}, error) {
+	tc.referenced[node.Name] = true
+
+	symbol, ok := tc.Resolve(node.Name)
+	if !ok {
+		tc.issues = append(tc.issues, TypeIssue{
+			Code:     IssueUnknownIdentifier,
+			Severity: "critical",
+			Message:  fmt.Sprintf("'%s' is not an option, group count, attribute or definition", node.Name),
+			SourceID: tc.sourceID,
+			Range:    node.Range,
+		})
+		return parser.TYPE_UNKNOWN, nil
+	}
+
+	if symbol.Kind == SymbolGroup {
+		tc.issues = append(tc.issues, TypeIssue{
+			Code:     IssueGroupReference,
+			Severity: "critical",
+			Message:  fmt.Sprintf("Group '%s' has no value; reference its options or group_%s_count", node.Name, node.Name),
+			SourceID: tc.sourceID,
+			Range:    node.Range,
+		})
+	}
+	return symbol.Type, nil
+}
+
+func (tc *TypeChecker) VisitBinaryOperation(node *parser.BinaryOperation) (interface{}, error) {
+	left := tc.infer(node.Left)
+	right := tc.infer(node.Right)
+	operator := node.Operator.String()
+
+	switch node.Operator {
+	case parser.TOKEN_PLUS, parser.TOKEN_MINUS, parser.TOKEN_MULTIPLY, parser.TOKEN_DIVIDE,
+		parser.TOKEN_MODULO, parser.TOKEN_MIN, parser.TOKEN_MAX:
+		tc.expect(node.Left, left, parser.TYPE_NUMBER, fmt.Sprintf("Operator '%s'", operator))
+		tc.expect(node.Right, right, parser.TYPE_NUMBER, fmt.Sprintf("Operator '%s'", operator))
+		return parser.TYPE_NUMBER, nil
+
+	case parser.TOKEN_LT, parser.TOKEN_LE, parser.TOKEN_GT, parser.TOKEN_GE:
+		tc.expect(node.Left, left, parser.TYPE_NUMBER, fmt.Sprintf("Comparison '%s'", operator))
+		tc.expect(node.Right, right, parser.TYPE_NUMBER, fmt.Sprintf("Comparison '%s'", operator))
+		return parser.TYPE_BOOLEAN, nil
+
+	case parser.TOKEN_EQ, parser.TOKEN_NE:
+		if left != parser.TYPE_UNKNOWN {
+			tc.expect(node.Right, right, left, fmt.Sprintf("Comparison '%s' with %s operand", operator, left))
+		}
+		return parser.TYPE_BOOLEAN, nil
+
+	case parser.TOKEN_AND, parser.TOKEN_OR, parser.TOKEN_IMPLIES_OP, parser.TOKEN_EQUIV_OP:
+		tc.expect(node.Left, left, parser.TYPE_BOOLEAN, fmt.Sprintf("Operator '%s'", operator))
+		tc.expect(node.Right, right, parser.TYPE_BOOLEAN, fmt.Sprintf("Operator '%s'", operator))
+		return parser.TYPE_BOOLEAN, nil
+	}
+
+	return parser.TYPE_UNKNOWN, nil
+}
+
+func (tc *TypeChecker) VisitUnaryOperation(node *parser.UnaryOperation) (interface{}, error) {
+	operand := tc.infer(node.Operand)
+
+	switch node.Operator {
+	case parser.TOKEN_MINUS, parser.TOKEN_PLUS:
+		tc.expect(node.Operand, operand, parser.TYPE_NUMBER, fmt.Sprintf("Unary '%s'", node.Operator))
+		return parser.TYPE_NUMBER, nil
+	case parser.TOKEN_NOT:
+		tc.expect(node.Operand, operand, parser.TYPE_BOOLEAN, "NOT")
+		return parser.TYPE_BOOLEAN, nil
+	}
+	return parser.TYPE_UNKNOWN, nil
+}
+
+func (tc *TypeChecker) VisitFunctionCall(node *parser.FunctionCall) (interface{}, error) {
+	args := make([]parser.ExpressionType, len(node.Args))
+	for i, arg := range node.Args {
+		args[i] = tc.infer(arg)
+	}
+	function := node.Function.String()
+
+	switch node.Function {
+	case parser.TOKEN_ABS, parser.TOKEN_NEGATE, parser.TOKEN_CEIL, parser.TOKEN_FLOOR,
+		parser.TOKEN_MIN, parser.TOKEN_MAX:
+		for i, arg := range node.Args {
+			tc.expect(arg, args[i], parser.TYPE_NUMBER, function)
+		}
+		return parser.TYPE_NUMBER, nil
+
+	case parser.TOKEN_IMPLIES, parser.TOKEN_EQUIV, parser.TOKEN_XOR:
+		for i, arg := range node.Args {
+			tc.expect(arg, args[i], parser.TYPE_BOOLEAN, function)
+		}
+		return parser.TYPE_BOOLEAN, nil
+
+	case parser.TOKEN_THRESHOLD:
+		if len(node.Args) == 2 {
+			tc.expect(node.Args[0], args[0], parser.TYPE_NUMBER, "THRESHOLD")
+			if _, constant := node.Args[1].(*parser.NumberLiteral); !constant {
+				tc.issues = append(tc.issues, TypeIssue{
+					Code:     IssueTypeMismatch,
+					Severity: "critical",
+					Message:  fmt.Sprintf("THRESHOLD expects a numeric constant, got '%s'", node.Args[1]),
+					SourceID: tc.sourceID,
+					Range:    node.Args[1].GetRange(),
+				})
+			}
+		}
+		return parser.TYPE_BOOLEAN, nil
+
+	case parser.TOKEN_ITE:
+		if len(node.Args) != 3 {
+			return parser.TYPE_UNKNOWN, nil
+		}
+		tc.expect(node.Args[0], args[0], parser.TYPE_BOOLEAN, "ITE condition")
+		if args[1] == parser.TYPE_UNKNOWN {
+			return args[2], nil
+		}
+		tc.expect(node.Args[2], args[2], args[1], "ITE else branch")
+		return args[1], nil
+	}
+
+	return parser.TYPE_UNKNOWN, nil
+}
+
+// wholeRange spans an entire single-line expression source
+func wholeRange(source string) parser.SourceRange {
+	return parser.SourceRange{
+		Start: parser.Position{Line: 1, Column: 1, Offset: 0},
+		End:   parser.Position{Line: 1, Column: len(source) + 1, Offset: len(source)},
+		Text:  source,
+	}
+}
@@ -0,0 +1,187 @@
+// type_checker_test.go - Unit tests for model-aware type checking
+package modelbuilder
+
+import (
+	"testing"
+
+	"DD/cpq"
+	"DD/parser"
+)
+
+func createTypeCheckTestModel() *cpq.Model {
+	model := cpq.NewModel("type-check", "Type Check Model")
+	model.AddGroup(cpq.Group{ID: "cpu", Name: "CPU", Type: cpq.SingleSelect, IsActive: true})
+	model.AddGroup(cpq.Group{ID: "addons", Name: "Add-ons", Type: cpq.MultiSelect, IsActive: true})
+	model.AddOption(cpq.Option{ID: "opt_a", Name: "A", GroupID: "cpu", IsActive: true,
+		Attributes: map[string]interface{}{"cores": 8.0, "gpu": true, "family": "x86"}})
+	model.AddOption(cpq.Option{ID: "opt_b", Name: "B", GroupID: "cpu", IsActive: true,
+		Attributes: map[string]interface{}{"cores": 16.0, "gpu": false}})
+	model.AddOption(cpq.Option{ID: "opt_c", Name: "C", GroupID: "addons", IsActive: true})
+	model.AddDefinition(cpq.Definition{ID: "highend", Name: "High end", Expression: "opt_b OR opt_c"})
+	model.AddDefinition(cpq.Definition{ID: "addon_total", Name: "Add-on total", Expression: "group_addons_count * 2"})
+	return model
+}
+
+func TestTypeChecker_Resolve(t *testing.T) {
+	checker, err := NewTypeChecker(createTypeCheckTestModel())
+	if err != nil {
+		t.Fatalf("Failed to create type checker: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		kind     SymbolKind
+		exprType parser.ExpressionType
+	}{
+		{"opt_a", SymbolOption, parser.TYPE_BOOLEAN},
+		{"cpu", SymbolGroup, parser.TYPE_UNKNOWN},
+		{"group_addons_count", SymbolGroupCount, parser.TYPE_NUMBER},
+		{"cores", SymbolAttribute, parser.TYPE_NUMBER},
+		{"gpu", SymbolAttribute, parser.TYPE_BOOLEAN},
+		{"family", SymbolAttribute, parser.TYPE_UNKNOWN},
+		{"highend", SymbolDefinition, parser.TYPE_BOOLEAN},
+		{"addon_total", SymbolDefinition, parser.TYPE_NUMBER},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbol, ok := checker.Resolve(tt.name)
+			if !ok {
+				t.Fatalf("Expected %s to resolve", tt.name)
+			}
+			if symbol.Kind != tt.kind || symbol.Type != tt.exprType {
+				t.Errorf("Expected %s %s, got %s %s", tt.kind, tt.exprType, symbol.Kind, symbol.Type)
+			}
+		})
+	}
+
+	if _, ok := checker.Resolve("group_cpu_count"); ok {
+		t.Error("Single-select groups should not have a count variable")
+	}
+}
+
+func TestTypeChecker_CheckExpression(t *testing.T) {
+	checker, err := NewTypeChecker(createTypeCheckTestModel())
+	if err != nil {
+		t.Fatalf("Failed to create type checker: %v", err)
+	}
+
+	tests := []struct {
+		expression string
+		exprType   parser.ExpressionType
+		issueCode  string // Expected code of the first issue, "" for none
+		issueText  string // Expected source text of the first issue
+	}{
+		{"opt_a -> opt_b", parser.TYPE_BOOLEAN, "", ""},
+		{"highend AND cores > 4", parser.TYPE_BOOLEAN, "", ""},
+		{"addon_total + group_addons_count", parser.TYPE_NUMBER, "", ""},
+		{"ITE(opt_a, 1, 2)", parser.TYPE_NUMBER, "", ""},
+		{"opt_a + true", parser.TYPE_NUMBER, IssueTypeMismatch, "opt_a"},
+		{"THRESHOLD(opt_a, cores)", parser.TYPE_BOOLEAN, IssueTypeMismatch, "opt_a"},
+		{"THRESHOLD(group_addons_count, cores)", parser.TYPE_BOOLEAN, IssueTypeMismatch, "cores"},
+		{"opt_a == 1", parser.TYPE_BOOLEAN, IssueTypeMismatch, "1"},
+		{"ITE(opt_a, 1, opt_b)", parser.TYPE_NUMBER, IssueTypeMismatch, "opt_b"},
+		{"opt_a AND missing", parser.TYPE_BOOLEAN, IssueUnknownIdentifier, "missing"},
+		{"cpu -> opt_a", parser.TYPE_BOOLEAN, IssueGroupReference, "cpu"},
+		{"opt_a AND family > 2", parser.TYPE_BOOLEAN, "", ""},
+		{"(opt_a", parser.TYPE_UNKNOWN, IssueSyntaxError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			exprType, issues := checker.CheckExpression(tt.expression)
+			if exprType != tt.exprType {
+				t.Errorf("Expected type %s, got %s", tt.exprType, exprType)
+			}
+			if tt.issueCode == "" {
+				if len(issues) > 0 {
+					t.Errorf("Expected no issues, got %v", issues)
+				}
+				return
+			}
+			if len(issues) == 0 {
+				t.Fatalf("Expected %s issue", tt.issueCode)
+			}
+			if issues[0].Code != tt.issueCode {
+				t.Errorf("Expected issue %s, got %s: %s", tt.issueCode, issues[0].Code, issues[0].Message)
+			}
+			if tt.issueText != "" && issues[0].Range.Text != tt.issueText {
+				t.Errorf("Expected issue range over %q, got %q", tt.issueText, issues[0].Range.Text)
+			}
+		})
+	}
+}
+
+func TestTypeChecker_CheckModel(t *testing.T) {
+	model := createTypeCheckTestModel()
+	model.AddRule(cpq.Rule{ID: "r_ok", Name: "OK", Type: cpq.RequiresRule, Expression: "opt_a -> highend", IsActive: true})
+	model.AddRule(cpq.Rule{ID: "r_true", Name: "Tautology", Type: cpq.ValidationRule, Expression: "opt_a OR !opt_a", IsActive: true})
+	model.AddRule(cpq.Rule{ID: "r_false", Name: "Contradiction", Type: cpq.ValidationRule, Expression: "opt_a AND !opt_a", IsActive: true})
+	model.AddRule(cpq.Rule{ID: "r_numeric", Name: "Numeric", Type: cpq.ValidationRule, Expression: "addon_total + 1", IsActive: true})
+
+	checker, err := NewTypeChecker(model)
+	if err != nil {
+		t.Fatalf("Failed to create type checker: %v", err)
+	}
+	report := checker.CheckModel()
+
+	if report.RuleTypes["r_ok"] != parser.TYPE_BOOLEAN.String() {
+		t.Errorf("Expected r_ok to be boolean, got %s", report.RuleTypes["r_ok"])
+	}
+
+	found := make(map[string]string)
+	for _, issue := range report.Issues {
+		found[issue.Code+":"+issue.SourceID] = issue.Severity
+	}
+
+	expected := map[string]string{
+		IssueConstantTrue + ":r_true":      "warning",
+		IssueConstantFalse + ":r_false":    "critical",
+		IssueNonBooleanRule + ":r_numeric": "critical",
+	}
+	for key, severity := range expected {
+		if found[key] != severity {
+			t.Errorf("Expected %s with severity %s, got %q", key, severity, found[key])
+		}
+	}
+
+	// opt_c is only referenced through the highend definition
+	for _, option := range []string{"opt_a", "opt_b", "opt_c"} {
+		if _, unused := found[IssueUnusedOption+":"+option]; unused {
+			t.Errorf("Option %s is referenced and should not be reported unused", option)
+		}
+	}
+	if !report.HasCritical() {
+		t.Error("Expected report to contain critical issues")
+	}
+}
+
+func TestValidateModel_TypeErrors(t *testing.T) {
+	model := createTypeCheckTestModel()
+	model.AddRule(cpq.Rule{ID: "r_mismatch", Name: "Mismatch", Type: cpq.ValidationRule, Expression: "opt_a + true", IsActive: true})
+
+	validator, err := NewModelValidator(model)
+	if err != nil {
+		t.Fatalf("Failed to create model validator: %v", err)
+	}
+	report, err := validator.ValidateModel()
+	if err != nil {
+		t.Fatalf("Failed to validate model: %v", err)
+	}
+
+	if report.IsValid {
+		t.Error("Model with type errors should be invalid")
+	}
+	hasTypeError := false
+	for _, validationError := range report.Errors {
+		if validationError.ErrorType == IssueTypeMismatch {
+			hasTypeError = true
+		}
+	}
+	if !hasTypeError {
+		t.Errorf("Expected a type_mismatch error, got %v", report.Errors)
+	}
+	if len(report.TypeIssues) == 0 {
+		t.Error("Report should include type checker issues")
+	}
+}
@@ -33,11 +33,27 @@ type CompilerContext interface {
 	ClearCache()
 }
 
+// DefinitionResolver is implemented by compiler contexts that support named
+// definitions; identifiers naming a definition compile to its body
+type DefinitionResolver interface {
+	GetDefinition(name string) (parser.Expression, bool)
+}
+
+// lookupDefinition resolves a named definition if the context supports them
+func lookupDefinition(context CompilerContext, name string) (parser.Expression, bool) {
+	resolver, ok := context.(DefinitionResolver)
+	if !ok {
+		return nil, false
+	}
+	return resolver.GetDefinition(name)
+}
+
 // StandardCompilerContext provides a complete implementation of CompilerContext
 type StandardCompilerContext struct {
 	mtbdd         *MTBDD
 	declaredVars  map[string]NodeRef
 	cache         map[string]NodeRef
+	definitions   map[string]parser.Expression
 	enableCaching bool
 }
 
@@ -47,6 +63,7 @@ func NewCompilerContext(mtbdd *MTBDD) *StandardCompilerContext {
 		mtbdd:         mtbdd,
 		declaredVars:  make(map[string]NodeRef),
 		cache:         make(map[string]NodeRef),
+		definitions:   make(map[string]parser.Expression),
 		enableCaching: true,
 	}
 }
@@ -127,6 +144,67 @@ func (ctx *StandardCompilerContext) ClearCache() {
 	ctx.cache = make(map[string]NodeRef)
 }
 
+// Define registers named definitions that compiled expressions may reference.
+// Definitions are checked for reference cycles before any is registered.
+// Redefining a name clears the cache, since cached results are keyed by
+// source text and may have been compiled against the old body.
+func (ctx *StandardCompilerContext) Define(definitions map[string]parser.Expression) error {
+	merged := make(map[string]parser.Expression, len(ctx.definitions)+len(definitions))
+	for name, body := range ctx.definitions {
+		merged[name] = body
+	}
+	redefined := false
+	for name, body := range definitions {
+		if _, isVariable := ctx.declaredVars[name]; isVariable {
+			return fmt.Errorf("definition '%s' conflicts with a declared variable", name)
+		}
+		if previous, exists := ctx.definitions[name]; exists && previous.String() != body.String() {
+			redefined = true
+		}
+		merged[name] = body
+	}
+	if _, err := parser.DefinitionOrder(merged); err != nil {
+		return err
+	}
+
+	ctx.definitions = merged
+	if redefined {
+		ctx.ClearCache()
+	}
+	return nil
+}
+
+func (ctx *StandardCompilerContext) GetDefinition(name string) (parser.Expression, bool) {
+	body, exists := ctx.definitions[name]
+	return body, exists
+}
+
+// referencedVariables returns the variables an expression depends on, looking
+// through any definitions it references
+func referencedVariables(expr parser.Expression, context CompilerContext) []string {
+	seen := make(map[string]bool)
+	var variables []string
+
+	var collect func(expr parser.Expression)
+	collect = func(expr parser.Expression) {
+		for _, name := range parser.CollectVariables(expr) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if body, ok := lookupDefinition(context, name); ok {
+				collect(body)
+				continue
+			}
+			variables = append(variables, name)
+		}
+	}
+	collect(expr)
+
+	sort.Strings(variables)
+	return variables
+}
+
 // ===== MTBDD COMPILER IMPLEMENTATION =====
 
 // MTBDDCompiler implements the visitor pattern for MTBDD compilation
@@ -168,8 +246,8 @@ func Compile(expr parser.Expression, mtbdd *MTBDD) (NodeRef, CompilerContext, er
 
 // CompileWithContext compiles using an existing context (for reuse)
 func CompileWithContext(expr parser.Expression, context CompilerContext) (NodeRef, error) {
-	// Auto-declare any new variables
-	variables := parser.CollectVariables(expr)
+	// Auto-declare any new variables, including those used by referenced definitions
+	variables := referencedVariables(expr, context)
 	if err := context.AutoDeclareVariables(variables); err != nil {
 		return NullRef, fmt.Errorf("failed to declare variables: %w", err)
 	}
@@ -239,6 +317,17 @@ func ParseAndCompile(input string, mtbdd *MTBDD) (NodeRef, CompilerContext, erro
 	return Compile(expr, mtbdd)
 }
 
+// ParseAndCompileWithContext parses and compiles using an existing context,
+// so named definitions registered on the context are resolved and shared
+func ParseAndCompileWithContext(input string, context CompilerContext) (NodeRef, error) {
+	expr, err := parser.ParseExpression(input)
+	if err != nil {
+		return NullRef, fmt.Errorf("parse error: %w", err)
+	}
+
+	return CompileWithContext(expr, context)
+}
+
 // ===== VISITOR IMPLEMENTATION =====
 
 func (c *MTBDDCompiler) VisitNumberLiteral(node *parser.NumberLiteral) (interface{}, error) {
@@ -251,7 +340,25 @@ func (c *MTBDDCompiler) VisitBooleanLiteral(node *parser.BooleanLiteral) (interf
 }
 
 func (c *MTBDDCompiler) VisitIdentifier(node *parser.Identifier) (interface{}, error) {
-	return c.context.GetVariable(node.Name)
+	body, isDefinition := lookupDefinition(c.context, node.Name)
+	if !isDefinition {
+		return c.context.GetVariable(node.Name)
+	}
+
+	// Definitions compile once and are shared by every reference through the cache
+	if cached, found := c.context.GetCached(node); found {
+		return cached, nil
+	}
+	result, err := body.Accept(c)
+	if err != nil {
+		return NullRef, fmt.Errorf("in definition '%s': %w", node.Name, err)
+	}
+	ref, ok := result.(NodeRef)
+	if !ok {
+		return NullRef, c.context.WrapError(fmt.Errorf("definition '%s' compilation failed", node.Name), node)
+	}
+	c.context.SetCached(node, ref)
+	return ref, nil
 }
 
 func (c *MTBDDCompiler) VisitBinaryOperation(node *parser.BinaryOperation) (interface{}, error) {
@@ -1275,6 +1275,161 @@ func TestParseAndCompile(t *testing.T) {
 }
 
 // Helper function to create a test MTBDD instance
+// TestMTBDDDefinitions tests named definitions resolved through the compiler context
+func TestMTBDDDefinitions(t *testing.T) {
+	parseDefinitions := func(t *testing.T, sources ...string) map[string]parser.Expression {
+		definitions := make(map[string]parser.Expression)
+		for _, source := range sources {
+			def, err := parser.ParseDefinition(source)
+			if err != nil {
+				t.Fatalf("ParseDefinition(%q) error: %v", source, err)
+			}
+			definitions[def.Name] = def.Body
+		}
+		return definitions
+	}
+
+	t.Run("Rule Referencing Definitions", func(t *testing.T) {
+		mtbddInstance := createTestMTBDD()
+		ctx := NewCompilerContext(mtbddInstance)
+		err := ctx.Define(parseDefinitions(t,
+			"let fastcpu = cpu_i9 OR cpu_xeon",
+			"let highend = fastcpu AND ram_64",
+		))
+		if err != nil {
+			t.Fatalf("Define error: %v", err)
+		}
+
+		rule, err := ParseAndCompileWithContext("highend -> psu_1000", ctx)
+		if err != nil {
+			t.Fatalf("Compilation error: %v", err)
+		}
+
+		// Definition names are not declared as variables
+		expectedVars := []string{"cpu_i9", "cpu_xeon", "psu_1000", "ram_64"}
+		if vars := ctx.GetDeclaredVariables(); !compareStringSlices(vars, expectedVars) {
+			t.Errorf("Expected variables %v, got %v", expectedVars, vars)
+		}
+
+		cases := []struct {
+			assignment map[string]bool
+			expected   bool
+		}{
+			{map[string]bool{"cpu_i9": true, "ram_64": true, "psu_1000": false, "cpu_xeon": false}, false},
+			{map[string]bool{"cpu_i9": true, "ram_64": true, "psu_1000": true, "cpu_xeon": false}, true},
+			{map[string]bool{"cpu_i9": false, "ram_64": true, "psu_1000": false, "cpu_xeon": false}, true},
+			{map[string]bool{"cpu_i9": false, "ram_64": true, "psu_1000": false, "cpu_xeon": true}, false},
+		}
+		for _, c := range cases {
+			if result := mtbddInstance.Evaluate(rule, c.assignment); result != c.expected {
+				t.Errorf("Evaluate(%v) = %v, expected %v", c.assignment, result, c.expected)
+			}
+		}
+	})
+
+	t.Run("Definitions Compile Once", func(t *testing.T) {
+		mtbddInstance := createTestMTBDD()
+		ctx := NewCompilerContext(mtbddInstance)
+		if err := ctx.Define(parseDefinitions(t, "let highend = (cpu_i9 OR cpu_xeon) AND ram_64")); err != nil {
+			t.Fatalf("Define error: %v", err)
+		}
+
+		first, err := ParseAndCompileWithContext("highend", ctx)
+		if err != nil {
+			t.Fatalf("Compilation error: %v", err)
+		}
+		second, err := ParseAndCompileWithContext("highend", ctx)
+		if err != nil {
+			t.Fatalf("Compilation error: %v", err)
+		}
+		if first != second {
+			t.Errorf("Expected shared node for repeated definition reference, got %v and %v", first, second)
+		}
+
+		cached, found := ctx.GetCached(&parser.Identifier{Name: "highend"})
+		if !found || cached != first {
+			t.Errorf("Expected definition to be cached as %v, got %v (found=%v)", first, cached, found)
+		}
+	})
+
+	t.Run("Redefinition Invalidates Cache", func(t *testing.T) {
+		mtbddInstance := createTestMTBDD()
+		ctx := NewCompilerContext(mtbddInstance)
+		if err := ctx.Define(parseDefinitions(t, "let fastcpu = cpu_i9")); err != nil {
+			t.Fatalf("Define error: %v", err)
+		}
+		if _, err := ParseAndCompileWithContext("fastcpu AND ram_64", ctx); err != nil {
+			t.Fatalf("Compilation error: %v", err)
+		}
+
+		if err := ctx.Define(parseDefinitions(t, "let fastcpu = cpu_xeon")); err != nil {
+			t.Fatalf("Define error: %v", err)
+		}
+		rule, err := ParseAndCompileWithContext("fastcpu AND ram_64", ctx)
+		if err != nil {
+			t.Fatalf("Compilation error: %v", err)
+		}
+
+		assignment := map[string]bool{"cpu_i9": false, "cpu_xeon": true, "ram_64": true}
+		if result := mtbddInstance.Evaluate(rule, assignment); result != true {
+			t.Errorf("Expected rule to use the new definition body for %v", assignment)
+		}
+	})
+
+	t.Run("Cycles Are Rejected", func(t *testing.T) {
+		ctx := NewCompilerContext(createTestMTBDD())
+		err := ctx.Define(parseDefinitions(t,
+			"let a = b AND x",
+			"let b = c OR y",
+			"let c = NOT a",
+		))
+		if err == nil {
+			t.Fatal("Expected cycle error but got none")
+		}
+		if !strings.Contains(err.Error(), "a -> b -> c -> a") {
+			t.Errorf("Expected cycle path in error, got: %v", err)
+		}
+		if _, ok := ctx.GetDefinition("a"); ok {
+			t.Error("Expected no definitions to be registered after a cycle error")
+		}
+	})
+
+	t.Run("Definition Syntax", func(t *testing.T) {
+		tests := []struct {
+			input       string
+			name        string
+			body        string
+			shouldError bool
+		}{
+			{input: "let highend = cpu_i9 AND ram_64", name: "highend", body: "(cpu_i9 AND ram_64)"},
+			{input: "  let  x=a", name: "x", body: "a"},
+			{input: "let big = a == b", name: "big", body: "(a == b)"},
+			{input: "highend = a", shouldError: true},
+			{input: "let AND = a", shouldError: true},
+			{input: "let x == a", shouldError: true},
+			{input: "let x a", shouldError: true},
+			{input: "let x = a b", shouldError: true},
+		}
+
+		for _, tt := range tests {
+			def, err := parser.ParseDefinition(tt.input)
+			if tt.shouldError {
+				if err == nil {
+					t.Errorf("ParseDefinition(%q): expected error but got %v", tt.input, def)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("ParseDefinition(%q) error: %v", tt.input, err)
+				continue
+			}
+			if def.Name != tt.name || def.Body.String() != tt.body {
+				t.Errorf("ParseDefinition(%q) = %s %s, expected %s %s", tt.input, def.Name, def.Body, tt.name, tt.body)
+			}
+		}
+	})
+}
+
 func createTestMTBDD() *MTBDD {
 	// In your real implementation, this would create your actual MTBDD
 	// For now, return a mock or basic instance
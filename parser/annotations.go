@@ -0,0 +1,206 @@
+// Package parser provides expression parsing and AST definitions
+// This file contains `@name(key="value")` annotations that prefix rule expressions
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ===== ANNOTATIONS =====
+
+// AnnotationArg is a single key="value" argument of an annotation
+type AnnotationArg struct {
+	Key   string
+	Value string
+}
+
+// Annotation is rule metadata written before an expression, such as
+// `@meta(severity="warning", owner="hardware")`
+type Annotation struct {
+	Name  string
+	Args  []AnnotationArg
+	Range SourceRange
+}
+
+// Get returns the value of an annotation argument
+func (a Annotation) Get(key string) (string, bool) {
+	for _, arg := range a.Args {
+		if arg.Key == key {
+			return arg.Value, true
+		}
+	}
+	return "", false
+}
+
+func (a Annotation) String() string {
+	if len(a.Args) == 0 {
+		return "@" + a.Name
+	}
+	args := make([]string, len(a.Args))
+	for i, arg := range a.Args {
+		args[i] = arg.Key + "=" + strconv.Quote(arg.Value)
+	}
+	return fmt.Sprintf("@%s(%s)", a.Name, strings.Join(args, ", "))
+}
+
+// AnnotatedExpression is an expression together with its annotation prefixes
+type AnnotatedExpression struct {
+	Annotations []Annotation
+	Expression  Expression
+}
+
+// ParseAnnotatedExpression parses an expression and keeps its annotations.
+// ParseExpression accepts the same input but discards them.
+func ParseAnnotatedExpression(input string) (*AnnotatedExpression, error) {
+	parser := NewParser(input)
+	expr, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	return &AnnotatedExpression{Annotations: parser.annotations, Expression: expr}, nil
+}
+
+// ParseAnnotations parses only the annotation prefixes of an expression, so
+// annotations can be read even when the expression after them is invalid
+func ParseAnnotations(input string) ([]Annotation, error) {
+	return NewParser(input).parseAnnotations()
+}
+
+// ===== ANNOTATION PARSING =====
+
+// parseAnnotations reads the annotation prefixes at the start of the input.
+// Annotations are scanned directly from the lexer because '@', '=' and
+// string literals are not expression tokens.
+func (p *Parser) parseAnnotations() ([]Annotation, error) {
+	var annotations []Annotation
+	for {
+		if err := p.lexer.skipTrivia(); err != nil {
+			return nil, err
+		}
+		if p.lexer.peek() != '@' {
+			return annotations, nil
+		}
+
+		start := p.lexer.currentPos()
+		p.lexer.advance()
+		name, err := p.annotationIdentifier("annotation name")
+		if err != nil {
+			return nil, err
+		}
+		annotation := Annotation{Name: name}
+
+		if err := p.lexer.skipTrivia(); err != nil {
+			return nil, err
+		}
+		if p.lexer.peek() == '(' {
+			p.lexer.advance()
+			if annotation.Args, err = p.parseAnnotationArgs(); err != nil {
+				return nil, err
+			}
+		}
+
+		end := p.lexer.currentPos()
+		annotation.Range = SourceRange{Start: start, End: end, Text: p.sourceText[start.Offset:end.Offset]}
+		annotations = append(annotations, annotation)
+	}
+}
+
+// parseAnnotationArgs reads `key="value", ...)` after an opening parenthesis
+func (p *Parser) parseAnnotationArgs() ([]AnnotationArg, error) {
+	var args []AnnotationArg
+	for {
+		if err := p.lexer.skipTrivia(); err != nil {
+			return nil, err
+		}
+		if p.lexer.peek() == ')' && len(args) == 0 {
+			p.lexer.advance()
+			return args, nil
+		}
+
+		key, err := p.annotationIdentifier("argument name")
+		if err != nil {
+			return nil, err
+		}
+		for _, arg := range args {
+			if arg.Key == key {
+				return nil, p.annotationError(fmt.Sprintf("Duplicate annotation argument '%s'", key), "Give each argument once")
+			}
+		}
+
+		if err := p.lexer.skipTrivia(); err != nil {
+			return nil, err
+		}
+		if p.lexer.peek() != '=' {
+			return nil, p.annotationError(fmt.Sprintf("Expected '=' after argument '%s'", key), `Write arguments as key="value"`)
+		}
+		p.lexer.advance()
+		if err := p.lexer.skipTrivia(); err != nil {
+			return nil, err
+		}
+		value, err := p.annotationString()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, AnnotationArg{Key: key, Value: value})
+
+		if err := p.lexer.skipTrivia(); err != nil {
+			return nil, err
+		}
+		switch p.lexer.peek() {
+		case ',':
+			p.lexer.advance()
+		case ')':
+			p.lexer.advance()
+			return args, nil
+		default:
+			return nil, p.annotationError("Expected ',' or ')' in annotation", "Separate arguments with ',' and close the annotation with ')'")
+		}
+	}
+}
+
+func (p *Parser) annotationIdentifier(what string) (string, error) {
+	if !unicode.IsLetter(p.lexer.peek()) {
+		return "", p.annotationError(fmt.Sprintf("Expected %s", what), "Names must start with a letter")
+	}
+	return p.lexer.readIdentifier(), nil
+}
+
+// annotationString reads a double-quoted string with Go escape sequences
+func (p *Parser) annotationString() (string, error) {
+	if p.lexer.peek() != '"' {
+		return "", p.annotationError("Expected quoted annotation value", `Quote values, e.g. owner="pricing"`)
+	}
+
+	start := p.lexer.position
+	p.lexer.advance()
+	for p.lexer.peek() != '"' {
+		switch p.lexer.peek() {
+		case 0, '\n':
+			return "", p.annotationError("Unterminated annotation value", "Close the value with '\"'")
+		case '\\':
+			p.lexer.advance()
+		}
+		p.lexer.advance()
+	}
+	p.lexer.advance()
+
+	value, err := strconv.Unquote(p.sourceText[start:p.lexer.position])
+	if err != nil {
+		return "", p.annotationError("Invalid escape in annotation value", `Use \" and \\ for quotes and backslashes`)
+	}
+	return value, nil
+}
+
+func (p *Parser) annotationError(message, suggestion string) *ParseError {
+	pos := p.lexer.currentPos()
+	return &ParseError{
+		Message:    message,
+		Range:      SourceRange{Start: pos, End: pos},
+		SourceText: p.sourceText,
+		ErrorType:  "syntax",
+		Suggestion: suggestion,
+	}
+}
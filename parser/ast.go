@@ -40,8 +40,9 @@ type Expression interface {
 
 // NumberLiteral represents a numeric constant
 type NumberLiteral struct {
-	Value float64
-	Range SourceRange
+	Value    float64
+	Range    SourceRange
+	Comments []Comment
 }
 
 func (n *NumberLiteral) Accept(visitor Visitor) (interface{}, error) {
@@ -58,8 +59,9 @@ func (n *NumberLiteral) String() string {
 
 // BooleanLiteral represents a boolean constant
 type BooleanLiteral struct {
-	Value bool
-	Range SourceRange
+	Value    bool
+	Range    SourceRange
+	Comments []Comment
 }
 
 func (b *BooleanLiteral) Accept(visitor Visitor) (interface{}, error) {
@@ -76,8 +78,9 @@ func (b *BooleanLiteral) String() string {
 
 // Identifier represents a variable reference
 type Identifier struct {
-	Name  string
-	Range SourceRange
+	Name     string
+	Range    SourceRange
+	Comments []Comment
 }
 
 func (i *Identifier) Accept(visitor Visitor) (interface{}, error) {
@@ -98,6 +101,7 @@ type BinaryOperation struct {
 	Operator TokenType
 	Right    Expression
 	Range    SourceRange
+	Comments []Comment
 }
 
 func (b *BinaryOperation) Accept(visitor Visitor) (interface{}, error) {
@@ -117,6 +121,7 @@ type UnaryOperation struct {
 	Operator TokenType
 	Operand  Expression
 	Range    SourceRange
+	Comments []Comment
 }
 
 func (u *UnaryOperation) Accept(visitor Visitor) (interface{}, error) {
@@ -136,6 +141,7 @@ type FunctionCall struct {
 	Function TokenType
 	Args     []Expression
 	Range    SourceRange
+	Comments []Comment
 }
 
 func (f *FunctionCall) Accept(visitor Visitor) (interface{}, error) {
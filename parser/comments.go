@@ -0,0 +1,155 @@
+// Package parser provides expression parsing and AST definitions
+// This file contains comment lexing and the attachment of comments to AST nodes
+package parser
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ===== COMMENTS =====
+
+// Comment is a `//` or `/* */` comment preserved on an AST node. Comments
+// attach to the node that follows them; comments after the last token
+// attach to the root as trailing comments.
+type Comment struct {
+	Text     string // Comment body without its delimiters
+	Block    bool   // true for /* */ comments
+	Trailing bool   // true when the comment follows the node
+	Range    SourceRange
+}
+
+func (c Comment) String() string {
+	if c.Block {
+		return "/*" + c.Text + "*/"
+	}
+	return "//" + c.Text
+}
+
+// CommentsOf returns the comments attached to an AST node
+func CommentsOf(expr Expression) []Comment {
+	switch node := expr.(type) {
+	case *NumberLiteral:
+		return node.Comments
+	case *BooleanLiteral:
+		return node.Comments
+	case *Identifier:
+		return node.Comments
+	case *BinaryOperation:
+		return node.Comments
+	case *UnaryOperation:
+		return node.Comments
+	case *FunctionCall:
+		return node.Comments
+	}
+	return nil
+}
+
+func addComment(expr Expression, comment Comment) {
+	switch node := expr.(type) {
+	case *NumberLiteral:
+		node.Comments = append(node.Comments, comment)
+	case *BooleanLiteral:
+		node.Comments = append(node.Comments, comment)
+	case *Identifier:
+		node.Comments = append(node.Comments, comment)
+	case *BinaryOperation:
+		node.Comments = append(node.Comments, comment)
+	case *UnaryOperation:
+		node.Comments = append(node.Comments, comment)
+	case *FunctionCall:
+		node.Comments = append(node.Comments, comment)
+	}
+}
+
+// ===== LEXING =====
+
+// skipTrivia skips whitespace and comments, recording each comment
+func (l *Lexer) skipTrivia() error {
+	for {
+		l.skipWhitespace()
+		if l.peek() != '/' || (l.peekNext() != '/' && l.peekNext() != '*') {
+			return nil
+		}
+
+		start := l.currentPos()
+		block := l.peekNext() == '*'
+		l.advance()
+		l.advance()
+
+		bodyStart := l.position
+		if block {
+			end := strings.Index(l.input[l.position:], "*/")
+			if end < 0 {
+				for l.position < len(l.input) {
+					l.advance()
+				}
+				return &ParseError{
+					Message:    "Unterminated block comment",
+					Range:      SourceRange{Start: start, End: l.currentPos(), Text: l.input[start.Offset:]},
+					SourceText: l.input,
+					ErrorType:  "lexical",
+					Suggestion: "Close the comment with '*/'",
+				}
+			}
+			for l.position < bodyStart+end {
+				l.advance()
+			}
+			l.advance()
+			l.advance()
+			l.comments = append(l.comments, Comment{
+				Text:  l.input[bodyStart : bodyStart+end],
+				Block: true,
+				Range: SourceRange{Start: start, End: l.currentPos(), Text: l.input[start.Offset:l.position]},
+			})
+			continue
+		}
+
+		for l.position < len(l.input) && l.peek() != '\n' {
+			l.advance()
+		}
+		text := strings.TrimRightFunc(l.input[bodyStart:l.position], unicode.IsSpace)
+		l.comments = append(l.comments, Comment{
+			Text:  text,
+			Range: SourceRange{Start: start, End: l.currentPos(), Text: l.input[start.Offset:l.position]},
+		})
+	}
+}
+
+// ===== ATTACHMENT =====
+
+// attachComments attaches each comment to the outermost node that starts
+// after it, or to the root as a trailing comment
+func attachComments(root Expression, comments []Comment) {
+	for _, comment := range comments {
+		if node := firstNodeAfter(root, comment.Range.End.Offset); node != nil {
+			addComment(node, comment)
+			continue
+		}
+		comment.Trailing = true
+		addComment(root, comment)
+	}
+}
+
+// firstNodeAfter returns the first node in pre-order starting at or after offset
+func firstNodeAfter(expr Expression, offset int) Expression {
+	if expr.GetRange().Start.Offset >= offset {
+		return expr
+	}
+
+	var children []Expression
+	switch node := expr.(type) {
+	case *BinaryOperation:
+		children = []Expression{node.Left, node.Right}
+	case *UnaryOperation:
+		children = []Expression{node.Operand}
+	case *FunctionCall:
+		children = node.Args
+	}
+	for _, child := range children {
+		if found := firstNodeAfter(child, offset); found != nil {
+			return found
+		}
+	}
+	return nil
+}
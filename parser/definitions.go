@@ -0,0 +1,159 @@
+// Package parser provides expression parsing and AST definitions
+// This file contains named definitions (`let name = expr`) and their dependency checks
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ===== DEFINITIONS =====
+
+// Definition is a named sub-expression that other expressions reference by name
+type Definition struct {
+	Name  string
+	Body  Expression
+	Range SourceRange
+}
+
+func (d *Definition) String() string {
+	return fmt.Sprintf("let %s = %s", d.Name, d.Body)
+}
+
+// ParseDefinition parses a definition of the form `let name = expr`
+func ParseDefinition(input string) (*Definition, error) {
+	parser := NewParser(input)
+	return parser.ParseDefinition()
+}
+
+// ParseDefinition parses `let name = expr` and returns the definition
+func (p *Parser) ParseDefinition() (*Definition, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	start := p.currentToken.Range.Start
+
+	if p.currentToken.Type != TOKEN_IDENTIFIER || p.currentToken.Value != "let" {
+		return nil, &ParseError{
+			Message:    "Expected 'let' at start of definition",
+			Range:      p.currentToken.Range,
+			SourceText: p.sourceText,
+			ErrorType:  "syntax",
+			Suggestion: "Write definitions as 'let name = expression'",
+		}
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	nameToken := p.currentToken
+	if nameToken.Type != TOKEN_IDENTIFIER {
+		return nil, &ParseError{
+			Message:    fmt.Sprintf("Expected definition name, got %s", nameToken.Type),
+			Range:      nameToken.Range,
+			SourceText: p.sourceText,
+			ErrorType:  "syntax",
+			Suggestion: "Definition names must be identifiers and cannot be keywords",
+		}
+	}
+
+	// A single '=' is not an expression token, so it is consumed directly
+	if err := p.lexer.skipTrivia(); err != nil {
+		return nil, err
+	}
+	if p.lexer.peek() != '=' || p.lexer.peekNext() == '=' {
+		pos := p.lexer.currentPos()
+		return nil, &ParseError{
+			Message:    fmt.Sprintf("Expected '=' after definition name '%s'", nameToken.Value),
+			Range:      SourceRange{Start: pos, End: pos},
+			SourceText: p.sourceText,
+			ErrorType:  "syntax",
+			Suggestion: "Write definitions as 'let name = expression'",
+		}
+	}
+	p.lexer.advance()
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.currentToken.Type != TOKEN_EOF {
+		return nil, &ParseError{
+			Message:    fmt.Sprintf("Unexpected token after definition: %s", p.currentToken.Type),
+			Range:      p.currentToken.Range,
+			SourceText: p.sourceText,
+			ErrorType:  "syntax",
+			Suggestion: "Remove extra tokens or check expression syntax",
+		}
+	}
+
+	attachComments(body, p.lexer.comments)
+
+	return &Definition{
+		Name:  nameToken.Value,
+		Body:  body,
+		Range: SourceRange{Start: start, End: body.GetRange().End, Text: p.sourceText},
+	}, nil
+}
+
+// ===== DEPENDENCY ORDER =====
+
+// DefinitionOrder returns definition names ordered so that every definition
+// comes after the definitions it references. A reference cycle is an error.
+func DefinitionOrder(definitions map[string]Expression) ([]string, error) {
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	order := make([]string, 0, len(names))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			// Report the cycle starting from its first occurrence on the path
+			for i, n := range path {
+				if n == name {
+					cycle := append(append([]string{}, path[i:]...), name)
+					return fmt.Errorf("definition cycle: %s", strings.Join(cycle, " -> "))
+				}
+			}
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, ref := range CollectVariables(definitions[name]) {
+			if _, ok := definitions[ref]; ok {
+				if err := visit(ref); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,374 @@
+// Package format provides canonical pretty-printing of rule expressions
+// Output uses minimal parentheses and round-trips through parser.ParseExpression
+package format
+
+import (
+	"DD/parser"
+	"strconv"
+	"strings"
+)
+
+// ===== CONFIGURATION =====
+
+// Config controls the layout of formatted expressions
+type Config struct {
+	MaxWidth int    // Preferred maximum line width; 0 disables line wrapping
+	Indent   string // Indentation used for continuation lines
+}
+
+// DefaultConfig returns the default formatting configuration
+func DefaultConfig() *Config {
+	return &Config{
+		MaxWidth: 80,
+		Indent:   "    ",
+	}
+}
+
+// ===== PRECEDENCE LEVELS =====
+
+// Precedence levels mirror the recursive-descent levels in parser.Parser,
+// from loosest to tightest binding
+const (
+	precOr         = iota + 1 // OR, ||
+	precLogical               // ->, <-> (non-associative)
+	precAnd                   // AND, &&
+	precNot                   // NOT, !
+	precComparison            // ==, !=, <, <=, >, >= (non-associative)
+	precAdditive              // +, -
+	precTerm                  // *, /, %, MIN, MAX
+	precUnary                 // unary -, +
+	precPrimary               // literals, identifiers, function calls
+)
+
+// ===== PRINTER =====
+
+// Printer formats expressions according to a Config
+type Printer struct {
+	config Config
+}
+
+// NewPrinter creates a printer with the given configuration
+func NewPrinter(config *Config) *Printer {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Printer{config: *config}
+}
+
+// ===== PUBLIC API =====
+
+// Format prints an expression canonically using the default configuration
+func Format(expr parser.Expression) string {
+	return NewPrinter(nil).Format(expr)
+}
+
+// Source parses and canonically formats an expression string, keeping its
+// annotations and comments
+func Source(input string) (string, error) {
+	annotated, err := parser.ParseAnnotatedExpression(input)
+	if err != nil {
+		return "", err
+	}
+	return NewPrinter(nil).FormatAnnotated(annotated), nil
+}
+
+// Normalize returns the canonical form of an expression string. It is a single
+// line unless the expression carries `//` comments. Input that does not parse
+// is returned unchanged so callers can store it as-is and let validation
+// report the error.
+func Normalize(input string) string {
+	annotated, err := parser.ParseAnnotatedExpression(input)
+	if err != nil {
+		return input
+	}
+	return NewPrinter(&Config{}).FormatAnnotated(annotated)
+}
+
+// Format prints an expression canonically
+func (p *Printer) Format(expr parser.Expression) string {
+	if expr == nil {
+		return ""
+	}
+	return p.format(expr, 0)
+}
+
+// FormatAnnotated prints annotations, one per line when wrapping is enabled,
+// followed by the canonically formatted expression
+func (p *Printer) FormatAnnotated(annotated *parser.AnnotatedExpression) string {
+	separator := "\n"
+	if p.config.MaxWidth <= 0 {
+		separator = " "
+	}
+
+	var result strings.Builder
+	for _, annotation := range annotated.Annotations {
+		result.WriteString(annotation.String())
+		result.WriteString(separator)
+	}
+	result.WriteString(p.Format(annotated.Expression))
+	return result.String()
+}
+
+// ===== LAYOUT =====
+
+// format renders expr at the given indentation level, wrapping when the flat
+// rendering would exceed the configured width
+func (p *Printer) format(expr parser.Expression, level int) string {
+	flat := p.flat(expr)
+	if !p.exceedsWidth(flat, level) {
+		return flat
+	}
+
+	switch node := expr.(type) {
+	case *parser.BinaryOperation:
+		if !isFunctionToken(node.Operator) {
+			return p.withComments(expr, p.formatChain(node, level), level)
+		}
+	case *parser.FunctionCall:
+		return p.withComments(expr, p.formatCall(node, level), level)
+	}
+
+	return flat
+}
+
+// formatChain breaks a binary operation with one operand per line, leading
+// each continuation line with the operator
+func (p *Printer) formatChain(node *parser.BinaryOperation, level int) string {
+	leftPrec, rightPrec := operandPrecedence(node)
+	operands := []parser.Expression{node.Left, node.Right}
+	if rightPrec > leftPrec {
+		// Left-associative operators print as a single chain
+		operands = flattenChain(node)
+	}
+	prefix := "\n" + strings.Repeat(p.config.Indent, level+1)
+
+	var result strings.Builder
+	for i, operand := range operands {
+		minPrec := leftPrec
+		if i > 0 {
+			result.WriteString(prefix)
+			result.WriteString(operatorText(node.Operator))
+			result.WriteString(" ")
+			minPrec = rightPrec
+		}
+		result.WriteString(p.operand(operand, minPrec, level+1))
+	}
+	return result.String()
+}
+
+// formatCall breaks a function call with one argument per line
+func (p *Printer) formatCall(node *parser.FunctionCall, level int) string {
+	prefix := "\n" + strings.Repeat(p.config.Indent, level+1)
+
+	var result strings.Builder
+	result.WriteString(node.Function.String())
+	result.WriteString("(")
+	for i, arg := range node.Args {
+		result.WriteString(prefix)
+		result.WriteString(p.format(arg, level+1))
+		if i < len(node.Args)-1 {
+			result.WriteString(",")
+		}
+	}
+	result.WriteString("\n")
+	result.WriteString(strings.Repeat(p.config.Indent, level))
+	result.WriteString(")")
+	return result.String()
+}
+
+// operand renders a child expression, parenthesizing it when its precedence
+// is lower than the position requires
+func (p *Printer) operand(expr parser.Expression, minPrec int, level int) string {
+	if precedence(expr) >= minPrec {
+		return p.format(expr, level)
+	}
+	return "(" + p.format(expr, level) + ")"
+}
+
+func (p *Printer) exceedsWidth(flat string, level int) bool {
+	if p.config.MaxWidth <= 0 {
+		return false
+	}
+	return len(p.config.Indent)*level+len(flat) > p.config.MaxWidth
+}
+
+// ===== FLAT RENDERING =====
+
+// flat renders an expression on a single line with minimal parentheses.
+// Only `//` comments, which must end their line, introduce line breaks.
+func (p *Printer) flat(expr parser.Expression) string {
+	return p.withComments(expr, p.flatNode(expr), 0)
+}
+
+func (p *Printer) flatNode(expr parser.Expression) string {
+	switch node := expr.(type) {
+	case *parser.NumberLiteral:
+		return formatNumber(node.Value)
+
+	case *parser.BooleanLiteral:
+		return strconv.FormatBool(node.Value)
+
+	case *parser.Identifier:
+		return node.Name
+
+	case *parser.BinaryOperation:
+		if isFunctionToken(node.Operator) {
+			return node.Operator.String() + "(" + p.flat(node.Left) + ", " + p.flat(node.Right) + ")"
+		}
+		leftPrec, rightPrec := operandPrecedence(node)
+		return p.flatOperand(node.Left, leftPrec) + " " + operatorText(node.Operator) + " " + p.flatOperand(node.Right, rightPrec)
+
+	case *parser.UnaryOperation:
+		if node.Operator == parser.TOKEN_NOT {
+			// NOT binds a comparison, so nested NOT and looser operators need parentheses
+			return "NOT " + p.flatOperand(node.Operand, precComparison)
+		}
+		// Unary +/- bind a primary only
+		return operatorText(node.Operator) + p.flatOperand(node.Operand, precPrimary)
+
+	case *parser.FunctionCall:
+		args := make([]string, len(node.Args))
+		for i, arg := range node.Args {
+			args[i] = p.flat(arg)
+		}
+		return node.Function.String() + "(" + strings.Join(args, ", ") + ")"
+	}
+
+	return expr.String()
+}
+
+func (p *Printer) flatOperand(expr parser.Expression, minPrec int) string {
+	if precedence(expr) >= minPrec {
+		return p.flat(expr)
+	}
+	return "(" + p.flat(expr) + ")"
+}
+
+// ===== COMMENTS =====
+
+// withComments surrounds a rendered node with its leading and trailing
+// comments. A `//` comment is always followed by a line break.
+func (p *Printer) withComments(expr parser.Expression, rendered string, level int) string {
+	comments := parser.CommentsOf(expr)
+	if len(comments) == 0 {
+		return rendered
+	}
+
+	var leading, trailing strings.Builder
+	for _, comment := range comments {
+		if comment.Trailing {
+			trailing.WriteString(" ")
+			trailing.WriteString(comment.String())
+			if !comment.Block {
+				trailing.WriteString("\n")
+			}
+			continue
+		}
+		leading.WriteString(comment.String())
+		if comment.Block {
+			leading.WriteString(" ")
+		} else {
+			leading.WriteString("\n")
+			leading.WriteString(strings.Repeat(p.config.Indent, level))
+		}
+	}
+	return strings.TrimSuffix(leading.String()+rendered+trailing.String(), "\n")
+}
+
+// ===== PRECEDENCE HELPERS =====
+
+// precedence returns the binding strength of the node's outermost operator
+func precedence(expr parser.Expression) int {
+	switch node := expr.(type) {
+	case *parser.BinaryOperation:
+		switch node.Operator {
+		case parser.TOKEN_OR:
+			return precOr
+		case parser.TOKEN_IMPLIES_OP, parser.TOKEN_EQUIV_OP:
+			return precLogical
+		case parser.TOKEN_AND:
+			return precAnd
+		case parser.TOKEN_EQ, parser.TOKEN_NE, parser.TOKEN_LT, parser.TOKEN_LE, parser.TOKEN_GT, parser.TOKEN_GE:
+			return precComparison
+		case parser.TOKEN_PLUS, parser.TOKEN_MINUS:
+			return precAdditive
+		case parser.TOKEN_MULTIPLY, parser.TOKEN_DIVIDE, parser.TOKEN_MODULO, parser.TOKEN_MIN, parser.TOKEN_MAX:
+			return precTerm
+		}
+		// Function-style binary operators print as calls
+		return precPrimary
+	case *parser.UnaryOperation:
+		if node.Operator == parser.TOKEN_NOT {
+			return precNot
+		}
+		return precUnary
+	case *parser.FunctionCall:
+		// ABS, NEGATE, CEIL, FLOOR and THRESHOLD are only accepted at factor
+		// level, so they cannot appear directly under a unary sign
+		switch node.Function {
+		case parser.TOKEN_ABS, parser.TOKEN_NEGATE, parser.TOKEN_CEIL, parser.TOKEN_FLOOR, parser.TOKEN_THRESHOLD:
+			return precUnary
+		}
+		return precPrimary
+	case *parser.NumberLiteral:
+		// A negative literal prints with a leading sign
+		if node.Value < 0 {
+			return precUnary
+		}
+		return precPrimary
+	default:
+		return precPrimary
+	}
+}
+
+// operandPrecedence returns the minimum precedence required of the left and
+// right operands of a binary operation
+func operandPrecedence(node *parser.BinaryOperation) (int, int) {
+	prec := precedence(node)
+	switch prec {
+	case precLogical:
+		// Non-associative; operands are parsed at AND level
+		return precAnd, precAnd
+	case precComparison:
+		// Non-associative; operands are parsed at additive level
+		return precAdditive, precAdditive
+	default:
+		// Left-associative
+		return prec, prec + 1
+	}
+}
+
+// flattenChain collects the operands of a left-nested chain of one operator
+func flattenChain(node *parser.BinaryOperation) []parser.Expression {
+	if left, ok := node.Left.(*parser.BinaryOperation); ok && left.Operator == node.Operator {
+		return append(flattenChain(left), node.Right)
+	}
+	return []parser.Expression{node.Left, node.Right}
+}
+
+// operatorText returns the canonical spelling of an operator
+func operatorText(op parser.TokenType) string {
+	switch op {
+	case parser.TOKEN_AND:
+		return "AND"
+	case parser.TOKEN_OR:
+		return "OR"
+	case parser.TOKEN_NOT:
+		return "NOT"
+	}
+	return op.String()
+}
+
+func isFunctionToken(op parser.TokenType) bool {
+	switch op {
+	case parser.TOKEN_XOR, parser.TOKEN_IMPLIES, parser.TOKEN_EQUIV:
+		return true
+	default:
+		return false
+	}
+}
+
+// formatNumber prints a number without exponent so the lexer can read it back
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
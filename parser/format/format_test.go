@@ -0,0 +1,269 @@
+package format
+
+import (
+	"DD/parser"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// TestFormatCanonical checks canonical spelling and minimal parentheses
+func TestFormatCanonical(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Symbolic AND", "a && b", "a AND b"},
+		{"Symbolic OR and NOT", "!a || b", "NOT a OR b"},
+		{"Redundant Parentheses", "((a AND b))", "a AND b"},
+		{"Precedence Kept", "(a OR b) AND c", "(a OR b) AND c"},
+		{"Left Associative Chain", "(a + b) + c", "a + b + c"},
+		{"Right Nested Chain", "a - (b - c)", "a - (b - c)"},
+		{"Arithmetic Precedence", "(x * y) + z", "x * y + z"},
+		{"Arithmetic Grouping", "(x + y) * z", "(x + y) * z"},
+		{"Implication", "(a AND b) -> c", "a AND b -> c"},
+		{"Chained Implication", "(a -> b) -> c", "(a -> b) -> c"},
+		{"Implication Under OR", "a OR (b -> c)", "a OR b -> c"},
+		{"OR Under Implication", "(a OR b) -> c", "(a OR b) -> c"},
+		{"Function Form Kept", "IMPLIES(a, b)", "IMPLIES(a, b)"},
+		{"Comparison", "(x + 1) >= 5", "x + 1 >= 5"},
+		{"Nested Comparison", "(x > 1) == true", "(x > 1) == true"},
+		{"Double Negation", "NOT (NOT a)", "NOT (NOT a)"},
+		{"NOT Over Comparison", "NOT (x > 5)", "NOT x > 5"},
+		{"NOT Over AND", "NOT (a AND b)", "NOT (a AND b)"},
+		{"Unary Minus", "-(x + y)", "-(x + y)"},
+		{"Unary Minus Of Function", "-(ABS(x))", "-(ABS(x))"},
+		{"Infix MIN", "a MIN b", "a MIN b"},
+		{"Decimal Number", "3.50 + 0.125", "3.5 + 0.125"},
+		{"Boolean Keywords", "TRUE AND FALSE", "true AND false"},
+		{"Function Arguments", "ITE(a && b, 1, 2)", "ITE(a AND b, 1, 2)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Source(tt.input)
+			if err != nil {
+				t.Fatalf("Source(%q) failed: %v", tt.input, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Source(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+			assertRoundTrip(t, tt.input, got)
+		})
+	}
+}
+
+// TestFormatLineWrapping checks that long rules are broken at operators
+func TestFormatLineWrapping(t *testing.T) {
+	input := "(cpu_i9 OR cpu_xeon OR cpu_epyc) AND ram_64 AND NOT cooling_air AND ITE(storage_nvme_2tb, psu_1000w_platinum, psu_750w_gold)"
+	printer := NewPrinter(&Config{MaxWidth: 40, Indent: "  "})
+
+	expr, err := parser.ParseExpression(input)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	got := printer.Format(expr)
+
+	expected := strings.Join([]string{
+		"(cpu_i9 OR cpu_xeon OR cpu_epyc)",
+		"  AND ram_64",
+		"  AND NOT cooling_air",
+		"  AND ITE(",
+		"    storage_nvme_2tb,",
+		"    psu_1000w_platinum,",
+		"    psu_750w_gold",
+		"  )",
+	}, "\n")
+	if got != expected {
+		t.Errorf("unexpected layout:\n%s\nexpected:\n%s", got, expected)
+	}
+
+	assertRoundTrip(t, input, got)
+	if again := printer.Format(mustParse(t, got)); again != got {
+		t.Errorf("wrapped formatting is not idempotent:\n%s\n---\n%s", got, again)
+	}
+}
+
+// TestNormalize checks single-line normalization and passthrough of invalid input
+func TestNormalize(t *testing.T) {
+	if got := Normalize("a&&(b||c)"); got != "a AND (b OR c)" {
+		t.Errorf("Normalize = %q", got)
+	}
+
+	invalid := "opt1:10.0"
+	if got := Normalize(invalid); got != invalid {
+		t.Errorf("Normalize should keep unparseable input, got %q", got)
+	}
+}
+
+// TestFormatPreservesCommentsAndAnnotations checks comments and annotations survive formatting
+func TestFormatPreservesCommentsAndAnnotations(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"/* lead */ a&&b", "/* lead */ a AND b"},
+		{"a && /* mid */ (b||c)", "a AND (/* mid */ b OR c)"},
+		{"// why\na->b", "// why\na -> b"},
+		{"a->b // trailing", "a -> b // trailing"},
+		{"@meta( owner = \"hw\" ) @deprecated a||b", "@meta(owner=\"hw\") @deprecated a OR b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := Normalize(tt.input)
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if again := Normalize(got); again != got {
+				t.Errorf("Normalize is not idempotent: %q -> %q", got, again)
+			}
+		})
+	}
+
+	wrapped, err := Source("@meta(owner=\"hw\") a")
+	if err != nil || wrapped != "@meta(owner=\"hw\")\na" {
+		t.Errorf("Source = %q, %v", wrapped, err)
+	}
+}
+
+// TestFormatRoundTripProperty checks Parse(Format(x)) == x and idempotency on random ASTs
+func TestFormatRoundTripProperty(t *testing.T) {
+	printers := []*Printer{
+		NewPrinter(&Config{}),
+		NewPrinter(&Config{MaxWidth: 30, Indent: "  "}),
+	}
+
+	property := func(seed int64) bool {
+		rng := rand.New(rand.NewSource(seed))
+		expr := randomExpression(rng, 4)
+
+		for _, printer := range printers {
+			formatted := printer.Format(expr)
+			reparsed, err := parser.ParseExpression(formatted)
+			if err != nil {
+				t.Errorf("formatted output does not parse: %q: %v", formatted, err)
+				return false
+			}
+			if !equalExpressions(expr, reparsed) {
+				t.Errorf("round trip changed AST:\n  original:  %s\n  formatted: %q\n  reparsed:  %s", expr, formatted, reparsed)
+				return false
+			}
+			if again := printer.Format(reparsed); again != formatted {
+				t.Errorf("formatting not idempotent: %q != %q", formatted, again)
+				return false
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
+
+// ===== HELPERS =====
+
+func mustParse(t *testing.T, input string) parser.Expression {
+	t.Helper()
+	expr, err := parser.ParseExpression(input)
+	if err != nil {
+		t.Fatalf("parse of %q failed: %v", input, err)
+	}
+	return expr
+}
+
+func assertRoundTrip(t *testing.T, input, formatted string) {
+	t.Helper()
+	original := mustParse(t, input)
+	reparsed := mustParse(t, formatted)
+	if !equalExpressions(original, reparsed) {
+		t.Errorf("round trip changed AST: %s vs %s", original, reparsed)
+	}
+}
+
+// equalExpressions compares two ASTs structurally, ignoring source ranges
+func equalExpressions(a, b parser.Expression) bool {
+	switch x := a.(type) {
+	case *parser.NumberLiteral:
+		y, ok := b.(*parser.NumberLiteral)
+		return ok && x.Value == y.Value
+	case *parser.BooleanLiteral:
+		y, ok := b.(*parser.BooleanLiteral)
+		return ok && x.Value == y.Value
+	case *parser.Identifier:
+		y, ok := b.(*parser.Identifier)
+		return ok && x.Name == y.Name
+	case *parser.BinaryOperation:
+		y, ok := b.(*parser.BinaryOperation)
+		return ok && x.Operator == y.Operator && equalExpressions(x.Left, y.Left) && equalExpressions(x.Right, y.Right)
+	case *parser.UnaryOperation:
+		y, ok := b.(*parser.UnaryOperation)
+		return ok && x.Operator == y.Operator && equalExpressions(x.Operand, y.Operand)
+	case *parser.FunctionCall:
+		y, ok := b.(*parser.FunctionCall)
+		if !ok || x.Function != y.Function || len(x.Args) != len(y.Args) {
+			return false
+		}
+		for i := range x.Args {
+			if !equalExpressions(x.Args[i], y.Args[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// randomExpression builds a random AST of the shapes the parser can produce
+func randomExpression(rng *rand.Rand, depth int) parser.Expression {
+	if depth == 0 || rng.Intn(4) == 0 {
+		switch rng.Intn(3) {
+		case 0:
+			return &parser.NumberLiteral{Value: float64(rng.Intn(1000)) / 8}
+		case 1:
+			return &parser.BooleanLiteral{Value: rng.Intn(2) == 0}
+		default:
+			return &parser.Identifier{Name: []string{"a", "b", "cpu_i9", "ram_64", "x1"}[rng.Intn(5)]}
+		}
+	}
+
+	binaryOps := []parser.TokenType{
+		parser.TOKEN_OR, parser.TOKEN_AND, parser.TOKEN_IMPLIES_OP, parser.TOKEN_EQUIV_OP,
+		parser.TOKEN_EQ, parser.TOKEN_NE, parser.TOKEN_LT, parser.TOKEN_LE, parser.TOKEN_GT, parser.TOKEN_GE,
+		parser.TOKEN_PLUS, parser.TOKEN_MINUS, parser.TOKEN_MULTIPLY, parser.TOKEN_DIVIDE, parser.TOKEN_MODULO,
+		parser.TOKEN_MIN, parser.TOKEN_MAX,
+	}
+	functions := []struct {
+		token parser.TokenType
+		arity int
+	}{
+		{parser.TOKEN_ABS, 1}, {parser.TOKEN_NEGATE, 1}, {parser.TOKEN_CEIL, 1}, {parser.TOKEN_FLOOR, 1},
+		{parser.TOKEN_THRESHOLD, 2}, {parser.TOKEN_IMPLIES, 2}, {parser.TOKEN_EQUIV, 2}, {parser.TOKEN_XOR, 2},
+		{parser.TOKEN_MIN, 2}, {parser.TOKEN_MAX, 2}, {parser.TOKEN_ITE, 3},
+	}
+
+	switch rng.Intn(3) {
+	case 0:
+		return &parser.BinaryOperation{
+			Left:     randomExpression(rng, depth-1),
+			Operator: binaryOps[rng.Intn(len(binaryOps))],
+			Right:    randomExpression(rng, depth-1),
+		}
+	case 1:
+		unaryOps := []parser.TokenType{parser.TOKEN_NOT, parser.TOKEN_MINUS, parser.TOKEN_PLUS}
+		return &parser.UnaryOperation{
+			Operator: unaryOps[rng.Intn(len(unaryOps))],
+			Operand:  randomExpression(rng, depth-1),
+		}
+	default:
+		fn := functions[rng.Intn(len(functions))]
+		args := make([]parser.Expression, fn.arity)
+		for i := range args {
+			args[i] = randomExpression(rng, depth-1)
+		}
+		return &parser.FunctionCall{Function: fn.token, Args: args}
+	}
+}
@@ -18,6 +18,7 @@ type Lexer struct {
 	column   int
 	startPos Position
 	keywords map[string]TokenType
+	comments []Comment // Comments skipped so far, in source order
 }
 
 func NewLexer(input string) *Lexer {
@@ -140,7 +141,9 @@ func (l *Lexer) makeToken(tokenType TokenType, value string) Token {
 }
 
 func (l *Lexer) NextToken() (Token, error) {
-	l.skipWhitespace()
+	if err := l.skipTrivia(); err != nil {
+		return Token{}, err
+	}
 
 	if l.position >= len(l.input) {
 		return Token{
@@ -296,6 +299,7 @@ type Parser struct {
 	lexer        *Lexer
 	currentToken Token
 	sourceText   string
+	annotations  []Annotation
 }
 
 func NewParser(input string) *Parser {
@@ -337,9 +341,16 @@ func (p *Parser) match(tokenTypes ...TokenType) bool {
 	return false
 }
 
-// Parse parses the input and returns the AST root
+// Parse parses the input and returns the AST root. Leading annotations are
+// kept on the parser and comments are attached to the AST.
 func (p *Parser) Parse() (Expression, error) {
-	err := p.advance() // Load first token
+	annotations, err := p.parseAnnotations()
+	if err != nil {
+		return nil, err
+	}
+	p.annotations = annotations
+
+	err = p.advance() // Load first token
 	if err != nil {
 		return nil, err
 	}
@@ -359,6 +370,7 @@ func (p *Parser) Parse() (Expression, error) {
 		}
 	}
 
+	attachComments(expr, p.lexer.comments)
 	return expr, nil
 }
 
@@ -935,3 +947,11 @@ func ParseExpression(input string) (Expression, error) {
 	parser := NewParser(input)
 	return parser.Parse()
 }
+
+// ParseFormula parses a price formula. Formulas also accept IF as an alias
+// of ITE; everywhere else IF is an ordinary identifier.
+func ParseFormula(input string) (Expression, error) {
+	parser := NewParser(input)
+	parser.lexer.keywords["IF"] = TOKEN_ITE
+	return parser.Parse()
+}
@@ -43,12 +43,18 @@ type ParseError struct {
 	SourceText string // Full source text for context display
 	Suggestion string // Optional suggestion for fixing the error
 	ErrorType  string // Category of error: "syntax", "semantic", "lexical", "mtbdd"
+	Err        error  // Optional underlying error, for errors.Is
 }
 
 func (e *ParseError) Error() string {
 	return fmt.Sprintf("Parse error at %s: %s", e.Range, e.Message)
 }
 
+// Unwrap returns the underlying error, if any
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
 // ShowError displays a user-friendly error with source context
 func (e *ParseError) ShowError() string {
 	var result strings.Builder
@@ -56,34 +62,56 @@ func (e *ParseError) ShowError() string {
 	result.WriteString(fmt.Sprintf("Error: %s\n", e.Message))
 	result.WriteString(fmt.Sprintf("  --> %s\n", e.Range))
 
-	// Show source context
+	// Show source context, underlining every line the range spans
 	lines := strings.Split(e.SourceText, "\n")
 	if e.Range.Start.Line > 0 && e.Range.Start.Line <= len(lines) {
-		lineNum := e.Range.Start.Line
-		sourceLine := lines[lineNum-1]
+		endLine := e.Range.End.Line
+		if endLine < e.Range.Start.Line {
+			endLine = e.Range.Start.Line
+		}
+		if endLine > len(lines) {
+			endLine = len(lines)
+		}
+		width := len(fmt.Sprint(endLine))
+		if width < 2 {
+			width = 2
+		}
+		gutter := strings.Repeat(" ", width)
 
-		result.WriteString(fmt.Sprintf("   |\n"))
-		result.WriteString(fmt.Sprintf("%2d | %s\n", lineNum, sourceLine))
-		result.WriteString(fmt.Sprintf("   | "))
+		result.WriteString(fmt.Sprintf("%s |\n", gutter))
+		for lineNum := e.Range.Start.Line; lineNum <= endLine; lineNum++ {
+			sourceLine := lines[lineNum-1]
+			result.WriteString(fmt.Sprintf("%*d | %s\n", width, lineNum, sourceLine))
 
-		// Add pointer to error location
-		for i := 0; i < e.Range.Start.Column-1; i++ {
-			if i < len(sourceLine) && sourceLine[i] == '\t' {
-				result.WriteString("\t")
+			// Columns are 1-based; the end column is exclusive
+			startCol, endCol := 1, len(sourceLine)+1
+			if lineNum == e.Range.Start.Line {
+				startCol = e.Range.Start.Column
 			} else {
-				result.WriteString(" ")
+				startCol += len(sourceLine) - len(strings.TrimLeft(sourceLine, " \t"))
+			}
+			if lineNum == e.Range.End.Line {
+				endCol = e.Range.End.Column
+			}
+			if endCol <= startCol {
+				if lineNum != e.Range.Start.Line {
+					continue
+				}
+				endCol = startCol + 1
 			}
-		}
 
-		// Show error range
-		errorLen := e.Range.End.Column - e.Range.Start.Column
-		if errorLen <= 0 {
-			errorLen = 1
-		}
-		for i := 0; i < errorLen; i++ {
-			result.WriteString("^")
+			result.WriteString(fmt.Sprintf("%s | ", gutter))
+			// Add pointer to error location
+			for i := 0; i < startCol-1; i++ {
+				if i < len(sourceLine) && sourceLine[i] == '\t' {
+					result.WriteString("\t")
+				} else {
+					result.WriteString(" ")
+				}
+			}
+			result.WriteString(strings.Repeat("^", endCol-startCol))
+			result.WriteString("\n")
 		}
-		result.WriteString("\n")
 	}
 
 	if e.Suggestion != "" {
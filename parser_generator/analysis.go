@@ -0,0 +1,774 @@
+package parser_generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// ===== GRAMMAR ANALYSIS =====
+
+// EndOfInput is the terminal that follows the start rule in FOLLOW sets
+const EndOfInput = "EOF"
+
+// GrammarAnalysis reports the FIRST/FOLLOW sets of a grammar and the
+// problems they reveal. Terminals are token names; literals used in rules
+// without a declaring token appear quoted.
+type GrammarAnalysis struct {
+	Grammar           string                `json:"grammar"`
+	Rules             []RuleAnalysis        `json:"rules"`
+	LeftRecursion     [][]string            `json:"leftRecursion"` // Cycles such as [a b a]
+	UnreachableRules  []string              `json:"unreachableRules"`
+	UnreachableTokens []string              `json:"unreachableTokens"`
+	Shadowed          []ShadowedAlternative `json:"shadowed"`
+	Conflicts         []LL1Conflict         `json:"conflicts"`
+}
+
+// RuleAnalysis holds the sets computed for one rule
+type RuleAnalysis struct {
+	Rule     string   `json:"rule"`
+	Nullable bool     `json:"nullable"`
+	First    []string `json:"first"`
+	Follow   []string `json:"follow"`
+}
+
+// ShadowedAlternative is a choice alternative a PEG parser never matches
+// because an earlier alternative succeeds on every input it would accept
+type ShadowedAlternative struct {
+	Rule        string `json:"rule"`
+	Choice      string `json:"choice"`
+	Alternative int    `json:"alternative"` // 1-based
+	By          int    `json:"by"`          // 1-based index of the earlier alternative
+	Reason      string `json:"reason"`
+}
+
+// LL1Conflict is a decision one token of lookahead cannot make. Between
+// choice alternatives the PEG parser takes the earlier one; for ?, * and +
+// it keeps matching, so the conflict may hide input the grammar meant for
+// what follows.
+type LL1Conflict struct {
+	Rule         string   `json:"rule"`
+	Expression   string   `json:"expression"`
+	Alternatives []int    `json:"alternatives"` // 1-based; nil for ?, * and +
+	Tokens       []string `json:"tokens"`
+}
+
+// HasErrors reports problems that make the generated parser loop forever
+func (a *GrammarAnalysis) HasErrors() bool {
+	return len(a.LeftRecursion) > 0
+}
+
+// HasWarnings reports problems the generated parser tolerates
+func (a *GrammarAnalysis) HasWarnings() bool {
+	return len(a.UnreachableRules) > 0 || len(a.UnreachableTokens) > 0 ||
+		len(a.Shadowed) > 0 || len(a.Conflicts) > 0
+}
+
+// String renders the analysis as a table for the command line
+func (a *GrammarAnalysis) String() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "Grammar analysis: %s\n\n", a.Grammar)
+
+	table := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "RULE\tNULLABLE\tFIRST\tFOLLOW")
+	for _, rule := range a.Rules {
+		nullable := "no"
+		if rule.Nullable {
+			nullable = "yes"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", rule.Rule, nullable,
+			strings.Join(rule.First, " "), strings.Join(rule.Follow, " "))
+	}
+	table.Flush()
+	buf.WriteString("\n")
+
+	section := func(title string, lines []string) {
+		if len(lines) == 0 {
+			fmt.Fprintf(&buf, "%s: none\n", title)
+			return
+		}
+		fmt.Fprintf(&buf, "%s:\n", title)
+		for _, line := range lines {
+			fmt.Fprintf(&buf, "  %s\n", line)
+		}
+	}
+
+	var cycles []string
+	for _, cycle := range a.LeftRecursion {
+		cycles = append(cycles, strings.Join(cycle, " -> "))
+	}
+	section("Left recursion", cycles)
+	section("Unreachable rules", a.UnreachableRules)
+	section("Unreachable tokens", a.UnreachableTokens)
+
+	var shadowed []string
+	for _, s := range a.Shadowed {
+		shadowed = append(shadowed, fmt.Sprintf("%s: alternative %d of (%s) is shadowed by alternative %d, %s",
+			s.Rule, s.Alternative, s.Choice, s.By, s.Reason))
+	}
+	section("Shadowed alternatives", shadowed)
+
+	var conflicts []string
+	for _, c := range a.Conflicts {
+		tokens := strings.Join(c.Tokens, " ")
+		if c.Alternatives == nil {
+			conflicts = append(conflicts, fmt.Sprintf("%s: %s can continue or stop on %s", c.Rule, c.Expression, tokens))
+		} else {
+			conflicts = append(conflicts, fmt.Sprintf("%s: alternatives %d and %d of (%s) both start with %s",
+				c.Rule, c.Alternatives[0], c.Alternatives[1], c.Expression, tokens))
+		}
+	}
+	section("LL(1) conflicts", conflicts)
+
+	return buf.String()
+}
+
+// termSet is a set of terminal names
+type termSet map[string]bool
+
+// addAll adds the terminals of other and reports whether the set grew
+func (s termSet) addAll(other termSet) bool {
+	changed := false
+	for term := range other {
+		if !s[term] {
+			s[term] = true
+			changed = true
+		}
+	}
+	return changed
+}
+
+// grammarAnalyzer computes the fixed points behind a GrammarAnalysis
+type grammarAnalyzer struct {
+	grammar    *Grammar
+	rules      map[string]*Rule
+	literals   map[string]string // Literal value to declaring token name
+	order      map[string]int    // Terminal sort order
+	nullable   map[string]bool
+	succeeds   map[string]bool // Rules that match every input
+	first      map[string]termSet
+	follow     map[string]termSet
+	nodeFollow map[Expression]termSet
+}
+
+// AnalyzeGrammar computes FIRST/FOLLOW/nullable sets for every rule and
+// reports left recursion through nullable prefixes, unreachable rules and
+// tokens, shadowed PEG alternatives and LL(1) conflicts
+func AnalyzeGrammar(grammar *Grammar) (*GrammarAnalysis, error) {
+	if grammar == nil {
+		return nil, fmt.Errorf("grammar is nil")
+	}
+	a := newGrammarAnalyzer(grammar)
+	if a.rules[grammar.StartRule] == nil {
+		return nil, fmt.Errorf("start rule '%s' not found", grammar.StartRule)
+	}
+	for _, rule := range grammar.Rules {
+		if rule.Expression == nil {
+			return nil, fmt.Errorf("rule '%s': rule must have an expression", rule.Name)
+		}
+		var err error
+		visitExpression(rule.Expression, func(expr Expression) {
+			if ref, ok := expr.(*RuleRef); ok && err == nil &&
+				a.rules[ref.Name] == nil && grammar.GetTokenByName(ref.Name) == nil {
+				err = fmt.Errorf("rule '%s': undefined rule or token: %s", rule.Name, ref.Name)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	a.computeFirst()
+	a.computeFollow()
+
+	analysis := &GrammarAnalysis{Grammar: grammar.Name}
+	for _, rule := range grammar.Rules {
+		analysis.Rules = append(analysis.Rules, RuleAnalysis{
+			Rule:     rule.Name,
+			Nullable: a.nullable[rule.Name],
+			First:    a.sorted(a.first[rule.Name]),
+			Follow:   a.sorted(a.follow[rule.Name]),
+		})
+	}
+	analysis.LeftRecursion = a.leftRecursion()
+	analysis.UnreachableRules, analysis.UnreachableTokens = a.unreachable()
+	for _, rule := range grammar.Rules {
+		a.checkDecisions(rule.Name, rule.Expression, analysis)
+	}
+	return analysis, nil
+}
+
+func newGrammarAnalyzer(grammar *Grammar) *grammarAnalyzer {
+	a := &grammarAnalyzer{
+		grammar:    grammar,
+		rules:      make(map[string]*Rule),
+		literals:   make(map[string]string),
+		order:      make(map[string]int),
+		nullable:   make(map[string]bool),
+		succeeds:   make(map[string]bool),
+		first:      make(map[string]termSet),
+		follow:     make(map[string]termSet),
+		nodeFollow: make(map[Expression]termSet),
+	}
+	for _, rule := range grammar.Rules {
+		a.rules[rule.Name] = rule
+		a.first[rule.Name] = termSet{}
+		a.follow[rule.Name] = termSet{}
+	}
+	for i, token := range grammar.Tokens {
+		a.order[token.Name] = i
+		if token.Literal != "" && !token.Fragment {
+			if _, ok := a.literals[token.Literal]; !ok {
+				a.literals[token.Literal] = token.Name
+			}
+		}
+	}
+	for i, literal := range implicitLiterals(grammar) {
+		a.order[a.terminal(&Literal{Value: literal})] = len(grammar.Tokens) + i
+	}
+	a.order[EndOfInput] = len(a.order)
+	return a
+}
+
+// visitExpression calls visit on expr and every expression nested in it
+func visitExpression(expr Expression, visit func(Expression)) {
+	visit(expr)
+	switch e := expr.(type) {
+	case *Sequence:
+		for _, item := range e.Items {
+			visitExpression(item, visit)
+		}
+	case *Choice:
+		for _, alt := range e.Alternatives {
+			visitExpression(alt, visit)
+		}
+	case *Group:
+		visitExpression(e.Expression, visit)
+	case *Optional:
+		visitExpression(e.Expression, visit)
+	case *Repetition:
+		visitExpression(e.Expression, visit)
+	case *Predicate:
+		visitExpression(e.Expression, visit)
+	}
+}
+
+// terminal names the token a reference or literal matches
+func (a *grammarAnalyzer) terminal(expr Expression) string {
+	switch e := expr.(type) {
+	case *RuleRef:
+		return e.Name
+	case *Literal:
+		if name, ok := a.literals[e.Value]; ok {
+			return name
+		}
+		return "'" + e.Value + "'"
+	}
+	return ""
+}
+
+// sorted lists terminals in declaration order with EOF last
+func (a *grammarAnalyzer) sorted(set termSet) []string {
+	terms := make([]string, 0, len(set))
+	for term := range set {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		oi, iok := a.order[terms[i]]
+		oj, jok := a.order[terms[j]]
+		if iok != jok {
+			return iok
+		}
+		if oi != oj {
+			return oi < oj
+		}
+		return terms[i] < terms[j]
+	})
+	return terms
+}
+
+// isNullable reports whether expr can match without consuming a token.
+// Predicates never consume.
+func (a *grammarAnalyzer) isNullable(expr Expression) bool {
+	switch e := expr.(type) {
+	case *RuleRef:
+		return a.nullable[e.Name]
+	case *Sequence:
+		return a.allNullable(e.Items)
+	case *Choice:
+		for _, alt := range e.Alternatives {
+			if a.isNullable(alt) {
+				return true
+			}
+		}
+		return false
+	case *Group:
+		return a.isNullable(e.Expression)
+	case *Optional, *Predicate:
+		return true
+	case *Repetition:
+		return e.Min == 0 || a.isNullable(e.Expression)
+	}
+	return false
+}
+
+func (a *grammarAnalyzer) allNullable(items []Expression) bool {
+	for _, item := range items {
+		if !a.isNullable(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// alwaysSucceeds reports whether expr matches at every position, so a PEG
+// choice never tries the alternatives after it
+func (a *grammarAnalyzer) alwaysSucceeds(expr Expression) bool {
+	switch e := expr.(type) {
+	case *RuleRef:
+		return a.succeeds[e.Name]
+	case *Sequence:
+		for _, item := range e.Items {
+			if !a.alwaysSucceeds(item) {
+				return false
+			}
+		}
+		return true
+	case *Choice:
+		for _, alt := range e.Alternatives {
+			if a.alwaysSucceeds(alt) {
+				return true
+			}
+		}
+		return false
+	case *Group:
+		return a.alwaysSucceeds(e.Expression)
+	case *Optional:
+		return true
+	case *Repetition:
+		return e.Min == 0 || a.alwaysSucceeds(e.Expression)
+	case *Predicate:
+		return e.Positive && a.alwaysSucceeds(e.Expression)
+	}
+	return false
+}
+
+// firstOf returns the terminals expr can start with
+func (a *grammarAnalyzer) firstOf(expr Expression) termSet {
+	set := termSet{}
+	switch e := expr.(type) {
+	case *RuleRef:
+		if a.rules[e.Name] != nil {
+			set.addAll(a.first[e.Name])
+		} else {
+			set[a.terminal(e)] = true
+		}
+	case *Literal:
+		set[a.terminal(e)] = true
+	case *Sequence:
+		set.addAll(a.firstOfSequence(e.Items))
+	case *Choice:
+		for _, alt := range e.Alternatives {
+			set.addAll(a.firstOf(alt))
+		}
+	case *Group:
+		set.addAll(a.firstOf(e.Expression))
+	case *Optional:
+		set.addAll(a.firstOf(e.Expression))
+	case *Repetition:
+		set.addAll(a.firstOf(e.Expression))
+	}
+	return set
+}
+
+func (a *grammarAnalyzer) firstOfSequence(items []Expression) termSet {
+	set := termSet{}
+	for _, item := range items {
+		set.addAll(a.firstOf(item))
+		if !a.isNullable(item) {
+			break
+		}
+	}
+	return set
+}
+
+// computeFirst iterates nullable, always-succeeds and FIRST to a fixed point
+func (a *grammarAnalyzer) computeFirst() {
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range a.grammar.Rules {
+			if !a.nullable[rule.Name] && a.isNullable(rule.Expression) {
+				a.nullable[rule.Name] = true
+				changed = true
+			}
+			if !a.succeeds[rule.Name] && a.alwaysSucceeds(rule.Expression) {
+				a.succeeds[rule.Name] = true
+				changed = true
+			}
+			if a.first[rule.Name].addAll(a.firstOf(rule.Expression)) {
+				changed = true
+			}
+		}
+	}
+}
+
+// computeFollow iterates FOLLOW to a fixed point, recording the follow set
+// of every expression node for the LL(1) checks
+func (a *grammarAnalyzer) computeFollow() {
+	a.follow[a.grammar.StartRule][EndOfInput] = true
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range a.grammar.Rules {
+			if a.followExpression(rule.Expression, a.follow[rule.Name]) {
+				changed = true
+			}
+		}
+	}
+}
+
+func (a *grammarAnalyzer) followExpression(expr Expression, follow termSet) bool {
+	if a.nodeFollow[expr] == nil {
+		a.nodeFollow[expr] = termSet{}
+	}
+	changed := a.nodeFollow[expr].addAll(follow)
+
+	switch e := expr.(type) {
+	case *RuleRef:
+		if a.rules[e.Name] != nil && a.follow[e.Name].addAll(follow) {
+			changed = true
+		}
+	case *Sequence:
+		for i, item := range e.Items {
+			next := a.firstOfSequence(e.Items[i+1:])
+			if a.allNullable(e.Items[i+1:]) {
+				next.addAll(follow)
+			}
+			if a.followExpression(item, next) {
+				changed = true
+			}
+		}
+	case *Choice:
+		for _, alt := range e.Alternatives {
+			if a.followExpression(alt, follow) {
+				changed = true
+			}
+		}
+	case *Repetition:
+		next := a.firstOf(e.Expression)
+		next.addAll(follow)
+		if a.followExpression(e.Expression, next) {
+			changed = true
+		}
+	case *Group:
+		changed = a.followExpression(e.Expression, follow) || changed
+	case *Optional:
+		changed = a.followExpression(e.Expression, follow) || changed
+	case *Predicate:
+		changed = a.followExpression(e.Expression, follow) || changed
+	}
+	return changed
+}
+
+// leftRefs returns the rules expr can reach without consuming a token
+func (a *grammarAnalyzer) leftRefs(expr Expression) []string {
+	switch e := expr.(type) {
+	case *RuleRef:
+		if a.rules[e.Name] != nil {
+			return []string{e.Name}
+		}
+	case *Sequence:
+		var refs []string
+		for _, item := range e.Items {
+			refs = append(refs, a.leftRefs(item)...)
+			if !a.isNullable(item) {
+				break
+			}
+		}
+		return refs
+	case *Choice:
+		var refs []string
+		for _, alt := range e.Alternatives {
+			refs = append(refs, a.leftRefs(alt)...)
+		}
+		return refs
+	case *Group:
+		return a.leftRefs(e.Expression)
+	case *Optional:
+		return a.leftRefs(e.Expression)
+	case *Repetition:
+		return a.leftRefs(e.Expression)
+	case *Predicate:
+		return a.leftRefs(e.Expression)
+	}
+	return nil
+}
+
+// leftRecursion finds one cycle per strongly connected component of the
+// left-reference graph, starting at the component's first declared rule
+func (a *grammarAnalyzer) leftRecursion() [][]string {
+	edges := make(map[string][]string)
+	for _, rule := range a.grammar.Rules {
+		edges[rule.Name] = a.leftRefs(rule.Expression)
+	}
+
+	// Tarjan's algorithm
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	component := make(map[string]int)
+	var stack []string
+	components := 0
+	var connect func(string)
+	connect = func(name string) {
+		index[name] = len(index)
+		lowlink[name] = index[name]
+		stack = append(stack, name)
+		onStack[name] = true
+		for _, next := range edges[name] {
+			if _, seen := index[next]; !seen {
+				connect(next)
+				lowlink[name] = min(lowlink[name], lowlink[next])
+			} else if onStack[next] {
+				lowlink[name] = min(lowlink[name], index[next])
+			}
+		}
+		if lowlink[name] == index[name] {
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component[top] = components
+				if top == name {
+					break
+				}
+			}
+			components++
+		}
+	}
+	for _, rule := range a.grammar.Rules {
+		if _, seen := index[rule.Name]; !seen {
+			connect(rule.Name)
+		}
+	}
+
+	var cycles [][]string
+	reported := make(map[int]bool)
+	for _, rule := range a.grammar.Rules {
+		start := rule.Name
+		if reported[component[start]] {
+			continue
+		}
+		// Breadth-first search for the shortest path back to start
+		previous := map[string]string{}
+		queue := []string{start}
+		found := false
+		for len(queue) > 0 && !found {
+			name := queue[0]
+			queue = queue[1:]
+			for _, next := range edges[name] {
+				if component[next] != component[start] {
+					continue
+				}
+				if next == start {
+					previous[start] = name
+					found = true
+					break
+				}
+				if _, seen := previous[next]; !seen {
+					previous[next] = name
+					queue = append(queue, next)
+				}
+			}
+		}
+		if !found {
+			continue
+		}
+		reported[component[start]] = true
+		cycle := []string{start}
+		for name := previous[start]; name != start; name = previous[name] {
+			cycle = append(cycle, name)
+		}
+		cycle = append(cycle, start)
+		for i, j := 1, len(cycle)-2; i < j; i, j = i+1, j-1 {
+			cycle[i], cycle[j] = cycle[j], cycle[i]
+		}
+		cycles = append(cycles, cycle)
+	}
+	return cycles
+}
+
+// unreachable lists the rules the start rule never references and the
+// tokens no reachable rule uses. Skip and fragment tokens are never used
+// by rules, so they are not reported.
+func (a *grammarAnalyzer) unreachable() ([]string, []string) {
+	reachable := make(map[string]bool)
+	used := make(map[string]bool)
+	var reach func(string)
+	reach = func(name string) {
+		if reachable[name] {
+			return
+		}
+		reachable[name] = true
+		visitExpression(a.rules[name].Expression, func(expr Expression) {
+			switch e := expr.(type) {
+			case *RuleRef:
+				if a.rules[e.Name] != nil {
+					reach(e.Name)
+				} else {
+					used[e.Name] = true
+				}
+			case *Literal:
+				used[a.terminal(e)] = true
+			}
+		})
+	}
+	reach(a.grammar.StartRule)
+
+	var rules, tokens []string
+	for _, rule := range a.grammar.Rules {
+		if !reachable[rule.Name] {
+			rules = append(rules, rule.Name)
+		}
+	}
+	for _, token := range a.grammar.Tokens {
+		if !token.Skip && !token.Fragment && !used[token.Name] {
+			tokens = append(tokens, token.Name)
+		}
+	}
+	return rules, tokens
+}
+
+// checkDecisions reports shadowed alternatives and LL(1) conflicts in the
+// choices, options and repetitions of expr
+func (a *grammarAnalyzer) checkDecisions(rule string, expr Expression, analysis *GrammarAnalysis) {
+	switch e := expr.(type) {
+	case *Choice:
+		a.checkChoice(rule, e, analysis)
+	case *Optional:
+		a.checkLoop(rule, e, e.Expression, analysis)
+	case *Repetition:
+		if e.Max != e.Min {
+			a.checkLoop(rule, e, e.Expression, analysis)
+		}
+	}
+
+	switch e := expr.(type) {
+	case *Sequence:
+		for _, item := range e.Items {
+			a.checkDecisions(rule, item, analysis)
+		}
+	case *Choice:
+		for _, alt := range e.Alternatives {
+			a.checkDecisions(rule, alt, analysis)
+		}
+	case *Group:
+		a.checkDecisions(rule, e.Expression, analysis)
+	case *Optional:
+		a.checkDecisions(rule, e.Expression, analysis)
+	case *Repetition:
+		a.checkDecisions(rule, e.Expression, analysis)
+	case *Predicate:
+		a.checkDecisions(rule, e.Expression, analysis)
+	}
+}
+
+func (a *grammarAnalyzer) checkChoice(rule string, choice *Choice, analysis *GrammarAnalysis) {
+	alts := choice.Alternatives
+	lookahead := make([]termSet, len(alts))
+	for i, alt := range alts {
+		lookahead[i] = a.firstOf(alt)
+		if a.isNullable(alt) {
+			lookahead[i].addAll(a.nodeFollow[choice])
+		}
+	}
+
+	for j := range alts {
+		for i := 0; i < j; i++ {
+			if reason := a.shadows(alts[i], alts[j]); reason != "" {
+				analysis.Shadowed = append(analysis.Shadowed, ShadowedAlternative{
+					Rule:        rule,
+					Choice:      choice.String(),
+					Alternative: j + 1,
+					By:          i + 1,
+					Reason:      reason,
+				})
+				break
+			}
+		}
+	}
+
+	for i := range alts {
+		for j := i + 1; j < len(alts); j++ {
+			common := termSet{}
+			for term := range lookahead[i] {
+				if lookahead[j][term] {
+					common[term] = true
+				}
+			}
+			if len(common) > 0 {
+				analysis.Conflicts = append(analysis.Conflicts, LL1Conflict{
+					Rule:         rule,
+					Expression:   choice.String(),
+					Alternatives: []int{i + 1, j + 1},
+					Tokens:       a.sorted(common),
+				})
+			}
+		}
+	}
+}
+
+// checkLoop reports the tokens on which ?, * or + cannot tell whether to
+// match again or stop
+func (a *grammarAnalyzer) checkLoop(rule string, loop, body Expression, analysis *GrammarAnalysis) {
+	follow := a.nodeFollow[loop]
+	common := termSet{}
+	for term := range a.firstOf(body) {
+		if follow[term] {
+			common[term] = true
+		}
+	}
+	if len(common) > 0 {
+		analysis.Conflicts = append(analysis.Conflicts, LL1Conflict{
+			Rule:       rule,
+			Expression: loop.String(),
+			Tokens:     a.sorted(common),
+		})
+	}
+}
+
+// shadows explains why a PEG choice never reaches later after trying
+// earlier, or returns "" when it may
+func (a *grammarAnalyzer) shadows(earlier, later Expression) string {
+	if a.alwaysSucceeds(earlier) {
+		return "which always succeeds"
+	}
+	prefix, items := sequenceItems(earlier), sequenceItems(later)
+	if len(prefix) > len(items) {
+		return ""
+	}
+	for i := range prefix {
+		if prefix[i].String() != items[i].String() {
+			return ""
+		}
+	}
+	if len(prefix) == len(items) {
+		return "which is identical"
+	}
+	return "which matches a prefix of it"
+}
+
+// sequenceItems flattens an alternative into the items it matches in order
+func sequenceItems(expr Expression) []Expression {
+	switch e := expr.(type) {
+	case *Sequence:
+		var items []Expression
+		for _, item := range e.Items {
+			items = append(items, sequenceItems(item)...)
+		}
+		return items
+	case *Group:
+		return sequenceItems(e.Expression)
+	}
+	return []Expression{expr}
+}
@@ -0,0 +1,199 @@
+package parser_generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const analysisTestGrammar = `%name "Analysis Test"
+%start program
+
+%token NUMBER     /\d+/
+%token IDENTIFIER /[a-z]+/
+%token ASSIGN     "="
+%token SEMICOLON  ";"
+%token COMMA      ","
+%token MINUS      "-"
+%token UNUSED     "?"
+%token WHITESPACE /\s+/ skip
+
+program -> statement*
+statement -> IDENTIFIER ASSIGN value SEMICOLON
+  | value SEMICOLON
+  | IDENTIFIER ASSIGN value SEMICOLON
+value -> NUMBER | IDENTIFIER | NUMBER list
+list -> (COMMA NUMBER)* COMMA?
+sign -> MINUS? sign NUMBER
+orphan -> NUMBER ":"
+`
+
+// TestAnalyzeGrammar tests FIRST/FOLLOW sets and the reported problems
+func TestAnalyzeGrammar(t *testing.T) {
+	grammar, err := NewGrammarParser(analysisTestGrammar).ParseGrammar()
+	if err != nil {
+		t.Fatalf("Failed to parse grammar: %v", err)
+	}
+	analysis, err := AnalyzeGrammar(grammar)
+	if err != nil {
+		t.Fatalf("AnalyzeGrammar failed: %v", err)
+	}
+
+	sets := make(map[string]RuleAnalysis)
+	for _, rule := range analysis.Rules {
+		sets[rule.Rule] = rule
+	}
+	expectedSets := []RuleAnalysis{
+		{Rule: "program", Nullable: true, First: []string{"NUMBER", "IDENTIFIER"}, Follow: []string{"EOF"}},
+		{Rule: "statement", First: []string{"NUMBER", "IDENTIFIER"}, Follow: []string{"NUMBER", "IDENTIFIER", "EOF"}},
+		{Rule: "value", First: []string{"NUMBER", "IDENTIFIER"}, Follow: []string{"SEMICOLON"}},
+		{Rule: "list", Nullable: true, First: []string{"COMMA"}, Follow: []string{"SEMICOLON"}},
+		{Rule: "orphan", First: []string{"NUMBER"}, Follow: []string{}},
+	}
+	for _, expected := range expectedSets {
+		if got := sets[expected.Rule]; !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, got)
+		}
+	}
+
+	if !reflect.DeepEqual(analysis.LeftRecursion, [][]string{{"sign", "sign"}}) {
+		t.Errorf("Unexpected left recursion: %v", analysis.LeftRecursion)
+	}
+	if !reflect.DeepEqual(analysis.UnreachableRules, []string{"sign", "orphan"}) {
+		t.Errorf("Unexpected unreachable rules: %v", analysis.UnreachableRules)
+	}
+	if !reflect.DeepEqual(analysis.UnreachableTokens, []string{"MINUS", "UNUSED"}) {
+		t.Errorf("Unexpected unreachable tokens: %v", analysis.UnreachableTokens)
+	}
+
+	expectedShadowed := []ShadowedAlternative{
+		{Rule: "statement", Choice: "IDENTIFIER ASSIGN value SEMICOLON | value SEMICOLON | IDENTIFIER ASSIGN value SEMICOLON",
+			Alternative: 3, By: 1, Reason: "which is identical"},
+		{Rule: "value", Choice: "NUMBER | IDENTIFIER | NUMBER list", Alternative: 3, By: 1, Reason: "which matches a prefix of it"},
+	}
+	if !reflect.DeepEqual(analysis.Shadowed, expectedShadowed) {
+		t.Errorf("Unexpected shadowed alternatives:\n%+v", analysis.Shadowed)
+	}
+
+	var conflicts []string
+	for _, c := range analysis.Conflicts {
+		conflicts = append(conflicts, c.Rule+" "+c.Expression+" "+strings.Join(c.Tokens, ","))
+	}
+	expectedConflicts := []string{
+		"statement IDENTIFIER ASSIGN value SEMICOLON | value SEMICOLON | IDENTIFIER ASSIGN value SEMICOLON IDENTIFIER",
+		"statement IDENTIFIER ASSIGN value SEMICOLON | value SEMICOLON | IDENTIFIER ASSIGN value SEMICOLON IDENTIFIER",
+		"statement IDENTIFIER ASSIGN value SEMICOLON | value SEMICOLON | IDENTIFIER ASSIGN value SEMICOLON IDENTIFIER",
+		"value NUMBER | IDENTIFIER | NUMBER list NUMBER",
+		"list (COMMA NUMBER)* COMMA",
+		"sign [MINUS] MINUS",
+	}
+	if !reflect.DeepEqual(conflicts, expectedConflicts) {
+		t.Errorf("Unexpected conflicts:\n%s", strings.Join(conflicts, "\n"))
+	}
+	if !analysis.HasErrors() || !analysis.HasWarnings() {
+		t.Errorf("Expected errors and warnings")
+	}
+
+	table := analysis.String()
+	for _, line := range []string{
+		"RULE       NULLABLE  FIRST              FOLLOW",
+		"program    yes       NUMBER IDENTIFIER  EOF",
+		"Left recursion:\n  sign -> sign",
+		"Unreachable rules:\n  sign\n  orphan",
+		"statement: alternative 3 of (IDENTIFIER ASSIGN value SEMICOLON | value SEMICOLON | IDENTIFIER ASSIGN value SEMICOLON) is shadowed by alternative 1, which is identical",
+		"statement: alternatives 1 and 2 of (IDENTIFIER ASSIGN value SEMICOLON | value SEMICOLON | IDENTIFIER ASSIGN value SEMICOLON) both start with IDENTIFIER",
+		"list: (COMMA NUMBER)* can continue or stop on COMMA",
+	} {
+		if !strings.Contains(table, line) {
+			t.Errorf("Expected table to contain %q:\n%s", line, table)
+		}
+	}
+}
+
+// TestAnalyzeExpressionGrammar tests that the bundled grammar is clean
+func TestAnalyzeExpressionGrammar(t *testing.T) {
+	grammar, err := ParseGrammarFile("expression_grammar.txt")
+	if err != nil {
+		t.Fatalf("Failed to parse grammar: %v", err)
+	}
+	analysis, err := AnalyzeGrammar(grammar)
+	if err != nil {
+		t.Fatalf("AnalyzeGrammar failed: %v", err)
+	}
+	if analysis.HasErrors() || analysis.HasWarnings() {
+		t.Errorf("Expected a clean analysis:\n%s", analysis)
+	}
+	for _, rule := range analysis.Rules {
+		if rule.Rule == "comparison_op" && !reflect.DeepEqual(rule.Follow, []string{
+			"DECIMAL", "NUMBER", "BOOLEAN", "MIN", "MAX", "ABS", "NEGATE", "CEIL", "FLOOR",
+			"THRESHOLD", "ITE", "IMPLIES", "EQUIV", "XOR", "IDENTIFIER", "PLUS", "MINUS", "LPAREN",
+		}) {
+			t.Errorf("Unexpected FOLLOW(comparison_op): %v", rule.Follow)
+		}
+	}
+}
+
+// TestAnalyzeLeftRecursion tests recursion through other rules and
+// nullable prefixes
+func TestAnalyzeLeftRecursion(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    string
+		expected [][]string
+	}{
+		{
+			name:     "indirect",
+			rules:    "a -> b X\nb -> c | X\nc -> a Y",
+			expected: [][]string{{"a", "b", "c", "a"}},
+		},
+		{
+			name:     "nullable prefix",
+			rules:    "a -> b a X | X\nb -> Y*",
+			expected: [][]string{{"a", "a"}},
+		},
+		{
+			name:     "predicate",
+			rules:    "a -> !b X\nb -> a",
+			expected: [][]string{{"a", "b", "a"}},
+		},
+		{
+			name:     "two cycles",
+			rules:    "a -> a X | b\nb -> c Y\nc -> b | X",
+			expected: [][]string{{"a", "a"}, {"b", "c", "b"}},
+		},
+		{
+			name:  "consumed prefix",
+			rules: "a -> X a | Y",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "%name \"LR\"\n%start a\n%token X \"x\"\n%token Y \"y\"\n" + tt.rules
+			grammar, err := NewGrammarParser(input).ParseGrammar()
+			if err != nil {
+				t.Fatalf("Failed to parse grammar: %v", err)
+			}
+			analysis, err := AnalyzeGrammar(grammar)
+			if err != nil {
+				t.Fatalf("AnalyzeGrammar failed: %v", err)
+			}
+			if !reflect.DeepEqual(analysis.LeftRecursion, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, analysis.LeftRecursion)
+			}
+
+			err = NewGenerator(&Config{}).Validate(grammar)
+			if tt.expected == nil && err != nil {
+				t.Errorf("Unexpected validation error: %v", err)
+			}
+			if tt.expected != nil && (err == nil || !strings.Contains(err.Error(), "left recursion detected: "+strings.Join(tt.expected[0], " -> "))) {
+				t.Errorf("Expected left recursion error, got %v", err)
+			}
+		})
+	}
+
+	grammar, _ := NewGrammarParser("%name \"U\"\n%start a\n%token X \"x\"\na -> X missing").ParseGrammar()
+	if _, err := AnalyzeGrammar(grammar); err == nil || !strings.Contains(err.Error(), "undefined rule or token: missing") {
+		t.Errorf("Expected an undefined reference error, got %v", err)
+	}
+}
@@ -0,0 +1,882 @@
+package parser_generator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ===== ANTLR4 IMPORT =====
+
+// ImportANTLR translates an ANTLR4 .g4 grammar. Lexer rules become regex
+// tokens (fragments are inlined) and parser rules become PEG rules, with
+// literals mapped to the lexer rule defining them. Alternatives keep their
+// order, so the result is only equivalent where ANTLR's adaptive prediction
+// and PEG's ordered choice agree. Actions, labels and options are dropped with
+// a warning; modes, imports, semantic predicates and left recursion are errors.
+func ImportANTLR(input string) (*Grammar, []Diagnostic, error) {
+	scanner := newImportScanner(input, true, []string{
+		"->", "..", "::", "+=", ":", ";", "|", "(", ")", "*", "+", "?", "~", ".", "=", "#", ",", "<", ">", "@",
+	})
+	tokens, scanErr := scanner.tokens()
+
+	p := &antlrImporter{builder: newImportBuilder("ANTLR", ""), tokens: tokens, lexerRules: make(map[string]*antlrRule)}
+	if scanErr != nil {
+		p.builder.diagnostics = append(p.builder.diagnostics, *scanErr)
+	}
+	p.parseGrammar()
+	p.convertLexerRules()
+	p.convertParserRules()
+	return p.builder.finish()
+}
+
+type antlrNodeKind int
+
+const (
+	antlrAlt antlrNodeKind = iota
+	antlrSeq
+	antlrRef
+	antlrString
+	antlrSet   // [a-z] with the raw body in text
+	antlrRange // 'a'..'z'
+	antlrNot   // ~x
+	antlrAny   // .
+	antlrRepeat
+	antlrAction
+	antlrSemPred // {...}?
+)
+
+// antlrNode is a parsed rule element, before translation
+type antlrNode struct {
+	kind     antlrNodeKind
+	tok      importToken
+	text     string // Rule name, literal value or set body
+	to       string // Range end
+	children []*antlrNode
+	min, max int
+	greedy   bool
+}
+
+type antlrCommand struct {
+	tok  importToken
+	name string
+	arg  string
+}
+
+type antlrRule struct {
+	tok      importToken
+	name     string
+	fragment bool
+	body     *antlrNode
+	commands []antlrCommand
+
+	pattern  string // Translated lexer pattern
+	resolved bool
+	visiting bool
+}
+
+type antlrImporter struct {
+	builder     *importBuilder
+	tokens      []importToken
+	pos         int
+	lexerRules  map[string]*antlrRule
+	lexerOrder  []*antlrRule
+	parserRules []*antlrRule
+	declared    []importToken // tokens { ... } entries
+}
+
+func (p *antlrImporter) peek() importToken {
+	return p.tokens[p.pos]
+}
+
+func (p *antlrImporter) peekAt(offset int) importToken {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *antlrImporter) next() importToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != importEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *antlrImporter) isPunct(text string) bool {
+	tok := p.peek()
+	return tok.kind == importPunct && tok.text == text
+}
+
+func (p *antlrImporter) isIdent(text string) bool {
+	tok := p.peek()
+	return tok.kind == importIdent && tok.text == text
+}
+
+// skipPast skips up to and including the next ';'
+func (p *antlrImporter) skipPast() {
+	for p.peek().kind != importEOF && !p.isPunct(";") {
+		p.next()
+	}
+	p.next()
+}
+
+func isLexerRuleName(name string) bool {
+	ch, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(ch)
+}
+
+// ----- Parsing -----
+
+func (p *antlrImporter) parseGrammar() {
+	p.parseHeader()
+
+	for p.peek().kind != importEOF {
+		tok := p.peek()
+		switch {
+		case p.isIdent("options") || p.isIdent("channels"):
+			p.next()
+			if p.peek().kind == importAction {
+				p.next()
+			}
+			p.builder.warnf(tok, "%s block ignored", tok.text)
+		case p.isIdent("tokens") && p.peekAt(1).kind == importAction:
+			p.next()
+			body := p.next()
+			for _, name := range strings.Split(body.text, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					p.declared = append(p.declared, importToken{kind: importIdent, text: name, line: body.line, column: body.column})
+				}
+			}
+		case p.isIdent("import"):
+			p.builder.errorf(tok, "grammar imports are not supported; merge the imported grammar into this file")
+			p.skipPast()
+		case p.isIdent("mode"):
+			p.builder.errorf(tok, "lexer modes are not supported")
+			p.skipPast()
+		case p.isPunct("@"):
+			p.next()
+			name := p.next().text
+			if p.isPunct("::") {
+				p.next()
+				name += "::" + p.next().text
+			}
+			if p.peek().kind == importAction {
+				p.next()
+			}
+			p.builder.warnf(tok, "@%s action ignored", name)
+		case tok.kind == importIdent:
+			p.parseRule()
+		default:
+			p.builder.errorf(tok, "expected a rule, found %s", describeImportToken(tok))
+			p.skipPast()
+		}
+	}
+}
+
+// parseHeader reads [lexer|parser] grammar Name;
+func (p *antlrImporter) parseHeader() {
+	tok := p.peek()
+	if p.isIdent("lexer") || p.isIdent("parser") {
+		p.next()
+	}
+	if !p.isIdent("grammar") {
+		p.builder.errorf(tok, "expected a grammar declaration, found %s", describeImportToken(tok))
+		return
+	}
+	p.next()
+	if p.peek().kind != importIdent {
+		p.builder.errorf(p.peek(), "expected a grammar name, found %s", describeImportToken(p.peek()))
+		p.skipPast()
+		return
+	}
+	p.builder.grammar.Name = p.next().text
+	if !p.isPunct(";") {
+		p.builder.errorf(p.peek(), "expected ';' after the grammar name, found %s", describeImportToken(p.peek()))
+		return
+	}
+	p.next()
+}
+
+func (p *antlrImporter) parseRule() {
+	rule := &antlrRule{}
+	if p.isIdent("fragment") {
+		p.next()
+		rule.fragment = true
+	}
+	rule.tok = p.next()
+	rule.name = rule.tok.text
+	if rule.tok.kind != importIdent {
+		p.builder.errorf(rule.tok, "expected a rule name, found %s", describeImportToken(rule.tok))
+		p.skipPast()
+		return
+	}
+
+	// Rule prequel: arguments, return values, options and init actions
+	for p.peek().kind != importEOF && !p.isPunct(":") {
+		tok := p.next()
+		switch {
+		case tok.kind == importCharSet:
+			p.builder.errorf(tok, "rule %s: rule arguments are not supported", rule.name)
+		case tok.kind == importIdent && (tok.text == "returns" || tok.text == "locals" || tok.text == "throws"):
+			p.builder.errorf(tok, "rule %s: %s is not supported", rule.name, tok.text)
+		case tok.kind == importIdent && tok.text == "options":
+			p.builder.warnf(tok, "rule %s: options ignored", rule.name)
+		case tok.kind == importPunct && tok.text == "@":
+			p.builder.warnf(tok, "rule %s: @%s action ignored", rule.name, p.peek().text)
+		case tok.kind == importPunct && tok.text == ";":
+			p.builder.errorf(tok, "rule %s: expected ':'", rule.name)
+			return
+		}
+	}
+	if !p.isPunct(":") {
+		p.builder.errorf(p.peek(), "rule %s: expected ':'", rule.name)
+		return
+	}
+	p.next()
+
+	body, ok := p.parseAlternatives(rule)
+	if !ok {
+		p.skipPast()
+		return
+	}
+	if !p.isPunct(";") {
+		p.builder.errorf(p.peek(), "rule %s: expected ';', found %s", rule.name, describeImportToken(p.peek()))
+		p.skipPast()
+		return
+	}
+	p.next()
+
+	// Exception handlers
+	for p.isIdent("catch") || p.isIdent("finally") {
+		tok := p.next()
+		p.builder.warnf(tok, "rule %s: %s handler ignored", rule.name, tok.text)
+		for p.peek().kind == importCharSet || p.peek().kind == importAction {
+			p.next()
+		}
+	}
+
+	rule.body = body
+	if _, exists := p.lexerRules[rule.name]; exists || p.parserRuleByName(rule.name) != nil {
+		p.builder.errorf(rule.tok, "rule %s is defined twice", rule.name)
+		return
+	}
+	if isLexerRuleName(rule.name) {
+		p.lexerRules[rule.name] = rule
+		p.lexerOrder = append(p.lexerOrder, rule)
+	} else {
+		if rule.fragment {
+			p.builder.errorf(rule.tok, "parser rule %s cannot be a fragment", rule.name)
+		}
+		p.parserRules = append(p.parserRules, rule)
+	}
+}
+
+func (p *antlrImporter) parserRuleByName(name string) *antlrRule {
+	for _, rule := range p.parserRules {
+		if rule.name == name {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (p *antlrImporter) parseAlternatives(rule *antlrRule) (*antlrNode, bool) {
+	node := &antlrNode{kind: antlrAlt, tok: p.peek()}
+	for {
+		alt, ok := p.parseAlternative(rule)
+		if !ok {
+			return nil, false
+		}
+		node.children = append(node.children, alt)
+		if !p.isPunct("|") {
+			break
+		}
+		p.next()
+	}
+	if len(node.children) == 1 {
+		return node.children[0], true
+	}
+	return node, true
+}
+
+func (p *antlrImporter) parseAlternative(rule *antlrRule) (*antlrNode, bool) {
+	node := &antlrNode{kind: antlrSeq, tok: p.peek()}
+	for {
+		switch {
+		case p.peek().kind == importEOF, p.isPunct("|"), p.isPunct(";"), p.isPunct(")"):
+			return p.collapseSeq(node), true
+		case p.isPunct("#"):
+			// Alternative label
+			p.next()
+			p.next()
+		case p.isPunct("->"):
+			p.next()
+			if !p.parseCommands(rule) {
+				return nil, false
+			}
+		default:
+			element, ok := p.parseElement(rule)
+			if !ok {
+				return nil, false
+			}
+			if element != nil {
+				node.children = append(node.children, element)
+			}
+		}
+	}
+}
+
+func (p *antlrImporter) collapseSeq(node *antlrNode) *antlrNode {
+	if len(node.children) == 1 {
+		return node.children[0]
+	}
+	return node
+}
+
+// parseCommands reads lexer commands: -> skip, channel(HIDDEN), ...
+func (p *antlrImporter) parseCommands(rule *antlrRule) bool {
+	for {
+		tok := p.next()
+		if tok.kind != importIdent {
+			p.builder.errorf(tok, "rule %s: expected a lexer command, found %s", rule.name, describeImportToken(tok))
+			return false
+		}
+		command := antlrCommand{tok: tok, name: tok.text}
+		if p.isPunct("(") {
+			p.next()
+			command.arg = p.next().text
+			if !p.isPunct(")") {
+				p.builder.errorf(p.peek(), "rule %s: expected ')' after %s(%s", rule.name, command.name, command.arg)
+				return false
+			}
+			p.next()
+		}
+		rule.commands = append(rule.commands, command)
+		if !p.isPunct(",") {
+			return true
+		}
+		p.next()
+	}
+}
+
+func (p *antlrImporter) parseElement(rule *antlrRule) (*antlrNode, bool) {
+	// Element options such as <assoc=right>
+	if p.isPunct("<") {
+		tok := p.next()
+		var option []string
+		for p.peek().kind != importEOF && !p.isPunct(">") {
+			option = append(option, p.next().text)
+		}
+		p.next()
+		p.builder.warnf(tok, "rule %s: element option <%s> ignored", rule.name, strings.Join(option, ""))
+		return nil, true
+	}
+
+	// Labels: name=element, name+=element
+	if p.peek().kind == importIdent && p.peekAt(1).kind == importPunct && (p.peekAt(1).text == "=" || p.peekAt(1).text == "+=") {
+		p.next()
+		p.next()
+	}
+
+	atom, ok := p.parseAtom(rule)
+	if !ok {
+		return nil, false
+	}
+
+	if p.isPunct("*") || p.isPunct("+") || p.isPunct("?") {
+		suffix := p.next()
+		repeat := &antlrNode{kind: antlrRepeat, tok: suffix, children: []*antlrNode{atom}, max: -1, greedy: true}
+		switch suffix.text {
+		case "+":
+			repeat.min = 1
+		case "?":
+			repeat.max = 1
+		}
+		if p.isPunct("?") {
+			p.next()
+			repeat.greedy = false
+		}
+		return repeat, true
+	}
+	return atom, true
+}
+
+func (p *antlrImporter) parseAtom(rule *antlrRule) (*antlrNode, bool) {
+	tok := p.next()
+	switch tok.kind {
+	case importIdent:
+		return &antlrNode{kind: antlrRef, tok: tok, text: tok.text}, true
+	case importString:
+		if p.isPunct("..") {
+			p.next()
+			end := p.next()
+			if end.kind != importString {
+				p.builder.errorf(end, "rule %s: expected a literal after '..'", rule.name)
+				return nil, false
+			}
+			return &antlrNode{kind: antlrRange, tok: tok, text: tok.text, to: end.text}, true
+		}
+		return &antlrNode{kind: antlrString, tok: tok, text: tok.text}, true
+	case importCharSet:
+		return &antlrNode{kind: antlrSet, tok: tok, text: tok.text}, true
+	case importAction:
+		if p.isPunct("?") {
+			p.next()
+			return &antlrNode{kind: antlrSemPred, tok: tok, text: tok.text}, true
+		}
+		return &antlrNode{kind: antlrAction, tok: tok, text: tok.text}, true
+	case importPunct:
+		switch tok.text {
+		case "(":
+			inner, ok := p.parseAlternatives(rule)
+			if !ok {
+				return nil, false
+			}
+			if !p.isPunct(")") {
+				p.builder.errorf(p.peek(), "rule %s: expected ')', found %s", rule.name, describeImportToken(p.peek()))
+				return nil, false
+			}
+			p.next()
+			return inner, true
+		case "~":
+			inner, ok := p.parseAtom(rule)
+			if !ok {
+				return nil, false
+			}
+			return &antlrNode{kind: antlrNot, tok: tok, children: []*antlrNode{inner}}, true
+		case ".":
+			return &antlrNode{kind: antlrAny, tok: tok}, true
+		}
+	}
+	p.builder.errorf(tok, "rule %s: unexpected %s", rule.name, describeImportToken(tok))
+	return nil, false
+}
+
+// ----- Lexer rules -----
+
+func (p *antlrImporter) convertLexerRules() {
+	for _, rule := range p.lexerOrder {
+		skip := false
+		for _, command := range rule.commands {
+			switch {
+			case command.name == "skip":
+				skip = true
+			case command.name == "channel":
+				// Tokens on other channels never reach the parser
+				skip = true
+			default:
+				p.builder.errorf(command.tok, "lexer rule %s: command %s is not supported", rule.name, command.name)
+			}
+		}
+		if rule.fragment && len(rule.commands) > 0 {
+			p.builder.errorf(rule.tok, "fragment %s cannot have lexer commands", rule.name)
+		}
+
+		token := &TokenDef{Name: rule.name, Skip: skip, Fragment: rule.fragment}
+		if rule.body.kind == antlrString && rule.body.text != "" {
+			token.Literal = rule.body.text
+			if !rule.fragment {
+				p.builder.literals[token.Literal] = rule.name
+			}
+		} else if pattern, ok := p.lexerPattern(rule); ok {
+			token.Pattern = pattern
+		}
+		p.builder.grammar.Tokens = append(p.builder.grammar.Tokens, token)
+	}
+
+	for _, tok := range p.declared {
+		if _, exists := p.lexerRules[tok.text]; !exists {
+			p.builder.errorf(tok, "token %s is declared in tokens {} but no lexer rule matches it", tok.text)
+		}
+	}
+}
+
+// lexerPattern translates a lexer rule to a regular expression, inlining
+// the rules it references
+func (p *antlrImporter) lexerPattern(rule *antlrRule) (string, bool) {
+	if rule.resolved {
+		return rule.pattern, rule.pattern != ""
+	}
+	if rule.visiting {
+		p.builder.errorf(rule.tok, "lexer rule %s is recursive and cannot be expressed as a regular expression", rule.name)
+		return "", false
+	}
+	rule.visiting = true
+	pattern, ok := p.lexerRegex(rule.body, rule)
+	rule.visiting = false
+	rule.resolved = true
+	if ok {
+		rule.pattern = pattern
+	}
+	return pattern, ok
+}
+
+// lexerRegex renders a lexer element. The result can be concatenated with
+// other elements; alternatives are wrapped in a group.
+func (p *antlrImporter) lexerRegex(node *antlrNode, rule *antlrRule) (string, bool) {
+	switch node.kind {
+	case antlrAlt:
+		parts := make([]string, len(node.children))
+		for i, child := range node.children {
+			part, ok := p.lexerRegex(child, rule)
+			if !ok {
+				return "", false
+			}
+			parts[i] = part
+		}
+		return "(?:" + strings.Join(parts, "|") + ")", true
+	case antlrSeq:
+		var result strings.Builder
+		for _, child := range node.children {
+			part, ok := p.lexerRegex(child, rule)
+			if !ok {
+				return "", false
+			}
+			result.WriteString(part)
+		}
+		return result.String(), true
+	case antlrRef:
+		ref, exists := p.lexerRules[node.text]
+		if !exists {
+			p.builder.errorf(node.tok, "lexer rule %s: undefined lexer rule %s", rule.name, node.text)
+			return "", false
+		}
+		pattern, ok := p.lexerPattern(ref)
+		if !ok || isRegexAtom(ref.body) {
+			return pattern, ok
+		}
+		return "(?:" + pattern + ")", true
+	case antlrString:
+		return regexp.QuoteMeta(node.text), true
+	case antlrSet, antlrRange:
+		ranges, ok := p.charRanges(node, rule)
+		if !ok {
+			return "", false
+		}
+		return regexClass(ranges, false), true
+	case antlrNot:
+		ranges, ok := p.charRanges(node.children[0], rule)
+		if !ok {
+			return "", false
+		}
+		return regexClass(ranges, true), true
+	case antlrAny:
+		return `[\s\S]`, true
+	case antlrRepeat:
+		inner, ok := p.lexerRegex(node.children[0], rule)
+		if !ok {
+			return "", false
+		}
+		if !isRegexAtom(node.children[0]) {
+			inner = "(?:" + inner + ")"
+		}
+		suffix := "*"
+		switch {
+		case node.min == 1:
+			suffix = "+"
+		case node.max == 1:
+			suffix = "?"
+		}
+		if !node.greedy {
+			suffix += "?"
+		}
+		return inner + suffix, true
+	case antlrAction:
+		p.builder.warnf(node.tok, "lexer rule %s: action ignored", rule.name)
+		return "", true
+	case antlrSemPred:
+		p.builder.errorf(node.tok, "lexer rule %s: semantic predicates are not supported", rule.name)
+		return "", false
+	}
+	return "", false
+}
+
+// isRegexAtom reports whether an element renders as a single regex atom
+func isRegexAtom(node *antlrNode) bool {
+	switch node.kind {
+	case antlrSet, antlrRange, antlrNot, antlrAny, antlrRef, antlrAlt:
+		return true
+	case antlrString:
+		return utf8.RuneCountInString(node.text) == 1
+	}
+	return false
+}
+
+// charRanges resolves an element that denotes a set of characters
+func (p *antlrImporter) charRanges(node *antlrNode, rule *antlrRule) ([]CharRange, bool) {
+	switch node.kind {
+	case antlrString:
+		if utf8.RuneCountInString(node.text) == 1 {
+			ch, _ := utf8.DecodeRuneInString(node.text)
+			return []CharRange{{Start: ch, End: ch}}, true
+		}
+	case antlrRange:
+		if utf8.RuneCountInString(node.text) == 1 && utf8.RuneCountInString(node.to) == 1 {
+			start, _ := utf8.DecodeRuneInString(node.text)
+			end, _ := utf8.DecodeRuneInString(node.to)
+			return []CharRange{{Start: start, End: end}}, true
+		}
+		p.builder.errorf(node.tok, "lexer rule %s: range bounds must be single characters", rule.name)
+		return nil, false
+	case antlrSet:
+		ranges, err := parseANTLRCharSet(node.text)
+		if err != nil {
+			p.builder.errorf(node.tok, "lexer rule %s: %v", rule.name, err)
+			return nil, false
+		}
+		return ranges, true
+	case antlrAlt:
+		var ranges []CharRange
+		for _, child := range node.children {
+			childRanges, ok := p.charRanges(child, rule)
+			if !ok {
+				return nil, false
+			}
+			ranges = append(ranges, childRanges...)
+		}
+		return ranges, true
+	case antlrRef:
+		if ref, exists := p.lexerRules[node.text]; exists && ref != rule && !ref.visiting {
+			ref.visiting = true
+			defer func() { ref.visiting = false }()
+			return p.charRanges(ref.body, rule)
+		}
+	}
+	p.builder.errorf(node.tok, "lexer rule %s: ~ only applies to character sets", rule.name)
+	return nil, false
+}
+
+// parseANTLRCharSet decodes the body of [...], resolving escapes and ranges
+func parseANTLRCharSet(body string) ([]CharRange, error) {
+	scanner := newImportScanner(body, true, nil)
+	read := func() (rune, error) {
+		ch := scanner.advance()
+		if ch != '\\' {
+			return ch, nil
+		}
+		if scanner.pos >= len(scanner.input) {
+			return 0, fmt.Errorf("dangling escape in [%s]", body)
+		}
+		if next := scanner.peekRune(0); next == 'p' || next == 'P' {
+			return 0, fmt.Errorf("Unicode property escapes such as \\%c{...} are not supported", next)
+		}
+		return scanner.readEscape(), nil
+	}
+
+	var ranges []CharRange
+	for scanner.pos < len(scanner.input) {
+		start, err := read()
+		if err != nil {
+			return nil, err
+		}
+		end := start
+		if scanner.peekRune(0) == '-' && scanner.pos+1 < len(scanner.input) {
+			scanner.advance()
+			if end, err = read(); err != nil {
+				return nil, err
+			}
+			if end < start {
+				return nil, fmt.Errorf("invalid range %c-%c in [%s]", start, end, body)
+			}
+		}
+		ranges = append(ranges, CharRange{Start: start, End: end})
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("empty character set")
+	}
+	return ranges, nil
+}
+
+// regexClass renders ranges as a character class both Go and JavaScript accept
+func regexClass(ranges []CharRange, negated bool) string {
+	var result strings.Builder
+	result.WriteByte('[')
+	if negated {
+		result.WriteByte('^')
+	}
+	for _, r := range ranges {
+		result.WriteString(regexClassChar(r.Start))
+		if r.End != r.Start {
+			result.WriteByte('-')
+			result.WriteString(regexClassChar(r.End))
+		}
+	}
+	result.WriteByte(']')
+	return result.String()
+}
+
+func regexClassChar(ch rune) string {
+	switch ch {
+	case '\\', ']', '[', '^', '-':
+		return `\` + string(ch)
+	case '\n':
+		return `\n`
+	case '\r':
+		return `\r`
+	case '\t':
+		return `\t`
+	case '\f':
+		return `\f`
+	}
+	if ch < 0x20 || ch == 0x7f {
+		return fmt.Sprintf(`\x%02X`, ch)
+	}
+	return string(ch)
+}
+
+// ----- Parser rules -----
+
+func (p *antlrImporter) convertParserRules() {
+	for _, rule := range p.parserRules {
+		p.checkLeftRecursion(rule)
+		expr, ok := p.parserExpr(rule.body, rule)
+		if !ok {
+			continue
+		}
+		if expr == nil {
+			p.builder.errorf(rule.tok, "rule %s matches nothing", rule.name)
+			continue
+		}
+		p.builder.grammar.Rules = append(p.builder.grammar.Rules, &Rule{Name: rule.name, Expression: expr, Public: true})
+	}
+}
+
+// checkLeftRecursion reports alternatives that start with the rule itself.
+// ANTLR rewrites these; a PEG parser would recurse forever.
+func (p *antlrImporter) checkLeftRecursion(rule *antlrRule) {
+	alternatives := []*antlrNode{rule.body}
+	if rule.body.kind == antlrAlt {
+		alternatives = rule.body.children
+	}
+	for _, alt := range alternatives {
+		first := alt
+		if alt.kind == antlrSeq {
+			first = nil
+			for _, child := range alt.children {
+				if child.kind != antlrAction {
+					first = child
+					break
+				}
+			}
+		}
+		if first != nil && first.kind == antlrRef && first.text == rule.name {
+			p.builder.errorf(first.tok, "rule %s is left-recursive; rewrite it as %s: operand (operator operand)* with one rule per precedence level", rule.name, rule.name)
+			return
+		}
+	}
+}
+
+// parserExpr translates a parser element. Dropped elements return nil.
+func (p *antlrImporter) parserExpr(node *antlrNode, rule *antlrRule) (Expression, bool) {
+	switch node.kind {
+	case antlrAlt:
+		var alternatives []Expression
+		empty := false
+		for _, child := range node.children {
+			expr, ok := p.parserExpr(child, rule)
+			if !ok {
+				return nil, false
+			}
+			if expr == nil {
+				empty = true
+			} else {
+				alternatives = append(alternatives, expr)
+			}
+		}
+		var result Expression
+		switch len(alternatives) {
+		case 0:
+			return nil, true
+		case 1:
+			result = alternatives[0]
+		default:
+			result = &Choice{Alternatives: alternatives}
+		}
+		if empty {
+			result = &Optional{Expression: result}
+		}
+		return result, true
+	case antlrSeq:
+		var items []Expression
+		for _, child := range node.children {
+			expr, ok := p.parserExpr(child, rule)
+			if !ok {
+				return nil, false
+			}
+			if expr != nil {
+				items = append(items, groupChoice(expr))
+			}
+		}
+		switch len(items) {
+		case 0:
+			return nil, true
+		case 1:
+			return items[0], true
+		}
+		return &Sequence{Items: items}, true
+	case antlrRef:
+		if node.text == "EOF" {
+			p.builder.warnf(node.tok, "rule %s: EOF dropped; generated parsers require end of input after the start rule", rule.name)
+			return nil, true
+		}
+		if isLexerRuleName(node.text) {
+			ref, exists := p.lexerRules[node.text]
+			if !exists {
+				p.builder.errorf(node.tok, "rule %s: undefined token %s", rule.name, node.text)
+				return nil, false
+			}
+			if ref.fragment {
+				p.builder.errorf(node.tok, "rule %s: fragment %s cannot be used in parser rules", rule.name, node.text)
+				return nil, false
+			}
+		} else if p.parserRuleByName(node.text) == nil {
+			p.builder.errorf(node.tok, "rule %s: undefined rule %s", rule.name, node.text)
+			return nil, false
+		}
+		return &RuleRef{Name: node.text}, true
+	case antlrString:
+		return &RuleRef{Name: p.builder.literalToken(node.text)}, true
+	case antlrRepeat:
+		inner, ok := p.parserExpr(node.children[0], rule)
+		if !ok || inner == nil {
+			return nil, ok
+		}
+		if !node.greedy {
+			p.builder.warnf(node.tok, "rule %s: non-greedy %s treated as greedy", rule.name, node.tok.text)
+		}
+		if node.min == 0 && node.max == 1 {
+			return &Optional{Expression: groupChoice(inner)}, true
+		}
+		return &Repetition{Expression: groupCompound(inner), Min: node.min, Max: node.max, Greedy: true}, true
+	case antlrAction:
+		p.builder.warnf(node.tok, "rule %s: action ignored", rule.name)
+		return nil, true
+	case antlrSemPred:
+		p.builder.errorf(node.tok, "rule %s: semantic predicates are not supported", rule.name)
+		return nil, false
+	case antlrSet, antlrRange, antlrNot, antlrAny:
+		p.builder.errorf(node.tok, "rule %s: character sets, ranges, ~ and . are only supported in lexer rules", rule.name)
+		return nil, false
+	}
+	return nil, false
+}
+
+// groupChoice parenthesizes a choice nested in a sequence or option
+func groupChoice(expr Expression) Expression {
+	if _, ok := expr.(*Choice); ok {
+		return &Group{Expression: expr}
+	}
+	return expr
+}
+
+// groupCompound parenthesizes a choice or sequence under a repetition
+func groupCompound(expr Expression) Expression {
+	if _, ok := expr.(*Sequence); ok {
+		return &Group{Expression: expr}
+	}
+	return groupChoice(expr)
+}
@@ -0,0 +1,156 @@
+package parser_generator
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ===== SHARED PEG CODE GENERATION =====
+
+// astNodeBuilders lists the Rule.ASTNode values with built-in constructors.
+// Rules without an ASTNode or Action are transparent: their items flow into
+// the calling rule, collapsed to the single expression when there is one.
+var astNodeBuilders = map[string]bool{
+	"BinaryOperation": true, // operand (operator operand)*, folded left
+	"UnaryOperation":  true, // operator* operand, folded right
+	"FunctionCall":    true, // function token followed by argument expressions
+	"NumberLiteral":   true,
+	"BooleanLiteral":  true,
+	"Identifier":      true,
+}
+
+// matcherSyntax describes how a target language spells the PEG combinators
+type matcherSyntax struct {
+	self  string                   // Receiver holding the combinators
+	token func(name string) string // Token type constant
+	ref   func(rule string) string // Reference to a rule's parse method
+	quote func(s string) string    // String literal
+}
+
+// emitMatcher renders a grammar expression as nested combinator calls
+func emitMatcher(expr Expression, grammar *Grammar, syntax matcherSyntax) (string, error) {
+	call := func(name string, args ...Expression) (string, error) {
+		parts := make([]string, len(args))
+		for i, arg := range args {
+			code, err := emitMatcher(arg, grammar, syntax)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = code
+		}
+		return fmt.Sprintf("%s.%s(%s)", syntax.self, name, strings.Join(parts, ", ")), nil
+	}
+
+	switch e := expr.(type) {
+	case *RuleRef:
+		if grammar.GetRuleByName(e.Name) != nil {
+			return fmt.Sprintf("%s.ref(%s)", syntax.self, syntax.ref(e.Name)), nil
+		}
+		return fmt.Sprintf("%s.token(%s)", syntax.self, syntax.token(e.Name)), nil
+	case *Literal:
+		return fmt.Sprintf("%s.literal(%s)", syntax.self, syntax.quote(e.Value)), nil
+	case *Sequence:
+		return call("seq", e.Items...)
+	case *Choice:
+		return call("choice", e.Alternatives...)
+	case *Group:
+		return emitMatcher(e.Expression, grammar, syntax)
+	case *Optional:
+		return call("opt", e.Expression)
+	case *Repetition:
+		inner, err := emitMatcher(e.Expression, grammar, syntax)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s.rep(%d, %d, %s)", syntax.self, e.Min, e.Max, inner), nil
+	case *Predicate:
+		if e.Positive {
+			return call("and", e.Expression)
+		}
+		return call("not", e.Expression)
+	case *CharClass:
+		return "", fmt.Errorf("character class %s is only supported in token patterns", e)
+	}
+	return "", fmt.Errorf("unsupported grammar expression %T", expr)
+}
+
+// ruleIdent converts a rule name such as logical_or into LogicalOr
+func ruleIdent(name string) string {
+	var result strings.Builder
+	upper := true
+	for _, ch := range name {
+		if ch == '_' || ch == '-' {
+			upper = true
+			continue
+		}
+		if upper {
+			ch = unicode.ToUpper(ch)
+			upper = false
+		}
+		result.WriteRune(ch)
+	}
+	return result.String()
+}
+
+// lexerTokens returns the tokens the generated lexer matches, in declaration order
+func lexerTokens(grammar *Grammar) []*TokenDef {
+	var tokens []*TokenDef
+	for _, token := range grammar.Tokens {
+		if !token.Fragment {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// implicitLiterals returns the literals used in rules that no literal token
+// declares. The generated lexers match them as LITERAL tokens.
+func implicitLiterals(grammar *Grammar) []string {
+	declared := make(map[string]bool)
+	for _, token := range grammar.Tokens {
+		if token.Literal != "" {
+			declared[token.Literal] = true
+		}
+	}
+
+	var literals []string
+	var visit func(Expression)
+	visit = func(expr Expression) {
+		switch e := expr.(type) {
+		case *Literal:
+			if !declared[e.Value] {
+				declared[e.Value] = true
+				literals = append(literals, e.Value)
+			}
+		case *Sequence:
+			for _, item := range e.Items {
+				visit(item)
+			}
+		case *Choice:
+			for _, alt := range e.Alternatives {
+				visit(alt)
+			}
+		case *Group:
+			visit(e.Expression)
+		case *Optional:
+			visit(e.Expression)
+		case *Repetition:
+			visit(e.Expression)
+		case *Predicate:
+			visit(e.Expression)
+		}
+	}
+	for _, rule := range grammar.Rules {
+		visit(rule.Expression)
+	}
+	return literals
+}
+
+// tokenLabel is how a token appears in "Expected ..." messages
+func tokenLabel(token *TokenDef) string {
+	if token.Literal != "" {
+		return "'" + token.Literal + "'"
+	}
+	return token.Name
+}
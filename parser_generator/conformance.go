@@ -0,0 +1,491 @@
+package parser_generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// ===== CROSS-LANGUAGE CONFORMANCE =====
+
+// EmbeddedJSRuntime selects the JavaScript interpreter built into the harness
+const EmbeddedJSRuntime = "embedded"
+
+// jsRuntimes are the local JavaScript runtimes tried in order, with the
+// arguments that run a script file
+var jsRuntimes = []struct {
+	command string
+	args    []string
+}{
+	{command: "node"},
+	{command: "deno", args: []string{"run", "--quiet"}},
+	{command: "bun", args: []string{"run"}},
+}
+
+// ConformanceOptions configures a conformance run
+type ConformanceOptions struct {
+	JSRuntime string // Command running the TypeScript side; empty picks a local runtime or the embedded one
+	GoCommand string // Go toolchain used to build the Go side; defaults to "go"
+	WorkDir   string // Directory for the generated parsers; defaults to a temporary directory
+}
+
+// ConformanceResult compares the two parsers on one input
+type ConformanceResult struct {
+	Input string `json:"input"`
+	Go    string `json:"go"`             // Canonical JSON of the Go parser's AST or error
+	TS    string `json:"ts"`             // Canonical JSON of the TypeScript parser's AST or error
+	Diff  string `json:"diff,omitempty"` // Line diff from Go to TypeScript; empty when they agree
+}
+
+// Match reports whether both parsers produced the same result
+func (r ConformanceResult) Match() bool {
+	return r.Diff == ""
+}
+
+// ConformanceReport is the outcome of a conformance run
+type ConformanceReport struct {
+	Grammar   string              `json:"grammar"`
+	JSRuntime string              `json:"jsRuntime"`
+	Results   []ConformanceResult `json:"results"`
+}
+
+// Mismatches returns the results where the parsers disagree
+func (r *ConformanceReport) Mismatches() []ConformanceResult {
+	var mismatches []ConformanceResult
+	for _, result := range r.Results {
+		if !result.Match() {
+			mismatches = append(mismatches, result)
+		}
+	}
+	return mismatches
+}
+
+// String summarizes the run and shows a diff for every mismatch
+func (r *ConformanceReport) String() string {
+	var result strings.Builder
+	mismatches := r.Mismatches()
+	result.WriteString(fmt.Sprintf("Conformance of %s (TypeScript on %s): %d inputs, %d mismatches\n",
+		r.Grammar, r.JSRuntime, len(r.Results), len(mismatches)))
+	for _, mismatch := range mismatches {
+		result.WriteString(fmt.Sprintf("\nInput %q:\n%s", mismatch.Input, mismatch.Diff))
+	}
+	return result.String()
+}
+
+// LoadCorpus reads conformance inputs. A .json file holds an array of
+// strings; any other file has one input per line, where blank lines and
+// lines starting with # are skipped and \n stands for a line break.
+func LoadCorpus(filename string) ([]string, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		var inputs []string
+		if err := json.Unmarshal(content, &inputs); err != nil {
+			return nil, fmt.Errorf("invalid corpus %s: %w", filename, err)
+		}
+		return inputs, nil
+	}
+
+	var inputs []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		inputs = append(inputs, strings.ReplaceAll(line, `\n`, "\n"))
+	}
+	return inputs, nil
+}
+
+// RunConformance generates the Go and TypeScript parsers for a grammar, parses
+// every corpus input with both and compares their canonical AST JSON,
+// including node ranges, and their errors with positions. The Go parser is
+// built with the Go toolchain; the TypeScript parser has its types stripped
+// and runs under a local JavaScript runtime or the embedded interpreter.
+func RunConformance(grammar *Grammar, corpus []string, options ConformanceOptions) (*ConformanceReport, error) {
+	generator := NewGenerator(&Config{PackageName: "parser"})
+	if err := generator.Validate(grammar); err != nil {
+		return nil, fmt.Errorf("invalid grammar: %w", err)
+	}
+
+	workDir := options.WorkDir
+	if workDir == "" {
+		dir, err := os.MkdirTemp("", "conformance-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create work directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		workDir = dir
+	}
+
+	corpusJSON, err := json.Marshal(corpus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode corpus: %w", err)
+	}
+
+	goResults, err := runGoConformance(generator, grammar, workDir, corpusJSON, options.GoCommand)
+	if err != nil {
+		return nil, err
+	}
+	runtime, tsResults, err := runTSConformance(generator, grammar, workDir, corpusJSON, options.JSRuntime)
+	if err != nil {
+		return nil, err
+	}
+	if len(goResults) != len(corpus) || len(tsResults) != len(corpus) {
+		return nil, fmt.Errorf("expected %d results, got %d from Go and %d from TypeScript",
+			len(corpus), len(goResults), len(tsResults))
+	}
+
+	report := &ConformanceReport{Grammar: grammar.Name, JSRuntime: runtime}
+	for i, input := range corpus {
+		goJSON, err := canonicalJSON(goResults[i])
+		if err != nil {
+			return nil, fmt.Errorf("go result for %q: %w", input, err)
+		}
+		tsJSON, err := canonicalJSON(tsResults[i])
+		if err != nil {
+			return nil, fmt.Errorf("typescript result for %q: %w", input, err)
+		}
+		result := ConformanceResult{Input: input, Go: goJSON, TS: tsJSON}
+		if goJSON != tsJSON {
+			result.Diff = diffLines(goJSON, tsJSON, "go", "ts")
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// canonicalJSON re-encodes a result with sorted keys and Go number formatting
+// so equal results from both sides compare equal as text
+func canonicalJSON(raw json.RawMessage) (string, error) {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// ----- Go side -----
+
+// conformanceGoDriver prints the canonical result for each corpus input
+const conformanceGoDriver = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"conformance/parser"
+)
+
+func main() {
+	data, err := os.ReadFile(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var inputs []string
+	if err := json.Unmarshal(data, &inputs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	results := make([]interface{}, len(inputs))
+	for i, input := range inputs {
+		expr, err := parser.ParseExpression(input)
+		if err != nil {
+			results[i] = canonicalError(err)
+		} else {
+			results[i] = map[string]interface{}{"ast": canonicalNode(expr)}
+		}
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(results); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func canonicalRange(r parser.SourceRange) map[string]interface{} {
+	return map[string]interface{}{
+		"start": []int{r.Start.Line, r.Start.Column},
+		"end":   []int{r.End.Line, r.End.Column},
+		"text":  r.Text,
+	}
+}
+
+func canonicalNode(expr parser.Expression) map[string]interface{} {
+	node := map[string]interface{}{"range": canonicalRange(expr.GetRange())}
+	switch e := expr.(type) {
+	case *parser.NumberLiteral:
+		node["kind"], node["value"] = "NumberLiteral", e.Value
+	case *parser.BooleanLiteral:
+		node["kind"], node["value"] = "BooleanLiteral", e.Value
+	case *parser.Identifier:
+		node["kind"], node["name"] = "Identifier", e.Name
+	case *parser.BinaryOperation:
+		node["kind"], node["operator"] = "BinaryOperation", e.Operator.String()
+		node["left"], node["right"] = canonicalNode(e.Left), canonicalNode(e.Right)
+	case *parser.UnaryOperation:
+		node["kind"], node["operator"] = "UnaryOperation", e.Operator.String()
+		node["operand"] = canonicalNode(e.Operand)
+	case *parser.FunctionCall:
+		args := make([]interface{}, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = canonicalNode(arg)
+		}
+		node["kind"], node["function"], node["args"] = "FunctionCall", e.Function.String(), args
+	default:
+		node["kind"], node["text"] = "Unknown", expr.String()
+	}
+	return node
+}
+
+func canonicalError(err error) map[string]interface{} {
+	parseErr, ok := err.(*parser.ParseError)
+	if !ok {
+		return map[string]interface{}{"error": map[string]interface{}{"message": err.Error()}}
+	}
+	return map[string]interface{}{"error": map[string]interface{}{
+		"type":       parseErr.ErrorType,
+		"message":    parseErr.Message,
+		"suggestion": parseErr.Suggestion,
+		"range":      canonicalRange(parseErr.Range),
+	}}
+}
+`
+
+func runGoConformance(generator *Generator, grammar *Grammar, workDir string, corpusJSON []byte, goCommand string) ([]json.RawMessage, error) {
+	if goCommand == "" {
+		goCommand = "go"
+	}
+	goTool, err := exec.LookPath(goCommand)
+	if err != nil {
+		return nil, fmt.Errorf("go toolchain not found: %w", err)
+	}
+
+	dir := filepath.Join(workDir, "go")
+	if err := generator.GenerateGo(grammar, filepath.Join(dir, "parser")); err != nil {
+		return nil, err
+	}
+	files := map[string]string{
+		"go.mod":      "module conformance\n\ngo 1.21\n",
+		"main.go":     conformanceGoDriver,
+		"corpus.json": string(corpusJSON),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	cmd := exec.Command(goTool, "run", ".", "corpus.json")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")
+	output, err := runCommand(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("go parser failed: %w", err)
+	}
+	return decodeResults(output, "go")
+}
+
+// ----- TypeScript side -----
+
+// conformanceJSDriver mirrors conformanceGoDriver for the stripped TypeScript parser
+const conformanceJSDriver = `
+function __canonicalRange(r) {
+  return { start: [r.start.line, r.start.column], end: [r.end.line, r.end.column], text: r.text };
+}
+
+function __canonicalNode(e) {
+  const node = { kind: e.kind, range: __canonicalRange(e.range) };
+  switch (e.kind) {
+    case 'NumberLiteral':
+    case 'BooleanLiteral':
+      node.value = e.value;
+      break;
+    case 'Identifier':
+      node.name = e.name;
+      break;
+    case 'BinaryOperation':
+      node.operator = tokenName(e.operator);
+      node.left = __canonicalNode(e.left);
+      node.right = __canonicalNode(e.right);
+      break;
+    case 'UnaryOperation':
+      node.operator = tokenName(e.operator);
+      node.operand = __canonicalNode(e.operand);
+      break;
+    case 'FunctionCall':
+      node.function = tokenName(e.func);
+      node.args = e.args.map(__canonicalNode);
+      break;
+    default:
+      node.kind = 'Unknown';
+      node.text = String(e);
+  }
+  return node;
+}
+
+function __conformance(inputs) {
+  return JSON.stringify(inputs.map((input) => {
+    try {
+      return { ast: __canonicalNode(parseExpression(input)) };
+    } catch (error) {
+      if (error instanceof ParseError) {
+        return { error: { type: error.errorType, message: error.message, suggestion: error.suggestion,
+          range: __canonicalRange(error.range) } };
+      }
+      return { error: { message: String(error && error.message) } };
+    }
+  }));
+}
+`
+
+func runTSConformance(generator *Generator, grammar *Grammar, workDir string, corpusJSON []byte, runtime string) (string, []json.RawMessage, error) {
+	source, err := generator.tsGen.Generate(grammar)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate TypeScript parser: %w", err)
+	}
+	dir := filepath.Join(workDir, "ts")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "parser.ts"), []byte(source), 0644); err != nil {
+		return "", nil, fmt.Errorf("failed to write parser file: %w", err)
+	}
+
+	script, err := StripTypeScript(source)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to strip TypeScript parser: %w", err)
+	}
+	// The corpus is embedded as a string so every runtime can read it
+	corpusLiteral, err := json.Marshal(string(corpusJSON))
+	if err != nil {
+		return "", nil, err
+	}
+	script += conformanceJSDriver
+
+	command, args := findJSRuntime(runtime)
+	if command == "" {
+		vm := goja.New()
+		value, err := vm.RunString(script + "\n__conformance(JSON.parse(" + string(corpusLiteral) + "));\n")
+		if err != nil {
+			return "", nil, fmt.Errorf("typescript parser failed in the embedded runtime: %w", err)
+		}
+		results, err := decodeResults([]byte(value.String()), "typescript")
+		return EmbeddedJSRuntime, results, err
+	}
+
+	path := filepath.Join(dir, "conformance.js")
+	script += "\nconsole.log(__conformance(JSON.parse(" + string(corpusLiteral) + ")));\n"
+	if err := os.WriteFile(path, []byte(script), 0644); err != nil {
+		return "", nil, fmt.Errorf("failed to write script: %w", err)
+	}
+	cmd := exec.Command(command, append(args, path)...)
+	cmd.Dir = dir
+	output, err := runCommand(cmd)
+	if err != nil {
+		return "", nil, fmt.Errorf("typescript parser failed under %s: %w", filepath.Base(command), err)
+	}
+	results, err := decodeResults(output, "typescript")
+	return filepath.Base(command), results, err
+}
+
+// findJSRuntime resolves the JavaScript runtime to run. An empty command
+// means the embedded interpreter.
+func findJSRuntime(runtime string) (string, []string) {
+	switch runtime {
+	case EmbeddedJSRuntime:
+		return "", nil
+	case "":
+		for _, candidate := range jsRuntimes {
+			if path, err := exec.LookPath(candidate.command); err == nil {
+				return path, candidate.args
+			}
+		}
+		return "", nil
+	}
+	for _, candidate := range jsRuntimes {
+		if filepath.Base(runtime) == candidate.command {
+			return runtime, candidate.args
+		}
+	}
+	return runtime, nil
+}
+
+func runCommand(cmd *exec.Cmd) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func decodeResults(output []byte, side string) ([]json.RawMessage, error) {
+	var results []json.RawMessage
+	if err := json.Unmarshal(bytes.TrimSpace(output), &results); err != nil {
+		return nil, fmt.Errorf("invalid %s output: %w", side, err)
+	}
+	return results, nil
+}
+
+// ----- Diffs -----
+
+// diffLines renders a line diff from a to b: unchanged lines are indented,
+// removed lines start with '-' and added lines with '+'
+func diffLines(a, b, nameA, nameB string) string {
+	left := strings.Split(a, "\n")
+	right := strings.Split(b, "\n")
+
+	// lcs[i][j] is the longest common subsequence of left[i:] and right[j:]
+	lcs := make([][]int, len(left)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(right)+1)
+	}
+	for i := len(left) - 1; i >= 0; i-- {
+		for j := len(right) - 1; j >= 0; j-- {
+			if left[i] == right[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var result strings.Builder
+	result.WriteString("--- " + nameA + "\n+++ " + nameB + "\n")
+	i, j := 0, 0
+	for i < len(left) || j < len(right) {
+		switch {
+		case i < len(left) && j < len(right) && left[i] == right[j]:
+			result.WriteString("  " + left[i] + "\n")
+			i++
+			j++
+		case i < len(left) && (j == len(right) || lcs[i+1][j] >= lcs[i][j+1]):
+			result.WriteString("- " + left[i] + "\n")
+			i++
+		default:
+			result.WriteString("+ " + right[j] + "\n")
+			j++
+		}
+	}
+	return result.String()
+}
@@ -0,0 +1,166 @@
+package parser_generator
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+)
+
+// TestStripTypeScript tests erasing the TypeScript syntax the generator emits
+func TestStripTypeScript(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "interface and type alias",
+			input:    "export interface Point {\n  x: number;\n}\ntype Pair = [Point, Point];\nconst a = 1;",
+			expected: "\n\nconst a = 1;",
+		},
+		{
+			name:     "class members",
+			input:    "export class A extends B implements C, D {\n  private readonly x: number = 1;\n  y?: string;\n  constructor(v: number, w: string = 'a:b') { super(); }\n  get(): Map<string, Set<number>> { return new Map<string, Set<number>>(); }\n}",
+			expected: "class A extends B {\n  x = 1;\n  y;\n  constructor(v, w = 'a:b') { super(); }\n  get() { return new Map(); }\n}",
+		},
+		{
+			name:     "string enum",
+			input:    "export enum Color {\n  Red = 'RED',\n  Blue = 'BLUE'\n}",
+			expected: "const Color = { Red: 'RED', Blue: 'BLUE' };",
+		},
+		{
+			name:     "const enum",
+			input:    "const enum Id {\n  A,\n  B = 5,\n  C,\n}",
+			expected: "const Id = { A: 0, B: 5, C: 6 };",
+		},
+		{
+			name:     "function signature types",
+			input:    "function f(a: number, ...rest: Array<() => void>): string | null {\n  return null;\n}",
+			expected: "function f(a, ...rest) {\n  return null;\n}",
+		},
+		{
+			name:     "arrow functions and casts",
+			input:    "const g = (x: Item): x is Expression => !(x as Token).ok;\nconst h = (a?: number) => a ?? 0;",
+			expected: "const g = (x) => !(x).ok;\nconst h = (a) => a ?? 0;",
+		},
+		{
+			name:     "object literals and ternaries are untouched",
+			input:    "const o: Record<string, string> = { a: x ? 'b' : 'c', [k]: (y) };\nswitch (v) { case 'x': break; }",
+			expected: "const o = { a: x ? 'b' : 'c', [k]: (y) };\nswitch (v) { case 'x': break; }",
+		},
+		{
+			name:     "regular expressions and division",
+			input:    "const r = /^[0-9.]/.test(s) ? a / b : c;",
+			expected: "const r = /^[0-9.]/.test(s) ? a / b : c;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StripTypeScript(tt.input)
+			if err != nil {
+				t.Fatalf("StripTypeScript failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := StripTypeScript("class A {\n  constructor(private x: number) {}\n}"); err == nil ||
+		!strings.Contains(err.Error(), "line 2: parameter properties are not supported") {
+		t.Errorf("Expected a parameter property error, got %v", err)
+	}
+}
+
+// TestStrippedParserRuns runs the generated TypeScript parser in the
+// embedded JavaScript runtime
+func TestStrippedParserRuns(t *testing.T) {
+	grammar, err := ParseGrammarFile("expression_grammar.txt")
+	if err != nil {
+		t.Fatalf("Failed to parse grammar: %v", err)
+	}
+	source, err := NewTypeScriptGenerator(&Config{}).Generate(grammar)
+	if err != nil {
+		t.Fatalf("TypeScript generation failed: %v", err)
+	}
+	script, err := StripTypeScript(source)
+	if err != nil {
+		t.Fatalf("StripTypeScript failed: %v", err)
+	}
+
+	value, err := goja.New().RunString(script + "\nparseExpression('MIN(1, x) >= -y AND NOT b').toString();")
+	if err != nil {
+		t.Fatalf("Stripped parser failed: %v", err)
+	}
+	if got := value.String(); got != "((MIN(1, x) >= (-y)) AND (NOTb))" {
+		t.Errorf("Unexpected AST: %s", got)
+	}
+}
+
+// TestConformance parses the shared corpus with the generated Go and
+// TypeScript parsers and requires identical ASTs and errors
+func TestConformance(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+	if testing.Short() {
+		t.Skip("compiling generated parsers is slow")
+	}
+
+	grammar, err := ParseGrammarFile("expression_grammar.txt")
+	if err != nil {
+		t.Fatalf("Failed to parse grammar: %v", err)
+	}
+	corpus, err := LoadCorpus("conformance_corpus.txt")
+	if err != nil {
+		t.Fatalf("Failed to load corpus: %v", err)
+	}
+	if len(corpus) < 40 || !strings.Contains(strings.Join(corpus, "|"), "a +\n  b *\n  c") {
+		t.Fatalf("Corpus not loaded correctly: %d inputs", len(corpus))
+	}
+
+	runtimes := []string{EmbeddedJSRuntime}
+	if command, _ := findJSRuntime(""); command != "" {
+		runtimes = append(runtimes, "")
+	}
+	for _, runtime := range runtimes {
+		report, err := RunConformance(grammar, corpus, ConformanceOptions{JSRuntime: runtime})
+		if err != nil {
+			t.Fatalf("RunConformance failed: %v", err)
+		}
+		if len(report.Results) != len(corpus) {
+			t.Errorf("Expected %d results, got %d", len(corpus), len(report.Results))
+		}
+		if len(report.Mismatches()) > 0 {
+			t.Errorf("%s", report)
+		}
+		t.Logf("%s", strings.TrimSpace(report.String()))
+	}
+}
+
+// TestConformanceDiff tests how mismatches are reported
+func TestConformanceDiff(t *testing.T) {
+	goJSON := "{\n  \"kind\": \"Identifier\",\n  \"name\": \"a\",\n  \"range\": [1, 2]\n}"
+	tsJSON := "{\n  \"kind\": \"Identifier\",\n  \"name\": \"a\",\n  \"range\": [1, 3]\n}"
+
+	diff := diffLines(goJSON, tsJSON, "go", "ts")
+	expected := "--- go\n+++ ts\n  {\n    \"kind\": \"Identifier\",\n    \"name\": \"a\",\n-   \"range\": [1, 2]\n+   \"range\": [1, 3]\n  }\n"
+	if diff != expected {
+		t.Errorf("Expected diff:\n%s\nGot:\n%s", expected, diff)
+	}
+
+	report := &ConformanceReport{Grammar: "G", JSRuntime: "node", Results: []ConformanceResult{
+		{Input: "a", Go: goJSON, TS: goJSON},
+		{Input: "b", Go: goJSON, TS: tsJSON, Diff: diff},
+	}}
+	if len(report.Mismatches()) != 1 {
+		t.Errorf("Expected 1 mismatch, got %d", len(report.Mismatches()))
+	}
+	summary := report.String()
+	if !strings.Contains(summary, "2 inputs, 1 mismatches") || !strings.Contains(summary, "Input \"b\":\n--- go") {
+		t.Errorf("Unexpected report:\n%s", summary)
+	}
+}
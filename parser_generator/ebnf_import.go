@@ -0,0 +1,263 @@
+package parser_generator
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ===== ISO EBNF IMPORT =====
+
+// ImportEBNF translates an ISO 14977 EBNF grammar. Terminals become literal
+// tokens and whitespace between them is skipped. Supported: definitions
+// (= ... ; or .), alternatives (|), concatenation (,), [optional], {repeated},
+// (groups), n * repetition and exceptions, which become negative lookahead.
+// Special sequences (? ... ?) are reported as errors.
+func ImportEBNF(input string, name string) (*Grammar, []Diagnostic, error) {
+	scanner := newImportScanner(input, false, []string{"=", ",", "|", ";", ".", "[", "]", "{", "}", "(", ")", "-", "*"})
+	tokens, scanErr := scanner.tokens()
+
+	p := &ebnfImporter{builder: newImportBuilder("EBNF", name), tokens: tokens}
+	if scanErr != nil {
+		p.builder.diagnostics = append(p.builder.diagnostics, *scanErr)
+	}
+	p.parseSyntax()
+	p.builder.grammar.Tokens = append(p.builder.grammar.Tokens, &TokenDef{Name: "WHITESPACE", Pattern: `\s+`, Skip: true})
+	return p.builder.finish()
+}
+
+type ebnfImporter struct {
+	builder *importBuilder
+	tokens  []importToken
+	pos     int
+}
+
+func (p *ebnfImporter) peek() importToken {
+	return p.tokens[p.pos]
+}
+
+func (p *ebnfImporter) next() importToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != importEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *ebnfImporter) isPunct(text string) bool {
+	tok := p.peek()
+	return tok.kind == importPunct && tok.text == text
+}
+
+// parseSyntax parses rules until the end of input, recovering at terminators
+func (p *ebnfImporter) parseSyntax() {
+	for p.peek().kind != importEOF {
+		if !p.parseRule() {
+			// Skip to the end of the broken rule
+			for p.peek().kind != importEOF && !p.isPunct(";") && !p.isPunct(".") {
+				p.next()
+			}
+			p.next()
+		}
+	}
+}
+
+func (p *ebnfImporter) parseRule() bool {
+	nameTok := p.peek()
+	name := p.metaIdentifier()
+	if name == "" {
+		p.builder.errorf(nameTok, "expected a rule name, found %s", describeImportToken(nameTok))
+		return false
+	}
+	if !p.isPunct("=") {
+		p.builder.errorf(p.peek(), "expected '=' after rule %s", name)
+		return false
+	}
+	p.next()
+
+	expr, ok := p.parseDefinitions()
+	if !ok {
+		return false
+	}
+	if !p.isPunct(";") && !p.isPunct(".") {
+		p.builder.errorf(p.peek(), "expected ';' at the end of rule %s, found %s", name, describeImportToken(p.peek()))
+		return false
+	}
+	p.next()
+
+	if expr == nil {
+		p.builder.errorf(nameTok, "rule %s is empty", name)
+		return true
+	}
+	if p.builder.grammar.GetRuleByName(name) != nil {
+		p.builder.errorf(nameTok, "rule %s is defined twice", name)
+		return true
+	}
+	p.builder.grammar.Rules = append(p.builder.grammar.Rules, &Rule{Name: name, Expression: expr, Public: true})
+	return true
+}
+
+// metaIdentifier reads a possibly multi-word name, joining words with '_'
+func (p *ebnfImporter) metaIdentifier() string {
+	var words []string
+	for p.peek().kind == importIdent || (len(words) > 0 && p.peek().kind == importNumber) {
+		words = append(words, p.next().text)
+	}
+	return strings.Join(words, "_")
+}
+
+// parseDefinitions parses alternatives. An empty alternative makes the rest optional.
+func (p *ebnfImporter) parseDefinitions() (Expression, bool) {
+	var alternatives []Expression
+	empty := false
+	for {
+		expr, ok := p.parseSingleDefinition()
+		if !ok {
+			return nil, false
+		}
+		if expr == nil {
+			empty = true
+		} else {
+			alternatives = append(alternatives, expr)
+		}
+		if !p.isPunct("|") {
+			break
+		}
+		p.next()
+	}
+
+	var result Expression
+	switch len(alternatives) {
+	case 0:
+		return nil, true
+	case 1:
+		result = alternatives[0]
+	default:
+		result = &Choice{Alternatives: alternatives}
+	}
+	if empty {
+		result = &Optional{Expression: result}
+	}
+	return result, true
+}
+
+func (p *ebnfImporter) parseSingleDefinition() (Expression, bool) {
+	var items []Expression
+	for {
+		expr, ok := p.parseTerm()
+		if !ok {
+			return nil, false
+		}
+		if expr != nil {
+			items = append(items, expr)
+		}
+		if !p.isPunct(",") {
+			break
+		}
+		p.next()
+	}
+	switch len(items) {
+	case 0:
+		return nil, true
+	case 1:
+		return items[0], true
+	}
+	return &Sequence{Items: items}, true
+}
+
+// parseTerm parses a factor with an optional exception (a - b)
+func (p *ebnfImporter) parseTerm() (Expression, bool) {
+	expr, ok := p.parseFactor()
+	if !ok || !p.isPunct("-") {
+		return expr, ok
+	}
+
+	minus := p.next()
+	exception, ok := p.parseFactor()
+	if !ok {
+		return nil, false
+	}
+	if expr == nil || exception == nil {
+		p.builder.errorf(minus, "exception needs expressions on both sides of '-'")
+		return nil, false
+	}
+	p.builder.warnf(minus, "exception %s - %s approximated as negative lookahead !%s %s", expr, exception, exception, expr)
+	return &Sequence{Items: []Expression{&Predicate{Expression: exception, Positive: false}, expr}}, true
+}
+
+// parseFactor parses a primary with an optional repetition count (3 * a)
+func (p *ebnfImporter) parseFactor() (Expression, bool) {
+	if p.peek().kind == importNumber {
+		countTok := p.next()
+		count, _ := strconv.Atoi(countTok.text)
+		if !p.isPunct("*") {
+			p.builder.errorf(p.peek(), "expected '*' after repetition count %s", countTok.text)
+			return nil, false
+		}
+		p.next()
+		expr, ok := p.parsePrimary()
+		if !ok || expr == nil {
+			if ok {
+				p.builder.errorf(countTok, "repetition count needs an expression")
+			}
+			return nil, false
+		}
+		return &Repetition{Expression: groupCompound(expr), Min: count, Max: count, Greedy: true}, true
+	}
+	return p.parsePrimary()
+}
+
+func (p *ebnfImporter) parsePrimary() (Expression, bool) {
+	tok := p.peek()
+	switch {
+	case tok.kind == importIdent:
+		return &RuleRef{Name: p.metaIdentifier()}, true
+	case tok.kind == importString:
+		p.next()
+		if tok.text == "" {
+			p.builder.errorf(tok, "empty terminal string")
+			return nil, false
+		}
+		return &RuleRef{Name: p.builder.literalToken(tok.text)}, true
+	case tok.kind == importSpecial:
+		p.next()
+		p.builder.errorf(tok, "special sequence ? %s ? is not supported; define it as a token", tok.text)
+		return nil, false
+	case p.isPunct("["):
+		return p.parseBracketed("]", func(expr Expression) Expression { return &Optional{Expression: expr} })
+	case p.isPunct("{"):
+		return p.parseBracketed("}", func(expr Expression) Expression {
+			return &Repetition{Expression: groupCompound(expr), Min: 0, Max: -1, Greedy: true}
+		})
+	case p.isPunct("("):
+		return p.parseBracketed(")", func(expr Expression) Expression { return &Group{Expression: expr} })
+	}
+	// Empty sequence
+	return nil, true
+}
+
+func (p *ebnfImporter) parseBracketed(close string, wrap func(Expression) Expression) (Expression, bool) {
+	open := p.next()
+	expr, ok := p.parseDefinitions()
+	if !ok {
+		return nil, false
+	}
+	if !p.isPunct(close) {
+		p.builder.errorf(p.peek(), "expected '%s' to close '%s' at line %d, found %s", close, open.text, open.line, describeImportToken(p.peek()))
+		return nil, false
+	}
+	p.next()
+	if expr == nil {
+		return nil, true
+	}
+	return wrap(expr), true
+}
+
+func describeImportToken(tok importToken) string {
+	switch tok.kind {
+	case importEOF:
+		return "end of input"
+	case importString:
+		return strconv.Quote(tok.text)
+	}
+	return "'" + tok.text + "'"
+}
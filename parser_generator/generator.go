@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // Config holds generator configuration
@@ -65,6 +66,10 @@ func (g *Generator) Validate(grammar *Grammar) error {
 		if tokenNames[token.Name] {
 			return fmt.Errorf("duplicate token name: %s", token.Name)
 		}
+		switch strings.ToUpper(token.Name) {
+		case "LITERAL", "EOF", "INVALID":
+			return fmt.Errorf("token name %s is reserved", token.Name)
+		}
 		tokenNames[token.Name] = true
 
 		if err := token.Validate(); err != nil {
@@ -90,6 +95,10 @@ func (g *Generator) Validate(grammar *Grammar) error {
 		if err := g.validateExpression(rule.Expression, grammar); err != nil {
 			return fmt.Errorf("rule '%s': %w", rule.Name, err)
 		}
+
+		if rule.ASTNode != "" && rule.Action == "" && !astNodeBuilders[rule.ASTNode] {
+			return fmt.Errorf("rule '%s': unknown AST node %s; add an action to build it", rule.Name, rule.ASTNode)
+		}
 	}
 
 	// Check for left recursion
@@ -138,65 +147,13 @@ func (g *Generator) validateExpression(expr Expression, grammar *Grammar) error
 	return nil
 }
 
-// checkLeftRecursion detects problematic left recursion
+// checkLeftRecursion rejects rules that reach themselves without consuming
+// a token, directly, indirectly or through a nullable prefix
 func (g *Generator) checkLeftRecursion(grammar *Grammar) error {
-	visiting := make(map[string]bool)
-	visited := make(map[string]bool)
-
-	var checkRule func(string) error
-	checkRule = func(ruleName string) error {
-		if visiting[ruleName] {
-			return fmt.Errorf("left recursion detected in rule: %s", ruleName)
-		}
-		if visited[ruleName] {
-			return nil
-		}
-
-		visiting[ruleName] = true
-		defer func() { visiting[ruleName] = false }()
-
-		rule := grammar.GetRuleByName(ruleName)
-		if rule != nil {
-			if err := g.checkExpressionLeftRecursion(rule.Expression, ruleName, checkRule, grammar); err != nil {
-				return err
-			}
-		}
-
-		visited[ruleName] = true
-		return nil
-	}
-
-	for _, rule := range grammar.Rules {
-		if err := checkRule(rule.Name); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-// checkExpressionLeftRecursion checks for left recursion in expressions
-func (g *Generator) checkExpressionLeftRecursion(expr Expression, currentRule string, checkRule func(string) error, grammar *Grammar) error {
-	switch e := expr.(type) {
-	case *RuleRef:
-		if e.Name == currentRule {
-			return fmt.Errorf("direct left recursion in rule: %s", currentRule)
-		}
-		if grammar.GetRuleByName(e.Name) != nil {
-			return checkRule(e.Name)
-		}
-	case *Sequence:
-		if len(e.Items) > 0 {
-			return g.checkExpressionLeftRecursion(e.Items[0], currentRule, checkRule, grammar)
-		}
-	case *Choice:
-		for _, alt := range e.Alternatives {
-			if err := g.checkExpressionLeftRecursion(alt, currentRule, checkRule, grammar); err != nil {
-				return err
-			}
-		}
-	case *Group:
-		return g.checkExpressionLeftRecursion(e.Expression, currentRule, checkRule, grammar)
+	analyzer := newGrammarAnalyzer(grammar)
+	analyzer.computeFirst()
+	if cycles := analyzer.leftRecursion(); len(cycles) > 0 {
+		return fmt.Errorf("left recursion detected: %s", strings.Join(cycles[0], " -> "))
 	}
 	return nil
 }
@@ -239,13 +196,9 @@ func (g *Generator) GenerateTypeScript(grammar *Grammar, outputDir string) error
 	return nil
 }
 
-// ParseGrammarFile parses a grammar file
+// ParseGrammarFile parses a grammar file, importing .ebnf and .g4 files
+// by extension. Use ImportGrammarFile to see import warnings.
 func ParseGrammarFile(filename string) (*Grammar, error) {
-	content, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
-	}
-
-	parser := NewGrammarParser(string(content))
-	return parser.ParseGrammar()
+	grammar, _, err := ImportGrammarFile(filename)
+	return grammar, err
 }
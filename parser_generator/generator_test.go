@@ -61,6 +61,44 @@ list -> NUMBER (COMMA NUMBER)*`,
 				return len(g.Rules) == 1
 			},
 		},
+		{
+			name: "alternatives on continuation lines",
+			input: `%name "Continuation Grammar"
+%start expr
+
+%token NUMBER /\d+/
+%token IDENTIFIER /[a-zA-Z]+/
+
+expr -> NUMBER
+  | IDENTIFIER
+  => Identifier`,
+			wantErr: false,
+			expected: func(g *Grammar) bool {
+				choice, ok := g.Rules[0].Expression.(*Choice)
+				return ok && len(choice.Alternatives) == 2 && g.Rules[0].ASTNode == "Identifier"
+			},
+		},
+		{
+			name: "grammar with predicates and action",
+			input: `%name "Predicate Grammar"
+%start expr
+
+%token NUMBER /\d+/
+%token LPAREN "("
+
+expr -> &NUMBER NUMBER !LPAREN { return nil, nil }`,
+			wantErr: false,
+			expected: func(g *Grammar) bool {
+				seq, ok := g.Rules[0].Expression.(*Sequence)
+				if !ok || len(seq.Items) != 3 {
+					return false
+				}
+				and, andOK := seq.Items[0].(*Predicate)
+				not, notOK := seq.Items[2].(*Predicate)
+				return andOK && and.Positive && notOK && !not.Positive &&
+					g.Rules[0].Action == "return nil, nil"
+			},
+		},
 		{
 			name: "grammar with undefined reference",
 			input: `%name "Bad Grammar"
@@ -2,7 +2,8 @@ package parser_generator
 
 import (
 	"fmt"
-	"sort"
+	"go/format"
+	"strconv"
 	"strings"
 )
 
@@ -16,10 +17,9 @@ func NewGoGenerator(config *Config) *GoGenerator {
 	return &GoGenerator{config: config}
 }
 
-// Generate creates complete Go parser code from grammar
-// In your go_generator.go Generate method, make sure you have this sequence:
-
-// You'll also need to update your Generate method to call these correctly:
+// Generate creates complete Go parser code from grammar. Each rule becomes a
+// memoizing PEG parse method built from ordered choice, repetition and
+// predicate combinators over the token stream.
 func (g *GoGenerator) Generate(grammar *Grammar) (string, error) {
 	var buf strings.Builder
 
@@ -44,20 +44,28 @@ func (g *GoGenerator) Generate(grammar *Grammar) (string, error) {
 	// Lexer implementation
 	g.generateLexerImplementation(&buf, grammar)
 
-	// Parser implementation
+	// Packrat parser runtime and rule methods
 	g.generateParserImplementation(&buf, grammar)
+	if err := g.generateRuleMethods(&buf, grammar); err != nil {
+		return "", err
+	}
+
+	// AST construction
+	g.generateASTBuilders(&buf)
 
 	// Utility functions
-	g.generateUtilityFunctions(&buf)
+	g.generateUtilityFunctions(&buf, grammar)
 
 	// Public API functions
 	g.generatePublicAPIFunctions(&buf)
 
-	return buf.String(), nil
+	code, err := format.Source([]byte(buf.String()))
+	if err != nil {
+		return "", fmt.Errorf("generated parser is not valid Go: %w", err)
+	}
+	return string(code), nil
 }
 
-// Add these missing methods to your go_generator.go file:
-
 func (g *GoGenerator) generateExpressionInterface(buf *strings.Builder) {
 	buf.WriteString(`// ===== CORE AST INTERFACE =====
 
@@ -209,8 +217,17 @@ type Visitor interface {
 `)
 }
 
-func (g *GoGenerator) generateUtilityFunctions(buf *strings.Builder) {
-	buf.WriteString(`// ===== UTILITY FUNCTIONS =====
+func (g *GoGenerator) generateUtilityFunctions(buf *strings.Builder, grammar *Grammar) {
+	// The type analyzer only mentions operators the grammar declares
+	cases := strings.NewReplacer(
+		"\t{{binary}}\n", typeCase(grammar, "TYPE_NUMBER", "PLUS", "MINUS", "MULTIPLY", "DIVIDE", "MODULO")+
+			typeCase(grammar, "TYPE_BOOLEAN", "EQ", "NE", "LT", "LE", "GT", "GE", "AND", "OR", "IMPLIES_OP", "EQUIV_OP"),
+		"\t{{unary}}\n", typeCase(grammar, "TYPE_NUMBER", "MINUS", "PLUS")+
+			typeCase(grammar, "TYPE_BOOLEAN", "NOT"),
+		"\t{{function}}\n", typeCase(grammar, "TYPE_NUMBER", "ABS", "NEGATE", "CEIL", "FLOOR", "MIN", "MAX")+
+			typeCase(grammar, "TYPE_BOOLEAN", "THRESHOLD", "IMPLIES", "EQUIV", "XOR"),
+	)
+	buf.WriteString(cases.Replace(`// ===== UTILITY FUNCTIONS =====
 
 // CollectVariables extracts all variable names from an expression
 func CollectVariables(expr Expression) []string {
@@ -290,10 +307,7 @@ func (t *TypeAnalyzer) VisitIdentifier(node *Identifier) (interface{}, error) {
 
 func (t *TypeAnalyzer) VisitBinaryOperation(node *BinaryOperation) (interface{}, error) {
 	switch node.Operator {
-	case TOKEN_PLUS, TOKEN_MINUS, TOKEN_MULTIPLY, TOKEN_DIVIDE, TOKEN_MODULO:
-		return TYPE_NUMBER, nil
-	case TOKEN_EQ, TOKEN_NE, TOKEN_LT, TOKEN_LE, TOKEN_GT, TOKEN_GE, TOKEN_AND, TOKEN_OR:
-		return TYPE_BOOLEAN, nil
+	{{binary}}
 	default:
 		return TYPE_UNKNOWN, nil
 	}
@@ -301,10 +315,7 @@ func (t *TypeAnalyzer) VisitBinaryOperation(node *BinaryOperation) (interface{},
 
 func (t *TypeAnalyzer) VisitUnaryOperation(node *UnaryOperation) (interface{}, error) {
 	switch node.Operator {
-	case TOKEN_MINUS, TOKEN_PLUS:
-		return TYPE_NUMBER, nil
-	case TOKEN_NOT:
-		return TYPE_BOOLEAN, nil
+	{{unary}}
 	default:
 		return TYPE_UNKNOWN, nil
 	}
@@ -312,18 +323,27 @@ func (t *TypeAnalyzer) VisitUnaryOperation(node *UnaryOperation) (interface{}, e
 
 func (t *TypeAnalyzer) VisitFunctionCall(node *FunctionCall) (interface{}, error) {
 	switch node.Function {
-	case TOKEN_ABS, TOKEN_NEGATE, TOKEN_CEIL, TOKEN_FLOOR:
-		return TYPE_NUMBER, nil
-	case TOKEN_THRESHOLD, TOKEN_IMPLIES, TOKEN_EQUIV, TOKEN_XOR:
-		return TYPE_BOOLEAN, nil
-	case TOKEN_ITE:
-		return TYPE_UNKNOWN, nil // Depends on then/else branches
+	{{function}}
 	default:
 		return TYPE_UNKNOWN, nil
 	}
 }
 
-`)
+`))
+}
+
+// typeCase renders a type analyzer case for the named tokens the grammar declares
+func typeCase(grammar *Grammar, exprType string, names ...string) string {
+	var constants []string
+	for _, name := range names {
+		if grammar.GetTokenByName(name) != nil {
+			constants = append(constants, "TOKEN_"+strings.ToUpper(name))
+		}
+	}
+	if len(constants) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\tcase %s:\n\t\treturn %s, nil\n", strings.Join(constants, ", "), exprType)
 }
 
 func (g *GoGenerator) generatePublicAPIFunctions(buf *strings.Builder) {
@@ -367,10 +387,11 @@ package %s
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
-	"unicode"
+	"unicode/utf8"
 )
 
 `, g.config.PackageName, g.config.PackageName))
@@ -492,37 +513,42 @@ func (g *GoGenerator) generateTokenDefinitions(buf *strings.Builder, grammar *Gr
 	buf.WriteString("// ===== TOKEN DEFINITIONS =====\n\n")
 	buf.WriteString("type TokenType int\n\n")
 	buf.WriteString("const (\n")
-
-	// Generate token constants
 	for i, token := range grammar.Tokens {
-		if token.Skip {
-			continue // Skip whitespace tokens in enum
+		if i == 0 {
+			buf.WriteString(fmt.Sprintf("\tTOKEN_%s TokenType = iota\n", strings.ToUpper(token.Name)))
+		} else {
+			buf.WriteString(fmt.Sprintf("\tTOKEN_%s\n", strings.ToUpper(token.Name)))
 		}
-		buf.WriteString(fmt.Sprintf("\tTOKEN_%s TokenType = %d\n",
-			strings.ToUpper(token.Name), i))
 	}
-
+	if len(grammar.Tokens) == 0 {
+		buf.WriteString("\tTOKEN_LITERAL TokenType = iota // Rule literals without a token of their own\n")
+	} else {
+		buf.WriteString("\tTOKEN_LITERAL // Rule literals without a token of their own\n")
+	}
 	buf.WriteString("\tTOKEN_EOF\n")
 	buf.WriteString("\tTOKEN_INVALID\n")
 	buf.WriteString(")\n\n")
 
-	// Generate token names map
+	// Display names and the labels used in "Expected ..." messages
 	buf.WriteString("var tokenNames = map[TokenType]string{\n")
 	for _, token := range grammar.Tokens {
-		if token.Skip {
-			continue
-		}
 		displayName := token.Name
 		if token.Literal != "" {
 			displayName = token.Literal
 		}
-		buf.WriteString(fmt.Sprintf("\tTOKEN_%s: \"%s\",\n",
-			strings.ToUpper(token.Name), displayName))
+		buf.WriteString(fmt.Sprintf("\tTOKEN_%s: %s,\n", strings.ToUpper(token.Name), strconv.Quote(displayName)))
 	}
+	buf.WriteString("\tTOKEN_LITERAL: \"LITERAL\",\n")
 	buf.WriteString("\tTOKEN_EOF: \"EOF\",\n")
 	buf.WriteString("\tTOKEN_INVALID: \"INVALID\",\n")
 	buf.WriteString("}\n\n")
 
+	buf.WriteString("var tokenLabels = map[TokenType]string{\n")
+	for _, token := range grammar.Tokens {
+		buf.WriteString(fmt.Sprintf("\tTOKEN_%s: %s,\n", strings.ToUpper(token.Name), strconv.Quote(tokenLabel(token))))
+	}
+	buf.WriteString("}\n\n")
+
 	buf.WriteString(`func (t TokenType) String() string {
 	if name, exists := tokenNames[t]; exists {
 		return name
@@ -544,1262 +570,666 @@ func (t Token) String() string {
 `)
 }
 
-func (g *GoGenerator) generateASTDefinitions(buf *strings.Builder, grammar *Grammar) {
-	buf.WriteString(`// ===== VISITOR PATTERN INTERFACE =====
+func (g *GoGenerator) generateLexerImplementation(buf *strings.Builder, grammar *Grammar) {
+	buf.WriteString(`// ===== LEXER =====
 
-// Visitor defines the interface for processing AST nodes
-type Visitor interface {
-	VisitNumberLiteral(node *NumberLiteral) (interface{}, error)
-	VisitBooleanLiteral(node *BooleanLiteral) (interface{}, error)
-	VisitIdentifier(node *Identifier) (interface{}, error)
-	VisitBinaryOperation(node *BinaryOperation) (interface{}, error)
-	VisitUnaryOperation(node *UnaryOperation) (interface{}, error)
-	VisitFunctionCall(node *FunctionCall) (interface{}, error)
+// tokenRule matches one token type at the start of the remaining input
+type tokenRule struct {
+	Type    TokenType
+	Literal string
+	Pattern *regexp.Regexp
+	Skip    bool
 }
 
-// ===== CORE AST INTERFACE =====
-
-// Expression is the base interface for all AST nodes
-type Expression interface {
-	Accept(visitor Visitor) (interface{}, error)
-	GetRange() SourceRange
-	String() string
-}
+var tokenRules = []tokenRule{
+`)
+	for _, token := range lexerTokens(grammar) {
+		name := "TOKEN_" + strings.ToUpper(token.Name)
+		var match string
+		if token.Literal != "" {
+			match = "Literal: " + strconv.Quote(token.Literal)
+		} else {
+			match = fmt.Sprintf("Pattern: regexp.MustCompile(%s)", strconv.Quote("^(?:"+token.Pattern+")"))
+		}
+		if token.Skip {
+			match += ", Skip: true"
+		}
+		buf.WriteString(fmt.Sprintf("\t{Type: %s, %s},\n", name, match))
+	}
+	for _, literal := range implicitLiterals(grammar) {
+		buf.WriteString(fmt.Sprintf("\t{Type: TOKEN_LITERAL, Literal: %s},\n", strconv.Quote(literal)))
+	}
 
-// ===== AST NODE IMPLEMENTATIONS =====
+	buf.WriteString(`}
 
-// NumberLiteral represents a numeric constant
-type NumberLiteral struct {
-	Value float64
-	Range SourceRange
+// match returns the length of the rule's match at the start of input, or -1
+func (r tokenRule) match(input string) int {
+	if r.Pattern == nil {
+		if strings.HasPrefix(input, r.Literal) {
+			return len(r.Literal)
+		}
+		return -1
+	}
+	if loc := r.Pattern.FindStringIndex(input); loc != nil {
+		return loc[1]
+	}
+	return -1
 }
 
-func (n *NumberLiteral) Accept(visitor Visitor) (interface{}, error) {
-	return visitor.VisitNumberLiteral(n)
+type Lexer struct {
+	input    string
+	position int
+	line     int
+	column   int
 }
 
-func (n *NumberLiteral) GetRange() SourceRange {
-	return n.Range
+func NewLexer(input string) *Lexer {
+	return &Lexer{
+		input:    input,
+		position: 0,
+		line:     1,
+		column:   1,
+	}
 }
 
-func (n *NumberLiteral) String() string {
-	return fmt.Sprintf("%.6g", n.Value)
+func (l *Lexer) currentPos() Position {
+	return Position{Line: l.line, Column: l.column, Offset: l.position}
 }
 
-// BooleanLiteral represents a boolean constant
-type BooleanLiteral struct {
-	Value bool
-	Range SourceRange
+// advance consumes n bytes, counting columns in characters
+func (l *Lexer) advance(n int) {
+	for _, ch := range l.input[l.position : l.position+n] {
+		if ch == '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+	}
+	l.position += n
 }
 
-func (b *BooleanLiteral) Accept(visitor Visitor) (interface{}, error) {
-	return visitor.VisitBooleanLiteral(b)
-}
+// NextToken returns the longest match among the token rules. On equal
+// length a literal beats a pattern, then the earlier declaration wins.
+func (l *Lexer) NextToken() (Token, error) {
+	for {
+		start := l.currentPos()
+		if l.position >= len(l.input) {
+			return Token{
+				Type:  TOKEN_EOF,
+				Value: "",
+				Range: SourceRange{Start: start, End: start, Text: ""},
+			}, nil
+		}
 
-func (b *BooleanLiteral) GetRange() SourceRange {
-	return b.Range
-}
+		rest := l.input[l.position:]
+		best, length := -1, 0
+		for i, rule := range tokenRules {
+			n := rule.match(rest)
+			if n > length || (n > 0 && n == length && rule.Pattern == nil && tokenRules[best].Pattern != nil) {
+				best, length = i, n
+			}
+		}
 
-func (b *BooleanLiteral) String() string {
-	return fmt.Sprintf("%t", b.Value)
-}
+		if best < 0 {
+			ch, size := utf8.DecodeRuneInString(rest)
+			l.advance(size)
+			return Token{}, &ParseError{
+				Message:    fmt.Sprintf("Unexpected character '%c'", ch),
+				Range:      SourceRange{Start: start, End: l.currentPos(), Text: string(ch)},
+				SourceText: l.input,
+				ErrorType:  "lexical",
+				Suggestion: "Remove or replace this character",
+			}
+		}
 
-// Identifier represents a variable reference
-type Identifier struct {
-	Name  string
-	Range SourceRange
+		text := rest[:length]
+		l.advance(length)
+		if tokenRules[best].Skip {
+			continue
+		}
+		return Token{
+			Type:  tokenRules[best].Type,
+			Value: text,
+			Range: SourceRange{Start: start, End: l.currentPos(), Text: text},
+		}, nil
+	}
 }
 
-func (i *Identifier) Accept(visitor Visitor) (interface{}, error) {
-	return visitor.VisitIdentifier(i)
+`)
 }
 
-func (i *Identifier) GetRange() SourceRange {
-	return i.Range
-}
+func (g *GoGenerator) generateParserImplementation(buf *strings.Builder, grammar *Grammar) {
+	buf.WriteString(`// ===== PARSER =====
 
-func (i *Identifier) String() string {
-	return i.Name
-}
+// matcher consumes tokens for one grammar expression and appends what it
+// matched to items. A matcher that fails consumes nothing.
+type matcher func(items *[]interface{}) bool
 
-// BinaryOperation represents binary operations like +, -, AND, OR, etc.
-type BinaryOperation struct {
-	Left     Expression
-	Operator TokenType
-	Right    Expression
-	Range    SourceRange
-}
+// builder constructs a rule's AST node from the items the rule matched
+type builder func(items []interface{}, rng SourceRange) (Expression, error)
 
-func (b *BinaryOperation) Accept(visitor Visitor) (interface{}, error) {
-	return visitor.VisitBinaryOperation(b)
+type memoKey struct {
+	rule int
+	pos  int
 }
 
-func (b *BinaryOperation) GetRange() SourceRange {
-	return b.Range
+type memoEntry struct {
+	items []interface{}
+	end   int
+	ok    bool
 }
 
-func (b *BinaryOperation) String() string {
-	return fmt.Sprintf("(%s %s %s)", b.Left.String(), b.Operator, b.Right.String())
+// Parser is a packrat parser: every rule result is memoized by token
+// position, so backtracking never re-parses a rule at the same place.
+type Parser struct {
+	sourceText string
+	tokens     []Token
+	lexErr     error
+	pos        int
+	memo       map[memoKey]*memoEntry
+	farthest   int             // Furthest token position where a match failed
+	expected   map[string]bool // What would have matched at farthest
+	quiet      int             // Predicate depth; failures inside predicates are not reported
+	buildErr   error
 }
 
-// UnaryOperation represents unary operations like -, +, NOT
-type UnaryOperation struct {
-	Operator TokenType
-	Operand  Expression
-	Range    SourceRange
-}
+func NewParser(input string) *Parser {
+	p := &Parser{
+		sourceText: input,
+		memo:       make(map[memoKey]*memoEntry),
+		expected:   make(map[string]bool),
+	}
 
-func (u *UnaryOperation) Accept(visitor Visitor) (interface{}, error) {
-	return visitor.VisitUnaryOperation(u)
+	lexer := NewLexer(input)
+	for {
+		token, err := lexer.NextToken()
+		if err != nil {
+			p.lexErr = err
+			break
+		}
+		p.tokens = append(p.tokens, token)
+		if token.Type == TOKEN_EOF {
+			break
+		}
+	}
+	return p
 }
 
-func (u *UnaryOperation) GetRange() SourceRange {
-	return u.Range
-}
+// Parse parses the input and returns the AST root
+func (p *Parser) Parse() (Expression, error) {
+	if p.lexErr != nil {
+		return nil, p.lexErr
+	}
 
-func (u *UnaryOperation) String() string {
-	return fmt.Sprintf("(%s%s)", u.Operator, u.Operand.String())
+`)
+	buf.WriteString(fmt.Sprintf("\titems, ok := p.parse%s()\n", ruleIdent(grammar.StartRule)))
+	buf.WriteString(`	if p.buildErr != nil {
+		return nil, p.buildErr
+	}
+	if ok && p.tokens[p.pos].Type != TOKEN_EOF {
+		p.fail("end of input")
+		ok = false
+	}
+	if !ok {
+		return nil, p.syntaxError()
+	}
+	expr, err := toExpression(items, p.span(0, p.pos))
+	if parseErr, isParseErr := err.(*ParseError); isParseErr {
+		parseErr.SourceText = p.sourceText
+	}
+	return expr, err
 }
 
-// FunctionCall represents function calls like ABS(x), ITE(cond, then, else)
-type FunctionCall struct {
-	Function TokenType
-	Args     []Expression
-	Range    SourceRange
-}
+// memoize runs a rule body once per position and builds its AST node
+func (p *Parser) memoize(rule int, build builder, body matcher) ([]interface{}, bool) {
+	key := memoKey{rule: rule, pos: p.pos}
+	if entry, exists := p.memo[key]; exists {
+		if entry.ok {
+			p.pos = entry.end
+		}
+		return entry.items, entry.ok
+	}
 
-func (f *FunctionCall) Accept(visitor Visitor) (interface{}, error) {
-	return visitor.VisitFunctionCall(f)
-}
+	start := p.pos
+	var items []interface{}
+	ok := body(&items)
+	if ok && build != nil {
+		node, err := build(items, p.span(start, p.pos))
+		if err != nil {
+			if parseErr, isParseErr := err.(*ParseError); isParseErr && parseErr.SourceText == "" {
+				parseErr.SourceText = p.sourceText
+			}
+			if p.buildErr == nil {
+				p.buildErr = err
+			}
+			ok = false
+		} else {
+			items = []interface{}{node}
+		}
+	} else if ok {
+		items = collapse(items)
+	}
+	if !ok {
+		p.pos = start
+		items = nil
+	}
 
-func (f *FunctionCall) GetRange() SourceRange {
-	return f.Range
+	p.memo[key] = &memoEntry{items: items, end: p.pos, ok: ok}
+	return items, ok
 }
 
-func (f *FunctionCall) String() string {
-	var argStrs []string
-	for _, arg := range f.Args {
-		argStrs = append(argStrs, arg.String())
+// seq matches each part in order
+func (p *Parser) seq(parts ...matcher) matcher {
+	return func(items *[]interface{}) bool {
+		pos, n := p.pos, len(*items)
+		for _, part := range parts {
+			if !part(items) {
+				p.pos, *items = pos, (*items)[:n]
+				return false
+			}
+		}
+		return true
 	}
-	return fmt.Sprintf("%s(%s)", f.Function, strings.Join(argStrs, ", "))
 }
 
-`)
+// choice is PEG ordered choice: the first alternative that matches wins
+func (p *Parser) choice(alternatives ...matcher) matcher {
+	return func(items *[]interface{}) bool {
+		for _, alternative := range alternatives {
+			if alternative(items) {
+				return true
+			}
+		}
+		return false
+	}
 }
 
-func (g *GoGenerator) generateLexerImplementation(buf *strings.Builder, grammar *Grammar) {
-	buf.WriteString(`// ===== LEXER =====
+// rep matches m greedily between min and max times; max < 0 is unbounded
+func (p *Parser) rep(min, max int, m matcher) matcher {
+	return func(items *[]interface{}) bool {
+		pos, n := p.pos, len(*items)
+		count := 0
+		for max < 0 || count < max {
+			before := p.pos
+			if !m(items) {
+				break
+			}
+			count++
+			if p.pos == before {
+				break // An empty match would repeat forever
+			}
+		}
+		if count < min {
+			p.pos, *items = pos, (*items)[:n]
+			return false
+		}
+		return true
+	}
+}
 
-type Lexer struct {
-	input    string
-	position int
-	line     int
-	column   int
-	startPos Position
-	keywords map[string]TokenType
+// opt matches m if possible
+func (p *Parser) opt(m matcher) matcher {
+	return func(items *[]interface{}) bool {
+		m(items)
+		return true
+	}
 }
 
-func NewLexer(input string) *Lexer {
-	keywords := map[string]TokenType{
-`)
+// and succeeds when m matches, without consuming input
+func (p *Parser) and(m matcher) matcher {
+	return func(items *[]interface{}) bool {
+		return p.lookahead(m, items)
+	}
+}
 
-	// Generate keywords map from grammar
-	for _, token := range grammar.Tokens {
-		if token.Skip {
-			continue
-		}
-		if token.Literal != "" && isIdentifierLike(token.Literal) {
-			buf.WriteString(fmt.Sprintf("\t\t\"%s\": TOKEN_%s,\n",
-				token.Literal, strings.ToUpper(token.Name)))
-			// Also add case variations if it looks like a keyword
-			if isAllUpper(token.Literal) {
-				lowerVersion := strings.ToLower(token.Literal)
-				buf.WriteString(fmt.Sprintf("\t\t\"%s\": TOKEN_%s,\n",
-					lowerVersion, strings.ToUpper(token.Name)))
-			}
-		}
+// not succeeds when m does not match, without consuming input
+func (p *Parser) not(m matcher) matcher {
+	return func(items *[]interface{}) bool {
+		return !p.lookahead(m, items)
 	}
+}
 
-	buf.WriteString(`	}
+func (p *Parser) lookahead(m matcher, items *[]interface{}) bool {
+	pos, n := p.pos, len(*items)
+	p.quiet++
+	ok := m(items)
+	p.quiet--
+	p.pos, *items = pos, (*items)[:n]
+	return ok
+}
 
-	return &Lexer{
-		input:    input,
-		position: 0,
-		line:     1,
-		column:   1,
-		keywords: keywords,
+// token matches one token of the given type
+func (p *Parser) token(tokenType TokenType) matcher {
+	return func(items *[]interface{}) bool {
+		token := p.tokens[p.pos]
+		if token.Type != tokenType {
+			p.fail(tokenLabels[tokenType])
+			return false
+		}
+		*items = append(*items, token)
+		p.pos++
+		return true
 	}
 }
 
-func (l *Lexer) currentPos() Position {
-	return Position{Line: l.line, Column: l.column, Offset: l.position}
+// literal matches one token with the given text, whatever its type
+func (p *Parser) literal(text string) matcher {
+	return func(items *[]interface{}) bool {
+		token := p.tokens[p.pos]
+		if token.Type == TOKEN_EOF || token.Value != text {
+			p.fail("'" + text + "'")
+			return false
+		}
+		*items = append(*items, token)
+		p.pos++
+		return true
+	}
 }
 
-func (l *Lexer) peek() rune {
-	if l.position >= len(l.input) {
-		return 0
+// ref matches a rule and appends its result
+func (p *Parser) ref(rule func() ([]interface{}, bool)) matcher {
+	return func(items *[]interface{}) bool {
+		result, ok := rule()
+		if ok {
+			*items = append(*items, result...)
+		}
+		return ok
 	}
-	return rune(l.input[l.position])
 }
 
-func (l *Lexer) peekNext() rune {
-	if l.position+1 >= len(l.input) {
-		return 0
+// fail records what was expected at the current position
+func (p *Parser) fail(label string) {
+	if p.quiet > 0 || p.pos < p.farthest {
+		return
 	}
-	return rune(l.input[l.position+1])
+	if p.pos > p.farthest {
+		p.farthest = p.pos
+		p.expected = make(map[string]bool)
+	}
+	p.expected[label] = true
 }
 
-func (l *Lexer) advance() rune {
-	if l.position >= len(l.input) {
-		return 0
+// syntaxError reports the furthest failure with everything expected there
+func (p *Parser) syntaxError() error {
+	token := p.tokens[p.farthest]
+	got := "'" + token.Value + "'"
+	if token.Type == TOKEN_EOF {
+		got = "end of input"
 	}
 
-	ch := rune(l.input[l.position])
-	l.position++
-
-	if ch == '\n' {
-		l.line++
-		l.column = 1
-	} else {
-		l.column++
+	expected := make([]string, 0, len(p.expected))
+	for label := range p.expected {
+		expected = append(expected, label)
 	}
+	sort.Strings(expected)
 
-	return ch
+	err := &ParseError{
+		Message:    fmt.Sprintf("Unexpected %s", got),
+		Range:      token.Range,
+		SourceText: p.sourceText,
+		ErrorType:  "syntax",
+	}
+	switch len(expected) {
+	case 0:
+	case 1:
+		err.Message = fmt.Sprintf("Expected %s, got %s", expected[0], got)
+		err.Suggestion = fmt.Sprintf("Add %s here", expected[0])
+	default:
+		last := len(expected) - 1
+		err.Message = fmt.Sprintf("Expected %s or %s, got %s", strings.Join(expected[:last], ", "), expected[last], got)
+	}
+	return err
 }
 
-func (l *Lexer) skipWhitespace() {
-	for unicode.IsSpace(l.peek()) {
-		l.advance()
+// span returns the source range covered by tokens [start, end)
+func (p *Parser) span(start, end int) SourceRange {
+	from := p.tokens[start].Range.Start
+	to := from
+	if end > start {
+		to = p.tokens[end-1].Range.End
 	}
+	return SourceRange{Start: from, End: to, Text: p.sourceText[from.Offset:to.Offset]}
 }
 
-func (l *Lexer) readNumber() (string, error) {
-	var result strings.Builder
-	hasDecimal := false
-
-	for {
-		ch := l.peek()
-		if unicode.IsDigit(ch) {
-			result.WriteRune(l.advance())
-		} else if ch == '.' && !hasDecimal && unicode.IsDigit(l.peekNext()) {
-			hasDecimal = true
-			result.WriteRune(l.advance())
-		} else {
-			break
-		}
-	}
-
-	return result.String(), nil
+`)
 }
 
-func (l *Lexer) readIdentifier() string {
-	var result strings.Builder
-
-	for {
-		ch := l.peek()
-		if unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '_' {
-			result.WriteRune(l.advance())
+// generateRuleMethods emits one memoized parse method per grammar rule
+func (g *GoGenerator) generateRuleMethods(buf *strings.Builder, grammar *Grammar) error {
+	buf.WriteString("// ===== GRAMMAR RULES =====\n\n")
+	buf.WriteString("const (\n")
+	for i, rule := range grammar.Rules {
+		if i == 0 {
+			buf.WriteString(fmt.Sprintf("\trule%s = iota\n", ruleIdent(rule.Name)))
 		} else {
-			break
+			buf.WriteString(fmt.Sprintf("\trule%s\n", ruleIdent(rule.Name)))
 		}
 	}
+	buf.WriteString(")\n\n")
 
-	return result.String()
-}
-
-func (l *Lexer) makeToken(tokenType TokenType, value string) Token {
-	return Token{
-		Type:  tokenType,
-		Value: value,
-		Range: SourceRange{Start: l.startPos, End: l.currentPos(), Text: value},
+	syntax := matcherSyntax{
+		self:  "p",
+		token: func(name string) string { return "TOKEN_" + strings.ToUpper(name) },
+		ref:   func(rule string) string { return "p.parse" + ruleIdent(rule) },
+		quote: strconv.Quote,
 	}
-}
-
-func (l *Lexer) NextToken() (Token, error) {
-	l.skipWhitespace()
 
-	if l.position >= len(l.input) {
-		return Token{
-			Type:  TOKEN_EOF,
-			Value: "",
-			Range: SourceRange{Start: l.currentPos(), End: l.currentPos(), Text: ""},
-		}, nil
-	}
-
-	l.startPos = l.currentPos()
-	ch := l.peek()
-
-`)
-
-	// Generate multi-character operators (longest first)
-	g.generateLexerOperators(buf, grammar)
-
-	buf.WriteString(`
-	// Numbers
-	if unicode.IsDigit(ch) {
-		value, err := l.readNumber()
+	for _, rule := range grammar.Rules {
+		body, err := emitMatcher(rule.Expression, grammar, syntax)
 		if err != nil {
-			return Token{}, &ParseError{
-				Message:    err.Error(),
-				Range:      SourceRange{Start: l.startPos, End: l.currentPos(), Text: value},
-				SourceText: l.input,
-				ErrorType:  "lexical",
-				Suggestion: "Use a valid number format like 123 or 123.45",
-			}
-		}
-		return l.makeToken(TOKEN_NUMBER, value), nil
-	}
-
-	// Identifiers and keywords
-	if unicode.IsLetter(ch) {
-		value := l.readIdentifier()
-		if tokenType, isKeyword := l.keywords[value]; isKeyword {
-			return l.makeToken(tokenType, value), nil
-		}
-		return l.makeToken(TOKEN_IDENTIFIER, value), nil
-	}
-
-	// Unknown character
-	l.advance()
-	return Token{}, &ParseError{
-		Message:    fmt.Sprintf("Unexpected character '%c'", ch),
-		Range:      SourceRange{Start: l.startPos, End: l.currentPos(), Text: string(ch)},
-		SourceText: l.input,
-		ErrorType:  "lexical",
-		Suggestion: "Remove or replace this character",
-	}
-}
-
-`)
-}
-
-func (g *GoGenerator) generateLexerOperators(buf *strings.Builder, grammar *Grammar) {
-	// Collect and sort operators by length (longest first)
-	type operator struct {
-		literal   string
-		tokenName string
-	}
-
-	var singleChar []operator
-	var multiChar []operator
-
-	for _, token := range grammar.Tokens {
-		if token.Skip || token.Literal == "" {
-			continue
-		}
-
-		op := operator{
-			literal:   token.Literal,
-			tokenName: strings.ToUpper(token.Name),
+			return fmt.Errorf("rule '%s': %w", rule.Name, err)
 		}
 
-		if len(token.Literal) == 1 {
-			singleChar = append(singleChar, op)
-		} else {
-			multiChar = append(multiChar, op)
+		name := ruleIdent(rule.Name)
+		build := "nil"
+		switch {
+		case rule.Action != "":
+			build = "build" + name + "Action"
+		case rule.ASTNode != "":
+			build = "build" + rule.ASTNode
 		}
-	}
 
-	// Sort multi-character operators by length (longest first)
-	sort.Slice(multiChar, func(i, j int) bool {
-		return len(multiChar[i].literal) > len(multiChar[j].literal)
-	})
-
-	// Generate multi-character operators with proper lookahead
-	if len(multiChar) > 0 {
-		buf.WriteString("\t// Multi-character operators\n")
-		for _, op := range multiChar {
-			literal := op.literal
-			tokenName := op.tokenName
-
-			if len(literal) == 2 {
-				buf.WriteString(fmt.Sprintf("\tif ch == '%c' && l.peekNext() == '%c' {\n",
-					literal[0], literal[1]))
-				buf.WriteString("\t\tl.advance()\n")
-				buf.WriteString("\t\tl.advance()\n")
-				buf.WriteString(fmt.Sprintf("\t\treturn l.makeToken(TOKEN_%s, \"%s\"), nil\n",
-					tokenName, literal))
-				buf.WriteString("\t}\n")
-			} else if len(literal) == 3 {
-				buf.WriteString(fmt.Sprintf("\tif ch == '%c' && l.peekNext() == '%c' {\n",
-					literal[0], literal[1]))
-				buf.WriteString("\t\t// Check third character\n")
-				buf.WriteString(fmt.Sprintf("\t\tif l.position+2 < len(l.input) && rune(l.input[l.position+2]) == '%c' {\n", literal[2]))
-				buf.WriteString("\t\t\tl.advance()\n")
-				buf.WriteString("\t\t\tl.advance()\n")
-				buf.WriteString("\t\t\tl.advance()\n")
-				buf.WriteString(fmt.Sprintf("\t\t\treturn l.makeToken(TOKEN_%s, \"%s\"), nil\n",
-					tokenName, literal))
-				buf.WriteString("\t\t}\n")
-				buf.WriteString("\t}\n")
-			}
-		}
-		buf.WriteString("\n")
-	}
-
-	// Generate single-character operators
-	if len(singleChar) > 0 {
-		buf.WriteString("\t// Single character tokens\n")
-		buf.WriteString("\tswitch ch {\n")
-		for _, op := range singleChar {
-			buf.WriteString(fmt.Sprintf("\tcase '%s':\n", op.literal))
-			buf.WriteString("\t\tl.advance()\n")
-			buf.WriteString(fmt.Sprintf("\t\treturn l.makeToken(TOKEN_%s, \"%s\"), nil\n",
-				op.tokenName, op.literal))
+		buf.WriteString(fmt.Sprintf("// parse%s implements: %s -> %s\n", name, rule.Name, rule.Expression))
+		buf.WriteString(fmt.Sprintf("func (p *Parser) parse%s() ([]interface{}, bool) {\n", name))
+		buf.WriteString(fmt.Sprintf("\treturn p.memoize(rule%s, %s, func(items *[]interface{}) bool {\n", name, build))
+		buf.WriteString(fmt.Sprintf("\t\treturn %s(items)\n", body))
+		buf.WriteString("\t})\n}\n\n")
+
+		if rule.Action != "" {
+			buf.WriteString(fmt.Sprintf("// build%sAction is the semantic action of rule %s\n", name, rule.Name))
+			buf.WriteString(fmt.Sprintf("func build%sAction(items []interface{}, rng SourceRange) (Expression, error) {\n", name))
+			buf.WriteString(rule.Action)
+			buf.WriteString("\n}\n\n")
 		}
-		buf.WriteString("\t}\n")
-	}
-}
-
-func (g *GoGenerator) generateParserImplementation(buf *strings.Builder, grammar *Grammar) {
-	buf.WriteString(`// ===== PARSER =====
-
-type Parser struct {
-	lexer        *Lexer
-	currentToken Token
-	sourceText   string
-}
-
-func NewParser(input string) *Parser {
-	lexer := NewLexer(input)
-	return &Parser{
-		lexer:      lexer,
-		sourceText: input,
 	}
-}
-
-func (p *Parser) advance() error {
-	token, err := p.lexer.NextToken()
-	if err != nil {
-		return err
-	}
-	p.currentToken = token
 	return nil
 }
 
-func (p *Parser) expect(tokenType TokenType) error {
-	if p.currentToken.Type != tokenType {
-		return &ParseError{
-			Message:    fmt.Sprintf("Expected %s, got %s", tokenType, p.currentToken.Type),
-			Range:      p.currentToken.Range,
-			SourceText: p.sourceText,
-			ErrorType:  "syntax",
-			Suggestion: fmt.Sprintf("Add %s here", tokenType),
-		}
-	}
-	return p.advance()
-}
+func (g *GoGenerator) generateASTBuilders(buf *strings.Builder) {
+	buf.WriteString(`// ===== AST CONSTRUCTION =====
 
-func (p *Parser) match(tokenTypes ...TokenType) bool {
-	for _, tokenType := range tokenTypes {
-		if p.currentToken.Type == tokenType {
-			return true
+// collapse reduces the items of a rule without an AST node to its single
+// expression, dropping delimiter tokens. Other item lists pass through.
+func collapse(items []interface{}) []interface{} {
+	var found Expression
+	for _, item := range items {
+		if expr, ok := item.(Expression); ok {
+			if found != nil {
+				return items
+			}
+			found = expr
 		}
 	}
-	return false
-}
-
-// Parse parses the input and returns the AST root
-func (p *Parser) Parse() (Expression, error) {
-	err := p.advance() // Load first token
-	if err != nil {
-		return nil, err
-	}
-
-	expr, err := p.parseExpression()
-	if err != nil {
-		return nil, err
+	if found == nil {
+		return items
 	}
+	return []interface{}{found}
+}
 
-	if p.currentToken.Type != TOKEN_EOF {
+// toExpression converts the start rule's items into the AST root
+func toExpression(items []interface{}, rng SourceRange) (Expression, error) {
+	if len(items) != 1 {
 		return nil, &ParseError{
-			Message:    fmt.Sprintf("Unexpected token after expression: %s", p.currentToken.Type),
-			Range:      p.currentToken.Range,
-			SourceText: p.sourceText,
+			Message:    "Input does not form a single expression",
+			Range:      rng,
 			ErrorType:  "syntax",
-			Suggestion: "Remove extra tokens or check expression syntax",
+			Suggestion: "Give the start rule an AST node",
 		}
 	}
-
-	return expr, nil
-}
-
-`)
-
-	// Generate parsing methods for each rule
-	g.generateParsingMethods(buf, grammar)
-}
-
-func (g *GoGenerator) generateRuleMethod(buf *strings.Builder, rule *Rule, grammar *Grammar) {
-	methodName := fmt.Sprintf("parse%s", capitalizeFirst(rule.Name))
-
-	buf.WriteString(fmt.Sprintf("// %s implements: %s\n", methodName, rule.Expression.String()))
-	buf.WriteString(fmt.Sprintf("func (p *Parser) %s() (Expression, error) {\n", methodName))
-
-	// Analyze the rule and generate appropriate parsing code
-	g.generateExpressionParsingCode(buf, rule.Expression, grammar, 1)
-
-	buf.WriteString("}\n\n")
+	return operand(items[0])
 }
 
-func (g *GoGenerator) generateExpressionParsingCode(buf *strings.Builder, expr Expression, grammar *Grammar, indent int) {
-	tabs := strings.Repeat("\t", indent)
-
-	switch e := expr.(type) {
-	case *RuleRef:
-		if grammar.GetTokenByName(e.Name) != nil {
-			// It's a token - generate token parsing
-			g.generateTokenParsing(buf, e.Name, grammar, indent)
-		} else {
-			// It's a rule reference
-			buf.WriteString(fmt.Sprintf("%sreturn p.parse%s()\n", tabs, capitalizeFirst(e.Name)))
-		}
-
-	case *Choice:
-		g.generateChoiceParsingCode(buf, e, grammar, indent)
-
-	case *Sequence:
-		g.generateSequenceParsingCode(buf, e, grammar, indent)
-
-	case *Repetition:
-		g.generateRepetitionParsingCode(buf, e, grammar, indent)
-
-	case *Optional:
-		g.generateOptionalParsingCode(buf, e, grammar, indent)
-
-	case *Literal:
-		g.generateLiteralParsing(buf, e, indent)
-
-	default:
-		buf.WriteString(fmt.Sprintf("%sreturn nil, fmt.Errorf(\"expression type not implemented\")\n", tabs))
+// operand converts an item to an expression; bare tokens become literals
+func operand(item interface{}) (Expression, error) {
+	switch value := item.(type) {
+	case Expression:
+		return value, nil
+	case Token:
+		return literalFromToken(value), nil
 	}
+	return nil, fmt.Errorf("unexpected item %v", item)
 }
 
-func (g *GoGenerator) generateChoiceParsingCode(buf *strings.Builder, choice *Choice, grammar *Grammar, indent int) {
-	tabs := strings.Repeat("\t", indent)
-
-	if len(choice.Alternatives) == 0 {
-		buf.WriteString(fmt.Sprintf("%sreturn nil, fmt.Errorf(\"empty choice\")\n", tabs))
-		return
-	}
-
-	if len(choice.Alternatives) == 1 {
-		g.generateExpressionParsingCode(buf, choice.Alternatives[0], grammar, indent)
-		return
-	}
-
-	// For multiple alternatives, generate lookahead-based parsing
-	buf.WriteString(fmt.Sprintf("%s// Try alternatives based on lookahead\n", tabs))
-
-	for i, alt := range choice.Alternatives {
-		if i > 0 {
-			buf.WriteString(fmt.Sprintf("%s} else ", tabs))
-		} else {
-			buf.WriteString(fmt.Sprintf("%s", tabs))
-		}
-
-		// Generate condition for this alternative
-		condition := g.generateLookaheadCondition(alt, grammar)
-		if condition != "" {
-			buf.WriteString(fmt.Sprintf("if %s {\n", condition))
-			g.generateExpressionParsingCode(buf, alt, grammar, indent+1)
-		} else {
-			buf.WriteString("{\n")
-			buf.WriteString(fmt.Sprintf("%s\t// Fallback alternative\n", tabs))
-			g.generateExpressionParsingCode(buf, alt, grammar, indent+1)
+func literalFromToken(token Token) Expression {
+	if token.Value != "" && (token.Value[0] == '.' || (token.Value[0] >= '0' && token.Value[0] <= '9')) {
+		if value, err := strconv.ParseFloat(token.Value, 64); err == nil {
+			return &NumberLiteral{Value: value, Range: token.Range}
 		}
 	}
-
-	buf.WriteString(fmt.Sprintf("%s} else {\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\treturn nil, &ParseError{\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\t\tMessage: \"No valid alternative found\",\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\t\tRange: p.currentToken.Range,\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\t\tSourceText: p.sourceText,\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\t\tErrorType: \"syntax\",\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\t}\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s}\n", tabs))
-}
-
-func (g *GoGenerator) generateLookaheadCondition(expr Expression, grammar *Grammar) string {
-	switch e := expr.(type) {
-	case *RuleRef:
-		if grammar.GetTokenByName(e.Name) != nil {
-			return fmt.Sprintf("p.currentToken.Type == TOKEN_%s", strings.ToUpper(e.Name))
-		}
-		// For rule references, we'd need more sophisticated FIRST set analysis
-		return ""
-	case *Sequence:
-		if len(e.Items) > 0 {
-			return g.generateLookaheadCondition(e.Items[0], grammar)
-		}
-	case *Literal:
-		// Find the token for this literal
-		for _, token := range grammar.Tokens {
-			if token.Literal == e.Value {
-				return fmt.Sprintf("p.currentToken.Type == TOKEN_%s", strings.ToUpper(token.Name))
-			}
-		}
+	switch strings.ToLower(token.Value) {
+	case "true":
+		return &BooleanLiteral{Value: true, Range: token.Range}
+	case "false":
+		return &BooleanLiteral{Value: false, Range: token.Range}
 	}
-	return ""
+	return &Identifier{Name: token.Value, Range: token.Range}
 }
 
-func (g *GoGenerator) generateSequenceParsingCode(buf *strings.Builder, seq *Sequence, grammar *Grammar, indent int) {
-	tabs := strings.Repeat("\t", indent)
-
-	if len(seq.Items) == 0 {
-		buf.WriteString(fmt.Sprintf("%sreturn &NumberLiteral{Value: 0, Range: p.currentToken.Range}, nil // empty sequence\n", tabs))
-		return
-	}
-
-	if len(seq.Items) == 1 {
-		g.generateExpressionParsingCode(buf, seq.Items[0], grammar, indent)
-		return
-	}
-
-	// For multi-item sequences, we need to implement proper left-associative parsing
-	buf.WriteString(fmt.Sprintf("%s// Parse sequence\n", tabs))
-	buf.WriteString(fmt.Sprintf("%sleft, err := func() (Expression, error) {\n", tabs))
-	g.generateExpressionParsingCode(buf, seq.Items[0], grammar, indent+1)
-	buf.WriteString(fmt.Sprintf("%s}()\n", tabs))
-	buf.WriteString(fmt.Sprintf("%sif err != nil {\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\treturn nil, err\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s}\n\n", tabs))
-
-	for i := 1; i < len(seq.Items); i++ {
-		buf.WriteString(fmt.Sprintf("%s// Parse item %d\n", tabs, i+1))
-		if g.isOperatorToken(seq.Items[i], grammar) {
-			// It's an operator token - expect it and continue
-			tokenName := g.getTokenNameFromExpression(seq.Items[i], grammar)
-			buf.WriteString(fmt.Sprintf("%sif !p.match(TOKEN_%s) {\n", tabs, strings.ToUpper(tokenName)))
-			buf.WriteString(fmt.Sprintf("%s\treturn nil, &ParseError{\n", tabs))
-			buf.WriteString(fmt.Sprintf("%s\t\tMessage: \"Expected %s\",\n", tabs, tokenName))
-			buf.WriteString(fmt.Sprintf("%s\t\tRange: p.currentToken.Range,\n", tabs))
-			buf.WriteString(fmt.Sprintf("%s\t\tSourceText: p.sourceText,\n", tabs))
-			buf.WriteString(fmt.Sprintf("%s\t\tErrorType: \"syntax\",\n", tabs))
-			buf.WriteString(fmt.Sprintf("%s\t}\n", tabs))
-			buf.WriteString(fmt.Sprintf("%s}\n", tabs))
-			buf.WriteString(fmt.Sprintf("%soperator := p.currentToken.Type\n", tabs))
-			buf.WriteString(fmt.Sprintf("%serr = p.advance()\n", tabs))
-			buf.WriteString(fmt.Sprintf("%sif err != nil {\n", tabs))
-			buf.WriteString(fmt.Sprintf("%s\treturn nil, err\n", tabs))
-			buf.WriteString(fmt.Sprintf("%s}\n\n", tabs))
-		} else {
-			// Parse the next item
-			buf.WriteString(fmt.Sprintf("%sright, err := func() (Expression, error) {\n", tabs))
-			g.generateExpressionParsingCode(buf, seq.Items[i], grammar, indent+1)
-			buf.WriteString(fmt.Sprintf("%s}()\n", tabs))
-			buf.WriteString(fmt.Sprintf("%sif err != nil {\n", tabs))
-			buf.WriteString(fmt.Sprintf("%s\treturn nil, err\n", tabs))
-			buf.WriteString(fmt.Sprintf("%s}\n\n", tabs))
+func firstToken(items []interface{}, rng SourceRange, node string) (Token, error) {
+	for _, item := range items {
+		if token, ok := item.(Token); ok {
+			return token, nil
 		}
 	}
-
-	// Create binary operation node if we have operator
-	buf.WriteString(fmt.Sprintf("%s// Create binary operation if applicable\n", tabs))
-	buf.WriteString(fmt.Sprintf("%sreturn &BinaryOperation{\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\tLeft: left,\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\tOperator: operator,\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\tRight: right,\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\tRange: SourceRange{Start: left.GetRange().Start, End: right.GetRange().End},\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s}, nil\n", tabs))
-}
-
-func (g *GoGenerator) generateRepetitionParsingCode(buf *strings.Builder, rep *Repetition, grammar *Grammar, indent int) {
-	tabs := strings.Repeat("\t", indent)
-
-	buf.WriteString(fmt.Sprintf("%s// Parse repetition (%d to %d)\n", tabs, rep.Min, rep.Max))
-	buf.WriteString(fmt.Sprintf("%sleft, err := func() (Expression, error) {\n", tabs))
-	g.generateExpressionParsingCode(buf, rep.Expression, grammar, indent+1)
-	buf.WriteString(fmt.Sprintf("%s}()\n", tabs))
-	buf.WriteString(fmt.Sprintf("%sif err != nil {\n", tabs))
-
-	if rep.Min == 0 {
-		buf.WriteString(fmt.Sprintf("%s\t// Zero repetitions allowed\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\treturn &NumberLiteral{Value: 0, Range: p.currentToken.Range}, nil\n", tabs))
-	} else {
-		buf.WriteString(fmt.Sprintf("%s\treturn nil, err\n", tabs))
-	}
-
-	buf.WriteString(fmt.Sprintf("%s}\n\n", tabs))
-
-	if rep.Max == -1 || rep.Max > 1 {
-		buf.WriteString(fmt.Sprintf("%s// Continue parsing while possible\n", tabs))
-		buf.WriteString(fmt.Sprintf("%sfor {\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\tif right, err := func() (Expression, error) {\n", tabs))
-		g.generateExpressionParsingCode(buf, rep.Expression, grammar, indent+2)
-		buf.WriteString(fmt.Sprintf("%s\t}(); err == nil {\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\t\t// Create left-associative tree\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\t\tleft = &BinaryOperation{\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\t\t\tLeft: left,\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\t\t\tOperator: TOKEN_PLUS, // Default operator for sequences\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\t\t\tRight: right,\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\t\t\tRange: SourceRange{Start: left.GetRange().Start, End: right.GetRange().End},\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\t\t}\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\t} else {\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\t\tbreak\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\t}\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s}\n\n", tabs))
-	}
-
-	buf.WriteString(fmt.Sprintf("%sreturn left, nil\n", tabs))
-}
-
-func (g *GoGenerator) generateOptionalParsingCode(buf *strings.Builder, opt *Optional, grammar *Grammar, indent int) {
-	tabs := strings.Repeat("\t", indent)
-
-	buf.WriteString(fmt.Sprintf("%s// Parse optional expression\n", tabs))
-	buf.WriteString(fmt.Sprintf("%sif expr, err := func() (Expression, error) {\n", tabs))
-	g.generateExpressionParsingCode(buf, opt.Expression, grammar, indent+1)
-	buf.WriteString(fmt.Sprintf("%s}(); err == nil {\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\treturn expr, nil\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s}\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s// Optional failed, return default\n", tabs))
-	buf.WriteString(fmt.Sprintf("%sreturn &NumberLiteral{Value: 0, Range: p.currentToken.Range}, nil\n", tabs))
-}
-
-func (g *GoGenerator) generateTokenParsing(buf *strings.Builder, tokenName string, grammar *Grammar, indent int) {
-	tabs := strings.Repeat("\t", indent)
-	upperName := strings.ToUpper(tokenName)
-
-	buf.WriteString(fmt.Sprintf("%sif p.currentToken.Type != TOKEN_%s {\n", tabs, upperName))
-	buf.WriteString(fmt.Sprintf("%s\treturn nil, &ParseError{\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\t\tMessage: fmt.Sprintf(\"Expected %s, got %%s\", p.currentToken.Type),\n", tabs, tokenName))
-	buf.WriteString(fmt.Sprintf("%s\t\tRange: p.currentToken.Range,\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\t\tSourceText: p.sourceText,\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\t\tErrorType: \"syntax\",\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\t}\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s}\n", tabs))
-	buf.WriteString(fmt.Sprintf("%svalue := p.currentToken.Value\n", tabs))
-	buf.WriteString(fmt.Sprintf("%srange_ := p.currentToken.Range\n", tabs))
-	buf.WriteString(fmt.Sprintf("%serr := p.advance()\n", tabs))
-	buf.WriteString(fmt.Sprintf("%sif err != nil {\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s\treturn nil, err\n", tabs))
-	buf.WriteString(fmt.Sprintf("%s}\n\n", tabs))
-
-	// Generate appropriate AST node based on token type
-	switch upperName {
-	case "NUMBER", "DECIMAL":
-		buf.WriteString(fmt.Sprintf("%snum, err := strconv.ParseFloat(value, 64)\n", tabs))
-		buf.WriteString(fmt.Sprintf("%sif err != nil {\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\treturn nil, &ParseError{\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\t\tMessage: fmt.Sprintf(\"Invalid number format: %%s\", value),\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\t\tRange: range_,\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\t\tSourceText: p.sourceText,\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\t\tErrorType: \"lexical\",\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s\t}\n", tabs))
-		buf.WriteString(fmt.Sprintf("%s}\n", tabs))
-		buf.WriteString(fmt.Sprintf("%sreturn &NumberLiteral{Value: num, Range: range_}, nil\n", tabs))
-
-	case "TRUE", "FALSE", "BOOLEAN":
-		buf.WriteString(fmt.Sprintf("%sboolVal := value == \"true\" || value == \"TRUE\"\n", tabs))
-		buf.WriteString(fmt.Sprintf("%sreturn &BooleanLiteral{Value: boolVal, Range: range_}, nil\n", tabs))
-
-	case "IDENTIFIER":
-		buf.WriteString(fmt.Sprintf("%sreturn &Identifier{Name: value, Range: range_}, nil\n", tabs))
-
-	default:
-		buf.WriteString(fmt.Sprintf("%s// Return literal for other tokens\n", tabs))
-		buf.WriteString(fmt.Sprintf("%sreturn &Identifier{Name: value, Range: range_}, nil\n", tabs))
-	}
-}
-
-func (g *GoGenerator) generateLiteralParsing(buf *strings.Builder, literal *Literal, indent int) {
-	tabs := strings.Repeat("\t", indent)
-
-	// Find the token that matches this literal
-	buf.WriteString(fmt.Sprintf("%s// Parse literal '%s'\n", tabs, literal.Value))
-	buf.WriteString(fmt.Sprintf("%sreturn &Identifier{Name: \"%s\", Range: p.currentToken.Range}, nil\n",
-		tabs, literal.Value))
-}
-
-func (g *GoGenerator) generatePublicAPI(buf *strings.Builder) {
-	buf.WriteString(`// ===== UTILITY FUNCTIONS =====
-
-// CollectVariables extracts all variable names from an expression
-func CollectVariables(expr Expression) []string {
-	collector := &VariableCollector{variables: make(map[string]bool)}
-	expr.Accept(collector)
-
-	result := make([]string, 0, len(collector.variables))
-	for name := range collector.variables {
-		result = append(result, name)
-	}
-	sort.Strings(result)
-	return result
-}
-
-// GetExpressionType determines the expected result type of an expression
-func GetExpressionType(expr Expression) ExpressionType {
-	analyzer := &TypeAnalyzer{}
-	result, _ := expr.Accept(analyzer)
-	if exprType, ok := result.(ExpressionType); ok {
-		return exprType
-	}
-	return TYPE_UNKNOWN
-}
-
-// ===== INTERNAL VISITORS FOR UTILITIES =====
-
-// VariableCollector implements Visitor to collect all variable names
-type VariableCollector struct {
-	variables map[string]bool
-}
-
-func (v *VariableCollector) VisitNumberLiteral(node *NumberLiteral) (interface{}, error) {
-	return nil, nil
-}
-
-func (v *VariableCollector) VisitBooleanLiteral(node *BooleanLiteral) (interface{}, error) {
-	return nil, nil
-}
-
-func (v *VariableCollector) VisitIdentifier(node *Identifier) (interface{}, error) {
-	v.variables[node.Name] = true
-	return nil, nil
-}
-
-func (v *VariableCollector) VisitBinaryOperation(node *BinaryOperation) (interface{}, error) {
-	node.Left.Accept(v)
-	node.Right.Accept(v)
-	return nil, nil
-}
-
-func (v *VariableCollector) VisitUnaryOperation(node *UnaryOperation) (interface{}, error) {
-	node.Operand.Accept(v)
-	return nil, nil
-}
-
-func (v *VariableCollector) VisitFunctionCall(node *FunctionCall) (interface{}, error) {
-	for _, arg := range node.Args {
-		arg.Accept(v)
-	}
-	return nil, nil
-}
-
-// TypeAnalyzer implements Visitor to determine expression types
-type TypeAnalyzer struct{}
-
-func (t *TypeAnalyzer) VisitNumberLiteral(node *NumberLiteral) (interface{}, error) {
-	return TYPE_NUMBER, nil
-}
-
-func (t *TypeAnalyzer) VisitBooleanLiteral(node *BooleanLiteral) (interface{}, error) {
-	return TYPE_BOOLEAN, nil
-}
-
-func (t *TypeAnalyzer) VisitIdentifier(node *Identifier) (interface{}, error) {
-	return TYPE_UNKNOWN, nil // Type depends on runtime value
-}
-
-func (t *TypeAnalyzer) VisitBinaryOperation(node *BinaryOperation) (interface{}, error) {
-	switch node.Operator {
-	case TOKEN_PLUS, TOKEN_MINUS, TOKEN_MULTIPLY, TOKEN_DIVIDE, TOKEN_MODULO:
-		return TYPE_NUMBER, nil
-	case TOKEN_EQ, TOKEN_NE, TOKEN_LT, TOKEN_LE, TOKEN_GT, TOKEN_GE, TOKEN_AND, TOKEN_OR:
-		return TYPE_BOOLEAN, nil
-	default:
-		return TYPE_UNKNOWN, nil
-	}
-}
-
-func (t *TypeAnalyzer) VisitUnaryOperation(node *UnaryOperation) (interface{}, error) {
-	switch node.Operator {
-	case TOKEN_MINUS, TOKEN_PLUS:
-		return TYPE_NUMBER, nil
-	case TOKEN_NOT:
-		return TYPE_BOOLEAN, nil
-	default:
-		return TYPE_UNKNOWN, nil
+	return Token{}, &ParseError{
+		Message:   fmt.Sprintf("%s needs a token", node),
+		Range:     rng,
+		ErrorType: "semantic",
 	}
 }
 
-func (t *TypeAnalyzer) VisitFunctionCall(node *FunctionCall) (interface{}, error) {
-	switch node.Function {
-	case TOKEN_ABS, TOKEN_NEGATE, TOKEN_CEIL, TOKEN_FLOOR:
-		return TYPE_NUMBER, nil
-	case TOKEN_THRESHOLD, TOKEN_IMPLIES, TOKEN_EQUIV, TOKEN_XOR:
-		return TYPE_BOOLEAN, nil
-	case TOKEN_ITE:
-		return TYPE_UNKNOWN, nil // Depends on then/else branches
-	default:
-		return TYPE_UNKNOWN, nil
+// mergeRanges spans from start to end, taking the text from the enclosing rule range
+func mergeRanges(start, end, rng SourceRange) SourceRange {
+	from, to := start.Start.Offset-rng.Start.Offset, end.End.Offset-rng.Start.Offset
+	text := ""
+	if 0 <= from && from <= to && to <= len(rng.Text) {
+		text = rng.Text[from:to]
 	}
+	return SourceRange{Start: start.Start, End: end.End, Text: text}
 }
 
-// ===== PUBLIC API =====
-
-// ParseExpression is the main entry point for parsing expressions
-func ParseExpression(input string) (Expression, error) {
-	parser := NewParser(input)
-	return parser.Parse()
-}
-
-// FormatError formats a ParseError for user-friendly display
-func FormatError(err error) string {
-	if parseErr, ok := err.(*ParseError); ok {
-		return parseErr.ShowError()
-	}
-	return err.Error()
-}
-
-// ValidateExpression validates an expression without evaluating it
-func ValidateExpression(input string) error {
-	_, err := ParseExpression(input)
-	return err
-}
-
-// GetExpressionVariables is a convenience function that matches the old API
-func GetExpressionVariables(input string) ([]string, error) {
-	ast, err := ParseExpression(input)
+func buildNumberLiteral(items []interface{}, rng SourceRange) (Expression, error) {
+	token, err := firstToken(items, rng, "NumberLiteral")
 	if err != nil {
 		return nil, err
 	}
-	return CollectVariables(ast), nil
-}
-`)
-}
-
-// Helper functions
-func (g *GoGenerator) isOperatorToken(expr Expression, grammar *Grammar) bool {
-	if ruleRef, ok := expr.(*RuleRef); ok {
-		token := grammar.GetTokenByName(ruleRef.Name)
-		if token != nil && token.Literal != "" {
-			// Check if it's an operator-like token
-			operators := []string{"+", "-", "*", "/", "%", "==", "!=", "<", "<=", ">", ">=", "&&", "||", "->", "<->"}
-			for _, op := range operators {
-				if token.Literal == op {
-					return true
-				}
-			}
-		}
-	}
-	return false
-}
-
-func (g *GoGenerator) getTokenNameFromExpression(expr Expression, grammar *Grammar) string {
-	if ruleRef, ok := expr.(*RuleRef); ok {
-		return ruleRef.Name
-	}
-	return ""
-}
-
-func isIdentifierLike(s string) bool {
-	if len(s) == 0 {
-		return false
-	}
-	for i, r := range s {
-		if i == 0 {
-			if !((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_') {
-				return false
-			}
-		} else {
-			if !((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_') {
-				return false
-			}
+	value, err := strconv.ParseFloat(token.Value, 64)
+	if err != nil {
+		return nil, &ParseError{
+			Message:    fmt.Sprintf("Invalid number '%s'", token.Value),
+			Range:      token.Range,
+			ErrorType:  "lexical",
+			Suggestion: "Use a valid number format like 123 or 123.45",
 		}
 	}
-	return true
+	return &NumberLiteral{Value: value, Range: token.Range}, nil
 }
 
-func isAllUpper(s string) bool {
-	for _, r := range s {
-		if r >= 'a' && r <= 'z' {
-			return false
-		}
+func buildBooleanLiteral(items []interface{}, rng SourceRange) (Expression, error) {
+	token, err := firstToken(items, rng, "BooleanLiteral")
+	if err != nil {
+		return nil, err
 	}
-	return len(s) > 0
+	return &BooleanLiteral{Value: strings.EqualFold(token.Value, "true"), Range: token.Range}, nil
 }
 
-func capitalizeFirst(s string) string {
-	if len(s) == 0 {
-		return s
+func buildIdentifier(items []interface{}, rng SourceRange) (Expression, error) {
+	token, err := firstToken(items, rng, "Identifier")
+	if err != nil {
+		return nil, err
 	}
-	return strings.ToUpper(s[:1]) + s[1:]
+	return &Identifier{Name: token.Value, Range: token.Range}, nil
 }
 
-// Replace the generateParsingMethods function in go_generator.go
-
-func (g *GoGenerator) generateParsingMethods(buf *strings.Builder, grammar *Grammar) {
-	startRule := grammar.StartRule
-	if startRule == "" && len(grammar.Rules) > 0 {
-		startRule = grammar.Rules[0].Name
+// buildBinaryOperation folds operand (operator operand)* to the left
+func buildBinaryOperation(items []interface{}, rng SourceRange) (Expression, error) {
+	left, err := operand(items[0])
+	if err != nil {
+		return nil, err
 	}
-
-	// Only generate main entry point if there's no "expression" rule
-	hasExpressionRule := false
-	for _, rule := range grammar.Rules {
-		if rule.Name == "expression" {
-			hasExpressionRule = true
-			break
+	for i := 1; i+1 < len(items); i += 2 {
+		operator, ok := items[i].(Token)
+		if !ok {
+			return nil, &ParseError{
+				Message:   "BinaryOperation expects an operator token between operands",
+				Range:     rng,
+					ErrorType: "semantic",
+			}
 		}
-	}
-
-	if !hasExpressionRule {
-		// Main entry point
-		buf.WriteString(`// parseExpression is the main entry point
-func (p *Parser) parseExpression() (Expression, error) {
-`)
-		if startRule != "" {
-			buf.WriteString(fmt.Sprintf("\treturn p.parse%s()\n", capitalizeFirst(startRule)))
-		} else {
-			buf.WriteString("\treturn nil, fmt.Errorf(\"no start rule defined\")\n")
+		right, err := operand(items[i+1])
+		if err != nil {
+			return nil, err
 		}
-		buf.WriteString("}\n\n")
-	}
-
-	// Generate simple methods for each rule using patterns from your reference parser
-	for _, rule := range grammar.Rules {
-		g.generateSimpleRuleMethod(buf, rule, grammar)
-	}
-}
-
-func (g *GoGenerator) generateSimpleRuleMethod(buf *strings.Builder, rule *Rule, grammar *Grammar) {
-	methodName := fmt.Sprintf("parse%s", capitalizeFirst(rule.Name))
-
-	buf.WriteString(fmt.Sprintf("// %s implements: %s\n", methodName, rule.Expression.String()))
-	buf.WriteString(fmt.Sprintf("func (p *Parser) %s() (Expression, error) {\n", methodName))
-
-	// Analyze the rule and generate appropriate simple parsing code
-	g.generateSimpleExpression(buf, rule.Expression, grammar)
-
-	buf.WriteString("}\n\n")
-}
-
-func (g *GoGenerator) generateSimpleExpression(buf *strings.Builder, expr Expression, grammar *Grammar) {
-	switch e := expr.(type) {
-	case *RuleRef:
-		if grammar.GetTokenByName(e.Name) != nil {
-			// It's a token - generate simple token parsing
-			g.generateSimpleTokenParsing(buf, e.Name)
-		} else {
-			// It's a rule reference
-			buf.WriteString(fmt.Sprintf("\treturn p.parse%s()\n", capitalizeFirst(e.Name)))
+		left = &BinaryOperation{
+			Left:     left,
+			Operator: operator.Type,
+			Right:    right,
+			Range:    mergeRanges(left.GetRange(), right.GetRange(), rng),
 		}
-
-	case *Choice:
-		g.generateSimpleChoice(buf, e, grammar)
-
-	case *Sequence:
-		g.generateSimpleSequence(buf, e, grammar)
-
-	case *Repetition:
-		g.generateSimpleRepetition(buf, e, grammar)
-
-	case *Optional:
-		buf.WriteString("\t// Optional - try to parse, ignore errors\n")
-		g.generateSimpleExpression(buf, e.Expression, grammar)
-
-	default:
-		buf.WriteString("\treturn nil, fmt.Errorf(\"expression type not supported\")\n")
 	}
+	return left, nil
 }
 
-func (g *GoGenerator) generateSimpleChoice(buf *strings.Builder, choice *Choice, grammar *Grammar) {
-	if len(choice.Alternatives) == 0 {
-		buf.WriteString("\treturn nil, fmt.Errorf(\"empty choice\")\n")
-		return
-	}
-
-	if len(choice.Alternatives) == 1 {
-		g.generateSimpleExpression(buf, choice.Alternatives[0], grammar)
-		return
+// buildUnaryOperation applies operator* to the final operand, innermost last
+func buildUnaryOperation(items []interface{}, rng SourceRange) (Expression, error) {
+	result, err := operand(items[len(items)-1])
+	if err != nil {
+		return nil, err
 	}
-
-	// Generate simple if-else chain based on lookahead
-	buf.WriteString("\t// Try alternatives based on current token\n")
-
-	for i, alt := range choice.Alternatives {
-		condition := g.getSimpleLookahead(alt, grammar)
-
-		if i == 0 {
-			if condition != "" {
-				buf.WriteString(fmt.Sprintf("\tif %s {\n", condition))
-				g.generateSimpleExpression(buf, alt, grammar)
-				buf.WriteString("\t}")
-			} else {
-				buf.WriteString("\t// First alternative (fallback)\n")
-				g.generateSimpleExpression(buf, alt, grammar)
-				return
-			}
-		} else {
-			if condition != "" {
-				buf.WriteString(fmt.Sprintf(" else if %s {\n", condition))
-				g.generateSimpleExpression(buf, alt, grammar)
-				buf.WriteString("\t}")
+	for i := len(items) - 2; i >= 0; i-- {
+		operator, ok := items[i].(Token)
+		if !ok {
+			return nil, &ParseError{
+				Message:   "UnaryOperation expects operator tokens before its operand",
+				Range:     rng,
+					ErrorType: "semantic",
 			}
 		}
-	}
-
-	buf.WriteString(" else {\n")
-	buf.WriteString("\t\treturn nil, fmt.Errorf(\"no valid alternative found\")\n")
-	buf.WriteString("\t}\n")
-}
-
-func (g *GoGenerator) generateSimpleSequence(buf *strings.Builder, seq *Sequence, grammar *Grammar) {
-	if len(seq.Items) == 0 {
-		buf.WriteString("\treturn &NumberLiteral{Value: 0, Range: p.currentToken.Range}, nil\n")
-		return
-	}
-
-	if len(seq.Items) == 1 {
-		g.generateSimpleExpression(buf, seq.Items[0], grammar)
-		return
-	}
-
-	// For binary operations, use the standard left-associative pattern
-	if len(seq.Items) == 3 {
-		// Pattern: left operator right
-		leftItem := seq.Items[0]
-		opItem := seq.Items[1]
-		rightItem := seq.Items[2]
-
-		if g.isOperatorRule(opItem, grammar) {
-			buf.WriteString("\tleft, err := func() (Expression, error) {\n")
-			buf.WriteString("\t\t")
-			g.generateSimpleExpression(buf, leftItem, grammar)
-			buf.WriteString("\t}()\n")
-			buf.WriteString("\tif err != nil {\n")
-			buf.WriteString("\t\treturn nil, err\n")
-			buf.WriteString("\t}\n\n")
-
-			buf.WriteString("\t// Check for operator\n")
-			tokenName := g.getTokenFromRule(opItem, grammar)
-			if tokenName != "" {
-				buf.WriteString(fmt.Sprintf("\tif p.currentToken.Type == TOKEN_%s {\n", strings.ToUpper(tokenName)))
-				buf.WriteString("\t\toperator := p.currentToken.Type\n")
-				buf.WriteString("\t\terr := p.advance()\n")
-				buf.WriteString("\t\tif err != nil {\n")
-				buf.WriteString("\t\t\treturn nil, err\n")
-				buf.WriteString("\t\t}\n\n")
-
-				buf.WriteString("\t\tright, err := func() (Expression, error) {\n")
-				buf.WriteString("\t\t\t")
-				g.generateSimpleExpression(buf, rightItem, grammar)
-				buf.WriteString("\t\t}()\n")
-				buf.WriteString("\t\tif err != nil {\n")
-				buf.WriteString("\t\t\treturn nil, err\n")
-				buf.WriteString("\t\t}\n\n")
-
-				buf.WriteString("\t\treturn &BinaryOperation{\n")
-				buf.WriteString("\t\t\tLeft: left,\n")
-				buf.WriteString("\t\t\tOperator: operator,\n")
-				buf.WriteString("\t\t\tRight: right,\n")
-				buf.WriteString("\t\t\tRange: SourceRange{Start: left.GetRange().Start, End: right.GetRange().End},\n")
-				buf.WriteString("\t\t}, nil\n")
-				buf.WriteString("\t}\n\n")
-				buf.WriteString("\treturn left, nil // No operator found\n")
-				return
-			}
+		result = &UnaryOperation{
+			Operator: operator.Type,
+			Operand:  result,
+			Range:    mergeRanges(operator.Range, result.GetRange(), rng),
 		}
 	}
-
-	// Fallback: just parse first item
-	buf.WriteString("\t// Sequence - parsing first item only\n")
-	g.generateSimpleExpression(buf, seq.Items[0], grammar)
-}
-
-func (g *GoGenerator) generateSimpleRepetition(buf *strings.Builder, rep *Repetition, grammar *Grammar) {
-	if rep.Min == 0 && rep.Max == -1 {
-		// Zero or more - use the pattern from your reference parser
-		buf.WriteString("\tleft, err := func() (Expression, error) {\n")
-		buf.WriteString("\t\t")
-		g.generateSimpleExpression(buf, rep.Expression, grammar)
-		buf.WriteString("\t}()\n")
-		buf.WriteString("\tif err != nil {\n")
-		buf.WriteString("\t\treturn nil, err\n")
-		buf.WriteString("\t}\n\n")
-
-		buf.WriteString("\tfor {\n")
-		buf.WriteString("\t\tif right, err := func() (Expression, error) {\n")
-		buf.WriteString("\t\t\t")
-		g.generateSimpleExpression(buf, rep.Expression, grammar)
-		buf.WriteString("\t\t}(); err == nil {\n")
-		buf.WriteString("\t\t\tleft = &BinaryOperation{\n")
-		buf.WriteString("\t\t\t\tLeft: left,\n")
-		buf.WriteString("\t\t\t\tOperator: TOKEN_PLUS, // Default for repetition\n")
-		buf.WriteString("\t\t\t\tRight: right,\n")
-		buf.WriteString("\t\t\t\tRange: SourceRange{Start: left.GetRange().Start, End: right.GetRange().End},\n")
-		buf.WriteString("\t\t\t}\n")
-		buf.WriteString("\t\t} else {\n")
-		buf.WriteString("\t\t\tbreak\n")
-		buf.WriteString("\t\t}\n")
-		buf.WriteString("\t}\n\n")
-		buf.WriteString("\treturn left, nil\n")
-	} else {
-		// Other repetition types - just parse once for now
-		g.generateSimpleExpression(buf, rep.Expression, grammar)
-	}
+	return result, nil
 }
 
-func (g *GoGenerator) generateSimpleTokenParsing(buf *strings.Builder, tokenName string) {
-	upperName := strings.ToUpper(tokenName)
-
-	buf.WriteString(fmt.Sprintf("\tif p.currentToken.Type != TOKEN_%s {\n", upperName))
-	buf.WriteString(fmt.Sprintf("\t\treturn nil, fmt.Errorf(\"expected %s, got %%s\", p.currentToken.Type)\n", tokenName))
-	buf.WriteString("\t}\n")
-	buf.WriteString("\tvalue := p.currentToken.Value\n")
-	buf.WriteString("\trange_ := p.currentToken.Range\n")
-	buf.WriteString("\terr := p.advance()\n")
-	buf.WriteString("\tif err != nil {\n")
-	buf.WriteString("\t\treturn nil, err\n")
-	buf.WriteString("\t}\n\n")
-
-	// Generate appropriate AST node
-	switch upperName {
-	case "NUMBER", "DECIMAL":
-		buf.WriteString("\tnum, err := strconv.ParseFloat(value, 64)\n")
-		buf.WriteString("\tif err != nil {\n")
-		buf.WriteString("\t\treturn nil, fmt.Errorf(\"invalid number: %s\", value)\n")
-		buf.WriteString("\t}\n")
-		buf.WriteString("\treturn &NumberLiteral{Value: num, Range: range_}, nil\n")
-
-	case "TRUE", "FALSE":
-		buf.WriteString("\tboolVal := value == \"true\" || value == \"TRUE\"\n")
-		buf.WriteString("\treturn &BooleanLiteral{Value: boolVal, Range: range_}, nil\n")
-
-	case "IDENTIFIER":
-		buf.WriteString("\treturn &Identifier{Name: value, Range: range_}, nil\n")
-
-	default:
-		buf.WriteString("\treturn &Identifier{Name: value, Range: range_}, nil\n")
-	}
-}
-
-func (g *GoGenerator) getSimpleLookahead(expr Expression, grammar *Grammar) string {
-	switch e := expr.(type) {
-	case *RuleRef:
-		if grammar.GetTokenByName(e.Name) != nil {
-			return fmt.Sprintf("p.currentToken.Type == TOKEN_%s", strings.ToUpper(e.Name))
-		}
-	case *Sequence:
-		if len(e.Items) > 0 {
-			return g.getSimpleLookahead(e.Items[0], grammar)
-		}
+// buildFunctionCall uses the first token as the function and every
+// expression item as an argument
+func buildFunctionCall(items []interface{}, rng SourceRange) (Expression, error) {
+	function, err := firstToken(items, rng, "FunctionCall")
+	if err != nil {
+		return nil, err
 	}
-	return ""
-}
-
-func (g *GoGenerator) isOperatorRule(expr Expression, grammar *Grammar) bool {
-	if ruleRef, ok := expr.(*RuleRef); ok {
-		token := grammar.GetTokenByName(ruleRef.Name)
-		if token != nil && token.Literal != "" {
-			// Check if it's an operator
-			operators := []string{"+", "-", "*", "/", "%", "==", "!=", "<", "<=", ">", ">=", "&&", "||", "->", "<->"}
-			for _, op := range operators {
-				if token.Literal == op {
-					return true
-				}
-			}
+	call := &FunctionCall{Function: function.Type, Range: rng}
+	for _, item := range items {
+		if arg, ok := item.(Expression); ok {
+			call.Args = append(call.Args, arg)
 		}
 	}
-	return false
+	return call, nil
 }
 
-func (g *GoGenerator) getTokenFromRule(expr Expression, grammar *Grammar) string {
-	if ruleRef, ok := expr.(*RuleRef); ok {
-		if grammar.GetTokenByName(ruleRef.Name) != nil {
-			return ruleRef.Name
-		}
-	}
-	return ""
+`)
 }
@@ -32,8 +32,8 @@ type TokenDef struct {
 type Rule struct {
 	Name       string     `json:"name"`
 	Expression Expression `json:"expression"`
-	Action     string     `json:"action"`  // Semantic action
-	ASTNode    string     `json:"astNode"` // AST node type
+	Action     string     `json:"action"`  // Target-language code building the rule's node
+	ASTNode    string     `json:"astNode"` // AST node built from the matched items
 	Public     bool       `json:"public"`  // Public API
 }
 
@@ -419,10 +419,60 @@ func (p *GrammarParser) parseRule() (*Rule, error) {
 	}
 	rule.Expression = expr
 
+	// Optional AST construction: "=> NodeType" and/or "{ action }"
+	p.skipContinuation()
+	if p.expect("=>") {
+		p.skipWhitespace()
+		rule.ASTNode = p.parseIdentifier()
+		if rule.ASTNode == "" {
+			return nil, p.error("expected AST node name after '=>'")
+		}
+		p.skipContinuation()
+	}
+	if p.peek() == '{' {
+		action, err := p.parseAction()
+		if err != nil {
+			return nil, err
+		}
+		rule.Action = action
+	}
+
 	p.skipToEndOfLine()
 	return rule, nil
 }
 
+// parseAction parses a brace-delimited semantic action, which may span lines
+func (p *GrammarParser) parseAction() (string, error) {
+	p.advance() // consume '{'
+	start := p.position
+	depth := 1
+	for p.position < len(p.input) {
+		switch p.peek() {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				action := strings.TrimSpace(p.input[start:p.position])
+				p.advance()
+				return action, nil
+			}
+		case '"', '\'':
+			if _, err := p.parseString(); err != nil {
+				return "", err
+			}
+			continue
+		case '`':
+			p.advance()
+			for p.position < len(p.input) && p.peek() != '`' {
+				p.advance()
+			}
+		}
+		p.advance()
+	}
+	return "", p.error("unterminated action")
+}
+
 // parseExpression parses grammar expressions
 func (p *GrammarParser) parseExpression() (Expression, error) {
 	return p.parseChoice()
@@ -438,7 +488,7 @@ func (p *GrammarParser) parseChoice() (Expression, error) {
 	alternatives := []Expression{left}
 
 	for {
-		p.skipWhitespace()
+		p.skipContinuation()
 		if p.peek() != '|' {
 			break
 		}
@@ -465,8 +515,8 @@ func (p *GrammarParser) parseSequence() (Expression, error) {
 	for {
 		p.skipWhitespace()
 
-		if p.position >= len(p.input) ||
-			p.peek() == '|' || p.peek() == ')' || p.peek() == ']' || p.isAtLineEnd() {
+		if p.position >= len(p.input) || p.peek() == '|' || p.peek() == ')' || p.peek() == ']' ||
+			p.peek() == '{' || p.isAtLineEnd() || strings.HasPrefix(p.input[p.position:], "=>") {
 			break
 		}
 
@@ -517,6 +567,13 @@ func (p *GrammarParser) parsePrimary() (Expression, error) {
 	ch := p.peek()
 
 	switch {
+	case ch == '&' || ch == '!':
+		p.advance()
+		expr, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		return &Predicate{Expression: expr, Positive: ch == '&'}, nil
 	case ch == '(':
 		return p.parseGroup()
 	case ch == '"' || ch == '\'':
@@ -606,6 +663,24 @@ func (p *GrammarParser) skipWhitespace() {
 	}
 }
 
+// skipContinuation skips whitespace and, when the next line continues the
+// current rule with '|', '=>' or '{', the line break before it
+func (p *GrammarParser) skipContinuation() {
+	p.skipWhitespace()
+	saved, line, column := p.position, p.line, p.column
+	for p.position < len(p.input) {
+		switch ch := p.peek(); {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			p.advance()
+			continue
+		case ch == '|' || ch == '{' || strings.HasPrefix(p.input[p.position:], "=>"):
+			return
+		}
+		break
+	}
+	p.position, p.line, p.column = saved, line, column
+}
+
 func (p *GrammarParser) skipToEndOfLine() {
 	for p.position < len(p.input) {
 		ch := p.peek()
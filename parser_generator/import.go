@@ -0,0 +1,431 @@
+package parser_generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// ===== GRAMMAR IMPORT =====
+
+// Diagnostic reports a construct an importer could not translate exactly
+type Diagnostic struct {
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"` // "error" or "warning"
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("line %d, column %d: %s: %s", d.Line, d.Column, d.Severity, d.Message)
+}
+
+// ImportError is returned when an import produced error diagnostics
+type ImportError struct {
+	Format      string
+	Diagnostics []Diagnostic
+}
+
+func (e *ImportError) Error() string {
+	var lines []string
+	for _, d := range e.Diagnostics {
+		if d.Severity == "error" {
+			lines = append(lines, "  "+d.String())
+		}
+	}
+	return fmt.Sprintf("%s import failed:\n%s", e.Format, strings.Join(lines, "\n"))
+}
+
+// ImportGrammarFile reads a grammar in the format given by its extension:
+// .ebnf for ISO EBNF, .g4 for ANTLR4 and the native syntax otherwise.
+// Warnings describe constructs that were approximated.
+func ImportGrammarFile(filename string) (*Grammar, []Diagnostic, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ebnf":
+		name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+		return ImportEBNF(string(content), name)
+	case ".g4":
+		return ImportANTLR(string(content))
+	}
+	grammar, err := NewGrammarParser(string(content)).ParseGrammar()
+	return grammar, nil, err
+}
+
+// importBuilder collects the grammar and diagnostics shared by the importers
+type importBuilder struct {
+	format      string
+	grammar     *Grammar
+	literals    map[string]string // Literal value -> token name
+	implicit    []*TokenDef       // Tokens created for literals, declared first
+	diagnostics []Diagnostic
+}
+
+func newImportBuilder(format, name string) *importBuilder {
+	return &importBuilder{
+		format:   format,
+		grammar:  &Grammar{Name: name, Options: map[string]string{"imported_from": format}},
+		literals: make(map[string]string),
+	}
+}
+
+func (b *importBuilder) errorf(tok importToken, format string, args ...interface{}) {
+	b.diagnostics = append(b.diagnostics, Diagnostic{
+		Line: tok.line, Column: tok.column, Severity: "error", Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (b *importBuilder) warnf(tok importToken, format string, args ...interface{}) {
+	b.diagnostics = append(b.diagnostics, Diagnostic{
+		Line: tok.line, Column: tok.column, Severity: "warning", Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// literalToken returns the token matching a literal, declaring one if needed
+func (b *importBuilder) literalToken(value string) string {
+	if name, exists := b.literals[value]; exists {
+		return name
+	}
+	name := b.uniqueTokenName(literalTokenName(value))
+	b.literals[value] = name
+	b.implicit = append(b.implicit, &TokenDef{Name: name, Literal: value})
+	return name
+}
+
+func (b *importBuilder) uniqueTokenName(base string) string {
+	taken := func(name string) bool {
+		if b.grammar.GetTokenByName(name) != nil || b.grammar.GetRuleByName(name) != nil {
+			return true
+		}
+		for _, token := range b.implicit {
+			if token.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+	name := base
+	for i := 2; taken(name); i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	return name
+}
+
+// finish returns the grammar, or an ImportError when errors were reported
+func (b *importBuilder) finish() (*Grammar, []Diagnostic, error) {
+	b.grammar.Tokens = append(b.implicit, b.grammar.Tokens...)
+	if len(b.grammar.Rules) > 0 && b.grammar.StartRule == "" {
+		b.grammar.StartRule = b.grammar.Rules[0].Name
+	}
+	for _, d := range b.diagnostics {
+		if d.Severity == "error" {
+			return nil, b.diagnostics, &ImportError{Format: b.format, Diagnostics: b.diagnostics}
+		}
+	}
+	return b.grammar, b.diagnostics, nil
+}
+
+var symbolNames = map[rune]string{
+	'+': "PLUS", '-': "MINUS", '*': "STAR", '/': "SLASH", '%': "PERCENT",
+	'=': "EQUALS", '<': "LT", '>': "GT", '!': "BANG", '&': "AMP", '|': "PIPE",
+	'(': "LPAREN", ')': "RPAREN", '[': "LBRACKET", ']': "RBRACKET", '{': "LBRACE", '}': "RBRACE",
+	',': "COMMA", ';': "SEMI", ':': "COLON", '.': "DOT", '?': "QUESTION", '^': "CARET",
+	'~': "TILDE", '@': "AT", '#': "HASH", '$': "DOLLAR", '"': "QUOTE", '\'': "APOS", '\\': "BACKSLASH",
+}
+
+// literalTokenName names the token for a literal: keywords by their text,
+// symbols by their characters
+func literalTokenName(value string) string {
+	if isWord(value) {
+		return "KW_" + strings.ToUpper(value)
+	}
+	var parts []string
+	for _, ch := range value {
+		switch {
+		case symbolNames[ch] != "":
+			parts = append(parts, symbolNames[ch])
+		case unicode.IsLetter(ch) || unicode.IsDigit(ch):
+			parts = append(parts, strings.ToUpper(string(ch)))
+		default:
+			parts = append(parts, fmt.Sprintf("U%04X", ch))
+		}
+	}
+	if len(parts) == 0 {
+		return "EMPTY"
+	}
+	return strings.Join(parts, "_")
+}
+
+func isWord(s string) bool {
+	if s == "" || !unicode.IsLetter(rune(s[0])) {
+		return false
+	}
+	for _, ch := range s {
+		if !unicode.IsLetter(ch) && !unicode.IsDigit(ch) && ch != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// ===== IMPORT SCANNER =====
+
+type importTokenKind int
+
+const (
+	importIdent importTokenKind = iota
+	importString
+	importCharSet // ANTLR [...] character set or argument block, raw body
+	importAction  // ANTLR {...} action, raw body
+	importSpecial // EBNF ? ... ? special sequence
+	importNumber
+	importPunct
+	importEOF
+)
+
+type importToken struct {
+	kind   importTokenKind
+	text   string
+	line   int
+	column int
+}
+
+// importScanner tokenizes EBNF and ANTLR grammar files
+type importScanner struct {
+	input  []rune
+	pos    int
+	line   int
+	column int
+	antlr  bool
+	puncts []string // Longest first
+	err    *Diagnostic
+}
+
+func newImportScanner(input string, antlr bool, puncts []string) *importScanner {
+	return &importScanner{input: []rune(input), line: 1, column: 1, antlr: antlr, puncts: puncts}
+}
+
+func (s *importScanner) peekRune(offset int) rune {
+	if s.pos+offset >= len(s.input) {
+		return 0
+	}
+	return s.input[s.pos+offset]
+}
+
+func (s *importScanner) advance() rune {
+	ch := s.input[s.pos]
+	s.pos++
+	if ch == '\n' {
+		s.line++
+		s.column = 1
+	} else {
+		s.column++
+	}
+	return ch
+}
+
+func (s *importScanner) fail(line, column int, message string) importToken {
+	if s.err == nil {
+		s.err = &Diagnostic{Line: line, Column: column, Severity: "error", Message: message}
+	}
+	s.pos = len(s.input)
+	return importToken{kind: importEOF, line: line, column: column}
+}
+
+// skipTrivia skips whitespace and comments: (* *) in EBNF, // and /* */ in ANTLR
+func (s *importScanner) skipTrivia() {
+	for s.pos < len(s.input) {
+		ch := s.peekRune(0)
+		switch {
+		case unicode.IsSpace(ch):
+			s.advance()
+		case !s.antlr && ch == '(' && s.peekRune(1) == '*':
+			s.skipUntil("*)")
+		case s.antlr && ch == '/' && s.peekRune(1) == '*':
+			s.skipUntil("*/")
+		case s.antlr && ch == '/' && s.peekRune(1) == '/':
+			for s.pos < len(s.input) && s.peekRune(0) != '\n' {
+				s.advance()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (s *importScanner) skipUntil(end string) {
+	s.advance()
+	s.advance()
+	for s.pos < len(s.input) && !strings.HasPrefix(string(s.input[s.pos:min(s.pos+len(end), len(s.input))]), end) {
+		s.advance()
+	}
+	for i := 0; i < len(end) && s.pos < len(s.input); i++ {
+		s.advance()
+	}
+}
+
+func (s *importScanner) next() importToken {
+	s.skipTrivia()
+	line, column := s.line, s.column
+	tok := importToken{line: line, column: column}
+	if s.pos >= len(s.input) {
+		tok.kind = importEOF
+		return tok
+	}
+
+	ch := s.peekRune(0)
+	switch {
+	case unicode.IsLetter(ch) || ch == '_':
+		start := s.pos
+		for s.pos < len(s.input) && (unicode.IsLetter(s.peekRune(0)) || unicode.IsDigit(s.peekRune(0)) || s.peekRune(0) == '_') {
+			s.advance()
+		}
+		tok.kind, tok.text = importIdent, string(s.input[start:s.pos])
+	case unicode.IsDigit(ch):
+		start := s.pos
+		for s.pos < len(s.input) && unicode.IsDigit(s.peekRune(0)) {
+			s.advance()
+		}
+		tok.kind, tok.text = importNumber, string(s.input[start:s.pos])
+	case ch == '\'' || (!s.antlr && ch == '"'):
+		value, ok := s.readString(ch)
+		if !ok {
+			return s.fail(line, column, "unterminated string literal")
+		}
+		tok.kind, tok.text = importString, value
+	case s.antlr && ch == '[':
+		body, ok := s.readDelimited('[', ']')
+		if !ok {
+			return s.fail(line, column, "unterminated character set")
+		}
+		tok.kind, tok.text = importCharSet, body
+	case s.antlr && ch == '{':
+		body, ok := s.readDelimited('{', '}')
+		if !ok {
+			return s.fail(line, column, "unterminated action")
+		}
+		tok.kind, tok.text = importAction, body
+	case !s.antlr && ch == '?':
+		body, ok := s.readDelimited('?', '?')
+		if !ok {
+			return s.fail(line, column, "unterminated special sequence")
+		}
+		tok.kind, tok.text = importSpecial, strings.TrimSpace(body)
+	default:
+		for _, punct := range s.puncts {
+			if strings.HasPrefix(string(s.input[s.pos:min(s.pos+len(punct), len(s.input))]), punct) {
+				for range punct {
+					s.advance()
+				}
+				tok.kind, tok.text = importPunct, punct
+				return tok
+			}
+		}
+		return s.fail(line, column, fmt.Sprintf("unexpected character %q", ch))
+	}
+	return tok
+}
+
+// readString reads a quoted literal. ANTLR literals use backslash escapes;
+// ISO EBNF terminals have none.
+func (s *importScanner) readString(quote rune) (string, bool) {
+	s.advance()
+	var value strings.Builder
+	for s.pos < len(s.input) {
+		ch := s.advance()
+		switch {
+		case ch == quote:
+			return value.String(), true
+		case ch == '\n':
+			return "", false
+		case ch == '\\' && s.antlr && s.pos < len(s.input):
+			value.WriteRune(s.readEscape())
+		default:
+			value.WriteRune(ch)
+		}
+	}
+	return "", false
+}
+
+// readEscape decodes the ANTLR escape after a backslash
+func (s *importScanner) readEscape() rune {
+	ch := s.advance()
+	switch ch {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	case 'u':
+		var hex strings.Builder
+		if s.peekRune(0) == '{' {
+			s.advance()
+			for s.pos < len(s.input) && s.peekRune(0) != '}' {
+				hex.WriteRune(s.advance())
+			}
+			if s.pos < len(s.input) {
+				s.advance()
+			}
+		} else {
+			for i := 0; i < 4 && s.pos < len(s.input); i++ {
+				hex.WriteRune(s.advance())
+			}
+		}
+		var value rune
+		if _, err := fmt.Sscanf(hex.String(), "%x", &value); err != nil {
+			return unicode.ReplacementChar
+		}
+		return value
+	}
+	return ch
+}
+
+// readDelimited reads a possibly nested block, returning its raw body
+func (s *importScanner) readDelimited(open, close rune) (string, bool) {
+	s.advance()
+	start := s.pos
+	depth := 1
+	for s.pos < len(s.input) {
+		ch := s.peekRune(0)
+		switch {
+		case ch == '\\' && s.antlr:
+			s.advance()
+		case ch == close:
+			depth--
+			if depth == 0 {
+				body := string(s.input[start:s.pos])
+				s.advance()
+				return body, true
+			}
+		case ch == open && open != close && open != '[':
+			depth++
+		}
+		if s.pos < len(s.input) {
+			s.advance()
+		}
+	}
+	return "", false
+}
+
+// tokens scans the whole input, returning a scan error as a diagnostic
+func (s *importScanner) tokens() ([]importToken, *Diagnostic) {
+	var tokens []importToken
+	for {
+		tok := s.next()
+		tokens = append(tokens, tok)
+		if tok.kind == importEOF {
+			return tokens, s.err
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package parser_generator
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const ebnfSample = `(* Arithmetic in ISO EBNF *)
+expression = term, { ("+" | "-"), term } ;
+term = factor, { ("*" | "/"), factor } ;
+factor = [ "-" ], ( number | "(", expression, ")" ) ;
+number = digit, { digit } ;
+digit = "0" | "1" | "2" | "3" | "4" | "5" | "6" | "7" | "8" | "9" ;
+`
+
+const antlrSample = `grammar Calc;
+
+options { language = Java; }
+
+@header { package calc; }
+
+prog : stat+ EOF ;
+stat : ID '=' expr ';'      # assign
+     | expr ';'             # print
+     ;
+expr : term (('+' | '-') term)* ;
+term : factor (('*' | '/') factor)* ;
+factor : sign=('+' | '-')? atom ;
+atom : INT | ID | '(' expr ')' ;
+
+PLUS : '+' ;
+ID : LETTER (LETTER | DIGIT)* ;
+INT : DIGIT+ ('.' DIGIT+)? ;
+STRING : '"' (~["\\\r\n] | '\\' .)*? '"' ;
+fragment LETTER : [a-zA-Z_] ;
+fragment DIGIT : '0'..'9' ;
+COMMENT : '//' ~[\r\n]* -> channel(HIDDEN) ;
+WS : [ \t\r\n]+ -> skip ;
+`
+
+// TestImportEBNF tests translation of ISO EBNF grammars
+func TestImportEBNF(t *testing.T) {
+	grammar, diagnostics, err := ImportEBNF(ebnfSample, "arith")
+	if err != nil {
+		t.Fatalf("ImportEBNF failed: %v", err)
+	}
+	if len(diagnostics) != 0 {
+		t.Errorf("Unexpected diagnostics: %v", diagnostics)
+	}
+
+	if grammar.Name != "arith" || grammar.StartRule != "expression" {
+		t.Errorf("Expected grammar arith starting at expression, got %s starting at %s", grammar.Name, grammar.StartRule)
+	}
+	if len(grammar.Rules) != 5 {
+		t.Errorf("Expected 5 rules, got %d", len(grammar.Rules))
+	}
+
+	expected := map[string]string{
+		"PLUS": "+", "MINUS": "-", "STAR": "*", "SLASH": "/", "LPAREN": "(", "RPAREN": ")", "KW_0": "",
+	}
+	for name, literal := range expected {
+		token := grammar.GetTokenByName(name)
+		if literal == "" {
+			if token != nil {
+				t.Errorf("Digit terminals should not be named as keywords: %s", name)
+			}
+			continue
+		}
+		if token == nil || token.Literal != literal {
+			t.Errorf("Expected token %s for %q, got %+v", name, literal, token)
+		}
+	}
+	if token := grammar.GetTokenByName("WHITESPACE"); token == nil || !token.Skip {
+		t.Error("Expected a skipped WHITESPACE token")
+	}
+
+	factor := grammar.GetRuleByName("factor").Expression.String()
+	if factor != "[MINUS] (number | LPAREN expression RPAREN)" {
+		t.Errorf("Unexpected factor translation: %s", factor)
+	}
+
+	generator := NewGenerator(&Config{PackageName: "arith"})
+	if err := generator.Validate(grammar); err != nil {
+		t.Fatalf("Imported grammar is invalid: %v", err)
+	}
+	if _, err := NewGoGenerator(&Config{PackageName: "arith"}).Generate(grammar); err != nil {
+		t.Errorf("Go generation failed: %v", err)
+	}
+}
+
+// TestImportEBNFConstructs tests the less common EBNF constructs
+func TestImportEBNFConstructs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		rule     string
+		expected string
+		warnings int
+	}{
+		{
+			name:     "multi-word meta identifier",
+			input:    `signed integer = [ "-" ], unsigned integer ; unsigned integer = "1" ;`,
+			rule:     "signed_integer",
+			expected: "[MINUS] unsigned_integer",
+		},
+		{
+			name:     "fixed repetition",
+			input:    `pair = 2 * "x" .`,
+			rule:     "pair",
+			expected: "KW_X{2,2}",
+		},
+		{
+			name:     "empty alternative",
+			input:    `sign = "+" | "-" | ;`,
+			rule:     "sign",
+			expected: "[PLUS | MINUS]",
+		},
+		{
+			name:     "exception",
+			input:    `name = word - "if" ; word = "a" | "if" ;`,
+			rule:     "name",
+			expected: "!KW_IF word",
+			warnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grammar, diagnostics, err := ImportEBNF(tt.input, "test")
+			if err != nil {
+				t.Fatalf("ImportEBNF failed: %v", err)
+			}
+			if len(diagnostics) != tt.warnings {
+				t.Errorf("Expected %d warnings, got %v", tt.warnings, diagnostics)
+			}
+			rule := grammar.GetRuleByName(tt.rule)
+			if rule == nil {
+				t.Fatalf("Rule %s not imported", tt.rule)
+			}
+			if got := rule.Expression.String(); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestImportANTLR tests translation of ANTLR4 combined grammars
+func TestImportANTLR(t *testing.T) {
+	grammar, diagnostics, err := ImportANTLR(antlrSample)
+	if err != nil {
+		t.Fatalf("ImportANTLR failed: %v", err)
+	}
+
+	var warnings []string
+	for _, d := range diagnostics {
+		warnings = append(warnings, d.Message)
+	}
+	joined := strings.Join(warnings, "\n")
+	for _, want := range []string{"options block ignored", "@header action ignored", "EOF dropped"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected warning %q, got:\n%s", want, joined)
+		}
+	}
+
+	if grammar.Name != "Calc" || grammar.StartRule != "prog" {
+		t.Errorf("Expected grammar Calc starting at prog, got %s starting at %s", grammar.Name, grammar.StartRule)
+	}
+
+	// Literals reuse the lexer rule that defines them
+	expr := grammar.GetRuleByName("expr").Expression.String()
+	if expr != "term ((PLUS | MINUS) term)*" {
+		t.Errorf("Unexpected expr translation: %s", expr)
+	}
+	if minus := grammar.GetTokenByName("MINUS"); minus == nil || minus.Literal != "-" {
+		t.Errorf("Expected implicit MINUS token, got %+v", minus)
+	}
+	if factor := grammar.GetRuleByName("factor").Expression.String(); factor != "[(PLUS | MINUS)] atom" {
+		t.Errorf("Labels should be dropped, got %s", factor)
+	}
+
+	tests := []struct {
+		token string
+		match []string
+		skip  bool
+	}{
+		{token: "ID", match: []string{"x", "_tmp1"}},
+		{token: "INT", match: []string{"42", "3.14"}},
+		{token: "STRING", match: []string{`"a\"b"`, `""`}},
+		{token: "COMMENT", match: []string{"// note"}, skip: true},
+		{token: "WS", match: []string{" \t\n"}, skip: true},
+	}
+	for _, tt := range tests {
+		token := grammar.GetTokenByName(tt.token)
+		if token == nil {
+			t.Errorf("Token %s not imported", tt.token)
+			continue
+		}
+		if token.Skip != tt.skip {
+			t.Errorf("Token %s: expected skip=%v", tt.token, tt.skip)
+		}
+		re, err := regexp.Compile("^(?:" + token.Pattern + ")$")
+		if err != nil {
+			t.Errorf("Token %s: invalid pattern %s: %v", tt.token, token.Pattern, err)
+			continue
+		}
+		for _, input := range tt.match {
+			if !re.MatchString(input) {
+				t.Errorf("Token %s: pattern %s should match %q", tt.token, token.Pattern, input)
+			}
+		}
+	}
+	if letter := grammar.GetTokenByName("LETTER"); letter == nil || !letter.Fragment {
+		t.Error("Expected LETTER to be imported as a fragment")
+	}
+
+	generator := NewGenerator(&Config{PackageName: "calc"})
+	if err := generator.Validate(grammar); err != nil {
+		t.Fatalf("Imported grammar is invalid: %v", err)
+	}
+	if _, err := NewGoGenerator(&Config{PackageName: "calc"}).Generate(grammar); err != nil {
+		t.Errorf("Go generation failed: %v", err)
+	}
+	if _, err := NewTypeScriptGenerator(&Config{}).Generate(grammar); err != nil {
+		t.Errorf("TypeScript generation failed: %v", err)
+	}
+}
+
+// TestImportDiagnostics tests that unsupported constructs are reported
+func TestImportDiagnostics(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		line   int
+		errMsg string
+	}{
+		{
+			name:   "EBNF special sequence",
+			format: "ebnf",
+			input:  "letter = ? any letter ? ;",
+			line:   1,
+			errMsg: "special sequence ? any letter ? is not supported",
+		},
+		{
+			name:   "EBNF missing terminator",
+			format: "ebnf",
+			input:  "a = \"x\"\nb = \"y\" ;",
+			line:   2,
+			errMsg: "expected ';' at the end of rule a",
+		},
+		{
+			name:   "EBNF unterminated comment is unterminated string",
+			format: "ebnf",
+			input:  "a = \"x ;",
+			line:   1,
+			errMsg: "unterminated string literal",
+		},
+		{
+			name:   "ANTLR left recursion",
+			format: "g4",
+			input:  "grammar E;\ne : e '+' e\n  | INT ;\nINT : [0-9]+ ;",
+			line:   2,
+			errMsg: "rule e is left-recursive",
+		},
+		{
+			name:   "ANTLR lexer mode",
+			format: "g4",
+			input:  "lexer grammar L;\nA : 'a' ;\nmode INSIDE;\nB : 'b' ;",
+			line:   3,
+			errMsg: "lexer modes are not supported",
+		},
+		{
+			name:   "ANTLR semantic predicate",
+			format: "g4",
+			input:  "grammar P;\ns : {isOk()}? ID ;\nID : [a-z]+ ;",
+			line:   2,
+			errMsg: "semantic predicates are not supported",
+		},
+		{
+			name:   "ANTLR unsupported lexer command",
+			format: "g4",
+			input:  "grammar P;\ns : ID ;\nID : [a-z]+ -> pushMode(X) ;",
+			line:   3,
+			errMsg: "command pushMode is not supported",
+		},
+		{
+			name:   "ANTLR recursive lexer rule",
+			format: "g4",
+			input:  "grammar P;\ns : NEST ;\nNEST : '(' NEST? ')' ;",
+			line:   3,
+			errMsg: "lexer rule NEST is recursive",
+		},
+		{
+			name:   "ANTLR undefined token",
+			format: "g4",
+			input:  "grammar P;\ns : ID NUM ;\nID : [a-z]+ ;",
+			line:   2,
+			errMsg: "undefined token NUM",
+		},
+		{
+			name:   "ANTLR unicode property",
+			format: "g4",
+			input:  "grammar P;\ns : ID ;\nID : [\\p{L}]+ ;",
+			line:   3,
+			errMsg: "Unicode property escapes",
+		},
+		{
+			name:   "ANTLR tokens without lexer rule",
+			format: "g4",
+			input:  "grammar P;\ntokens { INDENT }\ns : ID ;\nID : [a-z]+ ;",
+			line:   2,
+			errMsg: "token INDENT is declared in tokens {}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var diagnostics []Diagnostic
+			var err error
+			if tt.format == "ebnf" {
+				_, diagnostics, err = ImportEBNF(tt.input, "test")
+			} else {
+				_, diagnostics, err = ImportANTLR(tt.input)
+			}
+			if err == nil {
+				t.Fatal("Expected an import error")
+			}
+			if _, ok := err.(*ImportError); !ok {
+				t.Errorf("Expected *ImportError, got %T", err)
+			}
+
+			for _, d := range diagnostics {
+				if d.Severity == "error" && strings.Contains(d.Message, tt.errMsg) {
+					if d.Line != tt.line {
+						t.Errorf("Expected error on line %d, got %s", tt.line, d)
+					}
+					return
+				}
+			}
+			t.Errorf("Expected error containing %q, got %v", tt.errMsg, diagnostics)
+		})
+	}
+}
+
+// TestImportGrammarFile tests format selection by file extension
+func TestImportGrammarFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"arith.ebnf": ebnfSample,
+		"Calc.g4":    antlrSample,
+		"native.txt": "%name \"N\"\n%start s\n%token A \"a\"\ns -> A",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+
+		grammar, err := ParseGrammarFile(path)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if grammar.StartRule == "" || len(grammar.Rules) == 0 {
+			t.Errorf("%s: grammar not imported", name)
+		}
+		if want := map[string]string{"arith.ebnf": "EBNF", "Calc.g4": "ANTLR"}[name]; grammar.Options["imported_from"] != want {
+			t.Errorf("%s: expected imported_from %q, got %q", name, want, grammar.Options["imported_from"])
+		}
+	}
+}
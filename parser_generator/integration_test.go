@@ -6,6 +6,7 @@ package parser_generator
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -251,6 +252,15 @@ expr -> BAD`,
 			expectError: true,
 			errorMsg:    "undefined",
 		},
+		{
+			name: "unknown AST node",
+			grammar: `%name "Test"
+%start expr
+%token NUM /\d+/
+expr -> NUM => Number`,
+			expectError: true,
+			errorMsg:    "unknown AST node",
+		},
 		{
 			name: "left recursive grammar",
 			grammar: `%name "Test"
@@ -496,6 +506,124 @@ term -> NUMBER | LPAREN expr RPAREN
 	t.Logf("Cross-language consistency verified")
 }
 
+// TestGeneratedParsersRun compiles generated Go parsers and checks the ASTs
+// and errors they produce
+func TestGeneratedParsersRun(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not available")
+	}
+	if testing.Short() {
+		t.Skip("compiling generated parsers is slow")
+	}
+
+	predicateGrammar := `%name "Predicate Test"
+%start expr
+
+%token NUMBER /\d+/
+%token IDENTIFIER /[a-z]+/
+%token LPAREN "("
+%token RPAREN ")"
+%token COMMA ","
+%token WHITESPACE /\s+/ skip
+
+expr -> call | zero | name | number
+call -> IDENTIFIER &LPAREN LPAREN expr (COMMA expr)* RPAREN => FunctionCall
+zero -> "zero" { return &NumberLiteral{Value: 0, Range: rng}, nil }
+name -> IDENTIFIER !LPAREN => Identifier
+number -> NUMBER => NumberLiteral
+`
+
+	expressionGrammar, err := os.ReadFile("expression_grammar.txt")
+	if err != nil {
+		t.Fatalf("Failed to read expression grammar: %v", err)
+	}
+
+	packages := []struct {
+		name    string
+		grammar string
+		cases   string // Go composite literal entries of {input, want}
+	}{
+		{
+			name:    "expr",
+			grammar: string(expressionGrammar),
+			cases: `
+		{"1 + 2 * 3", "(1 + (2 * 3))"},
+		{"a AND b || !c", "((a AND b) OR (NOTc))"},
+		{"opt_a -> opt_b", "(opt_a -> opt_b)"},
+		{"NOT x == 2.5", "(NOT(x == 2.5))"},
+		{"MIN(1, x) >= -y", "(MIN(1, x) >= (-y))"},
+		{"ITE(true, ANDROID, 2)", "ITE(true, ANDROID, 2)"},
+		{"(1 + 2", "error: ')', '*', '+'"},
+		{"1 2", "error: got '2'"},
+		{"a @ b", "error: Unexpected character '@'"},`,
+		},
+		{
+			name:    "pred",
+			grammar: predicateGrammar,
+			cases: `
+		{"f(1, x)", "IDENTIFIER(1, x)"},
+		{"f(zero)", "IDENTIFIER(0)"},
+		{"x", "x"},
+		{"x(", "error: got end of input"},`,
+		},
+	}
+
+	moduleDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(moduleDir, "go.mod"), []byte("module generated\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+
+	for _, pkg := range packages {
+		grammar, err := NewGrammarParser(pkg.grammar).ParseGrammar()
+		if err != nil {
+			t.Fatalf("%s: failed to parse grammar: %v", pkg.name, err)
+		}
+		generator := NewGenerator(&Config{PackageName: pkg.name})
+		if err := generator.Validate(grammar); err != nil {
+			t.Fatalf("%s: grammar validation failed: %v", pkg.name, err)
+		}
+		dir := filepath.Join(moduleDir, pkg.name)
+		if err := generator.GenerateGo(grammar, dir); err != nil {
+			t.Fatalf("%s: Go generation failed: %v", pkg.name, err)
+		}
+
+		test := fmt.Sprintf(`package %s
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerated(t *testing.T) {
+	for _, tc := range []struct{ input, want string }{%s
+	} {
+		expr, err := ParseExpression(tc.input)
+		got := ""
+		if err != nil {
+			got = "error: " + err.Error()
+		} else {
+			got = expr.String()
+		}
+		if got != tc.want && !(strings.HasPrefix(tc.want, "error: ") && strings.Contains(got, tc.want[len("error: "):])) {
+			t.Errorf("%%q: expected %%q, got %%q", tc.input, tc.want, got)
+		}
+	}
+}
+`, pkg.name, pkg.cases)
+		if err := os.WriteFile(filepath.Join(dir, "parser_test.go"), []byte(test), 0644); err != nil {
+			t.Fatalf("Failed to write test: %v", err)
+		}
+	}
+
+	cmd := exec.Command(goTool, "test", "./...")
+	cmd.Dir = moduleDir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Generated parsers failed: %v\n%s", err, output)
+	}
+}
+
 // BenchmarkParserGeneration benchmarks the parser generation process
 func BenchmarkParserGeneration(b *testing.B) {
 	config := &Config{
@@ -0,0 +1,813 @@
+package parser_generator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ===== TYPESCRIPT TYPE STRIPPING =====
+
+// StripTypeScript erases TypeScript syntax so the code runs as JavaScript.
+// It covers what the TypeScript generator emits: type annotations, interfaces,
+// type aliases, access modifiers, implements clauses, generic arguments,
+// "as" casts and enums, which become plain objects (without the reverse
+// mapping numeric enums have). Everything else is copied byte for byte.
+// Namespaces, decorators and parameter properties are rejected.
+func StripTypeScript(source string) (string, error) {
+	tokens, err := scanTS(source)
+	if err != nil {
+		return "", err
+	}
+	s := &tsStripper{source: source, tokens: tokens}
+	if err := s.walk(0, len(tokens)); err != nil {
+		return "", err
+	}
+	return s.apply()
+}
+
+type tsTokenKind int
+
+const (
+	tsIdentTok tsTokenKind = iota
+	tsNumberTok
+	tsStringTok
+	tsTemplateTok
+	tsRegexTok
+	tsPunctTok
+	tsEOFTok
+)
+
+type tsToken struct {
+	kind       tsTokenKind
+	text       string
+	start, end int
+}
+
+// tsPuncts lists multi-character operators, longest first. ">" is always a
+// single token so nested generic arguments close one level at a time.
+var tsPuncts = []string{
+	"...", "===", "!==", "**=", "<<=", "&&=", "||=", "??=",
+	"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "++", "--",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "**", "<<",
+}
+
+// tsRegexKeywords may be followed by a regular expression literal
+var tsRegexKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true,
+	"delete": true, "void": true, "throw": true, "case": true, "do": true, "else": true, "yield": true, "await": true,
+}
+
+// scanTS tokenizes TypeScript source, skipping whitespace and comments
+func scanTS(source string) ([]tsToken, error) {
+	var tokens []tsToken
+	pos := 0
+	lineOf := func(offset int) int {
+		return strings.Count(source[:offset], "\n") + 1
+	}
+
+	for pos < len(source) {
+		ch := source[pos]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			pos++
+			continue
+		case strings.HasPrefix(source[pos:], "//"):
+			end := strings.IndexByte(source[pos:], '\n')
+			if end < 0 {
+				end = len(source) - pos
+			}
+			pos += end
+			continue
+		case strings.HasPrefix(source[pos:], "/*"):
+			end := strings.Index(source[pos+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", lineOf(pos))
+			}
+			pos += end + 4
+			continue
+		}
+
+		start := pos
+		tok := tsToken{start: start}
+		switch {
+		case ch == '_' || ch == '$' || ch == '#' || unicode.IsLetter(rune(ch)) || ch >= 0x80:
+			pos++
+			for pos < len(source) && (source[pos] == '_' || source[pos] == '$' || source[pos] >= 0x80 ||
+				unicode.IsLetter(rune(source[pos])) || unicode.IsDigit(rune(source[pos]))) {
+				pos++
+			}
+			tok.kind = tsIdentTok
+		case unicode.IsDigit(rune(ch)) || (ch == '.' && pos+1 < len(source) && unicode.IsDigit(rune(source[pos+1]))):
+			pos++
+			for pos < len(source) && (source[pos] == '.' || source[pos] == '_' || unicode.IsLetter(rune(source[pos])) || unicode.IsDigit(rune(source[pos]))) {
+				pos++
+			}
+			tok.kind = tsNumberTok
+		case ch == '\'' || ch == '"':
+			end, ok := scanTSString(source, pos)
+			if !ok {
+				return nil, fmt.Errorf("line %d: unterminated string", lineOf(pos))
+			}
+			pos = end
+			tok.kind = tsStringTok
+		case ch == '`':
+			end, ok := scanTSTemplate(source, pos)
+			if !ok {
+				return nil, fmt.Errorf("line %d: unterminated template literal", lineOf(pos))
+			}
+			pos = end
+			tok.kind = tsTemplateTok
+		case ch == '/' && tsRegexAllowed(tokens):
+			end, ok := scanTSRegex(source, pos)
+			if !ok {
+				return nil, fmt.Errorf("line %d: unterminated regular expression", lineOf(pos))
+			}
+			pos = end
+			tok.kind = tsRegexTok
+		default:
+			tok.kind = tsPunctTok
+			pos++
+			for _, punct := range tsPuncts {
+				if strings.HasPrefix(source[start:], punct) {
+					pos = start + len(punct)
+					break
+				}
+			}
+		}
+		tok.end = pos
+		tok.text = source[start:pos]
+		tokens = append(tokens, tok)
+	}
+	return append(tokens, tsToken{kind: tsEOFTok, start: len(source), end: len(source)}), nil
+}
+
+func scanTSString(source string, pos int) (int, bool) {
+	quote := source[pos]
+	for pos++; pos < len(source); pos++ {
+		switch source[pos] {
+		case '\\':
+			pos++
+		case quote:
+			return pos + 1, true
+		case '\n':
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// scanTSTemplate skips a template literal, including nested ${...} expressions
+func scanTSTemplate(source string, pos int) (int, bool) {
+	for pos++; pos < len(source); pos++ {
+		switch source[pos] {
+		case '\\':
+			pos++
+		case '`':
+			return pos + 1, true
+		case '$':
+			if pos+1 < len(source) && source[pos+1] == '{' {
+				depth := 0
+				for pos++; pos < len(source); pos++ {
+					switch source[pos] {
+					case '{':
+						depth++
+					case '}':
+						depth--
+					case '\'', '"':
+						end, ok := scanTSString(source, pos)
+						if !ok {
+							return 0, false
+						}
+						pos = end - 1
+					case '`':
+						end, ok := scanTSTemplate(source, pos)
+						if !ok {
+							return 0, false
+						}
+						pos = end - 1
+					}
+					if depth == 0 {
+						break
+					}
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+func scanTSRegex(source string, pos int) (int, bool) {
+	inClass := false
+	for pos++; pos < len(source); pos++ {
+		switch source[pos] {
+		case '\\':
+			pos++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '\n':
+			return 0, false
+		case '/':
+			if !inClass {
+				pos++
+				for pos < len(source) && unicode.IsLetter(rune(source[pos])) {
+					pos++
+				}
+				return pos, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// tsRegexAllowed reports whether a '/' starts a regular expression rather
+// than a division, judging by the previous token
+func tsRegexAllowed(tokens []tsToken) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	prev := tokens[len(tokens)-1]
+	switch prev.kind {
+	case tsIdentTok:
+		return tsRegexKeywords[prev.text]
+	case tsPunctTok:
+		return prev.text != ")" && prev.text != "]" && prev.text != "}"
+	}
+	return false
+}
+
+// tsEdit replaces source[start:end]
+type tsEdit struct {
+	start, end int
+	text       string
+}
+
+type tsStripper struct {
+	source string
+	tokens []tsToken
+	edits  []tsEdit
+	braces []bool // Open braces; true for class bodies
+}
+
+func (s *tsStripper) is(i int, text string) bool {
+	return i < len(s.tokens) && s.tokens[i].kind != tsStringTok && s.tokens[i].text == text
+}
+
+func (s *tsStripper) isIdent(i int) bool {
+	return i < len(s.tokens) && s.tokens[i].kind == tsIdentTok
+}
+
+func (s *tsStripper) errorf(i int, format string, args ...interface{}) error {
+	line := strings.Count(s.source[:s.tokens[i].start], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// remove deletes tokens [from, to)
+func (s *tsStripper) remove(from, to int) {
+	if from < to {
+		s.edits = append(s.edits, tsEdit{start: s.tokens[from].start, end: s.tokens[to-1].end})
+	}
+}
+
+// removeWord deletes one token and the spaces after it
+func (s *tsStripper) removeWord(i int) {
+	end := s.tokens[i].end
+	for end < len(s.source) && (s.source[end] == ' ' || s.source[end] == '\t') {
+		end++
+	}
+	s.edits = append(s.edits, tsEdit{start: s.tokens[i].start, end: end})
+}
+
+func (s *tsStripper) apply() (string, error) {
+	sort.SliceStable(s.edits, func(i, j int) bool { return s.edits[i].start < s.edits[j].start })
+	var result strings.Builder
+	pos := 0
+	for _, edit := range s.edits {
+		if edit.start < pos {
+			return "", fmt.Errorf("internal error: overlapping edits at offset %d", edit.start)
+		}
+		result.WriteString(s.source[pos:edit.start])
+		result.WriteString(edit.text)
+		pos = edit.end
+	}
+	result.WriteString(s.source[pos:])
+	return result.String(), nil
+}
+
+// matching returns the index of the bracket closing the one at i
+func (s *tsStripper) matching(i int) int {
+	open := s.tokens[i].text
+	close := map[string]string{"(": ")", "[": "]", "{": "}", "<": ">"}[open]
+	depth := 0
+	for j := i; j < len(s.tokens)-1; j++ {
+		if s.tokens[j].kind != tsPunctTok {
+			continue
+		}
+		switch s.tokens[j].text {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return len(s.tokens) - 1
+}
+
+// statementStart reports whether token i begins a statement
+func (s *tsStripper) statementStart(i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := s.tokens[i-1]
+	return prev.kind == tsPunctTok && (prev.text == ";" || prev.text == "{" || prev.text == "}")
+}
+
+func (s *tsStripper) inClassBody() bool {
+	return len(s.braces) > 0 && s.braces[len(s.braces)-1]
+}
+
+// expressionEnd reports whether token i can end an operand
+func (s *tsStripper) expressionEnd(i int) bool {
+	if i < 0 {
+		return false
+	}
+	tok := s.tokens[i]
+	switch tok.kind {
+	case tsIdentTok:
+		return !tsRegexKeywords[tok.text]
+	case tsPunctTok:
+		return tok.text == ")" || tok.text == "]"
+	}
+	return tok.kind != tsEOFTok
+}
+
+// walk strips tokens [i, end)
+func (s *tsStripper) walk(i, end int) error {
+	for i < end {
+		tok := s.tokens[i]
+		if s.inClassBody() && s.statementStart(i) && !s.is(i, "}") && !s.is(i, ";") {
+			next, err := s.member(i)
+			if err != nil {
+				return err
+			}
+			i = next
+			continue
+		}
+
+		if s.statementStart(i) && tok.kind == tsIdentTok {
+			next, handled, err := s.declaration(i)
+			if err != nil {
+				return err
+			}
+			if handled {
+				i = next
+				continue
+			}
+		}
+
+		switch {
+		case tok.kind == tsIdentTok && tok.text == "class":
+			i = s.classHeader(i)
+			continue
+		case tok.kind == tsIdentTok && tok.text == "function":
+			next, err := s.function(i)
+			if err != nil {
+				return err
+			}
+			i = next
+			continue
+		case tok.kind == tsIdentTok && (tok.text == "let" || tok.text == "const" || tok.text == "var"):
+			i = s.variable(i)
+			continue
+		case tok.kind == tsIdentTok && tok.text == "as" && s.expressionEnd(i-1) && i+1 < end:
+			typeEnd := s.skipType(i + 1)
+			s.edits = append(s.edits, tsEdit{start: s.tokens[i-1].end, end: s.tokens[typeEnd-1].end})
+			i = typeEnd
+			continue
+		case tok.kind == tsIdentTok && tok.text == "new" && s.isIdent(i+1):
+			j := i + 2
+			for s.is(j, ".") && s.isIdent(j+1) {
+				j += 2
+			}
+			if s.is(j, "<") {
+				close := s.matching(j)
+				s.remove(j, close+1)
+				j = close + 1
+			}
+			i = j
+			continue
+		case tok.kind == tsPunctTok && tok.text == "(":
+			close := s.matching(i)
+			if s.arrowParams(close) {
+				next, err := s.signature(i, close)
+				if err != nil {
+					return err
+				}
+				i = next
+				continue
+			}
+		case tok.kind == tsPunctTok && tok.text == "!" && s.expressionEnd(i-1) &&
+			(s.is(i+1, ".") || s.is(i+1, ")") || s.is(i+1, ";") || s.is(i+1, ",") || s.is(i+1, "[")):
+			// Non-null assertion
+			s.remove(i, i+1)
+		case tok.kind == tsPunctTok && tok.text == "@":
+			return s.errorf(i, "decorators are not supported")
+		case tok.kind == tsPunctTok && tok.text == "{":
+			s.braces = append(s.braces, false)
+		case tok.kind == tsPunctTok && tok.text == "}":
+			if len(s.braces) > 0 {
+				s.braces = s.braces[:len(s.braces)-1]
+			}
+		}
+		i++
+	}
+	return nil
+}
+
+// declaration handles statements that start with a TypeScript keyword
+func (s *tsStripper) declaration(i int) (int, bool, error) {
+	tok := s.tokens[i]
+	switch tok.text {
+	case "export":
+		if !s.isIdent(i + 1) {
+			break
+		}
+		s.removeWord(i)
+		if s.is(i+1, "default") {
+			s.removeWord(i + 1)
+			return i + 2, true, nil
+		}
+		// The declaration that follows still starts a statement
+		next, handled, err := s.declaration(i + 1)
+		if handled || err != nil {
+			return next, handled, err
+		}
+		return i + 1, true, nil
+	case "interface":
+		if s.isIdent(i + 1) {
+			j := i + 2
+			for !s.is(j, "{") && j < len(s.tokens)-1 {
+				j++
+			}
+			close := s.matching(j)
+			s.remove(i, close+1)
+			return close + 1, true, nil
+		}
+	case "type":
+		if s.isIdent(i+1) && (s.is(i+2, "=") || s.is(i+2, "<")) {
+			j := i + 2
+			if s.is(j, "<") {
+				j = s.matching(j) + 1
+			}
+			j = s.skipType(j + 1)
+			if s.is(j, ";") {
+				j++
+			}
+			s.remove(i, j)
+			return j, true, nil
+		}
+	case "const":
+		if s.is(i+1, "enum") {
+			return s.enum(i, i+1)
+		}
+	case "enum":
+		if s.isIdent(i + 1) {
+			return s.enum(i, i)
+		}
+	case "abstract":
+		if s.is(i+1, "class") {
+			s.removeWord(i)
+			return i + 1, true, nil
+		}
+	case "declare", "namespace", "module":
+		if s.isIdent(i + 1) {
+			return 0, false, s.errorf(i, "%s declarations are not supported", tok.text)
+		}
+	}
+	return i, false, nil
+}
+
+// enum rewrites an enum as an object literal
+func (s *tsStripper) enum(start, keyword int) (int, bool, error) {
+	name := s.tokens[keyword+1].text
+	if !s.is(keyword+2, "{") {
+		return 0, false, s.errorf(keyword, "expected '{' after enum %s", name)
+	}
+	close := s.matching(keyword + 2)
+
+	var members []string
+	next := 0
+	j := keyword + 3
+	for j < close {
+		member := s.tokens[j]
+		key := member.text
+		if member.kind != tsIdentTok && member.kind != tsStringTok {
+			return 0, false, s.errorf(j, "unexpected %q in enum %s", member.text, name)
+		}
+		j++
+		value := strconv.Itoa(next)
+		if s.is(j, "=") {
+			valueStart := j + 1
+			for j = valueStart; j < close && !s.is(j, ","); j++ {
+				if s.is(j, "(") || s.is(j, "[") || s.is(j, "{") {
+					j = s.matching(j)
+				}
+			}
+			value = s.source[s.tokens[valueStart].start:s.tokens[j-1].end]
+			if n, err := strconv.Atoi(value); err == nil {
+				next = n
+			}
+		}
+		next++
+		members = append(members, key+": "+value)
+		if s.is(j, ",") {
+			j++
+		}
+	}
+
+	s.edits = append(s.edits, tsEdit{
+		start: s.tokens[start].start,
+		end:   s.tokens[close].end,
+		text:  "const " + name + " = { " + strings.Join(members, ", ") + " };",
+	})
+	return close + 1, true, nil
+}
+
+// classHeader strips generic parameters and implements clauses, and marks
+// the body as a class body
+func (s *tsStripper) classHeader(i int) int {
+	j := i + 1
+	if s.isIdent(j) && s.tokens[j].text != "extends" && s.tokens[j].text != "implements" {
+		j++
+	}
+	if s.is(j, "<") {
+		close := s.matching(j)
+		s.remove(j, close+1)
+		j = close + 1
+	}
+	for j < len(s.tokens)-1 && !s.is(j, "{") {
+		switch {
+		case s.is(j, "implements"):
+			k := j
+			for !s.is(k, "{") && k < len(s.tokens)-1 {
+				k++
+			}
+			s.edits = append(s.edits, tsEdit{start: s.tokens[j-1].end, end: s.tokens[k].start, text: " "})
+			j = k
+			continue
+		case s.is(j, "<"):
+			close := s.matching(j)
+			s.remove(j, close+1)
+			j = close + 1
+			continue
+		}
+		j++
+	}
+	s.braces = append(s.braces, true)
+	return j + 1
+}
+
+// tsModifiers are stripped from class members
+var tsModifiers = map[string]bool{
+	"public": true, "private": true, "protected": true, "readonly": true,
+	"abstract": true, "override": true, "declare": true,
+}
+
+// isModifier reports whether token i is a modifier rather than a member name
+func (s *tsStripper) isModifier(i int) bool {
+	if !s.isIdent(i) || !tsModifiers[s.tokens[i].text] {
+		return false
+	}
+	next := s.tokens[i+1]
+	return next.kind == tsIdentTok || next.kind == tsStringTok || (next.kind == tsPunctTok && (next.text == "[" || next.text == "*"))
+}
+
+// member strips a class member's modifiers and types
+func (s *tsStripper) member(i int) (int, error) {
+	for s.isModifier(i) {
+		s.removeWord(i)
+		i++
+	}
+	for s.is(i, "static") || s.is(i, "async") || s.is(i, "*") ||
+		((s.is(i, "get") || s.is(i, "set")) && (s.isIdent(i+1) || s.is(i+1, "["))) {
+		i++
+	}
+
+	// Member name
+	switch {
+	case s.is(i, "["):
+		i = s.matching(i) + 1
+	case s.tokens[i].kind == tsIdentTok || s.tokens[i].kind == tsStringTok || s.tokens[i].kind == tsNumberTok:
+		i++
+	default:
+		return i, s.errorf(i, "unexpected %q in class body", s.tokens[i].text)
+	}
+	if s.is(i, "?") || s.is(i, "!") {
+		s.remove(i, i+1)
+		i++
+	}
+	if s.is(i, "<") {
+		close := s.matching(i)
+		s.remove(i, close+1)
+		i = close + 1
+	}
+
+	switch {
+	case s.is(i, "("):
+		return s.signature(i, s.matching(i))
+	case s.is(i, ":"):
+		typeEnd := s.skipType(i + 1)
+		s.remove(i, typeEnd)
+		return typeEnd, nil
+	}
+	return i, nil
+}
+
+// function strips a function declaration or expression's signature
+func (s *tsStripper) function(i int) (int, error) {
+	j := i + 1
+	if s.is(j, "*") {
+		j++
+	}
+	if s.isIdent(j) {
+		j++
+	}
+	if s.is(j, "<") {
+		close := s.matching(j)
+		s.remove(j, close+1)
+		j = close + 1
+	}
+	if !s.is(j, "(") {
+		return j, s.errorf(j, "expected '(' in function declaration")
+	}
+	return s.signature(j, s.matching(j))
+}
+
+// variable strips the type annotation of a variable declaration
+func (s *tsStripper) variable(i int) int {
+	j := i + 1
+	switch {
+	case s.isIdent(j):
+		j++
+	case s.is(j, "{") || s.is(j, "["):
+		// Destructuring patterns are walked normally; only a type follows
+		close := s.matching(j)
+		if !s.is(close+1, ":") {
+			return j
+		}
+		j = close + 1
+	default:
+		return j
+	}
+	if s.is(j, "!") {
+		s.remove(j, j+1)
+		j++
+	}
+	if s.is(j, ":") {
+		typeEnd := s.skipType(j + 1)
+		s.remove(j, typeEnd)
+		return typeEnd
+	}
+	return j
+}
+
+// arrowParams reports whether the parenthesis closing at close ends the
+// parameter list of an arrow function
+func (s *tsStripper) arrowParams(close int) bool {
+	if s.is(close+1, "=>") {
+		return true
+	}
+	if s.is(close+1, ":") {
+		return s.is(s.skipType(close+2), "=>")
+	}
+	return false
+}
+
+// signature strips parameter types and the return type of a parameter list
+// from open to close, returning the token after the signature
+func (s *tsStripper) signature(open, close int) (int, error) {
+	start := open + 1
+	for start < close {
+		// Find the end of this parameter
+		end := start
+		for end < close && !s.is(end, ",") {
+			if s.is(end, "(") || s.is(end, "[") || s.is(end, "{") || s.is(end, "<") {
+				end = s.matching(end)
+			}
+			end++
+		}
+		if err := s.parameter(start, end); err != nil {
+			return 0, err
+		}
+		start = end + 1
+	}
+
+	next := close + 1
+	if s.is(next, ":") {
+		typeEnd := s.skipType(next + 1)
+		s.remove(next, typeEnd)
+		next = typeEnd
+	}
+	return next, nil
+}
+
+// parameter strips the types from one parameter in tokens [start, end)
+func (s *tsStripper) parameter(start, end int) error {
+	j := start
+	if s.isModifier(j) {
+		return s.errorf(j, "parameter properties are not supported")
+	}
+	if s.is(j, "this") && s.is(j+1, ":") {
+		// A this parameter only declares a type; drop it with its comma
+		if s.is(end, ",") {
+			end++
+		}
+		s.remove(start, end)
+		return nil
+	}
+	if s.is(j, "...") {
+		j++
+	}
+	switch {
+	case s.is(j, "{") || s.is(j, "["):
+		j = s.matching(j) + 1
+	case s.isIdent(j):
+		j++
+	}
+	if s.is(j, "?") {
+		s.remove(j, j+1)
+		j++
+	}
+	if s.is(j, ":") {
+		typeEnd := s.skipType(j + 1)
+		s.remove(j, typeEnd)
+		j = typeEnd
+	}
+	if s.is(j, "=") {
+		return s.walk(j+1, end)
+	}
+	return nil
+}
+
+// skipType returns the index after the type starting at i
+func (s *tsStripper) skipType(i int) int {
+	if s.is(i, "|") || s.is(i, "&") {
+		i++
+	}
+	i = s.skipTypeOperand(i)
+	for s.is(i, "|") || s.is(i, "&") {
+		i = s.skipTypeOperand(i + 1)
+	}
+	return i
+}
+
+func (s *tsStripper) skipTypeOperand(i int) int {
+	for s.is(i, "keyof") || s.is(i, "typeof") || s.is(i, "readonly") || s.is(i, "unique") {
+		i++
+	}
+
+	switch {
+	case s.is(i, "("):
+		i = s.matching(i) + 1
+		if s.is(i, "=>") {
+			return s.skipType(i + 1)
+		}
+	case s.is(i, "{") || s.is(i, "["):
+		i = s.matching(i) + 1
+	case s.is(i, "new") && s.is(i+1, "("):
+		i = s.matching(i+1) + 1
+		if s.is(i, "=>") {
+			return s.skipType(i + 1)
+		}
+	case s.isIdent(i):
+		i++
+		for s.is(i, ".") && s.isIdent(i+1) {
+			i += 2
+		}
+		if s.is(i, "<") {
+			i = s.matching(i) + 1
+		}
+		if s.is(i, "is") {
+			return s.skipType(i + 1)
+		}
+	case i < len(s.tokens) && (s.tokens[i].kind == tsStringTok || s.tokens[i].kind == tsNumberTok || s.tokens[i].kind == tsTemplateTok):
+		i++
+	case s.is(i, "-") && i+1 < len(s.tokens) && s.tokens[i+1].kind == tsNumberTok:
+		i += 2
+	default:
+		return i
+	}
+
+	// Array and indexed access types
+	for s.is(i, "[") {
+		i = s.matching(i) + 1
+	}
+	return i
+}
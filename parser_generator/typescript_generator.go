@@ -1,6 +1,7 @@
 package parser_generator
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -15,10 +16,16 @@ func NewTypeScriptGenerator(config *Config) *TypeScriptGenerator {
 	return &TypeScriptGenerator{config: config}
 }
 
-// Generate creates TypeScript parser code from grammar
+// Generate creates TypeScript parser code from grammar. The output mirrors
+// the Go generator: the same lexer rules, packrat combinators and AST nodes.
 func (g *TypeScriptGenerator) Generate(grammar *Grammar) (string, error) {
 	var buf strings.Builder
 
+	buf.WriteString("// Generated by parser_generator - DO NOT EDIT\n\n")
+
+	// Positions and errors
+	g.generateCoreTypes(&buf)
+
 	// Generate token enums
 	g.generateTokens(&buf, grammar)
 
@@ -29,7 +36,9 @@ func (g *TypeScriptGenerator) Generate(grammar *Grammar) (string, error) {
 	g.generateLexer(&buf, grammar)
 
 	// Generate parser
-	g.generateParser(&buf, grammar)
+	if err := g.generateParser(&buf, grammar); err != nil {
+		return "", err
+	}
 
 	// Generate utility functions
 	g.generateUtilities(&buf, grammar)
@@ -37,6 +46,48 @@ func (g *TypeScriptGenerator) Generate(grammar *Grammar) (string, error) {
 	return buf.String(), nil
 }
 
+// tsString quotes s as a TypeScript string literal
+func tsString(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+// generateCoreTypes creates source positions and the parse error class
+func (g *TypeScriptGenerator) generateCoreTypes(buf *strings.Builder) {
+	buf.WriteString(`// Position is a location in the source text
+export interface Position {
+  line: number;
+  column: number;
+  offset: number;
+}
+
+// SourceRange is a range in the source text
+export interface SourceRange {
+  start: Position;
+  end: Position;
+  text: string;
+}
+
+// ParseError is a lexical, syntax or semantic error with its source range
+export class ParseError extends Error {
+  range: SourceRange;
+  sourceText: string;
+  errorType: string;
+  suggestion: string;
+
+  constructor(message: string, range: SourceRange, sourceText: string, errorType: string, suggestion: string = '') {
+    super(message);
+    this.name = 'ParseError';
+    this.range = range;
+    this.sourceText = sourceText;
+    this.errorType = errorType;
+    this.suggestion = suggestion;
+  }
+}
+
+`)
+}
+
 // generateTokens creates token enum definitions
 func (g *TypeScriptGenerator) generateTokens(buf *strings.Builder, grammar *Grammar) {
 	buf.WriteString("// Token definitions\n")
@@ -47,15 +98,47 @@ func (g *TypeScriptGenerator) generateTokens(buf *strings.Builder, grammar *Gram
 			strings.ToUpper(token.Name), token.Name))
 	}
 
+	buf.WriteString("  LITERAL = 'LITERAL',\n")
 	buf.WriteString("  EOF = 'EOF',\n")
 	buf.WriteString("  INVALID = 'INVALID'\n")
 	buf.WriteString("}\n\n")
 
-	// Token interface
-	buf.WriteString(`export interface Token {
+	// Display names and the labels used in "Expected ..." messages
+	buf.WriteString("const tokenNames: Record<string, string> = {\n")
+	for _, token := range grammar.Tokens {
+		displayName := token.Name
+		if token.Literal != "" {
+			displayName = token.Literal
+		}
+		buf.WriteString(fmt.Sprintf("  [TokenType.%s]: %s,\n", strings.ToUpper(token.Name), tsString(displayName)))
+	}
+	buf.WriteString("};\n\n")
+
+	buf.WriteString("const tokenLabels: Record<string, string> = {\n")
+	for _, token := range grammar.Tokens {
+		buf.WriteString(fmt.Sprintf("  [TokenType.%s]: %s,\n", strings.ToUpper(token.Name), tsString(tokenLabel(token))))
+	}
+	buf.WriteString("};\n\n")
+
+	buf.WriteString(`export function tokenName(type: TokenType): string {
+  return tokenNames[type] ?? type;
+}
+
+// Token is a lexical token
+export class Token {
   type: TokenType;
   value: string;
-  pos: number;
+  range: SourceRange;
+
+  constructor(type: TokenType, value: string, range: SourceRange) {
+    this.type = type;
+    this.value = value;
+    this.range = range;
+  }
+
+  toString(): string {
+    return tokenName(this.type) + "('" + this.value + "')";
+  }
 }
 
 `)
@@ -63,19 +146,39 @@ func (g *TypeScriptGenerator) generateTokens(buf *strings.Builder, grammar *Gram
 
 // generateAST creates AST node definitions
 func (g *TypeScriptGenerator) generateAST(buf *strings.Builder, grammar *Grammar) {
-	buf.WriteString(`// AST node interface
-export interface ASTNode {
+	buf.WriteString(`// Expression is the base interface for all AST nodes
+export interface Expression {
+  readonly kind: string;
+  range: SourceRange;
   toString(): string;
 }
 
-// Expression interface
-export interface Expression extends ASTNode {
-  // Marker interface for expressions
+// NumberLiteral represents a numeric constant
+export class NumberLiteral implements Expression {
+  readonly kind = 'NumberLiteral';
+  value: number;
+  range: SourceRange;
+
+  constructor(value: number, range: SourceRange) {
+    this.value = value;
+    this.range = range;
+  }
+
+  toString(): string {
+    return String(Number(this.value.toPrecision(6)));
+  }
 }
 
-// Literal represents a literal value
-export class Literal implements Expression {
-  constructor(public value: any) {}
+// BooleanLiteral represents a boolean constant
+export class BooleanLiteral implements Expression {
+  readonly kind = 'BooleanLiteral';
+  value: boolean;
+  range: SourceRange;
+
+  constructor(value: boolean, range: SourceRange) {
+    this.value = value;
+    this.range = range;
+  }
 
   toString(): string {
     return String(this.value);
@@ -84,35 +187,73 @@ export class Literal implements Expression {
 
 // Identifier represents a variable reference
 export class Identifier implements Expression {
-  constructor(public name: string) {}
+  readonly kind = 'Identifier';
+  name: string;
+  range: SourceRange;
+
+  constructor(name: string, range: SourceRange) {
+    this.name = name;
+    this.range = range;
+  }
 
   toString(): string {
     return this.name;
   }
 }
 
-// BinaryOp represents binary operations
-export class BinaryOp implements Expression {
-  constructor(
-    public left: Expression,
-    public operator: TokenType,
-    public right: Expression
-  ) {}
+// BinaryOperation represents binary operations like +, -, AND, OR, etc.
+export class BinaryOperation implements Expression {
+  readonly kind = 'BinaryOperation';
+  left: Expression;
+  operator: TokenType;
+  right: Expression;
+  range: SourceRange;
+
+  constructor(left: Expression, operator: TokenType, right: Expression, range: SourceRange) {
+    this.left = left;
+    this.operator = operator;
+    this.right = right;
+    this.range = range;
+  }
+
+  toString(): string {
+    return "(" + this.left.toString() + " " + tokenName(this.operator) + " " + this.right.toString() + ")";
+  }
+}
+
+// UnaryOperation represents unary operations like -, +, NOT
+export class UnaryOperation implements Expression {
+  readonly kind = 'UnaryOperation';
+  operator: TokenType;
+  operand: Expression;
+  range: SourceRange;
+
+  constructor(operator: TokenType, operand: Expression, range: SourceRange) {
+    this.operator = operator;
+    this.operand = operand;
+    this.range = range;
+  }
 
   toString(): string {
-    return "(" + this.left.toString() + " " + this.operator + " " + this.right.toString() + ")";
+    return "(" + tokenName(this.operator) + this.operand.toString() + ")";
   }
 }
 
-// UnaryOp represents unary operations
-export class UnaryOp implements Expression {
-  constructor(
-    public operator: TokenType,
-    public operand: Expression
-  ) {}
+// FunctionCall represents function calls like ABS(x), ITE(cond, then, else)
+export class FunctionCall implements Expression {
+  readonly kind = 'FunctionCall';
+  func: TokenType;
+  args: Expression[];
+  range: SourceRange;
+
+  constructor(func: TokenType, args: Expression[], range: SourceRange) {
+    this.func = func;
+    this.args = args;
+    this.range = range;
+  }
 
   toString(): string {
-    return "(" + this.operator + this.operand.toString() + ")";
+    return tokenName(this.func) + "(" + this.args.map((arg) => arg.toString()).join(", ") + ")";
   }
 }
 
@@ -121,259 +262,584 @@ export class UnaryOp implements Expression {
 
 // generateLexer creates lexer implementation
 func (g *TypeScriptGenerator) generateLexer(buf *strings.Builder, grammar *Grammar) {
-	buf.WriteString(`// Lexer tokenizes input text
+	buf.WriteString(`// TokenRule matches one token type at the current input position
+interface TokenRule {
+  type: TokenType;
+  literal?: string;
+  pattern?: RegExp;
+  skip?: boolean;
+}
+
+const tokenRules: TokenRule[] = [
+`)
+	for _, token := range lexerTokens(grammar) {
+		name := "TokenType." + strings.ToUpper(token.Name)
+		var match string
+		if token.Literal != "" {
+			match = "literal: " + tsString(token.Literal)
+		} else {
+			match = fmt.Sprintf("pattern: new RegExp(%s, 'y')", tsString("(?:"+token.Pattern+")"))
+		}
+		if token.Skip {
+			match += ", skip: true"
+		}
+		buf.WriteString(fmt.Sprintf("  { type: %s, %s },\n", name, match))
+	}
+	for _, literal := range implicitLiterals(grammar) {
+		buf.WriteString(fmt.Sprintf("  { type: TokenType.LITERAL, literal: %s },\n", tsString(literal)))
+	}
+
+	buf.WriteString(`];
+
+// matchRule returns the length of the rule's match at position, or -1
+function matchRule(rule: TokenRule, input: string, position: number): number {
+  if (rule.pattern === undefined) {
+    return input.startsWith(rule.literal as string, position) ? (rule.literal as string).length : -1;
+  }
+  rule.pattern.lastIndex = position;
+  const match = rule.pattern.exec(input);
+  return match === null ? -1 : match[0].length;
+}
+
+// Lexer tokenizes input text
 export class Lexer {
+  private input: string;
   private position = 0;
-  private current = '';
+  private line = 1;
+  private column = 1;
 
-  constructor(private input: string) {
-    this.readChar();
+  constructor(input: string) {
+    this.input = input;
   }
 
-  private readChar(): void {
-    if (this.position >= this.input.length) {
-      this.current = '\\0'; // EOF
-    } else {
-      this.current = this.input[this.position];
-    }
-    this.position++;
+  private currentPos(): Position {
+    return { line: this.line, column: this.column, offset: this.position };
   }
 
-  private peekChar(): string {
-    if (this.position >= this.input.length) {
-      return '\\0';
+  // advance consumes n code units, counting columns in characters
+  private advance(n: number): void {
+    for (const ch of this.input.slice(this.position, this.position + n)) {
+      if (ch === '\n') {
+        this.line++;
+        this.column = 1;
+      } else {
+        this.column++;
+      }
     }
-    return this.input[this.position];
+    this.position += n;
   }
 
-  private skipWhitespace(): void {
-    while (/\\s/.test(this.current)) {
-      this.readChar();
+  // nextToken returns the longest match among the token rules. On equal
+  // length a literal beats a pattern, then the earlier declaration wins.
+  nextToken(): Token {
+    for (;;) {
+      const start = this.currentPos();
+      if (this.position >= this.input.length) {
+        return new Token(TokenType.EOF, '', { start, end: start, text: '' });
+      }
+
+      let best = -1;
+      let length = 0;
+      tokenRules.forEach((rule, i) => {
+        const n = matchRule(rule, this.input, this.position);
+        if (n > length || (n > 0 && n === length && rule.pattern === undefined && tokenRules[best].pattern !== undefined)) {
+          best = i;
+          length = n;
+        }
+      });
+
+      if (best < 0) {
+        const ch = String.fromCodePoint(this.input.codePointAt(this.position) as number);
+        this.advance(ch.length);
+        throw new ParseError("Unexpected character '" + ch + "'", { start, end: this.currentPos(), text: ch },
+          this.input, 'lexical', 'Remove or replace this character');
+      }
+
+      const text = this.input.slice(this.position, this.position + length);
+      this.advance(length);
+      if (tokenRules[best].skip) {
+        continue;
+      }
+      return new Token(tokenRules[best].type, text, { start, end: this.currentPos(), text });
     }
   }
+}
 
-  // Get next token
-  nextToken(): Token {
-    this.skipWhitespace();
+`)
+}
 
-    const startPos = this.position - 1;
+// generateParser creates parser implementation
+func (g *TypeScriptGenerator) generateParser(buf *strings.Builder, grammar *Grammar) error {
+	buf.WriteString(`// ===== PARSER =====
 
-    switch (this.current) {
-`)
+type Item = Token | Expression;
 
-	// Generate token matching
-	for _, token := range grammar.Tokens {
-		if token.Skip {
-			continue // Skip whitespace tokens
-		}
+// Matcher consumes tokens for one grammar expression and appends what it
+// matched to items. A matcher that fails consumes nothing.
+type Matcher = (items: Item[]) => boolean;
 
-		if token.Literal != "" {
-			g.generateTSLiteralToken(buf, token)
-		} else if token.Pattern != "" {
-			g.generateTSPatternToken(buf, token)
-		}
+// Builder constructs a rule's AST node from the items the rule matched
+type Builder = (items: Item[], range: SourceRange) => Expression;
+
+interface MemoEntry {
+  items: Item[] | null;
+  end: number;
+}
+
+const enum RuleId {
+`)
+	for _, rule := range grammar.Rules {
+		buf.WriteString(fmt.Sprintf("  %s,\n", ruleIdent(rule.Name)))
 	}
+	buf.WriteString(`}
 
-	buf.WriteString(`      case '\\0':
-        return { type: TokenType.EOF, value: '', pos: startPos };
-      default:
-        if (/[a-zA-Z]/.test(this.current)) {
-          return this.readIdentifier(startPos);
-        }
-        if (/\\d/.test(this.current)) {
-          return this.readNumber(startPos);
+// Parser is a packrat parser: every rule result is memoized by token
+// position, so backtracking never re-parses a rule at the same place.
+export class Parser {
+  private sourceText: string;
+  private tokens: Token[] = [];
+  private lexError: ParseError | null = null;
+  private pos = 0;
+  private memo = new Map<string, MemoEntry>();
+  private farthest = 0;
+  private expected = new Set<string>();
+  private quiet = 0;
+  private buildError: Error | null = null;
+
+  constructor(input: string) {
+    this.sourceText = input;
+    const lexer = new Lexer(input);
+    for (;;) {
+      try {
+        const token = lexer.nextToken();
+        this.tokens.push(token);
+        if (token.type === TokenType.EOF) {
+          break;
         }
-        
-        const char = this.current;
-        this.readChar();
-        return { type: TokenType.INVALID, value: char, pos: startPos };
+      } catch (error) {
+        this.lexError = error as ParseError;
+        break;
+      }
     }
   }
 
-  private readIdentifier(startPos: number): Token {
-    const start = this.position - 1;
-    while (/[a-zA-Z0-9_]/.test(this.current)) {
-      this.readChar();
+  // Parse the input and return the AST
+  parse(): Expression {
+    if (this.lexError !== null) {
+      throw this.lexError;
+    }
+
+`)
+	buf.WriteString(fmt.Sprintf("    let items = this.parse%s();\n", ruleIdent(grammar.StartRule)))
+	buf.WriteString(`    if (this.buildError !== null) {
+      throw this.buildError;
+    }
+    if (items !== null && this.tokens[this.pos].type !== TokenType.EOF) {
+      this.fail('end of input');
+      items = null;
+    }
+    if (items === null) {
+      throw this.syntaxError();
+    }
+    try {
+      return toExpression(items, this.span(0, this.pos));
+    } catch (error) {
+      if (error instanceof ParseError) {
+        error.sourceText = this.sourceText;
+      }
+      throw error;
     }
-    const value = this.input.substring(start, this.position - 1);
-    return { type: TokenType.IDENTIFIER, value, pos: startPos };
   }
 
-  private readNumber(startPos: number): Token {
-    const start = this.position - 1;
-    while (/\\d/.test(this.current)) {
-      this.readChar();
+  // memoize runs a rule body once per position and builds its AST node
+  private memoize(rule: RuleId, build: Builder | null, body: Matcher): Item[] | null {
+    const key = rule + ':' + this.pos;
+    const entry = this.memo.get(key);
+    if (entry !== undefined) {
+      if (entry.items !== null) {
+        this.pos = entry.end;
+      }
+      return entry.items;
     }
-    if (this.current === '.' && /\\d/.test(this.peekChar())) {
-      this.readChar(); // consume '.'
-      while (/\\d/.test(this.current)) {
-        this.readChar();
+
+    const start = this.pos;
+    let items: Item[] | null = [];
+    if (!body(items)) {
+      items = null;
+    } else if (build !== null) {
+      try {
+        items = [build(items, this.span(start, this.pos))];
+      } catch (error) {
+        if (error instanceof ParseError && error.sourceText === '') {
+          error.sourceText = this.sourceText;
+        }
+        if (this.buildError === null) {
+          this.buildError = error as Error;
+        }
+        items = null;
       }
+    } else {
+      items = collapse(items);
+    }
+    if (items === null) {
+      this.pos = start;
     }
-    const value = this.input.substring(start, this.position - 1);
-    return { type: TokenType.NUMBER, value, pos: startPos };
+
+    this.memo.set(key, { items, end: this.pos });
+    return items;
   }
-}
 
-`)
-}
+  // seq matches each part in order
+  private seq(...parts: Matcher[]): Matcher {
+    return (items) => {
+      const pos = this.pos;
+      const n = items.length;
+      for (const part of parts) {
+        if (!part(items)) {
+          this.pos = pos;
+          items.length = n;
+          return false;
+        }
+      }
+      return true;
+    };
+  }
 
-// generateTSLiteralToken generates TypeScript code for literal tokens
-func (g *TypeScriptGenerator) generateTSLiteralToken(buf *strings.Builder, token *TokenDef) {
-	if len(token.Literal) == 1 {
-		buf.WriteString(fmt.Sprintf("      case '%s':\n", token.Literal))
-		buf.WriteString("        this.readChar();\n")
-		buf.WriteString(fmt.Sprintf("        return { type: TokenType.%s, value: '%s', pos: startPos };\n",
-			strings.ToUpper(token.Name), token.Literal))
-	} else {
-		// Multi-character literal - would need more complex matching
-		buf.WriteString(fmt.Sprintf("      // TODO: Handle multi-char literal '%s'\n", token.Literal))
-	}
-}
+  // choice is PEG ordered choice: the first alternative that matches wins
+  private choice(...alternatives: Matcher[]): Matcher {
+    return (items) => alternatives.some((alternative) => alternative(items));
+  }
 
-// generateTSPatternToken generates TypeScript code for pattern tokens
-func (g *TypeScriptGenerator) generateTSPatternToken(buf *strings.Builder, token *TokenDef) {
-	// For now, just add a comment - full regex support would be complex
-	buf.WriteString(fmt.Sprintf("      // TODO: Handle pattern token %s: %s\n",
-		token.Name, token.Pattern))
-}
+  // rep matches m greedily between min and max times; max < 0 is unbounded
+  private rep(min: number, max: number, m: Matcher): Matcher {
+    return (items) => {
+      const pos = this.pos;
+      const n = items.length;
+      let count = 0;
+      while (max < 0 || count < max) {
+        const before = this.pos;
+        if (!m(items)) {
+          break;
+        }
+        count++;
+        if (this.pos === before) {
+          break; // An empty match would repeat forever
+        }
+      }
+      if (count < min) {
+        this.pos = pos;
+        items.length = n;
+        return false;
+      }
+      return true;
+    };
+  }
 
-// generateParser creates parser implementation
-func (g *TypeScriptGenerator) generateParser(buf *strings.Builder, grammar *Grammar) {
-	buf.WriteString(`// Parser parses tokens into AST
-export class Parser {
-  private currentToken!: Token;
-  private peekToken!: Token;
+  // opt matches m if possible
+  private opt(m: Matcher): Matcher {
+    return (items) => {
+      m(items);
+      return true;
+    };
+  }
+
+  // and succeeds when m matches, without consuming input
+  private and(m: Matcher): Matcher {
+    return (items) => this.lookahead(m, items);
+  }
+
+  // not succeeds when m does not match, without consuming input
+  private not(m: Matcher): Matcher {
+    return (items) => !this.lookahead(m, items);
+  }
+
+  private lookahead(m: Matcher, items: Item[]): boolean {
+    const pos = this.pos;
+    const n = items.length;
+    this.quiet++;
+    const ok = m(items);
+    this.quiet--;
+    this.pos = pos;
+    items.length = n;
+    return ok;
+  }
+
+  // token matches one token of the given type
+  private token(type: TokenType): Matcher {
+    return (items) => {
+      const token = this.tokens[this.pos];
+      if (token.type !== type) {
+        this.fail(tokenLabels[type]);
+        return false;
+      }
+      items.push(token);
+      this.pos++;
+      return true;
+    };
+  }
 
-  constructor(private lexer: Lexer) {
-    this.nextToken();
-    this.nextToken();
+  // literal matches one token with the given text, whatever its type
+  private literal(text: string): Matcher {
+    return (items) => {
+      const token = this.tokens[this.pos];
+      if (token.type === TokenType.EOF || token.value !== text) {
+        this.fail("'" + text + "'");
+        return false;
+      }
+      items.push(token);
+      this.pos++;
+      return true;
+    };
   }
 
-  private nextToken(): void {
-    this.currentToken = this.peekToken;
-    this.peekToken = this.lexer.nextToken();
+  // ref matches a rule and appends its result
+  private ref(rule: () => Item[] | null): Matcher {
+    return (items) => {
+      const result = rule();
+      if (result === null) {
+        return false;
+      }
+      items.push(...result);
+      return true;
+    };
   }
 
-  private expectToken(tokenType: TokenType): void {
-    if (this.currentToken.type !== tokenType) {
-      throw new Error("Expected " + tokenType + ", got " + this.currentToken.type);
+  // fail records what was expected at the current position
+  private fail(label: string): void {
+    if (this.quiet > 0 || this.pos < this.farthest) {
+      return;
     }
-    this.nextToken();
+    if (this.pos > this.farthest) {
+      this.farthest = this.pos;
+      this.expected = new Set<string>();
+    }
+    this.expected.add(label);
+  }
+
+  // syntaxError reports the furthest failure with everything expected there
+  private syntaxError(): ParseError {
+    const token = this.tokens[this.farthest];
+    const got = token.type === TokenType.EOF ? 'end of input' : "'" + token.value + "'";
+    const expected = Array.from(this.expected).sort(compareStrings);
+
+    const error = new ParseError('Unexpected ' + got, token.range, this.sourceText, 'syntax');
+    if (expected.length === 1) {
+      error.message = 'Expected ' + expected[0] + ', got ' + got;
+      error.suggestion = 'Add ' + expected[0] + ' here';
+    } else if (expected.length > 1) {
+      const last = expected.length - 1;
+      error.message = 'Expected ' + expected.slice(0, last).join(', ') + ' or ' + expected[last] + ', got ' + got;
+    }
+    return error;
+  }
+
+  // span returns the source range covered by tokens [start, end)
+  private span(start: number, end: number): SourceRange {
+    const from = this.tokens[start].range.start;
+    const to = end > start ? this.tokens[end - 1].range.end : from;
+    return { start: from, end: to, text: this.sourceText.slice(from.offset, to.offset) };
   }
 
-  // Parse the input and return the AST
-  parse(): Expression {
 `)
 
-	// Generate parse method for start rule
-	if grammar.StartRule != "" {
-		buf.WriteString(fmt.Sprintf("    return this.parse%s();\n",
-			capitalizeFirst(grammar.StartRule)))
-	} else {
-		buf.WriteString("    throw new Error('No start rule defined');\n")
+	syntax := matcherSyntax{
+		self:  "this",
+		token: func(name string) string { return "TokenType." + strings.ToUpper(name) },
+		ref:   func(rule string) string { return "() => this.parse" + ruleIdent(rule) + "()" },
+		quote: tsString,
 	}
 
-	buf.WriteString("  }\n\n")
-
-	// Generate parsing methods for each rule
+	var actions strings.Builder
 	for _, rule := range grammar.Rules {
-		g.generateTSParseMethod(buf, rule, grammar)
+		body, err := emitMatcher(rule.Expression, grammar, syntax)
+		if err != nil {
+			return fmt.Errorf("rule '%s': %w", rule.Name, err)
+		}
+
+		name := ruleIdent(rule.Name)
+		build := "null"
+		switch {
+		case rule.Action != "":
+			build = "build" + name + "Action"
+			actions.WriteString(fmt.Sprintf("// build%sAction is the semantic action of rule %s\n", name, rule.Name))
+			actions.WriteString(fmt.Sprintf("function build%sAction(items: Item[], range: SourceRange): Expression {\n", name))
+			actions.WriteString(rule.Action)
+			actions.WriteString("\n}\n\n")
+		case rule.ASTNode != "":
+			build = "build" + rule.ASTNode
+		}
+
+		buf.WriteString(fmt.Sprintf("  // %s -> %s\n", rule.Name, rule.Expression))
+		buf.WriteString(fmt.Sprintf("  private parse%s(): Item[] | null {\n", name))
+		buf.WriteString(fmt.Sprintf("    return this.memoize(RuleId.%s, %s, (items) => %s(items));\n", name, build, body))
+		buf.WriteString("  }\n\n")
 	}
+	buf.WriteString("}\n\n")
+	buf.WriteString(actions.String())
+	return nil
 }
 
-// generateTSParseMethod creates a TypeScript parsing method for a rule
-func (g *TypeScriptGenerator) generateTSParseMethod(buf *strings.Builder, rule *Rule, grammar *Grammar) {
-	methodName := fmt.Sprintf("parse%s", capitalizeFirst(rule.Name))
-
-	buf.WriteString(fmt.Sprintf("  private %s(): Expression {\n", methodName))
-
-	// Generate parsing logic based on the rule expression
-	g.generateTSExpressionParsing(buf, rule.Expression, grammar)
-
-	buf.WriteString("  }\n\n")
-}
-
-// generateTSExpressionParsing generates TypeScript parsing code for expressions
-func (g *TypeScriptGenerator) generateTSExpressionParsing(buf *strings.Builder, expr Expression, grammar *Grammar) {
-	switch e := expr.(type) {
-	case *RuleRef:
-		if grammar.GetTokenByName(e.Name) != nil {
-			// It's a token
-			buf.WriteString(fmt.Sprintf("    if (this.currentToken.type !== TokenType.%s) {\n",
-				strings.ToUpper(e.Name)))
-			buf.WriteString(fmt.Sprintf("      throw new Error('Expected %s');\n", e.Name))
-			buf.WriteString("    }\n")
-			buf.WriteString("    const value = this.currentToken.value;\n")
-			buf.WriteString("    this.nextToken();\n")
-
-			if e.Name == "NUMBER" {
-				buf.WriteString("    const num = parseFloat(value);\n")
-				buf.WriteString("    if (isNaN(num)) {\n")
-				buf.WriteString("      throw new Error('Invalid number');\n")
-				buf.WriteString("    }\n")
-				buf.WriteString("    return new Literal(num);\n")
-			} else if e.Name == "IDENTIFIER" {
-				buf.WriteString("    return new Identifier(value);\n")
-			} else {
-				buf.WriteString("    return new Literal(value);\n")
-			}
-		} else {
-			// It's a rule reference
-			buf.WriteString(fmt.Sprintf("    return this.parse%s();\n", capitalizeFirst(e.Name)))
-		}
+// generateUtilities creates AST construction and the public API
+func (g *TypeScriptGenerator) generateUtilities(buf *strings.Builder, grammar *Grammar) {
+	buf.WriteString(`// ===== AST CONSTRUCTION =====
 
-	case *Choice:
-		buf.WriteString("    // Try each alternative\n")
-		for i, alt := range e.Alternatives {
-			if i > 0 {
-				buf.WriteString("    // Try next alternative\n")
-			}
-			g.generateTSExpressionParsing(buf, alt, grammar)
-		}
+function compareStrings(a: string, b: string): number {
+  return a < b ? -1 : a > b ? 1 : 0;
+}
 
-	case *Sequence:
-		buf.WriteString("    // Parse sequence\n")
-		for _, item := range e.Items {
-			g.generateTSExpressionParsing(buf, item, grammar)
-		}
+// collapse reduces the items of a rule without an AST node to its single
+// expression, dropping delimiter tokens. Other item lists pass through.
+function collapse(items: Item[]): Item[] {
+  const expressions = items.filter((item) => !(item instanceof Token));
+  return expressions.length === 1 ? expressions : items;
+}
 
-	case *Repetition:
-		buf.WriteString("    // Parse repetition\n")
-		if e.Min == 0 && e.Max == -1 {
-			buf.WriteString("    // Zero or more\n")
-		} else if e.Min == 1 && e.Max == -1 {
-			buf.WriteString("    // One or more\n")
-		}
-		g.generateTSExpressionParsing(buf, e.Expression, grammar)
+// toExpression converts the start rule's items into the AST root
+function toExpression(items: Item[], range: SourceRange): Expression {
+  if (items.length !== 1) {
+    throw new ParseError('Input does not form a single expression', range, '', 'syntax',
+      'Give the start rule an AST node');
+  }
+  return operand(items[0]);
+}
 
-	case *Optional:
-		buf.WriteString("    // Parse optional\n")
-		g.generateTSExpressionParsing(buf, e.Expression, grammar)
+// operand converts an item to an expression; bare tokens become literals
+function operand(item: Item): Expression {
+  return item instanceof Token ? literalFromToken(item) : item;
+}
 
-	case *Literal:
-		buf.WriteString(fmt.Sprintf("    // Parse literal '%s'\n", e.Value))
-		buf.WriteString("    return new Literal('" + e.Value + "');\n")
+function literalFromToken(token: Token): Expression {
+  if (/^[0-9.]/.test(token.value)) {
+    const value = Number(token.value);
+    if (!Number.isNaN(value)) {
+      return new NumberLiteral(value, token.range);
+    }
+  }
+  switch (token.value.toLowerCase()) {
+    case 'true':
+      return new BooleanLiteral(true, token.range);
+    case 'false':
+      return new BooleanLiteral(false, token.range);
+  }
+  return new Identifier(token.value, token.range);
+}
 
-	default:
-		buf.WriteString("    // TODO: Implement parsing for this expression type\n")
-		buf.WriteString("    throw new Error('Not implemented');\n")
-	}
+function firstToken(items: Item[], range: SourceRange, node: string): Token {
+  for (const item of items) {
+    if (item instanceof Token) {
+      return item;
+    }
+  }
+  throw new ParseError(node + ' needs a token', range, '', 'semantic');
 }
 
-// generateUtilities creates utility functions
-func (g *TypeScriptGenerator) generateUtilities(buf *strings.Builder, grammar *Grammar) {
-	buf.WriteString(`// Parse expression convenience function
+// mergeRanges spans from start to end, taking the text from the enclosing rule range
+function mergeRanges(start: SourceRange, end: SourceRange, range: SourceRange): SourceRange {
+  const from = start.start.offset - range.start.offset;
+  const to = end.end.offset - range.start.offset;
+  const text = 0 <= from && from <= to && to <= range.text.length ? range.text.slice(from, to) : '';
+  return { start: start.start, end: end.end, text };
+}
+
+function buildNumberLiteral(items: Item[], range: SourceRange): Expression {
+  const token = firstToken(items, range, 'NumberLiteral');
+  const value = Number(token.value);
+  if (token.value.trim() === '' || Number.isNaN(value)) {
+    throw new ParseError("Invalid number '" + token.value + "'", token.range, '', 'lexical',
+      'Use a valid number format like 123 or 123.45');
+  }
+  return new NumberLiteral(value, token.range);
+}
+
+function buildBooleanLiteral(items: Item[], range: SourceRange): Expression {
+  const token = firstToken(items, range, 'BooleanLiteral');
+  return new BooleanLiteral(token.value.toLowerCase() === 'true', token.range);
+}
+
+function buildIdentifier(items: Item[], range: SourceRange): Expression {
+  const token = firstToken(items, range, 'Identifier');
+  return new Identifier(token.value, token.range);
+}
+
+// buildBinaryOperation folds operand (operator operand)* to the left
+function buildBinaryOperation(items: Item[], range: SourceRange): Expression {
+  let left = operand(items[0]);
+  for (let i = 1; i + 1 < items.length; i += 2) {
+    const operator = items[i];
+    if (!(operator instanceof Token)) {
+      throw new ParseError('BinaryOperation expects an operator token between operands', range, '', 'semantic');
+    }
+    const right = operand(items[i + 1]);
+    left = new BinaryOperation(left, operator.type, right, mergeRanges(left.range, right.range, range));
+  }
+  return left;
+}
+
+// buildUnaryOperation applies operator* to the final operand, innermost last
+function buildUnaryOperation(items: Item[], range: SourceRange): Expression {
+  let result = operand(items[items.length - 1]);
+  for (let i = items.length - 2; i >= 0; i--) {
+    const operator = items[i];
+    if (!(operator instanceof Token)) {
+      throw new ParseError('UnaryOperation expects operator tokens before its operand', range, '', 'semantic');
+    }
+    result = new UnaryOperation(operator.type, result, mergeRanges(operator.range, result.range, range));
+  }
+  return result;
+}
+
+// buildFunctionCall uses the first token as the function and every
+// expression item as an argument
+function buildFunctionCall(items: Item[], range: SourceRange): Expression {
+  const func = firstToken(items, range, 'FunctionCall');
+  const args = items.filter((item): item is Expression => !(item instanceof Token));
+  return new FunctionCall(func.type, args, range);
+}
+
+// ===== PUBLIC API =====
+
+// Parse expression convenience function
 export function parseExpression(input: string): Expression {
-  const lexer = new Lexer(input);
-  const parser = new Parser(lexer);
+  const parser = new Parser(input);
   return parser.parse();
 }
 
 // Format parsing errors nicely
 export function formatError(error: Error): string {
-  return "Parse error: " + error.message;
+  if (!(error instanceof ParseError)) {
+    return "Parse error: " + error.message;
+  }
+  const lines = error.sourceText.split('\n');
+  const start = error.range.start;
+  let result = 'Error: ' + error.message + '\n';
+  if (start.line > 0 && start.line <= lines.length) {
+    const width = Math.max(1, error.range.end.column - start.column);
+    result += '  | ' + lines[start.line - 1] + '\n';
+    result += '  | ' + ' '.repeat(start.column - 1) + '^'.repeat(width) + '\n';
+  }
+  if (error.suggestion !== '') {
+    result += '\nSuggestion: ' + error.suggestion + '\n';
+  }
+  return result;
 }
 
+// Collect the variable names an expression references
+export function collectVariables(expr: Expression): string[] {
+  const names = new Set<string>();
+  const visit = (node: Expression): void => {
+    if (node instanceof Identifier) {
+      names.add(node.name);
+    } else if (node instanceof BinaryOperation) {
+      visit(node.left);
+      visit(node.right);
+    } else if (node instanceof UnaryOperation) {
+      visit(node.operand);
+    } else if (node instanceof FunctionCall) {
+      node.args.forEach(visit);
+    }
+  };
+  visit(expr);
+  return Array.from(names).sort(compareStrings);
+}
 `)
 }
@@ -5,6 +5,7 @@ package repository
 
 import (
 	"DD/cpq"
+	"DD/tax"
 	"time"
 )
 
@@ -33,6 +34,51 @@ type ModelRepository interface {
 	AddPricingRule(modelID string, rule *cpq.PriceRule) error
 	UpdatePricingRule(modelID, ruleID string, rule *cpq.PriceRule) error
 	DeletePricingRule(modelID, ruleID string) error
+	
+	AddDefinition(modelID string, definition *cpq.Definition) error
+	UpdateDefinition(modelID, definitionID string, definition *cpq.Definition) error
+	DeleteDefinition(modelID, definitionID string) error
+
+	SetVolumeTiers(modelID string, tiers []cpq.VolumeTier) error
+	SetPriceBooks(modelID string, books []cpq.PriceBook) error
+}
+
+// ExchangeRateRepository defines the interface for the local exchange-rate table
+type ExchangeRateRepository interface {
+	ListExchangeRates() (cpq.ExchangeRates, error)
+	SetExchangeRate(rate cpq.ExchangeRate) error
+}
+
+// TaxRateRepository defines the interface for the local jurisdiction
+// tax-rate table
+type TaxRateRepository interface {
+	ListTaxRates() ([]tax.Rate, error)
+	ReplaceTaxRates(rates []tax.Rate) error
+}
+
+// CustomerRepository defines the interface for customer accounts and their
+// pricing contracts
+type CustomerRepository interface {
+	CreateCustomer(customer *cpq.Customer) error
+	GetCustomer(id string) (*cpq.Customer, error)
+	ListCustomers() ([]*cpq.Customer, error)
+	UpdateCustomer(customer *cpq.Customer) error
+	DeleteCustomer(id string) error
+
+	CreateContract(contract *cpq.Contract) error
+	GetContract(id string) (*cpq.Contract, error)
+	ListContracts(customerID string) ([]*cpq.Contract, error)
+	UpdateContract(contract *cpq.Contract) error
+	DeleteContract(id string) error
+}
+
+// QuoteRepository defines the interface for multi-line quotes
+type QuoteRepository interface {
+	CreateQuote(quote *cpq.Quote) error
+	GetQuote(id string) (*cpq.Quote, error)
+	ListQuotes(customerID string) ([]*cpq.Quote, error) // Empty lists every quote
+	UpdateQuote(quote *cpq.Quote) error
+	DeleteQuote(id string) error
 }
 
 // ConfigurationRepository defines the interface for configuration data access
@@ -32,12 +32,16 @@ func (r *PostgresConfigRepository) GetConfiguration(id string, userID *string) (
 
 // UpdateConfiguration updates configuration metadata
 func (r *PostgresConfigRepository) UpdateConfiguration(id string, config *cpq.Configuration) error {
-	_, err := r.db.Exec(`
+	children, err := database.ChildConfigurationsJSON(config.Children)
+	if err != nil {
+		return fmt.Errorf("failed to encode sub-assemblies: %w", err)
+	}
+	_, err = r.db.Exec(`
 		UPDATE configurations 
 		SET name = $2, description = $3, is_valid = $4, total_price = $5, 
-		    status = $6, updated_at = NOW()
+		    status = $6, children = $7, updated_at = NOW()
 		WHERE id = $1
-	`, id, config.Name, config.Description, config.IsValid, config.TotalPrice, config.Status)
+	`, id, config.Name, config.Description, config.IsValid, config.TotalPrice, config.Status, children)
 	return err
 }
 
@@ -174,13 +178,18 @@ func (r *PostgresConfigRepository) SaveConfiguration(config *cpq.Configuration)
 	}
 	defer tx.Rollback()
 
+	children, err := database.ChildConfigurationsJSON(config.Children)
+	if err != nil {
+		return fmt.Errorf("failed to encode sub-assemblies: %w", err)
+	}
+
 	// Update configuration
 	_, err = tx.Exec(`
 		UPDATE configurations 
 		SET name = $2, description = $3, is_valid = $4, total_price = $5, 
-		    status = $6, updated_at = NOW()
+		    status = $6, children = $7, updated_at = NOW()
 		WHERE id = $1
-	`, config.ID, config.Name, config.Description, config.IsValid, config.TotalPrice, config.Status)
+	`, config.ID, config.Name, config.Description, config.IsValid, config.TotalPrice, config.Status, children)
 	if err != nil {
 		return fmt.Errorf("failed to update configuration: %w", err)
 	}
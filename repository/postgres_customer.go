@@ -0,0 +1,307 @@
+// repository/postgres_customer.go
+// PostgreSQL implementation of CustomerRepository
+
+package repository
+
+import (
+	"DD/cpq"
+	"DD/database"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PostgresCustomerRepository implements CustomerRepository using PostgreSQL
+type PostgresCustomerRepository struct {
+	db *database.DB
+}
+
+// NewPostgresCustomerRepository creates a new PostgreSQL customer repository
+func NewPostgresCustomerRepository(db *database.DB) *PostgresCustomerRepository {
+	return &PostgresCustomerRepository{db: db}
+}
+
+// CreateCustomer creates a customer account
+func (r *PostgresCustomerRepository) CreateCustomer(customer *cpq.Customer) error {
+	exemptions, err := database.TaxExemptionsJSON(customer)
+	if err != nil {
+		return fmt.Errorf("failed to encode tax exemptions: %w", err)
+	}
+	return r.db.QueryRow(`
+		INSERT INTO customers (id, name, email, is_active, tax_exemptions)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at, updated_at
+	`, customer.ID, customer.Name, nullableString(customer.Email), customer.IsActive, exemptions).
+		Scan(&customer.CreatedAt, &customer.UpdatedAt)
+}
+
+// GetCustomer retrieves a customer account
+func (r *PostgresCustomerRepository) GetCustomer(id string) (*cpq.Customer, error) {
+	customer := &cpq.Customer{}
+	var email sql.NullString
+	var exemptions []byte
+
+	err := r.db.QueryRow(`
+		SELECT id, name, email, is_active, tax_exemptions, created_at, updated_at
+		FROM customers WHERE id = $1
+	`, id).Scan(&customer.ID, &customer.Name, &email, &customer.IsActive, &exemptions, &customer.CreatedAt, &customer.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("customer not found: %s", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	customer.Email = email.String
+	if err := json.Unmarshal(exemptions, &customer.TaxExemptions); err != nil {
+		return nil, fmt.Errorf("failed to decode tax exemptions of %s: %w", customer.ID, err)
+	}
+	return customer, nil
+}
+
+// ListCustomers returns all customer accounts
+func (r *PostgresCustomerRepository) ListCustomers() ([]*cpq.Customer, error) {
+	rows, err := r.db.Query(`
+		SELECT id, name, email, is_active, tax_exemptions, created_at, updated_at
+		FROM customers ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	customers := []*cpq.Customer{}
+	for rows.Next() {
+		customer := &cpq.Customer{}
+		var email sql.NullString
+		var exemptions []byte
+		if err := rows.Scan(&customer.ID, &customer.Name, &email, &customer.IsActive, &exemptions, &customer.CreatedAt, &customer.UpdatedAt); err != nil {
+			return nil, err
+		}
+		customer.Email = email.String
+		if err := json.Unmarshal(exemptions, &customer.TaxExemptions); err != nil {
+			return nil, fmt.Errorf("failed to decode tax exemptions of %s: %w", customer.ID, err)
+		}
+		customers = append(customers, customer)
+	}
+
+	return customers, rows.Err()
+}
+
+// UpdateCustomer updates a customer account
+func (r *PostgresCustomerRepository) UpdateCustomer(customer *cpq.Customer) error {
+	exemptions, err := database.TaxExemptionsJSON(customer)
+	if err != nil {
+		return fmt.Errorf("failed to encode tax exemptions: %w", err)
+	}
+	result, err := r.db.Exec(`
+		UPDATE customers SET name = $2, email = $3, is_active = $4, tax_exemptions = $5
+		WHERE id = $1
+	`, customer.ID, customer.Name, nullableString(customer.Email), customer.IsActive, exemptions)
+	if err != nil {
+		return err
+	}
+	return expectRow(result, "customer", customer.ID)
+}
+
+// DeleteCustomer deletes a customer account and its contracts
+func (r *PostgresCustomerRepository) DeleteCustomer(id string) error {
+	result, err := r.db.Exec(`DELETE FROM customers WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return expectRow(result, "customer", id)
+}
+
+// CreateContract creates a contract with its negotiated prices
+func (r *PostgresCustomerRepository) CreateContract(contract *cpq.Contract) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO contracts (id, customer_id, name, model_id, currency, discount_percent,
+			max_discount_percent, min_total, effective_from, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING created_at, updated_at
+	`, contract.ID, contract.CustomerID, contract.Name, nullableString(contract.ModelID),
+		nullableString(strings.ToUpper(contract.Currency)), contract.DiscountPercent,
+		contract.MaxDiscountPercent, contract.MinTotal, contract.EffectiveFrom, contract.ExpiresAt).
+		Scan(&contract.CreatedAt, &contract.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert contract: %w", err)
+	}
+
+	if err := insertContractPrices(tx, contract); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetContract retrieves a contract with its negotiated prices
+func (r *PostgresCustomerRepository) GetContract(id string) (*cpq.Contract, error) {
+	contract, err := scanContract(r.db.QueryRow(contractSelect+` WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("contract not found: %s", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadContractPrices([]*cpq.Contract{contract}); err != nil {
+		return nil, err
+	}
+	return contract, nil
+}
+
+// ListContracts returns a customer's contracts with their negotiated prices
+func (r *PostgresCustomerRepository) ListContracts(customerID string) ([]*cpq.Contract, error) {
+	rows, err := r.db.Query(contractSelect+` WHERE customer_id = $1 ORDER BY effective_from`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contracts := []*cpq.Contract{}
+	for rows.Next() {
+		contract, err := scanContract(rows)
+		if err != nil {
+			return nil, err
+		}
+		contracts = append(contracts, contract)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadContractPrices(contracts); err != nil {
+		return nil, err
+	}
+	return contracts, nil
+}
+
+// UpdateContract updates a contract and replaces its negotiated prices
+func (r *PostgresCustomerRepository) UpdateContract(contract *cpq.Contract) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE contracts SET customer_id = $2, name = $3, model_id = $4, currency = $5,
+			discount_percent = $6, max_discount_percent = $7, min_total = $8,
+			effective_from = $9, expires_at = $10
+		WHERE id = $1
+	`, contract.ID, contract.CustomerID, contract.Name, nullableString(contract.ModelID),
+		nullableString(strings.ToUpper(contract.Currency)), contract.DiscountPercent,
+		contract.MaxDiscountPercent, contract.MinTotal, contract.EffectiveFrom, contract.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to update contract: %w", err)
+	}
+	if err := expectRow(result, "contract", contract.ID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM contract_prices WHERE contract_id = $1`, contract.ID); err != nil {
+		return fmt.Errorf("failed to delete existing contract prices: %w", err)
+	}
+	if err := insertContractPrices(tx, contract); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteContract deletes a contract
+func (r *PostgresCustomerRepository) DeleteContract(id string) error {
+	result, err := r.db.Exec(`DELETE FROM contracts WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return expectRow(result, "contract", id)
+}
+
+// contractSelect selects the columns read by scanContract
+const contractSelect = `
+	SELECT id, customer_id, name, model_id, currency, discount_percent,
+		max_discount_percent, min_total, effective_from, expires_at, created_at, updated_at
+	FROM contracts`
+
+// scanContract scans a contract row without its prices
+func scanContract(row interface{ Scan(...interface{}) error }) (*cpq.Contract, error) {
+	contract := &cpq.Contract{}
+	var modelID, currency sql.NullString
+	var expiresAt sql.NullTime
+
+	err := row.Scan(
+		&contract.ID, &contract.CustomerID, &contract.Name, &modelID, &currency,
+		&contract.DiscountPercent, &contract.MaxDiscountPercent, &contract.MinTotal,
+		&contract.EffectiveFrom, &expiresAt, &contract.CreatedAt, &contract.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	contract.ModelID = modelID.String
+	contract.Currency = strings.TrimSpace(currency.String)
+	if expiresAt.Valid {
+		expires := expiresAt.Time
+		contract.ExpiresAt = &expires
+	}
+	return contract, nil
+}
+
+// loadContractPrices loads the negotiated prices of contracts
+func (r *PostgresCustomerRepository) loadContractPrices(contracts []*cpq.Contract) error {
+	for _, contract := range contracts {
+		rows, err := r.db.Query(`
+			SELECT option_id, price FROM contract_prices
+			WHERE contract_id = $1 ORDER BY option_id
+		`, contract.ID)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var price cpq.ContractPrice
+			if err := rows.Scan(&price.OptionID, &price.Price); err != nil {
+				rows.Close()
+				return err
+			}
+			contract.Prices = append(contract.Prices, price)
+		}
+		rows.Close()
+	}
+	return nil
+}
+
+// insertContractPrices inserts a contract's negotiated prices within a transaction
+func insertContractPrices(tx *sql.Tx, contract *cpq.Contract) error {
+	for _, price := range contract.Prices {
+		_, err := tx.Exec(`
+			INSERT INTO contract_prices (contract_id, option_id, price)
+			VALUES ($1, $2, $3)
+		`, contract.ID, price.OptionID, price.Price)
+		if err != nil {
+			return fmt.Errorf("failed to insert contract price for %s: %w", price.OptionID, err)
+		}
+	}
+	return nil
+}
+
+// expectRow reports a not-found error when a statement touched no rows
+func expectRow(result sql.Result, kind, id string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s not found: %s", kind, id)
+	}
+	return nil
+}
@@ -6,6 +6,8 @@ package repository
 import (
 	"DD/cpq"
 	"DD/database"
+	"DD/parser/format"
+	"database/sql"
 	"fmt"
 )
 
@@ -42,12 +44,26 @@ func (r *PostgresModelRepository) UpdateModel(id string, model *cpq.Model) error
 	}
 	defer tx.Rollback()
 
+	marginFloors, err := database.MarginFloorsJSON(model)
+	if err != nil {
+		return fmt.Errorf("failed to encode margin floors: %w", err)
+	}
+	approvalPolicies, err := database.ApprovalPoliciesJSON(model)
+	if err != nil {
+		return fmt.Errorf("failed to encode approval policies: %w", err)
+	}
+	assemblyRules, err := database.AssemblyRulesJSON(model)
+	if err != nil {
+		return fmt.Errorf("failed to encode assembly rules: %w", err)
+	}
+
 	// Update model basic info
 	_, err = tx.Exec(`
 		UPDATE models 
-		SET name = $2, description = $3, version = $4, updated_at = NOW()
+		SET name = $2, description = $3, version = $4, currency = $5, term_months = $6, margin_floors = $7,
+		    approval_policies = $8, assembly_rules = $9, updated_at = NOW()
 		WHERE id = $1
-	`, id, model.Name, model.Description, model.Version)
+	`, id, model.Name, model.Description, model.Version, model.BaseCurrency(), model.TermMonths, marginFloors, approvalPolicies, assemblyRules)
 	if err != nil {
 		return fmt.Errorf("failed to update model: %w", err)
 	}
@@ -74,6 +90,22 @@ func (r *PostgresModelRepository) UpdateModel(id string, model *cpq.Model) error
 		return fmt.Errorf("failed to delete existing pricing rules: %w", err)
 	}
 
+	_, err = tx.Exec(`DELETE FROM definitions WHERE model_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete existing definitions: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM volume_tiers WHERE model_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete existing volume tiers: %w", err)
+	}
+
+	// Entries cascade with their price books
+	_, err = tx.Exec(`DELETE FROM price_books WHERE model_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete existing price books: %w", err)
+	}
+
 	// Insert groups
 	for _, group := range model.Groups {
 		_, err = tx.Exec(`
@@ -88,18 +120,23 @@ func (r *PostgresModelRepository) UpdateModel(id string, model *cpq.Model) error
 
 	// Insert options
 	for _, option := range model.Options {
+		components, err := database.PriceComponentsJSON(option)
+		if err != nil {
+			return fmt.Errorf("failed to encode price components of %s: %w", option.ID, err)
+		}
 		_, err = tx.Exec(`
-			INSERT INTO options (id, model_id, group_id, name, description, base_price, sku, display_order, is_active)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			INSERT INTO options (id, model_id, group_id, name, description, base_price, cost, tax_category, sku, display_order, is_active, price_components, child_model_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		`, option.ID, id, option.GroupID, option.Name, option.Description,
-			option.BasePrice, option.SKU, option.DisplayOrder, option.IsActive)
+			option.BasePrice, option.Cost, option.TaxCategory, option.SKU, option.DisplayOrder, option.IsActive, components, nullableString(option.ChildModelID))
 		if err != nil {
 			return fmt.Errorf("failed to insert option %s: %w", option.ID, err)
 		}
 	}
 
-	// Insert rules
+	// Insert rules in canonical form
 	for _, rule := range model.Rules {
+		rule.Expression = format.Normalize(rule.Expression)
 		_, err = tx.Exec(`
 			INSERT INTO rules (id, model_id, name, description, type, expression, message, priority, is_active)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
@@ -122,6 +159,30 @@ func (r *PostgresModelRepository) UpdateModel(id string, model *cpq.Model) error
 		}
 	}
 
+	// Insert definitions in canonical form
+	for _, definition := range model.Definitions {
+		definition.Expression = format.Normalize(definition.Expression)
+		_, err = tx.Exec(`
+			INSERT INTO definitions (id, model_id, name, description, expression)
+			VALUES ($1, $2, $3, $4, $5)
+		`, definition.ID, id, definition.Name, nullableString(definition.Description), definition.Expression)
+		if err != nil {
+			return fmt.Errorf("failed to insert definition %s: %w", definition.ID, err)
+		}
+	}
+
+	// Insert volume tiers
+	if err := insertVolumeTiers(tx, id, model.VolumeTiers); err != nil {
+		return err
+	}
+
+	// Insert price books with their entries
+	for _, book := range model.PriceBooks {
+		if err := r.db.InsertPriceBook(tx, id, book); err != nil {
+			return fmt.Errorf("failed to insert price book %s: %w", book.ID, err)
+		}
+	}
+
 	return tx.Commit()
 }
 
@@ -137,23 +198,31 @@ func (r *PostgresModelRepository) DeleteModel(id string) error {
 
 // AddOption adds a new option to a model
 func (r *PostgresModelRepository) AddOption(modelID string, option *cpq.Option) error {
-	_, err := r.db.Exec(`
-		INSERT INTO options (id, model_id, group_id, name, description, base_price, sku, display_order, is_active)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	components, err := database.PriceComponentsJSON(*option)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO options (id, model_id, group_id, name, description, base_price, cost, tax_category, sku, display_order, is_active, price_components, child_model_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`, option.ID, modelID, option.GroupID, option.Name, option.Description,
-		option.BasePrice, option.SKU, option.DisplayOrder, option.IsActive)
+		option.BasePrice, option.Cost, option.TaxCategory, option.SKU, option.DisplayOrder, option.IsActive, components, nullableString(option.ChildModelID))
 	return err
 }
 
 // UpdateOption updates an existing option
 func (r *PostgresModelRepository) UpdateOption(modelID, optionID string, option *cpq.Option) error {
-	_, err := r.db.Exec(`
+	components, err := database.PriceComponentsJSON(*option)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
 		UPDATE options 
 		SET name = $3, description = $4, group_id = $5, base_price = $6, sku = $7, 
-		    display_order = $8, is_active = $9, updated_at = NOW()
+		    display_order = $8, is_active = $9, price_components = $10, cost = $11, tax_category = $12, child_model_id = $13, updated_at = NOW()
 		WHERE id = $1 AND model_id = $2
 	`, optionID, modelID, option.Name, option.Description, option.GroupID,
-		option.BasePrice, option.SKU, option.DisplayOrder, option.IsActive)
+		option.BasePrice, option.SKU, option.DisplayOrder, option.IsActive, components, option.Cost, option.TaxCategory, nullableString(option.ChildModelID))
 	return err
 }
 
@@ -203,23 +272,25 @@ func (r *PostgresModelRepository) DeleteGroup(modelID, groupID string) error {
 
 // AddRule adds a new rule to a model
 func (r *PostgresModelRepository) AddRule(modelID string, rule *cpq.Rule) error {
+	expression := format.Normalize(rule.Expression)
 	_, err := r.db.Exec(`
 		INSERT INTO rules (id, model_id, name, description, type, expression, message, priority, is_active)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`, rule.ID, modelID, rule.Name, rule.Description, rule.Type,
-		rule.Expression, rule.Message, rule.Priority, rule.IsActive)
+		expression, rule.Message, rule.Priority, rule.IsActive)
 	return err
 }
 
 // UpdateRule updates an existing rule
 func (r *PostgresModelRepository) UpdateRule(modelID, ruleID string, rule *cpq.Rule) error {
+	expression := format.Normalize(rule.Expression)
 	_, err := r.db.Exec(`
 		UPDATE rules 
 		SET name = $3, description = $4, type = $5, expression = $6, 
 		    message = $7, priority = $8, is_active = $9, updated_at = NOW()
 		WHERE id = $1 AND model_id = $2
 	`, ruleID, modelID, rule.Name, rule.Description, rule.Type,
-		rule.Expression, rule.Message, rule.Priority, rule.IsActive)
+		expression, rule.Message, rule.Priority, rule.IsActive)
 	return err
 }
 
@@ -265,6 +336,112 @@ func (r *PostgresModelRepository) DeletePricingRule(modelID, ruleID string) erro
 	return err
 }
 
+// Definition operations
+
+// AddDefinition adds a new named definition to a model
+func (r *PostgresModelRepository) AddDefinition(modelID string, definition *cpq.Definition) error {
+	expression := format.Normalize(definition.Expression)
+	_, err := r.db.Exec(`
+		INSERT INTO definitions (id, model_id, name, description, expression)
+		VALUES ($1, $2, $3, $4, $5)
+	`, definition.ID, modelID, definition.Name, nullableString(definition.Description), expression)
+	return err
+}
+
+// UpdateDefinition updates an existing definition
+func (r *PostgresModelRepository) UpdateDefinition(modelID, definitionID string, definition *cpq.Definition) error {
+	expression := format.Normalize(definition.Expression)
+	_, err := r.db.Exec(`
+		UPDATE definitions 
+		SET name = $3, description = $4, expression = $5, updated_at = NOW()
+		WHERE id = $1 AND model_id = $2
+	`, definitionID, modelID, definition.Name, nullableString(definition.Description), expression)
+	return err
+}
+
+// DeleteDefinition removes a definition; rules referencing it no longer compile
+func (r *PostgresModelRepository) DeleteDefinition(modelID, definitionID string) error {
+	_, err := r.db.Exec(`
+		DELETE FROM definitions WHERE id = $1 AND model_id = $2
+	`, definitionID, modelID)
+	return err
+}
+
+// Volume tier operations
+
+// SetVolumeTiers replaces a model's volume tiers
+func (r *PostgresModelRepository) SetVolumeTiers(modelID string, tiers []cpq.VolumeTier) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM volume_tiers WHERE model_id = $1`, modelID)
+	if err != nil {
+		return fmt.Errorf("failed to delete existing volume tiers: %w", err)
+	}
+
+	if err := insertVolumeTiers(tx, modelID, tiers); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE models SET updated_at = NOW() WHERE id = $1`, modelID)
+	if err != nil {
+		return fmt.Errorf("failed to update model: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// Price book operations
+
+// SetPriceBooks replaces a model's price books and their entries
+func (r *PostgresModelRepository) SetPriceBooks(modelID string, books []cpq.PriceBook) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM price_books WHERE model_id = $1`, modelID)
+	if err != nil {
+		return fmt.Errorf("failed to delete existing price books: %w", err)
+	}
+
+	for _, book := range books {
+		if err := r.db.InsertPriceBook(tx, modelID, book); err != nil {
+			return fmt.Errorf("failed to insert price book %s: %w", book.ID, err)
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE models SET updated_at = NOW() WHERE id = $1`, modelID)
+	if err != nil {
+		return fmt.Errorf("failed to update model: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// insertVolumeTiers inserts volume tiers within a transaction
+func insertVolumeTiers(tx *sql.Tx, modelID string, tiers []cpq.VolumeTier) error {
+	for _, tier := range tiers {
+		mode := tier.Mode
+		if mode == "" {
+			mode = cpq.AllUnitsTiers
+		}
+		_, err := tx.Exec(`
+			INSERT INTO volume_tiers (id, model_id, name, option_id, group_id, mode, min_quantity, max_quantity, multiplier, priority)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`, tier.ID, modelID, tier.Name, nullableString(tier.OptionID), nullableString(tier.GroupID), mode,
+			tier.MinQuantity, tier.MaxQuantity, tier.Multiplier, tier.Priority)
+		if err != nil {
+			return fmt.Errorf("failed to insert volume tier %s: %w", tier.ID, err)
+		}
+	}
+	return nil
+}
+
 // Helper functions
 func nullableInt(i int) interface{} {
 	if i == 0 {
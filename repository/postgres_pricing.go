@@ -0,0 +1,53 @@
+// repository/postgres_pricing.go
+// PostgreSQL implementation of ExchangeRateRepository
+
+package repository
+
+import (
+	"DD/cpq"
+	"DD/database"
+	"strings"
+)
+
+// PostgresExchangeRateRepository implements ExchangeRateRepository using PostgreSQL
+type PostgresExchangeRateRepository struct {
+	db *database.DB
+}
+
+// NewPostgresExchangeRateRepository creates a new PostgreSQL exchange-rate repository
+func NewPostgresExchangeRateRepository(db *database.DB) *PostgresExchangeRateRepository {
+	return &PostgresExchangeRateRepository{db: db}
+}
+
+// ListExchangeRates returns every stored exchange rate
+func (r *PostgresExchangeRateRepository) ListExchangeRates() (cpq.ExchangeRates, error) {
+	rows, err := r.db.Query(`
+		SELECT from_currency, to_currency, rate, effective_from
+		FROM exchange_rates ORDER BY from_currency, to_currency, effective_from
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := cpq.ExchangeRates{}
+	for rows.Next() {
+		rate := cpq.ExchangeRate{}
+		if err := rows.Scan(&rate.FromCurrency, &rate.ToCurrency, &rate.Rate, &rate.EffectiveFrom); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
+
+// SetExchangeRate stores a rate, replacing one for the same pair and date
+func (r *PostgresExchangeRateRepository) SetExchangeRate(rate cpq.ExchangeRate) error {
+	_, err := r.db.Exec(`
+		INSERT INTO exchange_rates (from_currency, to_currency, rate, effective_from)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (from_currency, to_currency, effective_from) DO UPDATE SET rate = EXCLUDED.rate
+	`, strings.ToUpper(rate.FromCurrency), strings.ToUpper(rate.ToCurrency), rate.Rate, rate.EffectiveFrom)
+	return err
+}
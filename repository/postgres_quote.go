@@ -0,0 +1,304 @@
+// repository/postgres_quote.go
+// PostgreSQL implementation of QuoteRepository
+
+package repository
+
+import (
+	"DD/cpq"
+	"DD/database"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PostgresQuoteRepository implements QuoteRepository using PostgreSQL
+type PostgresQuoteRepository struct {
+	db *database.DB
+}
+
+// NewPostgresQuoteRepository creates a new PostgreSQL quote repository
+func NewPostgresQuoteRepository(db *database.DB) *PostgresQuoteRepository {
+	return &PostgresQuoteRepository{db: db}
+}
+
+// CreateQuote creates a quote with its lines
+func (r *PostgresQuoteRepository) CreateQuote(quote *cpq.Quote) error {
+	tiers, err := json.Marshal(quoteVolumeTiers(quote))
+	if err != nil {
+		return fmt.Errorf("failed to encode volume tiers: %w", err)
+	}
+	pricingContext, err := json.Marshal(quote.PricingContext)
+	if err != nil {
+		return fmt.Errorf("failed to encode pricing context: %w", err)
+	}
+	pricing, err := quotePricing(quote)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO quotes (id, name, customer_id, currency, status, valid_until,
+			discount_percent, volume_tiers, notes, created_by, pricing_context, pricing)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING created_at, updated_at
+	`, quote.ID, quote.Name, nullableString(quote.CustomerID), nullableString(strings.ToUpper(quote.Currency)),
+		string(quote.Status), quote.ValidUntil, quote.DiscountPercent, tiers, quote.Notes,
+		nullableString(quote.CreatedBy), pricingContext, pricing).
+		Scan(&quote.CreatedAt, &quote.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert quote: %w", err)
+	}
+
+	if err := insertQuoteLines(tx, quote); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetQuote retrieves a quote with its lines
+func (r *PostgresQuoteRepository) GetQuote(id string) (*cpq.Quote, error) {
+	quote, err := scanQuote(r.db.QueryRow(quoteSelect+` WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("quote not found: %s", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadQuoteLines([]*cpq.Quote{quote}); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// ListQuotes returns quotes with their lines, newest first, optionally for
+// one customer
+func (r *PostgresQuoteRepository) ListQuotes(customerID string) ([]*cpq.Quote, error) {
+	query := quoteSelect + ` ORDER BY created_at DESC`
+	args := []interface{}{}
+	if customerID != "" {
+		query = quoteSelect + ` WHERE customer_id = $1 ORDER BY created_at DESC`
+		args = append(args, customerID)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	quotes := []*cpq.Quote{}
+	for rows.Next() {
+		quote, err := scanQuote(rows)
+		if err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, quote)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadQuoteLines(quotes); err != nil {
+		return nil, err
+	}
+	return quotes, nil
+}
+
+// UpdateQuote updates a quote and replaces its lines
+func (r *PostgresQuoteRepository) UpdateQuote(quote *cpq.Quote) error {
+	tiers, err := json.Marshal(quoteVolumeTiers(quote))
+	if err != nil {
+		return fmt.Errorf("failed to encode volume tiers: %w", err)
+	}
+	pricingContext, err := json.Marshal(quote.PricingContext)
+	if err != nil {
+		return fmt.Errorf("failed to encode pricing context: %w", err)
+	}
+	pricing, err := quotePricing(quote)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE quotes SET name = $2, customer_id = $3, currency = $4, status = $5,
+			valid_until = $6, discount_percent = $7, volume_tiers = $8, notes = $9,
+			pricing_context = $10, pricing = $11
+		WHERE id = $1
+	`, quote.ID, quote.Name, nullableString(quote.CustomerID), nullableString(strings.ToUpper(quote.Currency)),
+		string(quote.Status), quote.ValidUntil, quote.DiscountPercent, tiers, quote.Notes, pricingContext, pricing)
+	if err != nil {
+		return fmt.Errorf("failed to update quote: %w", err)
+	}
+	if err := expectRow(result, "quote", quote.ID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM quote_lines WHERE quote_id = $1`, quote.ID); err != nil {
+		return fmt.Errorf("failed to delete existing quote lines: %w", err)
+	}
+	if err := insertQuoteLines(tx, quote); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteQuote deletes a quote and its lines
+func (r *PostgresQuoteRepository) DeleteQuote(id string) error {
+	result, err := r.db.Exec(`DELETE FROM quotes WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return expectRow(result, "quote", id)
+}
+
+// quoteSelect selects the columns read by scanQuote
+const quoteSelect = `
+	SELECT id, name, customer_id, currency, status, valid_until, discount_percent,
+		volume_tiers, notes, created_by, created_at, updated_at, pricing_context,
+		pricing
+	FROM quotes`
+
+// scanQuote scans a quote row without its lines
+func scanQuote(row interface{ Scan(...interface{}) error }) (*cpq.Quote, error) {
+	quote := &cpq.Quote{Lines: []cpq.QuoteLine{}}
+	var customerID, currency, createdBy sql.NullString
+	var status string
+	var tiers, pricingContext, pricing []byte
+
+	err := row.Scan(
+		&quote.ID, &quote.Name, &customerID, &currency, &status, &quote.ValidUntil,
+		&quote.DiscountPercent, &tiers, &quote.Notes, &createdBy, &quote.CreatedAt, &quote.UpdatedAt,
+		&pricingContext, &pricing,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	quote.CustomerID = customerID.String
+	quote.Currency = strings.TrimSpace(currency.String)
+	quote.Status = cpq.QuoteStatus(status)
+	quote.CreatedBy = createdBy.String
+	if err := json.Unmarshal(tiers, &quote.VolumeTiers); err != nil {
+		return nil, fmt.Errorf("failed to decode volume tiers of %s: %w", quote.ID, err)
+	}
+	if err := json.Unmarshal(pricingContext, &quote.PricingContext); err != nil {
+		return nil, fmt.Errorf("failed to decode pricing context of %s: %w", quote.ID, err)
+	}
+	if pricing != nil {
+		quote.Pricing = &cpq.QuotePricing{}
+		if err := json.Unmarshal(pricing, quote.Pricing); err != nil {
+			return nil, fmt.Errorf("failed to decode pricing of %s: %w", quote.ID, err)
+		}
+	}
+	return quote, nil
+}
+
+// loadQuoteLines loads the lines of quotes in order
+func (r *PostgresQuoteRepository) loadQuoteLines(quotes []*cpq.Quote) error {
+	for _, quote := range quotes {
+		rows, err := r.db.Query(`
+			SELECT id, type, description, quantity, discount_percent, configuration_id,
+				model_id, selections, children, sku, unit_price, unit_cost
+			FROM quote_lines WHERE quote_id = $1 ORDER BY position
+		`, quote.ID)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var line cpq.QuoteLine
+			var lineType string
+			var configurationID, modelID, sku sql.NullString
+			var selections, children []byte
+			err := rows.Scan(&line.ID, &lineType, &line.Description, &line.Quantity, &line.DiscountPercent,
+				&configurationID, &modelID, &selections, &children, &sku, &line.UnitPrice, &line.UnitCost)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			line.Type = cpq.QuoteLineType(lineType)
+			line.ConfigurationID = configurationID.String
+			line.ModelID = modelID.String
+			line.SKU = sku.String
+			if err := json.Unmarshal(selections, &line.Selections); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to decode selections of quote line %s: %w", line.ID, err)
+			}
+			if err := json.Unmarshal(children, &line.Children); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to decode sub-assemblies of quote line %s: %w", line.ID, err)
+			}
+			quote.Lines = append(quote.Lines, line)
+		}
+		rows.Close()
+	}
+	return nil
+}
+
+// insertQuoteLines inserts a quote's lines within a transaction
+func insertQuoteLines(tx *sql.Tx, quote *cpq.Quote) error {
+	for position, line := range quote.Lines {
+		selections := line.Selections
+		if selections == nil {
+			selections = []cpq.Selection{}
+		}
+		encoded, err := json.Marshal(selections)
+		if err != nil {
+			return fmt.Errorf("failed to encode selections of quote line %s: %w", line.ID, err)
+		}
+		children, err := database.ChildConfigurationsJSON(line.Children)
+		if err != nil {
+			return fmt.Errorf("failed to encode sub-assemblies of quote line %s: %w", line.ID, err)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO quote_lines (quote_id, id, position, type, description, quantity,
+				discount_percent, configuration_id, model_id, selections, children, sku, unit_price, unit_cost)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		`, quote.ID, line.ID, position, string(line.Type), line.Description, line.Quantity,
+			line.DiscountPercent, nullableString(line.ConfigurationID), nullableString(line.ModelID),
+			encoded, children, nullableString(line.SKU), line.UnitPrice, line.UnitCost)
+		if err != nil {
+			return fmt.Errorf("failed to insert quote line %s: %w", line.ID, err)
+		}
+	}
+	return nil
+}
+
+// quoteVolumeTiers returns a quote's volume tiers, never nil, for the
+// volume_tiers column
+func quoteVolumeTiers(quote *cpq.Quote) []cpq.VolumeTier {
+	if quote.VolumeTiers == nil {
+		return []cpq.VolumeTier{}
+	}
+	return quote.VolumeTiers
+}
+
+// quotePricing encodes a quote's pricing snapshot for the pricing column,
+// NULL when it has none
+func quotePricing(quote *cpq.Quote) (interface{}, error) {
+	if quote.Pricing == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(quote.Pricing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode pricing: %w", err)
+	}
+	return encoded, nil
+}
@@ -0,0 +1,68 @@
+// repository/postgres_tax.go
+// PostgreSQL implementation of TaxRateRepository
+
+package repository
+
+import (
+	"DD/database"
+	"DD/tax"
+	"fmt"
+)
+
+// PostgresTaxRateRepository implements TaxRateRepository using PostgreSQL
+type PostgresTaxRateRepository struct {
+	db *database.DB
+}
+
+// NewPostgresTaxRateRepository creates a new PostgreSQL tax-rate repository
+func NewPostgresTaxRateRepository(db *database.DB) *PostgresTaxRateRepository {
+	return &PostgresTaxRateRepository{db: db}
+}
+
+// ListTaxRates returns every stored jurisdiction tax rate
+func (r *PostgresTaxRateRepository) ListTaxRates() ([]tax.Rate, error) {
+	rows, err := r.db.Query(`
+		SELECT country, region, postal_prefix, category, name, rate
+		FROM tax_rates ORDER BY country, region, postal_prefix, category
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []tax.Rate{}
+	for rows.Next() {
+		rate := tax.Rate{}
+		if err := rows.Scan(&rate.Country, &rate.Region, &rate.PostalPrefix, &rate.Category, &rate.Name, &rate.Rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
+
+// ReplaceTaxRates replaces the stored rate table, as after a CSV import
+func (r *PostgresTaxRateRepository) ReplaceTaxRates(rates []tax.Rate) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM tax_rates`); err != nil {
+		return fmt.Errorf("failed to clear tax rates: %w", err)
+	}
+
+	for _, rate := range rates {
+		_, err := tx.Exec(`
+			INSERT INTO tax_rates (country, region, postal_prefix, category, name, rate)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, rate.Country, rate.Region, rate.PostalPrefix, rate.Category, rate.Name, rate.Rate)
+		if err != nil {
+			return fmt.Errorf("failed to insert tax rate %s: %w", rate.Name, err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,251 @@
+// assembly_handlers.go - Sub-assembly API Endpoints
+// Validates and prices configurations with sub-assemblies configured from
+// child models, on their own or as part of a configuration session
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"DD/cpq"
+	"github.com/gorilla/mux"
+)
+
+// modelSource looks up models by ID
+type modelSource interface {
+	GetModel(modelID string) (*cpq.Model, error)
+}
+
+// modelCatalog lists and looks up models
+type modelCatalog interface {
+	modelSource
+	ListModels() ([]*cpq.Model, error)
+}
+
+// loadModels returns every stored model keyed by ID, with a model that is
+// not stored yet, if any, in place of its stored version
+func loadModels(catalog modelCatalog, updated *cpq.Model) (map[string]*cpq.Model, error) {
+	summaries, err := catalog.ListModels()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+
+	models := make(map[string]*cpq.Model, len(summaries)+1)
+	for _, summary := range summaries {
+		// Listed models may be summaries without their options
+		model, err := catalog.GetModel(summary.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load model %s: %w", summary.ID, err)
+		}
+		models[model.ID] = model
+	}
+	if updated != nil {
+		models[updated.ID] = updated
+	}
+	return models, nil
+}
+
+// loadModelTree returns a model with every model its sub-assembly options
+// lead to, keyed by ID
+func loadModelTree(source modelSource, root *cpq.Model) (map[string]*cpq.Model, error) {
+	models := map[string]*cpq.Model{root.ID: root}
+	pending := root.ChildModelIDs()
+	for len(pending) > 0 {
+		modelID := pending[0]
+		pending = pending[1:]
+		if _, loaded := models[modelID]; loaded {
+			continue
+		}
+
+		model, err := source.GetModel(modelID)
+		if err != nil {
+			return nil, fmt.Errorf("sub-assembly model not found: %s", modelID)
+		}
+		models[modelID] = model
+		pending = append(pending, model.ChildModelIDs()...)
+	}
+	return models, nil
+}
+
+// validateModelTree checks a model's sub-assembly options and assembly
+// rules against the stored models below it
+func validateModelTree(source modelSource, model *cpq.Model) error {
+	if !model.IsAssembly() {
+		return nil
+	}
+	if err := cpq.ValidateAssemblyRules(model); err != nil {
+		return err
+	}
+	models, err := loadModelTree(source, model)
+	if err != nil {
+		return err
+	}
+	return cpq.ValidateModelTree(models, model.ID)
+}
+
+// AssemblyResponse reports the validation and rolled-up pricing of a
+// configuration with its sub-assemblies
+type AssemblyResponse struct {
+	IsValid    bool                 `json:"is_valid"`
+	Validation cpq.ValidationResult `json:"validation"`
+	Assembly   *cpq.AssemblyPrice   `json:"assembly"`
+	Pricing    *PricingResponse     `json:"pricing,omitempty"`
+	Timestamp  time.Time            `json:"timestamp"`
+}
+
+// CalculateAssemblyPrice validates and prices a configuration with its
+// sub-assemblies, applying the requested discount to the rolled-up total
+func (h *PricingHandlers) CalculateAssemblyPrice(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	var req AssemblyPricingRequest
+	if err := ParseJSONRequest(r, &req); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+
+	if req.ModelID == "" {
+		WriteValidationErrorResponse(w, map[string]string{
+			"model_id": "Model ID is required",
+		})
+		return
+	}
+
+	model, err := h.service.GetModel(req.ModelID)
+	if err != nil {
+		WriteNotFoundResponse(w, "Model")
+		return
+	}
+	models, err := loadModelTree(h.service, model)
+	if err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"model_id": err.Error(),
+		})
+		return
+	}
+
+	ctx, err := h.pricingContext(req.PricingContext)
+	if err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"pricing_context": err.Error(),
+		})
+		return
+	}
+
+	config := cpq.Configuration{ModelID: model.ID, Children: req.Children}
+	for _, selection := range req.Selections {
+		config.Selections = append(config.Selections, cpq.Selection{OptionID: selection.OptionID, Quantity: selection.Quantity})
+	}
+
+	price, err := cpq.PriceAssembly(models, config, ctx)
+	if err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"children": err.Error(),
+		})
+		return
+	}
+
+	// Discount and tax apply to the rolled-up total
+	pricing, err := cpq.ApplyDiscount(model, price.Total, req.DiscountPercent)
+	if err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"discount_percent": err.Error(),
+		})
+		return
+	}
+	pricing, err = cpq.ApplyAssemblyTax(models, model.ID, pricing, ctx, nil)
+	if err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"ship_to": err.Error(),
+		})
+		return
+	}
+
+	validation := cpq.ValidateAssembly(models, config).Localized(RequestLocalizer(w, r))
+	response := &AssemblyResponse{
+		IsValid:    validation.IsValid,
+		Validation: validation,
+		Assembly:   price,
+		Pricing:    NewPricingResponse(cpq.NewPricingResult(pricing)),
+		Timestamp:  time.Now().UTC(),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// SetChildren replaces the sub-assemblies configured for a session
+func (h *ConfigurationHandlersV2) SetChildren(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+	sessionID := vars["id"]
+
+	var req ChildrenRequest
+	if err := ParseJSONRequest(r, &req); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+
+	if _, err := h.service.GetSession(sessionID); err != nil {
+		WriteNotFoundResponse(w, "Configuration session")
+		return
+	}
+
+	session, err := h.service.SetChildren(sessionID, req.Children)
+	if err != nil {
+		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to update sub-assemblies", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{
+		"session_id":       session.ID,
+		"children":         session.Children,
+		"validation_state": session.ValidationState,
+		"pricing_state":    session.PricingState,
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// GetAssembly validates a session's configuration with its sub-assemblies
+// and prices it level by level
+func (h *ConfigurationHandlersV2) GetAssembly(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+	sessionID := vars["id"]
+
+	session, err := h.service.GetSession(sessionID)
+	if err != nil {
+		WriteNotFoundResponse(w, "Configuration session")
+		return
+	}
+
+	validation, price, err := h.service.GetSessionAssembly(sessionID)
+	if err != nil {
+		WriteErrorResponse(w, "ASSEMBLY_FAILED", "Failed to price sub-assemblies", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The session's pricing state carries its discount and tax
+	validation = validation.Localized(RequestLocalizer(w, r))
+	response := &AssemblyResponse{
+		IsValid:    validation.IsValid,
+		Validation: validation,
+		Assembly:   price,
+		Timestamp:  time.Now().UTC(),
+	}
+	if session.PricingState != nil {
+		response.Pricing = NewPricingResponse(session.PricingState)
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
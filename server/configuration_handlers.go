@@ -37,6 +37,8 @@ func (s *Server) setupConfigurationRoutes(router *mux.Router) {
 
 	// Configuration operations
 	router.HandleFunc("/{id}/validate", handlers.ValidateCurrentConfiguration).Methods("POST", "OPTIONS")
+	router.HandleFunc("/{id}/explain-conflict", handlers.ExplainConflict).Methods("POST", "OPTIONS")
+	router.HandleFunc("/{id}/repairs", handlers.SuggestRepairs).Methods("POST", "OPTIONS")
 	router.HandleFunc("/{id}/price", handlers.CalculatePrice).Methods("POST", "OPTIONS")
 	router.HandleFunc("/{id}/summary", handlers.GetConfigurationSummary).Methods("GET", "OPTIONS")
 	router.HandleFunc("/{id}/clone", handlers.CloneConfiguration).Methods("POST", "OPTIONS")
@@ -137,7 +139,7 @@ func (h *ConfigurationHandlers) GetConfiguration(w http.ResponseWriter, r *http.
 	}
 
 	config := configurator.GetCurrentConfiguration()
-	validation := configurator.ValidateCurrentConfiguration()
+	validation := configurator.ValidateCurrentConfiguration().Localized(RequestLocalizer(w, r))
 	price := configurator.GetDetailedPrice()
 
 	response := &ConfigurationResponse{
@@ -192,6 +194,7 @@ func (h *ConfigurationHandlers) UpdateConfiguration(w http.ResponseWriter, r *ht
 		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to update configuration", err.Error(), http.StatusBadRequest)
 		return
 	}
+	validation := result.ValidationResult.Localized(RequestLocalizer(w, r))
 
 	response := &ConfigurationResponse{
 		ID:          configID,
@@ -201,7 +204,7 @@ func (h *ConfigurationHandlers) UpdateConfiguration(w http.ResponseWriter, r *ht
 		IsValid:     result.IsValid,
 		Selections:  result.UpdatedConfig.Selections,
 		Price:       &result.PriceBreakdown,
-		Validation:  &result.ValidationResult,
+		Validation:  &validation,
 		UpdatedAt:   time.Now().UTC(),
 	}
 
@@ -287,10 +290,92 @@ func (h *ConfigurationHandlers) ValidateCurrentConfiguration(w http.ResponseWrit
 		WriteErrorResponse(w, "VALIDATION_FAILED", "Failed to validate configuration", err.Error(), http.StatusBadRequest)
 		return
 	}
+	localized := validation.Localized(RequestLocalizer(w, r))
 
 	response := &ValidationResponse{
-		IsValid:   validation.IsValid,
-		Result:    validation,
+		IsValid:   localized.IsValid,
+		Result:    &localized,
+		Timestamp: time.Now().UTC(),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// ExplainConflict reports the smallest set of selections and rules that
+// makes a configuration impossible to complete
+func (h *ConfigurationHandlers) ExplainConflict(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+	configID := vars["id"]
+
+	modelID := r.URL.Query().Get("model_id")
+	if modelID == "" {
+		WriteBadRequestResponse(w, "Model ID is required")
+		return
+	}
+
+	configurator, err := h.service.GetConfigurator(modelID)
+	if err != nil {
+		WriteNotFoundResponse(w, "Model")
+		return
+	}
+
+	explanation := configurator.ExplainCurrentConflict()
+	explanation.Localize(RequestLocalizer(w, r))
+	response := &ConflictExplanationResponse{
+		ConfigID:    configID,
+		HasConflict: explanation.HasConflict,
+		Explanation: explanation,
+		Timestamp:   time.Now().UTC(),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// SuggestRepairs returns the nearest valid configurations as change sets.
+// The optional body is a cpq.RepairOptions with pinned options and weights.
+func (h *ConfigurationHandlers) SuggestRepairs(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+	configID := vars["id"]
+
+	modelID := r.URL.Query().Get("model_id")
+	if modelID == "" {
+		WriteBadRequestResponse(w, "Model ID is required")
+		return
+	}
+
+	var options cpq.RepairOptions
+	if r.ContentLength != 0 {
+		if err := ParseJSONRequest(r, &options); err != nil {
+			WriteBadRequestResponse(w, "Invalid request body")
+			return
+		}
+	}
+
+	configurator, err := h.service.GetConfigurator(modelID)
+	if err != nil {
+		WriteNotFoundResponse(w, "Model")
+		return
+	}
+
+	repairs := configurator.SuggestRepairs(options)
+	if repairs == nil {
+		repairs = []cpq.Repair{}
+	}
+	localizer := RequestLocalizer(w, r)
+	for i := range repairs {
+		repairs[i].Localize(localizer)
+	}
+	response := &RepairResponse{
+		ConfigID:  configID,
+		Repairs:   repairs,
 		Timestamp: time.Now().UTC(),
 	}
 
@@ -329,12 +414,7 @@ func (h *ConfigurationHandlers) CalculatePrice(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	response := &PricingResponse{
-		Breakdown: pricing,
-		Total:     pricing.TotalPrice,
-		Currency:  "USD",
-		Timestamp: time.Now().UTC(),
-	}
+	response := NewPricingResponse(pricing)
 
 	duration := timer()
 	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
@@ -659,7 +739,7 @@ func (h *ConfigurationHandlers) ValidateSelection(w http.ResponseWriter, r *http
 	} else {
 		response = map[string]interface{}{
 			"valid":      result.IsValid,
-			"validation": result.ValidationResult,
+			"validation": result.ValidationResult.Localized(RequestLocalizer(w, r)),
 			"option_id":  req.OptionID,
 			"quantity":   req.Quantity,
 		}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -31,6 +32,8 @@ func (h *ConfigurationHandlersV2) CreateConfiguration(w http.ResponseWriter, r *
 		Name        string                 `json:"name"`
 		Description string                 `json:"description"`
 		Metadata    map[string]interface{} `json:"metadata"`
+
+		PricingContext *cpq.PricingContext `json:"pricing_context,omitempty"`
 	}
 
 	if err := ParseJSONRequest(r, &req); err != nil {
@@ -53,6 +56,16 @@ func (h *ConfigurationHandlersV2) CreateConfiguration(w http.ResponseWriter, r *
 		return
 	}
 
+	if req.PricingContext != nil {
+		session, err = h.service.SetPricingContext(session.ID, *req.PricingContext)
+		if err != nil {
+			WriteValidationErrorResponse(w, map[string]string{
+				"pricing_context": err.Error(),
+			})
+			return
+		}
+	}
+
 	// Get current configuration (which includes default selections)
 	config := session.Configurator.GetCurrentConfiguration()
 
@@ -70,6 +83,7 @@ func (h *ConfigurationHandlersV2) CreateConfiguration(w http.ResponseWriter, r *
 		"total_price":      config.TotalPrice,
 		"validation_state": session.ValidationState,
 		"pricing_state":    session.PricingState,
+		"pricing_context":  session.PricingContext,
 	}
 
 	duration := timer()
@@ -108,6 +122,9 @@ func (h *ConfigurationHandlersV2) GetConfiguration(w http.ResponseWriter, r *htt
 		"total_price":      config.TotalPrice,
 		"validation_state": session.ValidationState,
 		"pricing_state":    session.PricingState,
+		"pricing_context":  session.PricingContext,
+		"discount_percent": session.DiscountPercent,
+		"approval":         session.Approval,
 		"created_at":       session.CreatedAt,
 		"updated_at":       session.UpdatedAt,
 		"expires_at":       session.ExpiresAt,
@@ -156,7 +173,10 @@ func (h *ConfigurationHandlersV2) UpdateConfiguration(w http.ResponseWriter, r *
 	// Handle optional actions
 	switch req.Action {
 	case "complete":
-		h.service.CompleteSession(sessionID)
+		if err := h.service.CompleteSession(sessionID); err != nil {
+			writeCompleteError(w, err)
+			return
+		}
 	case "validate":
 		// Already validated during update
 	case "price":
@@ -173,7 +193,7 @@ func (h *ConfigurationHandlersV2) UpdateConfiguration(w http.ResponseWriter, r *
 	response := map[string]interface{}{
 		"session_id":        sessionID,
 		"configuration":     result.UpdatedConfig,
-		"validation_result": result.ValidationResult,
+		"validation_result": result.ValidationResult.Localized(RequestLocalizer(w, r)),
 		"price_breakdown":   result.PriceBreakdown,
 		"available_options": result.AvailableOptions,
 		"session_status":    session.Status,
@@ -234,10 +254,11 @@ func (h *ConfigurationHandlersV2) ValidateConfiguration(w http.ResponseWriter, r
 		WriteErrorResponse(w, "VALIDATION_FAILED", "Failed to validate configuration", err.Error(), http.StatusBadRequest)
 		return
 	}
+	localized := result.Localized(RequestLocalizer(w, r))
 
 	response := &ValidationResponse{
-		IsValid:   result.IsValid,
-		Result:    result,
+		IsValid:   localized.IsValid,
+		Result:    &localized,
 		Timestamp: time.Now().UTC(),
 	}
 
@@ -270,18 +291,44 @@ func (h *ConfigurationHandlersV2) CalculatePrice(w http.ResponseWriter, r *http.
 	// Get pricing from configurator
 	pricing := session.Configurator.GetDetailedPrice()
 	
-	// Create pricing result
-	pricingResult := &cpq.PricingResult{
-		BasePrice:  pricing.BasePrice,
-		TotalPrice: pricing.TotalPrice,
-		Breakdown:  &pricing,
+	response := NewPricingResponse(cpq.NewPricingResult(pricing))
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// SetPricingContext selects the price book, date and currency a session is
+// priced in
+func (h *ConfigurationHandlersV2) SetPricingContext(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+	sessionID := vars["id"]
+
+	var ctx cpq.PricingContext
+	if err := ParseJSONRequest(r, &ctx); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
 	}
 
-	response := &PricingResponse{
-		Breakdown: pricingResult,
-		Total:     pricing.TotalPrice,
-		Currency:  "USD",
-		Timestamp: time.Now().UTC(),
+	if _, err := h.service.GetSession(sessionID); err != nil {
+		WriteNotFoundResponse(w, "Configuration session")
+		return
+	}
+
+	session, err := h.service.SetPricingContext(sessionID, ctx)
+	if err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"pricing_context": err.Error(),
+		})
+		return
+	}
+
+	response := map[string]interface{}{
+		"session_id":      session.ID,
+		"pricing_context": session.PricingContext,
+		"pricing_state":   session.PricingState,
 	}
 
 	duration := timer()
@@ -466,7 +513,7 @@ func (h *ConfigurationHandlersV2) CompleteSession(w http.ResponseWriter, r *http
 	sessionID := vars["id"]
 
 	if err := h.service.CompleteSession(sessionID); err != nil {
-		WriteErrorResponse(w, "COMPLETE_FAILED", "Failed to complete session", err.Error(), http.StatusBadRequest)
+		writeCompleteError(w, err)
 		return
 	}
 
@@ -481,6 +528,182 @@ func (h *ConfigurationHandlersV2) CompleteSession(w http.ResponseWriter, r *http
 	WriteSuccessResponse(w, response, meta)
 }
 
+// writeCompleteError writes the response for a session that could not be
+// completed: a conflict while its quote awaits approval
+func writeCompleteError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errApprovalRequired) {
+		WriteErrorResponse(w, "APPROVAL_REQUIRED", "Quote requires approval", err.Error(), http.StatusConflict)
+		return
+	}
+	WriteErrorResponse(w, "COMPLETE_FAILED", "Failed to complete session", err.Error(), http.StatusBadRequest)
+}
+
+// SetDiscount sets the discount requested on a session's quote
+func (h *ConfigurationHandlersV2) SetDiscount(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+	sessionID := vars["id"]
+
+	var req struct {
+		DiscountPercent float64 `json:"discount_percent"`
+	}
+	if err := ParseJSONRequest(r, &req); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+
+	if _, err := h.service.GetSession(sessionID); err != nil {
+		WriteNotFoundResponse(w, "Configuration session")
+		return
+	}
+
+	session, err := h.service.SetDiscount(sessionID, req.DiscountPercent)
+	if err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"discount_percent": err.Error(),
+		})
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, approvalResponse(session), meta)
+}
+
+// SubmitForApproval submits a session's quote for approval
+func (h *ConfigurationHandlersV2) SubmitForApproval(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+	sessionID := vars["id"]
+
+	if _, err := h.service.GetSession(sessionID); err != nil {
+		WriteNotFoundResponse(w, "Configuration session")
+		return
+	}
+
+	userID := GetUserIDFromContext(r.Context())
+	if claims := claimsFromRequest(r); claims != nil {
+		userID = claims.Username
+	}
+
+	session, err := h.service.SubmitForApproval(sessionID, userID)
+	if err != nil {
+		WriteErrorResponse(w, "APPROVAL_FAILED", "Failed to submit for approval", err.Error(), http.StatusConflict)
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, approvalResponse(session), meta)
+}
+
+// ApproveSession approves a session's quote in the caller's role
+func (h *ConfigurationHandlersV2) ApproveSession(w http.ResponseWriter, r *http.Request) {
+	h.approvalAction(w, r, "approve", h.service.ApproveSession)
+}
+
+// RejectSession rejects a session's quote. A comment is required.
+func (h *ConfigurationHandlersV2) RejectSession(w http.ResponseWriter, r *http.Request) {
+	h.approvalAction(w, r, "reject", h.service.RejectSession)
+}
+
+// AddApprovalComment adds a comment to a session's approval history
+func (h *ConfigurationHandlersV2) AddApprovalComment(w http.ResponseWriter, r *http.Request) {
+	h.approvalAction(w, r, "comment", h.service.CommentOnApproval)
+}
+
+// approvalAction runs an approval step as the authenticated caller
+func (h *ConfigurationHandlersV2) approvalAction(w http.ResponseWriter, r *http.Request, action string,
+	step func(sessionID, userID, role, comment string) (*ConfigurationSession, error)) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+	sessionID := vars["id"]
+
+	claims := claimsFromRequest(r)
+	if claims == nil {
+		WriteErrorResponse(w, "MISSING_TOKEN", "Authorization header required", "", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Comment string `json:"comment"`
+	}
+	if r.ContentLength != 0 {
+		if err := ParseJSONRequest(r, &req); err != nil {
+			WriteBadRequestResponse(w, "Invalid request body")
+			return
+		}
+	}
+
+	session, err := h.service.GetSession(sessionID)
+	if err != nil {
+		WriteNotFoundResponse(w, "Configuration session")
+		return
+	}
+
+	if action != "comment" && (session.Approval == nil || !session.Approval.CanAct(claims.Role)) {
+		WriteErrorResponse(w, "FORBIDDEN", fmt.Sprintf("Role %s cannot %s this quote", claims.Role, action), "", http.StatusForbidden)
+		return
+	}
+
+	session, err = step(sessionID, claims.Username, claims.Role, req.Comment)
+	if err != nil {
+		WriteErrorResponse(w, "APPROVAL_FAILED", fmt.Sprintf("Failed to %s quote", action), err.Error(), http.StatusConflict)
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, approvalResponse(session), meta)
+}
+
+// GetApproval returns a session's approval state and history
+func (h *ConfigurationHandlersV2) GetApproval(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+	sessionID := vars["id"]
+
+	session, err := h.service.GetSession(sessionID)
+	if err != nil {
+		WriteNotFoundResponse(w, "Configuration session")
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, approvalResponse(session), meta)
+}
+
+// approvalResponse builds the approval view of a session
+func approvalResponse(session *ConfigurationSession) map[string]interface{} {
+	approval := session.Approval
+	if approval == nil {
+		approval = cpq.NewApproval()
+	}
+
+	return map[string]interface{}{
+		"session_id":       session.ID,
+		"status":           session.Status,
+		"discount_percent": session.DiscountPercent,
+		"approval_status":  approval.Status,
+		"requirements":     approval.Requirements,
+		"submitted_by":     approval.SubmittedBy,
+		"submitted_at":     approval.SubmittedAt,
+		"history":          approval.History,
+		"pricing_state":    session.PricingState,
+	}
+}
+
+// claimsFromRequest returns the token claims set by the auth middleware
+func claimsFromRequest(r *http.Request) *TokenClaims {
+	claims, _ := r.Context().Value("user_claims").(*TokenClaims)
+	return claims
+}
+
 // GetSystemStats returns system statistics including session data
 func (h *ConfigurationHandlersV2) GetSystemStats(w http.ResponseWriter, r *http.Request) {
 	timer := StartTimer()
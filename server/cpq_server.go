@@ -119,9 +119,20 @@ func (s *Server) setupConfigurationRoutesV2(router *mux.Router) {
 	router.HandleFunc("/{id}/selections", handlers.AddSelections).Methods("POST", "OPTIONS")
 	router.HandleFunc("/{id}/validate", handlers.ValidateConfiguration).Methods("POST", "OPTIONS")
 	router.HandleFunc("/{id}/price", handlers.CalculatePrice).Methods("POST", "OPTIONS")
+	router.HandleFunc("/{id}/pricing-context", handlers.SetPricingContext).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/{id}/children", handlers.SetChildren).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/{id}/assembly", handlers.GetAssembly).Methods("GET", "OPTIONS")
 	router.HandleFunc("/{id}/extend", handlers.ExtendSession).Methods("POST", "OPTIONS")
 	router.HandleFunc("/{id}/complete", handlers.CompleteSession).Methods("POST", "OPTIONS")
 	
+	// Discount approval workflow; approvers act with the role in their token
+	router.HandleFunc("/{id}/discount", handlers.SetDiscount).Methods("PUT", "OPTIONS")
+	router.Handle("/{id}/submit", s.claimsMiddleware(http.HandlerFunc(handlers.SubmitForApproval))).Methods("POST", "OPTIONS")
+	router.Handle("/{id}/approve", s.claimsMiddleware(http.HandlerFunc(handlers.ApproveSession))).Methods("POST", "OPTIONS")
+	router.Handle("/{id}/reject", s.claimsMiddleware(http.HandlerFunc(handlers.RejectSession))).Methods("POST", "OPTIONS")
+	router.Handle("/{id}/comments", s.claimsMiddleware(http.HandlerFunc(handlers.AddApprovalComment))).Methods("POST", "OPTIONS")
+	router.HandleFunc("/{id}/approval", handlers.GetApproval).Methods("GET", "OPTIONS")
+	
 	// User session management
 	router.HandleFunc("/user-sessions", handlers.GetUserSessions).Methods("GET", "OPTIONS")
 	
@@ -218,6 +229,14 @@ func (s *Server) setupRoutes() {
 	pricingRouter := api.PathPrefix("/pricing").Subrouter()
 	s.setupPricingRoutes(pricingRouter)
 
+	// Customer and contract routes
+	customerRouter := api.PathPrefix("/customers").Subrouter()
+	s.setupCustomerRoutes(customerRouter)
+
+	// Quote routes
+	quoteRouter := api.PathPrefix("/quotes").Subrouter()
+	s.setupQuoteRoutes(quoteRouter)
+
 	// V2 API Routes (Session-based)
 	if s.sessionService != nil {
 		apiV2 := s.router.PathPrefix("/api/v2").Subrouter()
@@ -548,6 +567,29 @@ func (s *Server) timingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// claimsMiddleware adds the claims of a valid Bearer token to the request
+// context. Requests without one pass through anonymously; handlers that
+// need a user check for the claims.
+func (s *Server) claimsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if s.authService == nil || !strings.HasPrefix(authHeader, "Bearer ") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		claims, err := s.authService.ValidateToken(strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
+			WriteErrorResponse(w, "INVALID_TOKEN", "Token is invalid or expired", err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "username", claims.Username)
+		ctx = context.WithValue(ctx, "user_claims", claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // responseRecorder captures response status code for logging
 type responseRecorder struct {
 	http.ResponseWriter
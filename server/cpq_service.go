@@ -12,6 +12,7 @@ import (
 
 	"DD/cpq"          // Main CPQ package
 	"DD/modelbuilder" // Model building tools
+	"DD/tax"          // Jurisdiction tax rates
 )
 
 // CPQService provides unified access to all CPQ functionality
@@ -23,6 +24,11 @@ type CPQService struct {
 	modelValidator   *modelbuilder.ModelValidator
 	priorityManager  *modelbuilder.RulePriorityManager
 	stats            *SystemStats
+	exchangeRates    cpq.ExchangeRates        // Kept in memory without a database
+	taxRates         *tax.Table               // Kept in memory without a database
+	customers        map[string]*cpq.Customer // Customer ID -> Customer
+	contracts        map[string]*cpq.Contract // Contract ID -> Contract
+	quotes           map[string]*cpq.Quote    // Quote ID -> Quote
 	mutex            sync.RWMutex
 	startTime        time.Time
 }
@@ -46,6 +52,9 @@ func NewCPQService() (*CPQService, error) {
 	service := &CPQService{
 		configurators: make(map[string]*cpq.Configurator),
 		models:        make(map[string]*cpq.Model),
+		customers:     make(map[string]*cpq.Customer),
+		contracts:     make(map[string]*cpq.Contract),
+		quotes:        make(map[string]*cpq.Quote),
 		stats: &SystemStats{
 			StartTime: time.Now(),
 		},
@@ -4,10 +4,12 @@ package server
 
 import (
 	"fmt"
+	"strings"
 	"time"
 	
 	"DD/cpq"
 	"DD/modelbuilder"
+	"DD/tax"
 )
 
 // CalculatePrice wrapper to match interface signature
@@ -17,13 +19,7 @@ func (s *CPQService) CalculatePrice(modelID string, configID string) (*cpq.Prici
 		return nil, err
 	}
 	
-	// Convert PriceBreakdown to PricingResult
-	return &cpq.PricingResult{
-		BasePrice:   breakdown.BasePrice,
-		Adjustments: breakdown.Adjustments,
-		TotalPrice:  breakdown.TotalPrice,
-		Breakdown:   breakdown,
-	}, nil
+	return cpq.NewPricingResult(*breakdown), nil
 }
 
 // These methods extend CPQService to fully implement CPQServiceInterface
@@ -302,4 +298,98 @@ func (s *CPQService) DeleteGroup(modelID, groupID string) error {
 	s.configurators[modelID] = configurator
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// Pricing Operations
+
+// SetVolumeTiers replaces a model's volume tiers
+func (s *CPQService) SetVolumeTiers(modelID string, tiers []cpq.VolumeTier) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	
+	model, exists := s.models[modelID]
+	if !exists {
+		return fmt.Errorf("model not found: %s", modelID)
+	}
+	
+	model.VolumeTiers = tiers
+	
+	// Recreate configurator so pricing uses the new tiers
+	configurator, err := cpq.NewConfigurator(model)
+	if err != nil {
+		return fmt.Errorf("failed to recreate configurator: %w", err)
+	}
+	s.configurators[modelID] = configurator
+	
+	return nil
+}
+
+// SetPriceBooks replaces a model's price books
+func (s *CPQService) SetPriceBooks(modelID string, books []cpq.PriceBook) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	
+	model, exists := s.models[modelID]
+	if !exists {
+		return fmt.Errorf("model not found: %s", modelID)
+	}
+	
+	model.PriceBooks = books
+	
+	// Recreate configurator so pricing uses the new books
+	configurator, err := cpq.NewConfigurator(model)
+	if err != nil {
+		return fmt.Errorf("failed to recreate configurator: %w", err)
+	}
+	s.configurators[modelID] = configurator
+	
+	return nil
+}
+
+// GetExchangeRates returns the exchange-rate table
+func (s *CPQService) GetExchangeRates() (cpq.ExchangeRates, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	
+	rates := make(cpq.ExchangeRates, len(s.exchangeRates))
+	copy(rates, s.exchangeRates)
+	return rates, nil
+}
+
+// SetExchangeRate stores a rate, replacing one for the same pair and date
+func (s *CPQService) SetExchangeRate(rate cpq.ExchangeRate) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	
+	for i, existing := range s.exchangeRates {
+		if strings.EqualFold(existing.FromCurrency, rate.FromCurrency) &&
+			strings.EqualFold(existing.ToCurrency, rate.ToCurrency) &&
+			existing.EffectiveFrom.Equal(rate.EffectiveFrom) {
+			s.exchangeRates[i] = rate
+			return nil
+		}
+	}
+	s.exchangeRates = append(s.exchangeRates, rate)
+	
+	return nil
+}
+
+// GetTaxRates returns the jurisdiction tax-rate table
+func (s *CPQService) GetTaxRates() (*tax.Table, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	
+	if s.taxRates == nil {
+		return tax.NewTable(nil)
+	}
+	return s.taxRates, nil
+}
+
+// SetTaxRates replaces the jurisdiction tax-rate table
+func (s *CPQService) SetTaxRates(table *tax.Table) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	
+	s.taxRates = table
+	return nil
+}
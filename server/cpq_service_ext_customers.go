@@ -0,0 +1,190 @@
+// cpq_service_ext_customers.go - Customer and contract operations for CPQService
+
+package server
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"DD/cpq"
+)
+
+// Customer Operations
+
+// CreateCustomer creates a customer account
+func (s *CPQService) CreateCustomer(customer *cpq.Customer) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.customers[customer.ID]; exists {
+		return fmt.Errorf("customer with ID %s already exists", customer.ID)
+	}
+
+	now := time.Now()
+	customer.CreatedAt = now
+	customer.UpdatedAt = now
+	stored := *customer
+	s.customers[customer.ID] = &stored
+
+	return nil
+}
+
+// GetCustomer retrieves a customer account
+func (s *CPQService) GetCustomer(customerID string) (*cpq.Customer, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	customer, exists := s.customers[customerID]
+	if !exists {
+		return nil, fmt.Errorf("customer not found: %s", customerID)
+	}
+	result := *customer
+	return &result, nil
+}
+
+// ListCustomers returns all customer accounts
+func (s *CPQService) ListCustomers() ([]*cpq.Customer, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	customers := make([]*cpq.Customer, 0, len(s.customers))
+	for _, customer := range s.customers {
+		result := *customer
+		customers = append(customers, &result)
+	}
+	sort.Slice(customers, func(i, j int) bool {
+		return customers[i].Name < customers[j].Name
+	})
+
+	return customers, nil
+}
+
+// UpdateCustomer updates a customer account
+func (s *CPQService) UpdateCustomer(customer *cpq.Customer) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing, exists := s.customers[customer.ID]
+	if !exists {
+		return fmt.Errorf("customer not found: %s", customer.ID)
+	}
+
+	customer.CreatedAt = existing.CreatedAt
+	customer.UpdatedAt = time.Now()
+	stored := *customer
+	s.customers[customer.ID] = &stored
+
+	return nil
+}
+
+// DeleteCustomer deletes a customer account and its contracts
+func (s *CPQService) DeleteCustomer(customerID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.customers[customerID]; !exists {
+		return fmt.Errorf("customer not found: %s", customerID)
+	}
+	delete(s.customers, customerID)
+
+	for id, contract := range s.contracts {
+		if contract.CustomerID == customerID {
+			delete(s.contracts, id)
+		}
+	}
+
+	return nil
+}
+
+// Contract Operations
+
+// CreateContract creates a pricing contract
+func (s *CPQService) CreateContract(contract *cpq.Contract) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.customers[contract.CustomerID]; !exists {
+		return fmt.Errorf("customer not found: %s", contract.CustomerID)
+	}
+	if _, exists := s.contracts[contract.ID]; exists {
+		return fmt.Errorf("contract with ID %s already exists", contract.ID)
+	}
+
+	now := time.Now()
+	contract.CreatedAt = now
+	contract.UpdatedAt = now
+	s.contracts[contract.ID] = copyContract(contract)
+
+	return nil
+}
+
+// GetContract retrieves a pricing contract
+func (s *CPQService) GetContract(contractID string) (*cpq.Contract, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	contract, exists := s.contracts[contractID]
+	if !exists {
+		return nil, fmt.Errorf("contract not found: %s", contractID)
+	}
+	return copyContract(contract), nil
+}
+
+// ListContracts returns a customer's pricing contracts
+func (s *CPQService) ListContracts(customerID string) ([]*cpq.Contract, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	contracts := []*cpq.Contract{}
+	for _, contract := range s.contracts {
+		if contract.CustomerID == customerID {
+			contracts = append(contracts, copyContract(contract))
+		}
+	}
+	sort.Slice(contracts, func(i, j int) bool {
+		return contracts[i].EffectiveFrom.Before(contracts[j].EffectiveFrom)
+	})
+
+	return contracts, nil
+}
+
+// UpdateContract updates a pricing contract
+func (s *CPQService) UpdateContract(contract *cpq.Contract) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing, exists := s.contracts[contract.ID]
+	if !exists {
+		return fmt.Errorf("contract not found: %s", contract.ID)
+	}
+	if _, exists := s.customers[contract.CustomerID]; !exists {
+		return fmt.Errorf("customer not found: %s", contract.CustomerID)
+	}
+
+	contract.CreatedAt = existing.CreatedAt
+	contract.UpdatedAt = time.Now()
+	s.contracts[contract.ID] = copyContract(contract)
+
+	return nil
+}
+
+// DeleteContract deletes a pricing contract
+func (s *CPQService) DeleteContract(contractID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.contracts[contractID]; !exists {
+		return fmt.Errorf("contract not found: %s", contractID)
+	}
+	delete(s.contracts, contractID)
+
+	return nil
+}
+
+// copyContract copies a contract so callers cannot modify stored terms
+func copyContract(contract *cpq.Contract) *cpq.Contract {
+	result := *contract
+	result.Prices = append([]cpq.ContractPrice(nil), contract.Prices...)
+	return &result
+}
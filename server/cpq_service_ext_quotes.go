@@ -0,0 +1,110 @@
+// cpq_service_ext_quotes.go - Quote operations for CPQService
+
+package server
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"DD/cpq"
+)
+
+// Quote Operations
+
+// CreateQuote creates a quote
+func (s *CPQService) CreateQuote(quote *cpq.Quote) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.quotes[quote.ID]; exists {
+		return fmt.Errorf("quote with ID %s already exists", quote.ID)
+	}
+
+	now := time.Now()
+	quote.CreatedAt = now
+	quote.UpdatedAt = now
+	s.quotes[quote.ID] = copyQuote(quote)
+
+	return nil
+}
+
+// GetQuote retrieves a quote
+func (s *CPQService) GetQuote(quoteID string) (*cpq.Quote, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	quote, exists := s.quotes[quoteID]
+	if !exists {
+		return nil, fmt.Errorf("quote not found: %s", quoteID)
+	}
+	return copyQuote(quote), nil
+}
+
+// ListQuotes returns quotes, newest first, optionally for one customer
+func (s *CPQService) ListQuotes(customerID string) ([]*cpq.Quote, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	quotes := []*cpq.Quote{}
+	for _, quote := range s.quotes {
+		if customerID == "" || quote.CustomerID == customerID {
+			quotes = append(quotes, copyQuote(quote))
+		}
+	}
+	sort.Slice(quotes, func(i, j int) bool {
+		return quotes[i].CreatedAt.After(quotes[j].CreatedAt)
+	})
+
+	return quotes, nil
+}
+
+// UpdateQuote updates a quote and its lines
+func (s *CPQService) UpdateQuote(quote *cpq.Quote) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing, exists := s.quotes[quote.ID]
+	if !exists {
+		return fmt.Errorf("quote not found: %s", quote.ID)
+	}
+
+	quote.CreatedAt = existing.CreatedAt
+	quote.UpdatedAt = time.Now()
+	s.quotes[quote.ID] = copyQuote(quote)
+
+	return nil
+}
+
+// DeleteQuote deletes a quote
+func (s *CPQService) DeleteQuote(quoteID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.quotes[quoteID]; !exists {
+		return fmt.Errorf("quote not found: %s", quoteID)
+	}
+	delete(s.quotes, quoteID)
+
+	return nil
+}
+
+// copyQuote copies a quote so callers cannot modify stored lines or its
+// pricing snapshot
+func copyQuote(quote *cpq.Quote) *cpq.Quote {
+	result := *quote
+	if quote.Pricing != nil {
+		pricing := *quote.Pricing
+		pricing.Lines = append([]cpq.QuoteLinePrice(nil), quote.Pricing.Lines...)
+		pricing.Adjustments = append([]cpq.PriceAdjustment(nil), quote.Pricing.Adjustments...)
+		result.Pricing = &pricing
+	}
+	result.VolumeTiers = append([]cpq.VolumeTier(nil), quote.VolumeTiers...)
+	result.Lines = make([]cpq.QuoteLine, len(quote.Lines))
+	for i, line := range quote.Lines {
+		line.Selections = append([]cpq.Selection(nil), line.Selections...)
+		line.Children = append([]cpq.Configuration(nil), line.Children...)
+		result.Lines[i] = line
+	}
+	return &result
+}
@@ -12,14 +12,19 @@ import (
 	"DD/cpq"
 	"DD/modelbuilder"
 	"DD/repository"
+	"DD/tax"
 )
 
 // CPQServiceV2 provides unified access to all CPQ functionality with database support
 type CPQServiceV2 struct {
 	// Repositories
-	modelRepo  repository.ModelRepository
-	configRepo repository.ConfigurationRepository
-	cache      cache.CacheRepository
+	modelRepo    repository.ModelRepository
+	configRepo   repository.ConfigurationRepository
+	rateRepo     repository.ExchangeRateRepository
+	taxRepo      repository.TaxRateRepository
+	customerRepo repository.CustomerRepository
+	quoteRepo    repository.QuoteRepository
+	cache        cache.CacheRepository
 
 	// In-memory components for performance
 	configurators map[string]*cpq.Configurator // Model ID -> Configurator
@@ -44,11 +49,19 @@ type CPQServiceV2 struct {
 func NewCPQServiceV2(
 	modelRepo repository.ModelRepository,
 	configRepo repository.ConfigurationRepository,
+	rateRepo repository.ExchangeRateRepository,
+	taxRepo repository.TaxRateRepository,
+	customerRepo repository.CustomerRepository,
+	quoteRepo repository.QuoteRepository,
 	cacheRepo cache.CacheRepository,
 ) (*CPQServiceV2, error) {
 	service := &CPQServiceV2{
 		modelRepo:     modelRepo,
 		configRepo:    configRepo,
+		rateRepo:      rateRepo,
+		taxRepo:       taxRepo,
+		customerRepo:  customerRepo,
+		quoteRepo:     quoteRepo,
 		cache:         cacheRepo,
 		configurators: make(map[string]*cpq.Configurator),
 		modelCache:    make(map[string]*cpq.Model),
@@ -683,6 +696,131 @@ func (s *CPQServiceV2) DeleteGroup(modelID, groupID string) error {
 	return nil
 }
 
+// SetVolumeTiers replaces a model's volume tiers
+func (s *CPQServiceV2) SetVolumeTiers(modelID string, tiers []cpq.VolumeTier) error {
+	if err := s.modelRepo.SetVolumeTiers(modelID, tiers); err != nil {
+		return err
+	}
+
+	// Invalidate caches
+	s.invalidateModelCache(modelID)
+	return nil
+}
+
+// SetPriceBooks replaces a model's price books
+func (s *CPQServiceV2) SetPriceBooks(modelID string, books []cpq.PriceBook) error {
+	if err := s.modelRepo.SetPriceBooks(modelID, books); err != nil {
+		return err
+	}
+
+	// Invalidate caches
+	s.invalidateModelCache(modelID)
+	return nil
+}
+
+// GetExchangeRates returns the local exchange-rate table
+func (s *CPQServiceV2) GetExchangeRates() (cpq.ExchangeRates, error) {
+	return s.rateRepo.ListExchangeRates()
+}
+
+// SetExchangeRate stores a rate, replacing one for the same pair and date
+func (s *CPQServiceV2) SetExchangeRate(rate cpq.ExchangeRate) error {
+	return s.rateRepo.SetExchangeRate(rate)
+}
+
+// GetTaxRates returns the local jurisdiction tax-rate table
+func (s *CPQServiceV2) GetTaxRates() (*tax.Table, error) {
+	rates, err := s.taxRepo.ListTaxRates()
+	if err != nil {
+		return nil, err
+	}
+	return tax.NewTable(rates)
+}
+
+// SetTaxRates replaces the jurisdiction tax-rate table
+func (s *CPQServiceV2) SetTaxRates(table *tax.Table) error {
+	return s.taxRepo.ReplaceTaxRates(table.Rates())
+}
+
+// Customer Operations
+
+// CreateCustomer creates a customer account
+func (s *CPQServiceV2) CreateCustomer(customer *cpq.Customer) error {
+	return s.customerRepo.CreateCustomer(customer)
+}
+
+// GetCustomer retrieves a customer account
+func (s *CPQServiceV2) GetCustomer(customerID string) (*cpq.Customer, error) {
+	return s.customerRepo.GetCustomer(customerID)
+}
+
+// ListCustomers returns all customer accounts
+func (s *CPQServiceV2) ListCustomers() ([]*cpq.Customer, error) {
+	return s.customerRepo.ListCustomers()
+}
+
+// UpdateCustomer updates a customer account
+func (s *CPQServiceV2) UpdateCustomer(customer *cpq.Customer) error {
+	return s.customerRepo.UpdateCustomer(customer)
+}
+
+// DeleteCustomer deletes a customer account and its contracts
+func (s *CPQServiceV2) DeleteCustomer(customerID string) error {
+	return s.customerRepo.DeleteCustomer(customerID)
+}
+
+// CreateContract creates a pricing contract
+func (s *CPQServiceV2) CreateContract(contract *cpq.Contract) error {
+	return s.customerRepo.CreateContract(contract)
+}
+
+// GetContract retrieves a pricing contract
+func (s *CPQServiceV2) GetContract(contractID string) (*cpq.Contract, error) {
+	return s.customerRepo.GetContract(contractID)
+}
+
+// ListContracts returns a customer's pricing contracts
+func (s *CPQServiceV2) ListContracts(customerID string) ([]*cpq.Contract, error) {
+	return s.customerRepo.ListContracts(customerID)
+}
+
+// UpdateContract updates a pricing contract
+func (s *CPQServiceV2) UpdateContract(contract *cpq.Contract) error {
+	return s.customerRepo.UpdateContract(contract)
+}
+
+// DeleteContract deletes a pricing contract
+func (s *CPQServiceV2) DeleteContract(contractID string) error {
+	return s.customerRepo.DeleteContract(contractID)
+}
+
+// Quote Operations
+
+// CreateQuote creates a quote
+func (s *CPQServiceV2) CreateQuote(quote *cpq.Quote) error {
+	return s.quoteRepo.CreateQuote(quote)
+}
+
+// GetQuote retrieves a quote
+func (s *CPQServiceV2) GetQuote(quoteID string) (*cpq.Quote, error) {
+	return s.quoteRepo.GetQuote(quoteID)
+}
+
+// ListQuotes returns quotes, optionally for one customer
+func (s *CPQServiceV2) ListQuotes(customerID string) ([]*cpq.Quote, error) {
+	return s.quoteRepo.ListQuotes(customerID)
+}
+
+// UpdateQuote updates a quote and its lines
+func (s *CPQServiceV2) UpdateQuote(quote *cpq.Quote) error {
+	return s.quoteRepo.UpdateQuote(quote)
+}
+
+// DeleteQuote deletes a quote
+func (s *CPQServiceV2) DeleteQuote(quoteID string) error {
+	return s.quoteRepo.DeleteQuote(quoteID)
+}
+
 // invalidateModelCache invalidates all caches for a model
 func (s *CPQServiceV2) invalidateModelCache(modelID string) {
 	s.mutex.Lock()
@@ -0,0 +1,353 @@
+// customer_handlers.go - Customer and Contract API Endpoints
+// Manages customer accounts and the contract terms applied to their pricing
+
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"DD/cpq"
+	"DD/tax"
+	"github.com/gorilla/mux"
+)
+
+// CustomerHandlers provides HTTP handlers for customers and contracts
+type CustomerHandlers struct {
+	service CPQServiceInterface
+}
+
+// NewCustomerHandlers creates new customer handlers
+func NewCustomerHandlers(service CPQServiceInterface) *CustomerHandlers {
+	return &CustomerHandlers{
+		service: service,
+	}
+}
+
+// setupCustomerRoutes sets up all customer-related routes
+func (s *Server) setupCustomerRoutes(router *mux.Router) {
+	handlers := NewCustomerHandlers(s.cpqService)
+
+	// Customer CRUD operations
+	router.HandleFunc("", handlers.ListCustomers).Methods("GET", "OPTIONS")
+	router.HandleFunc("", handlers.CreateCustomer).Methods("POST", "OPTIONS")
+	router.HandleFunc("/{id}", handlers.GetCustomer).Methods("GET", "OPTIONS")
+	router.HandleFunc("/{id}", handlers.UpdateCustomer).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/{id}", handlers.DeleteCustomer).Methods("DELETE", "OPTIONS")
+
+	// Contract CRUD operations
+	router.HandleFunc("/{id}/contracts", handlers.ListContracts).Methods("GET", "OPTIONS")
+	router.HandleFunc("/{id}/contracts", handlers.CreateContract).Methods("POST", "OPTIONS")
+	router.HandleFunc("/{id}/contracts/{contract_id}", handlers.GetContract).Methods("GET", "OPTIONS")
+	router.HandleFunc("/{id}/contracts/{contract_id}", handlers.UpdateContract).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/{id}/contracts/{contract_id}", handlers.DeleteContract).Methods("DELETE", "OPTIONS")
+}
+
+// Customer CRUD Operations
+
+// ListCustomers lists all customer accounts
+func (h *CustomerHandlers) ListCustomers(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	customers, err := h.service.ListCustomers()
+	if err != nil {
+		WriteInternalErrorResponse(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"customers": customers,
+		"count":     len(customers),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// CreateCustomer creates a customer account
+func (h *CustomerHandlers) CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	var customer cpq.Customer
+	if err := ParseJSONRequest(r, &customer); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+
+	if errors := validateCustomer(&customer); len(errors) > 0 {
+		WriteValidationErrorResponse(w, errors)
+		return
+	}
+
+	if err := h.service.CreateCustomer(&customer); err != nil {
+		WriteErrorResponse(w, "CREATE_FAILED", "Failed to create customer", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteCreatedResponse(w, customer, meta)
+}
+
+// GetCustomer retrieves a customer account
+func (h *CustomerHandlers) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	customerID := mux.Vars(r)["id"]
+
+	customer, err := h.service.GetCustomer(customerID)
+	if err != nil {
+		WriteNotFoundResponse(w, "Customer")
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, customer, meta)
+}
+
+// UpdateCustomer updates a customer account
+func (h *CustomerHandlers) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	customerID := mux.Vars(r)["id"]
+
+	var customer cpq.Customer
+	if err := ParseJSONRequest(r, &customer); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+	customer.ID = customerID
+
+	if errors := validateCustomer(&customer); len(errors) > 0 {
+		WriteValidationErrorResponse(w, errors)
+		return
+	}
+
+	if _, err := h.service.GetCustomer(customerID); err != nil {
+		WriteNotFoundResponse(w, "Customer")
+		return
+	}
+
+	if err := h.service.UpdateCustomer(&customer); err != nil {
+		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to update customer", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, customer, meta)
+}
+
+// DeleteCustomer deletes a customer account and its contracts
+func (h *CustomerHandlers) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	customerID := mux.Vars(r)["id"]
+
+	if err := h.service.DeleteCustomer(customerID); err != nil {
+		WriteNotFoundResponse(w, "Customer")
+		return
+	}
+
+	response := map[string]interface{}{
+		"customer_id": customerID,
+		"deleted":     true,
+		"deleted_at":  time.Now().UTC(),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// Contract CRUD Operations
+
+// ListContracts lists a customer's contracts
+func (h *CustomerHandlers) ListContracts(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	customerID := mux.Vars(r)["id"]
+
+	if _, err := h.service.GetCustomer(customerID); err != nil {
+		WriteNotFoundResponse(w, "Customer")
+		return
+	}
+
+	contracts, err := h.service.ListContracts(customerID)
+	if err != nil {
+		WriteInternalErrorResponse(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"customer_id": customerID,
+		"contracts":   contracts,
+		"count":       len(contracts),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// CreateContract creates a contract for a customer
+func (h *CustomerHandlers) CreateContract(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	customerID := mux.Vars(r)["id"]
+
+	var contract cpq.Contract
+	if err := ParseJSONRequest(r, &contract); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+	contract.CustomerID = customerID
+	if contract.EffectiveFrom.IsZero() {
+		contract.EffectiveFrom = time.Now().UTC()
+	}
+
+	if _, err := h.service.GetCustomer(customerID); err != nil {
+		WriteNotFoundResponse(w, "Customer")
+		return
+	}
+
+	if !h.validateContract(w, &contract) {
+		return
+	}
+
+	if err := h.service.CreateContract(&contract); err != nil {
+		WriteErrorResponse(w, "CREATE_FAILED", "Failed to create contract", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteCreatedResponse(w, contract, meta)
+}
+
+// GetContract retrieves a customer's contract
+func (h *CustomerHandlers) GetContract(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+
+	contract, err := h.service.GetContract(vars["contract_id"])
+	if err != nil || contract.CustomerID != vars["id"] {
+		WriteNotFoundResponse(w, "Contract")
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, contract, meta)
+}
+
+// UpdateContract replaces a customer's contract terms
+func (h *CustomerHandlers) UpdateContract(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+
+	var contract cpq.Contract
+	if err := ParseJSONRequest(r, &contract); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+
+	existing, err := h.service.GetContract(vars["contract_id"])
+	if err != nil || existing.CustomerID != vars["id"] {
+		WriteNotFoundResponse(w, "Contract")
+		return
+	}
+	contract.ID = existing.ID
+	contract.CustomerID = existing.CustomerID
+	if contract.EffectiveFrom.IsZero() {
+		contract.EffectiveFrom = existing.EffectiveFrom
+	}
+
+	if !h.validateContract(w, &contract) {
+		return
+	}
+
+	if err := h.service.UpdateContract(&contract); err != nil {
+		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to update contract", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, contract, meta)
+}
+
+// DeleteContract deletes a customer's contract
+func (h *CustomerHandlers) DeleteContract(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+	contractID := vars["contract_id"]
+
+	existing, err := h.service.GetContract(contractID)
+	if err != nil || existing.CustomerID != vars["id"] {
+		WriteNotFoundResponse(w, "Contract")
+		return
+	}
+
+	if err := h.service.DeleteContract(contractID); err != nil {
+		WriteErrorResponse(w, "DELETE_FAILED", "Failed to delete contract", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"customer_id": vars["id"],
+		"contract_id": contractID,
+		"deleted":     true,
+		"deleted_at":  time.Now().UTC(),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// Helper Functions
+
+// validateCustomer checks the required customer fields
+func validateCustomer(customer *cpq.Customer) map[string]string {
+	errors := map[string]string{}
+	if customer.ID == "" {
+		errors["id"] = "Customer ID is required"
+	}
+	if customer.Name == "" {
+		errors["name"] = "Customer name is required"
+	}
+	if err := tax.ValidateExemptions(customer.TaxExemptions); err != nil {
+		errors["tax_exemptions"] = err.Error()
+	}
+	return errors
+}
+
+// validateContract validates contract terms against the model they price,
+// writing a validation error response when they are invalid
+func (h *CustomerHandlers) validateContract(w http.ResponseWriter, contract *cpq.Contract) bool {
+	var model *cpq.Model
+	if contract.ModelID != "" {
+		var err error
+		model, err = h.service.GetModel(contract.ModelID)
+		if err != nil {
+			WriteValidationErrorResponse(w, map[string]string{
+				"model_id": "Model not found",
+			})
+			return false
+		}
+	}
+
+	if err := cpq.ValidateContract(contract, model); err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"contract": err.Error(),
+		})
+		return false
+	}
+	return true
+}
@@ -5,6 +5,7 @@ package server
 import (
 	"DD/cpq"
 	"DD/modelbuilder"
+	"DD/tax"
 )
 
 // RuleChange represents a change to a rule (temporary stub for interface compatibility)
@@ -54,6 +55,33 @@ type CPQServiceInterface interface {
 	UpdateRule(modelID string, ruleID string, rule *cpq.Rule) error
 	DeleteRule(modelID string, ruleID string) error
 
+	// Pricing operations
+	SetVolumeTiers(modelID string, tiers []cpq.VolumeTier) error
+	SetPriceBooks(modelID string, books []cpq.PriceBook) error
+	GetExchangeRates() (cpq.ExchangeRates, error)
+	SetExchangeRate(rate cpq.ExchangeRate) error
+	GetTaxRates() (*tax.Table, error)
+	SetTaxRates(table *tax.Table) error
+
+	// Customer operations
+	CreateCustomer(customer *cpq.Customer) error
+	GetCustomer(customerID string) (*cpq.Customer, error)
+	ListCustomers() ([]*cpq.Customer, error)
+	UpdateCustomer(customer *cpq.Customer) error
+	DeleteCustomer(customerID string) error
+	CreateContract(contract *cpq.Contract) error
+	GetContract(contractID string) (*cpq.Contract, error)
+	ListContracts(customerID string) ([]*cpq.Contract, error)
+	UpdateContract(contract *cpq.Contract) error
+	DeleteContract(contractID string) error
+
+	// Quote operations
+	CreateQuote(quote *cpq.Quote) error
+	GetQuote(quoteID string) (*cpq.Quote, error)
+	ListQuotes(customerID string) ([]*cpq.Quote, error)
+	UpdateQuote(quote *cpq.Quote) error
+	DeleteQuote(quoteID string) error
+
 	// System operations
 	GetStats() SystemStats
 	HealthCheck() error
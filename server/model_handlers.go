@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"DD/cpq"
@@ -66,6 +67,11 @@ func (s *Server) setupModelRoutes(router *mux.Router) {
 	router.HandleFunc("/{id}/pricing-rules/{rule_id}", handlers.UpdatePricingRule).Methods("PUT", "OPTIONS")
 	router.HandleFunc("/{id}/pricing-rules/{rule_id}", handlers.DeletePricingRule).Methods("DELETE", "OPTIONS")
 
+	router.HandleFunc("/{id}/definitions", handlers.GetDefinitions).Methods("GET", "OPTIONS")
+	router.HandleFunc("/{id}/definitions", handlers.CreateDefinition).Methods("POST", "OPTIONS")
+	router.HandleFunc("/{id}/definitions/{definition_id}", handlers.UpdateDefinition).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/{id}/definitions/{definition_id}", handlers.DeleteDefinition).Methods("DELETE", "OPTIONS")
+
 	router.HandleFunc("/{id}/statistics", handlers.GetModelStatistics).Methods("GET", "OPTIONS")
 
 	// Model building tools
@@ -179,6 +185,14 @@ func (h *ModelHandlers) CreateModel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Sub-assemblies must be stored models that do not contain this one
+	if err := validateModelTree(h.service, &model); err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"options": err.Error(),
+		})
+		return
+	}
+
 	// Get userID from context if available (set by auth middleware)
 	userID := ""
 	if claims := r.Context().Value("user_claims"); claims != nil {
@@ -263,6 +277,25 @@ func (h *ModelHandlers) UpdateModel(w http.ResponseWriter, r *http.Request) {
 	// Ensure ID matches
 	updatedModel.ID = modelID
 
+	if err := validateModelTree(h.service, &updatedModel); err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"options": err.Error(),
+		})
+		return
+	}
+
+	// Models using this one as a sub-assembly must still hold with the update
+	models, err := loadModels(h.service, &updatedModel)
+	if err != nil {
+		WriteInternalErrorResponse(w, err)
+		return
+	}
+	if err := cpq.ValidateParentTrees(models, modelID); err != nil {
+		WriteErrorResponse(w, "PARENT_MODEL_INVALID", "Update would invalidate models using this one as a sub-assembly",
+			err.Error(), http.StatusConflict)
+		return
+	}
+
 	// Update model in service
 	if err := h.service.UpdateModel(modelID, &updatedModel); err != nil {
 		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to update model", err.Error(), http.StatusBadRequest)
@@ -300,6 +333,18 @@ func (h *ModelHandlers) DeleteModel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A deleted sub-assembly model would leave its parents unconfigurable
+	models, err := loadModels(h.service, nil)
+	if err != nil {
+		WriteInternalErrorResponse(w, err)
+		return
+	}
+	if used := cpq.ModelParents(models)[modelID]; len(used) > 0 {
+		WriteErrorResponse(w, "MODEL_IN_USE", "Model is used as a sub-assembly",
+			fmt.Sprintf("used by: %s", strings.Join(used, ", ")), http.StatusConflict)
+		return
+	}
+
 	// Delete model from service
 	if err := h.service.DeleteModel(modelID); err != nil {
 		WriteErrorResponse(w, "DELETE_FAILED", "Failed to delete model", err.Error(), http.StatusBadRequest)
@@ -1070,6 +1115,12 @@ func (h *ModelHandlers) CreateOption(w http.ResponseWriter, r *http.Request) {
 
 	// Add option to model (in production, you'd update the stored model)
 	model.Options = append(model.Options, option)
+	if err := validateModelTree(h.service, model); err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"child_model_id": err.Error(),
+		})
+		return
+	}
 	if err := h.service.UpdateModel(modelID, model); err != nil {
 		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to add option", err.Error(), http.StatusBadRequest)
 		return
@@ -1166,6 +1217,9 @@ func (h *ModelHandlers) UpdateOption(w http.ResponseWriter, r *http.Request) {
 	if sku, ok := updateData["sku"].(string); ok {
 		updatedOption.SKU = sku
 	}
+	if childModelID, ok := updateData["child_model_id"].(string); ok {
+		updatedOption.ChildModelID = childModelID
+	}
 	
 	// Handle attributes as a map
 	if attrs, ok := updateData["attributes"].(map[string]interface{}); ok {
@@ -1174,6 +1228,23 @@ func (h *ModelHandlers) UpdateOption(w http.ResponseWriter, r *http.Request) {
 	
 	log.Printf("Updated option: %+v", updatedOption)
 	
+	// Check the sub-assembly the option leads to
+	if updatedOption.ChildModelID != existingOption.ChildModelID {
+		updated := *model
+		updated.Options = append([]cpq.Option(nil), model.Options...)
+		for i := range updated.Options {
+			if updated.Options[i].ID == optionID {
+				updated.Options[i] = updatedOption
+			}
+		}
+		if err := validateModelTree(h.service, &updated); err != nil {
+			WriteValidationErrorResponse(w, map[string]string{
+				"child_model_id": err.Error(),
+			})
+			return
+		}
+	}
+	
 	// Update option using the service method
 	if err := h.service.UpdateOption(modelID, optionID, &updatedOption); err != nil {
 		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to update option", err.Error(), http.StatusBadRequest)
@@ -1655,6 +1726,267 @@ func (h *ModelHandlers) DeletePricingRule(w http.ResponseWriter, r *http.Request
 	WriteSuccessResponse(w, response, meta)
 }
 
+// Definition Operations
+
+// definitionRequest accepts a definition as fields or as `let id = expression` source
+type definitionRequest struct {
+	cpq.Definition
+	Source string `json:"source,omitempty"`
+}
+
+// toDefinition resolves the request into a definition, parsing source when given
+func (req *definitionRequest) toDefinition() (cpq.Definition, error) {
+	if req.Source == "" {
+		return req.Definition, nil
+	}
+
+	definition, err := cpq.ParseDefinitionSource(req.Source)
+	if err != nil {
+		return cpq.Definition{}, err
+	}
+	definition.Name = req.Name
+	definition.Description = req.Description
+	if definition.Name == "" {
+		definition.Name = definition.ID
+	}
+	return definition, nil
+}
+
+// GetDefinitions retrieves a model's named definitions
+func (h *ModelHandlers) GetDefinitions(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	modelID, err := ExtractPathParam(r, "id")
+	if err != nil {
+		WriteBadRequestResponse(w, "Missing model ID")
+		return
+	}
+
+	model, err := h.service.GetModel(modelID)
+	if err != nil {
+		WriteNotFoundResponse(w, "Model")
+		return
+	}
+
+	response := map[string]interface{}{
+		"model_id":    modelID,
+		"definitions": model.Definitions,
+		"count":       len(model.Definitions),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// CreateDefinition adds a named definition to a model
+func (h *ModelHandlers) CreateDefinition(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	modelID, err := ExtractPathParam(r, "id")
+	if err != nil {
+		WriteBadRequestResponse(w, "Missing model ID")
+		return
+	}
+
+	var req definitionRequest
+	if err := ParseJSONRequest(r, &req); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+
+	definition, err := req.toDefinition()
+	if err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"source": err.Error(),
+		})
+		return
+	}
+
+	// Validate required fields
+	if definition.ID == "" {
+		WriteValidationErrorResponse(w, map[string]string{
+			"id": "Definition ID is required",
+		})
+		return
+	}
+
+	if definition.Expression == "" {
+		WriteValidationErrorResponse(w, map[string]string{
+			"expression": "Definition expression is required",
+		})
+		return
+	}
+
+	// Get the model
+	model, err := h.service.GetModel(modelID)
+	if err != nil {
+		WriteNotFoundResponse(w, "Model")
+		return
+	}
+
+	// Add definition to a copy and check the set still parses without cycles;
+	// the service may hand out its cached model
+	updated := *model
+	updated.Definitions = append(append([]cpq.Definition(nil), model.Definitions...), definition)
+	if _, err := updated.ParseDefinitions(); err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"expression": err.Error(),
+		})
+		return
+	}
+
+	if err := h.service.UpdateModel(modelID, &updated); err != nil {
+		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to add definition", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{
+		"model_id":   modelID,
+		"definition": definition,
+		"created":    true,
+		"created_at": time.Now().UTC(),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteCreatedResponse(w, response, meta)
+}
+
+// UpdateDefinition updates an existing named definition
+func (h *ModelHandlers) UpdateDefinition(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	modelID, err := ExtractPathParam(r, "id")
+	if err != nil {
+		WriteBadRequestResponse(w, "Missing model ID")
+		return
+	}
+
+	definitionID, err := ExtractPathParam(r, "definition_id")
+	if err != nil {
+		WriteBadRequestResponse(w, "Missing definition ID")
+		return
+	}
+
+	var req definitionRequest
+	if err := ParseJSONRequest(r, &req); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+
+	updatedDefinition, err := req.toDefinition()
+	if err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"source": err.Error(),
+		})
+		return
+	}
+
+	// Ensure ID matches; renaming would break references
+	if updatedDefinition.ID != "" && updatedDefinition.ID != definitionID {
+		WriteValidationErrorResponse(w, map[string]string{
+			"id": "Definition ID cannot be changed",
+		})
+		return
+	}
+	updatedDefinition.ID = definitionID
+
+	// Get the model
+	model, err := h.service.GetModel(modelID)
+	if err != nil {
+		WriteNotFoundResponse(w, "Model")
+		return
+	}
+
+	// Update the definition on a copy of the model; the service may hand out
+	// its cached model
+	if _, err := model.GetDefinition(definitionID); err != nil {
+		WriteNotFoundResponse(w, "Definition")
+		return
+	}
+
+	updated := *model
+	if err := updated.UpdateDefinition(updatedDefinition); err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"expression": err.Error(),
+		})
+		return
+	}
+
+	// Update model in service
+	if err := h.service.UpdateModel(modelID, &updated); err != nil {
+		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to update", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{
+		"model_id":   modelID,
+		"definition": updatedDefinition,
+		"updated":    true,
+		"updated_at": time.Now().UTC(),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// DeleteDefinition deletes a named definition that nothing references
+func (h *ModelHandlers) DeleteDefinition(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	modelID, err := ExtractPathParam(r, "id")
+	if err != nil {
+		WriteBadRequestResponse(w, "Missing model ID")
+		return
+	}
+
+	definitionID, err := ExtractPathParam(r, "definition_id")
+	if err != nil {
+		WriteBadRequestResponse(w, "Missing definition ID")
+		return
+	}
+
+	// Get the model
+	model, err := h.service.GetModel(modelID)
+	if err != nil {
+		WriteNotFoundResponse(w, "Model")
+		return
+	}
+
+	// Remove the definition from a copy of the model
+	updated := *model
+	if err := updated.RemoveDefinition(definitionID); err != nil {
+		WriteNotFoundResponse(w, "Definition")
+		return
+	}
+
+	// A removed definition would silently turn references into free variables
+	if references := updated.DefinitionReferences(definitionID); len(references) > 0 {
+		WriteErrorResponse(w, "DEFINITION_IN_USE", "Definition is still referenced",
+			fmt.Sprintf("referenced by: %s", strings.Join(references, ", ")), http.StatusConflict)
+		return
+	}
+
+	// Update model in service
+	if err := h.service.UpdateModel(modelID, &updated); err != nil {
+		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to update", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{
+		"model_id":      modelID,
+		"definition_id": definitionID,
+		"deleted":       true,
+		"deleted_at":    time.Now().UTC(),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
 // Helper Functions
 
 // getModelStatistics generates model usage statistics
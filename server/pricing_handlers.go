@@ -4,11 +4,14 @@
 package server
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"DD/cpq"
+	"DD/tax"
 	"github.com/gorilla/mux"
 )
 
@@ -32,11 +35,19 @@ func (s *Server) setupPricingRoutes(router *mux.Router) {
 	router.HandleFunc("/calculate", handlers.CalculatePrice).Methods("POST", "OPTIONS")
 	router.HandleFunc("/simulate", handlers.SimulatePricing).Methods("POST", "OPTIONS")
 	router.HandleFunc("/validate", handlers.ValidatePricing).Methods("POST", "OPTIONS")
+	router.HandleFunc("/assembly", handlers.CalculateAssemblyPrice).Methods("POST", "OPTIONS")
 
 	// Pricing structure queries
 	router.HandleFunc("/rules/{model_id}", handlers.GetPriceRules).Methods("GET", "OPTIONS")
 	router.HandleFunc("/volume-tiers", handlers.GetVolumeTiers).Methods("GET", "OPTIONS")
 	router.HandleFunc("/volume-tiers/{model_id}", handlers.GetModelVolumeTiers).Methods("GET", "OPTIONS")
+	router.HandleFunc("/volume-tiers/{model_id}", handlers.SetModelVolumeTiers).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/price-books/{model_id}", handlers.GetPriceBooks).Methods("GET", "OPTIONS")
+	router.HandleFunc("/price-books/{model_id}", handlers.SetPriceBooks).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/exchange-rates", handlers.GetExchangeRates).Methods("GET", "OPTIONS")
+	router.HandleFunc("/exchange-rates", handlers.SetExchangeRate).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/tax-rates", handlers.GetTaxRates).Methods("GET", "OPTIONS")
+	router.HandleFunc("/tax-rates", handlers.ImportTaxRates).Methods("PUT", "OPTIONS")
 
 	// Bulk pricing operations
 	router.HandleFunc("/bulk-calculate", handlers.BulkCalculate).Methods("POST", "OPTIONS")
@@ -84,6 +95,13 @@ func (h *PricingHandlers) CalculatePrice(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if err := h.applyPricingContext(configurator, req.PricingContext); err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"pricing_context": err.Error(),
+		})
+		return
+	}
+
 	// Apply selections
 	for _, selection := range req.Selections {
 		_, err := configurator.AddSelection(selection.OptionID, selection.Quantity)
@@ -96,25 +114,50 @@ func (h *PricingHandlers) CalculatePrice(w http.ResponseWriter, r *http.Request)
 	// Calculate pricing
 	pricing := configurator.GetDetailedPrice()
 
-	// Apply customer-specific context if provided
+	// Apply customer contract terms if provided
 	if req.CustomerID != "" {
-		pricing = h.applyCustomerPricing(pricing, req.CustomerID, req.Context)
+		pricing, err = h.applyCustomerPricing(pricing, req.CustomerID, req.ModelID, configurator.GetPricingContext().Date)
+		if errors.Is(err, cpq.ErrCustomerNotFound) {
+			WriteNotFoundResponse(w, "Customer")
+			return
+		}
+		if err != nil {
+			WriteValidationErrorResponse(w, map[string]string{
+				"customer_id": err.Error(),
+			})
+			return
+		}
 	}
 
-	// Convert to PricingResult
-	pricingResult := &cpq.PricingResult{
-		BasePrice:   pricing.BasePrice,
-		Adjustments: pricing.Adjustments,
-		TotalPrice:  pricing.TotalPrice,
-		Breakdown:   &pricing,
+	// Apply the requested discount and check margin floors
+	pricing, err = cpq.ApplyDiscount(model, pricing, req.DiscountPercent)
+	if err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"discount_percent": err.Error(),
+		})
+		return
 	}
 
-	response := &PricingResponse{
-		Breakdown: pricingResult,
-		Total:     pricing.TotalPrice,
-		Currency:  "USD",
-		Timestamp: time.Now().UTC(),
+	// Add tax at the ship-to address, if any
+	pricing, err = h.applyTax(model, configurator, pricing, req.CustomerID)
+	if errors.Is(err, cpq.ErrCustomerNotFound) {
+		WriteNotFoundResponse(w, "Customer")
+		return
 	}
+	if errors.Is(err, cpq.ErrCustomerInactive) {
+		WriteValidationErrorResponse(w, map[string]string{
+			"customer_id": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"ship_to": err.Error(),
+		})
+		return
+	}
+
+	response := NewPricingResponse(cpq.NewPricingResult(pricing))
 
 	duration := timer()
 	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
@@ -170,7 +213,7 @@ func (h *PricingHandlers) SimulatePricing(w http.ResponseWriter, r *http.Request
 		}
 
 		// Apply selections
-		var pricing cpq.PriceBreakdown
+		var pricing, listPricing cpq.PriceBreakdown
 		selectionSuccess := true
 		var selectionError string
 
@@ -185,21 +228,38 @@ func (h *PricingHandlers) SimulatePricing(w http.ResponseWriter, r *http.Request
 
 		if selectionSuccess {
 			pricing = configurator.GetDetailedPrice()
+			listPricing = pricing
 
-			// Apply customer-specific pricing if provided
+			// Apply customer contract terms if provided
 			if scenario.CustomerID != "" {
-				pricing = h.applyCustomerPricing(pricing, scenario.CustomerID, scenario.Context)
+				pricing, err = h.applyCustomerPricing(pricing, scenario.CustomerID, req.ModelID, configurator.GetPricingContext().Date)
+				if err != nil {
+					selectionSuccess = false
+					selectionError = err.Error()
+				}
+			}
+		}
+
+		// Apply the requested discount; a blocked discount still reports
+		// the margin it would have left
+		if selectionSuccess {
+			pricing, err = cpq.ApplyDiscount(model, pricing, scenario.DiscountPercent)
+			if err != nil {
+				selectionSuccess = false
+				selectionError = err.Error()
 			}
 		}
 
 		results[i] = map[string]interface{}{
-			"scenario_id": fmt.Sprintf("scenario_%d", i+1),
-			"success":     selectionSuccess,
-			"error":       selectionError,
-			"selections":  scenario.Selections,
-			"pricing":     pricing,
-			"total_price": pricing.TotalPrice,
-			"customer_id": scenario.CustomerID,
+			"scenario_id":      fmt.Sprintf("scenario_%d", i+1),
+			"success":          selectionSuccess,
+			"error":            selectionError,
+			"selections":       scenario.Selections,
+			"pricing":          pricing,
+			"total_price":      pricing.TotalPrice,
+			"customer_id":      scenario.CustomerID,
+			"discount_percent": scenario.DiscountPercent,
+			"margin":           marginImpact(listPricing, pricing),
 		}
 	}
 
@@ -253,6 +313,8 @@ func (h *PricingHandlers) ValidatePricing(w http.ResponseWriter, r *http.Request
 	isValid := true
 	validationErrors := []string{}
 	var priceBreakdown *cpq.PriceBreakdown
+	var listPricing cpq.PriceBreakdown
+	var margin map[string]interface{}
 
 	// Create configurator to test the configuration
 	configurator, err := cpq.NewConfigurator(model)
@@ -272,13 +334,28 @@ func (h *PricingHandlers) ValidatePricing(w http.ResponseWriter, r *http.Request
 		// If valid, calculate pricing breakdown
 		if isValid {
 			pricing := configurator.GetDetailedPrice()
+			listPricing = pricing
 			priceBreakdown = &pricing
 
-			// Apply customer-specific pricing if provided
+			// Apply customer contract terms if provided
 			if req.CustomerID != "" {
-				*priceBreakdown = h.applyCustomerPricing(*priceBreakdown, req.CustomerID, req.Context)
+				*priceBreakdown, err = h.applyCustomerPricing(*priceBreakdown, req.CustomerID, req.ModelID, configurator.GetPricingContext().Date)
+				if err != nil {
+					isValid = false
+					validationErrors = append(validationErrors, fmt.Sprintf("Contract pricing failed: %v", err))
+				}
 			}
 		}
+
+		// Apply the requested discount and check margin floors
+		if isValid {
+			*priceBreakdown, err = cpq.ApplyDiscount(model, *priceBreakdown, req.DiscountPercent)
+			if err != nil {
+				isValid = false
+				validationErrors = append(validationErrors, fmt.Sprintf("Discount rejected: %v", err))
+			}
+			margin = marginImpact(listPricing, *priceBreakdown)
+		}
 	}
 
 	validationResult := map[string]interface{}{
@@ -291,6 +368,7 @@ func (h *PricingHandlers) ValidatePricing(w http.ResponseWriter, r *http.Request
 		"is_valid":          isValid,
 		"validation_result": validationResult,
 		"price_breakdown":   priceBreakdown,
+		"margin":            margin,
 		"model_id":          req.ModelID,
 		"validated_at":      time.Now().UTC(),
 	}
@@ -302,19 +380,41 @@ func (h *PricingHandlers) ValidatePricing(w http.ResponseWriter, r *http.Request
 
 // Helper Functions
 
-// applyCustomerPricing applies customer-specific pricing modifications
-func (h *PricingHandlers) applyCustomerPricing(pricing cpq.PriceBreakdown, customerID string, context map[string]interface{}) cpq.PriceBreakdown {
-	// In production, this would look up customer-specific pricing rules
-	// For demo, apply a sample customer discount
-	if customerID == "enterprise_customer" {
-		pricing.Adjustments = append(pricing.Adjustments, cpq.PriceAdjustment{
-			RuleName: "Enterprise Customer Discount",
-			Amount:   -pricing.BasePrice * 0.05, // 5% discount
-		})
-		pricing.TotalPrice = pricing.BasePrice + pricing.BasePrice*-0.05
+// applyCustomerPricing applies the customer's contract for a model that is
+// active on the pricing date, a zero date meaning now, on top of list
+// pricing. Customers without a contract pay list price.
+func (h *PricingHandlers) applyCustomerPricing(pricing cpq.PriceBreakdown, customerID, modelID string, date time.Time) (cpq.PriceBreakdown, error) {
+	if _, err := cpq.PricingCustomer(h.service.GetCustomer, customerID); err != nil {
+		return pricing, err
 	}
 
-	return pricing
+	contracts, err := h.service.ListContracts(customerID)
+	if err != nil {
+		return pricing, fmt.Errorf("failed to load contracts: %w", err)
+	}
+
+	if date.IsZero() {
+		date = time.Now()
+	}
+	contract := cpq.ActiveContract(contracts, modelID, date)
+	if contract == nil {
+		return pricing, nil
+	}
+	return cpq.ApplyContract(pricing, contract)
+}
+
+// marginImpact reports the margin of a scenario and its change from list
+// pricing, in percentage points
+func marginImpact(list, pricing cpq.PriceBreakdown) map[string]interface{} {
+	return map[string]interface{}{
+		"total_cost":          pricing.TotalCost,
+		"margin":              pricing.Margin,
+		"margin_percent":      pricing.MarginPercent,
+		"list_margin_percent": list.MarginPercent,
+		"margin_impact":       pricing.MarginPercent - list.MarginPercent,
+		"violations":          pricing.MarginViolations,
+		"requires_approval":   pricing.RequiresApproval,
+	}
 }
 
 // generatePricingComparison generates comparison analysis between scenarios
@@ -486,9 +586,8 @@ func (h *PricingHandlers) GetModelVolumeTiers(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Get effective volume tiers for this model
-	// For now, return global tiers - in production, this would merge model-specific rules
-	effectiveTiers := h.getGlobalVolumeTiers()
+	// Models without tiers of their own are priced with the default tiers
+	effectiveTiers := cpq.EffectiveVolumeTiers(model)
 
 	// Extract volume-based pricing rules for reference
 	volumeRules := []cpq.PriceRule{}
@@ -503,7 +602,302 @@ func (h *PricingHandlers) GetModelVolumeTiers(w http.ResponseWriter, r *http.Req
 		"model_id":     modelID,
 		"tiers":        effectiveTiers,      // Changed from "base_tiers"
 		"tier_count":   len(effectiveTiers), // Changed from "rule_count"
-		"volume_rules": volumeRules,         // Keep for reference
+		"is_default":   len(model.VolumeTiers) == 0,
+		"volume_rules": volumeRules, // Keep for reference
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// GetPriceBooks retrieves the price books of a model
+func (h *PricingHandlers) GetPriceBooks(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+	modelID := vars["model_id"]
+
+	model, err := h.service.GetModel(modelID)
+	if err != nil {
+		WriteNotFoundResponse(w, "Model")
+		return
+	}
+
+	response := map[string]interface{}{
+		"model_id":     modelID,
+		"currency":     model.BaseCurrency(),
+		"price_books":  model.PriceBooks,
+		"count":        len(model.PriceBooks),
+		"default_book": "",
+	}
+	if book := model.DefaultPriceBook(); book != nil {
+		response["default_book"] = book.ID
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// SetPriceBooks replaces the price books of a model
+func (h *PricingHandlers) SetPriceBooks(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+	modelID := vars["model_id"]
+
+	var req PriceBooksRequest
+	if err := ParseJSONRequest(r, &req); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+
+	model, err := h.service.GetModel(modelID)
+	if err != nil {
+		WriteNotFoundResponse(w, "Model")
+		return
+	}
+
+	if err := cpq.ValidatePriceBooks(model, req.PriceBooks); err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"price_books": err.Error(),
+		})
+		return
+	}
+
+	if err := h.service.SetPriceBooks(modelID, req.PriceBooks); err != nil {
+		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to update price books", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"model_id":    modelID,
+		"price_books": req.PriceBooks,
+		"count":       len(req.PriceBooks),
+		"updated_at":  time.Now().UTC(),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// GetExchangeRates retrieves the local exchange-rate table
+func (h *PricingHandlers) GetExchangeRates(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	rates, err := h.service.GetExchangeRates()
+	if err != nil {
+		WriteErrorResponse(w, "RATES_FAILED", "Failed to load exchange rates", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"rates": rates,
+		"count": len(rates),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// SetExchangeRate stores an exchange rate from its effective date
+func (h *PricingHandlers) SetExchangeRate(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	var rate cpq.ExchangeRate
+	if err := ParseJSONRequest(r, &rate); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+
+	errors := map[string]string{}
+	if len(rate.FromCurrency) != 3 {
+		errors["from_currency"] = "Currency must be a three-letter code"
+	}
+	if len(rate.ToCurrency) != 3 {
+		errors["to_currency"] = "Currency must be a three-letter code"
+	}
+	if rate.Rate <= 0 {
+		errors["rate"] = "Rate must be positive"
+	}
+	if len(errors) > 0 {
+		WriteValidationErrorResponse(w, errors)
+		return
+	}
+	if rate.EffectiveFrom.IsZero() {
+		rate.EffectiveFrom = time.Now().UTC()
+	}
+
+	if err := h.service.SetExchangeRate(rate); err != nil {
+		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to store exchange rate", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, rate, meta)
+}
+
+// applyPricingContext prices a configurator in a requested context using
+// the stored exchange and tax rates; a nil context keeps the model's defaults
+func (h *PricingHandlers) applyPricingContext(configurator *cpq.Configurator, ctx *cpq.PricingContext) error {
+	if ctx == nil {
+		return nil
+	}
+
+	withRates, err := h.pricingContext(ctx)
+	if err != nil {
+		return err
+	}
+	return configurator.SetPricingContext(withRates)
+}
+
+// pricingContext returns a requested pricing context with the stored
+// exchange and tax rates; nil requests base pricing
+func (h *PricingHandlers) pricingContext(ctx *cpq.PricingContext) (cpq.PricingContext, error) {
+	if ctx == nil {
+		return cpq.PricingContext{}, nil
+	}
+
+	rates, err := h.service.GetExchangeRates()
+	if err != nil {
+		return cpq.PricingContext{}, fmt.Errorf("failed to load exchange rates: %w", err)
+	}
+	withRates := *ctx
+	withRates.Rates = rates
+
+	if withRates.ShipTo != nil {
+		withRates.TaxRates, err = h.service.GetTaxRates()
+		if err != nil {
+			return cpq.PricingContext{}, fmt.Errorf("failed to load tax rates: %w", err)
+		}
+	}
+	return withRates, nil
+}
+
+// applyTax adds tax at the configurator's ship-to address to a final price,
+// honouring the customer's exemptions
+func (h *PricingHandlers) applyTax(model *cpq.Model, configurator *cpq.Configurator, pricing cpq.PriceBreakdown, customerID string) (cpq.PriceBreakdown, error) {
+	ctx := configurator.GetPricingContext()
+	if ctx.ShipTo == nil {
+		return pricing, nil
+	}
+
+	var exemptions []tax.Exemption
+	if customerID != "" {
+		customer, err := cpq.PricingCustomer(h.service.GetCustomer, customerID)
+		if err != nil {
+			return pricing, err
+		}
+		exemptions = customer.TaxExemptions
+	}
+	return cpq.ApplyTax(model, pricing, ctx, exemptions)
+}
+
+// GetTaxRates retrieves the local jurisdiction tax-rate table, as JSON or,
+// with format=csv, in the CSV import format
+func (h *PricingHandlers) GetTaxRates(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	table, err := h.service.GetTaxRates()
+	if err != nil {
+		WriteErrorResponse(w, "RATES_FAILED", "Failed to load tax rates", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		var buf bytes.Buffer
+		if err := tax.WriteCSV(&buf, table); err != nil {
+			WriteErrorResponse(w, "RATES_FAILED", "Failed to export tax rates", err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="tax_rates.csv"`)
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+		return
+	}
+
+	rates := table.Rates()
+	response := map[string]interface{}{
+		"rates": rates,
+		"count": len(rates),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// ImportTaxRates replaces the tax-rate table with one imported from a CSV
+// request body
+func (h *PricingHandlers) ImportTaxRates(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	table, err := tax.ReadCSV(r.Body)
+	if err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"tax_rates": err.Error(),
+		})
+		return
+	}
+
+	if err := h.service.SetTaxRates(table); err != nil {
+		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to store tax rates", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"imported":    len(table.Rates()),
+		"imported_at": time.Now().UTC(),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// SetModelVolumeTiers replaces the volume tiers of a model
+func (h *PricingHandlers) SetModelVolumeTiers(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+	modelID := vars["model_id"]
+
+	var req VolumeTiersRequest
+	if err := ParseJSONRequest(r, &req); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+
+	model, err := h.service.GetModel(modelID)
+	if err != nil {
+		WriteNotFoundResponse(w, "Model")
+		return
+	}
+
+	if err := cpq.ValidateVolumeTiers(model, req.Tiers); err != nil {
+		WriteValidationErrorResponse(w, map[string]string{
+			"tiers": err.Error(),
+		})
+		return
+	}
+
+	if err := h.service.SetVolumeTiers(modelID, req.Tiers); err != nil {
+		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to update volume tiers", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	effectiveTiers := cpq.EffectiveVolumeTiers(&cpq.Model{VolumeTiers: req.Tiers})
+	response := map[string]interface{}{
+		"model_id":   modelID,
+		"tiers":      effectiveTiers,
+		"tier_count": len(effectiveTiers),
+		"is_default": len(req.Tiers) == 0,
+		"updated_at": time.Now().UTC(),
 	}
 
 	duration := timer()
@@ -561,6 +955,7 @@ func (h *PricingHandlers) BulkCalculate(w http.ResponseWriter, r *http.Request)
 	// Calculate summary statistics
 	successCount := 0
 	totalValue := 0.0
+	totalOneTime, totalMRR, totalARR, totalContractValue := 0.0, 0.0, 0.0, 0.0
 
 	for _, result := range results {
 		if result["success"].(bool) {
@@ -568,17 +963,33 @@ func (h *PricingHandlers) BulkCalculate(w http.ResponseWriter, r *http.Request)
 			if total, ok := result["total_price"].(float64); ok {
 				totalValue += total
 			}
+			if oneTime, ok := result["one_time_total"].(float64); ok {
+				totalOneTime += oneTime
+			}
+			if mrr, ok := result["mrr"].(float64); ok {
+				totalMRR += mrr
+			}
+			if arr, ok := result["arr"].(float64); ok {
+				totalARR += arr
+			}
+			if tcv, ok := result["total_contract_value"].(float64); ok {
+				totalContractValue += tcv
+			}
 		}
 	}
 
 	response := map[string]interface{}{
 		"results": results,
 		"summary": map[string]interface{}{
-			"total_requests":      len(req.Requests),
-			"successful_requests": successCount,
-			"failed_requests":     len(req.Requests) - successCount,
-			"total_value":         totalValue,
-			"parallel_processing": req.Parallel,
+			"total_requests":       len(req.Requests),
+			"successful_requests":  successCount,
+			"failed_requests":      len(req.Requests) - successCount,
+			"total_value":          totalValue,
+			"total_one_time":       totalOneTime,
+			"total_mrr":            totalMRR,
+			"total_arr":            totalARR,
+			"total_contract_value": totalContractValue,
+			"parallel_processing":  req.Parallel,
 		},
 		"processed_at": time.Now().UTC(),
 	}
@@ -752,6 +1163,14 @@ func (h *PricingHandlers) processSinglePricingRequest(req PricingRequest, index
 		}
 	}
 
+	if err := h.applyPricingContext(configurator, req.PricingContext); err != nil {
+		return map[string]interface{}{
+			"index":   index,
+			"success": false,
+			"error":   fmt.Sprintf("Invalid pricing context: %v", err),
+		}
+	}
+
 	// Apply selections
 	for _, selection := range req.Selections {
 		_, err := configurator.AddSelection(selection.OptionID, selection.Quantity)
@@ -767,19 +1186,52 @@ func (h *PricingHandlers) processSinglePricingRequest(req PricingRequest, index
 	// Calculate pricing
 	pricing := configurator.GetDetailedPrice()
 
-	// Apply customer pricing if specified
+	// Apply customer contract terms if specified
 	if req.CustomerID != "" {
-		pricing = h.applyCustomerPricing(pricing, req.CustomerID, req.Context)
+		pricing, err = h.applyCustomerPricing(pricing, req.CustomerID, req.ModelID, configurator.GetPricingContext().Date)
+		if err != nil {
+			return map[string]interface{}{
+				"index":   index,
+				"success": false,
+				"error":   fmt.Sprintf("Contract pricing failed: %v", err),
+			}
+		}
+	}
+
+	// Apply the requested discount and check margin floors
+	pricing, err = cpq.ApplyDiscount(model, pricing, req.DiscountPercent)
+	if err != nil {
+		return map[string]interface{}{
+			"index":   index,
+			"success": false,
+			"error":   fmt.Sprintf("Discount rejected: %v", err),
+		}
+	}
+
+	pricing, err = h.applyTax(model, configurator, pricing, req.CustomerID)
+	if err != nil {
+		return map[string]interface{}{
+			"index":   index,
+			"success": false,
+			"error":   fmt.Sprintf("Tax calculation failed: %v", err),
+		}
 	}
 
 	return map[string]interface{}{
-		"index":       index,
-		"success":     true,
-		"model_id":    req.ModelID,
-		"selections":  req.Selections,
-		"pricing":     pricing,
-		"total_price": pricing.TotalPrice,
-		"customer_id": req.CustomerID,
+		"index":                index,
+		"success":              true,
+		"model_id":             req.ModelID,
+		"selections":           req.Selections,
+		"pricing":              pricing,
+		"total_price":          pricing.TotalPrice,
+		"one_time_total":       pricing.OneTimeTotal,
+		"mrr":                  pricing.MRR,
+		"arr":                  pricing.ARR,
+		"total_contract_value": pricing.TotalContractValue,
+		"margin_percent":       pricing.MarginPercent,
+		"requires_approval":    pricing.RequiresApproval,
+		"currency":             pricing.Currency,
+		"customer_id":          req.CustomerID,
 	}
 }
 
@@ -0,0 +1,531 @@
+// quote_handlers.go - Quote API Endpoints
+// Manages multi-line quotes of configured products and plain SKUs
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"DD/cpq"
+	"github.com/gorilla/mux"
+)
+
+// defaultQuoteValidDays is how long a new quote is valid when no validity
+// date is given
+const defaultQuoteValidDays = 30
+
+// QuoteHandlers provides HTTP handlers for quotes
+type QuoteHandlers struct {
+	service CPQServiceInterface
+}
+
+// NewQuoteHandlers creates new quote handlers
+func NewQuoteHandlers(service CPQServiceInterface) *QuoteHandlers {
+	return &QuoteHandlers{
+		service: service,
+	}
+}
+
+// setupQuoteRoutes sets up all quote-related routes
+func (s *Server) setupQuoteRoutes(router *mux.Router) {
+	handlers := NewQuoteHandlers(s.cpqService)
+
+	// Quote CRUD operations
+	router.HandleFunc("", handlers.ListQuotes).Methods("GET", "OPTIONS")
+	router.HandleFunc("", handlers.CreateQuote).Methods("POST", "OPTIONS")
+	router.HandleFunc("/{id}", handlers.GetQuote).Methods("GET", "OPTIONS")
+	router.HandleFunc("/{id}", handlers.UpdateQuote).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/{id}", handlers.DeleteQuote).Methods("DELETE", "OPTIONS")
+
+	// Line operations
+	router.HandleFunc("/{id}/lines", handlers.AddLine).Methods("POST", "OPTIONS")
+	router.HandleFunc("/{id}/lines/{line_id}", handlers.UpdateLine).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/{id}/lines/{line_id}", handlers.DeleteLine).Methods("DELETE", "OPTIONS")
+
+	// Pricing and status
+	router.HandleFunc("/{id}/pricing", handlers.PriceQuote).Methods("GET", "OPTIONS")
+	router.HandleFunc("/{id}/send", handlers.transition(cpq.QuoteSent)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/{id}/accept", handlers.transition(cpq.QuoteAccepted)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/{id}/decline", handlers.transition(cpq.QuoteDeclined)).Methods("POST", "OPTIONS")
+	router.HandleFunc("/{id}/revise", handlers.transition(cpq.QuoteDraft)).Methods("POST", "OPTIONS")
+}
+
+// Quote CRUD Operations
+
+// ListQuotes lists quotes, optionally for the customer_id query parameter
+func (h *QuoteHandlers) ListQuotes(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	customerID := r.URL.Query().Get("customer_id")
+	quotes, err := h.service.ListQuotes(customerID)
+	if err != nil {
+		WriteInternalErrorResponse(w, err)
+		return
+	}
+
+	now := time.Now()
+	for _, quote := range quotes {
+		quote.Status = quote.StatusAt(now)
+	}
+
+	response := map[string]interface{}{
+		"quotes": quotes,
+		"count":  len(quotes),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// CreateQuote creates a draft quote
+func (h *QuoteHandlers) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	var quote cpq.Quote
+	if err := ParseJSONRequest(r, &quote); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+	quote.Status = cpq.QuoteDraft
+	quote.Pricing = nil
+	if quote.ValidUntil.IsZero() {
+		quote.ValidUntil = time.Now().UTC().AddDate(0, 0, defaultQuoteValidDays)
+	}
+	if quote.Lines == nil {
+		quote.Lines = []cpq.QuoteLine{}
+	}
+
+	if !h.validateQuote(w, &quote) {
+		return
+	}
+
+	if err := h.service.CreateQuote(&quote); err != nil {
+		WriteErrorResponse(w, "CREATE_FAILED", "Failed to create quote", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteCreatedResponse(w, quote, meta)
+}
+
+// GetQuote retrieves a quote with its current status and, once sent, the
+// pricing it was sent with
+func (h *QuoteHandlers) GetQuote(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	quote, err := h.service.GetQuote(mux.Vars(r)["id"])
+	if err != nil {
+		WriteNotFoundResponse(w, "Quote")
+		return
+	}
+	quote.Status = quote.StatusAt(time.Now())
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, quote, meta)
+}
+
+// UpdateQuote replaces a draft quote's header and lines
+func (h *QuoteHandlers) UpdateQuote(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	var quote cpq.Quote
+	if err := ParseJSONRequest(r, &quote); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+
+	existing, ok := h.editableQuote(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	quote.ID = existing.ID
+	quote.Status = existing.Status
+	quote.CreatedBy = existing.CreatedBy
+	quote.Pricing = nil
+	if quote.ValidUntil.IsZero() {
+		quote.ValidUntil = existing.ValidUntil
+	}
+	if quote.Lines == nil {
+		quote.Lines = []cpq.QuoteLine{}
+	}
+
+	if !h.validateQuote(w, &quote) {
+		return
+	}
+
+	if err := h.service.UpdateQuote(&quote); err != nil {
+		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to update quote", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, quote, meta)
+}
+
+// DeleteQuote deletes a quote
+func (h *QuoteHandlers) DeleteQuote(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	quoteID := mux.Vars(r)["id"]
+
+	if err := h.service.DeleteQuote(quoteID); err != nil {
+		WriteNotFoundResponse(w, "Quote")
+		return
+	}
+
+	response := map[string]interface{}{
+		"quote_id":   quoteID,
+		"deleted":    true,
+		"deleted_at": time.Now().UTC(),
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, response, meta)
+}
+
+// Line Operations
+
+// AddLine adds a line to a draft quote. A configured line may name a saved
+// configuration, whose model and selections are copied onto the line.
+func (h *QuoteHandlers) AddLine(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	var line cpq.QuoteLine
+	if err := ParseJSONRequest(r, &line); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+
+	quote, ok := h.editableQuote(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	if line.ID == "" {
+		line.ID = nextLineID(quote)
+	}
+	quote.Lines = append(quote.Lines, line)
+
+	if !h.validateQuote(w, quote) {
+		return
+	}
+
+	if err := h.service.UpdateQuote(quote); err != nil {
+		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to add quote line", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteCreatedResponse(w, quote, meta)
+}
+
+// UpdateLine replaces a line of a draft quote
+func (h *QuoteHandlers) UpdateLine(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+
+	var line cpq.QuoteLine
+	if err := ParseJSONRequest(r, &line); err != nil {
+		WriteBadRequestResponse(w, "Invalid request body")
+		return
+	}
+
+	quote, ok := h.editableQuote(w, vars["id"])
+	if !ok {
+		return
+	}
+	existing, err := quote.GetLine(vars["line_id"])
+	if err != nil {
+		WriteNotFoundResponse(w, "Quote line")
+		return
+	}
+	line.ID = existing.ID
+	*existing = line
+
+	if !h.validateQuote(w, quote) {
+		return
+	}
+
+	if err := h.service.UpdateQuote(quote); err != nil {
+		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to update quote line", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, quote, meta)
+}
+
+// DeleteLine removes a line from a draft quote
+func (h *QuoteHandlers) DeleteLine(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	vars := mux.Vars(r)
+
+	quote, ok := h.editableQuote(w, vars["id"])
+	if !ok {
+		return
+	}
+
+	lines := make([]cpq.QuoteLine, 0, len(quote.Lines))
+	for _, line := range quote.Lines {
+		if line.ID != vars["line_id"] {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == len(quote.Lines) {
+		WriteNotFoundResponse(w, "Quote line")
+		return
+	}
+	quote.Lines = lines
+
+	if err := h.service.UpdateQuote(quote); err != nil {
+		WriteErrorResponse(w, "UPDATE_FAILED", "Failed to delete quote line", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, quote, meta)
+}
+
+// Pricing and Status
+
+// PriceQuote prices a draft quote from the current models, exchange rates
+// and customer terms. A quote that has been sent keeps the prices it was
+// sent with.
+func (h *QuoteHandlers) PriceQuote(w http.ResponseWriter, r *http.Request) {
+	timer := StartTimer()
+
+	quote, err := h.service.GetQuote(mux.Vars(r)["id"])
+	if err != nil {
+		WriteNotFoundResponse(w, "Quote")
+		return
+	}
+
+	if quote.Editable() || quote.Pricing == nil {
+		pricing, err := h.priceQuote(quote)
+		if err != nil {
+			WriteErrorResponse(w, "PRICING_FAILED", "Failed to price quote", err.Error(), http.StatusBadRequest)
+			return
+		}
+		quote.Pricing = pricing
+	}
+	quote.Status = quote.StatusAt(time.Now())
+
+	duration := timer()
+	meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+	WriteSuccessResponse(w, quote, meta)
+}
+
+// transition returns a handler moving a quote to a status. A quote is
+// priced before it is sent, so customers never receive an unpriceable quote,
+// and keeps that pricing until it is revised to a draft.
+func (h *QuoteHandlers) transition(status cpq.QuoteStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		timer := StartTimer()
+
+		quote, err := h.service.GetQuote(mux.Vars(r)["id"])
+		if err != nil {
+			WriteNotFoundResponse(w, "Quote")
+			return
+		}
+
+		var pricing *cpq.QuotePricing
+		if status == cpq.QuoteSent {
+			pricing, err = h.priceQuote(quote)
+			if err != nil {
+				WriteErrorResponse(w, "PRICING_FAILED", "Failed to price quote", err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := quote.Transition(status, time.Now()); err != nil {
+			WriteErrorResponse(w, "TRANSITION_FAILED", "Failed to change quote status", err.Error(), http.StatusConflict)
+			return
+		}
+		switch status {
+		case cpq.QuoteSent:
+			quote.Pricing = pricing
+		case cpq.QuoteDraft:
+			quote.Pricing = nil
+		}
+
+		if err := h.service.UpdateQuote(quote); err != nil {
+			WriteErrorResponse(w, "UPDATE_FAILED", "Failed to update quote", err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		duration := timer()
+		meta := CreateMetadata(r.Header.Get("X-Request-ID"), duration)
+		WriteSuccessResponse(w, quote, meta)
+	}
+}
+
+// Helper Functions
+
+// editableQuote loads a quote that may be edited, writing an error response
+// when it is missing or no longer a draft
+func (h *QuoteHandlers) editableQuote(w http.ResponseWriter, quoteID string) (*cpq.Quote, bool) {
+	quote, err := h.service.GetQuote(quoteID)
+	if err != nil {
+		WriteNotFoundResponse(w, "Quote")
+		return nil, false
+	}
+	if !quote.Editable() {
+		WriteErrorResponse(w, "QUOTE_LOCKED", "Quote cannot be edited",
+			fmt.Sprintf("quote is %s; revise it to draft first", quote.Status), http.StatusConflict)
+		return nil, false
+	}
+	return quote, true
+}
+
+// validateQuote resolves a quote's configured lines and validates it,
+// writing a validation error response when it is invalid
+func (h *QuoteHandlers) validateQuote(w http.ResponseWriter, quote *cpq.Quote) bool {
+	errors := map[string]string{}
+	if quote.CustomerID != "" {
+		if _, err := h.service.GetCustomer(quote.CustomerID); err != nil {
+			errors["customer_id"] = "Customer not found"
+		}
+	}
+
+	for i := range quote.Lines {
+		if err := h.resolveLine(&quote.Lines[i]); err != nil {
+			errors[fmt.Sprintf("lines[%d]", i)] = err.Error()
+		}
+	}
+	if len(errors) == 0 {
+		if err := cpq.ValidateQuote(quote); err != nil {
+			errors["quote"] = err.Error()
+		}
+	}
+
+	if len(errors) > 0 {
+		WriteValidationErrorResponse(w, errors)
+		return false
+	}
+	return true
+}
+
+// resolveLine fills in a line's type, copies the model and selections of a
+// referenced configuration and checks configured selections are valid
+func (h *QuoteHandlers) resolveLine(line *cpq.QuoteLine) error {
+	if line.Type == "" {
+		line.Type = cpq.ProductLine
+		if line.ConfigurationID != "" || line.ModelID != "" {
+			line.Type = cpq.ConfiguredLine
+		}
+	}
+	if line.Type != cpq.ConfiguredLine {
+		return nil
+	}
+
+	if line.ConfigurationID != "" && line.ModelID == "" {
+		config, err := h.service.GetConfiguration(line.ConfigurationID, nil)
+		if err != nil {
+			return fmt.Errorf("configuration not found: %s", line.ConfigurationID)
+		}
+		line.ModelID = config.ModelID
+		line.Selections = append([]cpq.Selection(nil), config.Selections...)
+		line.Children = append([]cpq.Configuration(nil), config.Children...)
+	}
+
+	model, err := h.service.GetModel(line.ModelID)
+	if err != nil {
+		return fmt.Errorf("model not found: %s", line.ModelID)
+	}
+	if line.Description == "" {
+		line.Description = model.Name
+	}
+
+	if model.IsAssembly() || len(line.Children) > 0 {
+		models, err := loadModelTree(h.service, model)
+		if err != nil {
+			return err
+		}
+		config := cpq.Configuration{ModelID: model.ID, Selections: line.Selections, Children: line.Children}
+		if result := cpq.ValidateAssembly(models, config); !result.IsValid {
+			return fmt.Errorf("configuration of %s is not valid: %s", model.Name, result.Violations[0].Message)
+		}
+		return nil
+	}
+
+	configurator, err := cpq.NewConfigurator(model)
+	if err != nil {
+		return fmt.Errorf("failed to create configurator: %w", err)
+	}
+	update, err := configurator.LoadConfiguration(cpq.Configuration{ModelID: model.ID, Selections: line.Selections})
+	if err != nil {
+		return err
+	}
+	if !update.IsValid {
+		return fmt.Errorf("configuration of %s is not valid", model.Name)
+	}
+	return nil
+}
+
+// priceQuote prices a quote in its pricing context with the stored exchange
+// and tax rates, and the contracts and tax exemptions of its customer
+func (h *QuoteHandlers) priceQuote(quote *cpq.Quote) (*cpq.QuotePricing, error) {
+	models := make(map[string]*cpq.Model)
+	for _, modelID := range quote.ModelIDs() {
+		model, err := h.service.GetModel(modelID)
+		if err != nil {
+			return nil, fmt.Errorf("model not found: %s", modelID)
+		}
+		tree, err := loadModelTree(h.service, model)
+		if err != nil {
+			return nil, err
+		}
+		for id, treeModel := range tree {
+			models[id] = treeModel
+		}
+	}
+
+	ctx := quote.PricingContext
+	rates, err := h.service.GetExchangeRates()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exchange rates: %w", err)
+	}
+	ctx.Rates = rates
+	if ctx.ShipTo != nil {
+		ctx.TaxRates, err = h.service.GetTaxRates()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tax rates: %w", err)
+		}
+	}
+
+	var terms cpq.QuoteTerms
+	if quote.CustomerID != "" {
+		customer, err := cpq.PricingCustomer(h.service.GetCustomer, quote.CustomerID)
+		if err != nil {
+			return nil, err
+		}
+		terms.Contracts, err = h.service.ListContracts(customer.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load contracts: %w", err)
+		}
+		terms.Exemptions = customer.TaxExemptions
+	}
+
+	return cpq.PriceQuote(quote, models, ctx, terms)
+}
+
+// nextLineID returns an unused line ID for a quote
+func nextLineID(quote *cpq.Quote) string {
+	for n := len(quote.Lines) + 1; ; n++ {
+		id := fmt.Sprintf("line-%d", n)
+		if _, err := quote.GetLine(id); err != nil {
+			return id
+		}
+	}
+}
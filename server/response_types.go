@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"DD/cpq"
+	"DD/evaluator"
+	"DD/i18n"
 	"DD/modelbuilder"
 	"github.com/gorilla/mux"
 )
@@ -117,6 +119,21 @@ type ValidationResponse struct {
 	Timestamp   time.Time                      `json:"timestamp"`
 }
 
+// ConflictExplanationResponse represents a minimal conflict in a configuration
+type ConflictExplanationResponse struct {
+	ConfigID    string                         `json:"config_id"`
+	HasConflict bool                           `json:"has_conflict"`
+	Explanation *evaluator.ConflictExplanation `json:"explanation"`
+	Timestamp   time.Time                      `json:"timestamp"`
+}
+
+// RepairResponse represents the nearest valid configurations
+type RepairResponse struct {
+	ConfigID  string       `json:"config_id"`
+	Repairs   []cpq.Repair `json:"repairs"`
+	Timestamp time.Time    `json:"timestamp"`
+}
+
 // ConflictResponse represents rule conflict detection results
 type ConflictResponse struct {
 	Conflicts     []modelbuilder.RuleConflict `json:"conflicts"`
@@ -147,14 +164,87 @@ type PricingRequest struct {
 	Selections []SelectionRequest     `json:"selections" validate:"required"`
 	CustomerID string                 `json:"customer_id,omitempty"`
 	Context    map[string]interface{} `json:"context,omitempty"`
+
+	// Price book, date and currency to price in; defaults to base prices
+	PricingContext *cpq.PricingContext `json:"pricing_context,omitempty"`
+
+	// Discount requested on top of contract terms, checked against the
+	// model's margin floors
+	DiscountPercent float64 `json:"discount_percent,omitempty"`
+}
+
+// AssemblyPricingRequest prices a configuration with its sub-assemblies
+type AssemblyPricingRequest struct {
+	ModelID    string              `json:"model_id" validate:"required"`
+	Selections []SelectionRequest  `json:"selections"`
+	Children   []cpq.Configuration `json:"children"`
+
+	// Price book, date and currency to price in; sub-assemblies are priced
+	// in the same currency and term from their models' default books
+	PricingContext *cpq.PricingContext `json:"pricing_context,omitempty"`
+
+	// Discount requested on the rolled-up total
+	DiscountPercent float64 `json:"discount_percent,omitempty"`
+}
+
+// ChildrenRequest replaces a configuration session's sub-assemblies
+type ChildrenRequest struct {
+	Children []cpq.Configuration `json:"children"`
+}
+
+// VolumeTiersRequest replaces a model's volume tiers; an empty list restores
+// the default tiers
+type VolumeTiersRequest struct {
+	Tiers []cpq.VolumeTier `json:"tiers"`
+}
+
+// PriceBooksRequest replaces a model's price books
+type PriceBooksRequest struct {
+	PriceBooks []cpq.PriceBook `json:"price_books"`
 }
 
 // PricingResponse represents pricing calculation results
 type PricingResponse struct {
-	Breakdown *cpq.PricingResult `json:"breakdown"`
-	Total     float64            `json:"total"`
-	Currency  string             `json:"currency"`
-	Timestamp time.Time          `json:"timestamp"`
+	Breakdown          *cpq.PricingResult `json:"breakdown"`
+	Total              float64            `json:"total"`
+	OneTimeTotal       float64            `json:"one_time_total"`
+	MRR                float64            `json:"mrr"`
+	ARR                float64            `json:"arr"`
+	TermMonths         int                `json:"term_months"`
+	TotalContractValue float64            `json:"total_contract_value"`
+	Margin             float64            `json:"margin"`
+	MarginPercent      float64            `json:"margin_percent"`
+	RequiresApproval   bool               `json:"requires_approval"`
+	Tax                float64            `json:"tax,omitempty"`
+	GrossTotal         float64            `json:"gross_total,omitempty"`
+	Currency           string             `json:"currency"`
+	Timestamp          time.Time          `json:"timestamp"`
+}
+
+// NewPricingResponse builds a pricing response from a pricing result
+func NewPricingResponse(result *cpq.PricingResult) *PricingResponse {
+	response := &PricingResponse{
+		Breakdown:          result,
+		Total:              result.TotalPrice,
+		OneTimeTotal:       result.OneTimeTotal,
+		MRR:                result.MRR,
+		ARR:                result.ARR,
+		TotalContractValue: result.TotalContractValue,
+		Margin:             result.Margin,
+		MarginPercent:      result.MarginPercent,
+		RequiresApproval:   result.RequiresApproval,
+		Tax:                result.Tax,
+		GrossTotal:         result.GrossTotal,
+		Currency:           cpq.DefaultCurrency,
+		Timestamp:          time.Now().UTC(),
+	}
+	if result.Breakdown != nil {
+		response.TermMonths = result.Breakdown.TermMonths
+		if result.Breakdown.Currency != "" {
+			response.Currency = result.Breakdown.Currency
+		}
+	}
+	return response
 }
 
 // Analytics API Types
@@ -329,6 +419,14 @@ func ExtractPathParam(r *http.Request, param string) (string, error) {
 	return value, nil
 }
 
+// RequestLocalizer picks the message language from the Accept-Language
+// header and reports the choice in Content-Language
+func RequestLocalizer(w http.ResponseWriter, r *http.Request) *i18n.Localizer {
+	localizer := i18n.Default.Match(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", localizer.Locale())
+	return localizer
+}
+
 // Performance Monitoring Helpers
 
 // StartTimer returns a function to measure elapsed time
@@ -1,8 +1,10 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"DD/cpq"
 )
@@ -70,6 +72,12 @@ func (s *SessionService) GetSession(sessionID string) (*ConfigurationSession, er
 			return nil, fmt.Errorf("failed to create configurator: %w", err)
 		}
 		
+		// Restore the saved pricing context; fall back to base pricing if
+		// the book or rates it needs are gone
+		if err := s.applyPricingContext(configurator, session.PricingContext); err != nil {
+			session.PricingContext = cpq.PricingContext{}
+		}
+		
 		// Apply saved selections
 		for optionID, quantity := range session.Selections {
 			_, err = configurator.AddSelection(optionID, quantity)
@@ -120,22 +128,23 @@ func (s *SessionService) UpdateSessionConfiguration(sessionID string, selections
 	
 	// IMPORTANT: Validate the entire configuration after all selections are applied
 	// Don't use the result from the last AddSelection as it only validates that single addition
-	validationResult := session.Configurator.ValidateCurrentConfiguration()
+	validationResult := s.sessionValidation(session)
 	session.ValidationState = &validationResult
 	
 	// Update the result with the correct validation
 	result.ValidationResult = validationResult
 	result.UpdatedConfig = config
 	
-	// Get detailed pricing breakdown
-	priceBreakdown := session.Configurator.GetDetailedPrice()
+	// Get detailed pricing breakdown with the requested discount. A
+	// discount the new selections take below a blocking margin floor is
+	// kept; the quote cannot be submitted until it is lowered.
+	priceBreakdown, _ := s.sessionPricing(session)
 	
 	// Always update pricing state with current configuration pricing
-	session.PricingState = &cpq.PricingResult{
-		BasePrice:  priceBreakdown.BasePrice,
-		TotalPrice: priceBreakdown.TotalPrice,
-		Breakdown:  &priceBreakdown,
-	}
+	session.PricingState = cpq.NewPricingResult(priceBreakdown)
+	
+	// Edits after submission or approval invalidate the approval
+	s.invalidateApproval(session, "Configuration changed")
 	
 	// Save session state
 	if err := s.sessionStore.SaveSession(session); err != nil {
@@ -145,6 +154,181 @@ func (s *SessionService) UpdateSessionConfiguration(sessionID string, selections
 	return &result, nil
 }
 
+// SetPricingContext selects the price book, date and currency a session is
+// priced in and reprices its configuration
+func (s *SessionService) SetPricingContext(sessionID string, ctx cpq.PricingContext) (*ConfigurationSession, error) {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := s.applyPricingContext(session.Configurator, ctx); err != nil {
+		return nil, fmt.Errorf("invalid pricing context: %w", err)
+	}
+	session.PricingContext = ctx
+	
+	priceBreakdown, _ := s.sessionPricing(session)
+	session.PricingState = cpq.NewPricingResult(priceBreakdown)
+	s.invalidateApproval(session, "Pricing context changed")
+	
+	if err := s.sessionStore.SaveSession(session); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	
+	return session, nil
+}
+
+// SetDiscount sets the discount requested for a session's quote. A discount
+// breaching a blocking margin floor is rejected.
+func (s *SessionService) SetDiscount(sessionID string, percent float64) (*ConfigurationSession, error) {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	
+	previous := session.DiscountPercent
+	session.DiscountPercent = percent
+	priceBreakdown, err := s.sessionPricing(session)
+	if err != nil {
+		session.DiscountPercent = previous
+		return nil, err
+	}
+	session.PricingState = cpq.NewPricingResult(priceBreakdown)
+	s.invalidateApproval(session, "Discount changed")
+	
+	if err := s.sessionStore.SaveSession(session); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	
+	return session, nil
+}
+
+// sessionPricing prices a session's configuration, rolled up with its
+// sub-assemblies, with its requested discount, checking the model's margin
+// floors, and adds tax at the ship-to address of its pricing context
+func (s *SessionService) sessionPricing(session *ConfigurationSession) (cpq.PriceBreakdown, error) {
+	priceBreakdown := session.Configurator.GetDetailedPrice()
+	
+	model, err := s.cpqService.GetModel(session.ModelID)
+	if err != nil {
+		return priceBreakdown, fmt.Errorf("failed to get model for session: %w", err)
+	}
+	if !model.IsAssembly() && len(session.Children) == 0 {
+		priceBreakdown, err = cpq.ApplyDiscount(model, priceBreakdown, session.DiscountPercent)
+		if err != nil {
+			return priceBreakdown, err
+		}
+		return cpq.ApplyTax(model, priceBreakdown, session.Configurator.GetPricingContext(), nil)
+	}
+	
+	models, err := loadModelTree(s.cpqService, model)
+	if err != nil {
+		return priceBreakdown, err
+	}
+	price, err := cpq.PriceAssembly(models, sessionAssembly(session), session.Configurator.GetPricingContext())
+	if err != nil {
+		return priceBreakdown, err
+	}
+	priceBreakdown, err = cpq.ApplyDiscount(model, price.Total, session.DiscountPercent)
+	if err != nil {
+		return priceBreakdown, err
+	}
+	return cpq.ApplyAssemblyTax(models, model.ID, priceBreakdown, session.Configurator.GetPricingContext(), nil)
+}
+
+// sessionValidation validates a session's configuration with its
+// sub-assemblies and the model's assembly rules
+func (s *SessionService) sessionValidation(session *ConfigurationSession) cpq.ValidationResult {
+	model, err := s.cpqService.GetModel(session.ModelID)
+	if err != nil || (!model.IsAssembly() && len(session.Children) == 0) {
+		return session.Configurator.ValidateCurrentConfiguration()
+	}
+	
+	models, err := loadModelTree(s.cpqService, model)
+	if err != nil {
+		return cpq.ValidationResult{
+			IsValid: false,
+			Violations: []cpq.RuleViolation{{
+				RuleID: "assembly_model", RuleName: "Assembly model", Message: err.Error(), AffectedOptions: []string{},
+			}},
+			Suggestions: []string{},
+		}
+	}
+	return cpq.ValidateAssembly(models, sessionAssembly(session))
+}
+
+// sessionAssembly returns a session's configuration with its sub-assemblies
+func sessionAssembly(session *ConfigurationSession) cpq.Configuration {
+	config := session.Configurator.GetCurrentConfiguration()
+	config.ModelID = session.ModelID
+	config.Children = session.Children
+	return config
+}
+
+// SetChildren replaces the sub-assemblies configured for a session and
+// revalidates and reprices it
+func (s *SessionService) SetChildren(sessionID string, children []cpq.Configuration) (*ConfigurationSession, error) {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	
+	session.Children = children
+	validationResult := s.sessionValidation(session)
+	session.ValidationState = &validationResult
+	priceBreakdown, _ := s.sessionPricing(session)
+	session.PricingState = cpq.NewPricingResult(priceBreakdown)
+	s.invalidateApproval(session, "Sub-assemblies changed")
+	
+	if err := s.sessionStore.SaveSession(session); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	
+	return session, nil
+}
+
+// GetSessionAssembly validates and prices a session's configuration level
+// by level through its sub-assemblies
+func (s *SessionService) GetSessionAssembly(sessionID string) (cpq.ValidationResult, *cpq.AssemblyPrice, error) {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return cpq.ValidationResult{}, nil, err
+	}
+	
+	model, err := s.cpqService.GetModel(session.ModelID)
+	if err != nil {
+		return cpq.ValidationResult{}, nil, fmt.Errorf("failed to get model for session: %w", err)
+	}
+	models, err := loadModelTree(s.cpqService, model)
+	if err != nil {
+		return cpq.ValidationResult{}, nil, err
+	}
+	config := sessionAssembly(session)
+	price, err := cpq.PriceAssembly(models, config, session.Configurator.GetPricingContext())
+	if err != nil {
+		return cpq.ValidationResult{}, nil, err
+	}
+	return cpq.ValidateAssembly(models, config), price, nil
+}
+
+// applyPricingContext sets a configurator's pricing context with the
+// stored exchange and tax rates
+func (s *SessionService) applyPricingContext(configurator *cpq.Configurator, ctx cpq.PricingContext) error {
+	rates, err := s.cpqService.GetExchangeRates()
+	if err != nil {
+		return fmt.Errorf("failed to load exchange rates: %w", err)
+	}
+	ctx.Rates = rates
+
+	if ctx.ShipTo != nil {
+		ctx.TaxRates, err = s.cpqService.GetTaxRates()
+		if err != nil {
+			return fmt.Errorf("failed to load tax rates: %w", err)
+		}
+	}
+	return configurator.SetPricingContext(ctx)
+}
+
 // ValidateSessionConfiguration validates a session's configuration
 func (s *SessionService) ValidateSessionConfiguration(sessionID string) (*cpq.ValidationResult, error) {
 	session, err := s.GetSession(sessionID)
@@ -152,7 +336,7 @@ func (s *SessionService) ValidateSessionConfiguration(sessionID string) (*cpq.Va
 		return nil, err
 	}
 	
-	result := session.Configurator.ValidateCurrentConfiguration()
+	result := s.sessionValidation(session)
 	
 	// Cache the result
 	session.ValidationState = &result
@@ -165,11 +349,131 @@ func (s *SessionService) ValidateSessionConfiguration(sessionID string) (*cpq.Va
 	return &result, nil
 }
 
-// CompleteSession marks a session as completed
+// errApprovalRequired is returned when completing a session whose quote
+// is not approved as it stands
+var errApprovalRequired = errors.New("quote requires approval before it can be completed")
+
+// CompleteSession marks a session as completed. A quote that needs
+// approval must be approved as it stands.
 func (s *SessionService) CompleteSession(sessionID string) error {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	
+	requirements, fingerprint, err := s.approvalRequirements(session)
+	if err != nil {
+		return err
+	}
+	if len(requirements) > 0 && (session.Approval == nil || !session.Approval.Holds(fingerprint)) {
+		return errApprovalRequired
+	}
+	
 	return s.sessionStore.UpdateStatus(sessionID, SessionStatusCompleted)
 }
 
+// Approval workflow
+
+// SubmitForApproval submits a session's quote for approval. The approvals
+// it needs come from the model's policies and margin floors; a quote that
+// needs none is approved straight away.
+func (s *SessionService) SubmitForApproval(sessionID, userID string) (*ConfigurationSession, error) {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if validation := s.sessionValidation(session); !validation.IsValid {
+		return nil, fmt.Errorf("configuration must be valid to submit for approval")
+	}
+	
+	requirements, fingerprint, err := s.approvalRequirements(session)
+	if err != nil {
+		return nil, err
+	}
+	
+	if session.Approval == nil {
+		session.Approval = cpq.NewApproval()
+	}
+	if err := session.Approval.Submit(requirements, fingerprint, userID, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	
+	return s.saveApproval(session)
+}
+
+// ApproveSession records an approval of a session's quote by a user in a role
+func (s *SessionService) ApproveSession(sessionID, userID, role, comment string) (*ConfigurationSession, error) {
+	return s.updateApproval(sessionID, func(approval *cpq.Approval) error {
+		return approval.Approve(userID, role, comment, time.Now().UTC())
+	})
+}
+
+// RejectSession rejects a session's quote
+func (s *SessionService) RejectSession(sessionID, userID, role, comment string) (*ConfigurationSession, error) {
+	return s.updateApproval(sessionID, func(approval *cpq.Approval) error {
+		return approval.Reject(userID, role, comment, time.Now().UTC())
+	})
+}
+
+// CommentOnApproval adds a comment to a session's approval history
+func (s *SessionService) CommentOnApproval(sessionID, userID, role, comment string) (*ConfigurationSession, error) {
+	return s.updateApproval(sessionID, func(approval *cpq.Approval) error {
+		return approval.Comment(userID, role, comment, time.Now().UTC())
+	})
+}
+
+// updateApproval applies a workflow step to a session's approval
+func (s *SessionService) updateApproval(sessionID string, step func(*cpq.Approval) error) (*ConfigurationSession, error) {
+	session, err := s.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if session.Approval == nil {
+		session.Approval = cpq.NewApproval()
+	}
+	if err := step(session.Approval); err != nil {
+		return nil, err
+	}
+	
+	return s.saveApproval(session)
+}
+
+// saveApproval moves the session status with its approval and saves it
+func (s *SessionService) saveApproval(session *ConfigurationSession) (*ConfigurationSession, error) {
+	session.Status = string(session.Approval.Status)
+	if err := s.sessionStore.SaveSession(session); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	return session, nil
+}
+
+// approvalRequirements returns the approvals a session's quote needs and
+// the fingerprint of the quote as it stands
+func (s *SessionService) approvalRequirements(session *ConfigurationSession) ([]cpq.ApprovalRequirement, string, error) {
+	priceBreakdown, err := s.sessionPricing(session)
+	if err != nil {
+		return nil, "", err
+	}
+	
+	model, err := s.cpqService.GetModel(session.ModelID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get model for session: %w", err)
+	}
+	
+	selections := session.Configurator.GetCurrentConfiguration().Selections
+	return cpq.RequiredApprovals(model, priceBreakdown), cpq.QuoteFingerprint(selections, priceBreakdown), nil
+}
+
+// invalidateApproval returns a submitted or approved quote to draft after
+// an edit
+func (s *SessionService) invalidateApproval(session *ConfigurationSession, reason string) {
+	if session.Approval != nil && session.Approval.Invalidate(reason, time.Now().UTC()) {
+		session.Status = SessionStatusDraft
+	}
+}
+
 // GetUserSessions retrieves all active sessions for a user
 func (s *SessionService) GetUserSessions(userID string) ([]*ConfigurationSession, error) {
 	return s.sessionStore.GetUserSessions(userID)
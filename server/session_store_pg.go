@@ -67,9 +67,9 @@ func (s *PostgresSessionStore) CreateSession(modelID, userID string, configurato
 		INSERT INTO configuration_sessions (
 			id, model_id, model_version, mtbdd_snapshot, selections,
 			user_id, session_token, status, created_at, updated_at, 
-			accessed_at, expires_at, metadata
+			accessed_at, expires_at, metadata, pricing_context
 		) VALUES (
-			$1::uuid, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+			$1::uuid, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
 		)`
 	
 	selectionsJSON, err := json.Marshal(selections)
@@ -82,6 +82,12 @@ func (s *PostgresSessionStore) CreateSession(modelID, userID string, configurato
 		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 	
+	session.PricingContext = configurator.GetPricingContext()
+	pricingContextJSON, err := json.Marshal(session.PricingContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pricing context: %w", err)
+	}
+	
 	_, err = s.db.Exec(query,
 		session.ID,
 		session.ModelID,
@@ -96,6 +102,7 @@ func (s *PostgresSessionStore) CreateSession(modelID, userID string, configurato
 		session.AccessedAt,
 		session.ExpiresAt,
 		metadataJSON,
+		pricingContextJSON,
 	)
 	
 	if err != nil {
@@ -115,13 +122,14 @@ func (s *PostgresSessionStore) CreateSession(modelID, userID string, configurato
 func (s *PostgresSessionStore) GetSession(sessionID string) (*ConfigurationSession, error) {
 	var session ConfigurationSession
 	var mtbddSnapshot []byte
-	var selectionsJSON, validationJSON, pricingJSON, metadataJSON []byte
+	var selectionsJSON, validationJSON, pricingJSON, metadataJSON, pricingContextJSON, approvalJSON, childrenJSON []byte
 	
 	query := `
 		SELECT 
 			id, model_id, model_version, mtbdd_snapshot, selections,
 			validation_state, pricing_state, user_id, session_token,
-			status, created_at, updated_at, accessed_at, expires_at, metadata
+			status, created_at, updated_at, accessed_at, expires_at, metadata,
+			pricing_context, discount_percent, approval, children
 		FROM configuration_sessions
 		WHERE id = $1 AND expires_at > NOW()`
 	
@@ -141,6 +149,10 @@ func (s *PostgresSessionStore) GetSession(sessionID string) (*ConfigurationSessi
 		&session.AccessedAt,
 		&session.ExpiresAt,
 		&metadataJSON,
+		&pricingContextJSON,
+		&session.DiscountPercent,
+		&approvalJSON,
+		&childrenJSON,
 	)
 	
 	if err == sql.ErrNoRows {
@@ -153,6 +165,15 @@ func (s *PostgresSessionStore) GetSession(sessionID string) (*ConfigurationSessi
 	// Unmarshal JSON fields
 	json.Unmarshal(selectionsJSON, &session.Selections)
 	json.Unmarshal(metadataJSON, &session.Metadata)
+	if pricingContextJSON != nil {
+		json.Unmarshal(pricingContextJSON, &session.PricingContext)
+	}
+	if approvalJSON != nil {
+		json.Unmarshal(approvalJSON, &session.Approval)
+	}
+	if childrenJSON != nil {
+		json.Unmarshal(childrenJSON, &session.Children)
+	}
 	
 	if validationJSON != nil {
 		var validationState cpq.ValidationResult
@@ -221,6 +242,20 @@ func (s *PostgresSessionStore) SaveSession(session *ConfigurationSession) error
 		pricingJSON = []byte("null")
 	}
 	
+	pricingContextJSON, _ := json.Marshal(session.PricingContext)
+	
+	var approvalJSON []byte
+	if session.Approval != nil {
+		approvalJSON, _ = json.Marshal(session.Approval)
+	} else {
+		approvalJSON = []byte("null")
+	}
+	
+	childrenJSON := []byte("[]")
+	if len(session.Children) > 0 {
+		childrenJSON, _ = json.Marshal(session.Children)
+	}
+	
 	query := `
 		UPDATE configuration_sessions SET
 			mtbdd_snapshot = $2,
@@ -228,6 +263,11 @@ func (s *PostgresSessionStore) SaveSession(session *ConfigurationSession) error
 			validation_state = $4,
 			pricing_state = $5,
 			metadata = $6,
+			pricing_context = $7,
+			status = $8,
+			discount_percent = $9,
+			approval = $10,
+			children = $11,
 			updated_at = NOW(),
 			accessed_at = NOW()
 		WHERE id = $1`
@@ -239,6 +279,11 @@ func (s *PostgresSessionStore) SaveSession(session *ConfigurationSession) error
 		validationJSON,
 		pricingJSON,
 		metadataJSON,
+		pricingContextJSON,
+		session.Status,
+		session.DiscountPercent,
+		approvalJSON,
+		childrenJSON,
 	)
 	
 	if err != nil {
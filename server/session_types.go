@@ -26,16 +26,29 @@ type ConfigurationSession struct {
 	ValidationState *cpq.ValidationResult  `json:"validation_state,omitempty" db:"validation_state"`
 	PricingState    *cpq.PricingResult     `json:"pricing_state,omitempty" db:"pricing_state"`
 	
+	// Price book, date and currency the session is priced in
+	PricingContext cpq.PricingContext      `json:"pricing_context" db:"pricing_context"`
+	
+	// Requested discount and the approval it may need
+	DiscountPercent float64                `json:"discount_percent" db:"discount_percent"`
+	Approval        *cpq.Approval          `json:"approval,omitempty" db:"approval"`
+	
+	// Sub-assemblies configured for the session's sub-assembly options
+	Children []cpq.Configuration           `json:"children,omitempty" db:"children"`
+	
 	// Metadata
 	Metadata map[string]interface{}        `json:"metadata" db:"metadata"`
 }
 
 // SessionStatus constants
 const (
-	SessionStatusDraft      = "draft"
-	SessionStatusValidated  = "validated"
-	SessionStatusCompleted  = "completed"
-	SessionStatusAbandoned  = "abandoned"
+	SessionStatusDraft           = "draft"
+	SessionStatusValidated       = "validated"
+	SessionStatusPendingApproval = "pending_approval"
+	SessionStatusApproved        = "approved"
+	SessionStatusRejected        = "rejected"
+	SessionStatusCompleted       = "completed"
+	SessionStatusAbandoned       = "abandoned"
 )
 
 // SessionStore interface for session persistence
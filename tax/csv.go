@@ -0,0 +1,126 @@
+// tax/csv.go
+// CSV import and export of jurisdiction rate tables
+
+package tax
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSV columns. The header row names the columns, in any order; country,
+// name and rate are required. Lines starting with # are comments.
+const (
+	countryColumn      = "country"
+	regionColumn       = "region"
+	postalPrefixColumn = "postal_prefix"
+	categoryColumn     = "category"
+	nameColumn         = "name"
+	rateColumn         = "rate" // Percent, with or without a % sign
+)
+
+var csvColumns = []string{countryColumn, regionColumn, postalPrefixColumn, categoryColumn, nameColumn, rateColumn}
+
+// ReadCSV reads a rate table from CSV
+func ReadCSV(r io.Reader) (*Table, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("tax rate CSV is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tax rate CSV: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if !knownColumn(column) {
+			return nil, fmt.Errorf("unknown tax rate column %q", column)
+		}
+		if _, ok := columns[column]; ok {
+			return nil, fmt.Errorf("duplicate tax rate column %q", column)
+		}
+		columns[column] = i
+	}
+	for _, required := range []string{countryColumn, nameColumn, rateColumn} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("tax rate CSV is missing the %s column", required)
+		}
+	}
+
+	var rates []Rate
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tax rate CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(column string) string {
+			if i, ok := columns[column]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		rate, err := strconv.ParseFloat(strings.TrimSuffix(field(rateColumn), "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rate %q", line, field(rateColumn))
+		}
+
+		rates = append(rates, Rate{
+			Country:      field(countryColumn),
+			Region:       field(regionColumn),
+			PostalPrefix: field(postalPrefixColumn),
+			Category:     field(categoryColumn),
+			Name:         field(nameColumn),
+			Rate:         rate,
+		})
+	}
+
+	return NewTable(rates)
+}
+
+// WriteCSV writes a rate table as CSV that ReadCSV reads back
+func WriteCSV(w io.Writer, table *Table) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+
+	for _, rate := range table.rates {
+		record := []string{
+			rate.Country,
+			rate.Region,
+			rate.PostalPrefix,
+			rate.Category,
+			rate.Name,
+			strconv.FormatFloat(rate.Rate, 'f', -1, 64),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func knownColumn(column string) bool {
+	for _, c := range csvColumns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package tax
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	input := `# Canadian sales taxes
+name,country,region,rate,category
+GST,CA,,5%,
+BC PST,ca,bc,7,
+BC PST software,CA,BC,0,Software
+`
+	table, err := ReadCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadCSV failed: %v", err)
+	}
+
+	rates := table.Rates()
+	if len(rates) != 3 {
+		t.Fatalf("Expected 3 rates, got %d", len(rates))
+	}
+	if rates[0].Rate != 5 || rates[1].Region != "BC" || rates[2].Category != "software" {
+		t.Errorf("Unexpected rates %v", rates)
+	}
+
+	// Round trip
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, table); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	again, err := ReadCSV(&buf)
+	if err != nil {
+		t.Fatalf("ReadCSV of written table failed: %v", err)
+	}
+	if len(again.Rates()) != 3 || again.Rates()[2] != rates[2] {
+		t.Errorf("Expected round trip to keep rates, got %v", again.Rates())
+	}
+}
+
+func TestReadCSV_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"missing column", "country,name\nDE,VAT\n"},
+		{"unknown column", "country,name,rate,county\nDE,VAT,19,x\n"},
+		{"duplicate column", "country,name,rate,Rate\nDE,VAT,19,7\n"},
+		{"invalid rate", "country,name,rate\nDE,VAT,high\n"},
+		{"short record", "country,name,rate\nDE,VAT\n"},
+		{"invalid table", "country,name,rate\nDE,VAT,19\nDE,VAT,16\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadCSV(strings.NewReader(tt.input)); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+// tax/tax.go
+// Tax calculation from locally stored jurisdiction rate tables
+
+package tax
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DisplayMode is how prices are shown relative to tax
+type DisplayMode string
+
+const (
+	Exclusive DisplayMode = "exclusive" // Prices shown before tax, tax added on top
+	Inclusive DisplayMode = "inclusive" // Prices shown with their tax included
+)
+
+// Address locates a sale for tax purposes
+type Address struct {
+	Country    string `json:"country"` // ISO 3166-1 alpha-2
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+}
+
+// Rate is a tax levied by a jurisdiction. A jurisdiction is a country, a
+// region of it or a postal prefix within either; the taxes of every
+// jurisdiction containing an address apply together. A rate with a
+// category replaces the jurisdiction's standard rate for that category.
+type Rate struct {
+	Country      string  `json:"country"`
+	Region       string  `json:"region,omitempty"`
+	PostalPrefix string  `json:"postal_prefix,omitempty"`
+	Category     string  `json:"category,omitempty"` // Empty is the standard rate
+	Name         string  `json:"name"`
+	Rate         float64 `json:"rate"` // Percent
+}
+
+// jurisdiction identifies the jurisdiction levying a rate
+func (r Rate) jurisdiction() string {
+	return r.Country + "/" + r.Region + "/" + r.PostalPrefix
+}
+
+// key identifies a rate: its jurisdiction and category
+func (r Rate) key() string {
+	return r.jurisdiction() + "/" + r.Category
+}
+
+// contains reports whether the rate's jurisdiction contains an address
+func (r Rate) contains(address Address) bool {
+	return r.Country == address.Country &&
+		(r.Region == "" || r.Region == address.Region) &&
+		strings.HasPrefix(address.PostalCode, r.PostalPrefix)
+}
+
+// Table is a jurisdiction rate table
+type Table struct {
+	rates []Rate
+}
+
+// NewTable builds a rate table, normalizing codes and checking that each
+// jurisdiction has at most one rate per category
+func NewTable(rates []Rate) (*Table, error) {
+	table := &Table{rates: make([]Rate, 0, len(rates))}
+	seen := make(map[string]bool)
+
+	for i, rate := range rates {
+		rate.Country = normalizeCode(rate.Country)
+		rate.Region = normalizeCode(rate.Region)
+		rate.PostalPrefix = normalizePostalCode(rate.PostalPrefix)
+		rate.Category = normalizeCategory(rate.Category)
+
+		if len(rate.Country) != 2 {
+			return nil, fmt.Errorf("rate %d: country must be a two-letter code, got %q", i+1, rate.Country)
+		}
+		if rate.Name == "" {
+			return nil, fmt.Errorf("rate %d: name cannot be empty", i+1)
+		}
+		if rate.Rate < 0 || rate.Rate > 100 {
+			return nil, fmt.Errorf("rate %d: rate must be between 0 and 100 percent", i+1)
+		}
+
+		key := rate.key()
+		if seen[key] {
+			return nil, fmt.Errorf("rate %d: duplicate rate for %s", i+1, strings.TrimRight(key, "/"))
+		}
+		seen[key] = true
+
+		table.rates = append(table.rates, rate)
+	}
+
+	return table, nil
+}
+
+// Rates returns the rates in the table
+func (t *Table) Rates() []Rate {
+	rates := make([]Rate, len(t.rates))
+	copy(rates, t.rates)
+	return rates
+}
+
+// Covers reports whether the table has rates for a country
+func (t *Table) Covers(country string) bool {
+	country = normalizeCode(country)
+	for _, rate := range t.rates {
+		if rate.Country == country {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup returns the rates applying to a category at an address, one per
+// jurisdiction from the broadest to the most specific
+func (t *Table) Lookup(address Address, category string) []Rate {
+	address = normalizeAddress(address)
+	category = normalizeCategory(category)
+
+	standard := make(map[string]Rate)
+	specific := make(map[string]Rate)
+	for _, rate := range t.rates {
+		if !rate.contains(address) {
+			continue
+		}
+		switch rate.Category {
+		case "":
+			standard[rate.jurisdiction()] = rate
+		case category:
+			specific[rate.jurisdiction()] = rate
+		}
+	}
+
+	var rates []Rate
+	for jurisdiction, rate := range standard {
+		if override, ok := specific[jurisdiction]; ok {
+			rate = override
+		}
+		rates = append(rates, rate)
+	}
+	for jurisdiction, rate := range specific {
+		if _, ok := standard[jurisdiction]; !ok {
+			rates = append(rates, rate)
+		}
+	}
+
+	sort.Slice(rates, func(i, j int) bool {
+		a, b := rates[i], rates[j]
+		if (a.Region == "") != (b.Region == "") {
+			return a.Region == ""
+		}
+		if len(a.PostalPrefix) != len(b.PostalPrefix) {
+			return len(a.PostalPrefix) < len(b.PostalPrefix)
+		}
+		return a.Name < b.Name
+	})
+	return rates
+}
+
+// Exemption exempts a customer from the taxes of a country, or of one of
+// its regions, for some or all product categories
+type Exemption struct {
+	Country     string   `json:"country"`
+	Region      string   `json:"region,omitempty"`     // Empty exempts every jurisdiction in the country
+	Categories  []string `json:"categories,omitempty"` // Empty exempts every category
+	Certificate string   `json:"certificate,omitempty"`
+}
+
+// covers reports whether the exemption covers a rate for a category
+func (e Exemption) covers(rate Rate, category string) bool {
+	if normalizeCode(e.Country) != rate.Country {
+		return false
+	}
+	if region := normalizeCode(e.Region); region != "" && region != rate.Region {
+		return false
+	}
+	if len(e.Categories) == 0 {
+		return true
+	}
+	for _, c := range e.Categories {
+		if normalizeCategory(c) == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Line is an amount to tax, net of all discounts
+type Line struct {
+	ID       string
+	Category string
+	Amount   float64
+}
+
+// Component is one jurisdiction's tax on a line
+type Component struct {
+	Name   string  `json:"name"`
+	Rate   float64 `json:"rate"`
+	Amount float64 `json:"amount"`
+	Exempt bool    `json:"exempt,omitempty"`
+}
+
+// LineTax is the tax on a line. Display is the amount to show for the line:
+// its net amount, or the net amount with tax in inclusive display.
+type LineTax struct {
+	LineID     string      `json:"line_id"`
+	Category   string      `json:"category,omitempty"`
+	Net        float64     `json:"net"`
+	Tax        float64     `json:"tax"`
+	Display    float64     `json:"display"`
+	Components []Component `json:"components,omitempty"`
+}
+
+// JurisdictionTotal is a jurisdiction's tax at one rate across all lines.
+// The jurisdiction and category identify the total; Name labels it.
+type JurisdictionTotal struct {
+	Country      string  `json:"country"`
+	Region       string  `json:"region,omitempty"`
+	PostalPrefix string  `json:"postal_prefix,omitempty"`
+	Category     string  `json:"category,omitempty"`
+	Name         string  `json:"name"`
+	Taxable      float64 `json:"taxable"`
+	Tax          float64 `json:"tax"`
+}
+
+// Summary is the tax on a quote
+type Summary struct {
+	Address       Address             `json:"address"`
+	Display       DisplayMode         `json:"display"`
+	Lines         []LineTax           `json:"lines"`
+	Jurisdictions []JurisdictionTotal `json:"jurisdictions"`
+	Net           float64             `json:"net"`
+	Tax           float64             `json:"tax"`
+	Gross         float64             `json:"gross"`
+}
+
+// Calculate taxes lines at an address. Each line is taxed by every
+// jurisdiction containing the address, at the rate for its category, unless
+// an exemption covers it. Tax is rounded to cents per line and jurisdiction.
+func Calculate(table *Table, address Address, lines []Line, exemptions []Exemption, display DisplayMode) (*Summary, error) {
+	address = normalizeAddress(address)
+	if display == "" {
+		display = Exclusive
+	}
+	if display != Exclusive && display != Inclusive {
+		return nil, fmt.Errorf("unknown tax display %q", display)
+	}
+	if address.Country == "" {
+		return nil, fmt.Errorf("tax address must have a country")
+	}
+	if table == nil || !table.Covers(address.Country) {
+		return nil, fmt.Errorf("no tax rates for country %s", address.Country)
+	}
+
+	summary := &Summary{Address: address, Display: display, Lines: make([]LineTax, 0, len(lines))}
+	totals := make(map[string]*JurisdictionTotal)
+	var order []string
+
+	for _, line := range lines {
+		category := normalizeCategory(line.Category)
+		lineTax := LineTax{LineID: line.ID, Category: category, Net: line.Amount}
+
+		for _, rate := range table.Lookup(address, category) {
+			component := Component{Name: rate.Name, Rate: rate.Rate}
+			if exempt(exemptions, rate, category) {
+				component.Exempt = true
+			} else {
+				component.Amount = roundCents(line.Amount * rate.Rate / 100)
+			}
+			lineTax.Components = append(lineTax.Components, component)
+			lineTax.Tax += component.Amount
+
+			total, ok := totals[rate.key()]
+			if !ok {
+				total = &JurisdictionTotal{
+					Country:      rate.Country,
+					Region:       rate.Region,
+					PostalPrefix: rate.PostalPrefix,
+					Category:     rate.Category,
+					Name:         rate.Name,
+				}
+				totals[rate.key()] = total
+				order = append(order, rate.key())
+			}
+			if !component.Exempt {
+				total.Taxable += line.Amount
+			}
+			total.Tax += component.Amount
+		}
+
+		lineTax.Tax = roundCents(lineTax.Tax)
+		lineTax.Display = lineTax.Net
+		if display == Inclusive {
+			lineTax.Display = roundCents(lineTax.Net + lineTax.Tax)
+		}
+
+		summary.Lines = append(summary.Lines, lineTax)
+		summary.Net += lineTax.Net
+		summary.Tax += lineTax.Tax
+	}
+
+	for _, key := range order {
+		total := totals[key]
+		total.Taxable = roundCents(total.Taxable)
+		total.Tax = roundCents(total.Tax)
+		summary.Jurisdictions = append(summary.Jurisdictions, *total)
+	}
+
+	summary.Net = roundCents(summary.Net)
+	summary.Tax = roundCents(summary.Tax)
+	summary.Gross = roundCents(summary.Net + summary.Tax)
+	return summary, nil
+}
+
+// exempt reports whether any exemption covers a rate for a category
+func exempt(exemptions []Exemption, rate Rate, category string) bool {
+	for _, exemption := range exemptions {
+		if exemption.covers(rate, category) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateExemptions validates a customer's tax exemptions
+func ValidateExemptions(exemptions []Exemption) error {
+	for i, exemption := range exemptions {
+		if len(normalizeCode(exemption.Country)) != 2 {
+			return fmt.Errorf("exemption %d: country must be a two-letter code", i+1)
+		}
+	}
+	return nil
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func normalizePostalCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+}
+
+func normalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+func normalizeAddress(address Address) Address {
+	return Address{
+		Country:    normalizeCode(address.Country),
+		Region:     normalizeCode(address.Region),
+		PostalCode: normalizePostalCode(address.PostalCode),
+	}
+}
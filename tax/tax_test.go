@@ -0,0 +1,199 @@
+package tax
+
+import (
+	"testing"
+)
+
+func createTestTable(t *testing.T) *Table {
+	table, err := NewTable([]Rate{
+		{Country: "DE", Name: "VAT", Rate: 19},
+		{Country: "DE", Category: "books", Name: "VAT reduced", Rate: 7},
+		{Country: "CA", Name: "GST", Rate: 5},
+		{Country: "CA", Region: "BC", Name: "BC PST", Rate: 7},
+		{Country: "CA", Region: "BC", Category: "software", Name: "BC PST software", Rate: 0},
+		{Country: "us", Region: "ca", Name: "California", Rate: 6},
+		{Country: "US", Region: "CA", PostalPrefix: "941", Name: "San Francisco", Rate: 2.625},
+	})
+	if err != nil {
+		t.Fatalf("NewTable failed: %v", err)
+	}
+	return table
+}
+
+func TestTable_Lookup(t *testing.T) {
+	table := createTestTable(t)
+
+	tests := []struct {
+		name     string
+		address  Address
+		category string
+		want     []string
+	}{
+		{"country standard rate", Address{Country: "DE"}, "hardware", []string{"VAT"}},
+		{"category replaces standard rate", Address{Country: "de"}, "Books", []string{"VAT reduced"}},
+		{"country and region stack", Address{Country: "CA", Region: "BC"}, "", []string{"GST", "BC PST"}},
+		{"region category rate", Address{Country: "CA", Region: "BC"}, "software", []string{"GST", "BC PST software"}},
+		{"other region", Address{Country: "CA", Region: "ON"}, "", []string{"GST"}},
+		{"postal prefix", Address{Country: "US", Region: "CA", PostalCode: "94107"}, "", []string{"California", "San Francisco"}},
+		{"outside postal prefix", Address{Country: "US", Region: "CA", PostalCode: "90210"}, "", []string{"California"}},
+		{"uncovered country", Address{Country: "FR"}, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates := table.Lookup(tt.address, tt.category)
+			if len(rates) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, rates)
+			}
+			for i, name := range tt.want {
+				if rates[i].Name != name {
+					t.Errorf("Expected rate %d to be %s, got %s", i, name, rates[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestNewTable_Validation(t *testing.T) {
+	tests := []struct {
+		name  string
+		rates []Rate
+	}{
+		{"invalid country", []Rate{{Country: "DEU", Name: "VAT", Rate: 19}}},
+		{"missing name", []Rate{{Country: "DE", Rate: 19}}},
+		{"negative rate", []Rate{{Country: "DE", Name: "VAT", Rate: -1}}},
+		{"duplicate rate", []Rate{{Country: "DE", Name: "VAT", Rate: 19}, {Country: "de", Name: "VAT 2", Rate: 16}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTable(tt.rates); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	table := createTestTable(t)
+	lines := []Line{
+		{ID: "laptop", Amount: 1000},
+		{ID: "license", Category: "software", Amount: 200},
+	}
+
+	summary, err := Calculate(table, Address{Country: "CA", Region: "BC"}, lines, nil, Exclusive)
+	if err != nil {
+		t.Fatalf("Calculate failed: %v", err)
+	}
+
+	// Laptop 5% + 7%, license 5% + 0%
+	if summary.Lines[0].Tax != 120 || summary.Lines[1].Tax != 10 {
+		t.Errorf("Expected line tax 120.00 and 10.00, got %.2f and %.2f", summary.Lines[0].Tax, summary.Lines[1].Tax)
+	}
+	if summary.Net != 1200 || summary.Tax != 130 || summary.Gross != 1330 {
+		t.Errorf("Expected 1200.00 + 130.00 = 1330.00, got %.2f + %.2f = %.2f", summary.Net, summary.Tax, summary.Gross)
+	}
+	if summary.Lines[0].Display != 1000 {
+		t.Errorf("Exclusive display should show net amounts, got %.2f", summary.Lines[0].Display)
+	}
+
+	if len(summary.Jurisdictions) != 3 || summary.Jurisdictions[0].Name != "GST" || summary.Jurisdictions[0].Tax != 60 {
+		t.Errorf("Expected GST of 60.00 first, got %v", summary.Jurisdictions)
+	}
+}
+
+func TestCalculate_JurisdictionsSharingName(t *testing.T) {
+	table, err := NewTable([]Rate{
+		{Country: "US", Region: "CA", Name: "Sales tax", Rate: 6},
+		{Country: "US", Region: "CA", PostalPrefix: "941", Name: "Sales tax", Rate: 2.5},
+	})
+	if err != nil {
+		t.Fatalf("NewTable failed: %v", err)
+	}
+
+	summary, err := Calculate(table, Address{Country: "US", Region: "CA", PostalCode: "94105"}, []Line{{ID: "a", Amount: 100}}, nil, Exclusive)
+	if err != nil {
+		t.Fatalf("Calculate failed: %v", err)
+	}
+
+	// Each jurisdiction keeps its own total even when the rates share a name
+	if len(summary.Jurisdictions) != 2 {
+		t.Fatalf("Expected 2 jurisdictions, got %v", summary.Jurisdictions)
+	}
+	state, city := summary.Jurisdictions[0], summary.Jurisdictions[1]
+	if state.PostalPrefix != "" || state.Tax != 6 || state.Taxable != 100 {
+		t.Errorf("Expected state tax of 6.00 on 100.00, got %+v", state)
+	}
+	if city.PostalPrefix != "941" || city.Tax != 2.5 || city.Taxable != 100 {
+		t.Errorf("Expected city tax of 2.50 on 100.00, got %+v", city)
+	}
+}
+
+func TestCalculate_Inclusive(t *testing.T) {
+	table := createTestTable(t)
+
+	summary, err := Calculate(table, Address{Country: "DE"}, []Line{{ID: "a", Amount: 99.99}}, nil, Inclusive)
+	if err != nil {
+		t.Fatalf("Calculate failed: %v", err)
+	}
+
+	// Tax is the same either way; only the displayed line amount changes
+	if summary.Lines[0].Tax != 19 || summary.Lines[0].Display != 118.99 || summary.Gross != 118.99 {
+		t.Errorf("Expected tax 19.00 shown as 118.99, got %.2f shown as %.2f", summary.Lines[0].Tax, summary.Lines[0].Display)
+	}
+}
+
+func TestCalculate_Exemptions(t *testing.T) {
+	table := createTestTable(t)
+	address := Address{Country: "CA", Region: "BC"}
+	lines := []Line{
+		{ID: "laptop", Category: "hardware", Amount: 1000},
+		{ID: "book", Category: "books", Amount: 100},
+	}
+
+	tests := []struct {
+		name      string
+		exemption Exemption
+		want      float64
+	}{
+		{"country exemption", Exemption{Country: "CA"}, 0},
+		{"region exemption keeps federal tax", Exemption{Country: "CA", Region: "BC"}, 55},
+		{"category exemption", Exemption{Country: "CA", Categories: []string{"Hardware"}}, 12},
+		{"other country", Exemption{Country: "US"}, 132},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, err := Calculate(table, address, lines, []Exemption{tt.exemption}, Exclusive)
+			if err != nil {
+				t.Fatalf("Calculate failed: %v", err)
+			}
+			if summary.Tax != tt.want {
+				t.Errorf("Expected tax %.2f, got %.2f", tt.want, summary.Tax)
+			}
+		})
+	}
+
+	summary, _ := Calculate(table, address, lines[:1], []Exemption{{Country: "CA", Region: "BC"}}, Exclusive)
+	if components := summary.Lines[0].Components; len(components) != 2 || !components[1].Exempt || components[0].Exempt {
+		t.Errorf("Expected exempt BC PST beside taxed GST, got %v", components)
+	}
+}
+
+func TestCalculate_Errors(t *testing.T) {
+	table := createTestTable(t)
+	lines := []Line{{ID: "a", Amount: 10}}
+
+	if _, err := Calculate(table, Address{}, lines, nil, Exclusive); err == nil {
+		t.Error("Expected error for address without country")
+	}
+	if _, err := Calculate(table, Address{Country: "FR"}, lines, nil, Exclusive); err == nil {
+		t.Error("Expected error for country without rates")
+	}
+	if _, err := Calculate(table, Address{Country: "DE"}, lines, nil, "gross"); err == nil {
+		t.Error("Expected error for unknown display mode")
+	}
+	if err := ValidateExemptions([]Exemption{{Region: "BC"}}); err == nil {
+		t.Error("Expected error for exemption without country")
+	}
+}